@@ -45,10 +45,16 @@ run_and_parse() {
       return 1
     fi
     local time_ns
-    time_ns=$(echo "$out" | sed -n 's/.*TIME_NS=\([0-9][0-9]*\).*/\1/p' | head -n1)
+    # TIME_NS=<ns>, or TIME_NS: <ns> from print_time_ns.
+    time_ns=$(echo "$out" | sed -n 's/.*TIME_NS[=:] *\([0-9][0-9]*\).*/\1/p' | head -n1)
     local nparam
     nparam=$(echo "$out" | sed -n 's/.*N=\([0-9][0-9]*\).*/\1/p' | head -n1)
-    [[ -z "$nparam" ]] && nparam="${args[0]}"
+    if [[ -z "$nparam" ]]; then
+      # The first numeric argument; the VM takes a workload name first.
+      for a in "${args[@]}"; do
+        if [[ "$a" =~ ^[0-9]+$ ]]; then nparam="$a"; break; fi
+      done
+    fi
     local varfield
     varfield=$(echo "$out" | sed -n 's/.*VAR=\([0-9.][0-9.]*\).*/VAR=\1/p' | head -n1)
     extra_last="$varfield"
@@ -98,6 +104,14 @@ run_and_parse "${BIN_DIR}/fib_cli" "fib_iter" "tenge" "$FIB_ITER_N"
 echo " -> Running fib_rec for tenge with N=${FIB_REC_N}..."
 run_and_parse "${BIN_DIR}/fib_rec_cli" "fib_rec" "tenge" "$FIB_REC_N"
 
+# Tenge bytecode VM (impl='vm')
+echo " -> Running sort_qsort for vm with N=${SIZE}..."
+run_and_parse "${BIN_DIR}/vm" "sort_qsort" "vm" sort "$SIZE"
+echo " -> Running fib_iter for vm with N=${FIB_ITER_N}..."
+run_and_parse "${BIN_DIR}/vm" "fib_iter" "vm" fib_iter "$FIB_ITER_N"
+echo " -> Running fib_rec for vm with N=${FIB_REC_N}..."
+run_and_parse "${BIN_DIR}/vm" "fib_rec" "vm" fib_rec "$FIB_REC_N"
+
 # Monte Carlo
 echo " -> Running var_mc_sort for tenge with N=${VAR_N}..."
 run_and_parse "${BIN_DIR}/var_mc_tng_sort" "var_mc_sort" "tenge" "$VAR_N" "$VAR_STEPS" "$VAR_ALPHA"
@@ -105,6 +119,8 @@ echo " -> Running var_mc_zig for tenge with N=${VAR_N}..."
 run_and_parse "${BIN_DIR}/var_mc_tng_zig" "var_mc_zig" "tenge" "$VAR_N" "$VAR_STEPS" "$VAR_ALPHA"
 echo " -> Running var_mc_qsel for tenge with N=${VAR_N}..."
 run_and_parse "${BIN_DIR}/var_mc_tng_qsel" "var_mc_qsel" "tenge" "$VAR_N" "$VAR_STEPS" "$VAR_ALPHA"
+echo " -> Running var_mc_sort for vm with N=${VAR_N}..."
+run_and_parse "${BIN_DIR}/vm" "var_mc_sort" "vm" var_mc_sort "$VAR_N" "$VAR_STEPS" "$VAR_ALPHA"
 
 echo " -> Running var_mc for c with N=${VAR_N}..."
 run_and_parse "${BIN_DIR}/var_mc_c" "var_mc" "c" "$VAR_N" "$VAR_STEPS" "$VAR_ALPHA"
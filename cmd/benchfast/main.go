@@ -275,18 +275,28 @@ func targetsFor(kind string) []Target {
 		})
 	}
 
-	// VM (iter only)
+	// VM
 	if kind == "iter" {
 		t = append(t, Target{
 			Name: "vm",
 			Kind: "iter",
 			Bin:  ".bin/vm",
 			ArgsFn: func(n int) []string {
-				// existing vm expects fib_iter only; pass N as arg
 				return []string{"fib_iter", fmt.Sprintf("%d", n)}
 			},
 			OnlyIf: func() bool { return fileExists(".bin/vm") },
-			SkipMsg: "vm supports fib_iter only",
+			SkipMsg: "vm binary missing: .bin/vm",
+		})
+	} else {
+		t = append(t, Target{
+			Name: "vm",
+			Kind: "rec",
+			Bin:  ".bin/vm",
+			ArgsFn: func(n int) []string {
+				return []string{"fib_rec", fmt.Sprintf("%d", n)}
+			},
+			OnlyIf: func() bool { return fileExists(".bin/vm") },
+			SkipMsg: "vm binary missing: .bin/vm",
 		})
 	}
 
@@ -0,0 +1,82 @@
+// FILE: cmd/tenge/arith_test.go
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestIntegerArithmetic checks that integer arithmetic means the same on
+// every backend and in every profile: it wraps around, MIN / -1 included,
+// and division by zero is an error.
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+		err  string // FILE stands for the path of the program
+	}{
+		{"i64 wraps", `
+    var x: int = 9223372036854775807;
+    var y: int = x + 1;
+    print(y);
+    print(y - 1);
+    print(x * 3);
+`, "-9223372036854775808\n9223372036854775807\n9223372036854775805\n", ""},
+		{"i32 wraps", `
+    var x: i32 = 2147483647;
+    x = x + 1;
+    print(x);
+    x = x * 2;
+    print(x);
+`, "-2147483648\n0\n", ""},
+		{"u64 wraps", `
+    var u: u64 = 0;
+    u = u - 1;
+    print(u);
+    print(u * u);
+`, "18446744073709551615\n1\n", ""},
+		{"MIN / -1", `
+    var m: int = -9223372036854775807 - 1;
+    var d: int = -1;
+    print(m / d);
+    print(m % d);
+`, "-9223372036854775808\n0\n", ""},
+		{"i32 MIN / -1", `
+    var m: i32 = i32(-2147483647) - 1;
+    var d: i32 = -1;
+    print(m / d);
+    print(m % d);
+`, "-2147483648\n0\n", ""},
+		{"division by zero", `
+    var z: int = 0;
+    print(7 / z);
+`, "", "FILE:5:13: division by zero"},
+		{"remainder by zero", `
+    var z: u64 = 0;
+    print(u64(7) % z);
+`, "", "FILE:5:18: division by zero"},
+		{"i32 division by zero", `
+    var z: i32 = 0;
+    print(i32(7) / z);
+`, "", "FILE:5:18: division by zero"},
+	}
+	for _, tt := range tests {
+		for _, b := range allBackends {
+			t.Run(tt.name+"/"+b.name, func(t *testing.T) {
+				path := writeFile(t, "arith.tng", "#syntax latin\n\nfn main() {"+tt.body+"}\n")
+				got, err := b.run(t, path)
+				if tt.err == "" && err != nil {
+					t.Fatal(err)
+				}
+				if want := strings.ReplaceAll(tt.err, "FILE", path); tt.err != "" && (err == nil || err.Error() != want) {
+					t.Errorf("error %v, want %s", err, want)
+				}
+				if got != tt.want {
+					t.Errorf("output %q, want %q", got, tt.want)
+				}
+			})
+		}
+	}
+}
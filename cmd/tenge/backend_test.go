@@ -0,0 +1,87 @@
+// FILE: cmd/tenge/backend_test.go
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DauletBai/tenge/internal/aotminic"
+	"github.com/DauletBai/tenge/internal/lang/evaluator"
+	"github.com/DauletBai/tenge/internal/lang/object"
+	"github.com/DauletBai/tenge/internal/vm"
+)
+
+// backend runs the program at path with the command-line arguments args
+// and returns what it printed. The error is the run-time error of the
+// program, if it failed.
+type backend struct {
+	name string
+	run  func(t *testing.T, path string, args ...string) (string, error)
+}
+
+var (
+	interpreter = backend{"interpreter", interpret}
+	onVM        = backend{"vm", runOnVM}
+	debugC      = backend{"debug C", native(aotminic.Debug)}
+	releaseC    = backend{"release C", native(aotminic.Release)}
+
+	allBackends = []backend{interpreter, onVM, debugC, releaseC}
+)
+
+func interpret(t *testing.T, path string, args ...string) (string, error) {
+	t.Helper()
+	prog, err := loadProgram(path)
+	if err == nil {
+		_, err = checkProgram(prog)
+	}
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	var out strings.Builder
+	stdout, oldArgs := evaluator.Stdout, evaluator.Args
+	defer func() { evaluator.Stdout, evaluator.Args = stdout, oldArgs }()
+	evaluator.Stdout, evaluator.Args = &out, append([]string{path}, args...)
+	if e, ok := evaluator.RunModules(prog).(*object.Error); ok {
+		return out.String(), errors.New(e.Message)
+	}
+	return out.String(), nil
+}
+
+func runOnVM(t *testing.T, path string, args ...string) (string, error) {
+	t.Helper()
+	code, err := compileBytecode(path)
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	var out strings.Builder
+	err = vm.Run(code, append([]string{path}, args...), &out)
+	return out.String(), err
+}
+
+// native builds binaries with the C compiler in the given profile. The
+// tests using it are skipped when there is no C compiler.
+func native(p aotminic.Profile) func(t *testing.T, path string, args ...string) (string, error) {
+	return func(t *testing.T, path string, args ...string) (string, error) {
+		t.Helper()
+		if _, err := exec.LookPath(envOr("CC", "cc")); err != nil {
+			t.Skipf("no C compiler: %v", err)
+		}
+		bin := filepath.Join(t.TempDir(), "prog")
+		rt := filepath.Join("..", "..", "internal", "aotminic", "runtime")
+		if code := run([]string{"build", "-profile", p.String(), "-runtime", rt, "-o", bin, path}); code != exitOK {
+			t.Fatalf("tenge build -profile %s %s: exit %d", p, path, code)
+		}
+		var stderr strings.Builder
+		cmd := exec.Command(bin, args...)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil && stderr.Len() > 0 {
+			err = errors.New(strings.TrimSpace(stderr.String()))
+		}
+		return string(out), err
+	}
+}
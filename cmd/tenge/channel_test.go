@@ -0,0 +1,179 @@
+// FILE: cmd/tenge/channel_test.go
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestChannels runs programs passing messages between tasks on the
+// interpreter and the C backends, which must agree on what they print and
+// on the errors of closed channels. The VM runs no tasks.
+func TestChannels(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+		err  string // FILE stands for the path of the program
+	}{
+		{"pipeline", `#syntax latin
+
+fn produce(out: chan[int], n: int) {
+    var i: int = 0;
+    while i < n {
+        send(out, i);
+        i = i + 1;
+    }
+    close(out);
+}
+
+fn square(src: chan[int], out: chan[int]) {
+    var ok: bool = true;
+    var x: int = recv(src, &ok);
+    while ok {
+        send(out, x * x);
+        x = recv(src, &ok);
+    }
+    close(out);
+}
+
+fn sum(src: chan[int]) -> int {
+    var total: int = 0;
+    var ok: bool = true;
+    var x: int = recv(src, &ok);
+    while ok {
+        total = total + x;
+        x = recv(src, &ok);
+    }
+    return total;
+}
+
+fn main() {
+    let a = chan[int]();
+    let b = chan[int](4);
+    var total: int = 0;
+    join {
+        task produce(a, 100);
+        task square(a, b);
+        task total = sum(b);
+    }
+    print(total);
+}
+`, "328350\n", ""},
+		{"select with timeout", `#syntax latin
+
+fn later(c: chan[int]) {
+    select {
+        timeout(30) { }
+    }
+    send(c, 7);
+}
+
+fn main() {
+    let c = chan[int]();
+    let quiet = chan[int]();
+    select {
+        let x = recv(quiet) { print("got"); }
+        timeout(10) { print("timeout "); }
+    }
+    select {
+        recv(quiet) { print("got"); }
+        else { print("nothing ready "); }
+    }
+    join {
+        task later(c);
+        var got: int = 0;
+        var waits: int = 0;
+        while got == 0 {
+            select {
+                got = recv(c) { }
+                timeout(5) { waits = waits + 1; }
+            }
+        }
+        print(got);
+        if waits > 0 { print("waited"); }
+    }
+}
+`, "timeout nothing ready 7\nwaited", ""},
+		{"recv from a closed channel", `#syntax latin
+
+fn main() {
+    let c = chan[int](2);
+    send(c, 5);
+    close(c);
+    var ok: bool = false;
+    print(recv(c, &ok));
+    if ok { print("ok "); }
+    print(recv(c, &ok));
+    if !ok { print("closed"); }
+}
+`, "5\nok 0\nclosed", ""},
+		{"send on a closed channel", `#syntax latin
+
+fn main() {
+    let c = chan[int](1);
+    close(c);
+    send(c, 1);
+}
+`, "", "FILE:6:5: send on a closed channel"},
+		{"close of a closed channel", `#syntax latin
+
+fn main() {
+    let c = chan[int](1);
+    close(c);
+    close(c);
+}
+`, "", "FILE:6:5: close of a closed channel"},
+	}
+	for _, tt := range tests {
+		for _, b := range []backend{interpreter, debugC, releaseC} {
+			t.Run(tt.name+"/"+b.name, func(t *testing.T) {
+				path := writeFile(t, "chan.tng", tt.src)
+				got, err := b.run(t, path)
+				if tt.err == "" && err != nil {
+					t.Fatal(err)
+				}
+				if want := strings.ReplaceAll(tt.err, "FILE", path); tt.err != "" && (err == nil || err.Error() != want) {
+					t.Errorf("error %v, want %s", err, want)
+				}
+				if got != tt.want {
+					t.Errorf("output %q, want %q", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+// TestDeadlock checks the report of a program whose tasks all wait on
+// each other. Release builds do not look for deadlocks and hang instead.
+func TestDeadlock(t *testing.T) {
+	src := `#syntax latin
+
+fn wait(c: chan[int]) {
+    let x = recv(c);
+    print(x);
+}
+
+fn main() {
+    let c = chan[int]();
+    let d = chan[int]();
+    join {
+        task wait(c);
+        send(d, 1);
+    }
+}
+`
+	for _, b := range []backend{interpreter, debugC} {
+		t.Run(b.name, func(t *testing.T) {
+			path := writeFile(t, "deadlock.tng", src)
+			_, err := b.run(t, path)
+			want := strings.ReplaceAll(`FILE:13:9: deadlock: every task is blocked
+	FILE:4:13: blocked in recv
+	FILE:13:9: blocked in send`, "FILE", path)
+			if err == nil || err.Error() != want {
+				t.Errorf("error %v, want %s", err, want)
+			}
+		})
+	}
+}
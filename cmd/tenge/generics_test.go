@@ -0,0 +1,65 @@
+// FILE: cmd/tenge/generics_test.go
+
+package main
+
+import "testing"
+
+// genericsSrc calls generic functions with several type arguments. The
+// declarations typed T check that the interpreter, which has no types at
+// run time, gives them the type T stands for in the call.
+const genericsSrc = `#syntax latin
+
+fn mx[T: Ordered](a: T, b: T) -> T {
+    if a > b {
+        return a;
+    }
+    return b;
+}
+
+fn sum[T: Number](xs: []T) -> T {
+    var s: T = 0;
+    var i = 0;
+    while i < len(xs) {
+        s = s + xs[i];
+        i = i + 1;
+    }
+    return s;
+}
+
+fn half[T: Number](x: T) -> T {
+    var h: T;
+    h = x / 2;
+    return h;
+}
+
+fn main() {
+    print(mx(3, 7));
+    print(mx(f64(2.5), f64(-1)));
+    print(mx("ab", "b"));
+    print("\n");
+    let ys: []f64 = [f64(0.5), f64(0.25)];
+    print(sum(ys));
+    print(sum([1, 2, 3]));
+    print(half(f64(5)));
+    print(half(5));
+    print(half(u64(7)));
+}
+`
+
+// TestGenerics runs the instances of generic functions on every backend;
+// the C backend compiles each instance separately.
+func TestGenerics(t *testing.T) {
+	const want = "7\n2.5\nb\n0.75\n6\n2.5\n2\n3\n"
+	path := writeFile(t, "generics.tng", genericsSrc)
+	for _, b := range allBackends {
+		t.Run(b.name, func(t *testing.T) {
+			got, err := b.run(t, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("output %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -1,461 +1,926 @@
 // FILE: cmd/tenge/main.go
-// Purpose: Minimal AOT demo driver that maps known demo .tng sources to emitted C.
-// Supported demos:
-//   - benchmarks/src/tenge/fib_iter_cli.tng
-//   - benchmarks/src/tenge/fib_rec_cli.tng
-//   - benchmarks/src/tenge/sort_cli_qs.tng
-//   - benchmarks/src/tenge/sort_cli_ms.tng
-//   - benchmarks/src/tenge/var_mc_sort_cli.tng
-//   - benchmarks/src/tenge/var_mc_zig_cli.tng
-//   - benchmarks/src/tenge/var_mc_qsel_cli.tng
-//   - benchmarks/src/tenge/sort_cli_pdq.tng
-//   - benchmarks/src/tenge/sort_cli_radix.tng
+// Purpose: The tenge command-line driver.
+//
+//	tenge <command> [flags] [arguments]
+//
+// Exit codes are shared by all commands: 0 on success, 1 when the program
+// has errors (parse, type, runtime or failing tests) and 2 on bad usage.
+//
+// Diagnostics are printed in the language given by the -lang flag of any
+// command or the TENGE_LANG environment variable: kk, ru or en.
 
 package main
 
 import (
+	"bytes"
 	"errors"
+	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/DauletBai/tenge/internal/aotminic"
+	"github.com/DauletBai/tenge/internal/ir"
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/diag"
+	"github.com/DauletBai/tenge/internal/lang/evaluator"
+	"github.com/DauletBai/tenge/internal/lang/format"
+	"github.com/DauletBai/tenge/internal/lang/lexer"
+	"github.com/DauletBai/tenge/internal/lang/module"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/object"
+	"github.com/DauletBai/tenge/internal/lang/types"
+	"github.com/DauletBai/tenge/internal/lsp"
+	"github.com/DauletBai/tenge/internal/vm"
+)
+
+// version is overridden at link time with -ldflags "-X main.version=...".
+var version = "dev"
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
 )
 
-func usage() {
-	fmt.Fprintln(os.Stderr, "usage: tenge -o <out.c> <demo_source.tng>")
-	os.Exit(2)
+// errUsage reports a command-line mistake; the command has already
+// printed the details.
+var errUsage = errors.New("usage")
+
+// errFailed reports that diagnostics were already printed.
+var errFailed = errors.New("failed")
+
+type command struct {
+	name  string
+	args  string
+	short string
+	run   func(fs *flag.FlagSet, args []string) error
+	setup func(fs *flag.FlagSet) // registers the command's flags
 }
 
-func must(err error) {
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
-	}
-}
-
-func emitC(path string) (string, error) {
-	base := filepath.Base(path)
-	switch base {
-	case "fib_iter_cli.tng":
-		return cFibIter, nil
-	case "fib_rec_cli.tng":
-		return cFibRec, nil
-	case "sort_cli_qs.tng":
-		return cSortQS, nil
-	case "sort_cli_ms.tng":
-		return cSortMS, nil
-	case "var_mc_sort_cli.tng":
-		return cVarMCSort, nil
-	case "var_mc_zig_cli.tng":
-		return cVarMCZig, nil
-	case "var_mc_qsel_cli.tng":
-		return cVarMCQSel, nil
-	case "sort_cli_pdq.tng":
-		return cSortPDQ, nil
-	case "sort_cli_radix.tng":
-		return cSortRadix, nil
-	default:
-		return "", errors.New("unsupported AOT demo source: " + path)
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{name: "run", args: "[flags] <file.tng> [program args...]", short: "interpret a program", setup: setupRun, run: runRun},
+		{name: "check", args: "<file.tng>...", short: "parse and type-check without running", run: runCheck},
+		{name: "build", args: "[flags] <file.tng>", short: "compile a program to a native binary via C", setup: setupBuild, run: runBuild},
+		{name: "emit-c", args: "-o <out.c> <file.tng>", short: "write the generated C", setup: setupEmitC, run: runEmitC},
+		{name: "emit-ast", args: "[-o out] <file.tng>", short: "dump the syntax tree", setup: setupOutput, run: runEmitAST},
+		{name: "emit-bytecode", args: "-o <out.tbc> <file.tng>", short: "write VM bytecode", setup: setupOutput, run: runEmitBytecode},
+		{name: "emit-ir", args: "[-o out] [-passes list] <file.tng>", short: "dump the IR before and after each optimization pass", setup: setupEmitIR, run: runEmitIR},
+		{name: "disasm", args: "[-o out] <file.tbc|file.tng>", short: "list VM bytecode", setup: setupOutput, run: runDisasm},
+		{name: "fmt", args: "[flags] <file.tng>...", short: "format source files", setup: setupFmt, run: runFmt},
+		{name: "lsp", args: "", short: "run the language server on stdin and stdout", run: runLsp},
+		{name: "repl", args: "[-history file]", short: "start an interactive session", setup: setupRepl, run: runRepl},
+		{name: "test", args: "[-native] [dir|file.tng]...", short: "run *_test.tng files", setup: setupTest, run: runTest},
+		{name: "version", args: "", short: "print the tenge version", run: runVersion},
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: tenge <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", c.name, c.short)
 	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "run 'tenge <command> -h' for the flags of a command")
 }
 
 func main() {
-	if len(os.Args) < 3 || os.Args[1] != "-o" {
-		usage()
-	}
-	out := os.Args[2]
-	if len(os.Args) < 4 {
-		usage()
-	}
-	src := os.Args[3]
-
-	code, err := emitC(src)
-	must(err)
-
-	err = ioutil.WriteFile(out, []byte(code), 0644)
-	must(err)
-	fmt.Printf("C emitted: %s\n", out)
-}
-
-// --- C templates for demos ---
-
-// fib_iter with microsecond batching: TIME_NS reports avg nanoseconds per run
-const cFibIter = `#include <stdio.h>
-#include <stdint.h>
-#include <stdlib.h>
-#include <sys/time.h>
-
-static inline long long now_us(){
-    struct timeval tv;
-    gettimeofday(&tv, NULL);
-    return (long long)tv.tv_sec*1000000LL + (long long)tv.tv_usec;
-}
-
-int main(int argc,char**argv){
-    int n    = (argc>1)?atoi(argv[1]):90;
-    int reps = (argc>2)?atoi(argv[2]):1000000; // default 1e6 internal repetitions
-
-    // simple iterative Fibonacci
-    volatile unsigned __int128 sink = 0; // prevent over-optimization
-    long long t0 = now_us();
-    for(int r=0; r<reps; r++){
-        unsigned __int128 a=0,b=1;
-        for(int i=0;i<n;i++){ unsigned __int128 t=a+b; a=b; b=t; }
-        sink += b;
-    }
-    long long t1 = now_us();
-
-    long long elapsed_us = (t1 - t0);
-    long long avg_ns = (elapsed_us * 1000LL) / (reps>0?reps:1);
-
-    // keep the same output contract: TIME_NS is per-run average
-    printf("TASK=fib_iter,N=%d,TIME_NS=%lld\n", n, avg_ns);
-    (void)sink;
-    return 0;
-}
-`
-
-const cFibRec = `#include <stdio.h>
-#include <stdlib.h>
-#include <time.h>
-static inline long long now_ns(){struct timespec ts;clock_gettime(CLOCK_MONOTONIC,&ts);return (long long)ts.tv_sec*1000000000LL+ts.tv_nsec;}
-long long fib(int n){return n<2?n:fib(n-1)+fib(n-2);}
-int main(int argc,char**argv){int n=(argc>1)?atoi(argv[1]):35; long long t0=now_ns(); volatile long long r=fib(n); long long t1=now_ns(); (void)r; printf("TASK=fib_rec,N=%d,TIME_NS=%lld\n",n,(t1-t0)); return 0;}
-`
-
-const cSortQS = `#include <stdio.h>
-#include <stdlib.h>
-#include <time.h>
-static inline long long now_ns(){struct timespec ts;clock_gettime(CLOCK_MONOTONIC,&ts);return (long long)ts.tv_sec*1000000000LL+ts.tv_nsec;}
-static int cmpi(const void*a,const void*b){int ia=*(const int*)a, ib=*(const int*)b; return (ia>ib)-(ia<ib);}
-int main(int argc,char**argv){int n=(argc>1)?atoi(argv[1]):100000; int*arr=(int*)malloc(sizeof(int)*n); for(int i=0;i<n;i++){arr[i]=n-i;} long long t0=now_ns(); qsort(arr,n,sizeof(int),cmpi); long long t1=now_ns(); printf("TASK=sort_qsort,N=%d,TIME_NS=%lld\n",n,(t1-t0)); free(arr); return 0;}
-`
-
-const cSortMS = `#include <stdio.h>
-#include <stdlib.h>
-#include <string.h>
-#include <time.h>
-static inline long long now_ns(){struct timespec ts;clock_gettime(CLOCK_MONOTONIC,&ts);return (long long)ts.tv_sec*1000000000LL+ts.tv_nsec;}
-static void msort(int* a,int n,int* tmp){ if(n<=1) return; int m=n/2; msort(a,m,tmp); msort(a+m,n-m,tmp); int i=0,j=m,k=0; while(i<m && j<n){ tmp[k++]= (a[i]<=a[j])?a[i++]:a[j++]; } while(i<m) tmp[k++]=a[i++]; while(j<n) tmp[k++]=a[j++]; memcpy(a,tmp,sizeof(int)*n); }
-int main(int argc,char**argv){int n=(argc>1)?atoi(argv[1]):100000; int*arr=(int*)malloc(sizeof(int)*n); int*tmp=(int*)malloc(sizeof(int)*n); for(int i=0;i<n;i++){arr[i]=n-i;} long long t0=now_ns(); msort(arr,n,tmp); long long t1=now_ns(); printf("TASK=sort_msort,N=%d,TIME_NS=%lld\n",n,(t1-t0)); free(arr); free(tmp); return 0;}
-`
-
-// --- Monte Carlo VaR (full sort, Box–Muller) ---
-const cVarMCSort = `#include <stdio.h>
-#include <stdlib.h>
-#include <stdint.h>
-#include <math.h>
-#include <time.h>
-static inline long long now_ns(){struct timespec ts;clock_gettime(CLOCK_MONOTONIC,&ts);return (long long)ts.tv_sec*1000000000LL+ts.tv_nsec;}
-static uint64_t xs=0x9E3779B97F4A7C15ULL;
-static inline void s(uint64_t v){ xs = v? v:0x9E3779B97F4A7C15ULL; }
-static inline uint64_t n64(){ uint64_t x=xs; x^=x>>12; x^=x<<25; x^=x>>27; xs=x; return x*0x2545F4914F6CDD1DULL; }
-static inline double u01(){ return (n64()>>11) * (1.0/9007199254740992.0); }
-static inline double z01(){ double u1=u01(); if(u1<1e-300) u1=1e-300; double u2=u01(); return sqrt(-2.0*log(u1))*cos(2.0*M_PI*u2); }
-static int cmpd(const void*a,const void*b){double da=*(const double*)a, db=*(const double*)b; return (da>db)-(da<db);}
-int main(int argc,char**argv){
-    int N = (argc>1)?atoi(argv[1]):1000000;
-    int steps = (argc>2)?atoi(argv[2]):1;
-    double alpha = (argc>3)?atof(argv[3]):0.99;
-    const double S0=100.0, mu=0.05, sigma=0.20;
-    double T=(double)steps/252.0, dt=T/(double)steps;
-    double*loss=(double*)malloc(sizeof(double)*N);
-    s(123456789u);
-    long long t0=now_ns();
-    for(int i=0;i<N;i++){
-        double S=S0;
-        for(int k=0;k<steps;k++){
-            double z=z01();
-            double drift=(mu-0.5*sigma*sigma)*dt;
-            double diff=sigma*sqrt(dt)*z;
-            S*=exp(drift+diff);
-        }
-        loss[i]=-(S-S0);
-    }
-    qsort(loss,N,sizeof(double),cmpd);
-    int idx = N-1 - (int)((1.0 - alpha)*N);
-    if(idx<0) idx=0; if(idx>=N) idx=N-1;
-    double var=loss[idx];
-    long long t1=now_ns();
-    printf("TASK=var_mc_sort,N=%d,TIME_NS=%lld,VAR=%.6f\n",N,(t1-t0),var);
-    free(loss);
-    return 0;
-}
-`
-
-// --- Monte Carlo VaR (Ziggurat + full sort) ---
-const cVarMCZig = `#include <stdio.h>
-#include <stdlib.h>
-#include <stdint.h>
-#include <math.h>
-#include <time.h>
-static inline long long now_ns(){struct timespec ts;clock_gettime(CLOCK_MONOTONIC,&ts);return (long long)ts.tv_sec*1000000000LL+ts.tv_nsec;}
-static uint64_t xs=0x9E3779B97F4A7C15ULL;
-static inline void s(uint64_t v){ xs = v? v:0x9E3779B97F4A7C15ULL; }
-static inline uint64_t n64(){ uint64_t x=xs; x^=x>>12; x^=x<<25; x^=x>>27; xs=x; return x*0x2545F4914F6CDD1DULL; }
-static inline double u01(){ return (n64()>>11) * (1.0/9007199254740992.0); }
-static const double R=3.442619855899;
-static const double INV_R=1.0/3.442619855899;
-static const double X[129]={
-  3.713086246740, 3.442619855899, 3.223084984578, 3.083228858216, 2.978696252647,
-  2.894344007019, 2.823125350548, 2.761169372286, 2.706113573119, 2.656406411259,
-  2.610972248428, 2.569033625924, 2.530010240221, 2.493457369855, 2.459018177410,
-  2.426400252942, 2.395362534774, 2.365703151121, 2.337250756573, 2.309857274401,
-  2.283392779016, 2.257741442219, 2.232799516605, 2.208472701532, 2.184674478490,
-  2.161325530309, 2.138352161505, 2.115685757570, 2.093262315344, 2.071021998623,
-  2.048908755913, 2.026869017441, 2.004851427104, 1.982806617372, 1.960686993962,
-  1.938446463114, 1.916040252994, 1.893424732083, 1.870557257243, 1.847395041048,
-  1.823894034139, 1.800008806293, 1.775691455004, 1.750890542681, 1.725550061133,
-  1.699608381616, 1.673997226122, 1.647640652104, 1.620453028623, 1.592336021002,
-  1.563176605944, 1.532844131841, 1.501187233627, 1.468029285510, 1.433161927236,
-  1.396338520342, 1.357251772698, 1.315510551578, 1.270579286269, 1.221653345322,
-  1.167516788538, 1.106842816421, 1.037314720727, 0.955242247089, 0.854753190635,
-  0.724597525270, 0.546082246193, 0.298741512247, 0.000000000000, 0.0
-};
-static const double Y[129]={
-  0.000000000000, 0.002669629083, 0.005548995220, 0.008616049314, 0.011848249446,
-  0.015224797764, 0.018726306024, 0.022334586345, 0.026032444293, 0.029803507224,
-  0.033632081515, 0.037502980167, 0.041401422775, 0.045312939729, 0.049223288290,
-  0.053118446590, 0.056984626090, 0.060808309586, 0.064576309530, 0.068275837491,
-  0.071894571891, 0.075420740170, 0.078843194494, 0.082151511210, 0.085335102391,
-  0.088384327731, 0.091290632173, 0.094046675118, 0.096646467223, 0.099085510165,
-  0.101360925011, 0.103471548533, 0.105417999672, 0.107202718981, 0.108829996002,
-  0.110306003874, 0.111638817240, 0.112838427538, 0.113916744246, 0.114887585293,
-  0.115766654987, 0.116571522172, 0.117321572752, 0.118037957728, 0.118743520275,
-  0.119462709101, 0.120221462444, 0.121047083725, 0.121968107869, 0.123014145236,
-  0.124215690093, 0.125604879480, 0.127215225941, 0.129081361763, 0.131238819135,
-  0.133723852717, 0.136572375761, 0.139818999595, 0.143495163124, 0.147627263907,
-  0.152233899679, 0.157321197585, 0.162879231358, 0.168877573620, 0.175257410941,
-  0.181924907008, 0.188735170653, 0.195471201528, 0.201792000000, 0.0
-};
-static inline double ziggurat_norm(){
-    for(;;){
-        uint64_t u=n64();
-        int i=(int)(u & 127u);
-        double sign = ((u>>8)&1u)? -1.0 : 1.0;
-        double x = (double)(u>>12) * (1.0/4503599627370496.0) * X[i];
-        if ((double)(u & 0xffffffffu)*(1.0/4294967296.0) < (Y[i+1]/Y[i])) return sign*x;
-        if (i==0){
-            double r = -log( u01() ) * INV_R;
-            return sign*(R + r);
-        } else {
-            double y = Y[i+1] + (Y[i]-Y[i+1])*u01();
-            if (y < exp(-0.5*x*x)) return sign*x;
-        }
-    }
-}
-static int cmpd(const void*a,const void*b){double da=*(const double*)a, db=*(const double*)b; return (da>db)-(da<db);}
-int main(int argc,char**argv){
-    int N = (argc>1)?atoi(argv[1]):1000000;
-    int steps = (argc>2)?atoi(argv[2]):1;
-    double alpha = (argc>3)?atof(argv[3]):0.99;
-    const double S0=100.0, mu=0.05, sigma=0.20;
-    double T=(double)steps/252.0, dt=T/(double)steps;
-    double*loss=(double*)malloc(sizeof(double)*N);
-    s(123456789u);
-    long long t0=now_ns();
-    for(int i=0;i<N;i++){
-        double S=S0;
-        for(int k=0;k<steps;k++){
-            double z=ziggurat_norm();
-            double drift=(mu-0.5*sigma*sigma)*dt;
-            double diff=sigma*sqrt(dt)*z;
-            S*=exp(drift+diff);
-        }
-        loss[i]=-(S-S0);
-    }
-    qsort(loss,N,sizeof(double),cmpd);
-    int idx = N-1 - (int)((1.0 - alpha)*N);
-    if(idx<0) idx=0; if(idx>=N) idx=N-1;
-    double var=loss[idx];
-    long long t1=now_ns();
-    printf("TASK=var_mc_zig,N=%d,TIME_NS=%lld,VAR=%.6f\n",N,(t1-t0),var);
-    free(loss);
-    return 0;
-}
-`
-
-// --- Monte Carlo VaR (Ziggurat + Quickselect O(N)) ---
-const cVarMCQSel = `#include <stdio.h>
-#include <stdlib.h>
-#include <stdint.h>
-#include <math.h>
-#include <time.h>
-static inline long long now_ns(){struct timespec ts;clock_gettime(CLOCK_MONOTONIC,&ts);return (long long)ts.tv_sec*1000000000LL+ts.tv_nsec;}
-static uint64_t xs=0x9E3779B97F4A7C15ULL;
-static inline void s(uint64_t v){ xs = v? v:0x9E3779B97F4A7C15ULL; }
-static inline uint64_t n64(){ uint64_t x=xs; x^=x>>12; x^=x<<25; x^=x>>27; xs=x; return x*0x2545F4914F6CDD1DULL; }
-static inline double u01(){ return (n64()>>11) * (1.0/9007199254740992.0); }
-static const double R=3.442619855899;
-static const double INV_R=1.0/3.442619855899;
-static const double X[129]={
-  3.713086246740, 3.442619855899, 3.223084984578, 3.083228858216, 2.978696252647,
-  2.894344007019, 2.823125350548, 2.761169372286, 2.706113573119, 2.656406411259,
-  2.610972248428, 2.569033625924, 2.530010240221, 2.493457369855, 2.459018177410,
-  2.426400252942, 2.395362534774, 2.365703151121, 2.337250756573, 2.309857274401,
-  2.283392779016, 2.257741442219, 2.232799516605, 2.208472701532, 2.184674478490,
-  2.161325530309, 2.138352161505, 2.115685757570, 2.093262315344, 2.071021998623,
-  2.048908755913, 2.026869017441, 2.004851427104, 1.982806617372, 1.960686993962,
-  1.938446463114, 1.916040252994, 1.893424732083, 1.870557257243, 1.847395041048,
-  1.823894034139, 1.800008806293, 1.775691455004, 1.750890542681, 1.725550061133,
-  1.699608381616, 1.673997226122, 1.647640652104, 1.620453028623, 1.592336021002,
-  1.563176605944, 1.532844131841, 1.501187233627, 1.468029285510, 1.433161927236,
-  1.396338520342, 1.357251772698, 1.315510551578, 1.270579286269, 1.221653345322,
-  1.167516788538, 1.106842816421, 1.037314720727, 0.955242247089, 0.854753190635,
-  0.724597525270, 0.546082246193, 0.298741512247, 0.000000000000, 0.0
-};
-static const double Y[129]={
-  0.000000000000, 0.002669629083, 0.005548995220, 0.008616049314, 0.011848249446,
-  0.015224797764, 0.018726306024, 0.022334586345, 0.026032444293, 0.029803507224,
-  0.033632081515, 0.037502980167, 0.041401422775, 0.045312939729, 0.049223288290,
-  0.053118446590, 0.056984626090, 0.060808309586, 0.064576309530, 0.068275837491,
-  0.071894571891, 0.075420740170, 0.078843194494, 0.082151511210, 0.085335102391,
-  0.088384327731, 0.091290632173, 0.094046675118, 0.096646467223, 0.099085510165,
-  0.101360925011, 0.103471548533, 0.105417999672, 0.107202718981, 0.108829996002,
-  0.110306003874, 0.111638817240, 0.112838427538, 0.113916744246, 0.114887585293,
-  0.115766654987, 0.116571522172, 0.117321572752, 0.118037957728, 0.118743520275,
-  0.119462709101, 0.120221462444, 0.121047083725, 0.121968107869, 0.123014145236,
-  0.124215690093, 0.125604879480, 0.127215225941, 0.129081361763, 0.131238819135,
-  0.133723852717, 0.136572375761, 0.139818999595, 0.143495163124, 0.147627263907,
-  0.152233899679, 0.157321197585, 0.162879231358, 0.168877573620, 0.175257410941,
-  0.181924907008, 0.188735170653, 0.195471201528, 0.201792000000, 0.0
-};
-static inline double ziggurat_norm(){
-    for(;;){
-        uint64_t u=n64();
-        int i=(int)(u & 127u);
-        double sign = ((u>>8)&1u)? -1.0 : 1.0;
-        double x = (double)(u>>12) * (1.0/4503599627370496.0) * X[i];
-        if ((double)(u & 0xffffffffu)*(1.0/4294967296.0) < (Y[i+1]/Y[i])) return sign*x;
-        if (i==0){
-            double r = -log( u01() ) * INV_R;
-            return sign*(R + r);
-        } else {
-            double y = Y[i+1] + (Y[i]-Y[i+1])*u01();
-            if (y < exp(-0.5*x*x)) return sign*x;
-        }
-    }
-}
-// In-place Quickselect (k-th smallest)
-static double quickselect(double* a, int n, int k){
-    int l=0, r=n-1;
-    while(1){
-        if(l==r) return a[l];
-        double pivot=a[(l+r)/2];
-        int i=l, j=r;
-        while(i<=j){
-            while(a[i]<pivot) i++;
-            while(a[j]>pivot) j--;
-            if(i<=j){ double t=a[i]; a[i]=a[j]; a[j]=t; i++; j--; }
-        }
-        if(k<=j) r=j; else if(k>=i) l=i; else return a[k];
-    }
-}
-int main(int argc,char**argv){
-    int N = (argc>1)?atoi(argv[1]):1000000;
-    int steps = (argc>2)?atoi(argv[2]):1;
-    double alpha = (argc>3)?atof(argv[3]):0.99;
-    const double S0=100.0, mu=0.05, sigma=0.20;
-    double T=(double)steps/252.0, dt=T/(double)steps;
-    double*loss=(double*)malloc(sizeof(double)*N);
-    s(123456789u);
-    long long t0=now_ns();
-    for(int i=0;i<N;i++){
-        double S=S0;
-        for(int k=0;k<steps;k++){
-            double z=ziggurat_norm();
-            double drift=(mu-0.5*sigma*sigma)*dt;
-            double diff=sigma*sqrt(dt)*z;
-            S*=exp(drift+diff);
-        }
-        loss[i]=-(S-S0);
-    }
-    int idx = N-1 - (int)((1.0 - alpha)*N);
-    if(idx<0) idx=0; if(idx>=N) idx=N-1;
-    double var = quickselect(loss, N, idx);
-    long long t1=now_ns();
-    printf("TASK=var_mc_qsel,N=%d,TIME_NS=%lld,VAR=%.6f\n",N,(t1-t0),var);
-    free(loss);
-    return 0;
-}
-`
-
-// --- SORT: PDQ-like introsort with insertion cutoff (no libc conflicts) ---
-const cSortPDQ = `#include <stdio.h>
-#include <stdlib.h>
-#include <time.h>
-#include <math.h>
-static inline long long now_ns(){struct timespec ts;clock_gettime(CLOCK_MONOTONIC,&ts);return (long long)ts.tv_sec*1000000000LL+ts.tv_nsec;}
-static inline void iswap(int* a,int* b){int t=*a;*a=*b;*b=t;}
-static inline int median3(int a,int b,int c){ if(a<b){ if(b<c) return b; return (a<c)?c:a; } else { if(a<c) return a; return (b<c)?c:b; } }
-static void insertion(int* a,int n){ for(int i=1;i<n;i++){ int x=a[i],j=i-1; while(j>=0 && a[j]>x){ a[j+1]=a[j]; j--; } a[j+1]=x; } }
-static int part_range(int* a,int l,int r){
-    int m = l + ((r-l)>>1);
-    int p = median3(a[l], a[m], a[r]);
-    int i=l, j=r;
-    while(i<=j){
-        while(a[i]<p) i++;
-        while(a[j]>p) j--;
-        if(i<=j){ iswap(&a[i],&a[j]); i++; j--; }
-    }
-    return i;
-}
-static void heapify(int* a,int n,int i){ for(;;){ int L=i*2+1, R=L+1, big=i; if(L<n && a[L]>a[big]) big=L; if(R<n && a[R]>a[big]) big=R; if(big==i) break; iswap(&a[i],&a[big]); i=big; } }
-static void hs_heap_sort(int* a,int n){ for(int i=n/2-1;i>=0;i--) heapify(a,n,i); for(int i=n-1;i>0;i--){ iswap(&a[0],&a[i]); heapify(a,i,0); } }
-static void introsort_range(int* a,int l,int r,int depth){
-    const int CUT=24;
-    while(l<r){
-        int n = r-l+1;
-        if(n<=CUT){ insertion(a+l, n); return; }
-        if(depth==0){ hs_heap_sort(a+l, n); return; }
-        int p = part_range(a,l,r);
-        if(p-l < r-(p-1)){ introsort_range(a,l,p-1,depth-1); l=p; }
-        else { introsort_range(a,p,r,depth-1); r=p-1; }
-    }
-}
-static void sort_pdq(int* a,int n){ int depth = (int)(2.0 * floor(log((double)n)/log(2.0))); introsort_range(a,0,n-1,depth); }
-int main(int argc,char**argv){
-    int n=(argc>1)?atoi(argv[1]):100000;
-    int*arr=(int*)malloc(sizeof(int)*n);
-    for(int i=0;i<n;i++){ arr[i]=n-i; }
-    long long t0=now_ns();
-    sort_pdq(arr,n);
-    long long t1=now_ns();
-    printf("TASK=sort_pdq,N=%d,TIME_NS=%lld\n",n,(t1-t0));
-    free(arr);
-    return 0;
-}
-`
-
-// --- SORT: 32-bit unsigned LSD radix (4 passes) ---
-const cSortRadix = `#include <stdio.h>
-#include <stdlib.h>
-#include <stdint.h>
-#include <string.h>
-#include <time.h>
-static inline long long now_ns(){struct timespec ts;clock_gettime(CLOCK_MONOTONIC,&ts);return (long long)ts.tv_sec*1000000000LL+ts.tv_nsec;}
-static void radix_u32(uint32_t* a,uint32_t* tmp,int n){
-    const int K=256;
-    int cnt[K];
-    for(int pass=0; pass<4; pass++){
-        memset(cnt,0,sizeof(cnt));
-        int shift = pass*8;
-        for(int i=0;i<n;i++){ cnt[(a[i]>>shift)&0xFF]++; }
-        int sum=0; for(int i=0;i<K;i++){ int c=cnt[i]; cnt[i]=sum; sum+=c; }
-        for(int i=0;i<n;i++){ tmp[ cnt[(a[i]>>shift)&0xFF]++ ] = a[i]; }
-        memcpy(a,tmp,sizeof(uint32_t)*n);
-    }
-}
-int main(int argc,char**argv){
-    int n=(argc>1)?atoi(argv[1]):100000;
-    uint32_t* arr=(uint32_t*)malloc(sizeof(uint32_t)*n);
-    uint32_t* tmp=(uint32_t*)malloc(sizeof(uint32_t)*n);
-    for(int i=0;i<n;i++){ arr[i]=(uint32_t)(n-i); }
-    long long t0=now_ns();
-    radix_u32(arr,tmp,n);
-    long long t1=now_ns();
-    printf("TASK=sort_radix,N=%d,TIME_NS=%lld\n",n,(t1-t0));
-    free(arr); free(tmp);
-    return 0;
-}
-`
\ No newline at end of file
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return exitUsage
+	}
+	// Deprecated form kept for old scripts: tenge -o <out.c> <src>
+	if args[0] == "-o" {
+		args = append([]string{"emit-c"}, args...)
+	}
+	switch args[0] {
+	case "-h", "-help", "--help", "help":
+		usage(os.Stdout)
+		return exitOK
+	}
+
+	var cmd *command
+	for _, c := range commands {
+		if c.name == args[0] {
+			cmd = c
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "tenge: unknown command %q\n", args[0])
+		usage(os.Stderr)
+		return exitUsage
+	}
+
+	if tag := os.Getenv("TENGE_LANG"); tag != "" && !msg.SetLang(tag) {
+		fmt.Fprintf(os.Stderr, "tenge: unknown TENGE_LANG %q (want kk, ru or en)\n", tag)
+	}
+
+	fs := flag.NewFlagSet("tenge "+cmd.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: tenge %s %s\n", cmd.name, cmd.args)
+		fs.PrintDefaults()
+	}
+	fs.Func("lang", "`language` of diagnostics: kk, ru or en (default $TENGE_LANG, else en)", func(tag string) error {
+		if !msg.SetLang(tag) {
+			return fmt.Errorf("unknown language %q (want kk, ru or en)", tag)
+		}
+		return nil
+	})
+	fs.BoolVar(&diagJSON, "json", false, "print diagnostics as JSON, one object per line")
+	if cmd.setup != nil {
+		cmd.setup(fs)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	switch err := cmd.run(fs, fs.Args()); {
+	case err == nil:
+		return exitOK
+	case err == errUsage:
+		fs.Usage()
+		return exitUsage
+	case err == errFailed:
+		return exitError
+	default:
+		fmt.Fprintf(os.Stderr, "tenge %s: %v\n", cmd.name, err)
+		return exitError
+	}
+}
+
+// --- Shared front end ---
+
+// loadProgram parses path and the modules it imports, printing
+// diagnostics.
+func loadProgram(path string) (*module.Program, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	prog, errs := module.Load(path)
+	if len(errs) > 0 {
+		report(errs)
+		return nil, errFailed
+	}
+	return prog, nil
+}
+
+// checkProgram type-checks a loaded program, printing diagnostics.
+func checkProgram(prog *module.Program) (*types.Info, error) {
+	info, errs := types.CheckModules(prog)
+	if len(errs) > 0 {
+		report(errs)
+		return info, errFailed
+	}
+	return info, nil
+}
+
+// diagJSON selects JSON diagnostics, one object per line, for editors.
+var diagJSON bool
+
+// report prints diagnostics to stderr with their source lines, or as JSON.
+func report(diags diag.List) {
+	var p diag.Printer
+	for _, d := range diags {
+		if diagJSON {
+			diag.PrintJSON(os.Stderr, d)
+		} else {
+			p.Print(os.Stderr, d)
+		}
+	}
+}
+
+// oneFile returns the single file argument or errUsage.
+func oneFile(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errUsage
+	}
+	return args[0], nil
+}
+
+// --- run ---
+
+var (
+	runNoCheck bool
+	runVM      bool
+)
+
+func setupRun(fs *flag.FlagSet) {
+	fs.BoolVar(&runNoCheck, "nocheck", false, "skip type checking")
+	fs.BoolVar(&runVM, "vm", false, "run on the bytecode VM instead of the interpreter (implied for .tbc files)")
+}
+
+func runRun(fs *flag.FlagSet, args []string) error {
+	if len(args) < 1 {
+		return errUsage
+	}
+	path := args[0]
+	if runVM || filepath.Ext(path) == ".tbc" {
+		code, err := loadBytecode(path)
+		if err != nil {
+			return err
+		}
+		return runBytecode(code, args)
+	}
+	prog, err := loadProgram(path)
+	if err != nil {
+		return err
+	}
+	if !runNoCheck {
+		if _, err := checkProgram(prog); err != nil {
+			return err
+		}
+	}
+	evaluator.Args = args
+	result := evaluator.RunModules(prog)
+	if e, ok := result.(*object.Error); ok {
+		fmt.Fprintln(os.Stderr, e.Message)
+		return errFailed
+	}
+	return nil
+}
+
+// runBytecode runs a compiled program on the VM.
+func runBytecode(code *vm.Program, args []string) error {
+	if err := vm.Run(code, args, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return errFailed
+	}
+	return nil
+}
+
+// compileBytecode loads, checks and compiles a source file for the VM.
+// The VM needs the types, so -nocheck does not apply.
+func compileBytecode(path string) (*vm.Program, error) {
+	prog, err := loadProgram(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := checkProgram(prog)
+	if err != nil {
+		return nil, err
+	}
+	code, errs := vm.Compile(prog, info)
+	if len(errs) > 0 {
+		report(errs)
+		return nil, errFailed
+	}
+	return code, nil
+}
+
+// loadBytecode reads a .tbc file, without lexing or parsing anything, or
+// compiles a source file.
+func loadBytecode(path string) (*vm.Program, error) {
+	if filepath.Ext(path) != ".tbc" {
+		return compileBytecode(path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	code, err := vm.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return code, nil
+}
+
+// --- check ---
+
+func runCheck(fs *flag.FlagSet, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	failed := false
+	for _, path := range args {
+		prog, err := loadProgram(path)
+		if err == nil {
+			var info *types.Info
+			info, err = checkProgram(prog)
+			report(info.Warnings)
+		}
+		if err == errFailed {
+			failed = true
+		} else if err != nil {
+			return err
+		}
+	}
+	if failed {
+		return errFailed
+	}
+	return nil
+}
+
+// --- emit-c / emit-ast / emit-bytecode ---
+
+var outputPath string
+
+func setupOutput(fs *flag.FlagSet) {
+	fs.StringVar(&outputPath, "o", "", "output file (default: stdout)")
+}
+
+// writeOutput writes data to -o, or stdout when -o is empty.
+func writeOutput(data []byte) error {
+	if outputPath == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(outputPath, data, 0644)
+}
+
+// emitC produces C for a source file. cFile is the path the C code will
+// be written to; it is named in #line directives.
+func emitC(path, cFile string) ([]byte, *aotminic.SourceMap, error) {
+	if code, ok := demoC(path); ok {
+		if emitInstrument != "" || emitPGO != nil {
+			return nil, nil, errors.New(msg.Sprintf(msg.PGODemo, path))
+		}
+		return []byte(code), nil, nil
+	}
+	prog, err := loadProgram(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := checkProgram(prog)
+	if err != nil {
+		return nil, nil, err
+	}
+	if emitPGO != nil {
+		sum, err := aotminic.Checksum(prog)
+		if err != nil {
+			return nil, nil, err
+		}
+		if sum != emitPGO.Checksum {
+			return nil, nil, errors.New(msg.Sprintf(msg.PGOStale, emitPGOFile, path))
+		}
+	}
+	out, errs := aotminic.Emit(prog, info, aotminic.Options{
+		Source:     path,
+		Output:     cFile,
+		NoLines:    !emitLines,
+		Profile:    emitProfile,
+		Instrument: emitInstrument,
+		PGO:        emitPGO,
+	})
+	if len(errs) > 0 {
+		report(errs)
+		return nil, nil, errFailed
+	}
+	return out.C, out.Map, nil
+}
+
+var (
+	emitLines     bool
+	emitSourceMap string
+	emitProfile   aotminic.Profile
+
+	emitInstrument string        // set by build -pgo=gen
+	emitPGO        *aotminic.PGO // set by build -pgo=use
+	emitPGOFile    string
+)
+
+func setupEmitC(fs *flag.FlagSet) {
+	setupOutput(fs)
+	setupLines(fs)
+}
+
+func setupLines(fs *flag.FlagSet) {
+	fs.BoolVar(&emitLines, "lines", true, "write #line directives pointing at the .tng source")
+	fs.StringVar(&emitSourceMap, "sourcemap", "", "also write a JSON source map (C line -> tenge position) to `file`")
+	setupProfile(fs)
+}
+
+func setupProfile(fs *flag.FlagSet) {
+	emitProfile = aotminic.Release
+	fs.Func("profile", "build `profile`: release (default), debug (checks, -O0 -g) or hardened (checks, sanitizers)", func(s string) error {
+		p, err := aotminic.ParseProfile(s)
+		emitProfile = p
+		return err
+	})
+}
+
+// writeSourceMap writes m to -sourcemap when it was requested.
+func writeSourceMap(m *aotminic.SourceMap) error {
+	if emitSourceMap == "" {
+		return nil
+	}
+	if m == nil {
+		return errors.New("no source map for template-generated C")
+	}
+	data, err := m.JSON()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(emitSourceMap, append(data, '\n'), 0644)
+}
+
+func runEmitC(fs *flag.FlagSet, args []string) error {
+	path, err := oneFile(args)
+	if err != nil {
+		return err
+	}
+	code, smap, err := emitC(path, outputPath)
+	if err != nil {
+		return err
+	}
+	if err := writeOutput(code); err != nil {
+		return err
+	}
+	if err := writeSourceMap(smap); err != nil {
+		return err
+	}
+	if outputPath != "" {
+		fmt.Printf("C emitted: %s\n", outputPath)
+	}
+	return nil
+}
+
+func runEmitAST(fs *flag.FlagSet, args []string) error {
+	path, err := oneFile(args)
+	if err != nil {
+		return err
+	}
+	program, errs := module.ParseFile(path)
+	if len(errs) > 0 {
+		report(errs)
+		return errFailed
+	}
+	var b strings.Builder
+	if err := ast.Fprint(&b, program); err != nil {
+		return err
+	}
+	return writeOutput([]byte(b.String()))
+}
+
+func runEmitBytecode(fs *flag.FlagSet, args []string) error {
+	path, err := oneFile(args)
+	if err != nil {
+		return err
+	}
+	code, err := compileBytecode(path)
+	if err != nil {
+		return err
+	}
+	return writeOutput(vm.Encode(code))
+}
+
+// --- emit-ir ---
+
+var irPasses []ir.Pass
+
+func setupEmitIR(fs *flag.FlagSet) {
+	setupOutput(fs)
+	irPasses, _ = ir.ParsePasses(ir.DefaultPasses)
+	fs.Func("passes", "comma-separated `list` of passes to run, or none (default "+ir.DefaultPasses+")", func(s string) error {
+		passes, err := ir.ParsePasses(s)
+		irPasses = passes
+		return err
+	})
+}
+
+// runEmitIR lists the IR of a program, then after each pass the
+// functions the pass changed.
+func runEmitIR(fs *flag.FlagSet, args []string) error {
+	path, err := oneFile(args)
+	if err != nil {
+		return err
+	}
+	prog, err := loadProgram(path)
+	if err != nil {
+		return err
+	}
+	info, err := checkProgram(prog)
+	if err != nil {
+		return err
+	}
+	p, errs := ir.Build(prog, info)
+	if len(errs) > 0 {
+		report(errs)
+		return errFailed
+	}
+	var b strings.Builder
+	b.WriteString("; initial\n\n")
+	ir.Fprint(&b, p)
+	for _, pass := range irPasses {
+		before := make([]string, len(p.Funcs))
+		for i, f := range p.Funcs {
+			before[i] = f.String()
+		}
+		p.Run(pass)
+		fmt.Fprintf(&b, "\n; after %s\n", pass.Name)
+		changed := false
+		for i, f := range p.Funcs {
+			if err := f.Verify(); err != nil {
+				return fmt.Errorf("after %s: %v", pass.Name, err)
+			}
+			if s := f.String(); s != before[i] {
+				b.WriteString("\n" + s)
+				changed = true
+			}
+		}
+		if !changed {
+			b.WriteString("; no change\n")
+		}
+	}
+	return writeOutput([]byte(b.String()))
+}
+
+// --- disasm ---
+
+func runDisasm(fs *flag.FlagSet, args []string) error {
+	path, err := oneFile(args)
+	if err != nil {
+		return err
+	}
+	code, err := loadBytecode(path)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	vm.Disassemble(&b, code)
+	return writeOutput([]byte(b.String()))
+}
+
+// --- build ---
+
+var (
+	buildOut     string
+	buildCC      string
+	buildRuntime string
+	buildKeepC   bool
+	buildDebug   bool
+	buildPGO     string
+	buildPGOFile string
+)
+
+func setupBuild(fs *flag.FlagSet) {
+	fs.StringVar(&buildOut, "o", "", "output binary (default: source name without .tng)")
+	fs.StringVar(&buildCC, "cc", envOr("CC", "cc"), "C compiler")
+	fs.StringVar(&buildRuntime, "runtime", envOr("TENGE_RUNTIME", "internal/aotminic/runtime"), "directory of the C runtime")
+	fs.BoolVar(&buildKeepC, "keep-c", false, "keep the generated <out>.c")
+	fs.BoolVar(&buildDebug, "g", false, "include debug information in a release build (implies -keep-c)")
+	buildPGO = ""
+	fs.Func("pgo", "profile-guided optimization: `mode` gen builds a binary that records a profile as it exits, use builds with the profile", func(s string) error {
+		if s != "gen" && s != "use" {
+			return errors.New(msg.Sprintf(msg.PGOMode, s))
+		}
+		buildPGO = s
+		return nil
+	})
+	fs.StringVar(&buildPGOFile, "pgo-file", "", "profile `file` for -pgo (default <out>.pgo; $TENGE_PGO overrides it when the gen binary runs)")
+	setupLines(fs)
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func runBuild(fs *flag.FlagSet, args []string) error {
+	path, err := oneFile(args)
+	if err != nil {
+		return err
+	}
+	out := buildOut
+	if out == "" {
+		out = strings.TrimSuffix(filepath.Base(path), ".tng")
+	}
+	cFile := out + ".c"
+	if err := setupPGO(out); err != nil {
+		return err
+	}
+	code, smap, err := emitC(path, cFile)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(cFile, code, 0644); err != nil {
+		return err
+	}
+	if err := writeSourceMap(smap); err != nil {
+		return err
+	}
+	// gdb reads the C file for the lines that have no tenge counterpart.
+	debugInfo := buildDebug || emitProfile != aotminic.Release
+	if !buildKeepC && !debugInfo {
+		defer os.Remove(cFile)
+	}
+
+	var flags []string
+	if buildDebug && emitProfile == aotminic.Release {
+		flags = append(flags, "-g")
+	}
+	return compileC(cFile, out, flags...)
+}
+
+// compileC compiles cFile, with the runtime when there is one, to the
+// binary out, with the flags of the profile followed by flags.
+func compileC(cFile, out string, flags ...string) error {
+	ccArgs := append(emitProfile.CFlags(), flags...)
+	ccArgs = append(ccArgs, cFile)
+	if rt := filepath.Join(buildRuntime, "runtime.c"); fileExists(rt) {
+		ccArgs = append(ccArgs, "-I"+buildRuntime, rt)
+	}
+	ccArgs = append(ccArgs, "-lm", "-pthread", "-o", out)
+	cc := exec.Command(buildCC, ccArgs...)
+	cc.Stdout, cc.Stderr = os.Stdout, os.Stderr
+	if err := cc.Run(); err != nil {
+		return fmt.Errorf("%s: %v", buildCC, err)
+	}
+	return nil
+}
+
+// setupPGO prepares the C generation of a build for -pgo. The profile
+// lives next to the binary out unless -pgo-file says otherwise.
+func setupPGO(out string) error {
+	file := buildPGOFile
+	if file == "" {
+		file = out + ".pgo"
+	}
+	emitInstrument, emitPGO, emitPGOFile = "", nil, ""
+	switch buildPGO {
+	case "gen":
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+		emitInstrument = abs
+		return nil
+	case "use":
+		p, err := aotminic.ReadPGO(file)
+		if err != nil {
+			return errors.New(msg.Sprintf(msg.PGORecord, err))
+		}
+		emitPGO, emitPGOFile = p, file
+	}
+	return nil
+}
+
+func fileExists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
+
+// --- fmt ---
+
+var (
+	fmtTo    lexer.Syntax
+	fmtWrite bool
+	fmtCheck bool
+	fmtDiff  bool
+)
+
+func setupFmt(fs *flag.FlagSet) {
+	fs.Func("to", "convert the keywords to `syntax` (kazakh or latin) instead of formatting", func(s string) error {
+		syntax, ok := lexer.ParseSyntax(s)
+		if !ok {
+			return fmt.Errorf("unknown syntax %q (want kazakh or latin)", s)
+		}
+		fmtTo = syntax
+		return nil
+	})
+	fs.BoolVar(&fmtWrite, "w", false, "write the result to the source file instead of stdout")
+	fs.BoolVar(&fmtCheck, "check", false, "list the files whose formatting differs and fail if there are any")
+	fs.BoolVar(&fmtDiff, "diff", false, "print the changes as a unified diff instead of the result")
+}
+
+func runFmt(fs *flag.FlagSet, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	failed := false
+	for _, path := range args {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var out string
+		if fmtTo == lexer.Mixed {
+			out, err = format.Source(string(src))
+		} else {
+			out, err = lexer.Convert(string(src), fmtTo)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", path, err)
+			failed = true
+			continue
+		}
+		changed := out != string(src)
+		if fmtCheck && changed {
+			fmt.Println(path)
+			failed = true
+		}
+		if fmtDiff {
+			_, err = os.Stdout.WriteString(format.Diff(path, string(src), out))
+		}
+		switch {
+		case err != nil:
+		case fmtWrite && changed:
+			err = os.WriteFile(path, []byte(out), 0644)
+		case !fmtWrite && !fmtCheck && !fmtDiff:
+			_, err = os.Stdout.WriteString(out)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if failed {
+		return errFailed
+	}
+	return nil
+}
+
+// --- test ---
+
+var testNative bool
+
+func setupTest(fs *flag.FlagSet) {
+	fs.BoolVar(&testNative, "native", false, "compile the tests to a native binary via C and run that")
+	fs.StringVar(&buildCC, "cc", envOr("CC", "cc"), "C compiler, with -native")
+	fs.StringVar(&buildRuntime, "runtime", envOr("TENGE_RUNTIME", "internal/aotminic/runtime"), "directory of the C runtime, with -native")
+	setupProfile(fs)
+}
+
+// runTest runs every `test_*` function without parameters in the
+// *_test.tng files found under the arguments (default: current directory).
+// A test fails when it ends in a runtime error, e.g. a failed assert.
+// With -native the tests run compiled, which also tests the parts of the
+// C prelude that replace tenge code, such as module simd/f64x4.
+func runTest(fs *flag.FlagSet, args []string) error {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	var files []string
+	for _, arg := range args {
+		st, err := os.Stat(arg)
+		if err != nil {
+			return err
+		}
+		if !st.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		err = filepath.WalkDir(arg, func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasSuffix(p, "_test.tng") {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	failed := false
+	test := testFile
+	if testNative {
+		test = testFileNative
+	}
+	for _, path := range files {
+		if !test(path) {
+			failed = true
+		}
+	}
+	if failed {
+		fmt.Println("FAIL")
+		return errFailed
+	}
+	fmt.Println("PASS")
+	return nil
+}
+
+func testFile(path string) bool {
+	prog, err := loadProgram(path)
+	if err != nil {
+		fmt.Printf("FAIL\t%s [build failed]\n", path)
+		return false
+	}
+	if _, err := checkProgram(prog); err != nil {
+		fmt.Printf("FAIL\t%s [build failed]\n", path)
+		return false
+	}
+	env, result := evaluator.EvalModules(prog)
+	if isErrorObj(result) {
+		fmt.Printf("FAIL\t%s [setup: %s]\n", path, result.Inspect())
+		return false
+	}
+
+	var names []string
+	for _, name := range env.Names() {
+		if fn, ok := env.Get(name); ok && strings.HasPrefix(name, "test_") {
+			if a, ok := fn.(*object.Atqarm); ok && len(a.Literal.Parameters) == 0 {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	ok := true
+	for _, name := range names {
+		fn, _ := env.Get(name)
+		call := &ast.CallExpression{Function: &ast.Identifier{Value: name}}
+		callEnv := object.NewEnclosedEnvironment(env)
+		callEnv.Set(name, fn)
+		if result := evaluator.Eval(call, callEnv); isErrorObj(result) {
+			fmt.Printf("--- FAIL: %s\n    %s\n", name, result.(*object.Error).Message)
+			ok = false
+		}
+	}
+	status := "ok"
+	if !ok {
+		status = "FAIL"
+	}
+	fmt.Printf("%s\t%s\t%d tests\n", status, path, len(names))
+	return ok
+}
+
+// testFileNative compiles the tests of path into a binary whose main runs
+// them one after the other; the first that fails stops it.
+func testFileNative(path string) bool {
+	prog, err := loadProgram(path)
+	if err != nil {
+		fmt.Printf("FAIL\t%s [build failed]\n", path)
+		return false
+	}
+	info, err := checkProgram(prog)
+	if err != nil {
+		fmt.Printf("FAIL\t%s [build failed]\n", path)
+		return false
+	}
+	dir, err := os.MkdirTemp("", "tenge-test")
+	if err != nil {
+		fmt.Printf("FAIL\t%s [%v]\n", path, err)
+		return false
+	}
+	defer os.RemoveAll(dir)
+	cFile, bin := filepath.Join(dir, "test.c"), filepath.Join(dir, "test")
+	out, errs := aotminic.Emit(prog, info, aotminic.Options{Source: path, Output: cFile, Profile: emitProfile, Tests: true})
+	if len(errs) > 0 {
+		report(errs)
+		fmt.Printf("FAIL\t%s [build failed]\n", path)
+		return false
+	}
+	if err := os.WriteFile(cFile, out.C, 0644); err != nil || compileC(cFile, bin) != nil {
+		fmt.Printf("FAIL\t%s [build failed]\n", path)
+		return false
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(bin)
+	cmd.Stdout, cmd.Stderr = os.Stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		fmt.Printf("--- FAIL\n    %s\n", msg)
+		fmt.Printf("FAIL\t%s\t%d tests\n", path, len(out.Tests))
+		return false
+	}
+	os.Stderr.Write(stderr.Bytes())
+	fmt.Printf("ok\t%s\t%d tests (native)\n", path, len(out.Tests))
+	return true
+}
+
+func isErrorObj(obj object.Object) bool {
+	return obj != nil && obj.Type() == object.ERROR_OBJ
+}
+
+// --- lsp ---
+
+func runLsp(fs *flag.FlagSet, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	return lsp.NewServer(os.Stdin, os.Stdout).Serve()
+}
+
+// --- version ---
+
+func runVersion(fs *flag.FlagSet, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	fmt.Printf("tenge %s %s/%s (%s)\n", version, runtime.GOOS, runtime.GOARCH, runtime.Version())
+	return nil
+}
@@ -0,0 +1,127 @@
+// FILE: cmd/tenge/main_test.go
+
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DauletBai/tenge/internal/lang/lexer"
+)
+
+// writeFile writes a source file into a fresh directory and returns
+// its path.
+func writeFile(t *testing.T, name, src string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExitCodes(t *testing.T) {
+	ok := writeFile(t, "ok.tng", "jasa x = 1\nx = x + 1\n")
+	syntax := writeFile(t, "syntax.tng", "jasa = 1\n")
+	typeErr := writeFile(t, "type.tng", "jasa x : san = \"a\"\n")
+	runtime := writeFile(t, "runtime.tng", "jasa x = 0\nx = 1 / x\n")
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{nil, exitUsage},
+		{[]string{"bogus"}, exitUsage},
+		{[]string{"help"}, exitOK},
+		{[]string{"version"}, exitOK},
+		{[]string{"version", "extra"}, exitUsage},
+		{[]string{"run"}, exitUsage},
+		{[]string{"run", "-bogus", ok}, exitUsage},
+		{[]string{"check", "-h"}, exitOK},
+		{[]string{"check", ok}, exitOK},
+		{[]string{"check", ok, syntax}, exitError},
+		{[]string{"check", typeErr}, exitError},
+		{[]string{"check", filepath.Join(t.TempDir(), "missing.tng")}, exitError},
+		{[]string{"run", ok}, exitOK},
+		{[]string{"run", syntax}, exitError},
+		{[]string{"run", runtime}, exitError},
+		{[]string{"emit-ast", "-o", filepath.Join(t.TempDir(), "ast.txt"), ok}, exitOK},
+		{[]string{"emit-ast", ok, ok}, exitUsage},
+		{[]string{"emit-ir", "-passes=fold,dce", "-o", filepath.Join(t.TempDir(), "ir.txt"), ok}, exitOK},
+		{[]string{"emit-ir", "-passes=bogus", ok}, exitUsage},
+	}
+	for _, tt := range tests {
+		if got := run(tt.args); got != tt.want {
+			t.Errorf("tenge %s: exit %d, want %d", strings.Join(tt.args, " "), got, tt.want)
+		}
+	}
+}
+
+// TestEmitAST checks that -o receives the dump and stdout does not.
+func TestEmitAST(t *testing.T) {
+	src := writeFile(t, "f.tng", "jasa total = 1 + 2\n")
+	out := filepath.Join(t.TempDir(), "ast.txt")
+	if code := run([]string{"emit-ast", "-o", out, src}); code != exitOK {
+		t.Fatalf("exit %d", code)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "total") {
+		t.Errorf("dump does not mention the declared name:\n%s", data)
+	}
+}
+
+// convertTree writes every .tng file under dir, converted to syntax to,
+// into a fresh directory with the same layout and returns that directory.
+func convertTree(t *testing.T, dir string, to lexer.Syntax) string {
+	t.Helper()
+	out := t.TempDir()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".tng" {
+			return err
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		conv, err := lexer.Convert(string(src), to)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		dst := filepath.Join(out, rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(dst, []byte(conv), 0644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// TestConvertBenchmarks converts the benchmark sources, modules
+// included, from Latin to Kazakh and back, and checks every result.
+func TestConvertBenchmarks(t *testing.T) {
+	dir := filepath.Join("..", "..", "benchmarks", "src", "tenge")
+	kazakh := convertTree(t, dir, lexer.Kazakh)
+	latin := convertTree(t, kazakh, lexer.Latin)
+	for _, tree := range []string{kazakh, latin} {
+		mains, err := filepath.Glob(filepath.Join(tree, "*.tng"))
+		if err != nil || len(mains) == 0 {
+			t.Fatalf("no sources in %s: %v", tree, err)
+		}
+		for _, path := range mains {
+			if code := run([]string{"check", path}); code != exitOK {
+				src, _ := os.ReadFile(path)
+				t.Errorf("tenge check %s: exit %d\n%s", path, code, src)
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// FILE: cmd/tenge/parallel_test.go
+
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestParallelDeterminism runs reductions whose results depend on the
+// order of their values on 1, 3 and 8 threads: a floating-point sum of
+// terms of very different sizes, min and max with ties between -0 and 0,
+// and collect. Every thread count and every backend with parallel loops
+// must print the same.
+func TestParallelDeterminism(t *testing.T) {
+	path := writeFile(t, "parallel.tng", `#syntax latin
+
+fn main() {
+    let n: i64 = 10007;
+    var s: f64 = parallel for i in 0..n sum {
+        var x: f64 = 1.0 / f64(i + 1);
+        if i % 3 == 0 { x = x * 1e15; }
+        if i % 2 == 1 { x = -x; }
+        x
+    };
+    print(s);
+    var lo: i64 = parallel for i in 0..n min { (i * 7919) % 1009 };
+    var hi: i64 = parallel for i in 0..n max { (i * 7919) % 1009 };
+    print(lo);
+    print(hi);
+    var zero: f64 = 0.0;
+    var first: f64 = parallel for i in 0..n min {
+        var v: f64 = zero;
+        if i % 2 == 0 { v = -zero; }
+        v
+    };
+    print(1.0 / first);
+    var last: f64 = parallel for i in 0..n max {
+        var v: f64 = -zero;
+        if i % 2 == 1 { v = zero; }
+        v
+    };
+    print(1.0 / last);
+    var sq: []i64 = parallel for i in 0..n collect { i * i % 97 };
+    var h: i64 = 0;
+    var k: i64 = 0;
+    while k < len(sq) {
+        h = (h * 31 + sq[k]) % 1000000007;
+        k = k + 1;
+    }
+    print(h);
+    var out: []f64 = make_f64(n);
+    parallel for i in 0..n { out[i] = f64(i) * 0.5; }
+    var t: f64 = 0.0;
+    k = 0;
+    while k < n {
+        t = t + out[k];
+        k = k + 1;
+    }
+    print(t);
+}
+`)
+	want := ""
+	for _, b := range []backend{interpreter, debugC, releaseC} {
+		for _, n := range []int{1, 3, 8} {
+			t.Run(fmt.Sprintf("%s/%d threads", b.name, n), func(t *testing.T) {
+				t.Setenv("TENGE_THREADS", fmt.Sprint(n))
+				got, err := b.run(t, path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want == "" {
+					want = got
+				}
+				if got != want {
+					t.Errorf("output:\n%s\nwant:\n%s", got, want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// FILE: cmd/tenge/pgo_test.go
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DauletBai/tenge/internal/aotminic"
+)
+
+const pgoSrc = `#syntax latin
+
+fn rare(x: i64) -> i64 {
+    return x * 3;
+}
+
+fn step(x: i64) -> i64 {
+    if x % 100 == 0 {
+        return rare(x);
+    }
+    return x + 1;
+}
+
+fn main() {
+    var s: i64 = 0;
+    var i: i64 = 1;
+    while i < 1000 {
+        s = s + step(i);
+        i = i + 1;
+    }
+    print(s);
+}
+`
+
+// TestPGO records a profile with a -pgo=gen build, checks its counts and
+// builds again with -pgo=use, which must print the same and pass the
+// profile on to the C compiler.
+func TestPGO(t *testing.T) {
+	if _, err := exec.LookPath(envOr("CC", "cc")); err != nil {
+		t.Skipf("no C compiler: %v", err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pgo.tng")
+	if err := os.WriteFile(path, []byte(pgoSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rt := filepath.Join("..", "..", "internal", "aotminic", "runtime")
+	build := func(out string, flags ...string) int {
+		args := append([]string{"build", "-runtime", rt, "-o", out}, flags...)
+		return run(append(args, path))
+	}
+	exe := func(bin string) string {
+		out, err := exec.Command(bin).Output()
+		if err != nil {
+			t.Fatalf("%s: %v", bin, err)
+		}
+		return string(out)
+	}
+
+	gen := filepath.Join(dir, "gen")
+	if code := build(gen, "-pgo=gen"); code != exitOK {
+		t.Fatalf("build -pgo=gen: exit %d", code)
+	}
+	want := exe(gen)
+	prof, err := aotminic.ReadPGO(gen + ".pgo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := map[string]uint64{}
+	for _, f := range prof.Functions {
+		calls[f.Name] = f.Calls
+	}
+	if calls["step"] != 999 || calls["rare"] != 9 {
+		t.Errorf("calls %v, want step 999 and rare 9", calls)
+	}
+	var branch *aotminic.PGOBranch
+	for i, b := range prof.Branches {
+		if b.Line == 8 {
+			branch = &prof.Branches[i]
+		}
+	}
+	if branch == nil || branch.Taken != 9 || branch.NotTaken != 990 {
+		t.Errorf("branches %+v, want the if of line 8 taken 9 times out of 999", prof.Branches)
+	}
+
+	use := filepath.Join(dir, "use")
+	if code := build(use, "-pgo=use", "-pgo-file", gen+".pgo", "-keep-c"); code != exitOK {
+		t.Fatalf("build -pgo=use: exit %d", code)
+	}
+	if got := exe(use); got != want {
+		t.Errorf("-pgo=use printed %q, -pgo=gen %q", got, want)
+	}
+	c, err := os.ReadFile(use + ".c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hint := range []string{
+		"TNG_HOT int64_t step(", // the hot function
+		"if (TNG_EXPECT(((x % INT64_C(100)) == INT64_C(0)), 0.0090))", // the rare branch
+		"while (TNG_EXPECT((i < INT64_C(1000)), 0.9990))",             // the loop
+	} {
+		if !strings.Contains(string(c), hint) {
+			t.Errorf("the C of -pgo=use has no %s", hint)
+		}
+	}
+
+	// A profile applies only to the sources it was recorded from.
+	if err := os.WriteFile(path, []byte(strings.Replace(pgoSrc, "x * 3", "x * 4", 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code := build(use, "-pgo=use", "-pgo-file", gen+".pgo"); code != exitError {
+		t.Errorf("build -pgo=use of other sources: exit %d, want %d", code, exitError)
+	}
+	if code := build(use, "-pgo=use", "-pgo-file", filepath.Join(dir, "none.pgo")); code != exitError {
+		t.Errorf("build -pgo=use without a profile: exit %d, want %d", code, exitError)
+	}
+	if code := build(use, "-pgo=bogus"); code != exitUsage {
+		t.Errorf("build -pgo=bogus: exit %d, want %d", code, exitUsage)
+	}
+}
+
+func TestReadPGO(t *testing.T) {
+	tests := []struct {
+		name, data, err string
+	}{
+		{"not JSON", "{", "not a profile"},
+		{"other version", `{"version":2}`, "unsupported profile version 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, "p.pgo", tt.data)
+			_, err := aotminic.ReadPGO(path)
+			if err == nil || !strings.Contains(err.Error(), tt.err) || !strings.HasPrefix(err.Error(), path+": ") {
+				t.Errorf("error %v, want %s: %s", err, path, tt.err)
+			}
+		})
+	}
+}
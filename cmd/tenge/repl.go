@@ -0,0 +1,420 @@
+// FILE: cmd/tenge/repl.go
+// Purpose: An interactive session. Declarations persist between inputs;
+// the value of an expression statement is printed with its type. Input
+// with unclosed brackets continues on the next line, and every line read
+// is appended to a history file.
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/diag"
+	"github.com/DauletBai/tenge/internal/lang/evaluator"
+	"github.com/DauletBai/tenge/internal/lang/lexer"
+	"github.com/DauletBai/tenge/internal/lang/object"
+	"github.com/DauletBai/tenge/internal/lang/parser"
+	"github.com/DauletBai/tenge/internal/lang/token"
+	"github.com/DauletBai/tenge/internal/lang/types"
+)
+
+const (
+	prompt     = ">> "
+	contPrompt = ".. " // more lines of an unfinished input
+
+	historyMax = 1000 // lines kept in the history file
+)
+
+var replHistory string
+
+func setupRepl(fs *flag.FlagSet) {
+	fs.StringVar(&replHistory, "history", defaultHistory(), "`file` to keep input history in, empty for none")
+}
+
+// defaultHistory returns $TENGE_HISTORY, else ~/.tenge_history.
+func defaultHistory() string {
+	if path := os.Getenv("TENGE_HISTORY"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".tenge_history")
+}
+
+func runRepl(fs *flag.FlagSet, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	r := newSession(os.Stdout)
+	r.history = openHistory(replHistory)
+	defer r.history.close()
+	r.run(os.Stdin)
+	return nil
+}
+
+const replHelp = `Enter declarations, statements or expressions. Input with unclosed
+brackets continues on the next line.
+
+  :type <expr>      print the type of expr without evaluating it
+  :ast <code>       print the syntax tree of code
+  :tokens <code>    print the tokens of code
+  :load <file.tng>  run the declarations of a file in this session
+  :reset            forget all declarations
+  :history          print the input history
+  :help             print this help
+  :quit             end the session (or end of input)
+`
+
+// session is the state kept between inputs.
+type session struct {
+	out     *lineWriter
+	checker *types.Checker
+	env     *object.Environment
+	history *history
+}
+
+func newSession(out io.Writer) *session {
+	r := &session{out: &lineWriter{w: out, bol: true}}
+	r.reset()
+	evaluator.Stdout = r.out
+	return r
+}
+
+func (r *session) reset() {
+	r.checker = types.NewChecker()
+	r.env = object.NewEnvironment()
+}
+
+func (r *session) run(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	for {
+		input, ok := r.read(scanner)
+		if !ok {
+			fmt.Fprintln(r.out)
+			return
+		}
+		if strings.TrimSpace(input) == "" {
+			continue
+		}
+		if strings.HasPrefix(input, ":") {
+			if !r.command(input) {
+				return
+			}
+			continue
+		}
+		r.eval(input)
+	}
+}
+
+// read reads one input, going on while it has unclosed brackets.
+func (r *session) read(scanner *bufio.Scanner) (string, bool) {
+	r.out.endLine() // kórset does not end its output with a newline
+	fmt.Fprint(r.out, prompt)
+	var lines []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		r.history.add(line)
+		lines = append(lines, line)
+		input := strings.Join(lines, "\n")
+		if strings.HasPrefix(input, ":") || openBrackets(input) <= 0 {
+			return input, true
+		}
+		fmt.Fprint(r.out, contPrompt)
+	}
+	return "", false
+}
+
+// openBrackets returns the number of brackets opened and not yet closed in
+// src.
+func openBrackets(src string) int {
+	n := 0
+	l := lexer.New(src)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LBRACE, token.LPAREN, token.LBRACKET:
+			n++
+		case token.RBRACE, token.RPAREN, token.RBRACKET:
+			n--
+		}
+	}
+	return n
+}
+
+// command runs a :command. It returns false to end the session.
+func (r *session) command(input string) bool {
+	name, arg, _ := strings.Cut(strings.TrimSpace(input), " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case ":type":
+		r.typeOf(arg)
+	case ":ast":
+		if program, ok := r.parse(arg, ""); ok {
+			ast.Fprint(r.out, program)
+		}
+	case ":tokens":
+		l := lexer.New(arg)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			fmt.Fprintf(r.out, "%s %q @%s\n", tok.Type, tok.Literal, tok.Pos())
+		}
+	case ":load":
+		r.load(arg)
+	case ":reset":
+		r.reset()
+	case ":history":
+		for i, line := range r.history.lines() {
+			fmt.Fprintf(r.out, "%5d  %s\n", i+1, line)
+		}
+	case ":help":
+		fmt.Fprint(r.out, replHelp)
+	case ":quit":
+		return false
+	default:
+		fmt.Fprintf(r.out, "unknown command %s; try :help\n", name)
+	}
+	return true
+}
+
+// parse parses src, printing its errors. file names src in diagnostics.
+func (r *session) parse(src, file string) (*ast.Program, bool) {
+	p := parser.New(lexer.NewFile(file, src))
+	program := p.ParseProgram()
+	return program, r.report(src, p.Diagnostics())
+}
+
+// check type-checks program in the session, printing its errors.
+func (r *session) check(src string, program *ast.Program) bool {
+	r.checker.ResetErrors()
+	r.checker.CheckProgram(program)
+	return r.report(src, r.checker.Diagnostics())
+}
+
+// report prints errs with snippets from src, or from the file they name.
+// It returns true if there are none.
+func (r *session) report(src string, errs diag.List) bool {
+	printer := diag.Printer{Source: func(file string) ([]byte, error) {
+		if file != "" {
+			return os.ReadFile(file)
+		}
+		return []byte(src), nil
+	}}
+	for _, d := range errs {
+		printer.Print(r.out, d)
+	}
+	return len(errs) == 0
+}
+
+func (r *session) eval(src string) {
+	program, ok := r.parse(src, "")
+	if !ok {
+		return
+	}
+	m := r.mark()
+	if !r.check(src, program) {
+		r.undo(m)
+		return
+	}
+	result := evaluator.Eval(program, r.env)
+	if isError(result) {
+		r.undo(m)
+	}
+	if result == nil || result == object.NULL {
+		return
+	}
+	if t := r.resultType(program); t != nil && !isError(result) {
+		fmt.Fprintf(r.out, "%s : %s\n", result.Inspect(), t)
+		return
+	}
+	fmt.Fprintln(r.out, result.Inspect())
+}
+
+// resultType returns the type of the last statement of program if it is
+// an expression.
+func (r *session) resultType(program *ast.Program) types.Type {
+	if len(program.Statements) == 0 {
+		return nil
+	}
+	es, ok := program.Statements[len(program.Statements)-1].(*ast.ExpressionStatement)
+	if !ok {
+		return nil
+	}
+	t, ok := r.checker.Info().Types[es.Expression]
+	if !ok || types.IsVoid(t) {
+		return nil
+	}
+	return types.Default(t)
+}
+
+func (r *session) typeOf(src string) {
+	program, ok := r.parse(src, "")
+	if !ok {
+		return
+	}
+	if len(program.Statements) != 1 {
+		fmt.Fprintln(r.out, ":type needs one expression")
+		return
+	}
+	if _, ok := program.Statements[0].(*ast.ExpressionStatement); !ok {
+		fmt.Fprintln(r.out, ":type needs an expression")
+		return
+	}
+	if r.check(src, program) {
+		if t := r.resultType(program); t != nil {
+			fmt.Fprintln(r.out, t)
+		}
+	}
+}
+
+// load runs the declarations of a file in the session. Its main is not
+// called.
+func (r *session) load(path string) {
+	if path == "" {
+		fmt.Fprintln(r.out, "usage: :load <file.tng>")
+		return
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(r.out, err)
+		return
+	}
+	program, ok := r.parse(string(src), path)
+	if !ok {
+		return
+	}
+	m := r.mark()
+	if !r.check(string(src), program) {
+		r.undo(m)
+		return
+	}
+	if result := evaluator.Eval(program, r.env); isError(result) {
+		r.undo(m)
+		fmt.Fprintln(r.out, result.Inspect())
+	}
+}
+
+// mark records the top-level names of the session, so that undo can
+// forget the declarations of an input that failed.
+type mark struct {
+	syms map[string]*types.Symbol
+	vals map[string]object.Object
+}
+
+func (r *session) mark() mark {
+	m := mark{syms: make(map[string]*types.Symbol), vals: make(map[string]object.Object)}
+	scope := r.checker.Scope()
+	for _, name := range scope.Names() {
+		m.syms[name] = scope.LookupLocal(name)
+		m.vals[name], _ = r.env.Get(name)
+	}
+	return m
+}
+
+// undo forgets the declarations checked since m that did not run: all of
+// them after a type error, and those after the failing statement after a
+// run-time error. Otherwise the checker would accept names that have no
+// value.
+func (r *session) undo(m mark) {
+	scope := r.checker.Scope()
+	for _, name := range scope.Names() {
+		if scope.LookupLocal(name) == m.syms[name] {
+			continue
+		}
+		if val, _ := r.env.Get(name); val != m.vals[name] {
+			continue // it ran
+		}
+		if sym := m.syms[name]; sym != nil {
+			scope.Insert(sym)
+		} else {
+			scope.Remove(name)
+		}
+	}
+}
+
+// lineWriter remembers whether its output ends a line.
+type lineWriter struct {
+	w   io.Writer
+	bol bool // at the beginning of a line
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		lw.bol = p[len(p)-1] == '\n'
+	}
+	return lw.w.Write(p)
+}
+
+// endLine writes a newline unless the output already ends a line.
+func (lw *lineWriter) endLine() {
+	if !lw.bol {
+		lw.Write([]byte("\n"))
+	}
+}
+
+func isError(obj object.Object) bool {
+	return obj != nil && obj.Type() == object.ERROR_OBJ
+}
+
+// history appends the lines read to a file. The zero value and nil keep
+// no history.
+type history struct {
+	f    *os.File
+	past []string
+}
+
+// openHistory reads the history in path and opens it for appending. The
+// file is trimmed to its last historyMax lines. Without a path, or if the
+// file cannot be written, the session keeps no history.
+func openHistory(path string) *history {
+	if path == "" {
+		return nil
+	}
+	h := &history{}
+	if data, err := os.ReadFile(path); err == nil {
+		h.past = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		if len(h.past) == 1 && h.past[0] == "" {
+			h.past = nil
+		}
+		if len(h.past) > historyMax {
+			h.past = h.past[len(h.past)-historyMax:]
+			os.WriteFile(path, []byte(strings.Join(h.past, "\n")+"\n"), 0o600)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tenge: no history: %v\n", err)
+		return h
+	}
+	h.f = f
+	return h
+}
+
+func (h *history) add(line string) {
+	if h == nil || strings.TrimSpace(line) == "" {
+		return
+	}
+	h.past = append(h.past, line)
+	if h.f != nil {
+		fmt.Fprintln(h.f, line)
+	}
+}
+
+func (h *history) lines() []string {
+	if h == nil {
+		return nil
+	}
+	return h.past
+}
+
+func (h *history) close() {
+	if h != nil && h.f != nil {
+		h.f.Close()
+	}
+}
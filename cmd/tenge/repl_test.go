@@ -0,0 +1,129 @@
+// FILE: cmd/tenge/repl_test.go
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DauletBai/tenge/internal/lang/evaluator"
+)
+
+// transcript runs a session on input and returns its transcript.
+func transcript(t *testing.T, h *history, input string) string {
+	t.Helper()
+	stdout := evaluator.Stdout
+	defer func() { evaluator.Stdout = stdout }()
+	var out strings.Builder
+	r := newSession(&out)
+	r.history = h
+	r.run(strings.NewReader(input))
+	return out.String()
+}
+
+func TestRepl(t *testing.T) {
+	tests := []struct{ name, input, want string }{
+		{"declarations persist", "jasa x = 40\nx + 2\nkórset(x)\n",
+			">> \n>> 42 : san\n>> 40\n>> \n"},
+		{"multi-line input", "atqar'm f(n: san) -> san {\n  qaıtar n * 2\n}\nf(21)\n",
+			">> .. .. \n>> 42 : san\n>> \n"},
+		{"typed results", "[1, 2.5]\n\"a\" + \"b\"\n1 < 2\n",
+			">> [1, 2.5] : []aqsha\n>> ab : jol\n>> jan : aqıqat\n>> \n"},
+		{":type", ":type 1 > 2\n:type jasa y = 1\n:type 1; 2\n",
+			">> aqıqat\n>> :type needs an expression\n>> :type needs one expression\n>> \n"},
+		{"errors", "jasa s : jol = 1\n1 / 0\ns\n",
+			">> 1:16: error[E0311]: cannot use 1 (untyped san) as jol in declaration of s\n" +
+				"  |\n1 | jasa s : jol = 1\n  |                ^\n" +
+				">> ERROR: 1:3: division by zero\n" +
+				">> 1:1: error[E0312]: undefined: s\n  |\n1 | s\n  | ^\n>> \n"},
+		{"failed declarations", "jasa a = 1; jasa b = 1 / a - 1 / 0; jasa c = 2\nkórset(a)\nb\njasa a : jol = 3\na\n",
+			">> ERROR: 1:32: division by zero\n" +
+				">> 1\n" +
+				">> 1:1: error[E0312]: undefined: b\n  |\n1 | b\n  | ^\n" +
+				">> 1:16: error[E0311]: cannot use 3 (untyped san) as jol in declaration of a\n  |\n1 | jasa a : jol = 3\n  |                ^\n" +
+				"1:6: error[E0301]: a redeclared in this block\n  |\n1 | jasa a : jol = 3\n  |      ^ previous declaration of a\n" +
+				">> 1 : san\n>> \n"},
+		{":reset", "jasa x = 1\n:reset\nx\n",
+			">> \n>> \n>> 1:1: error[E0312]: undefined: x\n  |\n1 | x\n  | ^\n>> \n"},
+		{":tokens", ":tokens jasa a\n",
+			">> jasa \"jasa\" @1:1\nIDENT \"a\" @1:6\n>> \n"},
+		{":quit", ":bogus\n:quit\nkórset(9)\n",
+			">> unknown command :bogus; try :help\n>> "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := transcript(t, nil, tt.input); got != tt.want {
+				t.Errorf("transcript\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplLoad(t *testing.T) {
+	path := writeFile(t, "lib.tng", "atqar'm sq(x: san) -> san { qaıtar x * x }\natqar'm main() { kórset(\"main\") }\n")
+	bad := writeFile(t, "bad.tng", "jasa x : jol = 1\n")
+	input := fmt.Sprintf(":load %s\nsq(7)\n:load %s\n:load\n", path, bad)
+	want := fmt.Sprintf(">> \n>> 49 : san\n>> %s:1:16: error[E0311]: cannot use 1 (untyped san) as jol in declaration of x\n"+
+		"  |\n1 | jasa x : jol = 1\n  |                ^\n>> usage: :load <file.tng>\n>> \n", bad)
+	if got := transcript(t, nil, input); got != want {
+		t.Errorf("transcript\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestHistory checks that the history outlives the session and keeps only
+// its last historyMax lines.
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	h := openHistory(path)
+	transcript(t, h, "jasa x = 1\n\nkórset(x)\n")
+	h.close()
+
+	h = openHistory(path)
+	got := transcript(t, h, ":history\n")
+	h.close()
+	if want := ">>     1  jasa x = 1\n    2  kórset(x)\n    3  :history\n>> \n"; got != want {
+		t.Errorf("transcript\n%s\nwant\n%s", got, want)
+	}
+
+	var long []string
+	for i := range historyMax + 5 {
+		long = append(long, fmt.Sprint("line ", i))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(long, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	h = openHistory(path)
+	h.close()
+	if lines := h.lines(); len(lines) != historyMax || lines[0] != "line 5" {
+		t.Errorf("history of %d lines starting %q, want %d starting line 5", len(lines), lines[0], historyMax)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n != historyMax {
+		t.Errorf("history file of %d lines, want %d", n, historyMax)
+	}
+}
+
+func TestOpenBrackets(t *testing.T) {
+	tests := []struct {
+		src  string
+		want int
+	}{
+		{"f(1)", 0},
+		{"atqar'm f() {", 1},
+		{"jasa xs = [[1, 2], [", 2},
+		{"kórset(\"(\")", 0}, // in a string
+		{"jasa x = 1 // {", 0},
+		{"}", -1},
+	}
+	for _, tt := range tests {
+		if got := openBrackets(tt.src); got != tt.want {
+			t.Errorf("openBrackets(%q) = %d, want %d", tt.src, got, tt.want)
+		}
+	}
+}
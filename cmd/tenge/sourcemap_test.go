@@ -0,0 +1,143 @@
+// FILE: cmd/tenge/sourcemap_test.go
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/DauletBai/tenge/internal/aotminic"
+)
+
+const sourceMapSrc = `#syntax latin
+
+import "util/twice"
+
+fn main() {
+    let n = twice.of(21);
+    print("answer ");
+    print(n);
+}
+`
+
+const twiceSrc = `module twice
+
+pub fn of(x: i64) -> i64 {
+    return x * 2;
+}
+`
+
+// emitWithMap emits C for dir/main.tng and returns its lines and source map.
+func emitWithMap(t *testing.T, dir string, flags ...string) ([]string, *aotminic.SourceMap) {
+	t.Helper()
+	cFile, mapFile := filepath.Join(dir, "out.c"), filepath.Join(dir, "out.json")
+	args := append([]string{"emit-c", "-o", cFile, "-sourcemap", mapFile}, flags...)
+	if code := run(append(args, filepath.Join(dir, "main.tng"))); code != exitOK {
+		t.Fatalf("emit-c %s: exit %d", strings.Join(flags, " "), code)
+	}
+	c, err := os.ReadFile(cFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(mapFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m aotminic.SourceMap
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(string(c), "\n"), &m
+}
+
+// cPositions returns the file and line the C compiler gives each line of c
+// after its #line directives, indexed by C line.
+func cPositions(t *testing.T, c []string, cFile string) []string {
+	t.Helper()
+	pos := make([]string, len(c)+1)
+	file, line := cFile, 1
+	for i, text := range c {
+		if rest, ok := strings.CutPrefix(text, "#line "); ok {
+			n, name, _ := strings.Cut(rest, " ")
+			var err error
+			if line, err = strconv.Atoi(n); err != nil {
+				t.Fatalf("C line %d: %s", i+1, text)
+			}
+			if file, err = strconv.Unquote(name); err != nil {
+				t.Fatalf("C line %d: %s", i+1, text)
+			}
+			continue
+		}
+		pos[i+1] = fmt.Sprintf("%s:%d", file, line)
+		line++
+	}
+	return pos
+}
+
+// TestSourceMap checks that the #line directives and the source map agree
+// on where every statement, the imported ones included, came from.
+func TestSourceMap(t *testing.T) {
+	dir := t.TempDir()
+	main, twice := filepath.Join(dir, "main.tng"), filepath.Join(dir, "util", "twice.tng")
+	if err := os.MkdirAll(filepath.Dir(twice), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for path, src := range map[string]string{main: sourceMapSrc, twice: twiceSrc} {
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cFile := filepath.Join(dir, "out.c")
+	c, m := emitWithMap(t, dir)
+	if m.Version != 1 || m.File != cFile || m.Source != main {
+		t.Errorf("map of %s from %s (version %d), want %s from %s", m.File, m.Source, m.Version, cFile, main)
+	}
+
+	pos := cPositions(t, c, cFile)
+	sources := map[string]bool{}
+	for _, e := range m.Mappings {
+		want := fmt.Sprintf("%s:%d", cFile, e.CLine)
+		if e.Line != 0 {
+			source := main
+			if e.Source != "" {
+				source = e.Source
+			}
+			sources[source] = true
+			want = fmt.Sprintf("%s:%d", source, e.Line)
+		}
+		if e.CLine < 1 || e.CLine >= len(pos) {
+			t.Errorf("C line %d of %s out of range", e.CLine, want)
+		} else if pos[e.CLine] != want {
+			t.Errorf("C line %d is at %s for the compiler, %s for the map", e.CLine, pos[e.CLine], want)
+		}
+	}
+	if !sources[main] || !sources[twice] {
+		t.Errorf("map names %v, want %s and %s", sources, main, twice)
+	}
+
+	// The map works without the directives, with its own C line numbers.
+	c, m = emitWithMap(t, dir, "-lines=false")
+	found := false
+	for i, text := range c {
+		if strings.HasPrefix(text, "#line") {
+			t.Errorf("-lines=false: C line %d: %s", i+1, text)
+		}
+		if strings.Contains(text, `"answer "`) {
+			found = true
+			if line, col, ok := m.Lookup(i + 1); !ok || line != 7 || col != 5 {
+				t.Errorf("-lines=false: print of answer maps to %d:%d (%v), want 7:5", line, col, ok)
+			}
+		}
+	}
+	if !found {
+		t.Error("-lines=false: no C line prints answer")
+	}
+	if line, _, ok := m.Lookup(1); ok {
+		t.Errorf("C line 1 maps to tenge line %d", line)
+	}
+}
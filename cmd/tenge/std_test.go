@@ -0,0 +1,99 @@
+// FILE: cmd/tenge/std_test.go
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// testFunc matches the declaration of a test in a *_test.tng file.
+var testFunc = regexp.MustCompile(`(?m)^fn (test_\w+)\(\)`)
+
+// TestStd runs the tests of the standard modules on every backend: each
+// test file, followed by calls of its tests, is a program that prints ok
+// unless an assert fails. The modules promise the same numbers on every
+// backend, so the tests of random also check that the generators agree.
+func TestStd(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "..", "internal", "lang", "module", "std", "*", "*_test.tng"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no tests of the standard modules: %v", err)
+	}
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b strings.Builder
+		b.Write(src)
+		b.WriteString("\n")
+		for _, m := range testFunc.FindAllStringSubmatch(string(src), -1) {
+			b.WriteString(m[1] + "();\n")
+		}
+		b.WriteString("print(\"ok\");\n")
+		path := writeFile(t, filepath.Base(file), b.String())
+		for _, be := range allBackends {
+			t.Run(filepath.Base(file)+"/"+be.name, func(t *testing.T) {
+				if be.name == onVM.name && strings.Contains(string(src), "parallel for") {
+					t.Skip("the VM runs no parallel loops")
+				}
+				got, err := be.run(t, path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != "ok" {
+					t.Errorf("output %q, want ok", got)
+				}
+			})
+		}
+	}
+}
+
+// TestRandomSequences checks that every backend draws the same numbers
+// from the generators of random, bit for bit.
+func TestRandomSequences(t *testing.T) {
+	path := writeFile(t, "seq.tng", `#syntax latin
+import "random/xoshiro"
+import "random/philox"
+import "random/threefry"
+
+fn main() {
+    var x: []u64 = xoshiro.stream(99, 2);
+    var p: []u64 = philox.stream(99, 2);
+    var f: []u64 = threefry.stream(99, 2);
+    var i: i64 = 0;
+    while i < 5 {
+        print(xoshiro.next(x));
+        print(philox.next(p));
+        print(threefry.next(f));
+        i = i + 1;
+    }
+    xoshiro.jump(x);
+    print(xoshiro.next(x));
+    print(xoshiro.next_f64(x) * 1000000.0);
+    print(philox.next_f64(p));
+    print(threefry.next_f64(f));
+}
+`)
+	want, err := interpreter.run(t, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(want, "\n") != 19 {
+		t.Fatalf("interpreter output:\n%s", want)
+	}
+	for _, be := range allBackends[1:] {
+		t.Run(be.name, func(t *testing.T) {
+			got, err := be.run(t, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("output:\n%s\ninterpreter:\n%s", got, want)
+			}
+		})
+	}
+}
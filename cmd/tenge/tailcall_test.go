@@ -0,0 +1,96 @@
+// FILE: cmd/tenge/tailcall_test.go
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestTailCall recurses ten million times in tail position, far more than
+// any stack holds as nested calls, on every backend. The debug profile
+// compiles with -O0, so the C compiler does not turn the calls into jumps
+// itself: the loop has to be in the C. The interpreter, which takes
+// seconds for ten million calls, runs the other forms a million deep,
+// still far beyond its MaxDepth.
+func TestTailCall(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string // N stands for the depth
+		want string // %d stands for twice the depth
+		slow bool   // the interpreter recurses ten million times too
+	}{
+		{"result", `
+atqar'm count(n: san, acc: san) -> san {
+    eger n == 0 { qaıtar acc }
+    qaıtar count(n - 1, acc + 2)
+}
+kórset(count(N, 0))
+`, "%d", true},
+		{"no result", `
+jasa total = 0
+atqar'm count(n: san) {
+    eger n == 0 { qaıtar }
+    total = total + 2
+    count(n - 1)
+}
+count(N)
+kórset(total)
+`, "%d", false},
+		{"eger ending the body", `
+jasa total = 0
+atqar'm count(n: san) {
+    eger n > 0 {
+        total = total + 2
+        count(n - 1)
+    } áıtpece {
+        kórset("done ")
+    }
+}
+count(N)
+kórset(total)
+`, "done %d", false},
+	}
+	for _, tt := range tests {
+		for _, b := range allBackends {
+			t.Run(tt.name+"/"+b.name, func(t *testing.T) {
+				depth := 10000000
+				if b.name == interpreter.name && !tt.slow {
+					depth = 1000000
+				}
+				if b.name == interpreter.name && testing.Short() {
+					t.Skip("a million calls take seconds in the interpreter")
+				}
+				path := writeFile(t, "deep.tng", strings.Replace(tt.src, "N", fmt.Sprint(depth), 1))
+				got, err := b.run(t, path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := fmt.Sprintf(tt.want, 2*depth); got != want {
+					t.Errorf("output %q, want %q", got, want)
+				}
+			})
+		}
+	}
+}
+
+// TestStackOverflow checks that recursion deeper than the limit fails
+// with an error on the backends that count the calls.
+func TestStackOverflow(t *testing.T) {
+	path := writeFile(t, "deep.tng", `
+atqar'm sum(n: san) -> san {
+    eger n == 0 { qaıtar 0 }
+    qaıtar 1 + sum(n - 1)
+}
+kórset(sum(10000000))
+`)
+	for _, b := range []backend{interpreter, onVM} {
+		t.Run(b.name, func(t *testing.T) {
+			_, err := b.run(t, path)
+			if err == nil || !strings.Contains(err.Error(), "stack overflow") {
+				t.Errorf("error %v, want a stack overflow", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// FILE: cmd/vm/main.go
+// Purpose: Run a tenge program on the bytecode VM, for benchmarks.
+//
+//	vm [-dir benchmarks/src/tenge] <workload|file.tng|file.tbc> [program args...]
+//
+// A workload is the name of a benchmark source without the _cli.tng
+// suffix (fib_iter, fib_rec, sort, var_mc_sort); anything ending in .tng
+// or .tbc is run as given.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/DauletBai/tenge/internal/lang/diag"
+	"github.com/DauletBai/tenge/internal/lang/module"
+	"github.com/DauletBai/tenge/internal/lang/types"
+	"github.com/DauletBai/tenge/internal/vm"
+)
+
+// workloads maps the names benchfast and run.sh use to their sources.
+var workloads = map[string]string{
+	"fib_iter":    "fib_iter_cli.tng",
+	"fib_rec":     "fib_rec_cli.tng",
+	"sort":        "sort_cli_qs.tng",
+	"sort_qsort":  "sort_cli_qs.tng",
+	"var_mc":      "var_mc_sort_cli.tng",
+	"var_mc_sort": "var_mc_sort_cli.tng",
+}
+
+func main() {
+	dir := flag.String("dir", "benchmarks/src/tenge", "directory of the workload sources")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: vm [-dir dir] <workload|file.tng|file.tbc> [program args...]")
+		fmt.Fprintln(os.Stderr, "workloads: fib_iter, fib_rec, sort, var_mc_sort")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	args := flag.Args()
+	path := args[0]
+	if ext := filepath.Ext(path); ext != ".tng" && ext != ".tbc" {
+		name, ok := workloads[path]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "vm: unknown workload %q\n", path)
+			os.Exit(2)
+		}
+		path = filepath.Join(*dir, name)
+	}
+	args[0] = path
+	os.Exit(run(path, args))
+}
+
+func run(path string, args []string) int {
+	if filepath.Ext(path) == ".tbc" {
+		return runEncoded(path, args)
+	}
+	prog, errs := module.Load(path)
+	if len(errs) > 0 {
+		return report(errs)
+	}
+	info, errs := types.CheckModules(prog)
+	if len(errs) > 0 {
+		return report(errs)
+	}
+	code, errs := vm.Compile(prog, info)
+	if len(errs) > 0 {
+		return report(errs)
+	}
+	if err := vm.Run(code, args, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// runEncoded runs a .tbc file written by tenge emit-bytecode.
+func runEncoded(path string, args []string) int {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var code *vm.Program
+		if code, err = vm.Decode(data); err == nil {
+			err = vm.Run(code, args, os.Stdout)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		return 1
+	}
+	return 0
+}
+
+// report prints diagnostics to stderr and returns the exit code for them.
+func report(errs diag.List) int {
+	var p diag.Printer
+	for _, d := range errs {
+		p.Print(os.Stderr, d)
+	}
+	return 1
+}
@@ -2,4 +2,7 @@ module github.com/DauletBai/tenge
 
 go 1.24.1
 
-require github.com/shopspring/decimal v1.4.0 // indirect
+require (
+	github.com/shopspring/decimal v1.4.0
+	golang.org/x/text v0.25.0
+)
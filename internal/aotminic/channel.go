@@ -0,0 +1,543 @@
+// FILE: internal/aotminic/channel.go
+
+package aotminic
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/types"
+)
+
+// Channels are pointers to the tng_chan of chanC, which holds its values
+// as bytes: send passes the address of the value to send, and recv the
+// address of a temporary the value received is copied to. A tańda
+// statement becomes an array of the cases of its clauses, which
+// tng_select performs one of, and a chain of ifs running the body of the
+// clause it chose.
+
+// usesChannels reports whether the program uses channels.
+func (e *emitter) usesChannels(funcs []function, init []ast.Statement) bool {
+	found := false
+	visit := func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.TandaStatement:
+			found = true
+		case *ast.CallExpression:
+			if _, ok := n.Function.(*ast.TypeNode); ok {
+				found = true
+			}
+			if id, ok := n.Function.(*ast.Identifier); ok && chanOp(e.info, id) {
+				found = true
+			}
+		}
+		return !found
+	}
+	for _, f := range funcs {
+		ast.Inspect(f.lit.Body, visit)
+	}
+	for _, s := range init {
+		ast.Inspect(s, visit)
+	}
+	return found
+}
+
+// chanOp reports whether id names the built-in send, recv or close.
+func chanOp(info *types.Info, id *ast.Identifier) bool {
+	sym := info.Uses[id]
+	if sym == nil || sym.Kind != types.BuiltinSym {
+		return false
+	}
+	switch id.Value {
+	case "send", "recv", "close":
+		return true
+	}
+	return false
+}
+
+// chanElem returns the type of the values of the channel x.
+func (e *emitter) chanElem(x ast.Expression) types.Type {
+	if ch, ok := e.typeOf(x).(*types.Chan); ok {
+		return ch.Elem
+	}
+	e.errorf(x, msg.BackendChannel, x, e.typeOf(x))
+	return types.Typ[types.Any]
+}
+
+// makeChan emits `arna[T](n)`.
+func (e *emitter) makeChan(x *ast.CallExpression, tn *ast.TypeNode) string {
+	elem := e.chanElem(x)
+	capacity := "0"
+	if len(x.Arguments) > 0 {
+		capacity = e.convert(x.Arguments[0], types.Typ[types.San])
+	}
+	return "tng_chan_make(sizeof(" + e.ctype(elem, x) + "), " + capacity + ", " + e.pos(tn.Token) + ")"
+}
+
+// chanBuiltin emits a call of send, recv or close outside a tańda clause.
+func (e *emitter) chanBuiltin(name string, x *ast.CallExpression) string {
+	args := x.Arguments
+	ch, pos := e.expr(args[0]), e.pos(x.Function.(*ast.Identifier).Token)
+	switch name {
+	case "send":
+		elem := e.chanElem(args[0])
+		return "tng_chan_send(" + ch + ", (" + e.ctype(elem, x) + "[1]){" + e.convert(args[1], elem) + "}, " + pos + ")"
+	case "recv":
+		c := e.ctype(e.chanElem(args[0]), x)
+		ok := "NULL"
+		if len(args) == 2 {
+			ok = e.expr(args[1])
+		}
+		return "(*(" + c + " *)tng_chan_recv(" + ch + ", (" + c + "[1]){0}, " + ok + ", " + pos + "))"
+	}
+	return "tng_chan_close(" + ch + ", " + pos + ")"
+}
+
+// tanda emits a tańda statement. The channels and values of its clauses
+// are evaluated in order into temporaries before any of them is chosen.
+func (e *emitter) tanda(s *ast.TandaStatement) {
+	e.mark(s.Token)
+	e.tmp++
+	n := e.tmp
+	e.writeln("{")
+	e.indent++
+	var cases, vals []string
+	var clauses []*ast.TandaClause // the clause of each case
+	var fallback *ast.TandaClause  // the timeout or áıtpece clause
+	wait, timeout := "true", "-1"
+	for i, cl := range s.Clauses {
+		if cl.Comm == nil {
+			fallback, wait = cl, "false"
+			continue
+		}
+		id := cl.Comm.Function.(*ast.Identifier)
+		args := cl.Comm.Arguments
+		e.mark(id.Token)
+		if id.Value == "timeout" {
+			fallback, timeout = cl, fmt.Sprintf("tng_ms%d", n)
+			e.writeln("int64_t %s = %s;", timeout, e.convert(args[0], types.Typ[types.San]))
+			e.writeln("if (%s < 0) %s = 0;", timeout, timeout)
+			continue
+		}
+		elem := e.chanElem(args[0])
+		v := fmt.Sprintf("tng_v%d_%d", n, i)
+		ok := "NULL"
+		e.writeln("tng_chan *tng_c%d_%d = %s;", n, i, e.expr(args[0]))
+		switch id.Value {
+		case "send":
+			e.writeln("%s %s = %s;", e.ctype(elem, cl.Comm), v, e.convert(args[1], elem))
+		default:
+			e.writeln("%s %s = {0};", e.ctype(elem, cl.Comm), v)
+			if len(args) == 2 {
+				ok = e.expr(args[1])
+			}
+		}
+		cases = append(cases, fmt.Sprintf("{ tng_c%d_%d, %t, &%s, %s, %s }", n, i, id.Value == "send", v, ok, e.pos(id.Token)))
+		clauses = append(clauses, cl)
+		vals = append(vals, v)
+	}
+	e.mark(s.Token)
+	list := fmt.Sprintf("tng_cs%d", n)
+	if len(cases) == 0 {
+		e.writeln("tng_case *%s = NULL;", list)
+	} else {
+		e.writeln("tng_case %s[] = { %s };", list, strings.Join(cases, ", "))
+	}
+	k := fmt.Sprintf("tng_k%d", n)
+	e.writeln("int64_t %s = tng_select(%s, %d, %s, %s, %s, %s);", k, list, len(cases), wait, timeout, cString(s.Token.Literal), e.pos(s.Token))
+	keyword := "if"
+	for i, cl := range clauses {
+		e.writeln("%s (%s == %d) {", keyword, k, i)
+		e.clause(cl, vals[i])
+		keyword = "} else if"
+	}
+	if fallback != nil {
+		if keyword == "if" {
+			e.writeln("{")
+		} else {
+			e.writeln("} else {")
+		}
+		e.clause(fallback, "")
+	}
+	if keyword != "if" || fallback != nil {
+		e.writeln("}")
+	}
+	e.indent--
+	e.writeln("}")
+}
+
+// clause emits the body of a tańda clause, after the assignment of the
+// value received, held in v, to its target.
+func (e *emitter) clause(cl *ast.TandaClause, v string) {
+	e.indent++
+	if cl.Target != nil {
+		e.mark(cl.Target.Token)
+		elem := e.chanElem(cl.Comm.Arguments[0])
+		if cl.Define {
+			t := e.symType(cl.Target)
+			e.writeln("%s %s = %s;", e.ctype(t, cl.Target), cname(cl.Target.Value), e.cast(v, elem, t, cl.Target))
+		} else {
+			t := e.typeOf(cl.Target)
+			e.writeln("%s = %s;", e.expr(cl.Target), e.cast(v, elem, t, cl.Target))
+		}
+	}
+	e.indent--
+	e.block(cl.Body)
+}
+
+// cast converts the C value v of type from to type to.
+func (e *emitter) cast(v string, from, to types.Type, at ast.Node) string {
+	cf, ct := e.ctype(from, at), e.ctype(to, at)
+	switch {
+	case cf == ct:
+		return v
+	case isScalar(cf) && isScalar(ct):
+		return "(" + ct + ")" + v
+	}
+	e.errorf(at, msg.BackendUse, from, to)
+	return v
+}
+
+// chanC is the runtime of channels, emitted before threadsC in the
+// programs that use them.
+const chanC = `
+#include <errno.h>
+#include <pthread.h>
+
+/* --- Channels: typed queues passing values between tasks. ---
+
+   One mutex, tng_sched.mu, guards every channel. A thread that has to
+   wait enqueues a pending operation on each channel it waits for and
+   sleeps on the condition variable of its waiter until another thread
+   hands it a value, closes one of the channels or its timeout passes.
+
+   With TNG_DEADLOCK, which the profiles with checks define, tng_sched
+   also counts the threads running tenge code and lists the waiters
+   blocked without a timeout. When every thread is blocked nothing can
+   wake them any more, and the program exits with a report of where they
+   are blocked, as the interpreter does. */
+
+typedef struct tng_waiter {
+    pthread_cond_t cond;
+    bool woken;
+    int64_t clause;     /* the case that went on; -1 when the timeout passed */
+    const char *failed; /* the send that failed as its channel was closed */
+#ifdef TNG_DEADLOCK
+    const char *what, *pos;
+    bool main, listed;
+    struct tng_waiter *prev, *next; /* in tng_sched.blocked while listed */
+#endif
+} tng_waiter;
+
+/* A case is the operation of a send or recv call or of a tańda clause. */
+typedef struct {
+    struct tng_chan *ch;
+    bool send;
+    void *val; /* the value to send, or where the value received goes */
+    bool *ok;  /* set to whether a value was received; may be NULL */
+    const char *pos;
+} tng_case;
+
+/* A pending operation is a case enqueued on its channel by a waiter. */
+typedef struct tng_pending {
+    tng_waiter *w;
+    int64_t clause;
+    tng_case *c;
+    struct tng_pending *next;
+} tng_pending;
+
+typedef struct { tng_pending *first, *last; } tng_queue;
+
+typedef struct tng_chan {
+    size_t size;
+    int64_t cap, len, head; /* buf holds len values from head, wrapping around */
+    char *buf;
+    bool closed;
+    tng_queue recvq, sendq;
+} tng_chan;
+
+static struct {
+    pthread_mutex_t mu;
+#ifdef TNG_DEADLOCK
+    int64_t live, nblocked; /* threads running tenge code, the main one included */
+    tng_waiter *blocked;
+#endif
+} tng_sched = {
+    PTHREAD_MUTEX_INITIALIZER,
+#ifdef TNG_DEADLOCK
+    1, 0, NULL,
+#endif
+};
+
+static _Thread_local bool tng_off_main; /* set on the threads of the pool and of tasks */
+
+#ifdef TNG_DEADLOCK
+/* tng_pos_split splits a position file:line:col. */
+static void tng_pos_split(const char *pos, size_t *file, long *line, long *col) {
+    const char *c2 = strrchr(pos, ':'), *c1 = c2;
+    *file = strlen(pos), *line = 0, *col = 0;
+    if (!c2) return;
+    while (c1 > pos && *--c1 != ':') {}
+    if (*c1 != ':') return;
+    *file = (size_t)(c1 - pos), *line = atol(c1 + 1), *col = atol(c2 + 1);
+}
+
+static int tng_waiter_cmp(const void *x, const void *y) {
+    const char *a = (*(tng_waiter *const *)x)->pos, *b = (*(tng_waiter *const *)y)->pos;
+    size_t fa, fb;
+    long la, lb, ca, cb;
+    tng_pos_split(a, &fa, &la, &ca);
+    tng_pos_split(b, &fb, &lb, &cb);
+    int d = strncmp(a, b, fa < fb ? fa : fb);
+    if (d) return d;
+    if (fa != fb) return fa < fb ? -1 : 1;
+    if (la != lb) return la < lb ? -1 : 1;
+    return (ca > cb) - (ca < cb);
+}
+
+/* tng_check_deadlock exits when every thread is blocked. The caller holds
+   tng_sched.mu. */
+static void tng_check_deadlock(void) {
+    int64_t n = tng_sched.nblocked;
+    if (n == 0 || n < tng_sched.live) return;
+    tng_waiter **ws = tng_alloc((size_t)n * sizeof *ws);
+    int64_t i = 0;
+    for (tng_waiter *w = tng_sched.blocked; w; w = w->next) ws[i++] = w;
+    qsort(ws, (size_t)n, sizeof *ws, tng_waiter_cmp);
+    /* The error is the main thread's, as in the interpreter. */
+    const char *pos = ws[0]->pos;
+    for (i = 0; i < n; i++)
+        if (ws[i]->main) pos = ws[i]->pos;
+    fflush(stdout);
+    fprintf(stderr, "%s: " TNG_MSG_DEADLOCK, pos);
+    for (i = 0; i < n; i++) {
+        if (i > 0 && strcmp(ws[i]->pos, ws[i - 1]->pos) == 0 && strcmp(ws[i]->what, ws[i - 1]->what) == 0) continue;
+        fprintf(stderr, "\n\t%s: " TNG_MSG_BLOCKED_IN, ws[i]->pos, ws[i]->what);
+    }
+    fputc('\n', stderr);
+    exit(1);
+}
+
+/* tng_sched_live adds d to the threads running tenge code. */
+static void tng_sched_live(int64_t d) {
+    pthread_mutex_lock(&tng_sched.mu);
+    tng_sched.live += d;
+    tng_check_deadlock();
+    pthread_mutex_unlock(&tng_sched.mu);
+}
+#endif
+
+/* tng_waiter_block counts w as blocked in the operation what at pos until
+   it is woken. The caller holds tng_sched.mu. */
+static void tng_waiter_block(tng_waiter *w, const char *what, const char *pos) {
+#ifdef TNG_DEADLOCK
+    w->what = what, w->pos = pos, w->main = !tng_off_main, w->listed = true;
+    w->prev = NULL, w->next = tng_sched.blocked;
+    if (w->next) w->next->prev = w;
+    tng_sched.blocked = w;
+    tng_sched.nblocked++;
+    tng_check_deadlock();
+#else
+    (void)w, (void)what, (void)pos;
+#endif
+}
+
+/* tng_wake_waiter lets w go on. The caller holds tng_sched.mu. */
+static void tng_wake_waiter(tng_waiter *w) {
+    w->woken = true;
+#ifdef TNG_DEADLOCK
+    if (w->listed) {
+        if (w->prev) w->prev->next = w->next;
+        else tng_sched.blocked = w->next;
+        if (w->next) w->next->prev = w->prev;
+        w->listed = false;
+        tng_sched.nblocked--;
+    }
+#endif
+    pthread_cond_signal(&w->cond);
+}
+
+static void tng_enqueue(tng_queue *q, tng_pending *p) {
+    p->next = NULL;
+    if (q->last) q->last->next = p;
+    else q->first = p;
+    q->last = p;
+}
+
+/* tng_pop removes and returns the first pending operation whose waiter
+   has not gone on with another case. */
+static tng_pending *tng_pop(tng_queue *q) {
+    while (q->first) {
+        tng_pending *p = q->first;
+        q->first = p->next;
+        if (!q->first) q->last = NULL;
+        if (!p->w->woken) return p;
+    }
+    return NULL;
+}
+
+/* tng_forget removes the operations of w from q. */
+static void tng_forget(tng_queue *q, tng_waiter *w) {
+    tng_pending **link = &q->first;
+    q->last = NULL;
+    while (*link) {
+        if ((*link)->w == w) {
+            *link = (*link)->next;
+        } else {
+            q->last = *link;
+            link = &(*link)->next;
+        }
+    }
+}
+
+static void tng_chan_unmade(const char *what, const char *pos) {
+    char msg[160];
+    snprintf(msg, sizeof msg, TNG_MSG_CHAN_NOT_MADE, what);
+    tng_panic(pos, msg);
+}
+
+static tng_chan *tng_chan_make(size_t size, int64_t cap, const char *pos) {
+    if (cap < 0) {
+        char msg[160];
+        snprintf(msg, sizeof msg, TNG_MSG_NEGATIVE_CAPACITY, (long long)cap);
+        tng_panic(pos, msg);
+    }
+    tng_chan *ch = tng_alloc(sizeof *ch);
+    memset(ch, 0, sizeof *ch);
+    ch->size = size;
+    ch->cap = cap;
+    ch->buf = tng_alloc((size_t)cap * size);
+    return ch;
+}
+
+static void tng_chan_put(tng_chan *ch, const void *val) {
+    memcpy(ch->buf + (size_t)((ch->head + ch->len) % ch->cap) * ch->size, val, ch->size);
+    ch->len++;
+}
+
+/* tng_handed completes the pending operation p, letting its waiter go on
+   with the case p was enqueued for. */
+static void tng_handed(tng_pending *p, bool ok) {
+    if (p->c->ok) *p->c->ok = ok;
+    p->w->clause = p->clause;
+    tng_wake_waiter(p->w);
+}
+
+/* tng_ready performs the first of the n cases cs that can go on without
+   waiting and returns its index, or -1. The caller holds tng_sched.mu. */
+static int64_t tng_ready(tng_case *cs, int64_t n) {
+    for (int64_t i = 0; i < n; i++) {
+        tng_case *c = &cs[i];
+        tng_chan *ch = c->ch;
+        tng_pending *p;
+        if (c->send) {
+            if (ch->closed) tng_panic(c->pos, TNG_MSG_SEND_CLOSED);
+            if ((p = tng_pop(&ch->recvq))) {
+                memcpy(p->c->val, c->val, ch->size);
+                tng_handed(p, true);
+                return i;
+            }
+            if (ch->len < ch->cap) {
+                tng_chan_put(ch, c->val);
+                return i;
+            }
+            continue;
+        }
+        if (ch->len > 0) {
+            memcpy(c->val, ch->buf + (size_t)ch->head * ch->size, ch->size);
+            ch->head = (ch->head + 1) % ch->cap;
+            ch->len--;
+            if ((p = tng_pop(&ch->sendq))) {
+                tng_chan_put(ch, p->c->val);
+                tng_handed(p, true);
+            }
+        } else if ((p = tng_pop(&ch->sendq))) {
+            memcpy(c->val, p->c->val, ch->size);
+            tng_handed(p, true);
+        } else if (ch->closed) {
+            memset(c->val, 0, ch->size);
+            if (c->ok) *c->ok = false;
+            return i;
+        } else {
+            continue;
+        }
+        if (c->ok) *c->ok = true;
+        return i;
+    }
+    return -1;
+}
+
+/* tng_select waits until one of the n cases cs can go on, performs it and
+   returns its index. With wait false it returns -1 at once when none can
+   go on; with a timeout of ms >= 0 milliseconds it returns -1 when the
+   timeout has passed. what and pos name the operation, for deadlock
+   reports. */
+static int64_t tng_select(tng_case *cs, int64_t n, bool wait, int64_t ms, const char *what, const char *pos) {
+    for (int64_t i = 0; i < n; i++)
+        if (!cs[i].ch) tng_chan_unmade(cs[i].send ? "send" : "recv", cs[i].pos);
+    pthread_mutex_lock(&tng_sched.mu);
+    int64_t k = tng_ready(cs, n);
+    if (k >= 0 || !wait) {
+        pthread_mutex_unlock(&tng_sched.mu);
+        return k;
+    }
+    tng_waiter w = { .clause = -1 };
+    pthread_cond_init(&w.cond, NULL);
+    tng_pending *ps = tng_alloc((size_t)n * sizeof *ps);
+    for (int64_t i = 0; i < n; i++) {
+        ps[i] = (tng_pending){ &w, i, &cs[i], NULL };
+        tng_enqueue(cs[i].send ? &cs[i].ch->sendq : &cs[i].ch->recvq, &ps[i]);
+    }
+    if (ms >= 0) {
+        struct timespec t;
+        clock_gettime(CLOCK_REALTIME, &t);
+        t.tv_sec += ms / 1000;
+        t.tv_nsec += ms % 1000 * 1000000;
+        if (t.tv_nsec >= 1000000000) t.tv_sec++, t.tv_nsec -= 1000000000;
+        while (!w.woken)
+            if (pthread_cond_timedwait(&w.cond, &tng_sched.mu, &t) == ETIMEDOUT) break;
+        w.woken = true;
+    } else {
+        tng_waiter_block(&w, what, pos);
+        while (!w.woken) pthread_cond_wait(&w.cond, &tng_sched.mu);
+    }
+    for (int64_t i = 0; i < n; i++) tng_forget(cs[i].send ? &cs[i].ch->sendq : &cs[i].ch->recvq, &w);
+    if (w.failed) tng_panic(w.failed, TNG_MSG_SEND_CLOSED);
+    pthread_mutex_unlock(&tng_sched.mu);
+    pthread_cond_destroy(&w.cond);
+    free(ps);
+    return w.clause;
+}
+
+static void tng_chan_send(tng_chan *ch, void *val, const char *pos) {
+    tng_case c = { ch, true, val, NULL, pos };
+    tng_select(&c, 1, true, -1, "send", pos);
+}
+
+/* tng_chan_recv receives a value into val and returns val. */
+static void *tng_chan_recv(tng_chan *ch, void *val, bool *ok, const char *pos) {
+    tng_case c = { ch, false, val, ok, pos };
+    tng_select(&c, 1, true, -1, "recv", pos);
+    return val;
+}
+
+/* tng_chan_close closes ch: its receivers get the values left and then the
+   zero value, and its senders fail. */
+static void tng_chan_close(tng_chan *ch, const char *pos) {
+    if (!ch) tng_chan_unmade("close", pos);
+    pthread_mutex_lock(&tng_sched.mu);
+    if (ch->closed) tng_panic(pos, TNG_MSG_CLOSE_CLOSED);
+    ch->closed = true;
+    for (tng_pending *p; (p = tng_pop(&ch->recvq));) {
+        memset(p->c->val, 0, ch->size);
+        tng_handed(p, false);
+    }
+    for (tng_pending *p; (p = tng_pop(&ch->sendq));) {
+        p->w->failed = p->c->pos;
+        tng_wake_waiter(p->w);
+    }
+    pthread_mutex_unlock(&tng_sched.mu);
+}
+`
@@ -0,0 +1,741 @@
+// FILE: internal/aotminic/emit.go
+
+// Package aotminic compiles type-checked tenge programs ahead of time by
+// translating them to C.
+//
+// The generated file is self-contained (see prelude). Top-level functions
+// become C functions, top-level variables become globals initialised in
+// order by tng_init, and the C main runs tng_init followed by the tenge
+// `main` function when there is one, like the interpreter does.
+//
+// The modules of a multi-file program are emitted into the same file; the
+// top-level names of an imported module are prefixed with its import path
+// (stats/rng's next becomes stats_rng__next), and tng_init initialises the
+// modules in dependency order. Generic functions are monomorphized: every
+// set of type arguments they are called with becomes a separate C function
+// named after them (max__f64), so generic code costs nothing at run time.
+//
+// Arrays are allocated in arenas rather than one by one. Escape analysis
+// (see escapes) puts the arrays that may outlive the function making them
+// in a region that lasts as long as the program, and the others in a
+// scratch arena the function releases as it returns; loops release the
+// arrays that die with an iteration every time round.
+//
+// Functions that only loop over their array parameters (see kernel) get a
+// second version taking restrict, aligned pointers, which C compilers can
+// vectorize. The standard module simd/f64x4 is not compiled from its
+// source: its calls go to the vector code of the prelude.
+//
+// Parallel loops and tasks run on a pool of threads with work stealing
+// (see threadsC). Their bodies are outlined into functions of their own,
+// and each thread allocates from arenas of its own. Channels (see chanC)
+// are queues under one mutex; in programs with channels each task runs on
+// a thread of its own, and the profiles with checks report deadlocks;
+// release binaries hang on them.
+//
+// Builds can be guided by a profile (see PGO): a program emitted with
+// Options.Instrument records how its functions and conditions behave, and
+// a program emitted with that profile passes the branch probabilities on
+// to the C compiler and marks its hot and cold functions.
+//
+// Each statement is preceded by a `#line N "file.tng"` directive, so that
+// compiler diagnostics, gdb, addr2line, perf and the sanitizers report
+// tenge positions. The same positions are available as a SourceMap.
+package aotminic
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/diag"
+	"github.com/DauletBai/tenge/internal/lang/module"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/token"
+	"github.com/DauletBai/tenge/internal/lang/types"
+)
+
+// Options controls C generation.
+type Options struct {
+	Source  string  // path of the main tenge file; defaults to the file of the main module
+	Output  string  // path of the generated C file, used when returning to generated code
+	NoLines bool    // omit #line directives
+	Profile Profile // Debug and Hardened add runtime checks
+	Tests   bool    // main runs the tests of the main module instead of its main
+
+	// Instrument makes the program count the calls of its functions and
+	// the outcomes of its conditions, and write them to the file named by
+	// Instrument as it exits (see PGO).
+	Instrument string
+	// PGO is a profile of the program, recorded with Instrument, that
+	// guides the layout of branches and the inlining of functions.
+	PGO *PGO
+}
+
+// Output is a generated C translation unit.
+type Output struct {
+	C     []byte
+	Map   *SourceMap
+	Tests []string // with Options.Tests, the tests main runs, in order
+}
+
+// Emit translates prog to C. info must come from a successful type check
+// of prog. Constructs the C backend does not support are reported as
+// errors, like the other compiler passes.
+func Emit(prog *module.Program, info *types.Info, opts Options) (*Output, diag.List) {
+	if opts.Source == "" {
+		opts.Source = prog.Main().File
+	}
+	if opts.Source == "" {
+		opts.Source = "main.tng"
+	}
+	if opts.Output == "" {
+		opts.Output = strings.TrimSuffix(opts.Source, ".tng") + ".c"
+	}
+	e := &emitter{
+		opts:  opts,
+		info:  info,
+		cline: 1,
+		smap:  &SourceMap{Version: 1, File: opts.Output, Source: opts.Source},
+		seen:  make(map[string]bool),
+		names: make(map[*ast.Identifier]string),
+
+		outlined: make(map[ast.Node]string),
+		generics: make(map[*ast.AtqarmLiteral]*ast.Identifier),
+	}
+	e.program(prog)
+	return &Output{C: e.buf.Bytes(), Map: e.smap, Tests: e.tests}, e.errors
+}
+
+type emitter struct {
+	opts Options
+	info *types.Info
+	buf  bytes.Buffer
+
+	cline    int    // physical line of buf being written
+	inSource bool   // the current line is attributed to the tenge source
+	srcFile  string // file the C compiler attributes to the current line
+	srcLine  int    // line the C compiler attributes to the current line
+	indent   int
+
+	smap   *SourceMap
+	errors diag.List
+	seen   map[string]bool
+	names  map[*ast.Identifier]string // C names of top-level declarations
+
+	generics map[*ast.AtqarmLiteral]*ast.Identifier // top-level generic functions
+	subst    map[*types.TypeParam]types.Type        // type arguments of the instance being emitted
+
+	result types.Type                   // result type of the function being emitted; nil at top level
+	tmp    int                          // counter for temporaries
+	fn     *function                    // function being emitted
+	tails  map[*ast.CallExpression]bool // tail calls of fn to itself, which jump to tng_tail
+	frame  bool                         // fn allocates in the scratch arena and releases it as it returns
+
+	restrict map[*ast.Identifier]string // array parameters of a restrict kernel, by the prefix of their C names
+
+	threads  bool                // the program has parallel loops or tasks, or channels
+	channels bool                // the program uses channels
+	outlined map[ast.Node]string // C names of the helpers of parallel loops and tasks
+	groups   []string            // C names of the task groups of the enclosing kút blocks
+
+	esc   *escapes
+	pgo   *pgo     // set when instrumenting or applying a profile
+	tests []string // names of the tests main runs
+}
+
+// errorf reports code at the position of node at, naming compiled code as
+// the place that cannot run it. A message is only reported once.
+func (e *emitter) errorf(at ast.Node, code msg.Code, args ...interface{}) {
+	args = append(args, msg.Text(msg.InCompiled))
+	d := &diag.Diagnostic{Code: code, Span: diag.At(types.Pos(at)), Message: msg.Sprintf(code, args...)}
+	if !e.seen[d.Error()] {
+		e.seen[d.Error()] = true
+		e.errors = append(e.errors, d)
+	}
+}
+
+// --- Output and line bookkeeping ---
+
+// raw writes text that contains no tenge code, such as the prelude.
+func (e *emitter) raw(text string) {
+	e.buf.WriteString(text)
+	n := strings.Count(text, "\n")
+	e.cline += n
+	if e.inSource {
+		e.srcLine += n
+	}
+}
+
+// writeln writes one indented line.
+func (e *emitter) writeln(format string, args ...interface{}) {
+	e.raw(strings.Repeat("    ", e.indent) + fmt.Sprintf(format, args...) + "\n")
+}
+
+// mark attributes the next line to the tenge position of tok.
+func (e *emitter) mark(tok token.Token) {
+	if tok.Line == 0 {
+		return
+	}
+	file := tok.File
+	if file == "" {
+		file = e.opts.Source
+	}
+	if !e.opts.NoLines && (!e.inSource || e.srcFile != file || e.srcLine != tok.Line) {
+		e.raw(fmt.Sprintf("#line %d %s\n", tok.Line, cString(file)))
+	}
+	e.inSource, e.srcFile, e.srcLine = true, file, tok.Line
+	m := Mapping{CLine: e.cline, Line: tok.Line, Column: tok.Column}
+	if file != e.opts.Source {
+		m.Source = file
+	}
+	e.smap.Mappings = append(e.smap.Mappings, m)
+}
+
+// generated attributes the next lines to the C file itself.
+func (e *emitter) generated() {
+	if !e.inSource {
+		return
+	}
+	if !e.opts.NoLines {
+		e.raw(fmt.Sprintf("#line %d %s\n", e.cline+1, cString(e.opts.Output)))
+	}
+	e.inSource = false
+	e.smap.Mappings = append(e.smap.Mappings, Mapping{CLine: e.cline})
+}
+
+// --- Declarations ---
+
+type function struct {
+	name  *ast.Identifier
+	lit   *ast.AtqarmLiteral
+	sig   *types.Signature
+	cname string
+	subst map[*types.TypeParam]types.Type // type arguments of a generic instance
+
+	kernel *kernel // set when f is a kernel
+}
+
+func (e *emitter) program(prog *module.Program) {
+	var funcs []function
+	var globals []*ast.Identifier
+	var init []ast.Statement
+	var main *ast.Identifier
+	var tests []*ast.Identifier // test_* functions without parameters, as `tenge test` runs
+	lits := make(map[*ast.Identifier]*ast.AtqarmLiteral)
+	for _, m := range prog.Modules {
+		if prefix, ok := intrinsics[m.Path]; ok && m.Std {
+			for _, s := range m.Program.Statements {
+				if name, lit := ast.FuncDecl(s); lit != nil {
+					e.names[name] = prefix + name.Value
+					lits[name] = lit
+				}
+			}
+			continue
+		}
+		for _, s := range m.Program.Statements {
+			if name, lit := ast.FuncDecl(s); lit != nil {
+				e.declare(m, name)
+				lits[name] = lit
+				if len(lit.TypeParams) > 0 {
+					e.generics[lit] = name
+					continue
+				}
+				funcs = append(funcs, function{name: name, lit: lit, sig: e.info.Funcs[lit], cname: e.names[name]})
+				if m == prog.Main() && name.Value == "main" && len(lit.Parameters) == 0 {
+					main = name
+				}
+				if m == prog.Main() && strings.HasPrefix(name.Value, "test_") && len(lit.Parameters) == 0 {
+					tests = append(tests, name)
+				}
+				continue
+			}
+			switch s := s.(type) {
+			case *ast.JasaStatement:
+				e.declare(m, s.Name)
+				globals = append(globals, s.Name)
+			case *ast.BekitStatement:
+				e.declare(m, s.Name)
+				globals = append(globals, s.Name)
+			}
+			init = append(init, s)
+		}
+	}
+	funcs = append(funcs, e.instances()...)
+	e.esc = analyzeEscapes(e.info, lits, init)
+	for i := range funcs {
+		funcs[i].kernel = e.kernelOf(funcs[i])
+	}
+	e.preparePGO(prog, funcs, init)
+	e.channels = e.usesChannels(funcs, init)
+	e.threads = parallel(funcs, init) || e.channels
+
+	e.raw("// Code generated by tenge from " + e.opts.Source + ". DO NOT EDIT.\n")
+	e.raw("// Profile: " + e.opts.Profile.String() + "\n\n")
+	if e.threads {
+		e.raw("#define TNG_THREADS 1\n")
+	}
+	if e.channels {
+		e.raw("#define TNG_CHANNELS 1\n")
+		if e.opts.Profile.Checks() {
+			e.raw("#define TNG_DEADLOCK 1\n")
+		}
+	}
+	e.raw(messages())
+	e.raw(prelude)
+	if e.channels {
+		e.raw(chanC)
+	}
+	if e.threads {
+		e.raw(threadsC)
+	}
+	e.instrumentHead()
+
+	if len(globals) > 0 {
+		e.writeln("")
+		for _, g := range globals {
+			e.writeln("static %s %s;", e.ctype(e.symType(g), g), e.names[g])
+		}
+	}
+	if len(funcs) > 0 {
+		e.writeln("")
+		for _, f := range funcs {
+			e.writeln("static %s%s;", e.attributes(f), e.prototype(f))
+			if f.kernel != nil {
+				e.writeln("static %s%s;", e.attributes(f), e.restrictPrototype(f, f.kernel))
+			}
+		}
+	}
+	for _, f := range funcs {
+		e.writeln("")
+		e.outline(&f, f.lit.Body.Statements)
+		e.function(f)
+	}
+
+	e.writeln("")
+	e.outline(nil, init)
+	e.writeln("static void tng_init(void) {")
+	e.indent++
+	e.frame = e.esc.frames[nil]
+	if e.frame {
+		e.writeln("tng_mark tng_frame = tng_arena_mark(&tng_scratch);")
+	}
+	for _, s := range init {
+		e.topLevel(s)
+	}
+	if e.frame {
+		e.generated()
+		e.writeln("tng_arena_release(&tng_scratch, tng_frame);")
+	}
+	e.frame = false
+	e.indent--
+	e.generated()
+	e.writeln("}")
+
+	e.instrumentTail()
+
+	e.writeln("")
+	e.writeln("int main(int argc, char **argv) {")
+	e.indent++
+	e.writeln("tng_argc = argc;")
+	e.writeln("tng_argv = argv;")
+	if e.opts.Instrument != "" {
+		e.writeln("atexit(tng_pgo_write);")
+	}
+	e.writeln("tng_init();")
+	if e.opts.Tests {
+		sort.Slice(tests, func(i, j int) bool { return tests[i].Value < tests[j].Value })
+		for _, t := range tests {
+			e.writeln("%s();", e.names[t])
+			e.tests = append(e.tests, t.Value)
+		}
+	} else if main != nil {
+		e.writeln("%s();", e.names[main])
+	}
+	e.writeln("return 0;")
+	e.indent--
+	e.writeln("}")
+}
+
+// intrinsics maps the standard modules the prelude implements to the
+// prefix of the C names of their functions; the tenge source of these
+// modules is not compiled.
+var intrinsics = map[string]string{
+	"simd/f64x4": "tng_f64x4_",
+}
+
+// instances returns the instances of the generic functions the program
+// calls, including those only called from other instances.
+func (e *emitter) instances() []function {
+	calls := make([]*ast.CallExpression, 0, len(e.info.Instances))
+	for call := range e.info.Instances {
+		calls = append(calls, call)
+	}
+	sort.Slice(calls, func(i, j int) bool {
+		a, b := calls[i].Token, calls[j].Token
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+
+	var funcs []function
+	seen := make(map[string]bool)
+	var add func(inst *types.Instance, outer map[*types.TypeParam]types.Type)
+	add = func(inst *types.Instance, outer map[*types.TypeParam]types.Type) {
+		name := e.generics[inst.Func]
+		if name == nil {
+			return // a nested function; reported by local
+		}
+		generic := e.info.Funcs[inst.Func]
+		subst := make(map[*types.TypeParam]types.Type)
+		for i, tp := range generic.TypeParams {
+			subst[tp] = types.Subst(inst.TypeArgs[i], outer)
+		}
+		cname := e.instanceName(inst.Func, subst)
+		if seen[cname] {
+			return
+		}
+		seen[cname] = true
+		sig := types.Subst(generic, subst).(*types.Signature)
+		funcs = append(funcs, function{name: name, lit: inst.Func, sig: sig, cname: cname, subst: subst})
+		for _, call := range calls {
+			if inner := e.info.Instances[call]; inner.Outer == inst.Func {
+				add(inner, subst)
+			}
+		}
+	}
+	for _, call := range calls {
+		if inst := e.info.Instances[call]; inst.Outer == nil {
+			add(inst, nil)
+		}
+	}
+	return funcs
+}
+
+// instanceName returns the C name of a generic function instantiated with
+// the type arguments in subst, such as max__f64 or sum__arr_i32.
+func (e *emitter) instanceName(lit *ast.AtqarmLiteral, subst map[*types.TypeParam]types.Type) string {
+	generic := e.info.Funcs[lit]
+	args := make([]string, len(generic.TypeParams))
+	for i, tp := range generic.TypeParams {
+		args[i] = typeName(subst[tp])
+	}
+	return cname(e.names[e.generics[lit]] + "__" + strings.Join(args, "_"))
+}
+
+func typeName(t types.Type) string {
+	switch t := t.(type) {
+	case *types.Array:
+		return "arr_" + typeName(t.Elem)
+	case *types.Pointer:
+		return "ptr_" + typeName(t.Elem)
+	}
+	return t.String()
+}
+
+// declare assigns the C name of a top-level declaration of m.
+func (e *emitter) declare(m *module.Module, name *ast.Identifier) {
+	if m.Path == "" {
+		e.names[name] = cname(name.Value)
+		return
+	}
+	e.names[name] = cname(strings.ReplaceAll(m.Path, "/", "_") + "__" + name.Value)
+}
+
+func (e *emitter) symType(id *ast.Identifier) types.Type {
+	if sym := e.info.Defs[id]; sym != nil && sym.Type != nil {
+		return types.Subst(sym.Type, e.subst)
+	}
+	return types.Typ[types.Any]
+}
+
+func (e *emitter) prototype(f function) string {
+	params := make([]string, len(f.lit.Parameters))
+	for i, p := range f.lit.Parameters {
+		params[i] = e.ctype(f.sig.Params[i], p.Name) + " " + cname(p.Name.Value)
+	}
+	if len(params) == 0 {
+		params = []string{"void"}
+	}
+	return fmt.Sprintf("%s %s(%s)", e.ctype(f.sig.Result, f.name), f.cname, strings.Join(params, ", "))
+}
+
+func (e *emitter) function(f function) {
+	if f.kernel != nil {
+		e.kernelFunction(f, f.kernel)
+		return
+	}
+	e.mark(f.lit.Token)
+	e.writeln("static %s%s {", e.attributes(f), e.prototype(f))
+	e.body(f)
+	e.writeln("}")
+	e.generated()
+}
+
+// body emits the statements of f, indented, after the opening brace.
+func (e *emitter) body(f function) {
+	e.result, e.subst, e.fn = f.sig.Result, f.subst, &f
+	e.tails = e.tailCalls(f.lit)
+	e.frame = e.esc.frames[f.lit]
+	e.indent++
+	if e.opts.Instrument != "" {
+		e.writeln("TNG_PGO_ADD(tng_pgo_counters[%d]);", e.pgo.sites[f.lit].counter)
+	}
+	if e.frame {
+		e.writeln("tng_mark tng_frame = tng_arena_mark(&tng_scratch);")
+	}
+	if len(e.tails) > 0 {
+		e.writeln("tng_tail:;")
+	}
+	stmts := f.lit.Body.Statements
+	for _, s := range stmts {
+		e.stmt(s)
+	}
+	if e.frame {
+		if n := len(stmts); n == 0 || !isReturn(stmts[n-1]) {
+			e.generated()
+			e.writeln("tng_arena_release(&tng_scratch, tng_frame);")
+		}
+	}
+	e.indent--
+	e.result, e.subst, e.fn, e.tails, e.frame = nil, nil, nil, nil, false
+}
+
+// tailCalls returns the calls the function being emitted, lit, makes to
+// itself in tail position. They become a jump back to the start, so that
+// tail recursion runs in constant stack space, unless lit takes the
+// address of a variable, which a new call would get afresh.
+func (e *emitter) tailCalls(lit *ast.AtqarmLiteral) map[*ast.CallExpression]bool {
+	addressed := false
+	ast.Inspect(lit.Body, func(n ast.Node) bool {
+		if n, ok := n.(*ast.PrefixExpression); ok && n.Operator == "&" {
+			addressed = true
+		}
+		return true
+	})
+	if addressed {
+		return nil
+	}
+	var tails map[*ast.CallExpression]bool
+	for _, call := range ast.TailCalls(lit) {
+		if e.selfCall(call) {
+			if tails == nil {
+				tails = make(map[*ast.CallExpression]bool)
+			}
+			tails[call] = true
+		}
+	}
+	return tails
+}
+
+func isReturn(s ast.Statement) bool {
+	_, ok := s.(*ast.QaıtarStatement)
+	return ok
+}
+
+// selfCall reports whether x calls the function being emitted.
+func (e *emitter) selfCall(x *ast.CallExpression) bool {
+	callee, _ := e.callee(x)
+	return callee != "" && callee == e.fn.cname
+}
+
+// tailCall emits a tail call of f in the body of f: every argument is
+// computed before any parameter changes, then the code starts over.
+func (e *emitter) tailCall(x *ast.CallExpression) {
+	_, sig := e.callee(x)
+	e.writeln("{")
+	e.indent++
+	tmps := make([]string, len(x.Arguments))
+	for i, a := range x.Arguments {
+		e.tmp++
+		tmps[i] = fmt.Sprintf("tng_t%d", e.tmp)
+		e.writeln("%s %s = %s;", e.ctype(sig.Params[i], a), tmps[i], e.convert(a, sig.Params[i]))
+	}
+	for i, p := range e.fn.lit.Parameters {
+		e.writeln("%s = %s;", cname(p.Name.Value), tmps[i])
+	}
+	e.writeln("goto tng_tail;")
+	e.indent--
+	e.writeln("}")
+}
+
+// topLevel emits a statement of the program body inside tng_init; the
+// variables it declares are the globals.
+func (e *emitter) topLevel(s ast.Statement) {
+	var name *ast.Identifier
+	var value ast.Expression
+	switch s := s.(type) {
+	case *ast.JasaStatement:
+		name, value = s.Name, s.Value
+	case *ast.BekitStatement:
+		name, value = s.Name, s.Value
+	default:
+		e.stmt(s)
+		return
+	}
+	if value == nil {
+		return
+	}
+	e.mark(types.Pos(s))
+	e.writeln("%s = %s;", e.names[name], e.convert(value, e.symType(name)))
+}
+
+// --- Statements ---
+
+func (e *emitter) stmt(s ast.Statement) {
+	switch s := s.(type) {
+	case *ast.JasaStatement:
+		e.local(s, s.Name, s.Value, false)
+	case *ast.BekitStatement:
+		e.local(s, s.Name, s.Value, true)
+	case *ast.QaıtarStatement:
+		e.mark(s.Token)
+		if call, ok := s.ReturnValue.(*ast.CallExpression); ok && e.tails[call] {
+			e.tailCall(call)
+			return
+		}
+		release := func() {
+			if e.frame {
+				e.writeln("tng_arena_release(&tng_scratch, tng_frame);")
+			}
+		}
+		switch {
+		case s.ReturnValue == nil || e.result == nil:
+			release()
+			e.writeln("return;")
+		case types.IsVoid(e.result):
+			e.writeln("%s;", e.expr(s.ReturnValue))
+			release()
+			e.writeln("return;")
+		case e.frame:
+			// The result is computed before the arrays it may be
+			// computed from are released.
+			e.tmp++
+			tmp := fmt.Sprintf("tng_t%d", e.tmp)
+			e.writeln("{")
+			e.indent++
+			e.writeln("%s %s = %s;", e.ctype(e.result, s), tmp, e.convert(s.ReturnValue, e.result))
+			release()
+			e.writeln("return %s;", tmp)
+			e.indent--
+			e.writeln("}")
+		default:
+			e.writeln("return %s;", e.convert(s.ReturnValue, e.result))
+		}
+	case *ast.ExpressionStatement:
+		if eg, ok := s.Expression.(*ast.EgerExpression); ok {
+			e.mark(eg.Token)
+			e.eger(eg, "if")
+			return
+		}
+		e.mark(types.Pos(s))
+		if call, ok := s.Expression.(*ast.CallExpression); ok && e.tails[call] {
+			e.tailCall(call)
+			return
+		}
+		e.writeln("%s;", e.expr(s.Expression))
+	case *ast.AssignStatement:
+		e.mark(types.Pos(s))
+		target := e.expr(s.Target)
+		e.writeln("%s = %s;", target, e.convert(s.Value, e.typeOf(s.Target)))
+	case *ast.BlockStatement:
+		e.mark(s.Token)
+		e.writeln("{")
+		e.block(s)
+		e.writeln("}")
+	case *ast.AzirsheStatement:
+		e.mark(s.Token)
+		e.writeln("while (%s) {", e.condition(s, s.Condition))
+		// The arrays the body allocates in these arenas die with the
+		// iteration. A thread waiting for a task may run another one,
+		// whose arrays the heap region would lose, so only programs
+		// without threads release it.
+		var arenas []string
+		if e.esc.loops[s] {
+			arenas = append(arenas, "tng_scratch")
+		}
+		if e.esc.heapLoops[s] && !e.threads {
+			arenas = append(arenas, "tng_heap")
+		}
+		marks := make([]string, len(arenas))
+		e.indent++
+		for i, a := range arenas {
+			e.tmp++
+			marks[i] = fmt.Sprintf("tng_m%d", e.tmp)
+			e.writeln("tng_mark %s = tng_arena_mark(&%s);", marks[i], a)
+		}
+		e.indent--
+		e.block(s.Body)
+		e.indent++
+		if len(arenas) > 0 {
+			e.generated()
+		}
+		for i, a := range arenas {
+			e.writeln("tng_arena_release(&%s, %s);", a, marks[i])
+		}
+		e.indent--
+		e.writeln("}")
+	case *ast.KutStatement:
+		e.kut(s)
+	case *ast.MindetStatement:
+		e.mindet(s)
+	case *ast.TandaStatement:
+		e.tanda(s)
+	}
+}
+
+func (e *emitter) local(s ast.Statement, name *ast.Identifier, value ast.Expression, constant bool) {
+	if _, ok := value.(*ast.AtqarmLiteral); ok {
+		e.errorf(s, msg.BackendNested, name.Value)
+		return
+	}
+	e.mark(types.Pos(s))
+	t := e.symType(name)
+	decl := e.ctype(t, name) + " " + cname(name.Value)
+	if constant && value != nil {
+		if _, ok := t.(*types.Basic); ok {
+			decl = "const " + decl
+		}
+	}
+	if value == nil {
+		e.writeln("%s = {0};", decl)
+		return
+	}
+	e.writeln("%s = %s;", decl, e.convert(value, t))
+}
+
+func (e *emitter) block(b *ast.BlockStatement) {
+	e.indent++
+	for _, s := range b.Statements {
+		e.stmt(s)
+	}
+	e.indent--
+}
+
+// eger emits an if statement; keyword is "if" or "} else if" for chains.
+func (e *emitter) eger(eg *ast.EgerExpression, keyword string) {
+	e.writeln("%s (%s) {", keyword, e.condition(eg, eg.Condition))
+	e.block(eg.Consequence)
+	if eg.Alternative == nil {
+		e.writeln("}")
+		return
+	}
+	// `áıtpece eger` is parsed as an alternative holding a single eger.
+	if len(eg.Alternative.Statements) == 1 {
+		if es, ok := eg.Alternative.Statements[0].(*ast.ExpressionStatement); ok {
+			if next, ok := es.Expression.(*ast.EgerExpression); ok {
+				e.eger(next, "} else if")
+				return
+			}
+		}
+	}
+	e.writeln("} else {")
+	e.block(eg.Alternative)
+	e.writeln("}")
+}
@@ -0,0 +1,486 @@
+// FILE: internal/aotminic/escape.go
+
+package aotminic
+
+import (
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/types"
+)
+
+// escapes records where the arrays of a program are allocated. An array
+// escapes the function making it when it may be used after the function
+// returns: it is returned, stored in a global or through a pointer, has
+// its variable's address taken, or is passed to a parameter that escapes.
+// The arrays that do not escape go in the scratch arena (see prelude),
+// which functions release as they return.
+//
+// The arrays that escape go in the heap region. Most of them escape only
+// by being returned, and a loop that drops the results of the calls it
+// makes before the iteration ends can release the heap region every time
+// round, as long as the functions it calls keep nothing else.
+type escapes struct {
+	// scratch holds the allocation sites (array literals and calls of
+	// make_f64, make_i32, push and sort) whose arrays do not escape.
+	scratch map[ast.Expression]bool
+	// frames holds the functions allocating in the scratch arena; the nil
+	// key stands for the program body run by tng_init.
+	frames map[*ast.AtqarmLiteral]bool
+	// loops holds the loops whose scratch arrays all die with the
+	// iteration, so that each iteration can release them.
+	loops map[*ast.AzirsheStatement]bool
+	// heapLoops holds the loops whose iterations allocate in the heap
+	// region only arrays that die with the iteration.
+	heapLoops map[*ast.AzirsheStatement]bool
+	// params holds, for every function, which parameters escape.
+	params map[*ast.AtqarmLiteral][]bool
+	// keeps holds the functions that may keep an array they allocate, or
+	// a function they call allocates, after they return other than as
+	// their result: in a global, through a pointer or on a channel.
+	keeps map[*ast.AtqarmLiteral]bool
+}
+
+// escapeSink is the node every escaping array flows to, except the ones
+// a function returns, which flow to returnSink.
+var (
+	escapeSink = &ast.Identifier{Value: "<escape>"}
+	returnSink = &ast.Identifier{Value: "<return>"}
+)
+
+// analyzeEscapes runs the escape analysis over the top-level functions of
+// a program, given by their declaring names, and over its body. Whether a
+// parameter escapes depends on the functions its argument is passed to, so
+// the functions are analyzed until no more parameters escape.
+func analyzeEscapes(info *types.Info, funcs map[*ast.Identifier]*ast.AtqarmLiteral, body []ast.Statement) *escapes {
+	es := &escapes{params: make(map[*ast.AtqarmLiteral][]bool), keeps: make(map[*ast.AtqarmLiteral]bool)}
+	for _, lit := range funcs {
+		es.params[lit] = make([]bool, len(lit.Parameters))
+	}
+	for changed := true; changed; {
+		changed = false
+		es.scratch = make(map[ast.Expression]bool)
+		es.frames = make(map[*ast.AtqarmLiteral]bool)
+		es.loops = make(map[*ast.AzirsheStatement]bool)
+		es.heapLoops = make(map[*ast.AzirsheStatement]bool)
+		for _, lit := range funcs {
+			fl := newFlow(es, info, funcs)
+			for _, p := range lit.Parameters {
+				fl.declare(p.Name)
+				fl.params = append(fl.params, p.Name)
+			}
+			fl.block(lit.Body.Statements)
+			if fl.finish(lit) {
+				changed = true
+			}
+		}
+		fl := newFlow(es, info, funcs)
+		for _, s := range body {
+			switch s := s.(type) {
+			case *ast.JasaStatement:
+				fl.expr(s.Value)
+				fl.flow(s.Value, escapeSink) // a global
+			case *ast.BekitStatement:
+				fl.expr(s.Value)
+				fl.flow(s.Value, escapeSink)
+			default:
+				fl.stmt(s)
+			}
+		}
+		fl.finish(nil)
+	}
+	return es
+}
+
+// flow is the escape analysis of one function: a graph of the ways arrays
+// flow from allocation sites and variables into variables.
+type flow struct {
+	es    *escapes
+	info  *types.Info
+	funcs map[*ast.Identifier]*ast.AtqarmLiteral
+
+	locals  map[*ast.Identifier]bool                   // declaring names of the function's variables
+	edges   map[ast.Node][]ast.Node                    // where the arrays of a site, call or variable flow
+	sites   []ast.Expression                           // allocation sites, in order
+	calls   []*ast.CallExpression                      // calls of functions, in order
+	targets map[*ast.CallExpression]*ast.AtqarmLiteral // the functions they call, nil when unknown
+	params  []*ast.Identifier                          // declaring names of the parameters
+	loops   []*ast.AzirsheStatement                    // loops around the node being walked
+	in      map[ast.Node][]*ast.AzirsheStatement       // loops around each site and variable
+}
+
+func newFlow(es *escapes, info *types.Info, funcs map[*ast.Identifier]*ast.AtqarmLiteral) *flow {
+	return &flow{
+		es:      es,
+		info:    info,
+		funcs:   funcs,
+		locals:  make(map[*ast.Identifier]bool),
+		edges:   make(map[ast.Node][]ast.Node),
+		in:      make(map[ast.Node][]*ast.AzirsheStatement),
+		targets: make(map[*ast.CallExpression]*ast.AtqarmLiteral),
+	}
+}
+
+func (fl *flow) declare(name *ast.Identifier) {
+	fl.locals[name] = true
+	fl.in[name] = append([]*ast.AzirsheStatement(nil), fl.loops...)
+}
+
+func (fl *flow) site(x ast.Expression) {
+	fl.sites = append(fl.sites, x)
+	fl.in[x] = append([]*ast.AzirsheStatement(nil), fl.loops...)
+}
+
+// variable returns the declaring name of the local variable id refers
+// to, or nil.
+func (fl *flow) variable(id *ast.Identifier) *ast.Identifier {
+	if sym := fl.info.Uses[id]; sym != nil && sym.Decl != nil && fl.locals[sym.Decl] {
+		return sym.Decl
+	}
+	return nil
+}
+
+// sources returns the sites, calls and variables whose arrays the value of
+// x may share memory with. A call stands for the arrays the function
+// makes; the ones it is passed come from the arguments, which escape if
+// the function returns them.
+func (fl *flow) sources(x ast.Expression) []ast.Node {
+	switch x := x.(type) {
+	case *ast.Identifier:
+		if v := fl.variable(x); v != nil {
+			return []ast.Node{v}
+		}
+	case *ast.JyimLiteral:
+		return []ast.Node{x}
+	case *ast.QatarlasExpression:
+		if x.Reduction() == ast.Collect {
+			return []ast.Node{x}
+		}
+	case *ast.CallExpression:
+		switch fl.builtin(x) {
+		case "make_f64", "make_i32", "sort":
+			return []ast.Node{x}
+		case "push":
+			// push grows its argument in place when it has room.
+			return append([]ast.Node{x}, fl.sources(x.Arguments[0])...)
+		}
+		if _, ok := fl.targets[x]; ok {
+			return []ast.Node{x}
+		}
+	case *ast.EgerExpression:
+		var srcs []ast.Node
+		for _, b := range []*ast.BlockStatement{x.Consequence, x.Alternative} {
+			if b == nil || len(b.Statements) == 0 {
+				continue
+			}
+			if es, ok := b.Statements[len(b.Statements)-1].(*ast.ExpressionStatement); ok {
+				srcs = append(srcs, fl.sources(es.Expression)...)
+			}
+		}
+		return srcs
+	}
+	return nil
+}
+
+// flow records that the arrays of x flow to the node to.
+func (fl *flow) flow(x ast.Expression, to ast.Node) {
+	if x == nil {
+		return
+	}
+	for _, src := range fl.sources(x) {
+		fl.edges[src] = append(fl.edges[src], to)
+	}
+}
+
+// builtin returns the name of the built-in function x calls, or "".
+func (fl *flow) builtin(x *ast.CallExpression) string {
+	if id, ok := x.Function.(*ast.Identifier); ok {
+		if sym := fl.info.Uses[id]; sym != nil && sym.Kind == types.BuiltinSym && len(x.Arguments) > 0 {
+			return id.Value
+		}
+	}
+	return ""
+}
+
+func (fl *flow) block(stmts []ast.Statement) {
+	for _, s := range stmts {
+		fl.stmt(s)
+	}
+}
+
+func (fl *flow) stmt(s ast.Statement) {
+	switch s := s.(type) {
+	case *ast.JasaStatement:
+		fl.expr(s.Value)
+		fl.declare(s.Name)
+		fl.flow(s.Value, s.Name)
+	case *ast.BekitStatement:
+		fl.expr(s.Value)
+		fl.declare(s.Name)
+		fl.flow(s.Value, s.Name)
+	case *ast.AssignStatement:
+		fl.expr(s.Target)
+		fl.expr(s.Value)
+		if id, ok := s.Target.(*ast.Identifier); ok {
+			if v := fl.variable(id); v != nil {
+				fl.flow(s.Value, v)
+				return
+			}
+		}
+		fl.flow(s.Value, escapeSink)
+	case *ast.QaıtarStatement:
+		fl.expr(s.ReturnValue)
+		fl.flow(s.ReturnValue, returnSink)
+	case *ast.ExpressionStatement:
+		fl.expr(s.Expression)
+	case *ast.BlockStatement:
+		fl.block(s.Statements)
+	case *ast.AzirsheStatement:
+		fl.expr(s.Condition)
+		fl.loops = append(fl.loops, s)
+		fl.block(s.Body.Statements)
+		fl.loops = fl.loops[:len(fl.loops)-1]
+	case *ast.KutStatement:
+		fl.block(s.Body.Statements)
+	case *ast.MindetStatement:
+		// The arguments are used until the kút block ends, which is no
+		// later than the function returns.
+		fl.expr(s.Call)
+	case *ast.TandaStatement:
+		for _, cl := range s.Clauses {
+			if cl.Comm != nil {
+				fl.expr(cl.Comm)
+			}
+			if cl.Define {
+				fl.declare(cl.Target)
+			}
+			fl.block(cl.Body.Statements)
+		}
+	}
+}
+
+func (fl *flow) expr(x ast.Expression) {
+	switch x := x.(type) {
+	case *ast.JyimLiteral:
+		for _, el := range x.Elements {
+			fl.expr(el)
+		}
+		fl.site(x)
+	case *ast.PrefixExpression:
+		fl.expr(x.Right)
+		if x.Operator == "&" {
+			fl.flow(x.Right, escapeSink)
+		}
+	case *ast.InfixExpression:
+		fl.expr(x.Left)
+		fl.expr(x.Right)
+	case *ast.IndexExpression:
+		fl.expr(x.Left)
+		fl.expr(x.Index)
+	case *ast.EgerExpression:
+		fl.expr(x.Condition)
+		fl.block(x.Consequence.Statements)
+		if x.Alternative != nil {
+			fl.block(x.Alternative.Statements)
+		}
+	case *ast.QatarlasExpression:
+		// The body cannot assign the variables around it, so its arrays die
+		// with the iteration, which releases them (see outlineQatarlas).
+		fl.expr(x.From)
+		fl.expr(x.To)
+		fl.block(x.Body.Statements)
+		if x.Reduction() == ast.Collect {
+			fl.site(x)
+		}
+	case *ast.CallExpression:
+		for _, a := range x.Arguments {
+			fl.expr(a)
+		}
+		switch fl.builtin(x) {
+		case "make_f64", "make_i32", "push", "sort":
+			fl.site(x)
+			return
+		case "send":
+			// The value is received by another task.
+			fl.flow(x.Arguments[1], escapeSink)
+			return
+		case "":
+		default:
+			return
+		}
+		lit, escaping := fl.callee(x)
+		if lit != nil || escaping == nil {
+			fl.targets[x] = lit
+			fl.calls = append(fl.calls, x)
+			fl.in[x] = append([]*ast.AzirsheStatement(nil), fl.loops...)
+		}
+		for i, a := range x.Arguments {
+			if escaping == nil || i >= len(escaping) || escaping[i] {
+				fl.flow(a, escapeSink)
+			}
+		}
+	}
+}
+
+// callee returns the function x calls, nil when x converts a number or
+// calls something else, and which of its parameters escape; nil means
+// they all may.
+func (fl *flow) callee(x *ast.CallExpression) (*ast.AtqarmLiteral, []bool) {
+	if inst := fl.info.Instances[x]; inst != nil {
+		return inst.Func, fl.es.params[inst.Func]
+	}
+	id, ok := x.Function.(*ast.Identifier)
+	if sel, isSel := x.Function.(*ast.SelectorExpression); isSel {
+		id, ok = sel.Sel, true
+	}
+	if !ok {
+		return nil, nil
+	}
+	sym := fl.info.Uses[id]
+	if sym == nil {
+		return nil, nil
+	}
+	if sym.Kind == types.TypeSym {
+		return nil, []bool{false} // a conversion of a number
+	}
+	if lit := fl.funcs[sym.Decl]; lit != nil {
+		return lit, fl.es.params[lit]
+	}
+	return nil, nil
+}
+
+// reach returns the nodes the arrays of from flow to.
+func (fl *flow) reach(from ast.Node) map[ast.Node]bool {
+	seen := map[ast.Node]bool{from: true}
+	stack := []ast.Node{from}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, to := range fl.edges[n] {
+			if !seen[to] {
+				seen[to] = true
+				stack = append(stack, to)
+			}
+		}
+	}
+	return seen
+}
+
+// finish records the results for lit, nil for the program body, and
+// reports whether more of its parameters escape, or it keeps arrays when
+// it did not before.
+func (fl *flow) finish(lit *ast.AtqarmLiteral) bool {
+	changed := false
+	if lit != nil {
+		escaping := fl.es.params[lit]
+		for i, p := range fl.params {
+			if i < len(escaping) && !escaping[i] && escaped(fl.reach(p)) {
+				escaping[i] = true
+				changed = true
+			}
+		}
+		if !fl.es.keeps[lit] && fl.keeps() {
+			fl.es.keeps[lit] = true
+			changed = true
+		}
+	}
+	fl.heapLoops()
+	confined := make(map[*ast.AzirsheStatement]bool)
+	for _, s := range fl.sites {
+		reached := fl.reach(s)
+		if escaped(reached) {
+			continue
+		}
+		fl.es.scratch[s] = true
+		fl.es.frames[lit] = true
+		// A loop can release the array every iteration when the array
+		// only reaches variables declared in its body.
+		for _, l := range fl.in[s] {
+			if _, seen := confined[l]; !seen {
+				confined[l] = true
+			}
+			for n := range reached {
+				if n != ast.Node(s) && !within(fl.in[n], l) {
+					confined[l] = false
+				}
+			}
+		}
+	}
+	for l, ok := range confined {
+		if ok {
+			fl.es.loops[l] = true
+		}
+	}
+	return changed
+}
+
+// keeps reports whether the function keeps an array it or a function it
+// calls allocates other than as its result.
+func (fl *flow) keeps() bool {
+	for _, s := range fl.sites {
+		if fl.reach(s)[escapeSink] {
+			return true
+		}
+	}
+	for _, x := range fl.calls {
+		lit := fl.targets[x]
+		if lit == nil || fl.es.keeps[lit] || fl.reach(x)[escapeSink] {
+			return true
+		}
+	}
+	return false
+}
+
+// heapLoops records the loops that can release the heap region every
+// iteration: the arrays their bodies allocate there, all of them results
+// of calls of functions that keep nothing else, only reach variables
+// declared in the body.
+func (fl *flow) heapLoops() {
+	ok := make(map[*ast.AzirsheStatement]bool)
+	for _, x := range fl.calls {
+		for _, l := range fl.in[x] {
+			if _, seen := ok[l]; !seen {
+				ok[l] = true
+			}
+		}
+	}
+	for _, s := range fl.sites {
+		if escaped(fl.reach(s)) {
+			for _, l := range fl.in[s] {
+				ok[l] = false
+			}
+		}
+	}
+	for _, x := range fl.calls {
+		reached := fl.reach(x)
+		lit := fl.targets[x]
+		for _, l := range fl.in[x] {
+			if lit == nil || fl.es.keeps[lit] {
+				ok[l] = false
+			}
+			for n := range reached {
+				if n != ast.Node(x) && !within(fl.in[n], l) {
+					ok[l] = false
+				}
+			}
+		}
+	}
+	for l, ok := range ok {
+		if ok {
+			fl.es.heapLoops[l] = true
+		}
+	}
+}
+
+// escaped reports whether the arrays reaching the nodes reached outlive
+// the function.
+func escaped(reached map[ast.Node]bool) bool {
+	return reached[escapeSink] || reached[returnSink]
+}
+
+func within(loops []*ast.AzirsheStatement, l *ast.AzirsheStatement) bool {
+	for _, x := range loops {
+		if x == l {
+			return true
+		}
+	}
+	return false
+}
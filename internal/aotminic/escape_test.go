@@ -0,0 +1,141 @@
+// FILE: internal/aotminic/escape_test.go
+
+package aotminic_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DauletBai/tenge/internal/aotminic"
+	"github.com/DauletBai/tenge/internal/lang/lexer"
+	"github.com/DauletBai/tenge/internal/lang/module"
+	"github.com/DauletBai/tenge/internal/lang/parser"
+	"github.com/DauletBai/tenge/internal/lang/types"
+)
+
+// emit returns the C of src.
+func emit(t *testing.T, src string) string {
+	t.Helper()
+	p := parser.New(lexer.New(src))
+	prog, errs := module.LoadParsed("test.tng", p.ParseProgram())
+	errs = append(p.Diagnostics(), errs...)
+	var info *types.Info
+	if len(errs) == 0 {
+		info, errs = types.CheckModules(prog)
+	}
+	var out *aotminic.Output
+	if len(errs) == 0 {
+		out, errs = aotminic.Emit(prog, info, aotminic.Options{Output: "test.c"})
+	}
+	if len(errs) > 0 {
+		t.Fatal(errs[0])
+	}
+	return string(out.C)
+}
+
+// TestEscapes checks which arena the arrays of a program go in, and which
+// loops release an arena every iteration.
+func TestEscapes(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string // in the C
+		not  []string // not in the C
+	}{
+		{"local", `
+fn total(n: int) -> f64 {
+    let a = make_f64(n);
+    return a[0];
+}
+print(total(3));
+`, []string{"tng_arr_f64_make(&tng_scratch", "tng_frame = tng_arena_mark(&tng_scratch)"}, []string{"&tng_heap"}},
+		{"returned", `
+fn mk(n: int) -> []f64 { return make_f64(n); }
+print(len(mk(3)));
+`, []string{"tng_arr_f64_make(&tng_heap"}, []string{"&tng_scratch"}},
+		{"global", `
+let keep = make_f64(0);
+fn store(n: int) { keep = make_f64(n); }
+store(3);
+`, []string{"tng_arr_f64_make(&tng_heap"}, nil},
+		{"address taken", `
+fn f(n: int) -> f64 {
+    let a = make_f64(n);
+    let p = &a;
+    return (*p)[0];
+}
+print(f(2));
+`, []string{"tng_arr_f64_make(&tng_heap"}, nil},
+		{"loop", `
+fn f(n: int) -> f64 {
+    let s: f64 = 0.0;
+    let i = 0;
+    while i < n {
+        let a = make_f64(8);
+        s = s + a[0];
+        i = i + 1;
+    }
+    return s;
+}
+print(f(2));
+`, []string{"tng_arena_mark(&tng_scratch);\n"}, []string{"&tng_heap"}},
+		{"results dropped in a loop", `
+fn mk(n: int) -> []f64 { return make_f64(n); }
+fn f(n: int) -> f64 {
+    let s: f64 = 0.0;
+    let i = 0;
+    while i < n {
+        let a = mk(8);
+        s = s + a[0];
+        i = i + 1;
+    }
+    return s;
+}
+print(f(2));
+`, []string{"tng_arena_mark(&tng_heap)", "tng_arena_release(&tng_heap"}, nil},
+		{"result kept after the loop", `
+fn mk(n: int) -> []f64 { return make_f64(n); }
+fn f(n: int) -> f64 {
+    let last = mk(1);
+    let i = 0;
+    while i < n {
+        last = mk(8);
+        i = i + 1;
+    }
+    return last[0];
+}
+print(f(2));
+`, nil, []string{"tng_arena_mark(&tng_heap)"}},
+		{"callee keeping arrays", `
+let keep = make_f64(0);
+fn mk(n: int) -> []f64 {
+    keep = make_f64(n);
+    return make_f64(n);
+}
+fn f(n: int) -> f64 {
+    let s: f64 = 0.0;
+    let i = 0;
+    while i < n {
+        let a = mk(8);
+        s = s + a[0];
+        i = i + 1;
+    }
+    return s;
+}
+print(f(2));
+`, nil, []string{"tng_arena_mark(&tng_heap)"}},
+	}
+	for _, tt := range tests {
+		c := emit(t, tt.src)
+		for _, w := range tt.want {
+			if !strings.Contains(c, w) {
+				t.Errorf("%s: the C does not contain %q", tt.name, w)
+			}
+		}
+		for _, w := range tt.not {
+			if strings.Contains(c, w) {
+				t.Errorf("%s: the C contains %q", tt.name, w)
+			}
+		}
+	}
+}
@@ -0,0 +1,568 @@
+// FILE: internal/aotminic/expr.go
+
+package aotminic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/token"
+	"github.com/DauletBai/tenge/internal/lang/types"
+)
+
+// --- Types ---
+
+func (e *emitter) typeOf(x ast.Expression) types.Type {
+	if t := e.info.TypeOf(x); t != nil {
+		return types.Subst(t, e.subst)
+	}
+	return types.Typ[types.Any]
+}
+
+// ctype returns the C spelling of t. `any` values are 64-bit integers in
+// compiled code.
+func (e *emitter) ctype(t types.Type, at ast.Node) string {
+	switch t := t.(type) {
+	case *types.Basic:
+		switch t.Kind {
+		case types.Any, types.San, types.UntypedInt:
+			return "int64_t"
+		case types.I32, types.Tańba:
+			return "int32_t"
+		case types.U64:
+			return "uint64_t"
+		case types.F64, types.UntypedFloat:
+			return "double"
+		case types.Aqıqat:
+			return "bool"
+		case types.Jol:
+			return "const char *"
+		case types.Void:
+			return "void"
+		}
+	case *types.Array:
+		if n := arrayName(t); n != "" {
+			return n
+		}
+	case *types.Pointer:
+		return e.ctype(t.Elem, at) + " *"
+	case *types.Chan:
+		return "tng_chan *"
+	}
+	e.errorf(at, msg.BackendType, t)
+	return "int64_t"
+}
+
+// arrayName returns the prelude array type holding t's elements, or "".
+func arrayName(t *types.Array) string {
+	b, ok := t.Elem.(*types.Basic)
+	if !ok {
+		return ""
+	}
+	switch b.Kind {
+	case types.Any, types.San, types.UntypedInt:
+		return "tng_arr_i64"
+	case types.I32:
+		return "tng_arr_i32"
+	case types.U64:
+		return "tng_arr_u64"
+	case types.F64, types.UntypedFloat:
+		return "tng_arr_f64"
+	case types.Aqıqat:
+		return "tng_arr_bool"
+	}
+	return ""
+}
+
+func isScalar(c string) bool {
+	switch c {
+	case "int64_t", "int32_t", "uint64_t", "double", "bool":
+		return true
+	}
+	return false
+}
+
+// convert emits x as a value of type t.
+func (e *emitter) convert(x ast.Expression, t types.Type) string {
+	code := e.expr(x)
+	from, to := e.ctype(e.typeOf(x), x), e.ctype(t, x)
+	switch {
+	case from == to:
+		return code
+	case isScalar(from) && isScalar(to):
+		return "(" + to + ")" + code
+	}
+	e.errorf(x, msg.BackendUseTyped, x, e.typeOf(x), t)
+	return code
+}
+
+// --- Expressions ---
+
+func (e *emitter) expr(x ast.Expression) string {
+	switch x := x.(type) {
+	case *ast.Identifier:
+		return e.ident(x)
+	case *ast.SelectorExpression:
+		return e.ident(x.Sel)
+	case *ast.SanLiteral:
+		return e.intLit(x, x.Value)
+	case *ast.AqshaLiteral:
+		if c := e.ctype(e.typeOf(x), x); c != "double" {
+			e.errorf(x, msg.BackendUse, x, e.typeOf(x))
+		}
+		s := x.Value.String()
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s
+	case *ast.JolLiteral:
+		return cString(x.Value)
+	case *ast.AqıqatLiteral:
+		return strconv.FormatBool(x.Value)
+	case *ast.JyimLiteral:
+		return e.jyimLiteral(x)
+	case *ast.PrefixExpression:
+		return "(" + x.Operator + e.expr(x.Right) + ")"
+	case *ast.InfixExpression:
+		return e.infix(x)
+	case *ast.EgerExpression:
+		return e.egerValue(x)
+	case *ast.CallExpression:
+		return e.call(x)
+	case *ast.IndexExpression:
+		return e.index(x.Left, x.Index, x.Token)
+	case *ast.QatarlasExpression:
+		return e.qatarlas(x)
+	case *ast.AtqarmLiteral:
+		e.errorf(x, msg.BackendFuncLit)
+		return "0"
+	}
+	e.errorf(x, msg.BackendExpression, x)
+	return "0"
+}
+
+func (e *emitter) ident(id *ast.Identifier) string {
+	sym := e.info.Uses[id]
+	if sym != nil && sym.Decl == nil && sym.Name == "PI" {
+		return "3.141592653589793"
+	}
+	if sym != nil && (sym.Kind == types.BuiltinSym || sym.Kind == types.TypeSym) {
+		e.errorf(id, msg.BackendCallOnly, id.Value)
+	}
+	if sym != nil {
+		if sig, ok := sym.Type.(*types.Signature); ok && len(sig.TypeParams) > 0 {
+			e.errorf(id, msg.BackendGeneric, id.Value)
+		}
+	}
+	if sym != nil && sym.Decl != nil {
+		if name, ok := e.names[sym.Decl]; ok {
+			return name
+		}
+	}
+	return cname(id.Value)
+}
+
+func (e *emitter) intLit(x ast.Expression, v int64) string {
+	switch e.ctype(e.typeOf(x), x) {
+	case "uint64_t":
+		return fmt.Sprintf("UINT64_C(%d)", uint64(v))
+	case "double":
+		return fmt.Sprintf("%d.0", v)
+	case "int32_t":
+		return fmt.Sprintf("%d", v)
+	}
+	if v == -1<<63 {
+		return "INT64_MIN"
+	}
+	return fmt.Sprintf("INT64_C(%d)", v)
+}
+
+func (e *emitter) jyimLiteral(x *ast.JyimLiteral) string {
+	t, _ := e.typeOf(x).(*types.Array)
+	if t == nil {
+		t = &types.Array{Elem: types.Typ[types.Any]}
+	}
+	n := e.ctype(t, x)
+	if len(x.Elements) == 0 {
+		return n + "_make(" + e.arena(x) + ", 0)"
+	}
+	elems := make([]string, len(x.Elements))
+	for i, el := range x.Elements {
+		elems[i] = e.convert(el, t.Elem)
+	}
+	return fmt.Sprintf("%s_of(%s, %d, (%s[]){%s})", n, e.arena(x), len(elems), e.ctype(t.Elem, x), strings.Join(elems, ", "))
+}
+
+// arena returns the arena the array made by x is allocated in: the
+// scratch arena when escape analysis found it does not outlive its
+// function, and the program-long heap region otherwise.
+func (e *emitter) arena(x ast.Expression) string {
+	if e.esc.scratch[x] {
+		return "&tng_scratch"
+	}
+	return "&tng_heap"
+}
+
+func (e *emitter) infix(x *ast.InfixExpression) string {
+	l, r := e.expr(x.Left), e.expr(x.Right)
+	lt := e.typeOf(x.Left)
+	if types.Identical(lt, types.Typ[types.Jol]) {
+		if x.Operator == "+" {
+			return "tng_concat(" + l + ", " + r + ")"
+		}
+		return "(strcmp(" + l + ", " + r + ") " + x.Operator + " 0)"
+	}
+	t := e.ctype(e.typeOf(x), x)
+	if x.Operator == "%" && t == "double" {
+		return "fmod(" + l + ", " + r + ")"
+	}
+	if f := divOp(x.Operator, t); f != "" && !safeDivisor(x.Right) {
+		return f + "(" + l + ", " + r + ", " + e.pos(x.Token) + ")"
+	}
+	return "(" + l + " " + x.Operator + " " + r + ")"
+}
+
+// divOp returns the prelude function that performs an integer division or
+// remainder, or "" for other operations. Every profile calls it: division
+// by zero fails, and the other operations wrap around.
+func divOp(op, ctype string) string {
+	var suffix string
+	switch ctype {
+	case "int64_t":
+		suffix = "i64"
+	case "int32_t":
+		suffix = "i32"
+	case "uint64_t":
+		suffix = "u64"
+	default:
+		return ""
+	}
+	switch op {
+	case "/":
+		return "tng_div_" + suffix
+	case "%":
+		return "tng_mod_" + suffix
+	}
+	return ""
+}
+
+// safeDivisor reports whether divisor is a literal other than 0. Literals
+// are never negative, so C's own operator cannot fail on it either.
+func safeDivisor(divisor ast.Expression) bool {
+	lit, ok := divisor.(*ast.SanLiteral)
+	return ok && lit.Value != 0
+}
+
+// index emits element access. With checks the array is evaluated once into
+// a temporary unless it is a plain variable, which keeps the access an
+// lvalue for assignments.
+func (e *emitter) index(left, idx ast.Expression, tok token.Token) string {
+	if p := e.restricted(left); p != "" {
+		i := e.convert(idx, types.Typ[types.San])
+		if !e.opts.Profile.Checks() {
+			return p + "_data[" + i + "]"
+		}
+		return p + "_data[tng_index(" + i + ", " + p + "_len, " + e.pos(tok) + ")]"
+	}
+	a, i := e.expr(left), e.convert(idx, types.Typ[types.San])
+	if !e.opts.Profile.Checks() {
+		return "TNG_AT(" + a + ", " + i + ")"
+	}
+	if _, ok := left.(*ast.Identifier); ok {
+		return a + ".data[tng_index(" + i + ", " + a + ".len, " + e.pos(tok) + ")]"
+	}
+	e.tmp++
+	tmp := fmt.Sprintf("tng_t%d", e.tmp)
+	return fmt.Sprintf("({ %s %s = %s; %s.data[tng_index(%s, %s.len, %s)]; })",
+		e.ctype(e.typeOf(left), left), tmp, a, tmp, i, tmp, e.pos(tok))
+}
+
+// restricted returns the prefix of the C names of the data and length of
+// x in the restrict version of a kernel, if x is one of its arrays.
+func (e *emitter) restricted(x ast.Expression) string {
+	if id, ok := x.(*ast.Identifier); ok && e.restrict != nil {
+		if sym := e.info.Uses[id]; sym != nil && sym.Decl != nil {
+			return e.restrict[sym.Decl]
+		}
+	}
+	return ""
+}
+
+// egerValue emits an eger used as a value. Both branches must be a single
+// expression so that it maps onto the conditional operator.
+func (e *emitter) egerValue(x *ast.EgerExpression) string {
+	single := func(b *ast.BlockStatement) ast.Expression {
+		if b == nil || len(b.Statements) != 1 {
+			return nil
+		}
+		if es, ok := b.Statements[0].(*ast.ExpressionStatement); ok {
+			return es.Expression
+		}
+		return nil
+	}
+	then, els := single(x.Consequence), single(x.Alternative)
+	if then == nil || els == nil {
+		e.errorf(x, msg.BackendEgerSingle)
+		return "0"
+	}
+	t := e.typeOf(x)
+	return "(" + e.condition(x, x.Condition) + " ? " + e.convert(then, t) + " : " + e.convert(els, t) + ")"
+}
+
+func (e *emitter) call(x *ast.CallExpression) string {
+	if tn, ok := x.Function.(*ast.TypeNode); ok {
+		return e.makeChan(x, tn)
+	}
+	if id, ok := x.Function.(*ast.Identifier); ok {
+		if sym := e.info.Uses[id]; sym != nil {
+			switch sym.Kind {
+			case types.TypeSym:
+				if len(x.Arguments) == 1 {
+					return e.convert(x.Arguments[0], types.Subst(sym.Type, e.subst))
+				}
+			case types.BuiltinSym:
+				return e.builtin(id.Value, x)
+			}
+		}
+	}
+
+	callee, sig := e.callee(x)
+	if sig == nil {
+		e.errorf(x, msg.BackendDirectCall)
+		return "0"
+	}
+	args := make([]string, len(x.Arguments))
+	for i, a := range x.Arguments {
+		args[i] = e.convert(a, sig.Params[i])
+	}
+	return callee + "(" + strings.Join(args, ", ") + ")"
+}
+
+// callee returns the C name and signature of the function x calls. The
+// signature is nil when x is not a direct call of a function.
+func (e *emitter) callee(x *ast.CallExpression) (string, *types.Signature) {
+	if id, ok := x.Function.(*ast.Identifier); ok {
+		if sym := e.info.Uses[id]; sym != nil && (sym.Kind == types.TypeSym || sym.Kind == types.BuiltinSym) {
+			return "", nil
+		}
+	}
+	sig, ok := e.typeOf(x.Function).(*types.Signature)
+	id, isIdent := x.Function.(*ast.Identifier)
+	if sel, isSel := x.Function.(*ast.SelectorExpression); isSel {
+		id, isIdent = sel.Sel, true
+	}
+	inst := e.info.Instances[x]
+	if inst != nil {
+		sig, ok = types.Subst(inst.Sig, e.subst).(*types.Signature)
+	}
+	if !ok || !isIdent || len(sig.Params) != len(x.Arguments) {
+		return "", nil
+	}
+	if inst != nil {
+		return e.instance(inst), sig
+	}
+	return e.ident(id), sig
+}
+
+// instance returns the C name of the generic function called by inst in
+// the instance being emitted.
+func (e *emitter) instance(inst *types.Instance) string {
+	if e.generics[inst.Func] == nil {
+		return "0"
+	}
+	_, subst := e.info.Instantiate(inst, e.subst)
+	return e.instanceName(inst.Func, subst)
+}
+
+// builtin emits a call to a built-in function.
+func (e *emitter) builtin(name string, x *ast.CallExpression) string {
+	args := x.Arguments
+	arg := func(i int, t types.Type) string {
+		return e.convert(args[i], t)
+	}
+	f64, san := types.Typ[types.F64], types.Typ[types.San]
+
+	switch name {
+	case "kórset", "print":
+		parts := make([]string, 0, len(args))
+		for _, a := range args {
+			parts = append(parts, e.show(a))
+			if name == "print" && !types.Identical(e.typeOf(a), types.Typ[types.Jol]) {
+				parts = append(parts, "tng_newline()")
+			}
+		}
+		if len(parts) == 0 {
+			return "(void)0"
+		}
+		if e.threads {
+			// A line printed by one thread is not cut by the others.
+			parts = append(append([]string{"flockfile(stdout)"}, parts...), "funlockfile(stdout)")
+		}
+		return "(" + strings.Join(parts, ", ") + ")"
+	case "printi":
+		return "tng_show_i64(" + arg(0, san) + ")"
+	case "printf":
+		return "printf(\"%.*f\", (int)" + arg(1, san) + ", " + arg(0, f64) + ")"
+	case "print_time_ns":
+		return "tng_print_time_ns(" + arg(0, san) + ")"
+	case "argi", "argf":
+		def := "0"
+		if len(args) == 2 {
+			if name == "argi" {
+				def = arg(1, san)
+			} else {
+				def = arg(1, f64)
+			}
+		}
+		code := "tng_" + name + "(" + arg(0, san) + ", " + def + ")"
+		want, have := e.ctype(e.typeOf(x), x), "int64_t"
+		if name == "argf" {
+			have = "double"
+		}
+		if want != have {
+			code = "(" + want + ")" + code
+		}
+		return code
+	case "now_ns", "time_ns":
+		return "tng_now_ns()"
+	case "now_ms":
+		return "tng_now_ms()"
+	case "sqrt", "exp", "cos", "sin", "floor":
+		return name + "(" + arg(0, f64) + ")"
+	case "ln":
+		return "log(" + arg(0, f64) + ")"
+	case "make_f64":
+		return "tng_arr_f64_make(" + e.arena(x) + ", " + arg(0, san) + ")"
+	case "make_i32":
+		return "tng_arr_i32_make(" + e.arena(x) + ", " + arg(0, san) + ")"
+	case "len":
+		if types.Identical(e.typeOf(args[0]), types.Typ[types.Jol]) {
+			return "tng_jol_len(" + e.expr(args[0]) + ")"
+		}
+		if p := e.restricted(args[0]); p != "" {
+			return p + "_len"
+		}
+		return "(" + e.expr(args[0]) + ").len"
+	case "push":
+		t := e.typeOf(x)
+		elem := types.Type(types.Typ[types.Any])
+		if a, ok := t.(*types.Array); ok {
+			elem = a.Elem
+		}
+		return e.ctype(t, x) + "_push(" + e.arena(x) + ", " + e.expr(args[0]) + ", " + arg(1, elem) + ")"
+	case "index":
+		return e.index(args[0], args[1], x.Token)
+	case "sort":
+		return e.ctype(e.typeOf(x), x) + "_sort(" + e.arena(x) + ", " + e.expr(args[0]) + ")"
+	case "send", "recv", "close":
+		return e.chanBuiltin(name, x)
+	case "assert":
+		msg := cString("assertion failed")
+		if len(args) == 2 {
+			msg = "tng_concat(" + cString("assertion failed: ") + ", " + arg(1, types.Typ[types.Jol]) + ")"
+		}
+		return "((" + arg(0, types.Typ[types.Aqıqat]) + ") ? (void)0 : tng_panic(" + e.pos(x.Token) + ", " + msg + "))"
+	}
+	e.errorf(x, msg.BackendBuiltin, name)
+	return "0"
+}
+
+// show emits the code printing a value without a trailing newline.
+func (e *emitter) show(x ast.Expression) string {
+	t := e.typeOf(x)
+	if a, ok := t.(*types.Array); ok {
+		return e.ctype(a, x) + "_show(" + e.expr(x) + ")"
+	}
+	switch c := e.ctype(t, x); c {
+	case "const char *":
+		return "tng_show_jol(" + e.expr(x) + ")"
+	case "bool":
+		return "tng_show_bool(" + e.expr(x) + ")"
+	case "double":
+		return "tng_show_f64(" + e.expr(x) + ")"
+	case "uint64_t":
+		return "tng_show_u64(" + e.expr(x) + ")"
+	case "int64_t", "int32_t":
+		return "tng_show_i64(" + e.expr(x) + ")"
+	}
+	e.errorf(x, msg.BackendPrint, x, t)
+	return "(void)0"
+}
+
+// pos returns the C string "file:line:col" naming tok, for runtime
+// errors. Positions are the ones the interpreter reports.
+func (e *emitter) pos(tok token.Token) string {
+	if tok.File != "" {
+		return cString(tok.Pos())
+	}
+	return cString(e.opts.Source + ":" + tok.Pos())
+}
+
+// --- Names and literals ---
+
+// reserved holds names a tenge identifier cannot keep in C: keywords,
+// names used by the prelude and its headers, and main.
+var reserved = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`
+		auto break case char const continue default do double else enum
+		extern float for goto if inline int long register restrict return
+		short signed sizeof static struct switch typedef union unsigned void
+		volatile while bool true false main
+		abs exit free malloc memcpy memset printf putchar fputs qsort strcmp
+		strlen strtod strtoll snprintf atoi stdout stderr
+		sqrt exp log cos sin floor fmod isnan isinf signbit
+		index time clock_gettime NULL`) {
+		reserved[w] = true
+	}
+}
+
+// cname returns the C identifier for a tenge name: letters outside ASCII
+// are spelled as _uXXXX and names taken by C get a trailing underscore.
+func cname(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r < utf8.RuneSelf && (r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'):
+			b.WriteRune(r)
+		default:
+			fmt.Fprintf(&b, "_u%04X", r)
+		}
+	}
+	s := b.String()
+	if reserved[s] || strings.HasPrefix(s, "tng_") || strings.HasPrefix(s, "TNG_") {
+		s += "_"
+	}
+	return s
+}
+
+// cString returns s as a C string literal.
+func cString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&b, `\%03o`, c)
+		case c == '?' && i+1 < len(s) && s[i+1] == '?':
+			b.WriteString(`?\`) // avoid trigraphs
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,217 @@
+// FILE: internal/aotminic/kernel.go
+
+package aotminic
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/types"
+)
+
+// kernel describes a function that loops over its array parameters and
+// does nothing else with them than index them and take their length. It
+// calls no other function, dereferences no pointer and uses no global
+// array, so its arrays can only be reached through the parameters.
+//
+// A kernel is emitted a second time as <name>__restrict, which takes the
+// data of its arrays as restrict-qualified pointers aligned to TNG_ALIGN:
+// the C compiler may then keep elements in registers across stores and
+// vectorize the loops without checking at run time whether the arrays
+// overlap. The function itself calls that version, after checking that
+// no array it stores into overlaps another array parameter.
+type kernel struct {
+	arrays  []int        // indexes of the array parameters
+	written map[int]bool // array parameters stored into
+}
+
+// kernelOf returns the kernel f is, or nil.
+func (e *emitter) kernelOf(f function) *kernel {
+	k := &kernel{written: make(map[int]bool)}
+	params := make(map[*ast.Identifier]int)
+	for i, p := range f.lit.Parameters {
+		if a, ok := f.sig.Params[i].(*types.Array); ok && arrayName(a) != "" {
+			k.arrays = append(k.arrays, i)
+			params[p.Name] = i
+		}
+	}
+	if len(k.arrays) == 0 {
+		return nil
+	}
+
+	param := func(x ast.Expression) (int, bool) {
+		if id, ok := x.(*ast.Identifier); ok {
+			if sym := e.info.Uses[id]; sym != nil && sym.Decl != nil {
+				i, ok := params[sym.Decl]
+				return i, ok
+			}
+		}
+		return 0, false
+	}
+	allowed := make(map[*ast.Identifier]bool) // uses of the arrays as kernels may
+	loops, pure := false, true
+	ast.Inspect(f.lit.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AzirsheStatement:
+			loops = true
+		case *ast.QatarlasExpression, *ast.KutStatement, *ast.MindetStatement:
+			pure = false // outlined bodies cannot see the restrict pointers
+		case *ast.AssignStatement:
+			if ix, ok := n.Target.(*ast.IndexExpression); ok {
+				if i, ok := param(ix.Left); ok {
+					k.written[i] = true
+				}
+			}
+		case *ast.IndexExpression:
+			if _, ok := param(n.Left); ok {
+				allowed[n.Left.(*ast.Identifier)] = true
+			}
+		case *ast.PrefixExpression:
+			if n.Operator == "&" || n.Operator == "*" {
+				pure = false
+			}
+		case *ast.CallExpression:
+			switch e.builtinName(n) {
+			case "len", "index":
+				if _, ok := param(n.Arguments[0]); ok {
+					allowed[n.Arguments[0].(*ast.Identifier)] = true
+				}
+			case "":
+				if id, ok := n.Function.(*ast.Identifier); !ok || e.info.Uses[id] == nil || e.info.Uses[id].Kind != types.TypeSym {
+					pure = false
+				}
+			}
+		case *ast.Identifier:
+			sym := e.info.Uses[n]
+			if sym == nil || sym.Decl == nil {
+				break
+			}
+			if _, ok := params[sym.Decl]; ok && !allowed[n] {
+				pure = false // visited after its parent allowed it, if it did
+			}
+			if _, global := e.names[sym.Decl]; global && sym.Kind != types.FuncSym {
+				switch sym.Type.(type) {
+				case *types.Array, *types.Pointer:
+					pure = false
+				}
+			}
+		}
+		return pure
+	})
+	if !loops || !pure {
+		return nil
+	}
+	return k
+}
+
+// builtinName returns the name of the built-in function x calls, or "".
+func (e *emitter) builtinName(x *ast.CallExpression) string {
+	if id, ok := x.Function.(*ast.Identifier); ok {
+		if sym := e.info.Uses[id]; sym != nil && sym.Kind == types.BuiltinSym && len(x.Arguments) > 0 {
+			return id.Value
+		}
+	}
+	return ""
+}
+
+// restrictName returns the C name of the restrict version of kernel f.
+func restrictName(f function) string {
+	return f.cname + "__restrict"
+}
+
+// restrictPrototype returns the prototype of the restrict version of
+// kernel f: each array parameter a becomes tng_a_data and tng_a_len.
+func (e *emitter) restrictPrototype(f function, k *kernel) string {
+	params := make([]string, 0, len(f.lit.Parameters)+len(k.arrays))
+	for i, p := range f.lit.Parameters {
+		name := cname(p.Name.Value)
+		a, ok := f.sig.Params[i].(*types.Array)
+		if !ok || arrayName(a) == "" {
+			params = append(params, e.ctype(f.sig.Params[i], p.Name)+" "+name)
+			continue
+		}
+		elem := e.ctype(a.Elem, p.Name)
+		if !k.written[i] {
+			elem = "const " + elem
+		}
+		params = append(params, fmt.Sprintf("%s *restrict tng_%s_data", elem, name), fmt.Sprintf("int64_t tng_%s_len", name))
+	}
+	return fmt.Sprintf("%s %s(%s)", e.ctype(f.sig.Result, f.name), restrictName(f), strings.Join(params, ", "))
+}
+
+// kernelFunction emits kernel f: its restrict version, then the function
+// calling it.
+func (e *emitter) kernelFunction(f function, k *kernel) {
+	e.mark(f.lit.Token)
+	e.writeln("static %s%s {", e.attributes(f), e.restrictPrototype(f, k))
+	e.restrict = make(map[*ast.Identifier]string)
+	e.indent++
+	for _, i := range k.arrays {
+		name := cname(f.lit.Parameters[i].Name.Value)
+		e.restrict[f.lit.Parameters[i].Name] = "tng_" + name
+		e.writeln("tng_%s_data = TNG_ASSUME_ALIGNED(tng_%s_data);", name, name)
+	}
+	e.indent--
+	e.body(f)
+	e.restrict = nil
+	e.writeln("}")
+	e.generated()
+	e.writeln("")
+
+	var overlaps []string
+	for _, i := range k.arrays {
+		if !k.written[i] {
+			continue
+		}
+		for _, j := range k.arrays {
+			if j == i || k.written[j] && j < i || e.ctype(f.sig.Params[i], f.lit) != e.ctype(f.sig.Params[j], f.lit) {
+				continue
+			}
+			overlaps = append(overlaps, fmt.Sprintf("TNG_OVERLAP(%s, %s)",
+				cname(f.lit.Parameters[i].Name.Value), cname(f.lit.Parameters[j].Name.Value)))
+		}
+	}
+	args := make([]string, 0, len(f.lit.Parameters)+len(k.arrays))
+	for i, p := range f.lit.Parameters {
+		name := cname(p.Name.Value)
+		if a, ok := f.sig.Params[i].(*types.Array); ok && arrayName(a) != "" {
+			args = append(args, name+".data", name+".len")
+		} else {
+			args = append(args, name)
+		}
+	}
+	call := restrictName(f) + "(" + strings.Join(args, ", ") + ")"
+	ret := "return " + call + ";"
+	if types.IsVoid(f.sig.Result) {
+		ret = call + ";"
+	}
+
+	e.mark(f.lit.Token)
+	e.writeln("static %s%s {", e.attributes(f), e.prototype(f))
+	e.indent++
+	if len(overlaps) == 0 {
+		e.writeln("%s", ret)
+		e.indent--
+		e.writeln("}")
+		e.generated()
+		return
+	}
+	// Arrays that overlap run the body as written.
+	cond := strings.Join(overlaps, " || ")
+	if len(overlaps) > 1 {
+		cond = "(" + cond + ")"
+	}
+	e.writeln("if (!%s) {", cond)
+	e.indent++
+	e.writeln("%s", ret)
+	if types.IsVoid(f.sig.Result) {
+		e.writeln("return;")
+	}
+	e.indent--
+	e.writeln("}")
+	e.indent--
+	e.body(f)
+	e.writeln("}")
+	e.generated()
+}
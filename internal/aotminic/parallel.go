@@ -0,0 +1,707 @@
+// FILE: internal/aotminic/parallel.go
+
+package aotminic
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/types"
+)
+
+// Parallel loops and tasks run on the pool of threads of threadsC, which
+// follows the prelude of the programs that have any.
+//
+// C has no closures, so the body of a qatarlas loop is outlined before the
+// function it is in: <f>__par<k>_block runs one block of the range, with
+// copies of the variables the body uses from the function, and <f>__par<k>
+// splits the range into blocks, runs them on the pool and combines their
+// reductions. The blocks are the interpreter's and the reductions are
+// combined in block order, so the result does not depend on the number of
+// threads. Each mindet statement becomes <f>__task<k>, which copies the
+// arguments of the call and starts it; kút assigns the results in the
+// order the tasks were started once they have all finished.
+
+// parallel reports whether the program has parallel loops or tasks.
+func parallel(funcs []function, init []ast.Statement) bool {
+	found := false
+	visit := func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.QatarlasExpression, *ast.KutStatement, *ast.MindetStatement:
+			found = true
+		}
+		return !found
+	}
+	for _, f := range funcs {
+		ast.Inspect(f.lit.Body, visit)
+	}
+	for _, s := range init {
+		ast.Inspect(s, visit)
+	}
+	return found
+}
+
+// outline emits the helpers of the parallel loops and tasks of stmts, the
+// body of f or, when f is nil, of the program, inner ones first.
+func (e *emitter) outline(f *function, stmts []ast.Statement) {
+	owner := "tng_init"
+	if f != nil {
+		owner, e.subst = f.cname, f.subst
+	}
+	k := 0
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.QatarlasExpression:
+			ast.Inspect(n.From, visit)
+			ast.Inspect(n.To, visit)
+			ast.Inspect(n.Body, visit)
+			k++
+			e.outlineQatarlas(n, fmt.Sprintf("%s__par%d", owner, k))
+			return false
+		case *ast.MindetStatement:
+			ast.Inspect(n.Call, visit)
+			k++
+			e.outlineTask(n, fmt.Sprintf("%s__task%d", owner, k))
+			return false
+		}
+		return true
+	}
+	for _, s := range stmts {
+		ast.Inspect(s, visit)
+	}
+	e.subst = nil
+}
+
+// captured returns the declaring names of the local variables and
+// constants the body of x uses from outside it, in the order of their
+// first use.
+func (e *emitter) captured(x *ast.QatarlasExpression) []*ast.Identifier {
+	inside := make(map[*ast.Identifier]bool)
+	ast.Inspect(x, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Identifier); ok && e.info.Defs[id] != nil {
+			inside[id] = true
+		}
+		return true
+	})
+	var names []*ast.Identifier
+	seen := make(map[*ast.Identifier]bool)
+	ast.Inspect(x.Body, func(n ast.Node) bool {
+		id, ok := n.(*ast.Identifier)
+		if !ok {
+			return true
+		}
+		sym := e.info.Uses[id]
+		if sym == nil || sym.Decl == nil || inside[sym.Decl] || seen[sym.Decl] || sym.Kind != types.VarSym && sym.Kind != types.ConstSym {
+			return true
+		}
+		if _, global := e.names[sym.Decl]; !global {
+			seen[sym.Decl] = true
+			names = append(names, sym.Decl)
+		}
+		return true
+	})
+	return names
+}
+
+// reduced returns the type of the values x reduces or collects, or nil.
+func (e *emitter) reduced(x *ast.QatarlasExpression) types.Type {
+	switch x.Reduction() {
+	case "":
+		return nil
+	case ast.Collect:
+		if a, ok := e.typeOf(x).(*types.Array); ok {
+			return a.Elem
+		}
+	}
+	return e.typeOf(x)
+}
+
+// fold returns the statement adding the value tng_v to the reduction
+// tng_acc of the values before it.
+func (e *emitter) fold(x *ast.QatarlasExpression, ctype string) string {
+	switch x.Reduction() {
+	case ast.Min:
+		return "if (tng_v < tng_acc) tng_acc = tng_v;"
+	case ast.Max:
+		return "if (tng_v > tng_acc) tng_acc = tng_v;"
+	}
+	return "tng_acc = tng_acc + tng_v;"
+}
+
+// outlineQatarlas emits the context, block function and driver of x.
+func (e *emitter) outlineQatarlas(x *ast.QatarlasExpression, name string) {
+	e.outlined[x] = name
+	captured := e.captured(x)
+	reduction, elem := x.Reduction(), e.reduced(x)
+	var celem string
+	if elem != nil {
+		celem = e.ctype(elem, x)
+	}
+
+	e.generated()
+	e.writeln("typedef struct {")
+	e.indent++
+	for _, c := range captured {
+		e.writeln("%s %s;", e.ctype(e.symType(c), c), cname(c.Value))
+	}
+	e.writeln("int64_t lo, n, nb;")
+	switch reduction {
+	case "":
+	case ast.Collect:
+		e.writeln("%s out;", e.ctype(e.typeOf(x), x))
+	default:
+		e.writeln("%s *partials;", celem)
+	}
+	e.indent--
+	e.writeln("} %s_ctx;", name)
+	e.writeln("")
+
+	e.writeln("static void %s_block(void *tng_p, int64_t tng_b) {", name)
+	e.indent++
+	e.writeln("%s_ctx *tng_c = tng_p;", name)
+	for _, c := range captured {
+		e.writeln("%s %s = tng_c->%s;", e.ctype(e.symType(c), c), cname(c.Value), cname(c.Value))
+	}
+	e.writeln("int64_t tng_lo, tng_hi;")
+	e.writeln("tng_block(tng_c->lo, tng_c->n, tng_c->nb, tng_b, &tng_lo, &tng_hi);")
+	if reduction != "" && reduction != ast.Collect {
+		e.writeln("%s tng_acc = 0;", celem)
+	}
+	// The arrays an iteration allocates in the scratch arena die with it.
+	scratch := false
+	ast.Inspect(x.Body, func(n ast.Node) bool {
+		if y, ok := n.(ast.Expression); ok && e.esc.scratch[y] {
+			scratch = true
+		}
+		return !scratch
+	})
+	if scratch {
+		e.writeln("tng_mark tng_frame = tng_arena_mark(&tng_scratch);")
+	}
+	v := cname(x.Var.Value)
+	e.mark(x.Token)
+	e.writeln("for (int64_t %s = tng_lo; %s < tng_hi; %s++) {", v, v, v)
+	stmts := x.Body.Statements
+	if reduction != "" {
+		stmts = stmts[:len(stmts)-1]
+	}
+	e.block(&ast.BlockStatement{Token: x.Body.Token, Statements: stmts})
+	e.indent++
+	if reduction != "" {
+		last := x.Body.Statements[len(x.Body.Statements)-1].(*ast.ExpressionStatement).Expression
+		e.mark(types.Pos(last))
+		value := e.convert(last, elem)
+		if reduction == ast.Collect {
+			e.writeln("tng_c->out.data[%s - tng_c->lo] = %s;", v, value)
+		} else {
+			e.writeln("%s tng_v = %s;", celem, value)
+			e.writeln("if (%s == tng_lo) tng_acc = tng_v;", v)
+			e.writeln("else %s", e.fold(x, celem))
+		}
+	}
+	if scratch {
+		e.generated()
+		e.writeln("tng_arena_release(&tng_scratch, tng_frame);")
+	}
+	e.indent--
+	e.generated()
+	e.writeln("}")
+	if reduction != "" && reduction != ast.Collect {
+		e.writeln("tng_c->partials[tng_b] = tng_acc;")
+	}
+	e.indent--
+	e.writeln("}")
+	e.writeln("")
+
+	result, params := "void", name+"_ctx *tng_c, int64_t tng_from, int64_t tng_to"
+	switch reduction {
+	case "":
+	case ast.Collect:
+		result = e.ctype(e.typeOf(x), x)
+		params += ", tng_arena *tng_ar"
+	default:
+		result = celem
+	}
+	e.writeln("static %s %s(%s) {", result, name, params)
+	e.indent++
+	e.writeln("tng_c->lo = tng_from;")
+	e.writeln("tng_c->n = tng_to > tng_from ? tng_to - tng_from : 0;")
+	e.writeln("tng_c->nb = tng_c->n < TNG_MAX_BLOCKS ? tng_c->n : TNG_MAX_BLOCKS;")
+	switch reduction {
+	case "":
+		e.writeln("tng_parallel_for(tng_c->nb, %s_block, tng_c);", name)
+	case ast.Collect:
+		e.writeln("tng_c->out = %s_make(tng_ar, tng_c->n);", result)
+		e.writeln("tng_parallel_for(tng_c->nb, %s_block, tng_c);", name)
+		e.writeln("return tng_c->out;")
+	default:
+		e.writeln("%s tng_partials[TNG_MAX_BLOCKS];", celem)
+		e.writeln("tng_c->partials = tng_partials;")
+		e.writeln("tng_parallel_for(tng_c->nb, %s_block, tng_c);", name)
+		if reduction == ast.Sum {
+			e.writeln("if (tng_c->nb == 0) return 0;")
+		} else {
+			e.writeln("if (tng_c->nb == 0) tng_empty_reduction(%s, %s);", e.pos(x.Token), cString(x.Reduce.Value))
+		}
+		e.writeln("%s tng_acc = tng_partials[0];", celem)
+		e.writeln("for (int64_t tng_b = 1; tng_b < tng_c->nb; tng_b++) {")
+		e.indent++
+		e.writeln("%s tng_v = tng_partials[tng_b];", celem)
+		e.writeln("%s", e.fold(x, celem))
+		e.indent--
+		e.writeln("}")
+		e.writeln("return tng_acc;")
+	}
+	e.indent--
+	e.writeln("}")
+	e.writeln("")
+}
+
+// qatarlas emits a parallel loop: a call of its driver with the values of
+// the variables its body uses.
+func (e *emitter) qatarlas(x *ast.QatarlasExpression) string {
+	name := e.outlined[x]
+	if name == "" {
+		return "0"
+	}
+	var fields []string
+	for _, c := range e.captured(x) {
+		fields = append(fields, "."+cname(c.Value)+" = "+cname(c.Value))
+	}
+	if len(fields) == 0 {
+		fields = []string{".lo = 0"}
+	}
+	san := types.Typ[types.San]
+	args := []string{
+		"&(" + name + "_ctx){" + strings.Join(fields, ", ") + "}",
+		e.convert(x.From, san),
+		e.convert(x.To, san),
+	}
+	if x.Reduction() == ast.Collect {
+		args = append(args, e.arena(x))
+	}
+	return name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// outlineTask emits the context of task s and the functions starting the
+// call, running it and assigning its result.
+func (e *emitter) outlineTask(s *ast.MindetStatement, name string) {
+	callee, sig := e.callee(s.Call)
+	if sig == nil {
+		e.errorf(s.Call, msg.BackendDirectCall)
+		return
+	}
+	e.outlined[s] = name
+	var target types.Type
+	if s.Target != nil {
+		target = e.typeOf(s.Target)
+	}
+
+	e.generated()
+	e.writeln("typedef struct {")
+	e.indent++
+	e.writeln("tng_task task;")
+	if target != nil {
+		e.writeln("%s *target;", e.ctype(target, s.Target))
+		e.writeln("%s result;", e.ctype(sig.Result, s.Call))
+	}
+	for i, a := range s.Call.Arguments {
+		e.writeln("%s a%d;", e.ctype(sig.Params[i], a), i)
+	}
+	e.indent--
+	e.writeln("} %s_ctx;", name)
+	e.writeln("")
+
+	args := make([]string, len(s.Call.Arguments))
+	for i := range args {
+		args[i] = fmt.Sprintf("tng_c->a%d", i)
+	}
+	call := callee + "(" + strings.Join(args, ", ") + ")"
+	e.writeln("static void %s_run(tng_job *tng_j) {", name)
+	e.indent++
+	e.writeln("%s_ctx *tng_c = (%s_ctx *)tng_j;", name, name)
+	e.mark(s.Call.Token)
+	if target != nil {
+		e.writeln("tng_c->result = %s;", call)
+	} else {
+		e.writeln("%s;", call)
+	}
+	e.generated()
+	e.indent--
+	e.writeln("}")
+	e.writeln("")
+
+	finish := "NULL"
+	if target != nil {
+		finish = name + "_finish"
+		result := "tng_c->result"
+		from, to := e.ctype(sig.Result, s.Call), e.ctype(target, s.Target)
+		switch {
+		case from == to:
+		case isScalar(from) && isScalar(to):
+			result = "(" + to + ")" + result
+		default:
+			e.errorf(s.Call, msg.BackendUseTyped, s.Call, sig.Result, target)
+		}
+		e.writeln("static void %s(tng_task *tng_t) {", finish)
+		e.indent++
+		e.writeln("%s_ctx *tng_c = (%s_ctx *)tng_t;", name, name)
+		e.writeln("*tng_c->target = %s;", result)
+		e.indent--
+		e.writeln("}")
+		e.writeln("")
+	}
+
+	params := []string{"tng_tasks *tng_g"}
+	if target != nil {
+		params = append(params, e.ctype(target, s.Target)+" *tng_target")
+	}
+	for i, a := range s.Call.Arguments {
+		params = append(params, fmt.Sprintf("%s tng_a%d", e.ctype(sig.Params[i], a), i))
+	}
+	e.writeln("static void %s(%s) {", name, strings.Join(params, ", "))
+	e.indent++
+	e.writeln("%s_ctx *tng_c = tng_alloc(sizeof *tng_c);", name)
+	e.writeln("tng_c->task.job.run = %s_run;", name)
+	e.writeln("tng_c->task.finish = %s;", finish)
+	if target != nil {
+		e.writeln("tng_c->target = tng_target;")
+	}
+	for i := range s.Call.Arguments {
+		e.writeln("tng_c->a%d = tng_a%d;", i, i)
+	}
+	e.writeln("tng_task_start(tng_g, &tng_c->task);")
+	e.indent--
+	e.writeln("}")
+	e.writeln("")
+}
+
+// kut emits a kút block: the tasks started in it join its group, which
+// is waited for at its end.
+func (e *emitter) kut(s *ast.KutStatement) {
+	e.mark(s.Token)
+	e.tmp++
+	group := fmt.Sprintf("tng_g%d", e.tmp)
+	e.writeln("{")
+	e.indent++
+	e.writeln("tng_tasks %s = {0};", group)
+	e.indent--
+	e.groups = append(e.groups, group)
+	e.block(s.Body)
+	e.groups = e.groups[:len(e.groups)-1]
+	e.indent++
+	e.generated()
+	e.writeln("tng_task_join(&%s, %s, %s);", group, cString(s.Token.Literal), e.pos(s.Token))
+	e.indent--
+	e.writeln("}")
+}
+
+// mindet emits the start of task s in the group of the enclosing kút.
+func (e *emitter) mindet(s *ast.MindetStatement) {
+	name := e.outlined[s]
+	if name == "" || len(e.groups) == 0 {
+		return
+	}
+	_, sig := e.callee(s.Call)
+	args := []string{"&" + e.groups[len(e.groups)-1]}
+	if s.Target != nil {
+		args = append(args, "&"+e.expr(s.Target))
+	}
+	for i, a := range s.Call.Arguments {
+		args = append(args, e.convert(a, sig.Params[i]))
+	}
+	e.mark(s.Token)
+	e.writeln("%s(%s);", name, strings.Join(args, ", "))
+}
+
+// threadsC is the runtime of parallel loops and tasks, emitted after the
+// prelude of the programs that have any.
+const threadsC = `
+#include <pthread.h>
+#include <unistd.h>
+
+/* --- Threads: the pool running parallel loops and tasks. ---
+
+   The pool has $TENGE_THREADS threads, the main thread included, or one per
+   processor, started by the first parallel loop or task. Each thread has a
+   deque of jobs: it pushes the jobs it starts at the bottom and takes them
+   back from there, while idle threads steal from the top of the others'.
+   A thread waiting for jobs runs jobs itself meanwhile, so that waiting
+   never holds up the pool. */
+
+#define TNG_MAX_BLOCKS 1024 /* as in the interpreter */
+
+typedef struct tng_job {
+    void (*run)(struct tng_job *);
+    struct tng_group *group;
+} tng_job;
+
+/* A group counts the jobs started in it that have not finished. */
+typedef struct tng_group { int64_t pending; } tng_group;
+
+typedef struct {
+    pthread_mutex_t mu;
+    tng_job **jobs;
+    int64_t top, bottom, cap; /* jobs[top..bottom) */
+} tng_deque;
+
+static struct {
+    pthread_once_t once;
+    pthread_mutex_t mu; /* with cond, wakes threads when jobs are pushed or groups finish */
+    pthread_cond_t cond;
+    int64_t threads, queued;
+    tng_deque *deques;
+} tng_pool = { PTHREAD_ONCE_INIT, PTHREAD_MUTEX_INITIALIZER, PTHREAD_COND_INITIALIZER, 0, 0, NULL };
+
+static _Thread_local int64_t tng_self; /* deque of the current thread; 0 for main */
+
+static void tng_wake(void) {
+    pthread_mutex_lock(&tng_pool.mu);
+    pthread_cond_broadcast(&tng_pool.cond);
+    pthread_mutex_unlock(&tng_pool.mu);
+}
+
+static void tng_push(tng_job *j) {
+    tng_deque *d = &tng_pool.deques[tng_self];
+    pthread_mutex_lock(&d->mu);
+    if (d->bottom == d->cap) {
+        if (d->top > 0) {
+            memmove(d->jobs, d->jobs + d->top, (size_t)(d->bottom - d->top) * sizeof *d->jobs);
+        } else {
+            d->cap = d->cap ? 2 * d->cap : 64;
+            tng_job **jobs = tng_alloc((size_t)d->cap * sizeof *jobs);
+            if (d->bottom) memcpy(jobs, d->jobs, (size_t)d->bottom * sizeof *jobs);
+            free(d->jobs);
+            d->jobs = jobs;
+        }
+        d->bottom -= d->top;
+        d->top = 0;
+    }
+    d->jobs[d->bottom++] = j;
+    pthread_mutex_unlock(&d->mu);
+    __atomic_add_fetch(&tng_pool.queued, 1, __ATOMIC_SEQ_CST);
+    tng_wake();
+}
+
+/* tng_take pops a job from the current thread's deque, or steals one. */
+static tng_job *tng_take(void) {
+    for (int64_t k = 0; k < tng_pool.threads; k++) {
+        tng_deque *d = &tng_pool.deques[(tng_self + k) % tng_pool.threads];
+        tng_job *j = NULL;
+        pthread_mutex_lock(&d->mu);
+        if (d->bottom > d->top) j = k == 0 ? d->jobs[--d->bottom] : d->jobs[d->top++];
+        pthread_mutex_unlock(&d->mu);
+        if (j) {
+            __atomic_sub_fetch(&tng_pool.queued, 1, __ATOMIC_SEQ_CST);
+            return j;
+        }
+    }
+    return NULL;
+}
+
+static void tng_run(tng_job *j) {
+    tng_group *g = j->group;
+    j->run(j);
+    if (__atomic_sub_fetch(&g->pending, 1, __ATOMIC_SEQ_CST) == 0) tng_wake();
+}
+
+static void *tng_worker(void *arg) {
+    tng_self = (int64_t)(intptr_t)arg;
+#ifdef TNG_CHANNELS
+    tng_off_main = true;
+#endif
+    for (;;) {
+        tng_job *j = tng_take();
+        if (j) {
+            tng_run(j);
+            continue;
+        }
+        pthread_mutex_lock(&tng_pool.mu);
+        while (__atomic_load_n(&tng_pool.queued, __ATOMIC_SEQ_CST) == 0) pthread_cond_wait(&tng_pool.cond, &tng_pool.mu);
+        pthread_mutex_unlock(&tng_pool.mu);
+    }
+    return NULL;
+}
+
+static void tng_pool_start(void) {
+    const char *env = getenv("TENGE_THREADS");
+    int64_t n = env ? atoll(env) : 0;
+    if (n <= 0) n = (int64_t)sysconf(_SC_NPROCESSORS_ONLN);
+    if (n <= 0) n = 1;
+    tng_pool.threads = n;
+    tng_pool.deques = tng_alloc((size_t)n * sizeof *tng_pool.deques);
+    memset(tng_pool.deques, 0, (size_t)n * sizeof *tng_pool.deques);
+    for (int64_t i = 0; i < n; i++) pthread_mutex_init(&tng_pool.deques[i].mu, NULL);
+    for (int64_t i = 1; i < n; i++) {
+        /* The deque of a thread that cannot be started stays empty. */
+        pthread_t t;
+        if (pthread_create(&t, NULL, tng_worker, (void *)(intptr_t)i) == 0) pthread_detach(t);
+    }
+}
+
+static void tng_spawn(tng_group *g, tng_job *j) {
+    pthread_once(&tng_pool.once, tng_pool_start);
+    j->group = g;
+    __atomic_add_fetch(&g->pending, 1, __ATOMIC_SEQ_CST);
+    tng_push(j);
+}
+
+/* tng_wait runs jobs until those of g have all finished. */
+static void tng_wait(tng_group *g) {
+    while (__atomic_load_n(&g->pending, __ATOMIC_SEQ_CST) > 0) {
+        tng_job *j = tng_take();
+        if (j) {
+            tng_run(j);
+            continue;
+        }
+        pthread_mutex_lock(&tng_pool.mu);
+        while (__atomic_load_n(&g->pending, __ATOMIC_SEQ_CST) > 0 && __atomic_load_n(&tng_pool.queued, __ATOMIC_SEQ_CST) == 0)
+            pthread_cond_wait(&tng_pool.cond, &tng_pool.mu);
+        pthread_mutex_unlock(&tng_pool.mu);
+    }
+}
+
+/* --- Parallel loops: blocks of a range, claimed one at a time. --- */
+
+/* tng_block sets the bounds of block b of the n values from lo, split into
+   nb blocks whose lengths differ by at most one. */
+static inline void tng_block(int64_t lo, int64_t n, int64_t nb, int64_t b, int64_t *start, int64_t *end) {
+    int64_t q = n / nb, r = n % nb;
+    *start = lo + b * q + (b < r ? b : r);
+    *end = *start + q + (b < r);
+}
+
+typedef struct {
+    tng_job job;
+    void (*block)(void *, int64_t);
+    void *ctx;
+    int64_t nb, *next;
+} tng_for_job;
+
+static void tng_for_run(tng_job *j) {
+    tng_for_job *f = (tng_for_job *)j;
+    for (int64_t b; (b = __atomic_fetch_add(f->next, 1, __ATOMIC_RELAXED)) < f->nb;) f->block(f->ctx, b);
+}
+
+/* tng_parallel_for runs block(ctx, b) for the nb blocks b on the pool. */
+static void tng_parallel_for(int64_t nb, void (*block)(void *, int64_t), void *ctx) {
+    if (nb <= 0) return;
+    pthread_once(&tng_pool.once, tng_pool_start);
+    int64_t next = 0, n = tng_pool.threads < nb ? tng_pool.threads : nb;
+    tng_group g = {0};
+    tng_for_job *jobs = tng_alloc((size_t)n * sizeof *jobs);
+#ifdef TNG_DEADLOCK
+    /* The jobs count as running until the loop ends, which may overcount
+       the threads running tenge code but never undercounts them. */
+    tng_sched_live(n - 1);
+#endif
+    for (int64_t i = 0; i < n; i++) {
+        jobs[i] = (tng_for_job){ { tng_for_run, &g }, block, ctx, nb, &next };
+        if (i > 0) tng_spawn(&g, &jobs[i].job);
+    }
+    tng_for_run(&jobs[0].job);
+    tng_wait(&g);
+#ifdef TNG_DEADLOCK
+    tng_sched_live(1 - n);
+#endif
+    free(jobs);
+}
+
+static void tng_empty_reduction(const char *pos, const char *name) {
+    char msg[160];
+    snprintf(msg, sizeof msg, TNG_MSG_EMPTY_REDUCTION, name);
+    tng_panic(pos, msg);
+}
+
+/* --- Tasks: calls started in a kút block, which waits for them. ---
+
+   In programs with channels a task may wait for another, which a pool
+   thread waiting for it might be holding up, so each task runs on a
+   thread of its own and the kút block sleeps until they have finished. */
+
+typedef struct tng_task {
+    tng_job job;
+    void (*finish)(struct tng_task *); /* assigns the result; NULL without one */
+    struct tng_task *next;
+} tng_task;
+
+typedef struct {
+    tng_group group;
+    tng_task *first, *last; /* in the order they were started */
+#ifdef TNG_CHANNELS
+    tng_waiter *w; /* the kút block waiting for the tasks, if any */
+#endif
+} tng_tasks;
+
+#ifdef TNG_CHANNELS
+static void *tng_task_thread(void *arg) {
+    tng_task *t = arg;
+    tng_tasks *ts = (tng_tasks *)t->job.group;
+    bool off_main = tng_off_main;
+    tng_off_main = true;
+    t->job.run(&t->job);
+    tng_off_main = off_main;
+    pthread_mutex_lock(&tng_sched.mu);
+    if (--ts->group.pending == 0 && ts->w) tng_wake_waiter(ts->w);
+#ifdef TNG_DEADLOCK
+    tng_sched.live--;
+    tng_check_deadlock();
+#endif
+    pthread_mutex_unlock(&tng_sched.mu);
+    return NULL;
+}
+#endif
+
+static void tng_task_start(tng_tasks *ts, tng_task *t) {
+    t->next = NULL;
+    if (ts->last) ts->last->next = t;
+    else ts->first = t;
+    ts->last = t;
+#ifdef TNG_CHANNELS
+    t->job.group = &ts->group;
+    pthread_mutex_lock(&tng_sched.mu);
+    ts->group.pending++;
+#ifdef TNG_DEADLOCK
+    tng_sched.live++;
+#endif
+    pthread_mutex_unlock(&tng_sched.mu);
+    /* A task whose thread cannot be started runs at once instead. */
+    pthread_t th;
+    if (pthread_create(&th, NULL, tng_task_thread, t) == 0) pthread_detach(th);
+    else tng_task_thread(t);
+#else
+    tng_spawn(&ts->group, &t->job);
+#endif
+}
+
+/* tng_task_join waits for the tasks of ts, then assigns their results in
+   the order they were started. what and pos are the keyword, as written,
+   and the position of the kút block, for deadlock reports. */
+static void tng_task_join(tng_tasks *ts, const char *what, const char *pos) {
+#ifdef TNG_CHANNELS
+    pthread_mutex_lock(&tng_sched.mu);
+    if (ts->group.pending > 0) {
+        tng_waiter w = { .clause = -1 };
+        pthread_cond_init(&w.cond, NULL);
+        ts->w = &w;
+        tng_waiter_block(&w, what, pos);
+        while (!w.woken) pthread_cond_wait(&w.cond, &tng_sched.mu);
+        ts->w = NULL;
+        pthread_cond_destroy(&w.cond);
+    }
+    pthread_mutex_unlock(&tng_sched.mu);
+#else
+    (void)what;
+    (void)pos;
+    tng_wait(&ts->group);
+#endif
+    for (tng_task *t = ts->first, *next; t; t = next) {
+        next = t->next;
+        if (t->finish) t->finish(t);
+        free(t);
+    }
+}
+`
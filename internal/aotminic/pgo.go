@@ -0,0 +1,345 @@
+// FILE: internal/aotminic/pgo.go
+
+package aotminic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/diag"
+	"github.com/DauletBai/tenge/internal/lang/module"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/token"
+)
+
+// PGO is an execution profile, for profile-guided optimization. A program
+// built with Options.Instrument counts how often its functions are called,
+// its if conditions hold and its loops go round, and writes the counts as
+// JSON when it exits:
+//
+//	{"version":1,"source":"var.tng","checksum":"fnv64a:6f1c2e0d9a4b7c3e",
+//	 "functions":[{"source":"var.tng","line":3,"column":1,"name":"draw","calls":1000000}],
+//	 "branches":[{"source":"var.tng","line":9,"column":5,"taken":12,"not_taken":999988}],
+//	 "loops":[{"source":"var.tng","line":7,"column":5,"iterations":1000000,"exits":1}]}
+//
+// Sources are relative to the directory of the main file. The checksum is
+// that of the sources the program was built from (see Checksum); a profile
+// only applies to the same sources, as it refers to them by position.
+type PGO struct {
+	Version   int           `json:"version"`
+	Source    string        `json:"source"` // the main tenge file
+	Checksum  string        `json:"checksum"`
+	Functions []PGOFunction `json:"functions"`
+	Branches  []PGOBranch   `json:"branches"`
+	Loops     []PGOLoop     `json:"loops"`
+}
+
+// PGOSite is the position of a function, if or loop in a profile.
+type PGOSite struct {
+	Source string `json:"source"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// PGOFunction counts the calls of a function. A tail call of the function
+// to itself, which runs as a jump, does not count.
+type PGOFunction struct {
+	PGOSite
+	Name  string `json:"name"`
+	Calls uint64 `json:"calls"`
+}
+
+// PGOBranch counts the times the condition of an if held and did not.
+type PGOBranch struct {
+	PGOSite
+	Taken    uint64 `json:"taken"`
+	NotTaken uint64 `json:"not_taken"`
+}
+
+// PGOLoop counts the times the condition of a loop held and did not.
+type PGOLoop struct {
+	PGOSite
+	Iterations uint64 `json:"iterations"`
+	Exits      uint64 `json:"exits"`
+}
+
+// JSON encodes the profile.
+func (p *PGO) JSON() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// ReadPGO reads the profile in file.
+func ReadPGO(file string) (*PGO, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	p := new(PGO)
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, errors.New(msg.Sprintf(msg.PGOInvalid, file, err))
+	}
+	if p.Version != 1 {
+		return nil, errors.New(msg.Sprintf(msg.PGOVersion, file, p.Version))
+	}
+	return p, nil
+}
+
+// Checksum returns the checksum of the sources of the modules of prog
+// that are not built into the compiler.
+func Checksum(prog *module.Program) (string, error) {
+	h := fnv.New64a()
+	for _, m := range prog.Modules {
+		if m.Std || m.File == "" {
+			continue
+		}
+		src, err := os.ReadFile(m.File)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", m.Path, len(src))
+		h.Write(src)
+	}
+	return fmt.Sprintf("fnv64a:%016x", h.Sum64()), nil
+}
+
+// Counting sites, and the hints drawn from a profile.
+
+const (
+	siteFunction = iota
+	siteBranch
+	siteLoop
+)
+
+// site is a place the instrumented program counts at. Functions take one
+// counter, branches and loops two: the condition held, and did not.
+type site struct {
+	kind    int
+	name    string // of a function
+	pos     PGOSite
+	counter int // index of the first counter
+}
+
+// pgo holds what the emitter needs to instrument a program or to apply a
+// profile to it.
+type pgo struct {
+	sites    map[ast.Node]*site
+	order    []*site
+	counters int
+
+	profile  map[PGOSite][2]uint64 // counts of the profile by position
+	calls    uint64                // calls of all functions in the profile
+	checksum string
+}
+
+// site returns the position of tok as a profile records it.
+func (e *emitter) site(tok token.Token) PGOSite {
+	file := tok.File
+	if file == "" {
+		file = e.opts.Source
+	}
+	if rel, err := filepath.Rel(filepath.Dir(e.opts.Source), file); err == nil {
+		file = rel
+	}
+	return PGOSite{Source: filepath.ToSlash(file), Line: tok.Line, Column: tok.Column}
+}
+
+// preparePGO numbers the sites of the functions and top-level statements
+// to be emitted, and indexes the profile to apply.
+func (e *emitter) preparePGO(prog *module.Program, funcs []function, init []ast.Statement) {
+	if e.opts.Instrument == "" && e.opts.PGO == nil {
+		return
+	}
+	p := &pgo{sites: make(map[ast.Node]*site)}
+	add := func(n ast.Node, kind int, name string, tok token.Token) {
+		if _, ok := p.sites[n]; ok {
+			return
+		}
+		s := &site{kind: kind, name: name, pos: e.site(tok), counter: p.counters}
+		p.counters += 2
+		if kind == siteFunction {
+			p.counters--
+		}
+		p.sites[n] = s
+		p.order = append(p.order, s)
+	}
+	visit := func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.EgerExpression:
+			add(n, siteBranch, "", n.Token)
+		case *ast.AzirsheStatement:
+			add(n, siteLoop, "", n.Token)
+		}
+		return true
+	}
+	for _, f := range funcs {
+		add(f.lit, siteFunction, f.name.Value, f.lit.Token)
+		ast.Inspect(f.lit.Body, visit)
+	}
+	for _, s := range init {
+		ast.Inspect(s, visit)
+	}
+	e.pgo = p
+
+	if e.opts.Instrument != "" {
+		sum, err := Checksum(prog)
+		if err != nil {
+			e.errors = append(e.errors, &diag.Diagnostic{Span: diag.At(token.Token{File: e.opts.Source}), Message: err.Error()})
+		}
+		p.checksum = sum
+	}
+	if prof := e.opts.PGO; prof != nil {
+		p.profile = make(map[PGOSite][2]uint64)
+		for _, f := range prof.Functions {
+			p.profile[f.PGOSite] = [2]uint64{f.Calls}
+			p.calls += f.Calls
+		}
+		for _, b := range prof.Branches {
+			p.profile[b.PGOSite] = [2]uint64{b.Taken, b.NotTaken}
+		}
+		for _, l := range prof.Loops {
+			p.profile[l.PGOSite] = [2]uint64{l.Iterations, l.Exits}
+		}
+	}
+}
+
+// counted returns the counts the profile has for n, if any.
+func (e *emitter) counted(n ast.Node) ([2]uint64, bool) {
+	if e.pgo == nil || e.pgo.profile == nil || e.pgo.sites[n] == nil {
+		return [2]uint64{}, false
+	}
+	c, ok := e.pgo.profile[e.pgo.sites[n].pos]
+	return c, ok
+}
+
+// condition returns the C condition of the if or loop n: it counts the
+// outcomes in an instrumented build, and carries the probability that it
+// holds when a profile has run it.
+func (e *emitter) condition(n ast.Node, cond ast.Expression) string {
+	c := e.expr(cond)
+	if e.pgo == nil {
+		return c
+	}
+	if e.opts.Instrument != "" {
+		return fmt.Sprintf("tng_pgo_count(tng_pgo_counters + %d, %s)", e.pgo.sites[n].counter, c)
+	}
+	counts, ok := e.counted(n)
+	if total := counts[0] + counts[1]; ok && total > 0 {
+		return fmt.Sprintf("TNG_EXPECT(%s, %.4f)", c, float64(counts[0])/float64(total))
+	}
+	return c
+}
+
+// hotShare is the share of all calls a function must receive to be hot.
+const hotShare = 0.01
+
+// attributes returns the attributes the profile gives function f, followed
+// by a space: hot functions are inlined and optimized more aggressively,
+// and functions the profile never called are kept out of the way of the
+// others, with the calls to them taken as unlikely.
+func (e *emitter) attributes(f function) string {
+	counts, ok := e.counted(f.lit)
+	switch {
+	case !ok || e.pgo.calls == 0:
+		return ""
+	case counts[0] == 0:
+		return "TNG_COLD "
+	case float64(counts[0]) >= hotShare*float64(e.pgo.calls):
+		return "inline TNG_HOT "
+	}
+	return ""
+}
+
+// instrumentHead declares the counters of an instrumented program.
+func (e *emitter) instrumentHead() {
+	if e.opts.Instrument == "" {
+		return
+	}
+	e.writeln("")
+	e.writeln("/* --- PGO: counters, written to the profile as the program exits. --- */")
+	e.writeln("static uint64_t tng_pgo_counters[%d];", e.pgo.counters+1)
+	e.raw(pgoCountC)
+}
+
+// instrumentTail emits the table of the sites and the function writing
+// the profile, which main registers with atexit.
+func (e *emitter) instrumentTail() {
+	if e.opts.Instrument == "" {
+		return
+	}
+	str := func(s string) string {
+		j, _ := json.Marshal(s)
+		return cString(string(j))
+	}
+	e.writeln("")
+	e.writeln("static const tng_pgo_site tng_pgo_sites[] = {")
+	e.indent++
+	for _, s := range e.pgo.order {
+		e.writeln("{%d, %s, %s, %d, %d, %d},", s.kind, str(s.name), str(s.pos.Source), s.pos.Line, s.pos.Column, s.counter)
+	}
+	e.writeln("{-1, 0, 0, 0, 0, 0},")
+	e.indent--
+	e.writeln("};")
+	e.writeln("static const char *tng_pgo_source = %s;", str(filepath.ToSlash(filepath.Base(e.opts.Source))))
+	e.writeln("static const char *tng_pgo_checksum = %s;", str(e.pgo.checksum))
+	e.writeln("static const char *tng_pgo_file = %s;", cString(e.opts.Instrument))
+	e.raw(pgoWriteC)
+}
+
+// pgoCountC follows the counters of an instrumented program.
+const pgoCountC = `
+typedef struct {
+    int kind; /* 0 function, 1 branch, 2 loop */
+    const char *name, *source; /* JSON strings */
+    int64_t line, column, counter;
+} tng_pgo_site;
+
+/* Threads count with atomic additions. */
+#ifdef TNG_THREADS
+#define TNG_PGO_ADD(c) __atomic_fetch_add(&(c), 1, __ATOMIC_RELAXED)
+#else
+#define TNG_PGO_ADD(c) ((c)++)
+#endif
+
+/* tng_pgo_count counts whether a condition held in c[0], or not in c[1]. */
+static inline bool tng_pgo_count(uint64_t *c, bool held) {
+    TNG_PGO_ADD(c[!held]);
+    return held;
+}
+`
+
+// pgoWriteC writes the profile of an instrumented program, to the file
+// named by $TENGE_PGO or else to the one it was built for.
+const pgoWriteC = `
+static void tng_pgo_write(void) {
+    static const char *const sections[] = {"functions", "branches", "loops"};
+    const char *file = getenv("TENGE_PGO");
+    if (file == NULL || *file == 0) file = tng_pgo_file;
+    FILE *f = fopen(file, "w");
+    if (f == NULL) {
+        fprintf(stderr, "tenge: cannot write profile %s\n", file);
+        return;
+    }
+    fprintf(f, "{\"version\":1,\"source\":%s,\"checksum\":%s", tng_pgo_source, tng_pgo_checksum);
+    for (int k = 0; k < 3; k++) {
+        const char *sep = "";
+        fprintf(f, ",\n \"%s\":[", sections[k]);
+        for (const tng_pgo_site *s = tng_pgo_sites; s->kind >= 0; s++) {
+            if (s->kind != k) continue;
+            unsigned long long c0 = tng_pgo_counters[s->counter], c1 = tng_pgo_counters[s->counter + 1];
+            fprintf(f, "%s\n  {\"source\":%s,\"line\":%lld,\"column\":%lld", sep, s->source, (long long)s->line, (long long)s->column);
+            if (k == 0) fprintf(f, ",\"name\":%s,\"calls\":%llu}", s->name, c0);
+            if (k == 1) fprintf(f, ",\"taken\":%llu,\"not_taken\":%llu}", c0, c1);
+            if (k == 2) fprintf(f, ",\"iterations\":%llu,\"exits\":%llu}", c0, c1);
+            sep = ",";
+        }
+        fprintf(f, "]");
+    }
+    fprintf(f, "}\n");
+    fclose(f);
+}
+`
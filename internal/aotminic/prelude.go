@@ -0,0 +1,487 @@
+// FILE: internal/aotminic/prelude.go
+
+package aotminic
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DauletBai/tenge/internal/lang/msg"
+)
+
+// messages defines the TNG_MSG_* error texts of the prelude in the
+// language selected with msg.SetLang.
+func messages() string {
+	var b strings.Builder
+	for _, m := range []struct {
+		name string
+		code msg.Code
+	}{
+		{"OUT_OF_MEMORY", msg.OutOfMemory},
+		{"NEGATIVE_LENGTH", msg.NegativeLength},
+		{"OUT_OF_RANGE", msg.OutOfRange},
+		{"DIVISION_BY_ZERO", msg.DivisionByZero},
+		{"EMPTY_REDUCTION", msg.EmptyReduction},
+		{"SEND_CLOSED", msg.SendClosed},
+		{"CLOSE_CLOSED", msg.CloseClosed},
+		{"CHAN_NOT_MADE", msg.ChanNotMade},
+		{"NEGATIVE_CAPACITY", msg.NegativeCapacity},
+		{"DEADLOCK", msg.Deadlock},
+		{"BLOCKED_IN", msg.BlockedIn},
+	} {
+		text := strings.ReplaceAll(msg.Text(m.code), "%d", "%lld")
+		fmt.Fprintf(&b, "#define TNG_MSG_%s %s\n", m.name, cString(text))
+	}
+	return b.String()
+}
+
+// VectorC defines the vector types and the vector exp of the prelude. The
+// hand-written templates of cmd/tenge include it too, after <stdint.h>,
+// <string.h> and <math.h>.
+const VectorC = `/* --- Vectors: four doubles at a time, with the vector extensions of GCC
+   and Clang. ---
+
+   TNG_SIMD is left undefined for other compilers, or when TNG_NO_SIMD is
+   defined, and the code using vectors falls back to scalars. Vectors are
+   loaded and stored with memcpy, so no alignment is needed. tng_exp1 and
+   tng_exp4 compute exp the way the tenge source of module simd/f64x4
+   does: x = k ln 2 + r, a polynomial for e^r, and 2^k in two halves, each
+   a normal double. */
+
+#if (defined(__GNUC__) || defined(__clang__)) && !defined(TNG_NO_SIMD)
+#define TNG_SIMD 1
+typedef double tng_f64x4 __attribute__((vector_size(32)));
+typedef int64_t tng_i64x4 __attribute__((vector_size(32)));
+
+/* Vectors go through memory rather than function arguments: without AVX
+   the C ABI has no 32-byte registers to pass them in. */
+#define TNG_LOAD4(v, p) memcpy(&(v), (p), sizeof(v))
+#define TNG_STORE4(p, v) memcpy((p), &(v), sizeof(v))
+#endif
+
+#define TNG_LOG2E 1.4426950408889634
+#define TNG_LN2_HI 0.6931471803691238
+#define TNG_LN2_LO 1.9082149292705877e-10
+#define TNG_EXP_HI 709.782712893384
+#define TNG_EXP_LO -745.1332191019412
+
+#define TNG_EXP_POLY(p, r)                                                      \
+    p = r * (1.0 / 479001600.0) + 1.0 / 39916800.0;                             \
+    p = p * r + 1.0 / 3628800.0;                                                \
+    p = p * r + 1.0 / 362880.0;                                                 \
+    p = p * r + 1.0 / 40320.0;                                                  \
+    p = p * r + 1.0 / 5040.0;                                                   \
+    p = p * r + 1.0 / 720.0;                                                    \
+    p = p * r + 1.0 / 120.0;                                                    \
+    p = p * r + 1.0 / 24.0;                                                     \
+    p = p * r + 1.0 / 6.0;                                                      \
+    p = p * r + 0.5;                                                            \
+    p = p * r + 1.0;                                                            \
+    p = p * r + 1.0
+
+static inline double tng_pow2(int64_t k) {
+    uint64_t bits = (uint64_t)(k + 1023) << 52;
+    double v;
+    memcpy(&v, &bits, sizeof v);
+    return v;
+}
+
+static inline double tng_exp1(double x) {
+    if (x != x) return x;
+    if (x > TNG_EXP_HI) return HUGE_VAL;
+    if (x < TNG_EXP_LO) return 0.0;
+    double k = floor(x * TNG_LOG2E + 0.5);
+    double r = x - k * TNG_LN2_HI - k * TNG_LN2_LO, p;
+    TNG_EXP_POLY(p, r);
+    int64_t h = (int64_t)k / 2;
+    return p * tng_pow2(h) * tng_pow2((int64_t)k - h);
+}
+
+#ifdef TNG_SIMD
+/* tng_exp4 stores exp of src[0..3] in dst[0..3]. */
+static inline void tng_exp4(double *dst, const double *src) {
+    tng_f64x4 x, x0;
+    TNG_LOAD4(x, src);
+    /* Lanes out of range, or NaN, compute e^0 and are patched up last. */
+    tng_i64x4 hi = x > TNG_EXP_HI, lo = x < TNG_EXP_LO, nan = x != x;
+    tng_i64x4 special = hi | lo | nan;
+    x0 = x;
+    x = (tng_f64x4)((tng_i64x4)x & ~special);
+    tng_f64x4 t = x * TNG_LOG2E + 0.5;
+    tng_i64x4 k = __builtin_convertvector(t, tng_i64x4);
+    k += __builtin_convertvector(k, tng_f64x4) > t; /* floor: -1 where truncation rounded up */
+    tng_f64x4 kf = __builtin_convertvector(k, tng_f64x4);
+    tng_f64x4 r = x - kf * TNG_LN2_HI - kf * TNG_LN2_LO, p;
+    TNG_EXP_POLY(p, r);
+    tng_i64x4 h = k / 2;
+    p = p * (tng_f64x4)((h + 1023) << 52) * (tng_f64x4)((k - h + 1023) << 52);
+    tng_f64x4 inf = { HUGE_VAL, HUGE_VAL, HUGE_VAL, HUGE_VAL };
+    p = (tng_f64x4)(((tng_i64x4)p & ~special) | ((tng_i64x4)inf & hi) | ((tng_i64x4)x0 & nan));
+    TNG_STORE4(dst, p);
+}
+#endif
+`
+
+// prelude is written at the top of every generated C file. It makes the
+// output self-contained: only libc and libm are needed to link it.
+const prelude = `#include <stdbool.h>
+#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <math.h>
+#include <time.h>
+
+static int tng_argc;
+static char **tng_argv;
+
+static inline void tng_panic(const char *pos, const char *msg) {
+    fflush(stdout);
+    fprintf(stderr, "%s: %s\n", pos, msg);
+    exit(1);
+}
+
+static inline void *tng_alloc(size_t n) {
+    void *p = malloc(n ? n : 1);
+    if (!p) tng_panic("tenge", TNG_MSG_OUT_OF_MEMORY);
+    return p;
+}
+
+/* Compiled programs never free their long-lived arrays; keep the hardened profile's
+   AddressSanitizer from reporting that as leaks at exit. */
+#ifndef __has_feature
+#define __has_feature(x) 0
+#endif
+#if defined(__SANITIZE_ADDRESS__) || __has_feature(address_sanitizer)
+const char *__asan_default_options(void) { return "detect_leaks=0"; }
+#endif
+
+/* --- Printing: tng_show_* writes a value the way the interpreter does. --- */
+
+static inline void tng_show_i64(int64_t v) { printf("%lld", (long long)v); }
+static inline void tng_show_u64(uint64_t v) { printf("%llu", (unsigned long long)v); }
+static inline void tng_show_bool(bool v) { fputs(v ? "jan" : "j'n", stdout); }
+static inline void tng_show_jol(const char *v) { fputs(v, stdout); }
+static inline void tng_newline(void) { putchar('\n'); }
+
+/* Shortest representation that reads back exactly, in the layout of Go's
+   strconv.FormatFloat(v, 'g', -1, 64). */
+static inline void tng_show_f64(double v) {
+    char buf[40];
+    if (isnan(v)) { fputs("NaN", stdout); return; }
+    if (isinf(v)) { fputs(v > 0 ? "+Inf" : "-Inf", stdout); return; }
+    if (v == 0) { fputs(signbit(v) ? "-0" : "0", stdout); return; }
+    int prec = 1;
+    for (; prec < 17; prec++) {
+        snprintf(buf, sizeof buf, "%.*e", prec - 1, v);
+        if (strtod(buf, NULL) == v) break;
+    }
+    snprintf(buf, sizeof buf, "%.*e", prec - 1, v);
+    int exp = atoi(strchr(buf, 'e') + 1);
+    if (exp < -4 || exp >= 6) {
+        fputs(buf, stdout);
+        return;
+    }
+    int frac = prec - 1 - exp;
+    printf("%.*f", frac > 0 ? frac : 0, v);
+}
+
+static inline void tng_print_time_ns(int64_t ns) { printf("TIME_NS: %lld\n", (long long)ns); }
+
+/* --- Strings --- */
+
+static inline const char *tng_concat(const char *a, const char *b) {
+    size_t la = strlen(a), lb = strlen(b);
+    char *s = tng_alloc(la + lb + 1);
+    memcpy(s, a, la);
+    memcpy(s + la, b, lb + 1);
+    return s;
+}
+
+/* tng_jol_len counts characters (UTF-8 code points), not bytes. */
+static inline int64_t tng_jol_len(const char *s) {
+    int64_t n = 0;
+    for (; *s; s++) if ((*s & 0xC0) != 0x80) n++;
+    return n;
+}
+
+/* --- Program arguments and time --- */
+
+static inline int64_t tng_argi(int64_t i, int64_t def) {
+    if (i < 0 || i >= tng_argc) return def;
+    char *end;
+    long long v = strtoll(tng_argv[i], &end, 10);
+    return (*tng_argv[i] && !*end) ? (int64_t)v : def;
+}
+
+static inline double tng_argf(int64_t i, double def) {
+    if (i < 0 || i >= tng_argc) return def;
+    char *end;
+    double v = strtod(tng_argv[i], &end);
+    return (*tng_argv[i] && !*end) ? v : def;
+}
+
+static inline int64_t tng_now_ns(void) {
+    struct timespec ts;
+    clock_gettime(CLOCK_MONOTONIC, &ts);
+    return (int64_t)ts.tv_sec * 1000000000LL + ts.tv_nsec;
+}
+
+static inline int64_t tng_now_ms(void) { return tng_now_ns() / 1000000; }
+
+/* --- Arenas: arrays are bump-allocated from chunks, never one by one. ---
+
+   The compiler's escape analysis puts the arrays that outlive the function
+   making them in tng_heap, a region that lives as long as the program, and
+   the others in tng_scratch. A function marks tng_scratch on entry and
+   releases it as it returns, and a loop whose arrays die with the
+   iteration releases it every time round, so that hot loops do not
+   allocate from the C heap. A loop that drops the arrays the functions
+   it calls return releases tng_heap the same way. Released chunks are
+   kept for reuse.
+
+   In programs with parallel loops or tasks, which define TNG_THREADS,
+   every thread has arenas of its own. */
+
+typedef struct tng_chunk {
+    struct tng_chunk *prev;
+    char *data; /* TNG_ALIGN-aligned */
+    size_t cap, used;
+} tng_chunk;
+
+typedef struct { tng_chunk *top, *spare; } tng_arena;
+typedef struct { tng_chunk *top; size_t used; } tng_mark;
+
+#ifdef TNG_THREADS
+#define TNG_THREAD_LOCAL _Thread_local
+#else
+#define TNG_THREAD_LOCAL
+#endif
+
+static TNG_THREAD_LOCAL tng_arena tng_heap;    /* arrays that outlive the function making them */
+static TNG_THREAD_LOCAL tng_arena tng_scratch; /* arrays that do not */
+
+#define TNG_CHUNK_SIZE ((size_t)64 << 10)
+
+/* Array data is aligned for the widest vector loads, which kernels tell
+   the C compiler with TNG_ASSUME_ALIGNED. */
+#define TNG_ALIGN 32
+#if defined(__GNUC__) || defined(__clang__)
+#define TNG_ASSUME_ALIGNED(p) __builtin_assume_aligned(p, TNG_ALIGN)
+#else
+#define TNG_ASSUME_ALIGNED(p) (p)
+#endif
+
+static inline void *tng_arena_alloc(tng_arena *a, size_t n) {
+    n = (n + TNG_ALIGN - 1) & ~(size_t)(TNG_ALIGN - 1);
+    tng_chunk *c = a->top;
+    if (!c || c->cap - c->used < n) {
+        c = a->spare;
+        if (c && c->cap >= n) {
+            a->spare = NULL;
+        } else {
+            size_t cap = n > TNG_CHUNK_SIZE ? n : TNG_CHUNK_SIZE;
+            c = tng_alloc(sizeof(tng_chunk) + TNG_ALIGN + cap);
+            c->data = (char *)(((uintptr_t)(c + 1) + TNG_ALIGN - 1) & ~(uintptr_t)(TNG_ALIGN - 1));
+            c->cap = cap;
+        }
+        c->prev = a->top;
+        c->used = 0;
+        a->top = c;
+    }
+    void *p = c->data + c->used;
+    c->used += n;
+    return p;
+}
+
+static inline tng_mark tng_arena_mark(tng_arena *a) {
+    tng_mark m = { a->top, a->top ? a->top->used : 0 };
+    return m;
+}
+
+/* tng_arena_release frees everything allocated in a since m was taken. */
+static inline void tng_arena_release(tng_arena *a, tng_mark m) {
+    while (a->top != m.top) {
+        tng_chunk *c = a->top;
+        a->top = c->prev;
+        if (!a->spare || a->spare->cap < c->cap) {
+            free(a->spare);
+            a->spare = c;
+        } else {
+            free(c);
+        }
+    }
+    if (m.top) m.top->used = m.used;
+}
+
+/* --- Arrays: a length, a capacity and a pointer, like a Go slice. ---
+
+   Every function making an array takes the arena to allocate it in. */
+
+#define TNG_AT(a, i) ((a).data[(i)])
+
+/* TNG_OVERLAP reports whether two arrays of the same type share memory. */
+#define TNG_END(a) ((uintptr_t)(a).data + (uintptr_t)(a).len * sizeof *(a).data)
+#define TNG_OVERLAP(a, b) ((uintptr_t)(a).data < TNG_END(b) && (uintptr_t)(b).data < TNG_END(a))
+
+#define TNG_ARRAY(T, N, SHOW)                                                   \
+    typedef struct { int64_t len, cap; T *data; } N;                            \
+    static inline N N##_make(tng_arena *ar, int64_t n) {                        \
+        if (n < 0) tng_panic("tenge", TNG_MSG_NEGATIVE_LENGTH);               \
+        N a = { n, n, tng_arena_alloc(ar, (size_t)n * sizeof(T)) };             \
+        memset(a.data, 0, (size_t)n * sizeof(T));                               \
+        return a;                                                               \
+    }                                                                           \
+    static inline N N##_of(tng_arena *ar, int64_t n, const T *src) {            \
+        N a = N##_make(ar, n);                                                  \
+        if (n) memcpy(a.data, src, (size_t)n * sizeof(T));                      \
+        return a;                                                               \
+    }                                                                           \
+    static inline N N##_push(tng_arena *ar, N a, T v) {                         \
+        if (a.len == a.cap) {                                                   \
+            int64_t cap = a.cap ? 2 * a.cap : 4;                                \
+            T *data = tng_arena_alloc(ar, (size_t)cap * sizeof(T));             \
+            if (a.len) memcpy(data, a.data, (size_t)a.len * sizeof(T));         \
+            a.data = data;                                                      \
+            a.cap = cap;                                                        \
+        }                                                                       \
+        a.data[a.len++] = v;                                                    \
+        return a;                                                               \
+    }                                                                           \
+    static inline int N##_cmp(const void *x, const void *y) {                   \
+        T l = *(const T *)x, r = *(const T *)y;                                 \
+        return (l > r) - (l < r);                                               \
+    }                                                                           \
+    static inline N N##_sort(tng_arena *ar, N a) {                              \
+        N b = N##_of(ar, a.len, a.data);                                        \
+        qsort(b.data, (size_t)b.len, sizeof(T), N##_cmp);                       \
+        return b;                                                               \
+    }                                                                           \
+    static inline void N##_show(N a) {                                          \
+        putchar('[');                                                           \
+        for (int64_t i = 0; i < a.len; i++) {                                   \
+            if (i) fputs(", ", stdout);                                         \
+            SHOW(a.data[i]);                                                    \
+        }                                                                       \
+        putchar(']');                                                           \
+    }
+
+TNG_ARRAY(int64_t, tng_arr_i64, tng_show_i64)
+TNG_ARRAY(int32_t, tng_arr_i32, tng_show_i64)
+TNG_ARRAY(uint64_t, tng_arr_u64, tng_show_u64)
+TNG_ARRAY(double, tng_arr_f64, tng_show_f64)
+TNG_ARRAY(bool, tng_arr_bool, tng_show_bool)
+
+` + VectorC + `
+/* --- SIMD: module simd/f64x4, whose calls compile to these functions. --- */
+
+static inline int64_t tng_lanes(int64_t n, tng_arr_f64 a) { return a.len < n ? a.len : n; }
+
+/* TNG_SIMD_LOOP runs its body for i, i+4, ... while four elements are
+   left before n. */
+#ifdef TNG_SIMD
+#define TNG_SIMD_LOOP(i, n, ...) for (; i + 4 <= n; i += 4) __VA_ARGS__
+#else
+#define TNG_SIMD_LOOP(i, n, ...)
+#endif
+
+static inline void tng_f64x4_add(tng_arr_f64 dst, tng_arr_f64 a, tng_arr_f64 b) {
+    int64_t n = tng_lanes(tng_lanes(dst.len, a), b), i = 0;
+    TNG_SIMD_LOOP(i, n, {
+        tng_f64x4 x, y;
+        TNG_LOAD4(x, a.data + i);
+        TNG_LOAD4(y, b.data + i);
+        x += y;
+        TNG_STORE4(dst.data + i, x);
+    })
+    for (; i < n; i++) dst.data[i] = a.data[i] + b.data[i];
+}
+
+static inline void tng_f64x4_mul(tng_arr_f64 dst, tng_arr_f64 a, tng_arr_f64 b) {
+    int64_t n = tng_lanes(tng_lanes(dst.len, a), b), i = 0;
+    TNG_SIMD_LOOP(i, n, {
+        tng_f64x4 x, y;
+        TNG_LOAD4(x, a.data + i);
+        TNG_LOAD4(y, b.data + i);
+        x *= y;
+        TNG_STORE4(dst.data + i, x);
+    })
+    for (; i < n; i++) dst.data[i] = a.data[i] * b.data[i];
+}
+
+static inline void tng_f64x4_fma(tng_arr_f64 dst, tng_arr_f64 a, tng_arr_f64 b, tng_arr_f64 c) {
+    int64_t n = tng_lanes(tng_lanes(tng_lanes(dst.len, a), b), c), i = 0;
+    TNG_SIMD_LOOP(i, n, {
+        tng_f64x4 x, y, z;
+        TNG_LOAD4(x, a.data + i);
+        TNG_LOAD4(y, b.data + i);
+        TNG_LOAD4(z, c.data + i);
+        x = x * y + z;
+        TNG_STORE4(dst.data + i, x);
+    })
+    for (; i < n; i++) dst.data[i] = a.data[i] * b.data[i] + c.data[i];
+}
+
+static inline void tng_f64x4_exp(tng_arr_f64 dst, tng_arr_f64 a) {
+    int64_t n = tng_lanes(dst.len, a), i = 0;
+    TNG_SIMD_LOOP(i, n, { tng_exp4(dst.data + i, a.data + i); })
+    for (; i < n; i++) dst.data[i] = tng_exp1(a.data[i]);
+}
+
+/* --- Bounds checks, emitted by the debug and hardened profiles. --- */
+
+static inline int64_t tng_index(int64_t i, int64_t len, const char *pos) {
+    if (i < 0 || i >= len) {
+        char msg[160];
+        snprintf(msg, sizeof msg, TNG_MSG_OUT_OF_RANGE, (long long)i, (long long)len);
+        tng_panic(pos, msg);
+    }
+    return i;
+}
+
+/* --- Integer division, in every profile. ---
+
+   Integer arithmetic wraps around, as in the interpreter and the VM (the
+   C compiler is given -fwrapv), so MIN / -1 is MIN and MIN % -1 is 0,
+   which C leaves undefined and x86 traps on. Division by zero stops the
+   program. */
+
+#define TNG_DIV(T, S, WRAPS)                                                    \
+    static inline T tng_div_##S(T a, T b, const char *pos) {                    \
+        if (b == 0) tng_panic(pos, TNG_MSG_DIVISION_BY_ZERO);                   \
+        return (WRAPS) ? a : a / b;                                             \
+    }                                                                           \
+    static inline T tng_mod_##S(T a, T b, const char *pos) {                    \
+        if (b == 0) tng_panic(pos, TNG_MSG_DIVISION_BY_ZERO);                   \
+        return (WRAPS) ? 0 : a % b;                                             \
+    }
+
+TNG_DIV(int64_t, i64, a == INT64_MIN && b == -1)
+TNG_DIV(int32_t, i32, a == INT32_MIN && b == -1)
+TNG_DIV(uint64_t, u64, 0)
+
+/* --- PGO: hints from a profile, emitted by tenge build -pgo=use. ---
+
+   TNG_EXPECT(c, p) is condition c, which held with probability p when the
+   profile was recorded; the compiler lays out the likely path first. */
+
+#if defined(__has_builtin)
+#if __has_builtin(__builtin_expect_with_probability)
+#define TNG_EXPECT(c, p) __builtin_expect_with_probability(!!(c), 1, (p))
+#endif
+#endif
+#if !defined(TNG_EXPECT) && (defined(__GNUC__) || defined(__clang__))
+#define TNG_EXPECT(c, p) __builtin_expect(!!(c), (p) >= 0.5)
+#endif
+#ifndef TNG_EXPECT
+#define TNG_EXPECT(c, p) (c)
+#endif
+
+#if defined(__GNUC__) || defined(__clang__)
+#define TNG_HOT __attribute__((hot))
+#define TNG_COLD __attribute__((cold, noinline))
+#else
+#define TNG_HOT
+#define TNG_COLD
+#endif
+`
@@ -0,0 +1,74 @@
+// FILE: internal/aotminic/profile.go
+
+package aotminic
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/DauletBai/tenge/internal/lang/msg"
+)
+
+// Profile selects how generated C is checked and compiled. Arithmetic
+// means the same in every profile, as in the interpreter and the VM:
+// integers wrap around and division by zero is an error.
+type Profile int
+
+const (
+	// Release emits no runtime checks and optimises for speed. Deadlocks
+	// are not detected either: a release binary whose tasks all wait on
+	// channels hangs.
+	Release Profile = iota
+	// Debug emits bounds checks and deadlock detection and compiles
+	// without optimisation for the debugger.
+	Debug
+	// Hardened adds the checks of Debug to AddressSanitizer, UBSan and
+	// stack protection.
+	Hardened
+)
+
+var profileNames = [...]string{
+	Release:  "release",
+	Debug:    "debug",
+	Hardened: "hardened",
+}
+
+func (p Profile) String() string {
+	if p >= 0 && int(p) < len(profileNames) {
+		return profileNames[p]
+	}
+	return fmt.Sprintf("Profile(%d)", int(p))
+}
+
+// ParseProfile returns the profile called name.
+func ParseProfile(name string) (Profile, error) {
+	for p, n := range profileNames {
+		if n == name {
+			return Profile(p), nil
+		}
+	}
+	return Release, errors.New(msg.Sprintf(msg.UnknownProfile, name))
+}
+
+// Checks reports whether the emitter inserts runtime checks.
+func (p Profile) Checks() bool {
+	return p != Release
+}
+
+// CFlags returns the C compiler flags of the profile. The Makefile keeps a
+// copy of these as AOT_CFLAGS_<profile>. -ffp-contract=off keeps the
+// compiler from fusing multiplications and additions, which would round
+// differently from the interpreter.
+func (p Profile) CFlags() []string {
+	switch p {
+	case Debug:
+		return []string{"-O0", "-g", "-fwrapv", "-ffp-contract=off"}
+	case Hardened:
+		return []string{
+			"-O1", "-g", "-fno-omit-frame-pointer",
+			"-fsanitize=address,undefined", "-fno-sanitize-recover=undefined",
+			"-fwrapv", "-fstack-protector-strong", "-ffp-contract=off",
+		}
+	}
+	return []string{"-O3", "-march=native", "-fwrapv", "-ffp-contract=off"}
+}
@@ -0,0 +1,45 @@
+// FILE: internal/aotminic/sourcemap.go
+
+package aotminic
+
+import "encoding/json"
+
+// SourceMap relates lines of a generated C file to the tenge source they
+// were produced from. It is written as JSON next to the C file:
+//
+//	{"version":1,"file":"fib.c","source":"fib.tng",
+//	 "mappings":[{"c_line":190,"line":3,"column":5}, ...]}
+type SourceMap struct {
+	Version  int       `json:"version"`
+	File     string    `json:"file"`   // the generated C file
+	Source   string    `json:"source"` // the main tenge file
+	Mappings []Mapping `json:"mappings"`
+}
+
+// Mapping records that the C code starting at CLine was generated from the
+// statement starting at Line:Column. Entries are ordered by CLine; a C line
+// belongs to the last entry at or before it. Line 0 starts a range of code
+// that only exists in C. Source is set for code from an imported module.
+type Mapping struct {
+	CLine  int    `json:"c_line"`
+	Source string `json:"source,omitempty"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// Lookup returns the tenge position of a C line, or ok == false when the
+// line does not come from tenge code.
+func (m *SourceMap) Lookup(cLine int) (line, column int, ok bool) {
+	for _, e := range m.Mappings {
+		if e.CLine > cLine {
+			break
+		}
+		line, column = e.Line, e.Column
+	}
+	return line, column, line != 0
+}
+
+// JSON returns the indented JSON form of the map.
+func (m *SourceMap) JSON() ([]byte, error) {
+	return json.MarshalIndent(m, "", "  ")
+}
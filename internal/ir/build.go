@@ -0,0 +1,1038 @@
+// FILE: internal/ir/build.go
+
+package ir
+
+import (
+	"math"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/diag"
+	"github.com/DauletBai/tenge/internal/lang/module"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/token"
+	"github.com/DauletBai/tenge/internal/lang/types"
+)
+
+// Build lowers prog to the IR. info must come from a successful type
+// check of prog. The IR covers what the compiled backends do: direct
+// calls of top-level functions, with one instance per type arguments of
+// a generic function. Other constructs are reported as errors.
+//
+// Variables become SSA values as the code is lowered, with the method of
+// Braun et al., "Simple and Efficient Construction of Static Single
+// Assignment Form": a read looks for the last write in its block, then in
+// its predecessors, placing phis where they join. Variables whose address
+// is taken live in cells instead.
+func Build(prog *module.Program, info *types.Info) (*Program, diag.List) {
+	b := &builder{
+		info:      info,
+		prog:      &Program{},
+		seen:      make(map[string]bool),
+		globals:   make(map[*ast.Identifier]*Global),
+		addressed: make(map[*ast.Identifier]bool),
+		funcs:     make(map[types.FuncKey]*Func),
+		decls:     make(map[*ast.Identifier]*ast.AtqarmLiteral),
+		tops:      make(map[*ast.AtqarmLiteral]bool),
+	}
+	b.program(prog)
+	return b.prog, b.errors
+}
+
+type builder struct {
+	info   *types.Info
+	prog   *Program
+	errors diag.List
+	seen   map[string]bool
+
+	globals   map[*ast.Identifier]*Global            // top-level variables by declaring identifier
+	addressed map[*ast.Identifier]bool               // variables whose address is taken
+	funcs     map[types.FuncKey]*Func                // lowered and queued functions
+	decls     map[*ast.Identifier]*ast.AtqarmLiteral // top-level functions by name
+	tops      map[*ast.AtqarmLiteral]bool            // top-level function literals
+	queue     []pending                              // functions left to lower
+
+	fs *funcState // function being lowered
+}
+
+type pending struct {
+	f     *Func
+	lit   *ast.AtqarmLiteral
+	sig   *types.Signature
+	subst map[*types.TypeParam]types.Type
+}
+
+// funcState holds the variables of the function being lowered.
+type funcState struct {
+	f      *Func
+	b      *Block                                // block being filled
+	vars   map[*ast.Identifier]types.Type        // locals held in values, by declaring identifier
+	defs   map[*ast.Identifier]map[*Block]*Value // the value of each of them at the end of a block
+	cells  map[*ast.Identifier]*Value            // locals whose address is taken
+	sealed map[*Block]bool                       // blocks whose predecessors are all known
+	phis   map[*Block][]incomplete               // phis of unsealed blocks, waiting for their arguments
+	result types.Type                            // nil in the top-level code of a module
+	subst  map[*types.TypeParam]types.Type
+	pos    token.Token // position of the code being lowered
+}
+
+type incomplete struct {
+	id  *ast.Identifier
+	phi *Value
+}
+
+// errorf reports code at the position of node at, naming the IR as the
+// representation that has no form for it. A message is only reported once.
+func (b *builder) errorf(at ast.Node, code msg.Code, args ...interface{}) {
+	args = append(args, msg.Text(msg.InIR))
+	d := &diag.Diagnostic{Code: code, Span: diag.At(types.Pos(at)), Message: msg.Sprintf(code, args...)}
+	if !b.seen[d.Error()] {
+		b.seen[d.Error()] = true
+		b.errors = append(b.errors, d)
+	}
+}
+
+// --- Declarations ---
+
+func (b *builder) program(prog *module.Program) {
+	for _, m := range prog.Modules {
+		ast.Inspect(m.Program, func(n ast.Node) bool {
+			if p, ok := n.(*ast.PrefixExpression); ok && p.Operator == "&" {
+				if id, ok := p.Right.(*ast.Identifier); ok {
+					if sym := b.info.Uses[id]; sym != nil && sym.Decl != nil {
+						b.addressed[sym.Decl] = true
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	for _, m := range prog.Modules {
+		for _, s := range m.Program.Statements {
+			name, lit := ast.FuncDecl(s)
+			if lit == nil {
+				b.global(m, s)
+				continue
+			}
+			b.decls[name] = lit
+			b.tops[lit] = true
+			if len(lit.TypeParams) > 0 {
+				continue
+			}
+			f := b.function(types.FuncKey{Lit: lit}, qualified(m, name.Value), lit, b.info.Funcs[lit], nil)
+			if m == prog.Main() && name.Value == "main" && len(lit.Parameters) == 0 {
+				b.prog.Main = f
+			}
+		}
+	}
+	for _, m := range prog.Modules {
+		b.prog.Init = append(b.prog.Init, b.topLevel(m))
+	}
+	for len(b.queue) > 0 {
+		p := b.queue[0]
+		b.queue = b.queue[1:]
+		b.body(p)
+	}
+}
+
+// qualified returns the name of a top-level declaration of m as listings
+// show it.
+func qualified(m *module.Module, name string) string {
+	if m.Path == "" {
+		return name
+	}
+	return m.Path + "." + name
+}
+
+func (b *builder) global(m *module.Module, s ast.Statement) {
+	var name *ast.Identifier
+	switch s := s.(type) {
+	case *ast.JasaStatement:
+		name = s.Name
+	case *ast.BekitStatement:
+		name = s.Name
+	default:
+		return
+	}
+	g := &Global{Name: qualified(m, name.Value), Type: b.symType(name)}
+	b.prog.Globals = append(b.prog.Globals, g)
+	b.globals[name] = g
+}
+
+// function returns the function for key, queueing it for lowering the
+// first time.
+func (b *builder) function(key types.FuncKey, name string, lit *ast.AtqarmLiteral, sig *types.Signature, subst map[*types.TypeParam]types.Type) *Func {
+	if f, ok := b.funcs[key]; ok {
+		return f
+	}
+	f := &Func{Name: name, File: lit.Token.File, Result: norm(sig.Result)}
+	b.prog.Funcs = append(b.prog.Funcs, f)
+	b.funcs[key] = f
+	b.queue = append(b.queue, pending{f: f, lit: lit, sig: sig, subst: subst})
+	return f
+}
+
+// instance returns the instance of the generic function called by inst
+// in the function being lowered.
+func (b *builder) instance(inst *types.Instance, at ast.Node) *Func {
+	generic := b.info.Funcs[inst.Func]
+	if generic == nil || !b.tops[inst.Func] {
+		b.errorf(at, msg.BackendDirectCall)
+		return nil
+	}
+	key, subst := b.info.Instantiate(inst, b.fs.subst)
+	name := inst.Func.Name + "[" + key.Args + "]"
+	return b.function(key, name, inst.Func, types.Subst(generic, subst).(*types.Signature), subst)
+}
+
+func (b *builder) symType(id *ast.Identifier) types.Type {
+	if sym := b.info.Defs[id]; sym != nil && sym.Type != nil {
+		if b.fs != nil {
+			return norm(types.Subst(sym.Type, b.fs.subst))
+		}
+		return norm(sym.Type)
+	}
+	return norm(types.Typ[types.Any])
+}
+
+// begin starts lowering f.
+func (b *builder) begin(f *Func, result types.Type, subst map[*types.TypeParam]types.Type) {
+	b.fs = &funcState{
+		f:      f,
+		vars:   make(map[*ast.Identifier]types.Type),
+		defs:   make(map[*ast.Identifier]map[*Block]*Value),
+		cells:  make(map[*ast.Identifier]*Value),
+		sealed: make(map[*Block]bool),
+		phis:   make(map[*Block][]incomplete),
+		result: result,
+		subst:  subst,
+	}
+	entry := f.NewBlock()
+	b.seal(entry)
+	b.fs.b = entry
+}
+
+// end finishes the function being lowered: falling off its end returns,
+// and the blocks and phis nothing reaches are dropped.
+func (b *builder) end() {
+	f := b.fs.f
+	if b.open() {
+		b.fs.b.Kind = Ret
+	}
+	f.removeUnreachable()
+	f.simplifyPhis()
+	f.layout()
+	f.renumber()
+	b.fs = nil
+}
+
+func (b *builder) body(p pending) {
+	b.begin(p.f, norm(p.sig.Result), p.subst)
+	b.at(p.lit.Token)
+	for i, param := range p.lit.Parameters {
+		t := norm(p.sig.Params[i])
+		v := p.f.newValue(OpParam, t, nil, int64(i), param.Name.Token)
+		p.f.Params = append(p.f.Params, v)
+		b.bind(param.Name, t, v)
+	}
+	b.statements(p.lit.Body.Statements)
+	b.end()
+}
+
+// topLevel lowers the top-level statements of m into a function. The
+// variables they declare are the globals.
+func (b *builder) topLevel(m *module.Module) *Func {
+	name := "<init>"
+	if m.Path != "" {
+		name = "<init " + m.Path + ">"
+	}
+	f := &Func{Name: name, File: m.File, Result: types.Typ[types.Void]}
+	b.prog.Funcs = append(b.prog.Funcs, f)
+	b.begin(f, nil, nil)
+	for _, s := range m.Program.Statements {
+		if _, lit := ast.FuncDecl(s); lit != nil {
+			continue
+		}
+		var name *ast.Identifier
+		var value ast.Expression
+		switch s := s.(type) {
+		case *ast.JasaStatement:
+			name, value = s.Name, s.Value
+		case *ast.BekitStatement:
+			name, value = s.Name, s.Value
+		default:
+			b.stmt(s)
+			continue
+		}
+		b.at(types.Pos(s))
+		g := b.globals[name]
+		var v *Value
+		if value == nil {
+			v = b.zero(g.Type)
+		} else {
+			v = b.convert(value, g.Type)
+		}
+		b.emit(OpSetGlobal, types.Typ[types.Void], []*Value{v}, g)
+	}
+	b.end()
+	return f
+}
+
+// --- Blocks and values ---
+
+// at sets the source position of the values emitted next.
+func (b *builder) at(tok token.Token) {
+	if tok.Line != 0 {
+		b.fs.pos = tok
+	}
+}
+
+// emit appends a value to the current block.
+func (b *builder) emit(op Op, t types.Type, args []*Value, aux interface{}) *Value {
+	return b.fs.b.NewValue(op, t, args, aux, b.fs.pos)
+}
+
+func (b *builder) constant(t types.Type, c interface{}) *Value {
+	return b.emit(OpConst, t, nil, c)
+}
+
+// zero returns the zero value of t.
+func (b *builder) zero(t types.Type) *Value {
+	op, args, aux := zeroOf(t)
+	return b.emit(op, t, args, aux)
+}
+
+// open reports whether the current block has no jump or return yet.
+func (b *builder) open() bool {
+	cur := b.fs.b
+	return cur.Kind == Plain && len(cur.Succs) == 0
+}
+
+// start continues lowering in block to.
+func (b *builder) start(to *Block) {
+	b.fs.b = to
+}
+
+// jump ends the current block with a jump to to.
+func (b *builder) jump(to *Block) {
+	b.fs.b.addEdge(to)
+}
+
+// ret ends the current block with a return of v, which may be nil. Code
+// after it goes to a block nothing jumps to.
+func (b *builder) ret(v *Value) {
+	cur := b.fs.b
+	cur.Kind, cur.Control = Ret, v
+	dead := b.fs.f.NewBlock()
+	b.seal(dead)
+	b.start(dead)
+}
+
+// --- Variables ---
+
+// bind declares the local id holding v.
+func (b *builder) bind(id *ast.Identifier, t types.Type, v *Value) {
+	if b.addressed[id] {
+		b.fs.cells[id] = b.emit(OpAlloc, &types.Pointer{Elem: t}, []*Value{v}, nil)
+		return
+	}
+	b.fs.vars[id] = t
+	b.write(id, b.fs.b, v)
+}
+
+func (b *builder) write(id *ast.Identifier, blk *Block, v *Value) {
+	defs := b.fs.defs[id]
+	if defs == nil {
+		defs = make(map[*Block]*Value)
+		b.fs.defs[id] = defs
+	}
+	defs[blk] = v
+}
+
+// read returns the value of the local id at the end of blk.
+func (b *builder) read(id *ast.Identifier, blk *Block) *Value {
+	fs := b.fs
+	if v, ok := fs.defs[id][blk]; ok {
+		return v
+	}
+	t := fs.vars[id]
+	var v *Value
+	switch {
+	case !fs.sealed[blk]:
+		v = blk.insertPhi(t, fs.pos)
+		fs.phis[blk] = append(fs.phis[blk], incomplete{id: id, phi: v})
+	case len(blk.Preds) == 0:
+		// Only in code nothing reaches.
+		op, args, aux := zeroOf(t)
+		v = blk.NewValue(op, t, args, aux, fs.pos)
+	case len(blk.Preds) == 1:
+		v = b.read(id, blk.Preds[0])
+	default:
+		v = blk.insertPhi(t, fs.pos)
+		// The phi is the value while its arguments are read, which ends
+		// the search around a loop.
+		b.write(id, blk, v)
+		b.phiArgs(id, v)
+	}
+	b.write(id, blk, v)
+	return v
+}
+
+func (b *builder) phiArgs(id *ast.Identifier, phi *Value) {
+	for _, p := range phi.Block.Preds {
+		phi.Args = append(phi.Args, b.read(id, p))
+	}
+}
+
+// seal records that every predecessor of blk is known, completing the
+// phis that were waiting for them.
+func (b *builder) seal(blk *Block) {
+	for _, p := range b.fs.phis[blk] {
+		b.phiArgs(p.id, p.phi)
+	}
+	delete(b.fs.phis, blk)
+	b.fs.sealed[blk] = true
+}
+
+// --- Statements ---
+
+func (b *builder) statements(stmts []ast.Statement) {
+	for _, s := range stmts {
+		b.stmt(s)
+	}
+}
+
+func (b *builder) stmt(s ast.Statement) {
+	switch s := s.(type) {
+	case *ast.JasaStatement:
+		b.local(s, s.Name, s.Value)
+	case *ast.BekitStatement:
+		b.local(s, s.Name, s.Value)
+	case *ast.QaıtarStatement:
+		b.at(s.Token)
+		b.qaıtar(s)
+	case *ast.ExpressionStatement:
+		if eg, ok := s.Expression.(*ast.EgerExpression); ok {
+			b.at(eg.Token)
+			b.eger(eg)
+		} else {
+			b.at(types.Pos(s))
+			b.effect(s.Expression)
+		}
+	case *ast.AssignStatement:
+		b.at(types.Pos(s))
+		b.assign(s)
+	case *ast.BlockStatement:
+		b.statements(s.Statements)
+	case *ast.AzirsheStatement:
+		b.at(s.Token)
+		b.azirshe(s)
+	case *ast.KutStatement, *ast.MindetStatement, *ast.TandaStatement:
+		b.errorf(s, msg.BackendUnsupported, s.TokenLiteral())
+	}
+}
+
+func (b *builder) local(s ast.Statement, name *ast.Identifier, value ast.Expression) {
+	if _, ok := value.(*ast.AtqarmLiteral); ok {
+		b.errorf(s, msg.BackendNested, name.Value)
+		return
+	}
+	b.at(types.Pos(s))
+	t := b.symType(name)
+	var v *Value
+	if value == nil {
+		v = b.zero(t)
+	} else {
+		v = b.convert(value, t)
+	}
+	b.bind(name, t, v)
+}
+
+func (b *builder) qaıtar(s *ast.QaıtarStatement) {
+	result := b.fs.result
+	switch {
+	case s.ReturnValue == nil || result == nil:
+		b.ret(nil)
+	case isVoid(result):
+		b.effect(s.ReturnValue)
+		b.ret(nil)
+	default:
+		b.ret(b.convert(s.ReturnValue, result))
+	}
+}
+
+// effect lowers an expression evaluated only for its side effects.
+func (b *builder) effect(x ast.Expression) {
+	if call, ok := x.(*ast.CallExpression); ok {
+		b.call(call)
+		return
+	}
+	if _, ok := x.(*ast.QatarlasExpression); !ok && isVoid(b.typeOf(x)) {
+		b.errorf(x, msg.BackendEvaluate, x)
+		return
+	}
+	b.value(x)
+}
+
+func (b *builder) assign(s *ast.AssignStatement) {
+	void := types.Typ[types.Void]
+	switch target := s.Target.(type) {
+	case *ast.Identifier:
+		sym := b.info.Uses[target]
+		if sym == nil || sym.Decl == nil {
+			b.errorf(target, msg.BackendAssign, target)
+			return
+		}
+		if t, ok := b.fs.vars[sym.Decl]; ok {
+			b.write(sym.Decl, b.fs.b, b.convert(s.Value, t))
+		} else if cell, ok := b.fs.cells[sym.Decl]; ok {
+			b.emit(OpStore, void, []*Value{cell, b.convert(s.Value, cell.Type.(*types.Pointer).Elem)}, nil)
+		} else if g, ok := b.globals[sym.Decl]; ok {
+			b.emit(OpSetGlobal, void, []*Value{b.convert(s.Value, g.Type)}, g)
+		} else {
+			b.errorf(target, msg.BackendAssign, target)
+		}
+	case *ast.IndexExpression:
+		arr, ok := b.typeOf(target.Left).(*types.Array)
+		if !ok {
+			b.errorf(target, msg.BackendAssign, target)
+			return
+		}
+		a := b.value(target.Left)
+		i := b.convert(target.Index, types.Typ[types.San])
+		v := b.convert(s.Value, arr.Elem)
+		b.at(target.Token)
+		b.emit(OpSetIndex, void, []*Value{a, i, v}, nil)
+	case *ast.PrefixExpression:
+		p, ok := b.typeOf(target.Right).(*types.Pointer)
+		if target.Operator != "*" || !ok {
+			b.errorf(target, msg.BackendAssign, target)
+			return
+		}
+		ptr := b.value(target.Right)
+		v := b.convert(s.Value, p.Elem)
+		b.at(target.Token)
+		b.emit(OpStore, void, []*Value{ptr, v}, nil)
+	default:
+		b.errorf(target, msg.BackendAssign, target)
+	}
+}
+
+// azirshe lowers a loop. The block before it jumps only to the header, so
+// it is where invariant code can go.
+func (b *builder) azirshe(s *ast.AzirsheStatement) {
+	f := b.fs.f
+	header := f.NewBlock()
+	b.jump(header)
+	b.start(header)
+	body, exit := f.NewBlock(), f.NewBlock()
+	b.branch(s.Condition, body, exit)
+	b.seal(body)
+	b.start(body)
+	b.statements(s.Body.Statements)
+	b.jump(header)
+	b.seal(header)
+	b.seal(exit)
+	b.start(exit)
+}
+
+func (b *builder) eger(eg *ast.EgerExpression) {
+	f := b.fs.f
+	then, join := f.NewBlock(), f.NewBlock()
+	els := join
+	if eg.Alternative != nil {
+		els = f.NewBlock()
+	}
+	b.branch(eg.Condition, then, els)
+	b.seal(then)
+	b.start(then)
+	b.statements(eg.Consequence.Statements)
+	b.jump(join)
+	if eg.Alternative != nil {
+		b.seal(els)
+		b.start(els)
+		b.statements(eg.Alternative.Statements)
+		b.jump(join)
+	}
+	b.seal(join)
+	b.start(join)
+}
+
+// branch ends the current block with jumps to t when condition x holds
+// and to f when it does not. && and || jump as soon as they know.
+func (b *builder) branch(x ast.Expression, t, f *Block) {
+	switch x := x.(type) {
+	case *ast.AqıqatLiteral:
+		if x.Value {
+			b.jump(t)
+		} else {
+			b.jump(f)
+		}
+		return
+	case *ast.PrefixExpression:
+		if x.Operator == "!" {
+			b.branch(x.Right, f, t)
+			return
+		}
+	case *ast.InfixExpression:
+		if x.Operator == "&&" || x.Operator == "||" {
+			mid := b.fs.f.NewBlock()
+			if x.Operator == "&&" {
+				b.branch(x.Left, mid, f)
+			} else {
+				b.branch(x.Left, t, mid)
+			}
+			b.seal(mid)
+			b.start(mid)
+			b.branch(x.Right, t, f)
+			return
+		}
+	}
+	c := b.convert(x, types.Typ[types.Aqıqat])
+	cur := b.fs.b
+	cur.Kind, cur.Control = If, c
+	cur.addEdge(t)
+	cur.addEdge(f)
+}
+
+// --- Expressions ---
+
+func (b *builder) typeOf(x ast.Expression) types.Type {
+	if t := b.info.TypeOf(x); t != nil {
+		return norm(types.Subst(t, b.fs.subst))
+	}
+	return norm(types.Typ[types.Any])
+}
+
+// value lowers x to a value of its type.
+func (b *builder) value(x ast.Expression) *Value {
+	switch x := x.(type) {
+	case *ast.Identifier:
+		return b.ident(x)
+	case *ast.SelectorExpression:
+		return b.ident(x.Sel)
+	case *ast.SanLiteral, *ast.AqshaLiteral:
+		return b.literal(x, b.typeOf(x))
+	case *ast.JolLiteral:
+		return b.constant(types.Typ[types.Jol], x.Value)
+	case *ast.AqıqatLiteral:
+		return b.constant(types.Typ[types.Aqıqat], boolConst(x.Value))
+	case *ast.JyimLiteral:
+		return b.jyimLiteral(x)
+	case *ast.PrefixExpression:
+		return b.prefix(x)
+	case *ast.InfixExpression:
+		return b.infix(x)
+	case *ast.EgerExpression:
+		return b.egerValue(x)
+	case *ast.CallExpression:
+		return b.call(x)
+	case *ast.IndexExpression:
+		return b.index(x.Left, x.Index, x)
+	case *ast.AtqarmLiteral:
+		b.errorf(x, msg.BackendFuncLit)
+	case *ast.QatarlasExpression:
+		b.errorf(x, msg.BackendUnsupported, x.Token.Literal)
+	default:
+		b.errorf(x, msg.BackendExpression, x)
+	}
+	return b.zero(b.typeOf(x))
+}
+
+func boolConst(v bool) int64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// convert lowers x as a value of type t.
+func (b *builder) convert(x ast.Expression, t types.Type) *Value {
+	t = norm(t)
+	if ast.IsNumberLiteral(x) && (isInt(t) && !isBool(t) || isFloat(t) || isDecimal(t)) {
+		return b.literal(x, t)
+	}
+	return b.conv(b.value(x), t, x)
+}
+
+// conv converts v to type t.
+func (b *builder) conv(v *Value, t types.Type, at ast.Expression) *Value {
+	from := v.Type
+	switch {
+	case types.Identical(from, t):
+		return v
+	case isNumber(from) && isNumber(t):
+		return b.emit(OpConv, t, []*Value{v}, nil)
+	case isArray(from) && isArray(t):
+		// Arrays of any and of san share a representation.
+		return v
+	}
+	b.errorf(at, msg.BackendUseTyped, at, from, t)
+	return b.zero(t)
+}
+
+// isNumber reports whether t is a number type: an integer, f64 or aqsha.
+func isNumber(t types.Type) bool {
+	return isInt(t) && !isBool(t) || isFloat(t) || isDecimal(t)
+}
+
+// literal lowers the number literal x as a constant of type t.
+func (b *builder) literal(x ast.Expression, t types.Type) *Value {
+	d := ast.NumberValue(x)
+	switch {
+	case isDecimal(t):
+		return b.constant(t, d)
+	case isFloat(t):
+		f, _ := d.Float64()
+		return b.constant(t, f)
+	case !d.IsInteger() || !isInt(t) || isBool(t):
+		b.errorf(x, msg.BackendUse, x, t)
+		return b.zero(t)
+	}
+	return b.constant(t, wrap(t, d.IntPart()))
+}
+
+func (b *builder) ident(id *ast.Identifier) *Value {
+	sym := b.info.Uses[id]
+	if sym != nil && sym.Decl == nil && sym.Name == "PI" {
+		return b.constant(types.Typ[types.F64], math.Pi)
+	}
+	if sym == nil || sym.Decl == nil {
+		b.errorf(id, msg.BackendNotValue, id.Value)
+		return b.zero(b.typeOf(id))
+	}
+	if _, ok := b.fs.vars[sym.Decl]; ok {
+		return b.read(sym.Decl, b.fs.b)
+	}
+	if cell, ok := b.fs.cells[sym.Decl]; ok {
+		return b.emit(OpLoad, cell.Type.(*types.Pointer).Elem, []*Value{cell}, nil)
+	}
+	if g, ok := b.globals[sym.Decl]; ok {
+		return b.emit(OpGlobal, g.Type, nil, g)
+	}
+	if _, ok := b.decls[sym.Decl]; ok {
+		b.errorf(id, msg.BackendCallOnly, id.Value)
+	} else {
+		b.errorf(id, msg.BackendUnsupported, id.Value)
+	}
+	return b.zero(b.typeOf(id))
+}
+
+func (b *builder) jyimLiteral(x *ast.JyimLiteral) *Value {
+	t, _ := b.typeOf(x).(*types.Array)
+	if t == nil {
+		t = &types.Array{Elem: types.Typ[types.San]}
+	}
+	san := types.Typ[types.San]
+	arr := b.emit(OpMakeArray, t, []*Value{b.constant(san, int64(len(x.Elements)))}, nil)
+	for i, el := range x.Elements {
+		v := b.convert(el, t.Elem)
+		b.emit(OpSetIndex, types.Typ[types.Void], []*Value{arr, b.constant(san, int64(i)), v}, nil)
+	}
+	return arr
+}
+
+func (b *builder) prefix(x *ast.PrefixExpression) *Value {
+	t := b.typeOf(x)
+	switch x.Operator {
+	case "-":
+		if ast.IsNumberLiteral(x.Right) {
+			return b.literal(x, t)
+		}
+		if !isNumber(t) {
+			break
+		}
+		return b.emit(OpNeg, t, []*Value{b.convert(x.Right, t)}, nil)
+	case "!":
+		return b.emit(OpNot, types.Typ[types.Aqıqat], []*Value{b.convert(x.Right, types.Typ[types.Aqıqat])}, nil)
+	case "&":
+		id, _ := x.Right.(*ast.Identifier)
+		if id == nil {
+			break
+		}
+		sym := b.info.Uses[id]
+		if sym == nil {
+			break
+		}
+		if cell, ok := b.fs.cells[sym.Decl]; ok {
+			return cell
+		}
+		if g, ok := b.globals[sym.Decl]; ok {
+			return b.emit(OpAddr, &types.Pointer{Elem: g.Type}, nil, g)
+		}
+	case "*":
+		p := b.value(x.Right)
+		pt, ok := p.Type.(*types.Pointer)
+		if !ok {
+			break
+		}
+		b.at(x.Token)
+		return b.emit(OpLoad, pt.Elem, []*Value{p}, nil)
+	}
+	b.errorf(x, msg.BackendOperatorOn, x.Operator, x.Right)
+	return b.zero(t)
+}
+
+// operands returns the type both operands of a comparison are converted
+// to.
+func (b *builder) operands(x *ast.InfixExpression) types.Type {
+	return types.Comparand(x, b.typeOf(x.Left), b.typeOf(x.Right))
+}
+
+var arith = map[string]Op{
+	"+": OpAdd, "-": OpSub, "*": OpMul, "/": OpDiv, "%": OpMod,
+	"&": OpAnd, "|": OpOr, "^": OpXor, "<<": OpShl, ">>": OpShr,
+}
+
+func (b *builder) infix(x *ast.InfixExpression) *Value {
+	aqıqat := types.Typ[types.Aqıqat]
+	switch x.Operator {
+	case "&&", "||":
+		// The condition picks one of two constants.
+		f := b.fs.f
+		t, e, join := f.NewBlock(), f.NewBlock(), f.NewBlock()
+		b.branch(x, t, e)
+		b.seal(t)
+		b.seal(e)
+		b.start(t)
+		yes := b.constant(aqıqat, int64(1))
+		b.jump(join)
+		b.start(e)
+		no := b.constant(aqıqat, int64(0))
+		b.jump(join)
+		b.seal(join)
+		b.start(join)
+		phi := join.insertPhi(aqıqat, b.fs.pos)
+		phi.Args = []*Value{yes, no}
+		return phi
+	case "==", "!=", "<", "<=", ">", ">=":
+		t := b.operands(x)
+		l, r := b.convert(x.Left, t), b.convert(x.Right, t)
+		op := map[string]Op{"==": OpEq, "!=": OpNe, "<": OpLt, "<=": OpLe, ">": OpLt, ">=": OpLe}[x.Operator]
+		if x.Operator == ">" || x.Operator == ">=" {
+			l, r = r, l
+		}
+		return b.emit(op, aqıqat, []*Value{l, r}, nil)
+	}
+
+	t := b.typeOf(x)
+	if isJol(t) && x.Operator == "+" {
+		return b.emit(OpConcat, t, []*Value{b.value(x.Left), b.value(x.Right)}, nil)
+	}
+	op, ok := arith[x.Operator]
+	switch {
+	case !ok || !isNumber(t):
+		ok = false
+	case isFloat(t) || isDecimal(t):
+		ok = op <= OpMod
+	}
+	if !ok {
+		b.errorf(x, msg.BackendOperatorOn, x.Operator, t)
+		return b.zero(t)
+	}
+	l := b.convert(x.Left, t)
+	var r *Value
+	if op == OpShl || op == OpShr {
+		r = b.convert(x.Right, types.Typ[types.San])
+	} else {
+		r = b.convert(x.Right, t)
+	}
+	if op == OpDiv || op == OpMod {
+		b.at(x.Token)
+	}
+	return b.emit(op, t, []*Value{l, r}, nil)
+}
+
+// egerValue lowers an eger whose branches both end in a value to the phi
+// of the two.
+func (b *builder) egerValue(x *ast.EgerExpression) *Value {
+	t := b.typeOf(x)
+	if x.Alternative == nil || isVoid(t) {
+		b.errorf(x, msg.BackendEgerValue)
+		return b.zero(t)
+	}
+	f := b.fs.f
+	then, els, join := f.NewBlock(), f.NewBlock(), f.NewBlock()
+	b.branch(x.Condition, then, els)
+	b.seal(then)
+	b.seal(els)
+	var args []*Value
+	for _, blk := range []*Block{then, els} {
+		body := x.Consequence
+		if blk == els {
+			body = x.Alternative
+		}
+		b.start(blk)
+		n := len(body.Statements)
+		b.statements(body.Statements[:n-1])
+		last := body.Statements[n-1].(*ast.ExpressionStatement)
+		b.at(types.Pos(last))
+		args = append(args, b.convert(last.Expression, t))
+		b.jump(join)
+	}
+	b.seal(join)
+	b.start(join)
+	phi := join.insertPhi(t, b.fs.pos)
+	phi.Args = args
+	return phi
+}
+
+// index lowers a[i].
+func (b *builder) index(left, idx ast.Expression, at ast.Node) *Value {
+	arr, ok := b.typeOf(left).(*types.Array)
+	if !ok {
+		b.errorf(at, msg.BackendIndex, left, b.typeOf(left))
+		return b.zero(types.Typ[types.San])
+	}
+	a := b.value(left)
+	i := b.convert(idx, types.Typ[types.San])
+	if x, ok := at.(*ast.IndexExpression); ok {
+		b.at(x.Token)
+	} else {
+		b.at(types.Pos(at))
+	}
+	return b.emit(OpIndex, arr.Elem, []*Value{a, i}, nil)
+}
+
+// --- Calls ---
+
+// call lowers a call. A call of a function that returns nothing is a
+// void value.
+func (b *builder) call(x *ast.CallExpression) *Value {
+	if tn, ok := x.Function.(*ast.TypeNode); ok {
+		b.errorf(x, msg.BackendUnsupported, tn.Token.Literal)
+		return b.zero(b.typeOf(x))
+	}
+	if id, ok := x.Function.(*ast.Identifier); ok {
+		if sym := b.info.Uses[id]; sym != nil {
+			switch sym.Kind {
+			case types.TypeSym:
+				if len(x.Arguments) == 1 {
+					return b.convert(x.Arguments[0], types.Subst(sym.Type, b.fs.subst))
+				}
+			case types.BuiltinSym:
+				return b.builtin(id.Value, x)
+			}
+		}
+	}
+
+	sig, ok := b.info.TypeOf(x.Function).(*types.Signature)
+	if ok {
+		sig, ok = types.Subst(sig, b.fs.subst).(*types.Signature)
+	}
+	id, isIdent := x.Function.(*ast.Identifier)
+	if sel, isSel := x.Function.(*ast.SelectorExpression); isSel {
+		id, isIdent = sel.Sel, true
+	}
+	inst := b.info.Instances[x]
+	if inst != nil {
+		sig, ok = types.Subst(inst.Sig, b.fs.subst).(*types.Signature)
+	}
+	var lit *ast.AtqarmLiteral
+	if isIdent {
+		if sym := b.info.Uses[id]; sym != nil {
+			lit = b.decls[sym.Decl]
+		}
+	}
+	if !ok || lit == nil || len(sig.Params) != len(x.Arguments) {
+		b.errorf(x, msg.BackendDirectCall)
+		return b.zero(b.typeOf(x))
+	}
+	var fn *Func
+	if inst != nil {
+		fn = b.instance(inst, x)
+	} else {
+		fn = b.funcs[types.FuncKey{Lit: lit}]
+	}
+	if fn == nil {
+		return b.zero(b.typeOf(x))
+	}
+
+	args := make([]*Value, len(x.Arguments))
+	for i, a := range x.Arguments {
+		args[i] = b.convert(a, sig.Params[i])
+	}
+	b.at(x.Token)
+	v := b.emit(OpCall, norm(sig.Result), args, fn)
+	if isVoid(v.Type) {
+		return v
+	}
+	// The result is converted to the type recorded for the call, which
+	// differs from the signature for `any` results.
+	return b.conv(v, b.typeOf(x), x)
+}
+
+// Built-ins without side effects; the other built-ins are kept in order.
+var pureBuiltins = map[string]bool{
+	"sqrt": true, "ln": true, "exp": true, "cos": true, "sin": true, "floor": true,
+}
+
+// builtin lowers a call of a built-in function.
+func (b *builder) builtin(name string, x *ast.CallExpression) *Value {
+	args := x.Arguments
+	void := types.Typ[types.Void]
+	san, f64 := types.Typ[types.San], types.Typ[types.F64]
+	call := func(t types.Type, args ...*Value) *Value {
+		b.at(x.Token)
+		return b.emit(OpBuiltin, t, args, name)
+	}
+
+	switch name {
+	case "kórset", "print":
+		vs := make([]*Value, len(args))
+		for i, a := range args {
+			vs[i] = b.value(a)
+		}
+		return call(void, vs...)
+	case "printi", "print_time_ns":
+		return call(void, b.convert(args[0], san))
+	case "printf":
+		return call(void, b.convert(args[0], f64), b.convert(args[1], san))
+	case "argi", "argf":
+		// The default is 0 unless given; the value adapts to the type the
+		// checker gave the call, like a literal.
+		t := san
+		if name == "argf" {
+			t = f64
+		}
+		i := b.convert(args[0], san)
+		var def *Value
+		if len(args) == 2 {
+			def = b.convert(args[1], t)
+		} else {
+			op, _, aux := zeroOf(t)
+			def = b.emit(op, t, nil, aux)
+		}
+		return b.conv(call(t, i, def), b.typeOf(x), x)
+	case "now_ns", "time_ns", "now_ms":
+		return call(san)
+	case "sqrt", "ln", "exp", "cos", "sin", "floor":
+		return call(f64, b.convert(args[0], f64))
+	case "make_f64", "make_i32":
+		return b.emit(OpMakeArray, b.typeOf(x), []*Value{b.convert(args[0], san)}, nil)
+	case "len":
+		if t := b.typeOf(args[0]); !isArray(t) && !isJol(t) {
+			b.errorf(args[0], msg.BackendLen, args[0], t)
+			return b.zero(san)
+		}
+		return b.emit(OpLen, san, []*Value{b.value(args[0])}, nil)
+	case "push":
+		arr, _ := b.typeOf(x).(*types.Array)
+		if arr == nil {
+			arr = &types.Array{Elem: san}
+		}
+		return call(arr, b.value(args[0]), b.convert(args[1], arr.Elem))
+	case "index":
+		return b.index(args[0], args[1], x)
+	case "sort":
+		return call(b.typeOf(x), b.value(args[0]))
+	case "assert":
+		vs := []*Value{b.convert(args[0], types.Typ[types.Aqıqat])}
+		if len(args) == 2 {
+			vs = append(vs, b.convert(args[1], types.Typ[types.Jol]))
+		}
+		return call(void, vs...)
+	}
+	b.errorf(x, msg.BackendBuiltin, name)
+	return b.zero(b.typeOf(x))
+}
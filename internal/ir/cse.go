@@ -0,0 +1,73 @@
+// FILE: internal/ir/cse.go
+
+package ir
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// cse removes common subexpressions: a pure operation equal to one in a
+// block that dominates it, and so already computed on every path to it,
+// becomes that value. Loads are left alone, since a store may come
+// between them.
+func cse(f *Func) {
+	d := dominators(f)
+	avail := make(map[string]*Value)
+	var walk func(b *Block)
+	walk = func(b *Block) {
+		var added []string
+		for _, v := range b.Values {
+			if !v.pure() || v.Op == OpPhi || v.Op == OpZero {
+				continue
+			}
+			key := cseKey(v)
+			if w, ok := avail[key]; ok {
+				v.copyOf(w)
+				continue
+			}
+			avail[key] = v
+			added = append(added, key)
+		}
+		for _, c := range d.children[b] {
+			walk(c)
+		}
+		for _, key := range added {
+			delete(avail, key)
+		}
+	}
+	walk(f.Blocks[0])
+	f.removeCopies()
+}
+
+// commutative operations give the same result with their operands
+// swapped.
+var commutative = map[Op]bool{OpAdd: true, OpMul: true, OpAnd: true, OpOr: true, OpXor: true, OpEq: true, OpNe: true}
+
+// cseKey identifies what v computes: equal keys mean equal values.
+func cseKey(v *Value) string {
+	args := make([]int, len(v.Args))
+	for i, a := range v.Args {
+		for a.Op == OpCopy {
+			a = a.Args[0]
+		}
+		args[i] = a.ID
+	}
+	if commutative[v.Op] && args[0] > args[1] {
+		args[0], args[1] = args[1], args[0]
+	}
+	var b strings.Builder
+	aux := v.auxString()
+	if d, ok := v.Aux.(decimal.Decimal); ok {
+		// By digits, so that 1.50 keeps its own constant.
+		aux = d.Coefficient().String() + "e" + strconv.Itoa(int(d.Exponent()))
+	}
+	fmt.Fprintf(&b, "%s %s %T %s", v.Op, v.Type, v.Aux, aux)
+	for _, a := range args {
+		b.WriteString(" v" + strconv.Itoa(a))
+	}
+	return b.String()
+}
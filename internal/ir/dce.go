@@ -0,0 +1,92 @@
+// FILE: internal/ir/dce.go
+
+package ir
+
+// dce removes dead code: blocks that cannot be reached, values whose
+// results are never used and that have no effect, and jumps between
+// blocks that can be one.
+func dce(f *Func) {
+	f.removeUnreachable()
+	mergeBlocks(f)
+
+	live := make(map[*Value]bool)
+	var work []*Value
+	mark := func(v *Value) {
+		if v != nil && !live[v] {
+			live[v] = true
+			work = append(work, v)
+		}
+	}
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			if !v.removable() {
+				mark(v)
+			}
+		}
+		mark(b.Control)
+	}
+	for len(work) > 0 {
+		v := work[len(work)-1]
+		work = work[:len(work)-1]
+		for _, a := range v.Args {
+			mark(a)
+		}
+	}
+	for _, b := range f.Blocks {
+		b.Values = filter(b.Values, func(v *Value) bool { return live[v] })
+	}
+}
+
+// removable reports whether v may be dropped when its result is unused:
+// it has no effect and cannot fail. New arrays and cells are removable;
+// nothing else can see them.
+func (v *Value) removable() bool {
+	switch v.Op {
+	case OpAlloc, OpMakeArray:
+		return !v.traps()
+	}
+	return v.pure() && !v.traps()
+}
+
+// mergeBlocks joins each block that only jumps to a block with no other
+// predecessor with that block.
+func mergeBlocks(f *Func) {
+	for changed := true; changed; {
+		changed = false
+		for _, b := range f.Blocks {
+			if b.Kind != Plain || len(b.Succs) != 1 {
+				continue
+			}
+			c := b.Succs[0]
+			if c == b || c == f.Blocks[0] || len(c.Preds) != 1 {
+				continue
+			}
+			// With one predecessor, the phis of c have one argument.
+			for _, v := range c.Values {
+				if v.Op == OpPhi {
+					v.copyOf(v.Args[0])
+				}
+				v.Block = b
+			}
+			b.Values = append(b.Values, c.Values...)
+			b.Kind, b.Control, b.Succs = c.Kind, c.Control, c.Succs
+			for _, s := range c.Succs {
+				s.replacePred(c, b)
+			}
+			c.Values, c.Succs, c.Preds = nil, nil, nil
+			f.Blocks = removeBlock(f.Blocks, c)
+			changed = true
+			break
+		}
+	}
+	f.removeCopies()
+}
+
+func removeBlock(blocks []*Block, b *Block) []*Block {
+	for i, x := range blocks {
+		if x == b {
+			return append(blocks[:i], blocks[i+1:]...)
+		}
+	}
+	return blocks
+}
@@ -0,0 +1,101 @@
+// FILE: internal/ir/dom.go
+
+package ir
+
+// postorder returns the blocks reachable from the entry of f in
+// postorder: each block after its successors, loops aside.
+func postorder(f *Func) []*Block {
+	var order []*Block
+	seen := make(map[*Block]bool)
+	var visit func(b *Block)
+	visit = func(b *Block) {
+		seen[b] = true
+		for _, s := range b.Succs {
+			if !seen[s] {
+				visit(s)
+			}
+		}
+		order = append(order, b)
+	}
+	visit(f.Blocks[0])
+	return order
+}
+
+// domTree is the dominator tree of a function: a block dominates another
+// when every path from the entry to the other goes through it.
+type domTree struct {
+	idom     map[*Block]*Block   // immediate dominator; the entry has none
+	children map[*Block][]*Block // the blocks each one immediately dominates
+	order    map[*Block]int      // reverse postorder number
+}
+
+// dominators computes the dominator tree of f with the iterative
+// algorithm of Cooper, Harvey and Kennedy, "A Simple, Fast Dominance
+// Algorithm".
+func dominators(f *Func) *domTree {
+	post := postorder(f)
+	d := &domTree{
+		idom:     make(map[*Block]*Block),
+		children: make(map[*Block][]*Block),
+		order:    make(map[*Block]int),
+	}
+	for i, b := range post {
+		d.order[b] = len(post) - 1 - i
+	}
+	entry := f.Blocks[0]
+	d.idom[entry] = entry
+	intersect := func(a, b *Block) *Block {
+		for a != b {
+			for d.order[a] > d.order[b] {
+				a = d.idom[a]
+			}
+			for d.order[b] > d.order[a] {
+				b = d.idom[b]
+			}
+		}
+		return a
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := len(post) - 1; i >= 0; i-- {
+			b := post[i]
+			if b == entry {
+				continue
+			}
+			var idom *Block
+			for _, p := range b.Preds {
+				if d.idom[p] == nil {
+					continue
+				}
+				if idom == nil {
+					idom = p
+				} else {
+					idom = intersect(p, idom)
+				}
+			}
+			if d.idom[b] != idom {
+				d.idom[b] = idom
+				changed = true
+			}
+		}
+	}
+	delete(d.idom, entry)
+	for i := len(post) - 1; i >= 0; i-- {
+		b := post[i]
+		if p := d.idom[b]; p != nil {
+			d.children[p] = append(d.children[p], b)
+		}
+	}
+	return d
+}
+
+// dominates reports whether a dominates b.
+func (d *domTree) dominates(a, b *Block) bool {
+	for b != nil {
+		if a == b {
+			return true
+		}
+		b = d.idom[b]
+	}
+	return false
+}
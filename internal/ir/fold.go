@@ -0,0 +1,361 @@
+// FILE: internal/ir/fold.go
+
+package ir
+
+import (
+	"math"
+	"unicode/utf8"
+
+	"github.com/DauletBai/tenge/internal/lang/types"
+	"github.com/shopspring/decimal"
+)
+
+// fold replaces operations on constants by their result, computed as the
+// program would at run time: integers wrap at their width, and aqsha is
+// exact, with division rounded like the evaluator's. Operations that
+// would fail, such as division by zero, are left for the program to
+// report. Branches on constants become jumps, and operations that do
+// nothing, such as x + 0, become their operand.
+func fold(f *Func) {
+	for changed := true; changed; {
+		changed = false
+		for _, b := range f.Blocks {
+			for _, v := range b.Values {
+				if foldValue(v) {
+					changed = true
+				}
+			}
+			if b.Kind == If && b.Control.Op == OpConst {
+				taken := 0
+				if b.Control.Aux.(int64) == 0 {
+					taken = 1
+				}
+				b.removeEdge(1 - taken)
+				b.Kind, b.Control = Plain, nil
+				changed = true
+			}
+		}
+		if changed {
+			f.removeCopies()
+			f.removeUnreachable()
+			f.simplifyPhis()
+		}
+	}
+}
+
+// foldValue rewrites v in place when it can be simplified.
+func foldValue(v *Value) bool {
+	if v.Op == OpPhi || v.Op == OpConst || len(v.Args) == 0 {
+		return false
+	}
+	consts := make([]interface{}, len(v.Args))
+	all := true
+	for i, a := range v.Args {
+		if a.Op != OpConst {
+			all = false
+			break
+		}
+		consts[i] = a.Aux
+	}
+	if all {
+		if c, ok := evalConst(v, consts); ok {
+			v.Op, v.Args, v.Aux = OpConst, nil, c
+			return true
+		}
+		return false
+	}
+	if w := identity(v); w != nil {
+		v.copyOf(w)
+		return true
+	}
+	return false
+}
+
+// identity returns the operand v equals when the other operand makes the
+// operation do nothing, or nil. Only integers qualify: x + 0 is not x
+// for f64 -0, and it may change the digits of an aqsha.
+func identity(v *Value) *Value {
+	if !isInt(v.Type) || len(v.Args) != 2 {
+		return nil
+	}
+	x, y := v.Args[0], v.Args[1]
+	isConst := func(a *Value, c int64) bool {
+		return a.Op == OpConst && a.Aux.(int64) == c
+	}
+	switch v.Op {
+	case OpAdd, OpOr, OpXor:
+		if isConst(y, 0) {
+			return x
+		}
+		if isConst(x, 0) {
+			return y
+		}
+	case OpSub, OpShl, OpShr:
+		if isConst(y, 0) {
+			return x
+		}
+	case OpMul:
+		if isConst(y, 1) {
+			return x
+		}
+		if isConst(x, 1) {
+			return y
+		}
+	case OpDiv:
+		if isConst(y, 1) {
+			return x
+		}
+	}
+	return nil
+}
+
+// evalConst computes v from the constant values of its arguments.
+func evalConst(v *Value, args []interface{}) (interface{}, bool) {
+	t := v.Type
+	if s, ok := args[0].(string); ok && v.Op == OpLen {
+		return int64(utf8.RuneCountInString(s)), true
+	}
+	if !v.pure() {
+		return nil, false
+	}
+	switch v.Op {
+	case OpConv:
+		return convConst(args[0], v.Args[0].Type, t)
+	case OpEq, OpNe, OpLt, OpLe:
+		c, ok := compareConst(args[0], args[1], v.Args[0].Type)
+		if !ok {
+			return nil, false
+		}
+		var r bool
+		switch v.Op {
+		case OpEq:
+			r = c == 0
+		case OpNe:
+			r = c != 0
+		case OpLt:
+			r = c < 0
+		case OpLe:
+			r = c <= 0
+		}
+		return boolConst(r), true
+	case OpConcat:
+		return args[0].(string) + args[1].(string), true
+	case OpBuiltin:
+		x := args[0].(float64)
+		fn := map[string]func(float64) float64{
+			"sqrt": math.Sqrt, "ln": math.Log, "exp": math.Exp,
+			"cos": math.Cos, "sin": math.Sin, "floor": math.Floor,
+		}[v.Aux.(string)]
+		return fn(x), true
+	case OpNot:
+		return args[0].(int64) ^ 1, true
+	}
+
+	switch {
+	case isInt(t):
+		return intConst(v.Op, t, args)
+	case isFloat(t):
+		return floatConst(v.Op, args)
+	case isDecimal(t):
+		return decimalConst(v.Op, args)
+	}
+	return nil, false
+}
+
+// wrap truncates x to the width of the integer type t.
+func wrap(t types.Type, x int64) int64 {
+	if isI32(t) {
+		return int64(int32(x))
+	}
+	return x
+}
+
+func intConst(op Op, t types.Type, args []interface{}) (interface{}, bool) {
+	x := args[0].(int64)
+	if op == OpNeg {
+		return wrap(t, -x), true
+	}
+	y := args[1].(int64)
+	u := isU64(t)
+	var r int64
+	switch op {
+	case OpAdd:
+		r = x + y
+	case OpSub:
+		r = x - y
+	case OpMul:
+		r = x * y
+	case OpDiv, OpMod:
+		if y == 0 {
+			return nil, false
+		}
+		switch {
+		case u && op == OpDiv:
+			r = int64(uint64(x) / uint64(y))
+		case u:
+			r = int64(uint64(x) % uint64(y))
+		case op == OpDiv:
+			r = x / y
+		default:
+			r = x % y
+		}
+	case OpAnd:
+		r = x & y
+	case OpOr:
+		r = x | y
+	case OpXor:
+		r = x ^ y
+	case OpShl, OpShr:
+		// A negative count is an error in the interpreter.
+		if y < 0 {
+			return nil, false
+		}
+		switch {
+		case op == OpShl:
+			r = x << uint64(y)
+		case u:
+			r = int64(uint64(x) >> uint64(y))
+		default:
+			r = x >> uint64(y)
+		}
+	default:
+		return nil, false
+	}
+	return wrap(t, r), true
+}
+
+func floatConst(op Op, args []interface{}) (interface{}, bool) {
+	x := args[0].(float64)
+	if op == OpNeg {
+		return -x, true
+	}
+	y := args[1].(float64)
+	switch op {
+	case OpAdd:
+		return x + y, true
+	case OpSub:
+		return x - y, true
+	case OpMul:
+		return x * y, true
+	case OpDiv:
+		return x / y, true
+	case OpMod:
+		return math.Mod(x, y), true
+	}
+	return nil, false
+}
+
+func decimalConst(op Op, args []interface{}) (interface{}, bool) {
+	x := args[0].(decimal.Decimal)
+	if op == OpNeg {
+		return x.Neg(), true
+	}
+	y := args[1].(decimal.Decimal)
+	switch op {
+	case OpAdd:
+		return x.Add(y), true
+	case OpSub:
+		return x.Sub(y), true
+	case OpMul:
+		return x.Mul(y), true
+	case OpDiv:
+		if y.IsZero() {
+			return nil, false
+		}
+		return divide(x, y), true
+	case OpMod:
+		if y.IsZero() {
+			return nil, false
+		}
+		return x.Mod(y), true
+	}
+	return nil, false
+}
+
+// divide rounds like the evaluator and the VM do: to 16 decimal places,
+// or more when the quotient is small, so that it keeps 17 significant
+// digits.
+func divide(l, r decimal.Decimal) decimal.Decimal {
+	magnitude := (l.NumDigits() + int(l.Exponent())) - (r.NumDigits() + int(r.Exponent()))
+	places := 16
+	if 17-magnitude > places {
+		places = 17 - magnitude
+	}
+	return l.DivRound(r, int32(places))
+}
+
+// compareConst returns -1, 0 or 1 as x is less than, equal to or greater
+// than y, both constants of type t.
+func compareConst(x, y interface{}, t types.Type) (int, bool) {
+	cmp := func(less, greater bool) int {
+		switch {
+		case less:
+			return -1
+		case greater:
+			return 1
+		}
+		return 0
+	}
+	switch x := x.(type) {
+	case int64:
+		y := y.(int64)
+		if isU64(t) {
+			return cmp(uint64(x) < uint64(y), uint64(x) > uint64(y)), true
+		}
+		return cmp(x < y, x > y), true
+	case float64:
+		y := y.(float64)
+		if math.IsNaN(x) || math.IsNaN(y) {
+			// NaN is unordered: fold nothing rather than a wrong answer.
+			return 0, false
+		}
+		return cmp(x < y, x > y), true
+	case decimal.Decimal:
+		return x.Cmp(y.(decimal.Decimal)), true
+	case string:
+		y := y.(string)
+		return cmp(x < y, x > y), true
+	}
+	return 0, false
+}
+
+// convConst converts the constant x of type from to type to, like the
+// conversions of the VM.
+func convConst(x interface{}, from, to types.Type) (interface{}, bool) {
+	switch x := x.(type) {
+	case int64:
+		switch {
+		case isInt(to):
+			return wrap(to, x), true
+		case isFloat(to) && isU64(from):
+			return float64(uint64(x)), true
+		case isFloat(to):
+			return float64(x), true
+		case isDecimal(to) && isU64(from):
+			return decimal.NewFromUint64(uint64(x)), true
+		case isDecimal(to):
+			return decimal.NewFromInt(x), true
+		}
+	case float64:
+		switch {
+		case math.IsNaN(x) || math.IsInf(x, 0):
+			// The result depends on the machine.
+		case isInt(to) && math.Abs(x) < 1<<63:
+			if isU64(to) {
+				return int64(uint64(x)), x >= 0
+			}
+			return wrap(to, int64(x)), true
+		case isDecimal(to):
+			return decimal.NewFromFloat(x), true
+		}
+	case decimal.Decimal:
+		switch {
+		case isInt(to):
+			return wrap(to, x.IntPart()), true
+		case isFloat(to):
+			f, _ := x.Float64()
+			return f, true
+		}
+	}
+	return nil, false
+}
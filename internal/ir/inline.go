@@ -0,0 +1,123 @@
+// FILE: internal/ir/inline.go
+
+package ir
+
+// inlineLimit is the size, in values, of the largest function inline
+// copies into its callers.
+const inlineLimit = 40
+
+// inline replaces calls of small functions by their body. The calls in
+// the copied bodies stay calls, so that functions calling each other are
+// not copied without end.
+func inline(f *Func) {
+	var calls []*Value
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			if v.Op == OpCall && inlinable(f, v.Aux.(*Func)) {
+				calls = append(calls, v)
+			}
+		}
+	}
+	for _, call := range calls {
+		inlineCall(f, call)
+	}
+	if len(calls) > 0 {
+		f.removeCopies()
+		f.simplifyPhis()
+	}
+}
+
+// inlinable reports whether calls of callee in f may be inlined: callee
+// is small and does not call itself.
+func inlinable(f, callee *Func) bool {
+	if callee == f || len(callee.Blocks) == 0 {
+		return false
+	}
+	n := 0
+	for _, b := range callee.Blocks {
+		n += len(b.Values)
+		for _, v := range b.Values {
+			if v.Op == OpCall && v.Aux.(*Func) == callee {
+				return false
+			}
+		}
+	}
+	return n <= inlineLimit
+}
+
+// inlineCall splits the block of call after it, and puts a copy of the
+// body of the callee in between. The returns of the copy jump to the
+// rest of the block, where a phi merges the results.
+func inlineCall(f *Func, call *Value) {
+	b := call.Block
+	callee := call.Aux.(*Func)
+	i := 0
+	for b.Values[i] != call {
+		i++
+	}
+	rest := f.NewBlock()
+	rest.Values = append(rest.Values, b.Values[i+1:]...)
+	for _, v := range rest.Values {
+		v.Block = rest
+	}
+	rest.Kind, rest.Control, rest.Succs = b.Kind, b.Control, b.Succs
+	for _, s := range rest.Succs {
+		s.replacePred(b, rest)
+	}
+	b.Values = b.Values[:i+1]
+	b.Kind, b.Control, b.Succs = Plain, nil, nil
+
+	blocks := make(map[*Block]*Block)
+	values := make(map[*Value]*Value)
+	for j, p := range callee.Params {
+		values[p] = call.Args[j]
+	}
+	for _, cb := range callee.Blocks {
+		nb := f.NewBlock()
+		nb.Kind = cb.Kind
+		blocks[cb] = nb
+		for _, v := range cb.Values {
+			nv := f.newValue(v.Op, v.Type, nil, v.Aux, v.Pos)
+			nv.Block = nb
+			nb.Values = append(nb.Values, nv)
+			values[v] = nv
+		}
+	}
+	var results []*Value
+	for _, cb := range callee.Blocks {
+		nb := blocks[cb]
+		for j, v := range cb.Values {
+			nv := nb.Values[j]
+			for _, a := range v.Args {
+				nv.Args = append(nv.Args, values[a])
+			}
+		}
+		for _, p := range cb.Preds {
+			nb.Preds = append(nb.Preds, blocks[p])
+		}
+		for _, s := range cb.Succs {
+			nb.Succs = append(nb.Succs, blocks[s])
+		}
+		if cb.Kind != Ret {
+			nb.Control = values[cb.Control]
+			continue
+		}
+		r := values[cb.Control]
+		if r == nil && !isVoid(callee.Result) {
+			r = nb.NewValue(OpConst, callee.Result, nil, nil, call.Pos)
+			r.Op, r.Args, r.Aux = zeroOf(callee.Result)
+		}
+		results = append(results, r)
+		nb.Kind = Plain
+		nb.addEdge(rest)
+	}
+	b.addEdge(blocks[callee.Blocks[0]])
+
+	if isVoid(call.Type) {
+		b.Values = b.Values[:i]
+		return
+	}
+	phi := rest.insertPhi(call.Type, call.Pos)
+	phi.Args = results
+	call.copyOf(phi)
+}
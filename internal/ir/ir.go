@@ -0,0 +1,484 @@
+// FILE: internal/ir/ir.go
+
+// Package ir is an intermediate representation of checked programs: each
+// function is a graph of basic blocks whose values are in SSA form,
+// assigned once and merged by phis where control flow joins. Build lowers
+// a checked program, the passes in pass.go rewrite it and Fprint lists
+// it, which is what tenge emit-ir shows. The interpreter, the VM and the
+// C backend do not compile from it yet: they compile the syntax tree, and
+// the C compiler optimizes the C.
+package ir
+
+import (
+	"github.com/DauletBai/tenge/internal/lang/token"
+	"github.com/DauletBai/tenge/internal/lang/types"
+	"github.com/shopspring/decimal"
+)
+
+// Op is the operation of a value.
+type Op uint8
+
+const (
+	OpInvalid Op = iota
+
+	OpParam // Aux: the index of the parameter; params are in no block
+	OpConst // Aux: int64 (integers, tańba, aqıqat), float64, decimal.Decimal or string
+	OpZero  // the empty array or the nil pointer of Type
+	OpPhi   // Args[i] is the value coming from Block.Preds[i]
+	OpCopy  // Args[0]; left by passes and removed before they return
+
+	// Arithmetic on operands of the value's Type; shift counts are san.
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpAnd
+	OpOr
+	OpXor
+	OpShl
+	OpShr
+	OpNeg
+	OpNot
+
+	// Comparisons of two operands of the same type; the result is aqıqat.
+	OpEq
+	OpNe
+	OpLt
+	OpLe
+
+	OpConv   // Args[0] converted to Type
+	OpConcat // jol + jol
+
+	OpCall    // Aux: *Func; Args are the arguments
+	OpBuiltin // Aux: the name of the built-in
+
+	OpGlobal    // load of Aux (*Global)
+	OpSetGlobal // store of Args[0] in Aux (*Global)
+	OpAddr      // pointer to Aux (*Global)
+	OpAlloc     // new cell holding Args[0]: the home of a local whose address is taken
+	OpLoad      // *Args[0]
+	OpStore     // *Args[0] = Args[1]
+
+	OpMakeArray // new array of Type with Args[0] zero elements
+	OpIndex     // Args[0][Args[1]]
+	OpSetIndex  // Args[0][Args[1]] = Args[2]
+	OpLen       // length of an array or jol
+
+	numOps
+)
+
+var opNames = [...]string{
+	OpInvalid: "invalid", OpParam: "param", OpConst: "const", OpZero: "zero", OpPhi: "phi", OpCopy: "copy",
+	OpAdd: "add", OpSub: "sub", OpMul: "mul", OpDiv: "div", OpMod: "mod",
+	OpAnd: "and", OpOr: "or", OpXor: "xor", OpShl: "shl", OpShr: "shr", OpNeg: "neg", OpNot: "not",
+	OpEq: "eq", OpNe: "ne", OpLt: "lt", OpLe: "le",
+	OpConv: "conv", OpConcat: "concat", OpCall: "call", OpBuiltin: "builtin",
+	OpGlobal: "global", OpSetGlobal: "setglobal", OpAddr: "addr",
+	OpAlloc: "alloc", OpLoad: "load", OpStore: "store",
+	OpMakeArray: "makearray", OpIndex: "index", OpSetIndex: "setindex", OpLen: "len",
+}
+
+func (op Op) String() string {
+	if op < numOps {
+		return opNames[op]
+	}
+	return "op?"
+}
+
+// Program is a lowered program.
+type Program struct {
+	Globals []*Global
+	Funcs   []*Func // every function, Init included, in the order they were created
+	Init    []*Func // the top-level code of each module, in initialization order
+	Main    *Func   // nil when the main module has no main function
+}
+
+// Global is a top-level variable.
+type Global struct {
+	Name string
+	Type types.Type
+}
+
+// Func is a function: a top-level function, an instance of a generic
+// one, or the top-level code of a module.
+type Func struct {
+	Name   string
+	File   string
+	Params []*Value   // OpParam values
+	Result types.Type // Typ[Void] when the function returns nothing
+	Blocks []*Block   // Blocks[0] is the entry, which has no predecessors
+
+	nextValue int
+	nextBlock int
+}
+
+// BlockKind says how a block ends.
+type BlockKind uint8
+
+const (
+	Plain BlockKind = iota // jumps to Succs[0]
+	If                     // jumps to Succs[0] if Control is true, else to Succs[1]
+	Ret                    // returns Control, or nothing when it is nil
+)
+
+// Block is a basic block: values computed in order, then a jump or a
+// return. Phis come first.
+type Block struct {
+	ID      int
+	Kind    BlockKind
+	Values  []*Value
+	Control *Value
+	Succs   []*Block
+	Preds   []*Block
+	Func    *Func
+}
+
+// Value is the result of one operation.
+type Value struct {
+	ID    int
+	Op    Op
+	Type  types.Type // Typ[Void] for operations that produce nothing
+	Args  []*Value
+	Aux   interface{}
+	Block *Block
+	Pos   token.Token // source position, for diagnostics and line tables
+}
+
+// NewBlock appends an empty block to f.
+func (f *Func) NewBlock() *Block {
+	b := &Block{ID: f.nextBlock, Func: f}
+	f.nextBlock++
+	f.Blocks = append(f.Blocks, b)
+	return b
+}
+
+func (f *Func) newValue(op Op, t types.Type, args []*Value, aux interface{}, pos token.Token) *Value {
+	v := &Value{ID: f.nextValue, Op: op, Type: t, Args: args, Aux: aux, Pos: pos}
+	f.nextValue++
+	return v
+}
+
+// NewValue appends a value to b.
+func (b *Block) NewValue(op Op, t types.Type, args []*Value, aux interface{}, pos token.Token) *Value {
+	v := b.Func.newValue(op, t, args, aux, pos)
+	v.Block = b
+	b.Values = append(b.Values, v)
+	return v
+}
+
+// insertPhi adds a phi after the phis of b.
+func (b *Block) insertPhi(t types.Type, pos token.Token) *Value {
+	v := b.Func.newValue(OpPhi, t, nil, nil, pos)
+	v.Block = b
+	i := 0
+	for i < len(b.Values) && b.Values[i].Op == OpPhi {
+		i++
+	}
+	b.Values = append(b.Values[:i], append([]*Value{v}, b.Values[i:]...)...)
+	return v
+}
+
+// addEdge makes to a successor of b.
+func (b *Block) addEdge(to *Block) {
+	b.Succs = append(b.Succs, to)
+	to.Preds = append(to.Preds, b)
+}
+
+// removePred removes the i'th predecessor of b with its phi arguments.
+func (b *Block) removePred(i int) {
+	b.Preds = append(b.Preds[:i], b.Preds[i+1:]...)
+	for _, v := range b.Values {
+		if v.Op == OpPhi {
+			v.Args = append(v.Args[:i], v.Args[i+1:]...)
+		}
+	}
+}
+
+// removeEdge removes the edge from b to its i'th successor.
+func (b *Block) removeEdge(i int) {
+	s := b.Succs[i]
+	b.Succs = append(b.Succs[:i], b.Succs[i+1:]...)
+	for j, p := range s.Preds {
+		if p == b {
+			s.removePred(j)
+			return
+		}
+	}
+}
+
+// replacePred makes b's edges from old come from new.
+func (b *Block) replacePred(old, new *Block) {
+	for i, p := range b.Preds {
+		if p == old {
+			b.Preds[i] = new
+		}
+	}
+}
+
+// copyOf turns v into a copy of w.
+func (v *Value) copyOf(w *Value) {
+	v.Op, v.Args, v.Aux = OpCopy, []*Value{w}, nil
+}
+
+// removeCopies points the uses of copies at what they copy and drops the
+// copies.
+func (f *Func) removeCopies() {
+	resolve := func(v *Value) *Value {
+		for v != nil && v.Op == OpCopy {
+			v = v.Args[0]
+		}
+		return v
+	}
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			for i, a := range v.Args {
+				v.Args[i] = resolve(a)
+			}
+		}
+		b.Control = resolve(b.Control)
+	}
+	for _, b := range f.Blocks {
+		b.Values = filter(b.Values, func(v *Value) bool { return v.Op != OpCopy })
+	}
+}
+
+// simplifyPhis replaces phis whose arguments are all the same value, or
+// the phi itself, by that value until none is left.
+func (f *Func) simplifyPhis() {
+	for changed := true; changed; {
+		changed = false
+		for _, b := range f.Blocks {
+			for _, v := range b.Values {
+				if v.Op != OpPhi {
+					continue
+				}
+				var same *Value
+				trivial := true
+				for _, a := range v.Args {
+					for a.Op == OpCopy {
+						a = a.Args[0]
+					}
+					if a == v || a == same {
+						continue
+					}
+					if same != nil {
+						trivial = false
+						break
+					}
+					same = a
+				}
+				if !trivial {
+					continue
+				}
+				if same == nil {
+					// Only reachable from itself: the block is dead.
+					same = f.Blocks[0].NewValue(OpConst, v.Type, nil, nil, v.Pos)
+					same.Op, same.Args, same.Aux = zeroOf(v.Type)
+				}
+				v.copyOf(same)
+				changed = true
+			}
+		}
+		f.removeCopies()
+	}
+}
+
+// zeroOf returns the operation producing the zero value of t.
+func zeroOf(t types.Type) (Op, []*Value, interface{}) {
+	switch {
+	case isFloat(t):
+		return OpConst, nil, float64(0)
+	case isDecimal(t):
+		return OpConst, nil, decimal.Zero
+	case isJol(t):
+		return OpConst, nil, ""
+	case isArray(t) || isPointer(t):
+		return OpZero, nil, nil
+	}
+	return OpConst, nil, int64(0)
+}
+
+func filter(vs []*Value, keep func(*Value) bool) []*Value {
+	out := vs[:0]
+	for _, v := range vs {
+		if keep(v) {
+			out = append(out, v)
+		}
+	}
+	for i := len(out); i < len(vs); i++ {
+		vs[i] = nil
+	}
+	return out
+}
+
+// --- Types ---
+
+func basicKind(t types.Type) types.Kind {
+	if b, ok := t.(*types.Basic); ok {
+		return b.Kind
+	}
+	return types.Invalid
+}
+
+// isInt reports whether values of t are held as int64: the integer
+// types, tańba and aqıqat.
+func isInt(t types.Type) bool {
+	switch basicKind(t) {
+	case types.San, types.I32, types.U64, types.Tańba, types.Aqıqat:
+		return true
+	}
+	return false
+}
+
+func isI32(t types.Type) bool {
+	k := basicKind(t)
+	return k == types.I32 || k == types.Tańba
+}
+
+func isU64(t types.Type) bool     { return basicKind(t) == types.U64 }
+func isBool(t types.Type) bool    { return basicKind(t) == types.Aqıqat }
+func isFloat(t types.Type) bool   { return basicKind(t) == types.F64 }
+func isDecimal(t types.Type) bool { return basicKind(t) == types.Aqsha }
+func isJol(t types.Type) bool     { return basicKind(t) == types.Jol }
+func isVoid(t types.Type) bool    { return basicKind(t) == types.Void }
+
+func isArray(t types.Type) bool {
+	_, ok := t.(*types.Array)
+	return ok
+}
+
+func isPointer(t types.Type) bool {
+	_, ok := t.(*types.Pointer)
+	return ok
+}
+
+// norm returns the type values of t have in the IR: untyped constants
+// take their default type and `any` is san, as in compiled code.
+func norm(t types.Type) types.Type {
+	switch basicKind(t) {
+	case types.Any, types.UntypedInt:
+		return types.Typ[types.San]
+	case types.UntypedFloat:
+		return types.Typ[types.Aqsha]
+	}
+	switch t := t.(type) {
+	case *types.Array:
+		return &types.Array{Elem: norm(t.Elem)}
+	case *types.Pointer:
+		return &types.Pointer{Elem: norm(t.Elem)}
+	}
+	return t
+}
+
+// removeUnreachable drops the blocks that cannot be reached from the
+// entry, with their edges into reachable blocks.
+func (f *Func) removeUnreachable() {
+	seen := make(map[*Block]bool)
+	work := []*Block{f.Blocks[0]}
+	seen[f.Blocks[0]] = true
+	for len(work) > 0 {
+		b := work[len(work)-1]
+		work = work[:len(work)-1]
+		for _, s := range b.Succs {
+			if !seen[s] {
+				seen[s] = true
+				work = append(work, s)
+			}
+		}
+	}
+	for _, b := range f.Blocks {
+		if seen[b] {
+			continue
+		}
+		for len(b.Succs) > 0 {
+			b.removeEdge(0)
+		}
+	}
+	kept := f.Blocks[:0]
+	for _, b := range f.Blocks {
+		if seen[b] {
+			kept = append(kept, b)
+		}
+	}
+	f.Blocks = kept
+}
+
+// layout orders the blocks of f so that each comes after its
+// dominators, dropping those that cannot be reached.
+func (f *Func) layout() {
+	f.removeUnreachable()
+	post := postorder(f)
+	for i, b := range post {
+		f.Blocks[len(post)-1-i] = b
+	}
+}
+
+// renumber numbers the blocks and values of f in order, parameters first.
+func (f *Func) renumber() {
+	f.nextValue, f.nextBlock = 0, 0
+	for _, p := range f.Params {
+		p.ID = f.nextValue
+		f.nextValue++
+	}
+	for _, b := range f.Blocks {
+		b.ID = f.nextBlock
+		f.nextBlock++
+		for _, v := range b.Values {
+			v.ID = f.nextValue
+			f.nextValue++
+		}
+	}
+}
+
+// --- Effects ---
+
+// pure reports whether v only computes its result from its arguments:
+// it has no side effects and reads no memory, so it may be removed when
+// unused, or computed once for equal operations.
+func (v *Value) pure() bool {
+	switch v.Op {
+	case OpConst, OpZero, OpParam, OpPhi, OpCopy,
+		OpAdd, OpSub, OpMul, OpDiv, OpMod, OpAnd, OpOr, OpXor, OpShl, OpShr, OpNeg, OpNot,
+		OpEq, OpNe, OpLt, OpLe, OpConv, OpConcat, OpAddr:
+		return true
+	case OpBuiltin:
+		return pureBuiltins[v.Aux.(string)]
+	}
+	return false
+}
+
+// traps reports whether v may stop the program with a runtime error.
+func (v *Value) traps() bool {
+	switch v.Op {
+	case OpDiv, OpMod:
+		if isFloat(v.Type) {
+			return false
+		}
+		d := v.Args[1]
+		return d.Op != OpConst || isZero(d.Aux)
+	case OpShl, OpShr:
+		// A negative count is an error.
+		n := v.Args[1]
+		return n.Op != OpConst || n.Aux.(int64) < 0
+	case OpIndex, OpSetIndex, OpLoad, OpStore, OpCall, OpBuiltin:
+		return !v.pure()
+	case OpMakeArray:
+		n := v.Args[0]
+		return n.Op != OpConst || n.Aux.(int64) < 0
+	}
+	return false
+}
+
+// isZero reports whether the constant c is zero.
+func isZero(c interface{}) bool {
+	switch c := c.(type) {
+	case int64:
+		return c == 0
+	case float64:
+		return c == 0
+	case decimal.Decimal:
+		return c.IsZero()
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+// FILE: internal/ir/licm.go
+
+package ir
+
+// licm moves loop-invariant code out of loops: a pure operation in a loop
+// whose operands are all computed before the loop is computed once, in
+// the block that enters the loop. Only operations that cannot fail move,
+// since the loop may run no iteration.
+func licm(f *Func) {
+	d := dominators(f)
+	all := loops(f, d)
+	for _, l := range all {
+		pre := preheader(f, l, all)
+		for changed := true; changed; {
+			changed = false
+			for _, b := range l.blocks {
+				b.Values = filter(b.Values, func(v *Value) bool {
+					if !invariant(v, l) {
+						return true
+					}
+					v.Block = pre
+					pre.Values = append(pre.Values, v)
+					changed = true
+					return false
+				})
+			}
+		}
+	}
+}
+
+// loop is a natural loop: the blocks that reach one of the back edges to
+// header without going through header.
+type loop struct {
+	header *Block
+	blocks []*Block // header first
+	in     map[*Block]bool
+}
+
+// loops returns the loops of f, inner loops before the loops around them.
+func loops(f *Func, d *domTree) []*loop {
+	byHeader := make(map[*Block]*loop)
+	var found []*loop
+	for _, b := range postorder(f) {
+		for _, h := range b.Succs {
+			if !d.dominates(h, b) {
+				continue
+			}
+			l := byHeader[h]
+			if l == nil {
+				l = &loop{header: h, blocks: []*Block{h}, in: map[*Block]bool{h: true}}
+				byHeader[h] = l
+				found = append(found, l)
+			}
+			work := []*Block{b}
+			for len(work) > 0 {
+				x := work[len(work)-1]
+				work = work[:len(work)-1]
+				if l.in[x] {
+					continue
+				}
+				l.in[x] = true
+				l.blocks = append(l.blocks, x)
+				work = append(work, x.Preds...)
+			}
+		}
+	}
+	// A loop inside another has fewer blocks.
+	for i := 1; i < len(found); i++ {
+		for j := i; j > 0 && len(found[j].blocks) < len(found[j-1].blocks); j-- {
+			found[j], found[j-1] = found[j-1], found[j]
+		}
+	}
+	for _, l := range found {
+		sortBlocks(l.blocks, d)
+	}
+	return found
+}
+
+// sortBlocks orders blocks as the dominator tree does, so that a value is
+// looked at after the values it uses.
+func sortBlocks(blocks []*Block, d *domTree) {
+	for i := 1; i < len(blocks); i++ {
+		for j := i; j > 0 && d.order[blocks[j]] < d.order[blocks[j-1]]; j-- {
+			blocks[j], blocks[j-1] = blocks[j-1], blocks[j]
+		}
+	}
+}
+
+// preheader returns the block that enters loop l, adding one when the
+// header has several predecessors outside the loop. A new block is part
+// of the loops around l.
+func preheader(f *Func, l *loop, all []*loop) *Block {
+	var outside []int
+	for i, p := range l.header.Preds {
+		if !l.in[p] {
+			outside = append(outside, i)
+		}
+	}
+	if len(outside) == 1 {
+		if p := l.header.Preds[outside[0]]; len(p.Succs) == 1 {
+			return p
+		}
+	}
+
+	pre := f.NewBlock()
+	for _, outer := range all {
+		if outer != l && outer.in[l.header] {
+			outer.in[pre] = true
+			outer.blocks = append(outer.blocks, pre)
+		}
+	}
+	// The phis of the header take the values from outside through a phi
+	// of the preheader.
+	for _, v := range l.header.Values {
+		if v.Op != OpPhi {
+			continue
+		}
+		phi := pre.insertPhi(v.Type, v.Pos)
+		for _, i := range outside {
+			phi.Args = append(phi.Args, v.Args[i])
+		}
+	}
+	var preds []*Block
+	for _, i := range outside {
+		p := l.header.Preds[i]
+		preds = append(preds, p)
+		for k, s := range p.Succs {
+			if s == l.header {
+				p.Succs[k] = pre
+			}
+		}
+	}
+	pre.Preds = preds
+	phis := pre.Values
+	for k := len(outside) - 1; k >= 0; k-- {
+		l.header.removePred(outside[k])
+	}
+	pre.addEdge(l.header)
+	k := 0
+	for _, v := range l.header.Values {
+		if v.Op == OpPhi {
+			v.Args = append(v.Args, phis[k])
+			k++
+		}
+	}
+	f.simplifyPhis()
+	return pre
+}
+
+// invariant reports whether v can move out of l.
+func invariant(v *Value, l *loop) bool {
+	if !v.pure() || v.traps() || v.Op == OpPhi {
+		return false
+	}
+	for _, a := range v.Args {
+		if l.in[a.Block] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+// FILE: internal/ir/pass.go
+
+package ir
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/DauletBai/tenge/internal/lang/msg"
+)
+
+// Pass is an optimization that rewrites one function at a time.
+type Pass struct {
+	Name string
+	Doc  string
+	run  func(*Func)
+}
+
+// Passes lists the passes in the order DefaultPasses runs them.
+var Passes = []Pass{
+	{"tailcall", "turn calls a function makes to itself as it returns into loops", tailcall},
+	{"inline", "copy the body of small functions into their callers", inline},
+	{"fold", "compute operations on constants and branches on them", fold},
+	{"cse", "compute equal operations once", cse},
+	{"licm", "move loop-invariant operations out of loops", licm},
+	{"dce", "remove unused values and unreachable blocks", dce},
+}
+
+// DefaultPasses is the list tenge emit-ir runs unless told otherwise.
+const DefaultPasses = "tailcall,inline,fold,cse,licm,dce"
+
+// ParsePasses parses a comma-separated list of pass names; "none" is the
+// empty list.
+func ParsePasses(list string) ([]Pass, error) {
+	if list == "none" || list == "" {
+		return nil, nil
+	}
+	var passes []Pass
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, p := range Passes {
+			if p.Name == name {
+				passes = append(passes, p)
+				found = true
+				break
+			}
+		}
+		if !found {
+			var names []string
+			for _, p := range Passes {
+				names = append(names, p.Name)
+			}
+			return nil, errors.New(msg.Sprintf(msg.UnknownPass, name, strings.Join(names, ", ")))
+		}
+	}
+	return passes, nil
+}
+
+// Run applies pass to every function of p.
+func (p *Program) Run(pass Pass) {
+	for _, f := range p.Funcs {
+		pass.run(f)
+		f.removeCopies()
+		f.layout()
+		f.renumber()
+	}
+}
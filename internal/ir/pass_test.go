@@ -0,0 +1,117 @@
+// FILE: internal/ir/pass_test.go
+
+package ir_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DauletBai/tenge/internal/ir"
+	"github.com/DauletBai/tenge/internal/lang/module"
+	"github.com/DauletBai/tenge/internal/lang/types"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// build lowers the program at path.
+func build(t *testing.T, path string) *ir.Program {
+	t.Helper()
+	prog, errs := module.Load(path)
+	var info *types.Info
+	if len(errs) == 0 {
+		info, errs = types.CheckModules(prog)
+	}
+	var p *ir.Program
+	if len(errs) == 0 {
+		p, errs = ir.Build(prog, info)
+	}
+	if len(errs) > 0 {
+		t.Fatal(errs[0])
+	}
+	return p
+}
+
+// TestPasses runs each pass alone over testdata/<pass>.tng and compares
+// the listing with testdata/<pass>.golden.
+func TestPasses(t *testing.T) {
+	for _, pass := range ir.Passes {
+		t.Run(pass.Name, func(t *testing.T) {
+			p := build(t, filepath.Join("testdata", pass.Name+".tng"))
+			p.Run(pass)
+			for _, f := range p.Funcs {
+				if err := f.Verify(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var b strings.Builder
+			ir.Fprint(&b, p)
+			golden := filepath.Join("testdata", pass.Name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, []byte(b.String()), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := b.String(); got != string(want) {
+				t.Errorf("after %s:\n%s\nwant:\n%s", pass.Name, got, want)
+			}
+		})
+	}
+}
+
+// TestDefaultPasses runs the default list over every test program; the
+// IR must stay well formed after each pass.
+func TestDefaultPasses(t *testing.T) {
+	passes, err := ir.ParsePasses(ir.DefaultPasses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, _ := filepath.Glob(filepath.Join("testdata", "*.tng"))
+	for _, path := range paths {
+		p := build(t, path)
+		for _, pass := range passes {
+			p.Run(pass)
+			for _, f := range p.Funcs {
+				if err := f.Verify(); err != nil {
+					t.Errorf("%s: after %s: %v", path, pass.Name, err)
+				}
+			}
+		}
+	}
+}
+
+func TestParsePasses(t *testing.T) {
+	tests := []struct {
+		list string
+		want string // the names of the passes, or "error"
+	}{
+		{"none", ""},
+		{"", ""},
+		{"fold", "fold"},
+		{"dce, fold,dce", "dce,fold,dce"},
+		{ir.DefaultPasses, ir.DefaultPasses},
+		{"bogus", "error"},
+		{"fold,", "error"},
+	}
+	for _, tt := range tests {
+		passes, err := ir.ParsePasses(tt.list)
+		var names []string
+		for _, p := range passes {
+			names = append(names, p.Name)
+		}
+		got := strings.Join(names, ",")
+		if err != nil {
+			got = "error"
+		}
+		if got != tt.want {
+			t.Errorf("ParsePasses(%q) = %q, want %q", tt.list, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// FILE: internal/ir/print.go
+
+package ir
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Fprint writes a listing of p: the globals, then every function.
+//
+//	func fib(v0 : san) -> san
+//	  b0:
+//	    v1 = const 2 : san
+//	    v2 = lt v0, v1 : aqıqat
+//	    if v2 -> b1, b2
+func Fprint(w io.Writer, p *Program) {
+	for _, g := range p.Globals {
+		fmt.Fprintf(w, "global %s : %s\n", g.Name, g.Type)
+	}
+	for i, f := range p.Funcs {
+		if i > 0 || len(p.Globals) > 0 {
+			fmt.Fprintln(w)
+		}
+		io.WriteString(w, f.String())
+	}
+}
+
+// String returns the listing of f.
+func (f *Func) String() string {
+	var b strings.Builder
+	params := make([]string, len(f.Params))
+	for i, p := range f.Params {
+		params[i] = fmt.Sprintf("v%d : %s", p.ID, p.Type)
+	}
+	fmt.Fprintf(&b, "func %s(%s)", f.Name, strings.Join(params, ", "))
+	if !isVoid(f.Result) {
+		fmt.Fprintf(&b, " -> %s", f.Result)
+	}
+	b.WriteString("\n")
+	for _, blk := range f.Blocks {
+		fmt.Fprintf(&b, "  b%d:", blk.ID)
+		if len(blk.Preds) > 0 {
+			b.WriteString(" <-")
+			for _, p := range blk.Preds {
+				fmt.Fprintf(&b, " b%d", p.ID)
+			}
+		}
+		b.WriteString("\n")
+		for _, v := range blk.Values {
+			fmt.Fprintf(&b, "    %s\n", v.LongString())
+		}
+		switch blk.Kind {
+		case Plain:
+			if len(blk.Succs) == 1 {
+				fmt.Fprintf(&b, "    jump b%d\n", blk.Succs[0].ID)
+			}
+		case If:
+			fmt.Fprintf(&b, "    if %s -> b%d, b%d\n", blk.Control, blk.Succs[0].ID, blk.Succs[1].ID)
+		case Ret:
+			if blk.Control != nil {
+				fmt.Fprintf(&b, "    ret %s\n", blk.Control)
+			} else {
+				b.WriteString("    ret\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// String returns the name of v, such as "v3".
+func (v *Value) String() string {
+	return "v" + strconv.Itoa(v.ID)
+}
+
+// LongString returns v as a listing shows it: "v3 = add v1, v2 : san".
+// Values that produce nothing show only the operation.
+func (v *Value) LongString() string {
+	var b strings.Builder
+	if !isVoid(v.Type) {
+		fmt.Fprintf(&b, "%s = ", v)
+	}
+	b.WriteString(v.Op.String())
+	sep := " "
+	if aux := v.auxString(); aux != "" {
+		b.WriteString(" " + aux)
+		sep = ", "
+	}
+	if v.Op == OpCall || v.Op == OpBuiltin {
+		b.WriteString("(")
+		sep = ""
+	}
+	for i, a := range v.Args {
+		if i > 0 {
+			sep = ", "
+		}
+		b.WriteString(sep + a.String())
+	}
+	if v.Op == OpCall || v.Op == OpBuiltin {
+		b.WriteString(")")
+	}
+	if !isVoid(v.Type) {
+		fmt.Fprintf(&b, " : %s", v.Type)
+	}
+	return b.String()
+}
+
+func (v *Value) auxString() string {
+	switch aux := v.Aux.(type) {
+	case *Func:
+		return aux.Name
+	case *Global:
+		return aux.Name
+	case string:
+		if v.Op == OpConst {
+			return strconv.Quote(aux)
+		}
+		return aux
+	case int64:
+		if v.Op == OpConst && isU64(v.Type) {
+			return strconv.FormatUint(uint64(aux), 10)
+		}
+		if v.Op == OpConst && isBool(v.Type) {
+			return strconv.FormatBool(aux != 0)
+		}
+		return strconv.FormatInt(aux, 10)
+	case float64:
+		return strconv.FormatFloat(aux, 'g', -1, 64)
+	case decimal.Decimal:
+		return aux.String()
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+// FILE: internal/ir/tailcall.go
+
+package ir
+
+// tailcall turns the calls a function makes to itself just before it
+// returns into jumps back to its start, so that the recursion runs as a
+// loop in constant stack space. The parameters become phis of the old
+// entry block, which the calls jump to with their arguments. The
+// backends, which compile from the syntax tree, find the same calls with
+// ast.TailCalls.
+func tailcall(f *Func) {
+	var tails []*Value
+	for _, b := range f.Blocks {
+		if v := tailCall(f, b); v != nil {
+			tails = append(tails, v)
+		}
+	}
+	if len(tails) == 0 {
+		return
+	}
+
+	entry := f.Blocks[0]
+	start := f.NewBlock()
+	f.Blocks = append([]*Block{start}, f.Blocks[:len(f.Blocks)-1]...)
+	start.addEdge(entry)
+	phis := make(map[*Value]*Value)
+	for _, p := range f.Params {
+		phi := entry.insertPhi(p.Type, p.Pos)
+		phi.Args = []*Value{p}
+		phis[p] = phi
+	}
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			for i, a := range v.Args {
+				if phi := phis[a]; phi != nil && v != phi {
+					v.Args[i] = phi
+				}
+			}
+		}
+		if phi := phis[b.Control]; phi != nil {
+			b.Control = phi
+		}
+	}
+
+	for _, call := range tails {
+		b := call.Block
+		for i, p := range f.Params {
+			phi := phis[p]
+			phi.Args = append(phi.Args, call.Args[i])
+		}
+		b.Values = b.Values[:len(b.Values)-1]
+		b.Kind, b.Control = Plain, nil
+		b.addEdge(entry)
+	}
+	f.simplifyPhis()
+}
+
+// tailCall returns the call of f that b returns the result of, if it
+// ends that way.
+func tailCall(f *Func, b *Block) *Value {
+	if b.Kind != Ret || len(b.Values) == 0 {
+		return nil
+	}
+	v := b.Values[len(b.Values)-1]
+	if v.Op != OpCall || v.Aux.(*Func) != f {
+		return nil
+	}
+	if b.Control != v && !(b.Control == nil && isVoid(v.Type)) {
+		return nil
+	}
+	return v
+}
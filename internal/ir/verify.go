@@ -0,0 +1,108 @@
+// FILE: internal/ir/verify.go
+
+package ir
+
+import "fmt"
+
+// Verify checks that f is well formed: the edges agree in both
+// directions, each block ends as its kind says, phis come first with one
+// argument per predecessor, and every value is computed before it is
+// used.
+func (f *Func) Verify() error {
+	if len(f.Blocks) == 0 {
+		return fmt.Errorf("%s: no blocks", f.Name)
+	}
+	if len(f.Blocks[0].Preds) > 0 {
+		return fmt.Errorf("%s: the entry block has predecessors", f.Name)
+	}
+	index := make(map[*Value]int)
+	for _, p := range f.Params {
+		index[p] = -1
+	}
+	in := make(map[*Block]bool)
+	for _, b := range f.Blocks {
+		in[b] = true
+		for i, v := range b.Values {
+			index[v] = i
+		}
+	}
+	count := func(bs []*Block, x *Block) int {
+		n := 0
+		for _, b := range bs {
+			if b == x {
+				n++
+			}
+		}
+		return n
+	}
+	d := dominators(f)
+	for _, b := range f.Blocks {
+		want := map[BlockKind]int{Plain: 1, If: 2, Ret: 0}[b.Kind]
+		if len(b.Succs) != want {
+			return fmt.Errorf("%s: b%d has %d successors", f.Name, b.ID, len(b.Succs))
+		}
+		if b.Kind == If && b.Control == nil {
+			return fmt.Errorf("%s: b%d branches on nothing", f.Name, b.ID)
+		}
+		for _, s := range b.Succs {
+			if !in[s] || count(s.Preds, b) != count(b.Succs, s) {
+				return fmt.Errorf("%s: edge b%d -> b%d is one-sided", f.Name, b.ID, s.ID)
+			}
+		}
+		for _, p := range b.Preds {
+			if !in[p] || count(p.Succs, b) != count(b.Preds, p) {
+				return fmt.Errorf("%s: edge b%d -> b%d is one-sided", f.Name, p.ID, b.ID)
+			}
+		}
+		// uses checks that a is available at position pos of block at; a
+		// phi uses its i'th argument at the end of the i'th predecessor.
+		uses := func(user string, a *Value, at *Block, pos int) error {
+			i, ok := index[a]
+			if !ok {
+				return fmt.Errorf("%s: %s uses %s, which is not in the function", f.Name, user, a)
+			}
+			if _, reached := d.order[at]; i < 0 || !reached {
+				return nil
+			}
+			if a.Block == at && i >= pos {
+				return fmt.Errorf("%s: %s uses %s before it is computed", f.Name, user, a)
+			}
+			if !d.dominates(a.Block, at) {
+				return fmt.Errorf("%s: %s uses %s, which does not dominate b%d", f.Name, user, a, at.ID)
+			}
+			return nil
+		}
+		phis := true
+		for _, v := range b.Values {
+			if v.Block != b {
+				return fmt.Errorf("%s: %s is in b%d but says b%d", f.Name, v.LongString(), b.ID, v.Block.ID)
+			}
+			if v.Op != OpPhi {
+				phis = false
+				for _, a := range v.Args {
+					if err := uses(v.LongString(), a, b, index[v]); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if !phis {
+				return fmt.Errorf("%s: %s comes after other values", f.Name, v.LongString())
+			}
+			if len(v.Args) != len(b.Preds) {
+				return fmt.Errorf("%s: %s has %d arguments for %d predecessors", f.Name, v.LongString(), len(v.Args), len(b.Preds))
+			}
+			for i, a := range v.Args {
+				if err := uses(v.LongString(), a, b.Preds[i], len(b.Preds[i].Values)); err != nil {
+					return err
+				}
+			}
+		}
+		if b.Control != nil {
+			if err := uses(fmt.Sprintf("b%d", b.ID), b.Control, b, len(b.Values)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
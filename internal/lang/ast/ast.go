@@ -3,14 +3,16 @@
 package ast
 
 import (
-	"bytes"
-	//"strings"
+	"strings"
 
+	"github.com/DauletBai/tenge/internal/lang/lexer"
 	"github.com/DauletBai/tenge/internal/lang/token"
 	"github.com/shopspring/decimal"
 )
 
-// Node represents a node in the Abstract Syntax Tree.
+// Node represents a node in the Abstract Syntax Tree. String returns the
+// node as source on one line, which parses back to the same tree; see
+// Printer for other layouts.
 type Node interface {
 	TokenLiteral() string
 	String() string
@@ -31,15 +33,11 @@ type Expression interface {
 // Program is the root node of the AST.
 type Program struct {
 	Statements []Statement
+	Comments   []token.Token // COMMENT tokens in source order
+	Syntax     lexer.Syntax  // keyword set selected by the #syntax pragma
 }
 
-func (p *Program) String() string {
-	var out bytes.Buffer
-	for _, s := range p.Statements {
-		out.WriteString(s.String())
-	}
-	return out.String()
-}
+func (p *Program) String() string { return printString(p) }
 func (p *Program) TokenLiteral() string {
 	if len(p.Statements) > 0 {
 		return p.Statements[0].TokenLiteral()
@@ -52,61 +50,202 @@ type Identifier struct {
 	Token token.Token // The IDENT token
 	Value string
 }
+
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
-func (i *Identifier) String() string       { return i.Value }
+func (i *Identifier) String() string       { return printString(i) }
 
-// TypeNode represents a type annotation (e.g., ': san').
+// TypeNode represents a type annotation (e.g., ': san', ': []f64', ': &u64',
+// ': arna[f64]').
 type TypeNode struct {
-	Token token.Token // The type token (e.g., token.SAN)
+	Token token.Token // The type token (e.g., token.SAN), '[' for slices, '&' for pointers or 'arna' for channels
+	Name  string      // The type name; the Kazakh spelling for type keywords
+	Elem  *TypeNode   // Element type for '[]T', '&T' and 'arna[T]'
 }
+
 func (tn *TypeNode) expressionNode()      {}
 func (tn *TypeNode) TokenLiteral() string { return tn.Token.Literal }
-func (tn *TypeNode) String() string       { return tn.Token.Literal }
+func (tn *TypeNode) String() string       { return printString(tn) }
 
 // BekitStatement represents a constant declaration (`bekit`).
 type BekitStatement struct {
-	Token token.Token // The 'bekit' token
-	Name  *Identifier
-	Type  *TypeNode
-	Value Expression
+	Token  token.Token // The 'bekit' token
+	Name   *Identifier
+	Type   *TypeNode
+	Value  Expression
+	Public bool // declared `ashyq bekit`: visible to importing modules
 }
+
 func (bs *BekitStatement) statementNode()       {}
 func (bs *BekitStatement) TokenLiteral() string { return bs.Token.Literal }
-func (bs *BekitStatement) String() string       { return "bekit statement" } // Placeholder
+func (bs *BekitStatement) String() string       { return printString(bs) }
 
 // JasaStatement represents a variable declaration (`jasa`).
 type JasaStatement struct {
-	Token token.Token // The 'jasa' token
-	Name  *Identifier
-	Type  *TypeNode
-	Value Expression
+	Token  token.Token // The 'jasa' token
+	Name   *Identifier
+	Type   *TypeNode
+	Value  Expression
+	Public bool // declared `ashyq jasa`: visible to importing modules
 }
+
 func (js *JasaStatement) statementNode()       {}
 func (js *JasaStatement) TokenLiteral() string { return js.Token.Literal }
-func (js *JasaStatement) String() string       { return "jasa statement" } // Placeholder
+func (js *JasaStatement) String() string       { return printString(js) }
 
 // QaıtarStatement represents a return statement (`qaıtar`).
 type QaıtarStatement struct {
 	Token       token.Token // The 'qaıtar' token
-	ReturnValue Expression
+	ReturnValue Expression  // nil for a bare `qaıtar`
 }
+
 func (qs *QaıtarStatement) statementNode()       {}
 func (qs *QaıtarStatement) TokenLiteral() string { return qs.Token.Literal }
-func (qs *QaıtarStatement) String() string       { return "qaıtar statement" } // Placeholder
+func (qs *QaıtarStatement) String() string       { return printString(qs) }
+
+// ModulStatement names the module a file belongs to (`modul rng`).
+type ModulStatement struct {
+	Token token.Token // The 'modul' token
+	Name  *Identifier
+}
+
+func (ms *ModulStatement) statementNode()       {}
+func (ms *ModulStatement) TokenLiteral() string { return ms.Token.Literal }
+func (ms *ModulStatement) String() string       { return printString(ms) }
+
+// EngizStatement imports a module (`engiz "stats/rng"`). Its exported
+// names are used qualified by the last element of the path: `rng.next`.
+type EngizStatement struct {
+	Token token.Token // The 'engiz' token
+	Path  *JolLiteral
+}
+
+func (es *EngizStatement) statementNode()       {}
+func (es *EngizStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *EngizStatement) String() string       { return printString(es) }
+
+// Name returns the qualifier the import binds.
+func (es *EngizStatement) Name() string {
+	path := es.Path.Value
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
 
 // ExpressionStatement is a statement that consists of a single expression.
 type ExpressionStatement struct {
 	Token      token.Token
 	Expression Expression
 }
+
 func (es *ExpressionStatement) statementNode()       {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
-func (es *ExpressionStatement) String() string {
-	if es.Expression != nil {
-		return es.Expression.String()
-	}
-	return ""
+func (es *ExpressionStatement) String() string       { return printString(es) }
+
+// BadStmt is a placeholder for a statement that failed to parse. It spans
+// the tokens the parser skipped to get back in sync.
+type BadStmt struct {
+	From token.Token // The first token of the statement
+	To   token.Token // The last token skipped
+}
+
+func (bs *BadStmt) statementNode()       {}
+func (bs *BadStmt) TokenLiteral() string { return bs.From.Literal }
+func (bs *BadStmt) String() string       { return "BadStmt" }
+
+// BadExpr is a placeholder for an expression that failed to parse, kept so
+// that the statement around it (`jasa x = <error>`) still declares x.
+type BadExpr struct {
+	Token token.Token // The first token of the expression
+}
+
+func (be *BadExpr) expressionNode()      {}
+func (be *BadExpr) TokenLiteral() string { return be.Token.Literal }
+func (be *BadExpr) String() string       { return "BadExpr" }
+
+// AssignStatement assigns to an existing variable, array element or
+// pointer target (`x = 1`, `a[i] = 2`, `*p = 3`).
+type AssignStatement struct {
+	Token  token.Token // The '=' token
+	Target Expression
+	Value  Expression
+}
+
+func (as *AssignStatement) statementNode()       {}
+func (as *AssignStatement) TokenLiteral() string { return as.Token.Literal }
+func (as *AssignStatement) String() string       { return printString(as) }
+
+// BlockStatement is a braced list of statements.
+type BlockStatement struct {
+	Token      token.Token // The '{' token
+	Statements []Statement
+	Rbrace     token.Token // The '}' token; EOF if the block is not closed
+}
+
+func (bs *BlockStatement) statementNode()       {}
+func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) String() string       { return printString(bs) }
+
+// AzirsheStatement represents a loop (`ázirshe cond { ... }`).
+type AzirsheStatement struct {
+	Token     token.Token // The 'ázirshe' token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (as *AzirsheStatement) statementNode()       {}
+func (as *AzirsheStatement) TokenLiteral() string { return as.Token.Literal }
+func (as *AzirsheStatement) String() string       { return printString(as) }
+
+// KutStatement runs its block and then waits for the tasks started in it
+// (`kút { ... }`, latin `join { ... }`).
+type KutStatement struct {
+	Token token.Token // The 'kút' token
+	Body  *BlockStatement
+}
+
+func (ks *KutStatement) statementNode()       {}
+func (ks *KutStatement) TokenLiteral() string { return ks.Token.Literal }
+func (ks *KutStatement) String() string       { return printString(ks) }
+
+// MindetStatement starts a call as a task of the enclosing kút block
+// (`mindet x = f(a)`, latin `task x = f(a)`). The arguments are computed
+// when the task starts; the result is assigned to Target when the block
+// has waited for it.
+type MindetStatement struct {
+	Token  token.Token // The 'mindet' token
+	Target *Identifier // nil when the result is not kept
+	Call   *CallExpression
+}
+
+func (ms *MindetStatement) statementNode()       {}
+func (ms *MindetStatement) TokenLiteral() string { return ms.Token.Literal }
+func (ms *MindetStatement) String() string       { return printString(ms) }
+
+// TandaStatement waits until one of its clauses can go on and runs that
+// clause (`tańda { ... }`, latin `select { ... }`). When several can, the
+// first one in the source wins.
+type TandaStatement struct {
+	Token   token.Token // The 'tańda' token
+	Clauses []*TandaClause
+	Rbrace  token.Token // The '}' closing the clauses
+}
+
+func (ts *TandaStatement) statementNode()       {}
+func (ts *TandaStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *TandaStatement) String() string       { return printString(ts) }
+
+// TandaClause is one clause of a tańda statement: `recv(c) { ... }`,
+// `x = recv(c) { ... }`, `jasa x = recv(c) { ... }`, `send(c, v) { ... }`,
+// `timeout(ms) { ... }` or `áıtpece { ... }`, which runs when no other
+// clause can go on at once.
+type TandaClause struct {
+	Token  token.Token     // The first token of the clause
+	Define bool            // `jasa x = recv(c)`: x is declared for the body
+	Target *Identifier     // the variable given the received value, or nil
+	Comm   *CallExpression // nil for áıtpece
+	Body   *BlockStatement
 }
 
 // --- Expression Nodes ---
@@ -116,6 +255,7 @@ type SanLiteral struct {
 	Token token.Token
 	Value int64
 }
+
 func (sl *SanLiteral) expressionNode()      {}
 func (sl *SanLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *SanLiteral) String() string       { return sl.Token.Literal }
@@ -125,15 +265,232 @@ type AqshaLiteral struct {
 	Token token.Token
 	Value decimal.Decimal
 }
+
 func (al *AqshaLiteral) expressionNode()      {}
 func (al *AqshaLiteral) TokenLiteral() string { return al.Token.Literal }
 func (al *AqshaLiteral) String() string       { return al.Token.Literal }
 
+// IsNumberLiteral reports whether x is a number literal, possibly negated.
+func IsNumberLiteral(x Expression) bool {
+	switch x := x.(type) {
+	case *SanLiteral, *AqshaLiteral:
+		return true
+	case *PrefixExpression:
+		return x.Operator == "-" && IsNumberLiteral(x.Right)
+	}
+	return false
+}
+
+// NumberValue returns the exact value of a number literal.
+func NumberValue(x Expression) decimal.Decimal {
+	switch x := x.(type) {
+	case *SanLiteral:
+		return decimal.NewFromInt(x.Value)
+	case *AqshaLiteral:
+		return x.Value
+	case *PrefixExpression:
+		return NumberValue(x.Right).Neg()
+	}
+	return decimal.Zero
+}
+
 // AqıqatLiteral represents a boolean literal (`jan` or `j'n`).
 type AqıqatLiteral struct {
 	Token token.Token
 	Value bool
 }
+
 func (al *AqıqatLiteral) expressionNode()      {}
 func (al *AqıqatLiteral) TokenLiteral() string { return al.Token.Literal }
-func (al *AqıqatLiteral) String() string       { return al.Token.Literal }
\ No newline at end of file
+func (al *AqıqatLiteral) String() string       { return al.Token.Literal }
+
+// JolLiteral represents a string literal.
+type JolLiteral struct {
+	Token token.Token
+	Value string
+}
+
+func (jl *JolLiteral) expressionNode()      {}
+func (jl *JolLiteral) TokenLiteral() string { return jl.Token.Literal }
+func (jl *JolLiteral) String() string       { return quote(jl.Value) }
+
+// JyimLiteral represents an array literal (`[1, 2, 3]`).
+type JyimLiteral struct {
+	Token    token.Token // The '[' token
+	Elements []Expression
+}
+
+func (jl *JyimLiteral) expressionNode()      {}
+func (jl *JyimLiteral) TokenLiteral() string { return jl.Token.Literal }
+func (jl *JyimLiteral) String() string       { return printString(jl) }
+
+// PrefixExpression is a unary operator application (`-x`, `!ok`, `&x`, `*p`).
+type PrefixExpression struct {
+	Token    token.Token // The operator token
+	Operator string
+	Right    Expression
+}
+
+func (pe *PrefixExpression) expressionNode()      {}
+func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) String() string       { return printString(pe) }
+
+// InfixExpression is a binary operator application (`a + b`).
+type InfixExpression struct {
+	Token    token.Token // The operator token
+	Left     Expression
+	Operator string
+	Right    Expression
+}
+
+func (ie *InfixExpression) expressionNode()      {}
+func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) String() string       { return printString(ie) }
+
+// EgerExpression represents a conditional (`eger cond { ... } áıtpece { ... }`).
+// It is an expression so that both branches may yield a value.
+type EgerExpression struct {
+	Token       token.Token // The 'eger' token
+	Condition   Expression
+	Consequence *BlockStatement
+	Alternative *BlockStatement // nil when there is no `áıtpece`
+}
+
+func (ee *EgerExpression) expressionNode()      {}
+func (ee *EgerExpression) TokenLiteral() string { return ee.Token.Literal }
+func (ee *EgerExpression) String() string       { return printString(ee) }
+
+// Parameter is a single function parameter with an optional type.
+type Parameter struct {
+	Name *Identifier
+	Type *TypeNode // nil when the parameter is untyped
+}
+
+func (p *Parameter) String() string {
+	if p.Type == nil {
+		return p.Name.String()
+	}
+	return p.Name.String() + ": " + p.Type.String()
+}
+
+// TypeParam is a type parameter of a generic function (`T: Ordered`).
+type TypeParam struct {
+	Name       *Identifier
+	Constraint *Identifier // nil when any type is allowed
+}
+
+func (tp *TypeParam) String() string {
+	if tp.Constraint == nil {
+		return tp.Name.String()
+	}
+	return tp.Name.String() + ": " + tp.Constraint.String()
+}
+
+// AtqarmLiteral represents a function (`atqar'm (a, b: san) -> san { ... }`).
+// A generic function lists its type parameters first:
+// `atqar'm [T: Ordered] (a: []T) -> T { ... }`.
+type AtqarmLiteral struct {
+	Token      token.Token // The 'atqar'm' token
+	Name       string      // Name of the binding it was declared with, if any
+	TypeParams []*TypeParam
+	Parameters []*Parameter
+	ReturnType *TypeNode // nil when the function returns nothing or is untyped
+	Body       *BlockStatement
+}
+
+func (al *AtqarmLiteral) expressionNode()      {}
+func (al *AtqarmLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *AtqarmLiteral) String() string       { return printString(al) }
+
+// FuncDecl returns the function literal bound by a declaration, if any.
+func FuncDecl(s Statement) (*Identifier, *AtqarmLiteral) {
+	switch s := s.(type) {
+	case *JasaStatement:
+		if fn, ok := s.Value.(*AtqarmLiteral); ok {
+			return s.Name, fn
+		}
+	case *BekitStatement:
+		if fn, ok := s.Value.(*AtqarmLiteral); ok {
+			return s.Name, fn
+		}
+	}
+	return nil, nil
+}
+
+// CallExpression represents a function call or a type conversion (`f64(x)`).
+type CallExpression struct {
+	Token     token.Token // The '(' token
+	Function  Expression  // Identifier or AtqarmLiteral
+	Arguments []Expression
+}
+
+func (ce *CallExpression) expressionNode()      {}
+func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) String() string       { return printString(ce) }
+
+// SelectorExpression is a name qualified by a module (`rng.next`).
+type SelectorExpression struct {
+	Token token.Token // The '.' token
+	X     Expression
+	Sel   *Identifier
+}
+
+func (se *SelectorExpression) expressionNode()      {}
+func (se *SelectorExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SelectorExpression) String() string       { return printString(se) }
+
+// IndexExpression represents element access (`a[i]`).
+type IndexExpression struct {
+	Token token.Token // The '[' token
+	Left  Expression
+	Index Expression
+}
+
+func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) String() string       { return printString(ie) }
+
+// QatarlasExpression is a parallel loop (`qatarlas ár i ishinde 0..n { ... }`,
+// latin `parallel for i in 0..n { ... }`). The body runs once for every i
+// from From up to but not including To, spread over threads in blocks of
+// consecutive i. Followed by a reduction (`... 0..n sum { x }`) the loop
+// is a value made of the values its body ends in; see Reduction.
+type QatarlasExpression struct {
+	Token  token.Token // The 'qatarlas' token
+	Var    *Identifier
+	From   Expression
+	To     Expression
+	Reduce *Identifier // nil for a loop without a value
+	Body   *BlockStatement
+}
+
+func (qe *QatarlasExpression) expressionNode()      {}
+func (qe *QatarlasExpression) TokenLiteral() string { return qe.Token.Literal }
+func (qe *QatarlasExpression) String() string       { return printString(qe) }
+
+// Reductions of a parallel loop, by their names in both syntaxes.
+const (
+	Sum     = "sum"     // the values added up in order of i
+	Min     = "min"     // the least value, the first of equal ones
+	Max     = "max"     // the greatest value, the first of equal ones
+	Collect = "collect" // an array of the values in order of i
+)
+
+var reductions = map[string]string{
+	"sum": Sum, "qosyndy": Sum, "қосынды": Sum,
+	"min": Min, "kishi": Min, "кіші": Min,
+	"max": Max, "úlken": Max, "ulken": Max, "үлкен": Max,
+	"collect": Collect, "jına": Collect, "jina": Collect, "жина": Collect,
+}
+
+// Reduction returns the reduction of qe (Sum, Min, Max or Collect), ""
+// for a loop without one and "?" for an unknown name.
+func (qe *QatarlasExpression) Reduction() string {
+	if qe.Reduce == nil {
+		return ""
+	}
+	if r, ok := reductions[qe.Reduce.Value]; ok {
+		return r
+	}
+	return "?"
+}
@@ -0,0 +1,84 @@
+// FILE: internal/lang/ast/print.go
+
+package ast
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/DauletBai/tenge/internal/lang/token"
+)
+
+// Fprint writes an indented tree dump of node to w, one field per line.
+// It is meant for debugging (`tenge emit-ast`), not for round-tripping.
+func Fprint(w io.Writer, node Node) error {
+	p := &dumper{w: w}
+	p.value(reflect.ValueOf(node), 0)
+	p.line(0, "")
+	return p.err
+}
+
+type dumper struct {
+	w   io.Writer
+	err error
+	buf strings.Builder
+}
+
+func (p *dumper) line(depth int, s string) {
+	if p.err != nil {
+		return
+	}
+	if p.buf.Len() > 0 {
+		_, p.err = io.WriteString(p.w, p.buf.String()+"\n")
+		p.buf.Reset()
+	}
+	if s != "" {
+		p.buf.WriteString(strings.Repeat(".  ", depth))
+		p.buf.WriteString(s)
+	}
+}
+
+func (p *dumper) value(v reflect.Value, depth int) {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			p.buf.WriteString("nil")
+			return
+		}
+		p.value(v.Elem(), depth)
+	case reflect.Slice:
+		p.buf.WriteString(fmt.Sprintf("[]%s (len = %d) {", v.Type().Elem(), v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			p.line(depth+1, fmt.Sprintf("%d: ", i))
+			p.value(v.Index(i), depth+1)
+		}
+		if v.Len() > 0 {
+			p.line(depth, "}")
+		} else {
+			p.buf.WriteString("}")
+		}
+	case reflect.Struct:
+		if tok, ok := v.Interface().(token.Token); ok {
+			p.buf.WriteString(fmt.Sprintf("%s %q @%s", tok.Type, tok.Literal, tok.Pos()))
+			return
+		}
+		if s, ok := v.Interface().(fmt.Stringer); ok && v.Type().PkgPath() != reflect.TypeOf(Program{}).PkgPath() {
+			p.buf.WriteString(s.String())
+			return
+		}
+		p.buf.WriteString(v.Type().String() + " {")
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			p.line(depth+1, f.Name+": ")
+			p.value(v.Field(i), depth+1)
+		}
+		p.line(depth, "}")
+	default:
+		p.buf.WriteString(fmt.Sprintf("%#v", v.Interface()))
+	}
+}
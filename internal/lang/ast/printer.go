@@ -0,0 +1,706 @@
+// FILE: internal/lang/ast/printer.go
+
+package ast
+
+import (
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/DauletBai/tenge/internal/lang/lexer"
+	"github.com/DauletBai/tenge/internal/lang/token"
+)
+
+// PrintMode selects how a Printer lays out code.
+type PrintMode uint
+
+const (
+	// Multiline puts every statement on a line of its own and indents
+	// blocks by four spaces. A block written on one line stays on one
+	// line. When printing a Program, its comments are kept, and so are
+	// single blank lines between statements if the Printer has the Source.
+	// Without Multiline blocks are printed as `{ a; b }`.
+	Multiline PrintMode = 1 << iota
+
+	// Parens puts every operator application in parentheses, as in
+	// `(a + (b * (-c)))`, to show how an expression was grouped.
+	Parens
+)
+
+const indent = "    "
+
+// Printer prints syntax trees as tenge source. Keywords keep the spelling
+// they were written with; expressions get only the parentheses they need
+// and statements lose their semicolons.
+type Printer struct {
+	Mode   PrintMode
+	Source string // the text the tree was parsed from, if known
+}
+
+// Print writes node to w. In Multiline mode a Program ends with a newline.
+func (cfg *Printer) Print(w io.Writer, node Node) error {
+	p := &printer{Printer: *cfg}
+	if program, ok := node.(*Program); ok && p.multiline() {
+		p.comments = program.Comments
+	}
+	p.node(node)
+	if _, ok := node.(*Program); ok && p.multiline() {
+		p.flush(math.MaxInt)
+		if p.out.Len() > 0 {
+			p.out.WriteString("\n")
+		}
+	}
+	_, err := io.WriteString(w, p.out.String())
+	return err
+}
+
+// printString prints node on one line, for the String methods.
+func printString(node Node) string {
+	var out strings.Builder
+	(&Printer{}).Print(&out, node)
+	return out.String()
+}
+
+type printer struct {
+	Printer
+	out      strings.Builder
+	comments []token.Token // not printed yet
+	depth    int           // of block nesting
+	fresh    bool          // at the start of a block: no blank line before the next line
+	lines    []string      // of Source, split on demand
+}
+
+func (p *printer) multiline() bool { return p.Mode&Multiline != 0 }
+
+func (p *printer) word(s string) { p.out.WriteString(s) }
+
+// keyword writes the keyword tok as written, or in its main spelling if
+// tok was not read from source.
+func (p *printer) keyword(tok token.Token, tt token.TokenType) {
+	if tok.Literal != "" {
+		p.word(tok.Literal)
+		return
+	}
+	p.word(string(tt))
+}
+
+// line starts a new line for code from line n of the source. It keeps a
+// blank line above n, but not at the start of a block or of the output.
+func (p *printer) line(n int) {
+	if p.out.Len() > 0 {
+		p.word("\n")
+		if !p.fresh && p.blankBefore(n) {
+			p.word("\n")
+		}
+	}
+	p.fresh = false
+	p.word(strings.Repeat(indent, p.depth))
+}
+
+func (p *printer) blankBefore(n int) bool {
+	if p.Source == "" || n < 2 {
+		return false
+	}
+	if p.lines == nil {
+		p.lines = strings.Split(p.Source, "\n")
+	}
+	return n-2 < len(p.lines) && strings.TrimSpace(p.lines[n-2]) == ""
+}
+
+// flush prints the comments that start before offset. A comment that
+// follows code on its line stays at the end of the line printed last;
+// the others get lines of their own.
+func (p *printer) flush(offset int) {
+	for len(p.comments) > 0 && p.comments[0].Offset < offset {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		if p.out.Len() > 0 && p.trailing(c) {
+			p.word(" " + c.Literal)
+			continue
+		}
+		p.line(c.Line)
+		p.word(c.Literal)
+	}
+}
+
+func (p *printer) trailing(c token.Token) bool {
+	if c.Offset > len(p.Source) {
+		return false
+	}
+	start := strings.LastIndexByte(p.Source[:c.Offset], '\n') + 1
+	return strings.TrimSpace(p.Source[start:c.Offset]) != ""
+}
+
+func (p *printer) node(node Node) {
+	switch n := node.(type) {
+	case *Program:
+		p.statements(n.Statements)
+	case Statement:
+		p.stmt(n)
+	case Expression:
+		p.expr(n, precLowest)
+	}
+}
+
+// statements prints a list of statements: one per line with their
+// comments in Multiline mode, separated by semicolons otherwise.
+func (p *printer) statements(list []Statement) {
+	for i, s := range list {
+		if p.multiline() {
+			start := stmtStart(s)
+			p.flush(start.Offset)
+			p.line(start.Line)
+		} else if i > 0 {
+			p.word("; ")
+		}
+		p.stmt(s)
+	}
+}
+
+func (p *printer) block(b *BlockStatement) {
+	if !p.multiline() || (b.Token.Line > 0 && b.Token.Line == b.Rbrace.Line) {
+		if len(b.Statements) == 0 {
+			p.word("{}")
+			return
+		}
+		mode := p.Mode
+		p.Mode &^= Multiline
+		p.word("{ ")
+		p.statements(b.Statements)
+		p.word(" }")
+		p.Mode = mode
+		return
+	}
+	p.word("{")
+	empty := p.out.Len()
+	p.depth++
+	p.fresh = true
+	p.statements(b.Statements)
+	if b.Rbrace.Type == token.RBRACE {
+		p.flush(b.Rbrace.Offset)
+	}
+	p.depth--
+	p.fresh = false
+	if p.out.Len() > empty {
+		p.word("\n" + strings.Repeat(indent, p.depth))
+	}
+	p.word("}")
+}
+
+func (p *printer) stmt(s Statement) {
+	switch s := s.(type) {
+	case *JasaStatement:
+		p.public(s.Public, s.Token)
+		p.binding(s.Token, token.JASA, s.Name, s.Type, s.Value)
+	case *BekitStatement:
+		p.public(s.Public, s.Token)
+		if fn, ok := s.Value.(*AtqarmLiteral); ok && s.Token.Type == token.ATQARM {
+			p.function(fn, s.Name)
+			return
+		}
+		p.binding(s.Token, token.BEKIT, s.Name, s.Type, s.Value)
+	case *QaıtarStatement:
+		p.keyword(s.Token, token.QAITAR)
+		if s.ReturnValue != nil {
+			p.word(" ")
+			p.expr(s.ReturnValue, precLowest)
+		}
+	case *AzirsheStatement:
+		p.keyword(s.Token, token.AZIRSHE)
+		p.word(" ")
+		p.expr(s.Condition, precLowest)
+		p.word(" ")
+		p.block(s.Body)
+	case *KutStatement:
+		p.keyword(s.Token, token.KUT)
+		p.word(" ")
+		p.block(s.Body)
+	case *MindetStatement:
+		p.keyword(s.Token, token.MINDET)
+		p.word(" ")
+		if s.Target != nil {
+			p.ident(s.Target)
+			p.word(" = ")
+		}
+		p.expr(s.Call, precLowest)
+	case *TandaStatement:
+		p.keyword(s.Token, token.TANDA)
+		p.word(" ")
+		p.tanda(s)
+	case *ModulStatement:
+		p.keyword(s.Token, token.MODUL)
+		p.word(" ")
+		p.ident(s.Name)
+	case *EngizStatement:
+		p.keyword(s.Token, token.ENGIZ)
+		p.word(" ")
+		p.expr(s.Path, precLowest)
+	case *ExpressionStatement:
+		if s.Expression != nil {
+			p.expr(s.Expression, precLowest)
+		}
+	case *AssignStatement:
+		p.expr(s.Target, precLowest)
+		p.word(" = ")
+		p.expr(s.Value, precLowest)
+	case *BlockStatement:
+		p.block(s)
+	case *BadStmt:
+		p.word("BadStmt")
+	}
+}
+
+// tanda writes the clauses of a tańda statement like the statements of a
+// block, one per line in Multiline mode.
+func (p *printer) tanda(s *TandaStatement) {
+	if !p.multiline() || (s.Token.Line > 0 && s.Token.Line == s.Rbrace.Line) {
+		if len(s.Clauses) == 0 {
+			p.word("{}")
+			return
+		}
+		mode := p.Mode
+		p.Mode &^= Multiline
+		p.word("{ ")
+		for i, cl := range s.Clauses {
+			if i > 0 {
+				p.word("; ")
+			}
+			p.clause(cl)
+		}
+		p.word(" }")
+		p.Mode = mode
+		return
+	}
+	p.word("{")
+	empty := p.out.Len()
+	p.depth++
+	p.fresh = true
+	for _, cl := range s.Clauses {
+		p.flush(cl.Token.Offset)
+		p.line(cl.Token.Line)
+		p.clause(cl)
+	}
+	if s.Rbrace.Type == token.RBRACE {
+		p.flush(s.Rbrace.Offset)
+	}
+	p.depth--
+	p.fresh = false
+	if p.out.Len() > empty {
+		p.word("\n" + strings.Repeat(indent, p.depth))
+	}
+	p.word("}")
+}
+
+func (p *printer) clause(cl *TandaClause) {
+	if cl.Define {
+		p.keyword(cl.Token, token.JASA)
+		p.word(" ")
+	}
+	if cl.Target != nil {
+		p.ident(cl.Target)
+		p.word(" = ")
+	}
+	if cl.Comm != nil {
+		p.expr(cl.Comm, precLowest)
+	} else {
+		p.keyword(cl.Token, token.AITPECE)
+	}
+	p.word(" ")
+	p.block(cl.Body)
+}
+
+// public writes `ashyq ` for a public declaration, spelled like its
+// keyword kw.
+func (p *printer) public(public bool, kw token.Token) {
+	if public {
+		p.word(lexer.Respell(token.ASHYQ, kw.Literal) + " ")
+	}
+}
+
+// binding writes `jasa name: T = value`. A function with its type spelled
+// out, `jasa f: atqar'm (x: san) { ... }`, keeps that form.
+func (p *printer) binding(kw token.Token, tt token.TokenType, name *Identifier, typ *TypeNode, value Expression) {
+	p.keyword(kw, tt)
+	p.word(" ")
+	p.ident(name)
+	if typ != nil {
+		p.word(": ")
+		if isFuncType(typ, value) {
+			p.function(value.(*AtqarmLiteral), nil)
+			return
+		}
+		p.typ(typ)
+	}
+	if value != nil {
+		p.word(" = ")
+		p.expr(value, precLowest)
+	}
+}
+
+// function writes a function literal, or a function declaration
+// `atqar'm name(...)` if name is not nil.
+func (p *printer) function(fn *AtqarmLiteral, name *Identifier) {
+	p.keyword(fn.Token, token.ATQARM)
+	sep := " "
+	if name != nil {
+		p.word(" ")
+		p.ident(name)
+		sep = ""
+	}
+	if len(fn.TypeParams) > 0 {
+		p.word(sep + "[")
+		for i, tp := range fn.TypeParams {
+			if i > 0 {
+				p.word(", ")
+			}
+			p.ident(tp.Name)
+			if tp.Constraint != nil {
+				p.word(": ")
+				p.ident(tp.Constraint)
+			}
+		}
+		p.word("]")
+	}
+	p.word(sep + "(")
+	p.items(len(fn.Parameters), func(i int) token.Token { return fn.Parameters[i].Name.Token }, func(i int) {
+		param := fn.Parameters[i]
+		p.ident(param.Name)
+		if param.Type != nil {
+			p.word(": ")
+			p.typ(param.Type)
+		}
+	})
+	p.word(")")
+	if fn.ReturnType != nil {
+		p.word(" -> ")
+		p.typ(fn.ReturnType)
+	}
+	p.word(" ")
+	p.block(fn.Body)
+}
+
+func (p *printer) typ(tn *TypeNode) {
+	switch tn.Token.Type {
+	case token.LBRACKET:
+		p.word("[]")
+		p.typ(tn.Elem)
+	case token.AMPERSAND:
+		p.word("&")
+		p.typ(tn.Elem)
+	case token.ARNA:
+		p.keyword(tn.Token, token.ARNA)
+		p.word("[")
+		p.typ(tn.Elem)
+		p.word("]")
+	default:
+		if tn.Token.Literal != "" {
+			p.word(tn.Token.Literal)
+		} else {
+			p.word(tn.Name)
+		}
+	}
+}
+
+func (p *printer) ident(id *Identifier) {
+	if id.Token.Literal != "" {
+		p.word(id.Token.Literal)
+		return
+	}
+	p.word(id.Value)
+}
+
+// Operator precedences, as in the parser.
+const (
+	precLowest = iota
+	precOr
+	precAnd
+	precCompare
+	precSum
+	precProduct
+	precPrefix
+	precCall
+	precPrimary
+)
+
+var binaryPrec = map[string]int{
+	"||": precOr,
+	"&&": precAnd,
+	"==": precCompare, "!=": precCompare, "<": precCompare, "<=": precCompare, ">": precCompare, ">=": precCompare,
+	"+": precSum, "-": precSum, "|": precSum, "^": precSum,
+	"*": precProduct, "/": precProduct, "%": precProduct, "<<": precProduct, ">>": precProduct, "&": precProduct,
+}
+
+// precedence returns how tightly e binds. Conditionals and function
+// literals extend as far right as they can, so they bind loosest.
+func precedence(e Expression) int {
+	switch e := e.(type) {
+	case *InfixExpression:
+		return binaryPrec[e.Operator]
+	case *PrefixExpression:
+		return precPrefix
+	case *CallExpression, *IndexExpression, *SelectorExpression:
+		return precCall
+	case *EgerExpression, *AtqarmLiteral, *QatarlasExpression:
+		return precLowest
+	}
+	return precPrimary
+}
+
+// expr writes e, in parentheses if it binds less tightly than prec or if
+// it is an operator application in Parens mode.
+func (p *printer) expr(e Expression, prec int) {
+	if precedence(e) < prec || p.Mode&Parens != 0 && isOperation(e) {
+		p.word("(")
+		p.expr1(e)
+		p.word(")")
+		return
+	}
+	p.expr1(e)
+}
+
+func isOperation(e Expression) bool {
+	switch e.(type) {
+	case *InfixExpression, *PrefixExpression:
+		return true
+	}
+	return false
+}
+
+// expr1 writes e without parentheses around it.
+func (p *printer) expr1(e Expression) {
+	switch e := e.(type) {
+	case *Identifier:
+		p.ident(e)
+	case *SanLiteral:
+		if e.Token.Literal != "" {
+			p.word(e.Token.Literal)
+		} else {
+			p.word(strconv.FormatInt(e.Value, 10))
+		}
+	case *AqshaLiteral:
+		if e.Token.Literal != "" {
+			p.word(e.Token.Literal)
+		} else {
+			p.word(e.Value.String())
+		}
+	case *AqıqatLiteral:
+		switch {
+		case e.Token.Literal != "":
+			p.word(e.Token.Literal)
+		case e.Value:
+			p.word(token.JAN)
+		default:
+			p.word(token.JYN)
+		}
+	case *JolLiteral:
+		p.word(quote(e.Value))
+	case *JyimLiteral:
+		p.word("[")
+		p.list(e.Elements)
+		p.word("]")
+	case *PrefixExpression:
+		p.word(e.Operator)
+		if _, ok := e.Right.(*PrefixExpression); ok {
+			p.expr(e.Right, precPrimary) // -(-x), not --x
+		} else {
+			p.expr(e.Right, precPrefix)
+		}
+	case *InfixExpression:
+		prec := binaryPrec[e.Operator]
+		p.expr(e.Left, prec)
+		p.word(" " + e.Operator + " ")
+		p.expr(e.Right, prec+1)
+	case *EgerExpression:
+		p.eger(e)
+	case *AtqarmLiteral:
+		p.function(e, nil)
+	case *QatarlasExpression:
+		p.keyword(e.Token, token.QATARLAS)
+		p.word(" " + lexer.Respell(token.AR, e.Token.Literal) + " ")
+		p.ident(e.Var)
+		p.word(" " + lexer.Respell(token.ISHINDE, e.Token.Literal) + " ")
+		p.expr(e.From, precSum)
+		p.word("..")
+		p.expr(e.To, precSum)
+		p.word(" ")
+		if e.Reduce != nil {
+			p.ident(e.Reduce)
+			p.word(" ")
+		}
+		p.block(e.Body)
+	case *CallExpression:
+		p.expr(e.Function, precCall)
+		p.word("(")
+		p.list(e.Arguments)
+		p.word(")")
+	case *IndexExpression:
+		p.expr(e.Left, precCall)
+		p.word("[")
+		p.expr(e.Index, precLowest)
+		p.word("]")
+	case *SelectorExpression:
+		p.expr(e.X, precCall)
+		p.word(".")
+		p.ident(e.Sel)
+	case *TypeNode:
+		p.typ(e)
+	case *BadExpr:
+		p.word("BadExpr")
+	}
+}
+
+func (p *printer) list(exprs []Expression) {
+	p.items(len(exprs), func(i int) token.Token { return exprStart(exprs[i]) }, func(i int) {
+		p.expr(exprs[i], precLowest)
+	})
+}
+
+// items writes n items separated by commas; start returns where item i
+// begins in the source. In Multiline mode the comments before an item are
+// printed there, and the item then starts a line of its own, indented one
+// level deeper, so that a comment stays with the item it follows.
+func (p *printer) items(n int, start func(i int) token.Token, item func(i int)) {
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			p.word(",")
+		}
+		if tok := start(i); p.multiline() && len(p.comments) > 0 && p.comments[0].Offset < tok.Offset {
+			p.depth++
+			p.flush(tok.Offset)
+			p.line(tok.Line)
+			p.depth--
+		} else if i > 0 {
+			p.word(" ")
+		}
+		item(i)
+	}
+}
+
+func (p *printer) eger(e *EgerExpression) {
+	p.keyword(e.Token, token.EGER)
+	p.word(" ")
+	p.expr(e.Condition, precLowest)
+	p.word(" ")
+	p.block(e.Consequence)
+	if e.Alternative == nil {
+		return
+	}
+	p.word(" " + lexer.Respell(token.AITPECE, e.Token.Literal) + " ")
+	if next := elseIf(e.Alternative); next != nil {
+		p.eger(next)
+		return
+	}
+	p.block(e.Alternative)
+}
+
+// elseIf returns the conditional of an `áıtpece eger` chain, which the
+// parser desugars into a block that starts where the eger does.
+func elseIf(b *BlockStatement) *EgerExpression {
+	if len(b.Statements) != 1 {
+		return nil
+	}
+	es, ok := b.Statements[0].(*ExpressionStatement)
+	if !ok {
+		return nil
+	}
+	next, ok := es.Expression.(*EgerExpression)
+	if !ok || next.Token.Line != b.Token.Line || next.Token.Column != b.Token.Column {
+		return nil
+	}
+	return next
+}
+
+// quote returns s as a string literal, with the escapes the lexer reads.
+func quote(s string) string {
+	var out strings.Builder
+	out.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\t':
+			out.WriteString(`\t`)
+		default:
+			out.WriteRune(r)
+		}
+	}
+	out.WriteByte('"')
+	return out.String()
+}
+
+// stmtStart returns the first token of s.
+func stmtStart(s Statement) token.Token {
+	switch s := s.(type) {
+	case *AssignStatement:
+		return exprStart(s.Target)
+	case *BadStmt:
+		return s.From
+	case *JasaStatement:
+		return s.Token
+	case *BekitStatement:
+		return s.Token
+	case *QaıtarStatement:
+		return s.Token
+	case *AzirsheStatement:
+		return s.Token
+	case *KutStatement:
+		return s.Token
+	case *MindetStatement:
+		return s.Token
+	case *TandaStatement:
+		return s.Token
+	case *ModulStatement:
+		return s.Token
+	case *EngizStatement:
+		return s.Token
+	case *ExpressionStatement:
+		return s.Token
+	case *BlockStatement:
+		return s.Token
+	}
+	return token.Token{}
+}
+
+// exprStart returns the first token of e, not counting parentheses.
+func exprStart(e Expression) token.Token {
+	switch e := e.(type) {
+	case *InfixExpression:
+		return exprStart(e.Left)
+	case *CallExpression:
+		return exprStart(e.Function)
+	case *IndexExpression:
+		return exprStart(e.Left)
+	case *SelectorExpression:
+		return exprStart(e.X)
+	case *Identifier:
+		return e.Token
+	case *PrefixExpression:
+		return e.Token
+	case *SanLiteral:
+		return e.Token
+	case *AqshaLiteral:
+		return e.Token
+	case *AqıqatLiteral:
+		return e.Token
+	case *JolLiteral:
+		return e.Token
+	case *JyimLiteral:
+		return e.Token
+	case *EgerExpression:
+		return e.Token
+	case *AtqarmLiteral:
+		return e.Token
+	case *QatarlasExpression:
+		return e.Token
+	case *TypeNode:
+		return e.Token
+	case *BadExpr:
+		return e.Token
+	}
+	return token.Token{}
+}
@@ -0,0 +1,340 @@
+// FILE: internal/lang/ast/printer_test.go
+
+package ast_test
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/lexer"
+	"github.com/DauletBai/tenge/internal/lang/parser"
+	"github.com/DauletBai/tenge/internal/lang/token"
+	"github.com/shopspring/decimal"
+)
+
+// edgeCases are programs whose printing is easy to get wrong.
+var edgeCases = []string{
+	// precedence and associativity
+	"kórset(1 + 2 * 3, (1 + 2) * 3, 1 - (2 - 3), (1 - 2) - 3)",
+	"kórset(a || b && c, (a || b) && c, !(a && b), a == (b == c))",
+	"kórset(1 << 2 + 3, 1 << (2 + 3), a & b | c, a & (b | c), a ^ b % 4)",
+	"kórset(a / b / c, a / (b / c), a % (b * c), -(a + b) * c)",
+	"kórset(f(a)[i].x, (f)(a), g(h(1, 2)[0]), xs[i + 1][j])",
+	// unary operators
+	"kórset(-1, - -1, -(-1), !!ok, -a * -b, -(a * b), 1 - -2, *p + *q, &x)",
+	"jasa x = -9223372036854775807",
+	"kórset(-2.5, 3 - -2.5, -x.y, -f(1), -xs[0])",
+	// literals
+	`kórset("tab\t", "quote \" and \\", "line\n", 1.5, 0.1, 1e10, 10.50, jan, j'n)`,
+	"jasa xs = [[1, 2], [], [3]]",
+	// declarations and types
+	"jasa x : san = 1\nbekit y : aqsha = 1.25\njasa z : jol",
+	"#syntax kazakh\njasa m : [][]arna[[]&san]\njasa p : &[]san = &xs",
+	"ashyq bekit LIMIT = 10\nashyq atqar'm f() {}",
+	"modul m\nengiz \"a/b\"",
+	// generic functions
+	"atqar'm max[T: Ordered](a: T, b: T) -> T { eger a > b { qaıtar a }\n qaıtar b }",
+	"atqar'm pair[K, V: Number](k: K, xs: [][]V) -> [][]V { qaıtar xs }",
+	"atqar'm apply(f: atqar'm, x: san) -> san { qaıtar f(x) }",
+	// control flow
+	"eger a { f() } áıtpece eger b { g() } áıtpece { h() }",
+	"jasa v = eger a { 1 } áıtpece { 2 }",
+	"ázirshe i < n { i = i + 1\n xs[i] = *p }",
+	"atqar'm f(n: san) -> san { eger n < 2 { qaıtar n }\n qaıtar f(n - 1) + f(n - 2) }",
+	"atqar'm g() { qaıtar }",
+	// parallel code and channels, whose keywords need a #syntax pragma
+	"#syntax kazakh\njasa s = qatarlas ár i ishinde 0..n sum { i * i }",
+	"#syntax kazakh\nqatarlas ár i ishinde 0..n + 1 { out[i] = i }",
+	"#syntax kazakh\nkút { mindet a = f(1)\n mindet g(2) }",
+	"#syntax kazakh\njasa c = arna[san](4)\njasa d = arna[[]f64](0)",
+	"#syntax kazakh\ntańda { jasa v = recv(c) { kórset(v) }\n x = recv(d) { }\n send(c, 1) { }\n áıtpece { } }",
+	// latin keywords
+	"fn f(a: int) -> int { if a > 0 { return a } else { return -a } }",
+	"#syntax latin\nvar xs: array = []\nconst k = parallel for i in 0..10 max { i }",
+}
+
+// sources returns the tenge files shipped with the repository.
+func sources(t *testing.T) map[string]string {
+	files := map[string]string{}
+	for _, pattern := range []string{
+		"../../../benchmarks/src/tenge/*.tng",
+		"../module/std/*/*.tng",
+	} {
+		names, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			src, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files[filepath.ToSlash(name)] = string(src)
+		}
+	}
+	if len(files) == 0 {
+		t.Fatal("no tenge sources found")
+	}
+	return files
+}
+
+func parse(src string) (*ast.Program, []string) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	return program, p.Errors()
+}
+
+// TestPrintRoundTrip checks that printing a program and parsing the
+// result gives back the same tree, in each print mode.
+func TestPrintRoundTrip(t *testing.T) {
+	inputs := sources(t)
+	for i, src := range edgeCases {
+		inputs["edge case "+strconv.Itoa(i+1)] = src
+	}
+	modes := []struct {
+		name string
+		mode ast.PrintMode
+	}{
+		{"String", 0},
+		{"Parens", ast.Parens},
+		{"Multiline", ast.Multiline},
+		{"Multiline|Parens", ast.Multiline | ast.Parens},
+	}
+	for name, src := range inputs {
+		want, errs := parse(src)
+		if len(errs) > 0 {
+			if strings.HasPrefix(name, "edge case") {
+				t.Errorf("%s: %s: %s", name, src, errs[0])
+			}
+			continue // not ours to print
+		}
+		for _, m := range modes {
+			var out strings.Builder
+			if err := (&ast.Printer{Mode: m.mode, Source: src}).Print(&out, want); err != nil {
+				t.Fatal(err)
+			}
+			printed := out.String()
+			if m.mode == 0 && printed != want.String() {
+				t.Errorf("%s: Print and String differ:\n%s\n%s", name, printed, want.String())
+			}
+			if m.mode&ast.Multiline == 0 {
+				// One line has no room for the #syntax line, which is
+				// kept with the comments.
+				printed = pragma.FindString(src) + printed
+			}
+			got, errs := parse(printed)
+			if len(errs) > 0 {
+				t.Errorf("%s, %s: printed code does not parse: %s\n%s", name, m.name, errs[0], printed)
+				continue
+			}
+			if path, ok := equal(reflect.ValueOf(want), reflect.ValueOf(got), "Program"); !ok {
+				t.Errorf("%s, %s: the tree differs at %s; printed:\n%s", name, m.name, path, printed)
+			}
+		}
+	}
+}
+
+var (
+	pragma    = regexp.MustCompile(`^#syntax \w+\n`)
+	tokenType = reflect.TypeOf(token.Token{})
+)
+
+// equal reports whether two trees are the same apart from positions and
+// comments, and if not, the path of the first difference. The token of an
+// expression statement is only where its expression starts, which is a
+// parenthesis in Parens mode, so it is not compared.
+func equal(a, b reflect.Value, path string) (string, bool) {
+	if a.Kind() != b.Kind() {
+		return path, false
+	}
+	switch a.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			return path, a.IsNil() == b.IsNil()
+		}
+		if a.Elem().Type() != b.Elem().Type() {
+			return path + " (" + a.Elem().Type().String() + " vs " + b.Elem().Type().String() + ")", false
+		}
+		return equal(a.Elem(), b.Elem(), path)
+	case reflect.Slice:
+		if a.Len() != b.Len() {
+			return path + " (length)", false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if p, ok := equal(a.Index(i), b.Index(i), path+"["+strconv.Itoa(i)+"]"); !ok {
+				return p, false
+			}
+		}
+		return path, true
+	case reflect.Struct:
+		if a.Type() == tokenType {
+			x, y := a.Interface().(token.Token), b.Interface().(token.Token)
+			return path, x.Type == y.Type && x.Literal == y.Literal
+		}
+		if a.Type() != b.Type() {
+			return path, false
+		}
+		for i := 0; i < a.NumField(); i++ {
+			f := a.Type().Field(i)
+			if !f.IsExported() || f.Name == "Comments" {
+				continue
+			}
+			if _, ok := a.Interface().(ast.ExpressionStatement); ok && f.Name == "Token" {
+				continue
+			}
+			if p, ok := equal(a.Field(i), b.Field(i), path+"."+f.Name); !ok {
+				return p, false
+			}
+		}
+		return path, true
+	}
+	if !a.CanInterface() {
+		return path, true
+	}
+	return path, reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// TestPrintGenerated checks the round trip of TestPrintRoundTrip on
+// random trees, which reach operator nestings no one writes by hand.
+func TestPrintGenerated(t *testing.T) {
+	g := &generator{r: rand.New(rand.NewSource(1))}
+	for i := 0; i < 500; i++ {
+		want := g.program()
+		for _, mode := range []ast.PrintMode{0, ast.Parens, ast.Multiline} {
+			var out strings.Builder
+			if err := (&ast.Printer{Mode: mode}).Print(&out, want); err != nil {
+				t.Fatal(err)
+			}
+			got, errs := parse(out.String())
+			if len(errs) > 0 {
+				t.Fatalf("program %d, mode %d: printed code does not parse: %s\n%s", i, mode, errs[0], out.String())
+			}
+			if path, ok := equal(reflect.ValueOf(want), reflect.ValueOf(got), "Program"); !ok {
+				t.Fatalf("program %d, mode %d: the tree differs at %s; printed:\n%s", i, mode, path, out.String())
+			}
+		}
+	}
+}
+
+// generator builds random trees with the tokens the parser would give
+// them.
+type generator struct {
+	r     *rand.Rand
+	depth int
+}
+
+// tok returns the token the lexer makes of s.
+func tok(s string) token.Token {
+	return lexer.New(s).NextToken()
+}
+
+var (
+	genNames   = []string{"a", "b", "xs", "f"}
+	genStrings = []string{"", "s", "two words"}
+	genInfix   = []string{
+		"+", "-", "*", "/", "%", "<<", ">>", "&", "|", "^",
+		"==", "!=", "<", "<=", ">", ">=", "&&", "||",
+	}
+)
+
+func (g *generator) pick(xs []string) string { return xs[g.r.Intn(len(xs))] }
+
+func (g *generator) program() *ast.Program {
+	program := &ast.Program{}
+	for n := 1 + g.r.Intn(3); n > 0; n-- {
+		program.Statements = append(program.Statements, g.stmt())
+	}
+	return program
+}
+
+func (g *generator) ident() *ast.Identifier {
+	name := g.pick(genNames)
+	return &ast.Identifier{Token: tok(name), Value: name}
+}
+
+func (g *generator) block() *ast.BlockStatement {
+	b := &ast.BlockStatement{Token: tok("{"), Rbrace: tok("}")}
+	for n := g.r.Intn(3); n > 0; n-- {
+		b.Statements = append(b.Statements, g.stmt())
+	}
+	return b
+}
+
+func (g *generator) stmt() ast.Statement {
+	g.depth++
+	defer func() { g.depth-- }()
+	switch n := g.r.Intn(4); {
+	case n == 0:
+		return &ast.JasaStatement{Token: tok("jasa"), Name: g.ident(), Value: g.expr()}
+	case n == 1:
+		var target ast.Expression = g.ident()
+		if g.r.Intn(2) == 0 {
+			target = &ast.IndexExpression{Token: tok("["), Left: target, Index: g.expr()}
+		}
+		return &ast.AssignStatement{Token: tok("="), Target: target, Value: g.expr()}
+	case n == 2 && g.depth < 3:
+		return &ast.AzirsheStatement{Token: tok("ázirshe"), Condition: g.expr(), Body: g.block()}
+	}
+	return &ast.ExpressionStatement{Expression: g.call(g.ident())}
+}
+
+func (g *generator) call(fn ast.Expression) *ast.CallExpression {
+	call := &ast.CallExpression{Token: tok("("), Function: fn}
+	for n := g.r.Intn(3); n > 0; n-- {
+		call.Arguments = append(call.Arguments, g.expr())
+	}
+	return call
+}
+
+func (g *generator) expr() ast.Expression {
+	g.depth++
+	defer func() { g.depth-- }()
+	n := g.r.Intn(10)
+	if g.depth > 4 {
+		n = 0
+	}
+	switch n {
+	case 0:
+		switch g.r.Intn(5) {
+		case 0:
+			v := g.r.Int63n(1000)
+			return &ast.SanLiteral{Token: tok(strconv.FormatInt(v, 10)), Value: v}
+		case 1:
+			v := decimal.New(g.r.Int63n(1000), -2)
+			return &ast.AqshaLiteral{Token: tok(v.StringFixed(2)), Value: v}
+		case 2:
+			v := g.pick(genStrings)
+			return &ast.JolLiteral{Token: tok(strconv.Quote(v)), Value: v}
+		case 3:
+			v := g.r.Intn(2) == 0
+			lit := "j'n"
+			if v {
+				lit = "jan"
+			}
+			return &ast.AqıqatLiteral{Token: tok(lit), Value: v}
+		}
+		return g.ident()
+	case 1, 2:
+		op := g.pick([]string{"-", "!"})
+		return &ast.PrefixExpression{Token: tok(op), Operator: op, Right: g.expr()}
+	case 3, 4, 5, 6:
+		op := g.pick(genInfix)
+		return &ast.InfixExpression{Token: tok(op), Left: g.expr(), Operator: op, Right: g.expr()}
+	case 7:
+		return g.call(g.expr())
+	case 8:
+		return &ast.IndexExpression{Token: tok("["), Left: g.expr(), Index: g.expr()}
+	}
+	lit := &ast.JyimLiteral{Token: tok("[")}
+	for n := g.r.Intn(3); n > 0; n-- {
+		lit.Elements = append(lit.Elements, g.expr())
+	}
+	return lit
+}
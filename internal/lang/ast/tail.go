@@ -0,0 +1,50 @@
+// FILE: internal/lang/ast/tail.go
+
+package ast
+
+// TailCalls returns the calls in tail position in lit: the values of its
+// qaıtar statements and, when lit declares no result, the call that ends
+// its body, directly or as the last statement of a branch of an eger that
+// ends it. lit returns right after such a call, so a backend can run a
+// call of lit itself as a jump back to its start. Function literals
+// nested in lit are not searched.
+//
+// The backends compile from the syntax tree, so they find tail calls
+// here; the tailcall pass of the IR applies the same rule to its blocks.
+func TailCalls(lit *AtqarmLiteral) []*CallExpression {
+	var calls []*CallExpression
+	Inspect(lit.Body, func(n Node) bool {
+		switch n := n.(type) {
+		case *AtqarmLiteral:
+			return false
+		case *QaıtarStatement:
+			if call, ok := n.ReturnValue.(*CallExpression); ok {
+				calls = append(calls, call)
+			}
+		}
+		return true
+	})
+	if lit.ReturnType == nil {
+		calls = appendTrailing(calls, lit.Body)
+	}
+	return calls
+}
+
+// appendTrailing appends the calls that end block to calls.
+func appendTrailing(calls []*CallExpression, block *BlockStatement) []*CallExpression {
+	if block == nil || len(block.Statements) == 0 {
+		return calls
+	}
+	s, ok := block.Statements[len(block.Statements)-1].(*ExpressionStatement)
+	if !ok {
+		return calls
+	}
+	switch x := s.Expression.(type) {
+	case *CallExpression:
+		calls = append(calls, x)
+	case *EgerExpression:
+		calls = appendTrailing(calls, x.Consequence)
+		calls = appendTrailing(calls, x.Alternative)
+	}
+	return calls
+}
@@ -0,0 +1,502 @@
+// FILE: internal/lang/ast/walk.go
+
+package ast
+
+import (
+	"fmt"
+
+	"github.com/DauletBai/tenge/internal/lang/token"
+)
+
+// Kind identifies the concrete type of a node, for passes that keep
+// tables by node type.
+type Kind uint8
+
+const (
+	InvalidKind Kind = iota
+
+	ProgramKind
+	IdentifierKind
+	TypeNodeKind
+
+	// Statements
+	BekitKind
+	JasaKind
+	QaıtarKind
+	ModulKind
+	EngizKind
+	ExpressionStmtKind
+	BadStmtKind
+	AssignKind
+	BlockKind
+	AzirsheKind
+	KutKind
+	MindetKind
+	TandaKind
+
+	// Expressions
+	SanKind
+	AqshaKind
+	AqıqatKind
+	JolKind
+	JyimKind
+	PrefixKind
+	InfixKind
+	EgerKind
+	AtqarmKind
+	CallKind
+	SelectorKind
+	IndexKind
+	QatarlasKind
+	BadExprKind
+
+	NumKinds // the number of kinds, for tables indexed by Kind
+)
+
+var kindNames = [NumKinds]string{
+	InvalidKind:        "Invalid",
+	ProgramKind:        "Program",
+	IdentifierKind:     "Identifier",
+	TypeNodeKind:       "TypeNode",
+	BekitKind:          "BekitStatement",
+	JasaKind:           "JasaStatement",
+	QaıtarKind:         "QaıtarStatement",
+	ModulKind:          "ModulStatement",
+	EngizKind:          "EngizStatement",
+	ExpressionStmtKind: "ExpressionStatement",
+	BadStmtKind:        "BadStmt",
+	AssignKind:         "AssignStatement",
+	BlockKind:          "BlockStatement",
+	AzirsheKind:        "AzirsheStatement",
+	KutKind:            "KutStatement",
+	MindetKind:         "MindetStatement",
+	TandaKind:          "TandaStatement",
+	SanKind:            "SanLiteral",
+	AqshaKind:          "AqshaLiteral",
+	AqıqatKind:         "AqıqatLiteral",
+	JolKind:            "JolLiteral",
+	JyimKind:           "JyimLiteral",
+	PrefixKind:         "PrefixExpression",
+	InfixKind:          "InfixExpression",
+	EgerKind:           "EgerExpression",
+	AtqarmKind:         "AtqarmLiteral",
+	CallKind:           "CallExpression",
+	SelectorKind:       "SelectorExpression",
+	IndexKind:          "IndexExpression",
+	QatarlasKind:       "QatarlasExpression",
+	BadExprKind:        "BadExpr",
+}
+
+// String returns the name of the node type of k.
+func (k Kind) String() string {
+	if k < NumKinds {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("Kind(%d)", k)
+}
+
+// KindOf returns the kind of n, or InvalidKind for nil and for node types
+// this package does not define.
+func KindOf(n Node) Kind {
+	switch n.(type) {
+	case *Program:
+		return ProgramKind
+	case *Identifier:
+		return IdentifierKind
+	case *TypeNode:
+		return TypeNodeKind
+	case *BekitStatement:
+		return BekitKind
+	case *JasaStatement:
+		return JasaKind
+	case *QaıtarStatement:
+		return QaıtarKind
+	case *ModulStatement:
+		return ModulKind
+	case *EngizStatement:
+		return EngizKind
+	case *ExpressionStatement:
+		return ExpressionStmtKind
+	case *BadStmt:
+		return BadStmtKind
+	case *AssignStatement:
+		return AssignKind
+	case *BlockStatement:
+		return BlockKind
+	case *AzirsheStatement:
+		return AzirsheKind
+	case *KutStatement:
+		return KutKind
+	case *MindetStatement:
+		return MindetKind
+	case *TandaStatement:
+		return TandaKind
+	case *SanLiteral:
+		return SanKind
+	case *AqshaLiteral:
+		return AqshaKind
+	case *AqıqatLiteral:
+		return AqıqatKind
+	case *JolLiteral:
+		return JolKind
+	case *JyimLiteral:
+		return JyimKind
+	case *PrefixExpression:
+		return PrefixKind
+	case *InfixExpression:
+		return InfixKind
+	case *EgerExpression:
+		return EgerKind
+	case *AtqarmLiteral:
+		return AtqarmKind
+	case *CallExpression:
+		return CallKind
+	case *SelectorExpression:
+		return SelectorKind
+	case *IndexExpression:
+		return IndexKind
+	case *QatarlasExpression:
+		return QatarlasKind
+	case *BadExpr:
+		return BadExprKind
+	}
+	return InvalidKind
+}
+
+// A Visitor's Visit method is called by Walk for each node. If it returns
+// a visitor w, Walk visits the children of the node with w and then calls
+// w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree rooted at node in depth-first order, in the
+// order the children appear in the source. The names and types of
+// function parameters are visited as Identifier and TypeNode children of
+// the function. Walk panics on a node type it does not know, so that a new
+// node type cannot be skipped silently.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	switch n := node.(type) {
+	case *Program:
+		walkStatements(v, n.Statements)
+	case *Identifier, *SanLiteral, *AqshaLiteral, *AqıqatLiteral, *JolLiteral, *BadStmt, *BadExpr:
+		// no children
+	case *TypeNode:
+		if n.Elem != nil {
+			Walk(v, n.Elem)
+		}
+	case *BekitStatement:
+		walkBinding(v, n.Name, n.Type, n.Value)
+	case *JasaStatement:
+		walkBinding(v, n.Name, n.Type, n.Value)
+	case *QaıtarStatement:
+		if n.ReturnValue != nil {
+			Walk(v, n.ReturnValue)
+		}
+	case *ModulStatement:
+		Walk(v, n.Name)
+	case *EngizStatement:
+		Walk(v, n.Path)
+	case *ExpressionStatement:
+		if n.Expression != nil {
+			Walk(v, n.Expression)
+		}
+	case *AssignStatement:
+		Walk(v, n.Target)
+		Walk(v, n.Value)
+	case *BlockStatement:
+		walkStatements(v, n.Statements)
+	case *AzirsheStatement:
+		Walk(v, n.Condition)
+		Walk(v, n.Body)
+	case *KutStatement:
+		Walk(v, n.Body)
+	case *MindetStatement:
+		if n.Target != nil {
+			Walk(v, n.Target)
+		}
+		Walk(v, n.Call)
+	case *TandaStatement:
+		for _, cl := range n.Clauses {
+			if cl.Target != nil {
+				Walk(v, cl.Target)
+			}
+			if cl.Comm != nil {
+				Walk(v, cl.Comm)
+			}
+			Walk(v, cl.Body)
+		}
+	case *JyimLiteral:
+		walkExpressions(v, n.Elements)
+	case *PrefixExpression:
+		Walk(v, n.Right)
+	case *InfixExpression:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	case *EgerExpression:
+		Walk(v, n.Condition)
+		Walk(v, n.Consequence)
+		if n.Alternative != nil {
+			Walk(v, n.Alternative)
+		}
+	case *AtqarmLiteral:
+		for _, tp := range n.TypeParams {
+			Walk(v, tp.Name)
+			if tp.Constraint != nil {
+				Walk(v, tp.Constraint)
+			}
+		}
+		for _, p := range n.Parameters {
+			Walk(v, p.Name)
+			if p.Type != nil {
+				Walk(v, p.Type)
+			}
+		}
+		if n.ReturnType != nil {
+			Walk(v, n.ReturnType)
+		}
+		Walk(v, n.Body)
+	case *CallExpression:
+		Walk(v, n.Function)
+		walkExpressions(v, n.Arguments)
+	case *SelectorExpression:
+		Walk(v, n.X)
+		Walk(v, n.Sel)
+	case *IndexExpression:
+		Walk(v, n.Left)
+		Walk(v, n.Index)
+	case *QatarlasExpression:
+		Walk(v, n.Var)
+		Walk(v, n.From)
+		Walk(v, n.To)
+		if n.Reduce != nil {
+			Walk(v, n.Reduce)
+		}
+		Walk(v, n.Body)
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+	v.Visit(nil)
+}
+
+// walkBinding walks a declaration. In the typed form
+// `jasa f: atqar'm (x) { ... }` the type is the function itself, which is
+// visited once, as the value.
+func walkBinding(v Visitor, name *Identifier, typ *TypeNode, value Expression) {
+	Walk(v, name)
+	if typ != nil && !isFuncType(typ, value) {
+		Walk(v, typ)
+	}
+	if value != nil {
+		Walk(v, value)
+	}
+}
+
+// isFuncType reports whether typ is the 'atqar'm' of the function value,
+// as the parser makes it for `jasa f: atqar'm (x) { ... }`.
+func isFuncType(typ *TypeNode, value Expression) bool {
+	fn, ok := value.(*AtqarmLiteral)
+	return ok && typ.Token.Type == token.ATQARM && typ.Token == fn.Token
+}
+
+func walkStatements(v Visitor, list []Statement) {
+	for _, s := range list {
+		Walk(v, s)
+	}
+}
+
+func walkExpressions(v Visitor, list []Expression) {
+	for _, e := range list {
+		Walk(v, e)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree rooted at node in the order of Walk. It calls
+// f(node) for each node, and visits the children of the node if f returns
+// true. After the children it calls f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Rewrite rebuilds the tree rooted at node bottom-up: the children of a
+// node are rewritten first and stored back in it, then f is called with
+// the node and its result takes the place of the node. f returns its
+// argument to keep a node. A nil result removes a statement from its
+// list; elsewhere it may only replace an optional child, such as the
+// value of a qaıtar. Rewrite panics if a result does not fit where the
+// node was, e.g. an expression in place of a statement or anything but
+// an Identifier in place of a name.
+//
+// Rewrite changes the nodes in place; it returns the new root.
+func Rewrite(node Node, f func(Node) Node) Node {
+	r := rewriter(f)
+	switch n := node.(type) {
+	case *Program:
+		n.Statements = r.statements(n.Statements)
+	case *Identifier, *SanLiteral, *AqshaLiteral, *AqıqatLiteral, *JolLiteral, *BadStmt, *BadExpr:
+		// no children
+	case *TypeNode:
+		n.Elem = r.typ(n.Elem)
+	case *BekitStatement:
+		n.Name, n.Type, n.Value = r.binding(n.Name, n.Type, n.Value)
+	case *JasaStatement:
+		n.Name, n.Type, n.Value = r.binding(n.Name, n.Type, n.Value)
+	case *QaıtarStatement:
+		n.ReturnValue = r.expr(n.ReturnValue)
+	case *ModulStatement:
+		n.Name = r.ident(n.Name)
+	case *EngizStatement:
+		if n.Path != nil {
+			n.Path = Rewrite(n.Path, f).(*JolLiteral)
+		}
+	case *ExpressionStatement:
+		n.Expression = r.expr(n.Expression)
+	case *AssignStatement:
+		n.Target = r.expr(n.Target)
+		n.Value = r.expr(n.Value)
+	case *BlockStatement:
+		n.Statements = r.statements(n.Statements)
+	case *AzirsheStatement:
+		n.Condition = r.expr(n.Condition)
+		n.Body = r.block(n.Body)
+	case *KutStatement:
+		n.Body = r.block(n.Body)
+	case *MindetStatement:
+		n.Target = r.ident(n.Target)
+		if n.Call != nil {
+			n.Call = Rewrite(n.Call, f).(*CallExpression)
+		}
+	case *TandaStatement:
+		for _, cl := range n.Clauses {
+			cl.Target = r.ident(cl.Target)
+			if cl.Comm != nil {
+				cl.Comm = Rewrite(cl.Comm, f).(*CallExpression)
+			}
+			cl.Body = r.block(cl.Body)
+		}
+	case *JyimLiteral:
+		n.Elements = r.expressions(n.Elements)
+	case *PrefixExpression:
+		n.Right = r.expr(n.Right)
+	case *InfixExpression:
+		n.Left = r.expr(n.Left)
+		n.Right = r.expr(n.Right)
+	case *EgerExpression:
+		n.Condition = r.expr(n.Condition)
+		n.Consequence = r.block(n.Consequence)
+		n.Alternative = r.block(n.Alternative)
+	case *AtqarmLiteral:
+		for _, tp := range n.TypeParams {
+			tp.Name = r.ident(tp.Name)
+			tp.Constraint = r.ident(tp.Constraint)
+		}
+		for _, p := range n.Parameters {
+			p.Name = r.ident(p.Name)
+			p.Type = r.typ(p.Type)
+		}
+		n.ReturnType = r.typ(n.ReturnType)
+		n.Body = r.block(n.Body)
+	case *CallExpression:
+		n.Function = r.expr(n.Function)
+		n.Arguments = r.expressions(n.Arguments)
+	case *SelectorExpression:
+		n.X = r.expr(n.X)
+		n.Sel = r.ident(n.Sel)
+	case *IndexExpression:
+		n.Left = r.expr(n.Left)
+		n.Index = r.expr(n.Index)
+	case *QatarlasExpression:
+		n.Var = r.ident(n.Var)
+		n.From = r.expr(n.From)
+		n.To = r.expr(n.To)
+		n.Reduce = r.ident(n.Reduce)
+		n.Body = r.block(n.Body)
+	default:
+		panic(fmt.Sprintf("ast.Rewrite: unexpected node type %T", n))
+	}
+	return f(node)
+}
+
+// rewriter rewrites the children of the node types, skipping nil ones.
+type rewriter func(Node) Node
+
+func (r rewriter) binding(name *Identifier, typ *TypeNode, value Expression) (*Identifier, *TypeNode, Expression) {
+	name = r.ident(name)
+	if typ != nil && isFuncType(typ, value) {
+		value = r.expr(value)
+		if fn, ok := value.(*AtqarmLiteral); ok {
+			typ.Token = fn.Token // keeps the typed form for a new function
+		}
+		return name, typ, value
+	}
+	return name, r.typ(typ), r.expr(value)
+}
+
+func (r rewriter) statements(list []Statement) []Statement {
+	out := list[:0]
+	for _, s := range list {
+		if s := Rewrite(s, r); s != nil {
+			out = append(out, s.(Statement))
+		}
+	}
+	return out
+}
+
+func (r rewriter) expressions(list []Expression) []Expression {
+	for i, e := range list {
+		list[i] = r.expr(e)
+	}
+	return list
+}
+
+func (r rewriter) expr(e Expression) Expression {
+	if e == nil {
+		return nil
+	}
+	if n := Rewrite(e, r); n != nil {
+		return n.(Expression)
+	}
+	return nil
+}
+
+func (r rewriter) ident(id *Identifier) *Identifier {
+	if id == nil {
+		return nil
+	}
+	if n := Rewrite(id, r); n != nil {
+		return n.(*Identifier)
+	}
+	return nil
+}
+
+func (r rewriter) typ(tn *TypeNode) *TypeNode {
+	if tn == nil {
+		return nil
+	}
+	if n := Rewrite(tn, r); n != nil {
+		return n.(*TypeNode)
+	}
+	return nil
+}
+
+func (r rewriter) block(b *BlockStatement) *BlockStatement {
+	if b == nil {
+		return nil
+	}
+	if n := Rewrite(b, r); n != nil {
+		return n.(*BlockStatement)
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+// FILE: internal/lang/ast/walk_test.go
+
+package ast_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/token"
+	"github.com/shopspring/decimal"
+)
+
+func id(name string) *ast.Identifier {
+	return &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+}
+
+func num(v int64) *ast.SanLiteral {
+	return &ast.SanLiteral{Token: token.Token{Type: token.SAN_LIT, Literal: fmt.Sprint(v)}, Value: v}
+}
+
+func block(stmts ...ast.Statement) *ast.BlockStatement {
+	return &ast.BlockStatement{Token: token.Token{Type: token.LBRACE, Literal: "{"}, Statements: stmts, Rbrace: token.Token{Type: token.RBRACE, Literal: "}"}}
+}
+
+func call(name string, args ...ast.Expression) *ast.CallExpression {
+	return &ast.CallExpression{Token: token.Token{Type: token.LPAREN, Literal: "("}, Function: id(name), Arguments: args}
+}
+
+func san() *ast.TypeNode {
+	return &ast.TypeNode{Token: token.Token{Type: token.SAN, Literal: "san"}, Name: "san"}
+}
+
+// samples returns a new node of every concrete type, with every optional
+// child set, by kind.
+func samples() map[ast.Kind]ast.Node {
+	stmt := func() ast.Statement { return &ast.ExpressionStatement{Expression: call("f")} }
+	return map[ast.Kind]ast.Node{
+		ast.ProgramKind:    &ast.Program{Statements: []ast.Statement{stmt(), stmt()}},
+		ast.IdentifierKind: id("x"),
+		ast.TypeNodeKind:   &ast.TypeNode{Token: token.Token{Type: token.LBRACKET, Literal: "["}, Elem: san()},
+
+		ast.BekitKind:          &ast.BekitStatement{Name: id("k"), Type: san(), Value: num(1)},
+		ast.JasaKind:           &ast.JasaStatement{Name: id("v"), Type: san(), Value: num(2)},
+		ast.QaıtarKind:         &ast.QaıtarStatement{ReturnValue: id("r")},
+		ast.ModulKind:          &ast.ModulStatement{Name: id("m")},
+		ast.EngizKind:          &ast.EngizStatement{Path: &ast.JolLiteral{Value: "a/b"}},
+		ast.ExpressionStmtKind: stmt(),
+		ast.BadStmtKind:        &ast.BadStmt{},
+		ast.AssignKind:         &ast.AssignStatement{Target: id("a"), Value: num(3)},
+		ast.BlockKind:          block(stmt(), stmt()),
+		ast.AzirsheKind:        &ast.AzirsheStatement{Condition: id("c"), Body: block(stmt())},
+		ast.KutKind:            &ast.KutStatement{Body: block(&ast.MindetStatement{Call: call("g")})},
+		ast.MindetKind:         &ast.MindetStatement{Target: id("t"), Call: call("g", num(4))},
+		ast.TandaKind: &ast.TandaStatement{Clauses: []*ast.TandaClause{
+			{Define: true, Target: id("v"), Comm: call("recv", id("ch")), Body: block(stmt())},
+			{Comm: call("send", id("ch"), num(5)), Body: block()},
+			{Body: block(stmt())},
+		}},
+
+		ast.SanKind:    num(6),
+		ast.AqshaKind:  &ast.AqshaLiteral{Value: decimal.New(125, -2)},
+		ast.AqıqatKind: &ast.AqıqatLiteral{Value: true},
+		ast.JolKind:    &ast.JolLiteral{Value: "s"},
+		ast.JyimKind:   &ast.JyimLiteral{Elements: []ast.Expression{num(1), id("y")}},
+		ast.PrefixKind: &ast.PrefixExpression{Operator: "-", Right: id("p")},
+		ast.InfixKind:  &ast.InfixExpression{Left: id("l"), Operator: "+", Right: num(7)},
+		ast.EgerKind:   &ast.EgerExpression{Condition: id("c"), Consequence: block(stmt()), Alternative: block(stmt())},
+		ast.AtqarmKind: &ast.AtqarmLiteral{
+			Token:      token.Token{Type: token.ATQARM, Literal: "atqar'm"},
+			TypeParams: []*ast.TypeParam{{Name: id("T"), Constraint: id("Number")}, {Name: id("U")}},
+			Parameters: []*ast.Parameter{{Name: id("a"), Type: san()}, {Name: id("b")}},
+			ReturnType: san(),
+			Body:       block(&ast.QaıtarStatement{ReturnValue: id("a")}),
+		},
+		ast.CallKind:     call("h", num(8), id("z")),
+		ast.SelectorKind: &ast.SelectorExpression{X: id("m"), Sel: id("f")},
+		ast.IndexKind:    &ast.IndexExpression{Left: id("xs"), Index: num(0)},
+		ast.QatarlasKind: &ast.QatarlasExpression{Var: id("i"), From: num(0), To: id("n"), Reduce: id("sum"), Body: block(stmt())},
+		ast.BadExprKind:  &ast.BadExpr{},
+	}
+}
+
+// children returns the nodes below n, found by reflection, so that a child
+// Walk or Rewrite does not know about is noticed.
+func children(n ast.Node) []ast.Node {
+	var out []ast.Node
+	var visit func(v reflect.Value, top bool)
+	visit = func(v reflect.Value, top bool) {
+		switch v.Kind() {
+		case reflect.Interface:
+			if !v.IsNil() {
+				visit(v.Elem(), top)
+			}
+		case reflect.Ptr:
+			if v.IsNil() {
+				return
+			}
+			if node, ok := v.Interface().(ast.Node); ok && ast.KindOf(node) != ast.InvalidKind && !top {
+				out = append(out, node)
+			}
+			visit(v.Elem(), false)
+		case reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				visit(v.Index(i), false)
+			}
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				if v.Type().Field(i).IsExported() {
+					visit(v.Field(i), false)
+				}
+			}
+		}
+	}
+	visit(reflect.ValueOf(n), true)
+	return out
+}
+
+func sameNodes(t *testing.T, what string, kind ast.Kind, got, want []ast.Node) {
+	t.Helper()
+	count := make(map[ast.Node]int)
+	for _, n := range want {
+		count[n]++
+	}
+	for _, n := range got {
+		count[n]--
+	}
+	for n, c := range count {
+		if c > 0 {
+			t.Errorf("%s of %s skips the %s %s", what, kind, ast.KindOf(n), n)
+		}
+		if c < 0 {
+			t.Errorf("%s of %s reaches the %s %s %d extra times", what, kind, ast.KindOf(n), n, -c)
+		}
+	}
+}
+
+type recorder struct{ nodes *[]ast.Node }
+
+func (r recorder) Visit(n ast.Node) ast.Visitor {
+	if n != nil {
+		*r.nodes = append(*r.nodes, n)
+	}
+	return r
+}
+
+func TestKinds(t *testing.T) {
+	all := samples()
+	for k := ast.InvalidKind + 1; k < ast.NumKinds; k++ {
+		n, ok := all[k]
+		if !ok {
+			t.Errorf("no sample of %s", k)
+			continue
+		}
+		if got := ast.KindOf(n); got != k {
+			t.Errorf("KindOf(%T) = %s, want %s", n, got, k)
+		}
+		if name := reflect.TypeOf(n).Elem().Name(); k.String() != name {
+			t.Errorf("kind %d is named %s, want %s", k, k, name)
+		}
+	}
+	if k := ast.KindOf(nil); k != ast.InvalidKind {
+		t.Errorf("KindOf(nil) = %s", k)
+	}
+	if s := ast.NumKinds.String(); s != fmt.Sprintf("Kind(%d)", ast.NumKinds) {
+		t.Errorf("NumKinds.String() = %s", s)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	for k, n := range samples() {
+		var nodes []ast.Node
+		var ends int
+		ast.Inspect(n, func(n ast.Node) bool {
+			if n == nil {
+				ends++
+			}
+			return true
+		})
+		ast.Walk(recorder{&nodes}, n)
+		if len(nodes) == 0 || nodes[0] != n {
+			t.Errorf("Walk of %s does not start at the node", k)
+			continue
+		}
+		if ends != len(nodes) {
+			t.Errorf("Inspect of %s ends %d nodes, visits %d", k, ends, len(nodes))
+		}
+		sameNodes(t, "Walk", k, nodes[1:], children(n))
+	}
+}
+
+func TestWalkPanicsOnUnknownNode(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Walk of an unknown node type does not panic")
+		}
+	}()
+	ast.Inspect(&ast.ExpressionStatement{Expression: unknown{}}, func(ast.Node) bool { return true })
+}
+
+type unknown struct{ ast.Expression }
+
+func TestRewrite(t *testing.T) {
+	// Keeping every node changes nothing and reaches every node once,
+	// children first.
+	for k, n := range samples() {
+		want := append(children(n), n)
+		var got []ast.Node
+		before := n.String()
+		root := ast.Rewrite(n, func(n ast.Node) ast.Node {
+			got = append(got, n)
+			return n
+		})
+		if root != n || n.String() != before {
+			t.Errorf("Rewrite of %s keeping every node changed it: %s, want %s", k, root, before)
+		}
+		if len(got) == 0 || got[len(got)-1] != n {
+			t.Errorf("Rewrite of %s does not end at the node", k)
+		}
+		sameNodes(t, "Rewrite", k, got, want)
+	}
+
+	// Replacing every identifier reaches every name.
+	for k, n := range samples() {
+		ast.Rewrite(n, func(n ast.Node) ast.Node {
+			if x, ok := n.(*ast.Identifier); ok {
+				return id(x.Value + "2")
+			}
+			return n
+		})
+		for _, c := range children(n) {
+			if x, ok := c.(*ast.Identifier); ok && x.Value[len(x.Value)-1] != '2' {
+				t.Errorf("Rewrite of %s kept the identifier %s", k, x.Value)
+			}
+		}
+	}
+
+	// Removing statements drops them from their list.
+	p := samples()[ast.ProgramKind].(*ast.Program)
+	ast.Rewrite(p, func(n ast.Node) ast.Node {
+		if _, ok := n.(*ast.ExpressionStatement); ok {
+			return nil
+		}
+		return n
+	})
+	if len(p.Statements) != 0 {
+		t.Errorf("Rewrite kept %d removed statements", len(p.Statements))
+	}
+}
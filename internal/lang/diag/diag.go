@@ -0,0 +1,193 @@
+// FILE: internal/lang/diag/diag.go
+
+// Package diag holds structured diagnostics: a message with its code and
+// source span, secondary labels, notes and suggested fixes. Print renders
+// them with the offending source line and a caret span; PrintJSON writes
+// one JSON object per diagnostic for editors.
+//
+//	prog.tng:3:5: error[E0312]: undefined: qaytar
+//	  |
+//	3 |     qaytar x;
+//	  |     ^^^^^^
+//	  = help: did you mean `qaıtar`?
+package diag
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/token"
+)
+
+// Severity tells errors from warnings.
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+)
+
+func (s Severity) String() string {
+	if s == Warning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Span is a range of one source line. Columns count runes from 1.
+type Span struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Len    int    `json:"length"`
+}
+
+// At returns the span of tok.
+func At(tok token.Token) Span {
+	n := utf8.RuneCountInString(tok.Literal)
+	if tok.Type == token.JOL_LIT {
+		n += 2 // the quotes
+	}
+	if n == 0 {
+		n = 1
+	}
+	return Span{File: tok.File, Line: tok.Line, Column: tok.Column, Len: n}
+}
+
+// String returns "file:line:col", "line:col" without a file, or just the
+// file without a line.
+func (s Span) String() string {
+	if s.Line == 0 {
+		return s.File
+	}
+	if s.File == "" {
+		return fmt.Sprintf("%d:%d", s.Line, s.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", s.File, s.Line, s.Column)
+}
+
+// Label marks a secondary span, such as an earlier declaration.
+type Label struct {
+	Span    Span   `json:"span"`
+	Message string `json:"message"`
+}
+
+// Fix suggests replacing the text of Span with Text.
+type Fix struct {
+	Span Span   `json:"span"`
+	Text string `json:"replacement"`
+}
+
+// Diagnostic is one error or warning.
+type Diagnostic struct {
+	Severity Severity
+	Code     msg.Code
+	Span     Span
+	Message  string
+	Labels   []Label
+	Notes    []string
+	Fixes    []Fix
+}
+
+// New returns a diagnostic at tok with the message code in the selected
+// language.
+func New(sev Severity, tok token.Token, code msg.Code, args ...interface{}) *Diagnostic {
+	return &Diagnostic{Severity: sev, Code: code, Span: At(tok), Message: msg.Sprintf(code, args...)}
+}
+
+// Error returns "file:line:col: message", the one-line form.
+func (d *Diagnostic) Error() string {
+	if pos := d.Span.String(); pos != "" {
+		return pos + ": " + d.Message
+	}
+	return d.Message
+}
+
+// Label adds a secondary label at tok and returns d.
+func (d *Diagnostic) Label(tok token.Token, message string) *Diagnostic {
+	d.Labels = append(d.Labels, Label{Span: At(tok), Message: message})
+	return d
+}
+
+// Note adds a note and returns d.
+func (d *Diagnostic) Note(note string) *Diagnostic {
+	d.Notes = append(d.Notes, note)
+	return d
+}
+
+// Suggest adds a fix replacing tok with the candidate closest to its
+// literal, if one is close enough, and returns d.
+func (d *Diagnostic) Suggest(tok token.Token, candidates []string) *Diagnostic {
+	if s, ok := Closest(tok.Literal, candidates); ok {
+		d.Fixes = append(d.Fixes, Fix{Span: At(tok), Text: s})
+	}
+	return d
+}
+
+// List is a list of diagnostics in the order they were reported.
+type List []*Diagnostic
+
+// Strings returns the one-line form of every diagnostic.
+func (l List) Strings() []string {
+	out := make([]string, len(l))
+	for i, d := range l {
+		out[i] = d.Error()
+	}
+	return out
+}
+
+// Closest returns the candidate with the smallest edit distance to name,
+// if that distance is small for the length of name: two edits for names
+// longer than four letters, and for names of three or four one changed
+// or transposed letter, since a letter more or less makes too many short
+// names alike (end and send). Shorter names get no suggestion at all.
+func Closest(name string, candidates []string) (string, bool) {
+	n := utf8.RuneCountInString(name)
+	if n < 3 {
+		return "", false
+	}
+	limit := 2
+	if n <= 4 {
+		limit = 1
+	}
+	best, bestDist := "", limit+1
+	for _, c := range candidates {
+		if c == name || n <= 4 && utf8.RuneCountInString(c) != n {
+			continue
+		}
+		if d := Distance(name, c); d < bestDist || d == bestDist && c < best {
+			best, bestDist = c, d
+		}
+	}
+	return best, bestDist <= limit
+}
+
+// Distance returns the edit distance between a and b, counting the
+// insertion, deletion or substitution of a rune and the transposition of
+// two adjacent runes as one edit each.
+func Distance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	// d[i][j] is the distance between s[:i] and t[:j].
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
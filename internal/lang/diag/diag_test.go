@@ -0,0 +1,118 @@
+// FILE: internal/lang/diag/diag_test.go
+
+package diag_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DauletBai/tenge/internal/lang/diag"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/token"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"qaıtar", "qaıtar", 0},
+		{"qaytar", "qaıtar", 1}, // runes, not bytes
+		{"teh", "the", 1},       // a transposition is one edit
+		{"totl", "total", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := diag.Distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := diag.Distance(tt.b, tt.a); got != tt.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestClosest(t *testing.T) {
+	names := []string{"fn", "if", "send", "end", "total", "count", "qaıtar", "jasa"}
+	tests := []struct {
+		name, want string // want is empty for no suggestion
+	}{
+		{"n", ""},      // too short: not fn
+		{"i", ""},      // not if
+		{"ef", ""},     // not if
+		{"ned", "end"}, // a transposition
+		{"sned", "send"},
+		{"totl", ""}, // a dropped letter in a short name
+		{"totaal", "total"},
+		{"cuont", "count"},
+		{"qaytar", "qaıtar"},
+		{"jsaa", "jasa"},
+		{"xyz", ""},
+		{"counting", ""}, // three edits
+		{"total", ""},    // the name itself is not a suggestion
+	}
+	for _, tt := range tests {
+		got, ok := diag.Closest(tt.name, names)
+		if !ok {
+			got = ""
+		}
+		if got != tt.want {
+			t.Errorf("Closest(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	// Ties go to the candidate first in order.
+	if got, _ := diag.Closest("abd", []string{"abe", "abc"}); got != "abc" {
+		t.Errorf("Closest(abd) = %q, want abc", got)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	msg.SetLang("en")
+	src := "jasa total = 1\nkórset(totla)\n"
+	tok := token.Token{Type: token.IDENT, Literal: "totla", File: "p.tng", Line: 2, Column: 8}
+	decl := token.Token{Type: token.IDENT, Literal: "total", File: "p.tng", Line: 1, Column: 6}
+	d := diag.New(diag.Error, tok, msg.Undefined, "totla").
+		Label(decl, "declared here").
+		Note("names are case-sensitive").
+		Suggest(tok, []string{"total"})
+	var b strings.Builder
+	p := &diag.Printer{Source: func(string) ([]byte, error) { return []byte(src), nil }}
+	p.Print(&b, d)
+	want := `p.tng:2:8: error[E0312]: undefined: totla
+  |
+1 | jasa total = 1
+  |      ----- declared here
+2 | kórset(totla)
+  |        ^^^^^
+  = note: names are case-sensitive
+  = help: did you mean ` + "`total`" + `?
+`
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	b.Reset()
+	if err := diag.PrintJSON(&b, d); err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{`"line":2`, `"column":8`, `"length":5`, `"code":"E0312"`, `"replacement":"total"`, `"message":"declared here"`} {
+		if !strings.Contains(b.String(), part) {
+			t.Errorf("JSON %s does not contain %s", b.String(), part)
+		}
+	}
+}
+
+// TestPrintWithoutSource prints only the message when the line cannot be
+// read.
+func TestPrintWithoutSource(t *testing.T) {
+	msg.SetLang("en")
+	tok := token.Token{Type: token.IDENT, Literal: "x", File: "gone.tng", Line: 3, Column: 1}
+	var b strings.Builder
+	p := &diag.Printer{Source: func(string) ([]byte, error) { return nil, nil }}
+	p.Print(&b, diag.New(diag.Warning, tok, msg.Undefined, "x"))
+	if want := "gone.tng:3:1: warning[E0312]: undefined: x\n"; b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
@@ -0,0 +1,179 @@
+// FILE: internal/lang/diag/print.go
+
+package diag
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DauletBai/tenge/internal/lang/msg"
+)
+
+// Printer renders diagnostics as text. Source returns the contents of a
+// file; when it is nil, files are read from disk. Diagnostics whose line
+// cannot be found are printed without a snippet.
+type Printer struct {
+	Source func(file string) ([]byte, error)
+
+	files map[string][]string
+}
+
+// mark is an underlined span: the primary span with carets, labels with
+// dashes.
+type mark struct {
+	span    Span
+	under   rune
+	message string
+}
+
+// Print writes d to w:
+//
+//	file:line:col: error[code]: message
+//	  |
+//	3 | source line
+//	  |     ^^^^^^
+//	  = note: ...
+func (p *Printer) Print(w io.Writer, d *Diagnostic) {
+	sev := msg.Text(msg.SeverityError)
+	if d.Severity == Warning {
+		sev = msg.Text(msg.SeverityWarning)
+	}
+	code := ""
+	if d.Code != "" {
+		code = "[" + string(d.Code) + "]"
+	}
+	if pos := d.Span.String(); pos != "" {
+		fmt.Fprintf(w, "%s: ", pos)
+	}
+	fmt.Fprintf(w, "%s%s: %s\n", sev, code, d.Message)
+
+	marks := []mark{{d.Span, '^', ""}}
+	for _, l := range d.Labels {
+		if l.Span == d.Span && marks[0].message == "" {
+			marks[0].message = l.Message // label the carets themselves
+			continue
+		}
+		marks = append(marks, mark{l.Span, '-', l.Message})
+	}
+	// The primary file first, then by position.
+	sort.SliceStable(marks, func(i, j int) bool {
+		a, b := marks[i].span, marks[j].span
+		if a.File != b.File {
+			if a.File == d.Span.File || b.File == d.Span.File {
+				return a.File == d.Span.File
+			}
+			return a.File < b.File
+		}
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+
+	width := 1
+	for _, m := range marks {
+		width = max(width, len(strconv.Itoa(m.span.Line)))
+	}
+	pad := strings.Repeat(" ", width)
+
+	shown, file, line := false, d.Span.File, 0
+	for _, m := range marks {
+		src, ok := p.line(m.span.File, m.span.Line)
+		if !ok {
+			continue
+		}
+		if !shown {
+			fmt.Fprintf(w, "%s |\n", pad)
+			shown = true
+		}
+		if m.span.File != file {
+			fmt.Fprintf(w, "%s::: %s\n", pad, m.span)
+			file, line = m.span.File, 0
+		}
+		if m.span.Line != line {
+			fmt.Fprintf(w, "%*d | %s\n", width, m.span.Line, src)
+			line = m.span.Line
+		}
+		fmt.Fprintf(w, "%s | %s\n", pad, underline(src, m))
+	}
+
+	for _, n := range d.Notes {
+		fmt.Fprintf(w, "%s = %s: %s\n", pad, msg.Text(msg.NoteLabel), n)
+	}
+	for _, f := range d.Fixes {
+		fmt.Fprintf(w, "%s = %s: %s\n", pad, msg.Text(msg.HelpLabel), msg.Sprintf(msg.DidYouMean, f.Text))
+	}
+}
+
+// underline returns the marker line for m under src. Tabs before the span
+// are kept so that it lines up however tabs are displayed.
+func underline(src string, m mark) string {
+	runes := []rune(src)
+	start := min(max(m.span.Column-1, 0), len(runes))
+	var b strings.Builder
+	for _, r := range runes[:start] {
+		if r == '\t' {
+			b.WriteRune('\t')
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	n := max(min(m.span.Len, len(runes)-start), 1)
+	b.WriteString(strings.Repeat(string(m.under), n))
+	if m.message != "" {
+		b.WriteString(" " + m.message)
+	}
+	return b.String()
+}
+
+// line returns line n of file, counting from 1.
+func (p *Printer) line(file string, n int) (string, bool) {
+	lines, ok := p.files[file]
+	if !ok {
+		read := p.Source
+		if read == nil {
+			read = os.ReadFile
+		}
+		if src, err := read(file); err == nil {
+			lines = strings.Split(string(src), "\n")
+		}
+		if p.files == nil {
+			p.files = make(map[string][]string)
+		}
+		p.files[file] = lines
+	}
+	if n < 1 || n > len(lines) {
+		return "", false
+	}
+	return strings.TrimSuffix(lines[n-1], "\r"), true
+}
+
+// jsonDiagnostic is the JSON form of a Diagnostic.
+type jsonDiagnostic struct {
+	Span
+	Severity string   `json:"severity"`
+	Code     msg.Code `json:"code,omitempty"`
+	Message  string   `json:"message"`
+	Labels   []Label  `json:"labels,omitempty"`
+	Notes    []string `json:"notes,omitempty"`
+	Fixes    []Fix    `json:"fixes,omitempty"`
+}
+
+// PrintJSON writes d to w as one line of JSON:
+//
+//	{"file":"prog.tng","line":3,"column":5,"length":6,"severity":"error",
+//	 "code":"E0312","message":"undefined: qaytar",
+//	 "fixes":[{"span":{...},"replacement":"qaıtar"}]}
+func PrintJSON(w io.Writer, d *Diagnostic) error {
+	return json.NewEncoder(w).Encode(jsonDiagnostic{
+		Span:     d.Span,
+		Severity: d.Severity.String(),
+		Code:     d.Code,
+		Message:  d.Message,
+		Labels:   d.Labels,
+		Notes:    d.Notes,
+		Fixes:    d.Fixes,
+	})
+}
@@ -0,0 +1,277 @@
+// FILE: internal/lang/evaluator/arith.go
+
+package evaluator
+
+import (
+	"math"
+
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/object"
+	"github.com/shopspring/decimal"
+)
+
+// numericRank orders the numeric objects for promotion: a binary
+// operation is carried out in the representation of the higher-ranked
+// operand (san < i32 < u64 < aqsha < f64). Untyped constants are san at
+// run time, so i32 ranks above san to keep the type of its operand.
+func numericRank(obj object.Object) int {
+	switch obj.(type) {
+	case *object.San:
+		return 1
+	case *object.I32:
+		return 2
+	case *object.U64:
+		return 3
+	case *object.Aqsha:
+		return 4
+	case *object.F64:
+		return 5
+	}
+	return 0
+}
+
+func evalInfix(operator string, left, right object.Object) object.Object {
+	lr, rr := numericRank(left), numericRank(right)
+	if lr > 0 && rr > 0 {
+		if operator == "<<" || operator == ">>" {
+			return evalShift(operator, left, right)
+		}
+		if lr < rr {
+			left = convert(left, right.Type())
+		} else if rr < lr {
+			right = convert(right, left.Type())
+		}
+		switch l := left.(type) {
+		case *object.San:
+			return evalSanInfix(operator, l.Value, right.(*object.San).Value)
+		case *object.I32:
+			return evalI32Infix(operator, l.Value, right.(*object.I32).Value)
+		case *object.U64:
+			return evalU64Infix(operator, l.Value, right.(*object.U64).Value)
+		case *object.Aqsha:
+			return evalAqshaInfix(operator, l.Value, right.(*object.Aqsha).Value)
+		case *object.F64:
+			return evalF64Infix(operator, l.Value, right.(*object.F64).Value)
+		}
+	}
+
+	switch l := left.(type) {
+	case *object.Jol:
+		if r, ok := right.(*object.Jol); ok {
+			return evalJolInfix(operator, l.Value, r.Value)
+		}
+	case *object.Aqıqat:
+		if r, ok := right.(*object.Aqıqat); ok {
+			switch operator {
+			case "==":
+				return nativeBoolToAqıqat(l.Value == r.Value)
+			case "!=":
+				return nativeBoolToAqıqat(l.Value != r.Value)
+			}
+		}
+	}
+	if left.Type() != right.Type() {
+		return newError(msg.TypeMismatch, left.Type(), operator, right.Type())
+	}
+	return newError(msg.UnknownOp, string(left.Type())+" "+operator+" "+string(right.Type()))
+}
+
+func evalShift(operator string, left, right object.Object) object.Object {
+	n, ok := toInt(right)
+	if !ok || n < 0 {
+		return newError(msg.ShiftCount, right.Inspect())
+	}
+	switch l := left.(type) {
+	case *object.San:
+		if operator == "<<" {
+			return &object.San{Value: l.Value << uint64(n)}
+		}
+		return &object.San{Value: l.Value >> uint64(n)}
+	case *object.I32:
+		if operator == "<<" {
+			return &object.I32{Value: l.Value << uint64(n)}
+		}
+		return &object.I32{Value: l.Value >> uint64(n)}
+	case *object.U64:
+		if operator == "<<" {
+			return &object.U64{Value: l.Value << uint64(n)}
+		}
+		return &object.U64{Value: l.Value >> uint64(n)}
+	}
+	return newError(msg.UnknownOp, string(left.Type())+" "+operator+" "+string(right.Type()))
+}
+
+func evalSanInfix(operator string, l, r int64) object.Object {
+	switch operator {
+	case "+":
+		return &object.San{Value: l + r}
+	case "-":
+		return &object.San{Value: l - r}
+	case "*":
+		return &object.San{Value: l * r}
+	case "/":
+		if r == 0 {
+			return newError(msg.DivisionByZero)
+		}
+		return &object.San{Value: l / r}
+	case "%":
+		if r == 0 {
+			return newError(msg.DivisionByZero)
+		}
+		return &object.San{Value: l % r}
+	case "&":
+		return &object.San{Value: l & r}
+	case "|":
+		return &object.San{Value: l | r}
+	case "^":
+		return &object.San{Value: l ^ r}
+	}
+	return compare(operator, cmpOrdered(l, r), object.SAN_OBJ)
+}
+
+// evalI32Infix computes in int32, so every result wraps to 32 bits as it
+// does in compiled code.
+func evalI32Infix(operator string, l, r int32) object.Object {
+	switch operator {
+	case "+":
+		return &object.I32{Value: l + r}
+	case "-":
+		return &object.I32{Value: l - r}
+	case "*":
+		return &object.I32{Value: l * r}
+	case "/":
+		if r == 0 {
+			return newError(msg.DivisionByZero)
+		}
+		return &object.I32{Value: l / r}
+	case "%":
+		if r == 0 {
+			return newError(msg.DivisionByZero)
+		}
+		return &object.I32{Value: l % r}
+	case "&":
+		return &object.I32{Value: l & r}
+	case "|":
+		return &object.I32{Value: l | r}
+	case "^":
+		return &object.I32{Value: l ^ r}
+	}
+	return compare(operator, cmpOrdered(l, r), object.I32_OBJ)
+}
+
+func evalU64Infix(operator string, l, r uint64) object.Object {
+	switch operator {
+	case "+":
+		return &object.U64{Value: l + r}
+	case "-":
+		return &object.U64{Value: l - r}
+	case "*":
+		return &object.U64{Value: l * r}
+	case "/":
+		if r == 0 {
+			return newError(msg.DivisionByZero)
+		}
+		return &object.U64{Value: l / r}
+	case "%":
+		if r == 0 {
+			return newError(msg.DivisionByZero)
+		}
+		return &object.U64{Value: l % r}
+	case "&":
+		return &object.U64{Value: l & r}
+	case "|":
+		return &object.U64{Value: l | r}
+	case "^":
+		return &object.U64{Value: l ^ r}
+	}
+	return compare(operator, cmpOrdered(l, r), object.U64_OBJ)
+}
+
+func evalF64Infix(operator string, l, r float64) object.Object {
+	switch operator {
+	case "+":
+		return &object.F64{Value: l + r}
+	case "-":
+		return &object.F64{Value: l - r}
+	case "*":
+		return &object.F64{Value: l * r}
+	case "/":
+		return &object.F64{Value: l / r}
+	case "%":
+		return &object.F64{Value: math.Mod(l, r)}
+	}
+	// NaN compares unequal to everything, including itself.
+	if l != l || r != r {
+		return nativeBoolToAqıqat(operator == "!=")
+	}
+	return compare(operator, cmpOrdered(l, r), object.F64_OBJ)
+}
+
+// divide rounds to 16 decimal places like decimal.Div, but keeps at least
+// 17 significant digits, so that small quotients of decimal constants such
+// as 1.0 / 9007199254740992.0 survive the conversion to f64.
+func divide(l, r decimal.Decimal) decimal.Decimal {
+	magnitude := (l.NumDigits() + int(l.Exponent())) - (r.NumDigits() + int(r.Exponent()))
+	places := 16
+	if 17-magnitude > places {
+		places = 17 - magnitude
+	}
+	return l.DivRound(r, int32(places))
+}
+
+func evalAqshaInfix(operator string, l, r decimal.Decimal) object.Object {
+	switch operator {
+	case "+":
+		return &object.Aqsha{Value: l.Add(r)}
+	case "-":
+		return &object.Aqsha{Value: l.Sub(r)}
+	case "*":
+		return &object.Aqsha{Value: l.Mul(r)}
+	case "/":
+		if r.IsZero() {
+			return newError(msg.DivisionByZero)
+		}
+		return &object.Aqsha{Value: divide(l, r)}
+	case "%":
+		if r.IsZero() {
+			return newError(msg.DivisionByZero)
+		}
+		return &object.Aqsha{Value: l.Mod(r)}
+	}
+	return compare(operator, l.Cmp(r), object.AQSHA_OBJ)
+}
+
+func evalJolInfix(operator string, l, r string) object.Object {
+	if operator == "+" {
+		return &object.Jol{Value: l + r}
+	}
+	return compare(operator, cmpOrdered(l, r), object.JOL_OBJ)
+}
+
+func cmpOrdered[T int32 | int64 | uint64 | float64 | string](l, r T) int {
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	}
+	return 0
+}
+
+func compare(operator string, c int, t object.ObjectType) object.Object {
+	switch operator {
+	case "==":
+		return nativeBoolToAqıqat(c == 0)
+	case "!=":
+		return nativeBoolToAqıqat(c != 0)
+	case "<":
+		return nativeBoolToAqıqat(c < 0)
+	case "<=":
+		return nativeBoolToAqıqat(c <= 0)
+	case ">":
+		return nativeBoolToAqıqat(c > 0)
+	case ">=":
+		return nativeBoolToAqıqat(c >= 0)
+	}
+	return newError(msg.UnknownOp, string(t)+" "+operator+" "+string(t))
+}
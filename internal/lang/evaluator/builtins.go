@@ -0,0 +1,264 @@
+// FILE: internal/lang/evaluator/builtins.go
+
+package evaluator
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/object"
+)
+
+// Stdout receives the output of the printing built-ins.
+var Stdout io.Writer = os.Stdout
+
+// stdoutMu keeps the output of one call of a printing built-in together
+// when parallel code prints.
+var stdoutMu sync.Mutex
+
+// Args are the program arguments seen by argi/argf; Args[0] is the
+// program name.
+var Args []string
+
+var start = time.Now()
+
+var constants = map[string]object.Object{
+	"PI": &object.F64{Value: math.Pi},
+}
+
+var builtins = map[string]*object.Builtin{}
+
+func init() {
+	def := func(name string, fn object.BuiltinFunction) {
+		builtins[name] = &object.Builtin{Name: name, Fn: fn}
+	}
+
+	// kórset prints its arguments as they are.
+	def("kórset", func(args ...object.Object) object.Object {
+		stdoutMu.Lock()
+		defer stdoutMu.Unlock()
+		for _, a := range args {
+			fmt.Fprint(Stdout, a.Inspect())
+		}
+		return object.NULL
+	})
+	// print writes strings verbatim and any other value on its own line.
+	def("print", func(args ...object.Object) object.Object {
+		stdoutMu.Lock()
+		defer stdoutMu.Unlock()
+		for _, a := range args {
+			if s, ok := a.(*object.Jol); ok {
+				fmt.Fprint(Stdout, s.Value)
+			} else {
+				fmt.Fprintln(Stdout, a.Inspect())
+			}
+		}
+		return object.NULL
+	})
+	def("printi", func(args ...object.Object) object.Object {
+		if err := arity("printi", args, 1); err != nil {
+			return err
+		}
+		stdoutMu.Lock()
+		defer stdoutMu.Unlock()
+		fmt.Fprint(Stdout, args[0].Inspect())
+		return object.NULL
+	})
+	def("printf", func(args ...object.Object) object.Object {
+		if err := arity("printf", args, 2); err != nil {
+			return err
+		}
+		stdoutMu.Lock()
+		defer stdoutMu.Unlock()
+		f, ok := toFloat(args[0])
+		digits, ok2 := toInt(args[1])
+		if !ok || !ok2 {
+			return newError(msg.PrintfArgs, args[0].Type(), args[1].Type())
+		}
+		fmt.Fprint(Stdout, strconv.FormatFloat(f, 'f', int(digits), 64))
+		return object.NULL
+	})
+	def("print_time_ns", func(args ...object.Object) object.Object {
+		if err := arity("print_time_ns", args, 1); err != nil {
+			return err
+		}
+		stdoutMu.Lock()
+		defer stdoutMu.Unlock()
+		fmt.Fprintf(Stdout, "TIME_NS: %s\n", args[0].Inspect())
+		return object.NULL
+	})
+
+	def("argi", func(args ...object.Object) object.Object {
+		return argument("argi", args, func(s string) (object.Object, bool) {
+			n, err := strconv.ParseInt(s, 10, 64)
+			return &object.San{Value: n}, err == nil
+		}, &object.San{})
+	})
+	def("argf", func(args ...object.Object) object.Object {
+		return argument("argf", args, func(s string) (object.Object, bool) {
+			f, err := strconv.ParseFloat(s, 64)
+			return &object.F64{Value: f}, err == nil
+		}, &object.F64{})
+	})
+
+	nowNs := func(args ...object.Object) object.Object {
+		return &object.San{Value: int64(time.Since(start))}
+	}
+	def("now_ns", nowNs)
+	def("time_ns", nowNs)
+	def("now_ms", func(args ...object.Object) object.Object {
+		return &object.San{Value: time.Since(start).Milliseconds()}
+	})
+
+	math1 := func(name string, f func(float64) float64) {
+		def(name, func(args ...object.Object) object.Object {
+			if err := arity(name, args, 1); err != nil {
+				return err
+			}
+			x, ok := toFloat(args[0])
+			if !ok {
+				return newError(msg.NumberArg, name, args[0].Type())
+			}
+			return &object.F64{Value: f(x)}
+		})
+	}
+	math1("sqrt", math.Sqrt)
+	math1("ln", math.Log)
+	math1("exp", math.Exp)
+	math1("cos", math.Cos)
+	math1("sin", math.Sin)
+	math1("floor", math.Floor)
+
+	makeArray := func(name string, zero object.Object) {
+		def(name, func(args ...object.Object) object.Object {
+			if err := arity(name, args, 1); err != nil {
+				return err
+			}
+			n, ok := toInt(args[0])
+			if !ok || n < 0 {
+				return newError(msg.InvalidLength, name, args[0].Inspect())
+			}
+			elems := make([]object.Object, n)
+			for i := range elems {
+				elems[i] = zero
+			}
+			return &object.Jyim{Elements: elems}
+		})
+	}
+	makeArray("make_f64", &object.F64{})
+	makeArray("make_i32", &object.I32{})
+
+	def("len", func(args ...object.Object) object.Object {
+		if err := arity("len", args, 1); err != nil {
+			return err
+		}
+		switch arg := args[0].(type) {
+		case *object.Jyim:
+			return &object.San{Value: int64(len(arg.Elements))}
+		case *object.Jol:
+			return &object.San{Value: int64(len([]rune(arg.Value)))}
+		}
+		return newError(msg.LenArg, args[0].Type())
+	})
+	def("push", func(args ...object.Object) object.Object {
+		if err := arity("push", args, 2); err != nil {
+			return err
+		}
+		arr, ok := args[0].(*object.Jyim)
+		if !ok {
+			return newError(msg.FirstJyimArg, "push", args[0].Type())
+		}
+		elems := make([]object.Object, len(arr.Elements), len(arr.Elements)+1)
+		copy(elems, arr.Elements)
+		return &object.Jyim{Elements: append(elems, args[1])}
+	})
+	def("index", func(args ...object.Object) object.Object {
+		if err := arity("index", args, 2); err != nil {
+			return err
+		}
+		arr, ok := args[0].(*object.Jyim)
+		i, ok2 := toInt(args[1])
+		if !ok || !ok2 {
+			return newError(msg.IndexArgs, args[0].Type(), args[1].Type())
+		}
+		if i < 0 || i >= int64(len(arr.Elements)) {
+			return newError(msg.OutOfRange, i, len(arr.Elements))
+		}
+		return arr.Elements[i]
+	})
+	def("sort", func(args ...object.Object) object.Object {
+		if err := arity("sort", args, 1); err != nil {
+			return err
+		}
+		arr, ok := args[0].(*object.Jyim)
+		if !ok {
+			return newError(msg.JyimArgRT, "sort", args[0].Type())
+		}
+		elems := make([]object.Object, len(arr.Elements))
+		copy(elems, arr.Elements)
+		var err object.Object
+		sort.SliceStable(elems, func(i, j int) bool {
+			less := evalInfix("<", elems[i], elems[j])
+			if isError(less) {
+				err = less
+				return false
+			}
+			return less == object.JAN
+		})
+		if err != nil {
+			return err
+		}
+		return &object.Jyim{Elements: elems}
+	})
+	def("assert", func(args ...object.Object) object.Object {
+		if len(args) < 1 || len(args) > 2 {
+			return newError(msg.BuiltinArgCount, "assert", len(args), msg.Text(msg.OneOrTwo))
+		}
+		if ok, err := truthy(args[0]); err != nil {
+			return err
+		} else if !ok {
+			if len(args) == 2 {
+				return newError(msg.AssertionMessage, args[1].Inspect())
+			}
+			return newError(msg.AssertionFailed)
+		}
+		return object.NULL
+	})
+}
+
+func arity(name string, args []object.Object, want int) *object.Error {
+	if len(args) != want {
+		return newError(msg.BuiltinArgCount, name, len(args), fmt.Sprint(want))
+	}
+	return nil
+}
+
+// argument implements argi/argf: the value of Args[i] or the default
+// (second argument, else zero) when it is missing or malformed.
+func argument(name string, args []object.Object, parse func(string) (object.Object, bool), zero object.Object) object.Object {
+	if len(args) < 1 || len(args) > 2 {
+		return newError(msg.BuiltinArgCount, name, len(args), msg.Text(msg.OneOrTwo))
+	}
+	i, ok := toInt(args[0])
+	if !ok {
+		return newError(msg.IndexNotSan, name, args[0].Type())
+	}
+	def := zero
+	if len(args) == 2 {
+		def = convert(args[1], zero.Type())
+	}
+	if i < 0 || i >= int64(len(Args)) {
+		return def
+	}
+	if v, ok := parse(Args[i]); ok {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,474 @@
+// FILE: internal/lang/evaluator/channel.go
+
+package evaluator
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/object"
+	"github.com/DauletBai/tenge/internal/lang/token"
+)
+
+// Channels pass values between the goroutines running tasks and parallel
+// loops. One mutex, sched, guards every channel. A goroutine that has to
+// wait enqueues a waiter on the channels it waits for and sleeps until
+// another goroutine hands it a value, closes a channel or its timeout
+// passes.
+//
+// sched also counts the goroutines running tenge code and the waiters
+// blocked without a timeout. When every goroutine is blocked nothing can
+// wake them any more, so each blocked operation fails with an error that
+// lists where the goroutines are blocked.
+var sched = struct {
+	sync.Mutex
+	live    int              // goroutines running tenge code, the main one included
+	blocked map[*waiter]bool // waiters without a timeout that nothing has woken yet
+}{live: 1, blocked: make(map[*waiter]bool)}
+
+// waiter is a goroutine waiting in a channel operation, a tańda statement
+// or for the tasks of a kút block or the workers of a qatarlas loop.
+type waiter struct {
+	what  string      // the operation as written, for deadlock reports
+	at    token.Token // where it waits
+	wake  chan struct{}
+	woken bool
+
+	// Set by the goroutine that wakes the waiter.
+	clause int           // the clause that went on; -1 when the timeout passed
+	val    object.Object // the value received
+	ok     bool          // false when the channel was closed
+	err    *object.Error
+}
+
+func newWaiter(what string, at token.Token) *waiter {
+	return &waiter{what: what, at: at, wake: make(chan struct{}, 1)}
+}
+
+// block counts w as blocked until it is woken. The caller holds sched.
+func (w *waiter) block() {
+	sched.blocked[w] = true
+	checkDeadlock()
+}
+
+// wakeUp lets w go on, unless it already has. The caller holds sched.
+func (w *waiter) wakeUp() {
+	if w.woken {
+		return
+	}
+	w.woken = true
+	delete(sched.blocked, w)
+	w.wake <- struct{}{}
+}
+
+// checkDeadlock fails every blocked waiter when all the goroutines are
+// blocked. The caller holds sched.
+func checkDeadlock() {
+	if len(sched.blocked) == 0 || len(sched.blocked) < sched.live {
+		return
+	}
+	ws := make([]*waiter, 0, len(sched.blocked))
+	for w := range sched.blocked {
+		ws = append(ws, w)
+	}
+	sort.Slice(ws, func(i, j int) bool {
+		a, b := ws[i].at, ws[j].at
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	var report strings.Builder
+	report.WriteString(msg.Sprintf(msg.Deadlock))
+	last := ""
+	for _, w := range ws {
+		line := w.at.Pos() + ": " + msg.Sprintf(msg.BlockedIn, w.what)
+		if line != last {
+			report.WriteString("\n\t" + line)
+			last = line
+		}
+	}
+	for _, w := range ws {
+		w.err = &object.Error{Message: w.at.Pos() + ": " + report.String()}
+		w.wakeUp()
+	}
+}
+
+// join counts the goroutines a block waits for: the tasks of a kút block
+// or the workers of a qatarlas loop.
+type join struct {
+	n  int     // goroutines still running; guarded by sched
+	w  *waiter // the block waiting for them, if any
+	wg sync.WaitGroup
+}
+
+// add counts a goroutine about to start.
+func (j *join) add() {
+	sched.Lock()
+	j.n++
+	sched.live++
+	sched.Unlock()
+	j.wg.Add(1)
+}
+
+// done is called by a goroutine as it ends.
+func (j *join) done() {
+	sched.Lock()
+	j.n--
+	sched.live--
+	if j.n == 0 && j.w != nil {
+		j.w.wakeUp()
+	}
+	checkDeadlock()
+	sched.Unlock()
+	j.wg.Done()
+}
+
+// wait waits until the goroutines have ended. It fails if they deadlock;
+// they are then failing too, and end waits for them to end.
+func (j *join) wait(what string, at token.Token) *object.Error {
+	sched.Lock()
+	if j.n == 0 {
+		sched.Unlock()
+		return nil
+	}
+	j.w = newWaiter(what, at)
+	j.w.block()
+	sched.Unlock()
+	<-j.w.wake
+	return j.w.err
+}
+
+func (j *join) end() { j.wg.Wait() }
+
+// channel is a value of type arna[T]: a queue of up to cap values, or an
+// unbuffered channel passing each value straight from a sender to a
+// receiver when cap is 0.
+type channel struct {
+	zero   object.Object // the zero value of T, received from a closed channel
+	cap    int
+	buf    []object.Object
+	closed bool
+	recvq  []*pending
+	sendq  []*pending
+}
+
+func (c *channel) Type() object.ObjectType { return "ARNA" }
+func (c *channel) Inspect() string         { return token.ARNA }
+
+// pending is a waiter enqueued on a channel by one of its clauses.
+type pending struct {
+	w      *waiter
+	clause int
+	val    object.Object // the value to send
+	at     token.Token
+}
+
+// pop removes and returns the first pending operation whose waiter has
+// not gone on with another clause.
+func pop(q *[]*pending) *pending {
+	for len(*q) > 0 {
+		p := (*q)[0]
+		*q = (*q)[1:]
+		if !p.w.woken {
+			return p
+		}
+	}
+	return nil
+}
+
+// forget removes the operations of w from the queues of c.
+func (c *channel) forget(w *waiter) {
+	for _, q := range []*[]*pending{&c.recvq, &c.sendq} {
+		kept := (*q)[:0]
+		for _, p := range *q {
+			if p.w != w {
+				kept = append(kept, p)
+			}
+		}
+		*q = kept
+	}
+}
+
+// comm is the operation of a send or recv call or of a tańda clause.
+type comm struct {
+	ch   *channel
+	send bool
+	val  object.Object // the value to send
+	at   token.Token
+}
+
+// ready performs the first of cs that can go on without waiting and
+// returns its index and the value received, or -1. The caller holds sched.
+func ready(cs []comm) (int, object.Object, bool, *object.Error) {
+	for i, c := range cs {
+		ch := c.ch
+		if c.send {
+			if ch.closed {
+				return i, nil, false, newErrorAt(c.at, msg.SendClosed)
+			}
+			if p := pop(&ch.recvq); p != nil {
+				p.w.clause, p.w.val, p.w.ok = p.clause, c.val, true
+				p.w.wakeUp()
+				return i, nil, true, nil
+			}
+			if len(ch.buf) < ch.cap {
+				ch.buf = append(ch.buf, c.val)
+				return i, nil, true, nil
+			}
+			continue
+		}
+		if len(ch.buf) > 0 {
+			v := ch.buf[0]
+			ch.buf = ch.buf[1:]
+			if p := pop(&ch.sendq); p != nil {
+				ch.buf = append(ch.buf, p.val)
+				p.w.clause = p.clause
+				p.w.wakeUp()
+			}
+			return i, v, true, nil
+		}
+		if p := pop(&ch.sendq); p != nil {
+			p.w.clause = p.clause
+			p.w.wakeUp()
+			return i, p.val, true, nil
+		}
+		if ch.closed {
+			return i, ch.zero, false, nil
+		}
+	}
+	return -1, nil, false, nil
+}
+
+// choose waits until one of cs can go on, performs it and returns its
+// index, the value received and whether it came from a send rather than
+// a closed channel. With wait false it returns -1 at once when none can
+// go on; with a timeout of zero or more it returns -1 when the timeout
+// has passed.
+func choose(cs []comm, wait bool, timeout time.Duration, what string, at token.Token) (int, object.Object, bool, *object.Error) {
+	sched.Lock()
+	if i, v, ok, err := ready(cs); i >= 0 || !wait {
+		sched.Unlock()
+		return i, v, ok, err
+	}
+	w := newWaiter(what, at)
+	for i, c := range cs {
+		p := &pending{w: w, clause: i, val: c.val, at: c.at}
+		if c.send {
+			c.ch.sendq = append(c.ch.sendq, p)
+		} else {
+			c.ch.recvq = append(c.ch.recvq, p)
+		}
+	}
+	if timeout >= 0 {
+		timer := time.AfterFunc(timeout, func() {
+			sched.Lock()
+			if !w.woken {
+				w.clause = -1
+				w.wakeUp()
+			}
+			sched.Unlock()
+		})
+		defer timer.Stop()
+	} else {
+		w.block()
+	}
+	sched.Unlock()
+
+	<-w.wake
+	sched.Lock()
+	for _, c := range cs {
+		c.ch.forget(w)
+	}
+	sched.Unlock()
+	return w.clause, w.val, w.ok, w.err
+}
+
+// closeChan closes ch: its receivers get the values left and then the
+// zero value, and its senders fail.
+func closeChan(ch *channel, at token.Token) *object.Error {
+	sched.Lock()
+	defer sched.Unlock()
+	if ch.closed {
+		return newErrorAt(at, msg.CloseClosed)
+	}
+	ch.closed = true
+	for p := pop(&ch.recvq); p != nil; p = pop(&ch.recvq) {
+		p.w.clause, p.w.val, p.w.ok = p.clause, ch.zero, false
+		p.w.wakeUp()
+	}
+	for p := pop(&ch.sendq); p != nil; p = pop(&ch.sendq) {
+		p.w.err = newErrorAt(p.at, msg.SendClosed)
+		p.w.wakeUp()
+	}
+	return nil
+}
+
+// evalMakeChan evaluates `arna[T](n)`.
+func evalMakeChan(node *ast.CallExpression, tn *ast.TypeNode, env *object.Environment) object.Object {
+	ch := &channel{zero: zeroValue(tn.Elem, env)}
+	if len(node.Arguments) > 0 {
+		v := Eval(node.Arguments[0], env)
+		if isError(v) {
+			return v
+		}
+		n, ok := toInt(v)
+		if !ok {
+			return newErrorAt(tn.Token, msg.ConvertRT, v.Type(), "san")
+		}
+		if n < 0 {
+			return newErrorAt(tn.Token, msg.NegativeCapacity, n)
+		}
+		ch.cap = int(n)
+	}
+	return ch
+}
+
+// chanOps are the built-ins evalChanOp runs. They know where they are
+// called from, for the positions of their errors and deadlock reports.
+var chanOps = map[string]bool{"send": true, "recv": true, "close": true}
+
+// chanArg returns the channel v, or an error for the operation id.
+func chanArg(id *ast.Identifier, v object.Object) (*channel, *object.Error) {
+	if ch, ok := v.(*channel); ok {
+		return ch, nil
+	}
+	if v == object.NULL {
+		return nil, newErrorAt(id.Token, msg.ChanNotMade, id.Value)
+	}
+	return nil, newErrorAt(id.Token, msg.ConvertRT, v.Type(), token.ARNA)
+}
+
+// storeOK sets the variable p points to, the second argument of recv, to
+// whether a value was received.
+func storeOK(id *ast.Identifier, p object.Object, ok bool) *object.Error {
+	ptr, isPtr := p.(*object.Pointer)
+	if !isPtr {
+		return newErrorAt(id.Token, msg.NotPointer, p.Type())
+	}
+	ptr.Store(nativeBoolToAqıqat(ok))
+	return nil
+}
+
+// evalChanOp evaluates a call of send, recv or close.
+func evalChanOp(node *ast.CallExpression, id *ast.Identifier, env *object.Environment) object.Object {
+	args := evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+	want := map[string]int{"send": 2, "recv": 1, "close": 1}[id.Value]
+	if len(args) != want && !(id.Value == "recv" && len(args) == 2) {
+		return withCallPos(id, arity(id.Value, args, want))
+	}
+	ch, err := chanArg(id, args[0])
+	if err != nil {
+		return err
+	}
+	switch id.Value {
+	case "send":
+		c := comm{ch: ch, send: true, val: coerceLike(ch.zero, args[1]), at: id.Token}
+		if _, _, _, err := choose([]comm{c}, true, -1, id.Value, id.Token); err != nil {
+			return err
+		}
+	case "recv":
+		_, v, ok, err := choose([]comm{{ch: ch, at: id.Token}}, true, -1, id.Value, id.Token)
+		if err != nil {
+			return err
+		}
+		if len(args) == 2 {
+			if err := storeOK(id, args[1], ok); err != nil {
+				return err
+			}
+		}
+		return v
+	case "close":
+		if err := closeChan(ch, id.Token); err != nil {
+			return err
+		}
+	}
+	return object.NULL
+}
+
+func withCallPos(id *ast.Identifier, err *object.Error) *object.Error {
+	if err == nil || hasPos(err) {
+		return err
+	}
+	return &object.Error{Message: id.Token.Pos() + ": " + err.Message}
+}
+
+// evalTanda evaluates the channels and values of all the clauses of a
+// tańda statement, in order, then waits until one of them can go on and
+// runs its body.
+func evalTanda(node *ast.TandaStatement, env *object.Environment) object.Object {
+	var cs []comm
+	var clauses []int       // the clause of each comm
+	var oks []object.Object // the second argument of each recv, if any
+	fallback := -1          // the timeout or áıtpece clause
+	wait, timeout := true, time.Duration(-1)
+	for i, cl := range node.Clauses {
+		if cl.Comm == nil {
+			fallback, wait = i, false
+			continue
+		}
+		id := cl.Comm.Function.(*ast.Identifier)
+		args := evalExpressions(cl.Comm.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		if id.Value == "timeout" {
+			ms, ok := toInt(args[0])
+			if !ok {
+				return newErrorAt(id.Token, msg.ConvertRT, args[0].Type(), "san")
+			}
+			fallback, timeout = i, time.Duration(max(ms, 0))*time.Millisecond
+			continue
+		}
+		ch, err := chanArg(id, args[0])
+		if err != nil {
+			return err
+		}
+		c := comm{ch: ch, at: id.Token}
+		var ok object.Object
+		if id.Value == "send" {
+			c.send, c.val = true, coerceLike(ch.zero, args[1])
+		} else if len(args) == 2 {
+			ok = args[1]
+		}
+		cs = append(cs, c)
+		clauses = append(clauses, i)
+		oks = append(oks, ok)
+	}
+
+	k, v, ok, err := choose(cs, wait, timeout, node.Token.Literal, node.Token)
+	if err != nil {
+		return err
+	}
+	var cl *ast.TandaClause
+	if k < 0 {
+		cl = node.Clauses[fallback]
+	} else {
+		cl = node.Clauses[clauses[k]]
+		if oks[k] != nil {
+			if err := storeOK(cl.Comm.Function.(*ast.Identifier), oks[k], ok); err != nil {
+				return err
+			}
+		}
+	}
+	cenv := object.NewEnclosedEnvironment(env)
+	if cl.Target != nil {
+		if cl.Define {
+			cenv.Set(cl.Target.Value, v)
+		} else {
+			old, _ := env.Get(cl.Target.Value)
+			env.Assign(cl.Target.Value, coerceLike(old, v))
+		}
+	}
+	return Eval(cl.Body, cenv)
+}
@@ -0,0 +1,899 @@
+// FILE: internal/lang/evaluator/evaluator.go
+
+// Package evaluator implements the tree-walking interpreter (stage 1 of
+// the roadmap).
+package evaluator
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/module"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/object"
+	"github.com/DauletBai/tenge/internal/lang/token"
+	"github.com/shopspring/decimal"
+)
+
+// MaxDepth is the number of nested calls after which a program fails
+// with a stack overflow, as on the VM. It is lower than the VM's, because
+// every call of the interpreter nests several calls of Go, whose stack
+// would run out first.
+const MaxDepth = 1 << 17
+
+// depth counts the calls in progress, in all threads together.
+var depth atomic.Int64
+
+// Run evaluates the program's top-level statements and then calls a
+// parameterless `main` function if the program declares one.
+func Run(program *ast.Program, env *object.Environment) object.Object {
+	result := Eval(program, env)
+	if isError(result) {
+		return result
+	}
+	return callMain(env, result)
+}
+
+// RunModules is Run for a loaded multi-file program: it evaluates every
+// module and then calls `main` of the main module.
+func RunModules(prog *module.Program) object.Object {
+	env, result := EvalModules(prog)
+	if isError(result) {
+		return result
+	}
+	return callMain(env, result)
+}
+
+// EvalModules evaluates the top-level statements of the modules of prog in
+// dependency order, each in its own environment in which the qualifiers of
+// its imports are bound. It returns the environment of the main module.
+func EvalModules(prog *module.Program) (*object.Environment, object.Object) {
+	envs := make(map[*module.Module]*object.Environment)
+	var env *object.Environment
+	var result object.Object = object.NULL
+	for _, m := range prog.Modules {
+		env = object.NewEnvironment()
+		for _, imp := range m.Imports {
+			env.Set(imp.Decl.Name(), &object.Module{Name: imp.Module.Name, Env: envs[imp.Module]})
+		}
+		envs[m] = env
+		if result = Eval(m.Program, env); isError(result) {
+			return env, result
+		}
+	}
+	return env, result
+}
+
+func callMain(env *object.Environment, result object.Object) object.Object {
+	if fn, ok := env.Get("main"); ok {
+		if main, ok := fn.(*object.Atqarm); ok && len(main.Literal.Parameters) == 0 {
+			return applyFunction(main, nil)
+		}
+	}
+	return result
+}
+
+// Eval evaluates node in env.
+func Eval(node ast.Node, env *object.Environment) object.Object {
+	switch node := node.(type) {
+
+	// Statements
+	case *ast.Program:
+		return evalProgram(node, env)
+	case *ast.BlockStatement:
+		return evalBlockStatement(node, object.NewEnclosedEnvironment(env))
+	case *ast.ExpressionStatement:
+		return Eval(node.Expression, env)
+	case *ast.JasaStatement:
+		return evalBinding(node.Name, node.Type, node.Value, env)
+	case *ast.BekitStatement:
+		return evalBinding(node.Name, node.Type, node.Value, env)
+	case *ast.QaıtarStatement:
+		if node.ReturnValue == nil {
+			return &object.QaıtarValue{Value: object.NULL}
+		}
+		if call, ok := node.ReturnValue.(*ast.CallExpression); ok {
+			if tail := evalTailCall(call, env); tail != nil {
+				return tail
+			}
+		}
+		val := Eval(node.ReturnValue, env)
+		if isError(val) {
+			return val
+		}
+		return &object.QaıtarValue{Value: val}
+	case *ast.AssignStatement:
+		return evalAssign(node, env)
+	case *ast.AzirsheStatement:
+		return evalAzirshe(node, env)
+	case *ast.KutStatement:
+		return evalKut(node, env)
+	case *ast.MindetStatement:
+		return evalMindet(node, env)
+	case *ast.TandaStatement:
+		return evalTanda(node, env)
+	case *ast.ModulStatement, *ast.EngizStatement:
+		// Resolved by the module loader; qualifiers are bound by EvalModules.
+		return object.NULL
+
+	// Expressions
+	case *ast.SanLiteral:
+		return &object.San{Value: node.Value}
+	case *ast.AqshaLiteral:
+		return &object.Aqsha{Value: node.Value}
+	case *ast.JolLiteral:
+		return &object.Jol{Value: node.Value}
+	case *ast.AqıqatLiteral:
+		return nativeBoolToAqıqat(node.Value)
+	case *ast.JyimLiteral:
+		elements := evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+		return &object.Jyim{Elements: elements}
+	case *ast.Identifier:
+		return evalIdentifier(node, env)
+	case *ast.PrefixExpression:
+		return evalPrefixExpression(node, env)
+	case *ast.InfixExpression:
+		return evalInfixExpression(node, env)
+	case *ast.EgerExpression:
+		return evalEgerExpression(node, env)
+	case *ast.AtqarmLiteral:
+		return &object.Atqarm{Literal: node, Env: env}
+	case *ast.CallExpression:
+		return evalCallExpression(node, env)
+	case *ast.IndexExpression:
+		return evalIndexExpression(node, env)
+	case *ast.SelectorExpression:
+		return evalSelectorExpression(node, env)
+	case *ast.QatarlasExpression:
+		return evalQatarlas(node, env)
+	}
+	return newError(msg.CannotEvaluate, node)
+}
+
+func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+	var result object.Object = object.NULL
+	for _, statement := range program.Statements {
+		result = Eval(statement, env)
+		switch result := result.(type) {
+		case *object.QaıtarValue:
+			if result.Call != nil {
+				return applyTailCall(result.Call)
+			}
+			return result.Value
+		case *object.Error:
+			return result
+		}
+	}
+	return result
+}
+
+func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+	var result object.Object = object.NULL
+	for _, statement := range block.Statements {
+		result = Eval(statement, env)
+		if result != nil {
+			rt := result.Type()
+			if rt == object.QAITAR_VAL || rt == object.ERROR_OBJ {
+				return result
+			}
+		}
+	}
+	return result
+}
+
+func evalBinding(name *ast.Identifier, tn *ast.TypeNode, value ast.Expression, env *object.Environment) object.Object {
+	var val object.Object
+	if value == nil {
+		val = zeroValue(tn, env)
+	} else {
+		val = Eval(value, env)
+		if isError(val) {
+			return val
+		}
+		val = coerce(val, tn, env)
+		if isError(val) {
+			return val
+		}
+	}
+	env.Set(name.Value, val)
+	return object.NULL
+}
+
+func evalAssign(node *ast.AssignStatement, env *object.Environment) object.Object {
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		old, ok := env.Get(target.Value)
+		if !ok {
+			return newErrorAt(target.Token, msg.Undefined, target.Value)
+		}
+		env.Assign(target.Value, coerceLike(old, val))
+	case *ast.IndexExpression:
+		left := Eval(target.Left, env)
+		if isError(left) {
+			return left
+		}
+		index := Eval(target.Index, env)
+		if isError(index) {
+			return index
+		}
+		arr, ok := left.(*object.Jyim)
+		if !ok {
+			return newErrorAt(target.Token, msg.NotIndexable, left.Type())
+		}
+		i, ok := toInt(index)
+		if !ok {
+			return newErrorAt(target.Token, msg.BadIndex, index.Inspect())
+		}
+		if i < 0 || i >= int64(len(arr.Elements)) {
+			return newErrorAt(target.Token, msg.OutOfRange, i, len(arr.Elements))
+		}
+		arr.Elements[i] = coerceLike(arr.Elements[i], val)
+	case *ast.PrefixExpression:
+		ptr := Eval(target.Right, env)
+		if isError(ptr) {
+			return ptr
+		}
+		p, ok := ptr.(*object.Pointer)
+		if !ok {
+			return newErrorAt(target.Token, msg.NotPointer, ptr.Type())
+		}
+		p.Store(coerceLike(p.Load(), val))
+	}
+	return object.NULL
+}
+
+func evalAzirshe(node *ast.AzirsheStatement, env *object.Environment) object.Object {
+	for {
+		cond := Eval(node.Condition, env)
+		if isError(cond) {
+			return cond
+		}
+		ok, err := truthy(cond)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return object.NULL
+		}
+		result := Eval(node.Body, env)
+		if result != nil && (result.Type() == object.QAITAR_VAL || result.Type() == object.ERROR_OBJ) {
+			return result
+		}
+	}
+}
+
+func evalEgerExpression(ee *ast.EgerExpression, env *object.Environment) object.Object {
+	cond := Eval(ee.Condition, env)
+	if isError(cond) {
+		return cond
+	}
+	ok, err := truthy(cond)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return Eval(ee.Consequence, env)
+	} else if ee.Alternative != nil {
+		return Eval(ee.Alternative, env)
+	}
+	return object.NULL
+}
+
+func truthy(obj object.Object) (bool, *object.Error) {
+	if b, ok := obj.(*object.Aqıqat); ok {
+		return b.Value, nil
+	}
+	return false, newError(msg.NonBoolCondition, obj.Inspect(), obj.Type())
+}
+
+func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+	if val, ok := env.Get(node.Value); ok {
+		return val
+	}
+	if builtin, ok := builtins[node.Value]; ok {
+		return builtin
+	}
+	if val, ok := constants[node.Value]; ok {
+		return val
+	}
+	return newErrorAt(node.Token, msg.Undefined, node.Value)
+}
+
+func evalSelectorExpression(node *ast.SelectorExpression, env *object.Environment) object.Object {
+	x := Eval(node.X, env)
+	if isError(x) {
+		return x
+	}
+	mod, ok := x.(*object.Module)
+	if !ok {
+		return newErrorAt(node.Token, msg.NotModulRT, node.X)
+	}
+	if val, ok := mod.Env.Get(node.Sel.Value); ok {
+		return val
+	}
+	return newErrorAt(node.Sel.Token, msg.Undefined, node)
+}
+
+func evalPrefixExpression(node *ast.PrefixExpression, env *object.Environment) object.Object {
+	if node.Operator == "&" {
+		id, ok := node.Right.(*ast.Identifier)
+		if !ok {
+			return newErrorAt(node.Token, msg.AddressOf, node.Right)
+		}
+		if _, ok := env.Get(id.Value); !ok {
+			return newErrorAt(id.Token, msg.Undefined, id.Value)
+		}
+		return &object.Pointer{Env: env, Name: id.Value}
+	}
+
+	right := Eval(node.Right, env)
+	if isError(right) {
+		return right
+	}
+	switch node.Operator {
+	case "!":
+		if b, ok := right.(*object.Aqıqat); ok {
+			return nativeBoolToAqıqat(!b.Value)
+		}
+	case "-":
+		switch r := right.(type) {
+		case *object.San:
+			return &object.San{Value: -r.Value}
+		case *object.I32:
+			return &object.I32{Value: -r.Value}
+		case *object.U64:
+			return &object.U64{Value: -r.Value}
+		case *object.F64:
+			return &object.F64{Value: -r.Value}
+		case *object.Aqsha:
+			return &object.Aqsha{Value: r.Value.Neg()}
+		}
+	case "*":
+		if p, ok := right.(*object.Pointer); ok {
+			return p.Load()
+		}
+	}
+	return newErrorAt(node.Token, msg.UnknownOp, node.Operator+string(right.Type()))
+}
+
+func evalInfixExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	// && and || short-circuit.
+	if node.Operator == "&&" || node.Operator == "||" {
+		l, err := truthy(left)
+		if err != nil {
+			return err
+		}
+		if l == (node.Operator == "||") {
+			return nativeBoolToAqıqat(l)
+		}
+		right := Eval(node.Right, env)
+		if isError(right) {
+			return right
+		}
+		r, err := truthy(right)
+		if err != nil {
+			return err
+		}
+		return nativeBoolToAqıqat(r)
+	}
+
+	right := Eval(node.Right, env)
+	if isError(right) {
+		return right
+	}
+	result := evalInfix(node.Operator, left, right)
+	if err, ok := result.(*object.Error); ok && node.Token.Line > 0 {
+		return &object.Error{Message: node.Token.Pos() + ": " + err.Message}
+	}
+	return result
+}
+
+func evalCallExpression(node *ast.CallExpression, env *object.Environment) object.Object {
+	if tn, ok := node.Function.(*ast.TypeNode); ok {
+		return evalMakeChan(node, tn, env)
+	}
+	if id, ok := node.Function.(*ast.Identifier); ok {
+		if _, bound := env.Get(id.Value); !bound {
+			if chanOps[id.Value] {
+				return evalChanOp(node, id, env)
+			}
+			if kind, ok := conversions[id.Value]; ok {
+				if len(node.Arguments) != 1 {
+					return newErrorAt(id.Token, msg.ConversionArgs, id.Value)
+				}
+				arg := Eval(node.Arguments[0], env)
+				if isError(arg) {
+					return arg
+				}
+				return convert(arg, kind)
+			}
+		}
+	}
+
+	function := Eval(node.Function, env)
+	if isError(function) {
+		return function
+	}
+	args := evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+	result := applyFunction(function, args)
+	if err, ok := result.(*object.Error); ok && !hasPos(err) {
+		return &object.Error{Message: node.Token.Pos() + ": " + err.Message}
+	}
+	return result
+}
+
+func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+	var result []object.Object
+	for _, e := range exps {
+		evaluated := Eval(e, env)
+		if isError(evaluated) {
+			return []object.Object{evaluated}
+		}
+		result = append(result, evaluated)
+	}
+	return result
+}
+
+func applyFunction(fn object.Object, args []object.Object) object.Object {
+	switch fn := fn.(type) {
+	case *object.Atqarm:
+		if depth.Add(1) > MaxDepth {
+			depth.Add(-1)
+			return newError(msg.StackOverflow, MaxDepth)
+		}
+		defer depth.Add(-1)
+		var at *token.Token // the last call of itself, once fn has looped
+		for {
+			result := callAtqarm(fn, args)
+			rv, ok := result.(*object.QaıtarValue)
+			if !ok {
+				if err, ok := result.(*object.Error); ok && at != nil && !hasPos(err) {
+					return &object.Error{Message: at.Pos() + ": " + err.Message}
+				}
+				return result
+			}
+			if rv.Call.Fn.Literal != fn.Literal {
+				return coerceResult(fn, args, applyTailCall(rv.Call))
+			}
+			// fn calls itself as it returns: run the call as the next
+			// iteration instead of a nested call.
+			fn, args, at = rv.Call.Fn, rv.Call.Args, &rv.Call.At
+		}
+	case *object.Builtin:
+		return fn.Fn(args...)
+	}
+	return newError(msg.NotCallable, fn.Type())
+}
+
+// callAtqarm runs the body of fn. It returns the result, or a QaıtarValue
+// holding the call fn returns the result of.
+func callAtqarm(fn *object.Atqarm, args []object.Object) object.Object {
+	if len(args) != len(fn.Literal.Parameters) {
+		return newError(msg.BuiltinArgCount, fn.Inspect(), len(args), fmt.Sprint(len(fn.Literal.Parameters)))
+	}
+	env := object.NewEnclosedEnvironment(fn.Env)
+	bindTypeParams(fn.Literal, args, env)
+	for i, param := range fn.Literal.Parameters {
+		arg := coerce(args[i], param.Type, env)
+		if isError(arg) {
+			return arg
+		}
+		env.Set(param.Name.Value, arg)
+	}
+	var evaluated object.Object
+	if fn.Literal.ReturnType == nil {
+		evaluated = evalTailBlock(fn.Literal.Body, env)
+	} else {
+		evaluated = evalBlockStatement(fn.Literal.Body, env)
+	}
+	if isError(evaluated) {
+		return evaluated
+	}
+	if rv, ok := evaluated.(*object.QaıtarValue); ok && rv.Call != nil {
+		return rv
+	}
+	return coerceResult(fn, args, unwrapReturnValue(evaluated))
+}
+
+// coerceResult converts the result of calling fn with args to its return
+// type.
+func coerceResult(fn *object.Atqarm, args []object.Object, result object.Object) object.Object {
+	if fn.Literal.ReturnType == nil || isError(result) {
+		return result
+	}
+	var env *object.Environment
+	if len(fn.Literal.TypeParams) > 0 {
+		env = object.NewEnvironment()
+		bindTypeParams(fn.Literal, args, env)
+	}
+	return coerce(result, fn.Literal.ReturnType, env)
+}
+
+// evalTailCall evaluates the arguments of `qaıtar f(args)` when f is a
+// user function, leaving the call to the function returning. It returns
+// nil for other calls.
+func evalTailCall(call *ast.CallExpression, env *object.Environment) object.Object {
+	id, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return nil
+	}
+	obj, _ := env.Get(id.Value)
+	fn, ok := obj.(*object.Atqarm)
+	if !ok || len(call.Arguments) != len(fn.Literal.Parameters) {
+		return nil
+	}
+	args := evalExpressions(call.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+	return &object.QaıtarValue{Call: &object.TailCall{Fn: fn, Args: args, At: call.Token}}
+}
+
+// evalTailBlock is evalBlockStatement for the body of a function declaring
+// no result: the call ending it, as ast.TailCalls finds it, is left to the
+// function returning like the call of a qaıtar, so that tail recursion
+// without results does not grow the stack either.
+func evalTailBlock(block *ast.BlockStatement, env *object.Environment) object.Object {
+	n := len(block.Statements)
+	if n == 0 {
+		return object.NULL
+	}
+	for _, statement := range block.Statements[:n-1] {
+		result := Eval(statement, env)
+		if result != nil {
+			rt := result.Type()
+			if rt == object.QAITAR_VAL || rt == object.ERROR_OBJ {
+				return result
+			}
+		}
+	}
+	if s, ok := block.Statements[n-1].(*ast.ExpressionStatement); ok {
+		switch x := s.Expression.(type) {
+		case *ast.CallExpression:
+			if tail := evalTailCall(x, env); tail != nil {
+				return tail
+			}
+		case *ast.EgerExpression:
+			return evalTailEger(x, env)
+		}
+	}
+	return Eval(block.Statements[n-1], env)
+}
+
+// evalTailEger is evalEgerExpression for an eger ending the body of a
+// function declaring no result.
+func evalTailEger(ee *ast.EgerExpression, env *object.Environment) object.Object {
+	cond := Eval(ee.Condition, env)
+	if isError(cond) {
+		return cond
+	}
+	ok, err := truthy(cond)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return evalTailBlock(ee.Consequence, object.NewEnclosedEnvironment(env))
+	} else if ee.Alternative != nil {
+		return evalTailBlock(ee.Alternative, object.NewEnclosedEnvironment(env))
+	}
+	return object.NULL
+}
+
+// applyTailCall makes a call left by evalTailCall, like
+// evalCallExpression.
+func applyTailCall(tc *object.TailCall) object.Object {
+	result := applyFunction(tc.Fn, tc.Args)
+	if err, ok := result.(*object.Error); ok && !hasPos(err) {
+		return &object.Error{Message: tc.At.Pos() + ": " + err.Message}
+	}
+	return result
+}
+
+// bindTypeParams makes the type parameters of a generic function usable as
+// conversions in its body. Values carry their type at run time, so T
+// converts to the type of the argument T was inferred from.
+func bindTypeParams(lit *ast.AtqarmLiteral, args []object.Object, env *object.Environment) {
+	for _, tp := range lit.TypeParams {
+		name := tp.Name.Value
+		kind := typeArg(name, lit.Parameters, args)
+		env.Set(name, &object.Builtin{Name: name, Fn: func(args ...object.Object) object.Object {
+			if err := arity(name, args, 1); err != nil {
+				return err
+			}
+			if kind == "" || !isNumber(args[0]) {
+				return args[0]
+			}
+			return convert(args[0], kind)
+		}})
+	}
+}
+
+// typeArg returns the run-time type of the first argument whose parameter
+// type is name or []name; it is empty when no argument decides it.
+func typeArg(name string, params []*ast.Parameter, args []object.Object) object.ObjectType {
+	for i, p := range params {
+		tn, arg := p.Type, args[i]
+		for tn != nil && tn.Token.Type == token.LBRACKET && arg != nil {
+			jyim, ok := arg.(*object.Jyim)
+			if !ok || len(jyim.Elements) == 0 {
+				arg = nil
+				break
+			}
+			tn, arg = tn.Elem, jyim.Elements[0]
+		}
+		if tn != nil && arg != nil && tn.Token.Type == token.IDENT && tn.Name == name {
+			return arg.Type()
+		}
+	}
+	return ""
+}
+
+func unwrapReturnValue(obj object.Object) object.Object {
+	if returnValue, ok := obj.(*object.QaıtarValue); ok {
+		return returnValue.Value
+	}
+	return obj
+}
+
+func evalIndexExpression(node *ast.IndexExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+	index := Eval(node.Index, env)
+	if isError(index) {
+		return index
+	}
+	arr, ok := left.(*object.Jyim)
+	if !ok {
+		return newErrorAt(node.Token, msg.IndexNotSupported, left.Type())
+	}
+	i, ok := toInt(index)
+	if !ok {
+		return newErrorAt(node.Token, msg.BadIndex, index.Inspect())
+	}
+	if i < 0 || i >= int64(len(arr.Elements)) {
+		return newErrorAt(node.Token, msg.OutOfRange, i, len(arr.Elements))
+	}
+	return arr.Elements[i]
+}
+
+// --- Values and conversions ---
+
+func nativeBoolToAqıqat(input bool) *object.Aqıqat {
+	if input {
+		return object.JAN
+	}
+	return object.JYN
+}
+
+// conversions maps conversion names to the object type they produce.
+var conversions = map[string]object.ObjectType{
+	"san":   object.SAN_OBJ,
+	"i64":   object.SAN_OBJ,
+	"i32":   object.I32_OBJ,
+	"u64":   object.U64_OBJ,
+	"f64":   object.F64_OBJ,
+	"aqsha": object.AQSHA_OBJ,
+}
+
+// coerce converts val to the declared type, if any. Only numeric
+// conversions are performed; other annotations are checked statically. A
+// type parameter of the function running in env converts like T(val).
+// The elements of an array are converted in place, which changes only
+// arrays made by a literal: others already have their type.
+func coerce(val object.Object, tn *ast.TypeNode, env *object.Environment) object.Object {
+	if arr, ok := val.(*object.Jyim); ok && tn != nil && tn.Token.Type == token.LBRACKET {
+		for i, el := range arr.Elements {
+			el = coerce(el, tn.Elem, env)
+			if isError(el) {
+				return el
+			}
+			arr.Elements[i] = el
+		}
+		return arr
+	}
+	if tn == nil || !isNumber(val) {
+		return val
+	}
+	if kind, ok := conversions[tn.Name]; ok {
+		return convert(val, kind)
+	}
+	if conv := typeParam(tn, env); conv != nil {
+		return conv.Fn(val)
+	}
+	return val
+}
+
+// typeParam returns the conversion bindTypeParams bound in env for the
+// type parameter tn names, or nil if tn is not a type parameter.
+func typeParam(tn *ast.TypeNode, env *object.Environment) *object.Builtin {
+	if env == nil || tn.Token.Type != token.IDENT {
+		return nil
+	}
+	obj, _ := env.Get(tn.Name)
+	if conv, ok := obj.(*object.Builtin); ok && conv.Name == tn.Name {
+		return conv
+	}
+	return nil
+}
+
+// coerceLike converts val to the numeric representation of old, so that
+// assignments keep a variable's type. An array takes the representation
+// of the elements of old, when it has any.
+func coerceLike(old, val object.Object) object.Object {
+	if o, ok := old.(*object.Jyim); ok {
+		if v, ok := val.(*object.Jyim); ok && len(o.Elements) > 0 && v != o {
+			for i, el := range v.Elements {
+				v.Elements[i] = coerceLike(o.Elements[0], el)
+			}
+		}
+		return val
+	}
+	if old == nil || !isNumber(old) || !isNumber(val) || old.Type() == val.Type() {
+		return val
+	}
+	return convert(val, old.Type())
+}
+
+func convert(val object.Object, kind object.ObjectType) object.Object {
+	if v, ok := val.(*object.I32); ok {
+		val = &object.San{Value: int64(v.Value)}
+	}
+	switch kind {
+	case object.SAN_OBJ, object.I32_OBJ:
+		var n int64
+		switch v := val.(type) {
+		case *object.San:
+			n = v.Value
+		case *object.U64:
+			n = int64(v.Value)
+		case *object.F64:
+			n = int64(v.Value)
+		case *object.Aqsha:
+			n = v.Value.IntPart()
+		default:
+			return newError(msg.ConvertRT, val.Type(), "san")
+		}
+		if kind == object.I32_OBJ {
+			return &object.I32{Value: int32(n)}
+		}
+		return &object.San{Value: n}
+	case object.U64_OBJ:
+		switch v := val.(type) {
+		case *object.San:
+			return &object.U64{Value: uint64(v.Value)}
+		case *object.U64:
+			return v
+		case *object.F64:
+			return &object.U64{Value: uint64(v.Value)}
+		case *object.Aqsha:
+			return &object.U64{Value: uint64(v.Value.IntPart())}
+		}
+	case object.F64_OBJ:
+		if f, ok := toFloat(val); ok {
+			return &object.F64{Value: f}
+		}
+	case object.AQSHA_OBJ:
+		switch v := val.(type) {
+		case *object.San:
+			return &object.Aqsha{Value: decimal.NewFromInt(v.Value)}
+		case *object.U64:
+			return &object.Aqsha{Value: decimal.NewFromUint64(v.Value)}
+		case *object.F64:
+			return &object.Aqsha{Value: decimal.NewFromFloat(v.Value)}
+		case *object.Aqsha:
+			return v
+		}
+	}
+	return newError(msg.ConvertRT, val.Type(), kind)
+}
+
+func zeroValue(tn *ast.TypeNode, env *object.Environment) object.Object {
+	if tn == nil {
+		return object.NULL
+	}
+	switch tn.Token.Type {
+	case token.LBRACKET, token.JYIM:
+		return &object.Jyim{}
+	case token.JOL:
+		return &object.Jol{}
+	case token.AQIQAT:
+		return object.JYN
+	}
+	if kind, ok := conversions[tn.Name]; ok {
+		return convert(&object.San{}, kind)
+	}
+	if conv := typeParam(tn, env); conv != nil {
+		return conv.Fn(&object.San{})
+	}
+	return object.NULL
+}
+
+func isNumber(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.San, *object.I32, *object.U64, *object.F64, *object.Aqsha:
+		return true
+	}
+	return false
+}
+
+func toInt(obj object.Object) (int64, bool) {
+	switch v := obj.(type) {
+	case *object.San:
+		return v.Value, true
+	case *object.I32:
+		return int64(v.Value), true
+	case *object.U64:
+		return int64(v.Value), true
+	}
+	return 0, false
+}
+
+func toFloat(obj object.Object) (float64, bool) {
+	switch v := obj.(type) {
+	case *object.San:
+		return float64(v.Value), true
+	case *object.I32:
+		return float64(v.Value), true
+	case *object.U64:
+		return float64(v.Value), true
+	case *object.F64:
+		return v.Value, true
+	case *object.Aqsha:
+		f, _ := v.Value.Float64()
+		return f, true
+	}
+	return math.NaN(), false
+}
+
+// --- Errors ---
+
+func newError(code msg.Code, a ...interface{}) *object.Error {
+	return &object.Error{Message: msg.Sprintf(code, a...)}
+}
+
+func newErrorAt(tok token.Token, code msg.Code, a ...interface{}) *object.Error {
+	return &object.Error{Message: tok.Pos() + ": " + msg.Sprintf(code, a...)}
+}
+
+// hasPos reports whether err starts with "line:col:" or "file:line:col:".
+func hasPos(err *object.Error) bool {
+	pos, _, ok := strings.Cut(err.Message, ": ")
+	if !ok {
+		return false
+	}
+	parts := strings.Split(pos, ":")
+	if len(parts) < 2 {
+		return false
+	}
+	_, lineErr := strconv.Atoi(parts[len(parts)-2])
+	_, colErr := strconv.Atoi(parts[len(parts)-1])
+	return lineErr == nil && colErr == nil
+}
+
+func isError(obj object.Object) bool {
+	if obj != nil {
+		return obj.Type() == object.ERROR_OBJ
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+// FILE: internal/lang/evaluator/evaluator_test.go
+
+package evaluator_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DauletBai/tenge/internal/lang/evaluator"
+	"github.com/DauletBai/tenge/internal/lang/lexer"
+	"github.com/DauletBai/tenge/internal/lang/object"
+	"github.com/DauletBai/tenge/internal/lang/parser"
+)
+
+// run evaluates src and returns what it printed and its result.
+func run(t *testing.T, src string) (string, object.Object) {
+	t.Helper()
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("%s: %s", src, errs[0])
+	}
+	var out strings.Builder
+	stdout := evaluator.Stdout
+	evaluator.Stdout = &out
+	defer func() { evaluator.Stdout = stdout }()
+	result := evaluator.Run(program, object.NewEnvironment())
+	return out.String(), result
+}
+
+func TestOutput(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"kórset(1 + 2 * 3)", "7"},
+		{"kórset(7 / 2, \" \", 7 % 2, \" \", -7 / 2)", "3 1 -3"},
+		{"kórset(1 < 2, 2 <= 1, 1 == 1 && 2 != 2)", "janj'nj'n"},
+		{"kórset(\"a\" + \"b\")", "ab"},
+		{"jasa a : aqsha = 0.1\nkórset(a + 0.2)", "0.3"},
+		{"jasa x : i32 = 7\nkórset(x / 2)", "3"},
+		{"jasa x = 1\nx = x + 1\nkórset(x)", "2"},
+		{"jasa x = eger 1 > 2 { 1 } áıtpece { 2 }\nkórset(x)", "2"},
+		{"jasa i = 0\názirshe i < 3 { kórset(i)\ni = i + 1 }", "012"},
+		{"jasa xs = [1, 2, 3]\nxs[1] = 5\nkórset(xs[1], len(xs))", "53"},
+		{"jasa f : atqar'm (n: san) -> san { eger n < 2 { qaıtar n }\nqaıtar f(n - 1) + f(n - 2) }\nkórset(f(15))", "610"},
+		{"jasa add : atqar'm (a: san) { qaıtar atqar'm (b: san) { qaıtar a + b } }\nkórset(add(2)(3))", "5"},
+		{"jasa main : atqar'm () { kórset(\"main\") }", "main"},
+	}
+	for _, tt := range tests {
+		out, result := run(t, tt.src)
+		if err, ok := result.(*object.Error); ok {
+			t.Errorf("%q: %s", tt.src, err.Message)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("%q printed %q, want %q", tt.src, out, tt.want)
+		}
+	}
+}
+
+// TestI32Wraps checks that every i32 operation wraps to 32 bits, as it
+// does in compiled code.
+func TestI32Wraps(t *testing.T) {
+	tests := []struct{ expr, want string }{
+		{"s + i32(1) > s", "j'n"},
+		{"(s + i32(1)) / i32(2)", "-1073741824"},
+		{"s * 2", "-2"},
+		{"-s - 2", "2147483647"},
+		{"s << 1", "-2"},
+		{"f(s)", "-2147483648"},
+		{"i32(s) + 1 == i32(-2147483648)", "jan"},
+	}
+	for _, tt := range tests {
+		src := "jasa s : i32 = 2147483647\njasa f : atqar'm (x: i32) -> i32 { qaıtar x + 1 }\nkórset(" + tt.expr + ")"
+		out, result := run(t, src)
+		if err, ok := result.(*object.Error); ok {
+			t.Errorf("%s: %s", tt.expr, err.Message)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("%s printed %s, want %s", tt.expr, out, tt.want)
+		}
+	}
+}
+
+// TestArrayLiterals checks that the elements of an array literal take the
+// element type it is declared, passed or returned with.
+func TestArrayLiterals(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"jasa xs : []i32 = [1, 2147483647]\nkórset(xs[0] + xs[1])", "-2147483648"},
+		{"jasa xs : []u64 = [0]\nkórset(xs[0] - 1)", "18446744073709551615"},
+		{"jasa xs : []f64 = [1, 3]\nkórset(xs[1] / 2)", "1.5"},
+		{"jasa m : [][]f64 = [[1], [3]]\nkórset(m[1][0] / 2)", "1.5"},
+		{"jasa xs : []u64 = [1]\nxs = [0]\nkórset(xs[0] - 1)", "18446744073709551615"},
+		{"jasa xs : []f64 = [1]\nxs[0] = 3\nkórset(xs[0] / 2)", "1.5"},
+		{"atqar'm f(a: []i32) -> i32 { qaıtar a[0] + a[1] }\nkórset(f([2147483647, 1]))", "-2147483648"},
+		{"atqar'm g() -> []f64 { qaıtar [1, 2] }\nkórset(g()[0] / 4)", "0.25"},
+	}
+	for _, tt := range tests {
+		out, result := run(t, tt.src)
+		if err, ok := result.(*object.Error); ok {
+			t.Errorf("%q: %s", tt.src, err.Message)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("%q printed %s, want %s", tt.src, out, tt.want)
+		}
+	}
+}
+
+// TestRuntimeErrors checks that runtime errors stop the program.
+func TestRuntimeErrors(t *testing.T) {
+	for _, src := range []string{
+		"kórset(1 / 0)",
+		"jasa x : i32 = 1\nkórset(x % 0)",
+		"jasa xs = [1]\nkórset(xs[1])",
+		"jasa xs = [1]\nxs[-1] = 0",
+		"kórset(qate)",
+		"jasa f = 1\nf(2)",
+	} {
+		out, result := run(t, src+"\nkórset(\"after\")")
+		if _, ok := result.(*object.Error); !ok {
+			t.Errorf("%q: no error, result %v", src, result)
+		}
+		if strings.Contains(out, "after") {
+			t.Errorf("%q: kept running after the error", src)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// FILE: internal/lang/evaluator/parallel.go
+
+package evaluator
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/lexer"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/object"
+	"github.com/DauletBai/tenge/internal/lang/token"
+)
+
+// maxBlocks bounds the number of blocks a qatarlas range is split into.
+// The blocks depend only on the range, never on the number of threads, so
+// a reduction adds up its values in the same order on every run; the C
+// backend splits ranges the same way.
+const maxBlocks = 1024
+
+// Threads returns the number of goroutines parallel loops run on:
+// $TENGE_THREADS when it is a positive number, GOMAXPROCS otherwise.
+func Threads() int {
+	if n, err := strconv.Atoi(os.Getenv("TENGE_THREADS")); err == nil && n > 0 {
+		return n
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// block returns the bounds of block b of the n values from lo, split into
+// nb blocks whose lengths differ by at most one.
+func block(lo, n, nb, b int64) (int64, int64) {
+	q, r := n/nb, n%nb
+	start := lo + b*q + min(b, r)
+	end := start + q
+	if b < r {
+		end++
+	}
+	return start, end
+}
+
+func evalQatarlas(node *ast.QatarlasExpression, env *object.Environment) object.Object {
+	var bounds [2]int64
+	for i, e := range []ast.Expression{node.From, node.To} {
+		v := Eval(e, env)
+		if isError(v) {
+			return v
+		}
+		n, ok := toInt(v)
+		if !ok {
+			return newErrorAt(node.Token, msg.ConvertRT, v.Type(), "san")
+		}
+		bounds[i] = n
+	}
+	lo, n := bounds[0], max(bounds[1]-bounds[0], 0)
+	nb := min(n, maxBlocks)
+	reduction := node.Reduction()
+
+	var elements []object.Object
+	if reduction == ast.Collect {
+		elements = make([]object.Object, n)
+	}
+	partials := make([]object.Object, nb)
+	errs := make([]object.Object, nb)
+	var next atomic.Int64
+	var failed atomic.Int64 // the lowest block that failed; later ones need not run
+	failed.Store(nb)
+
+	run := func(b int64) {
+		start, end := block(lo, n, nb, b)
+		var acc object.Object
+		for i := start; i < end; i++ {
+			ienv := object.NewEnclosedEnvironment(env)
+			ienv.Set(node.Var.Value, &object.San{Value: i})
+			v := Eval(node.Body, ienv)
+			if !isError(v) {
+				v, acc = reduce(node, reduction, acc, v)
+			}
+			if isError(v) {
+				errs[b] = v
+				for f := failed.Load(); b < f && !failed.CompareAndSwap(f, b); f = failed.Load() {
+				}
+				return
+			}
+			if elements != nil {
+				elements[i-lo] = v
+			}
+		}
+		partials[b] = acc
+	}
+	var workers join
+	for w := min(int64(Threads()), nb); w > 0; w-- {
+		workers.add()
+		go func() {
+			defer workers.done()
+			for b := next.Add(1) - 1; b < nb && b < failed.Load(); b = next.Add(1) - 1 {
+				run(b)
+			}
+		}()
+	}
+	err := workers.wait(node.Token.Literal, node.Token)
+	workers.end()
+	if err != nil {
+		return err
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	switch reduction {
+	case "":
+		return object.NULL
+	case ast.Collect:
+		return &object.Jyim{Elements: elements}
+	}
+	if n == 0 {
+		if reduction == ast.Sum {
+			return &object.San{}
+		}
+		return newErrorAt(node.Token, msg.EmptyReduction, node.Reduce.Value)
+	}
+	var acc object.Object
+	for _, p := range partials {
+		if _, acc = reduce(node, reduction, acc, p); isError(acc) {
+			return acc
+		}
+	}
+	return acc
+}
+
+// reduce adds v to the reduction acc of the values before it. It returns
+// v, or an error, and the new reduction; the first value starts it.
+func reduce(node *ast.QatarlasExpression, reduction string, acc, v object.Object) (object.Object, object.Object) {
+	if acc == nil || reduction == "" || reduction == ast.Collect {
+		return v, v
+	}
+	var op string
+	switch reduction {
+	case ast.Sum:
+		sum := evalInfix("+", acc, v)
+		if err, ok := sum.(*object.Error); ok {
+			return withPos(node, err), nil
+		}
+		return v, sum
+	case ast.Min:
+		op = "<"
+	case ast.Max:
+		op = ">"
+	}
+	better := evalInfix(op, v, acc)
+	if err, ok := better.(*object.Error); ok {
+		return withPos(node, err), nil
+	}
+	if better == object.JAN {
+		return v, v
+	}
+	return v, acc
+}
+
+func withPos(node *ast.QatarlasExpression, err *object.Error) *object.Error {
+	if hasPos(err) {
+		return err
+	}
+	return &object.Error{Message: node.Token.Pos() + ": " + err.Message}
+}
+
+// kútName binds the group of tasks of the innermost kút block. It is not a
+// valid identifier, so programs cannot see it.
+const kútName = "<kút>"
+
+// group holds the tasks started in a kút block, in the order they were
+// started.
+type group struct {
+	tasks   []*task
+	running join
+}
+
+func (g *group) Type() object.ObjectType { return "GROUP" }
+func (g *group) Inspect() string         { return kútName }
+
+// task is a call running on its own goroutine.
+type task struct {
+	node   *ast.MindetStatement
+	env    *object.Environment // where the result is assigned
+	result object.Object
+}
+
+// evalKut runs the block of a kút statement, waits for the tasks started
+// in it and then assigns their results in the order they were started.
+// The first error, in that order, ends the statement.
+func evalKut(node *ast.KutStatement, env *object.Environment) object.Object {
+	g := &group{}
+	kenv := object.NewEnclosedEnvironment(env)
+	kenv.Set(kútName, g)
+	result := Eval(node.Body, kenv)
+	err := g.running.wait(node.Token.Literal, node.Token)
+	g.running.end()
+	if isError(result) {
+		return result
+	}
+	if err != nil {
+		return err
+	}
+	for _, t := range g.tasks {
+		if isError(t.result) {
+			return t.result
+		}
+		if t.node.Target != nil {
+			name := t.node.Target.Value
+			old, _ := t.env.Get(name)
+			t.env.Assign(name, coerceLike(old, t.result))
+		}
+	}
+	return object.NULL
+}
+
+// evalMindet evaluates the function and arguments of a task and starts
+// the call on a new goroutine.
+func evalMindet(node *ast.MindetStatement, env *object.Environment) object.Object {
+	obj, _ := env.Get(kútName)
+	g, ok := obj.(*group)
+	if !ok {
+		return newErrorAt(node.Token, msg.TaskOutsideKut, node.Token.Literal, lexer.Respell(token.KUT, node.Token.Literal))
+	}
+	function := Eval(node.Call.Function, env)
+	if isError(function) {
+		return function
+	}
+	args := evalExpressions(node.Call.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+	t := &task{node: node, env: env}
+	g.tasks = append(g.tasks, t)
+	g.running.add()
+	go func() {
+		defer g.running.done()
+		t.result = applyFunction(function, args)
+		if err, ok := t.result.(*object.Error); ok && !hasPos(err) {
+			t.result = &object.Error{Message: node.Call.Token.Pos() + ": " + err.Message}
+		}
+	}()
+	return object.NULL
+}
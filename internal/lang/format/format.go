@@ -0,0 +1,160 @@
+// FILE: internal/lang/format/format.go
+
+// Package format lays out tenge source in the one canonical style. The
+// source is parsed and printed back from the syntax tree by ast.Printer:
+// a statement per line without semicolons, four spaces of indentation
+// per block, single spaces around binary operators and only the
+// parentheses that are needed. Comments and single blank lines between
+// statements are kept, and so are blocks written on one line. Keywords
+// keep their spelling; lexer.Convert changes the keyword set.
+//
+// Formatting formatted source returns it unchanged.
+package format
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/lexer"
+	"github.com/DauletBai/tenge/internal/lang/parser"
+)
+
+// Source returns src formatted. It fails with the first parse error when
+// src does not parse.
+func Source(src string) (string, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if errs := p.Diagnostics(); len(errs) > 0 {
+		return "", errs[0]
+	}
+	var out strings.Builder
+	printer := ast.Printer{Mode: ast.Multiline, Source: src}
+	if err := printer.Print(&out, program); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// context is the number of unchanged lines Diff shows around a change.
+const context = 3
+
+// Diff returns the changes from a to b as a unified diff of the file name,
+// or "" if they are equal.
+func Diff(name, a, b string) string {
+	if a == b {
+		return ""
+	}
+	x, y := lines(a), lines(b)
+	edits := diffLines(x, y)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s (formatted)\n", name, name)
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			i++
+			continue
+		}
+		// A hunk runs from context lines before a change to context lines
+		// after the last change closer than 2*context lines to the next.
+		start := max(i-context, 0)
+		end := i
+		for j := i; j < len(edits); j++ {
+			if edits[j].op != ' ' {
+				end = j + 1
+			} else if j-end >= 2*context {
+				break
+			}
+		}
+		end = min(end+context, len(edits))
+
+		ax, ay := edits[start].x, edits[start].y
+		var nx, ny int
+		for _, e := range edits[start:end] {
+			if e.op != '+' {
+				nx++
+			}
+			if e.op != '-' {
+				ny++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(ax, nx), hunkRange(ay, ny))
+		for _, e := range edits[start:end] {
+			text := y[e.y]
+			if e.op == '-' || e.op == ' ' {
+				text = x[e.x]
+			}
+			out.WriteString(string(e.op) + text + "\n")
+		}
+		i = end
+	}
+	return out.String()
+}
+
+// hunkRange returns the "start,count" of a hunk header. The start is
+// 1-based, or the line before the hunk when it is empty.
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
+
+// lines splits s into lines. A last line without a newline carries the
+// marker diff prints after it, so that it differs from the same line with
+// one.
+func lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	list := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	if !strings.HasSuffix(s, "\n") {
+		list[len(list)-1] += "\n\\ No newline at end of file"
+	}
+	return list
+}
+
+// edit is one line of a diff: ' ' keeps x[x] (which is y[y]), '-'
+// deletes x[x] and '+' inserts y[y]. x and y are the positions in the
+// two texts before the line.
+type edit struct {
+	op   byte
+	x, y int
+}
+
+// diffLines returns the edits turning x into y, from the longest common
+// subsequence of their lines.
+func diffLines(x, y []string) []edit {
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			edits = append(edits, edit{' ', i, j})
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', i, j})
+			i++
+		default:
+			edits = append(edits, edit{'+', i, j})
+			j++
+		}
+	}
+	return edits
+}
@@ -0,0 +1,142 @@
+// FILE: internal/lang/format/format_test.go
+
+package format_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DauletBai/tenge/internal/lang/format"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct{ name, src, want string }{
+		{"layout", "let   x=1;let y =2\nif x>y{print(x)}else{print(y)}\n",
+			"let x = 1\nlet y = 2\nif x > y { print(x) } else { print(y) }\n"},
+		{"blocks", "fn f(n: int) -> int {\nif n < 2 { return n }\n\n\n  return f(n-1)+f(n-2) }",
+			"fn f(n: int) -> int {\n    if n < 2 { return n }\n\n    return f(n - 1) + f(n - 2)\n}\n"},
+		{"parentheses", "print((1+2)*3, (1*2)+3, -(-x), a-(b-c))",
+			"print((1 + 2) * 3, 1 * 2 + 3, -(-x), a - (b - c))\n"},
+		{"spelling", "#syntax kazakh\njasa x = 1\nkórset(x)\n",
+			"#syntax kazakh\njasa x = 1\nkórset(x)\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := format.Source(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Source(%q) =\n%s\nwant\n%s", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestComments checks that every comment stays next to the code it was
+// written next to.
+func TestComments(t *testing.T) {
+	tests := []struct{ name, src, want string }{
+		{"own lines", "// header\n\n\n\nlet x = 1\n// about y\nlet y = 2\n",
+			"// header\n\nlet x = 1\n// about y\nlet y = 2\n"},
+		{"end of line", "let x = 1   // one\nfn f() { // body\n    return // done\n}\n",
+			"let x = 1 // one\nfn f() { // body\n    return // done\n}\n"},
+		{"end of block", "fn f() {\n    g()\n    // last\n}\n",
+			"fn f() {\n    g()\n    // last\n}\n"},
+		{"after a parameter", "fn add(a: int, // left\n       b: int) -> int { // sum\n    return a + b\n}\n",
+			"fn add(a: int, // left\n    b: int) -> int { // sum\n    return a + b\n}\n"},
+		{"before the parameters", "fn f(   // why\n a: int) {}\n",
+			"fn f( // why\n    a: int) {}\n"},
+		{"after an argument", "let x = add(1, // one\n  2)\n",
+			"let x = add(1, // one\n    2)\n"},
+		{"between arguments", "add(1,\n// two\n2)\n",
+			"add(1,\n    // two\n    2)\n"},
+		{"after an element", "let y = [1, // first\n  2, 3]\n",
+			"let y = [1, // first\n    2, 3]\n"},
+		{"end of file", "let x = 1\n\n// bye\n",
+			"let x = 1\n\n// bye\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := format.Source(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Source(%q) =\n%s\nwant\n%s", tt.src, got, tt.want)
+			}
+			if again, err := format.Source(got); err != nil || again != got {
+				t.Errorf("formatting again gives\n%s\n(%v)", again, err)
+			}
+		})
+	}
+}
+
+// TestIdempotent formats the tenge files of the repository twice: the
+// second time must change nothing.
+func TestIdempotent(t *testing.T) {
+	var names []string
+	for _, pattern := range []string{
+		"../../../benchmarks/src/tenge/*.tng",
+		"../module/std/*/*.tng",
+	} {
+		list, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, list...)
+	}
+	if len(names) == 0 {
+		t.Fatal("no tenge sources found")
+	}
+	for _, name := range names {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		once, err := format.Source(string(src))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		twice, err := format.Source(once)
+		if err != nil {
+			t.Errorf("%s: formatted source does not parse: %v", name, err)
+			continue
+		}
+		if d := format.Diff(name, once, twice); d != "" {
+			t.Errorf("formatting %s again changes it:\n%s", name, d)
+		}
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	_, err := format.Source("jasa = 1")
+	if err == nil || err.Error() != `1:6: expected next token to be IDENT, got "=" instead` {
+		t.Errorf("Source of a bad program: %v", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct{ name, a, b, want string }{
+		{"equal", "a\n", "a\n", ""},
+		{"one line", "a\nb\nc\n", "a\nB\nc\n",
+			"--- f.tng\n+++ f.tng (formatted)\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"},
+		{"inserted", "a\n", "a\nb\n",
+			"--- f.tng\n+++ f.tng (formatted)\n@@ -1,1 +1,2 @@\n a\n+b\n"},
+		{"from nothing", "", "a\n",
+			"--- f.tng\n+++ f.tng (formatted)\n@@ -0,0 +1,1 @@\n+a\n"},
+		{"final newline", "a", "a\n",
+			"--- f.tng\n+++ f.tng (formatted)\n@@ -1,1 +1,1 @@\n-a\n\\ No newline at end of file\n+a\n"},
+		{"two hunks", "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n", "0\n2\n3\n4\n5\n6\n7\n8\n9\nX\n",
+			"--- f.tng\n+++ f.tng (formatted)\n@@ -1,4 +1,4 @@\n-1\n+0\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+X\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := format.Diff("f.tng", tt.a, tt.b); got != tt.want {
+				t.Errorf("Diff =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,54 +3,68 @@
 package lexer
 
 import (
+	"strings"
 	"unicode"
 	"unicode/utf8"
 
+	"golang.org/x/text/unicode/norm"
+
 	"github.com/DauletBai/tenge/internal/lang/token"
 )
 
-var keywords = map[string]token.TokenType{
-	"jasa":    token.JASA,
-	"bekit":   token.BEKIT,
-	"atqar'm": token.ATQARM,
-	"qaıtar":  token.QAITAR,
-	"eger":    token.EGER,
-	"áıtpece": token.AITPECE,
-	"ázirshe": token.AZIRSHE,
-	"jan":     token.JAN,
-	"j'n":     token.JYN,
-	"kórset":  token.KORSET,
-	"san":     token.SAN,
-	"aqsha":   token.AQSHA, 
-	"jol":     token.JOL,
-	"tańba":   token.TANBA,
-	"aqıqat":  token.AQIQAT,
-	"j'i'm":   token.JYIM,
-}
-
+// LookupIdent returns the keyword token type of ident in Mixed syntax, or
+// IDENT.
 func LookupIdent(ident string) token.TokenType {
-	if tok, ok := keywords[ident]; ok {
-		return tok
-	}
-	return token.IDENT
+	return lookup(ident, Mixed)
 }
 
 type Lexer struct {
+	file         string
+	syntax       Syntax
 	input        string
 	position     int
 	readPosition int
 	ch           rune
+
+	line   int // line of ch
+	column int // column of ch
+
+	comments []token.Token
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
+// Syntax returns the keyword set in effect, as selected by the last
+// #syntax pragma.
+func (l *Lexer) Syntax() Syntax { return l.syntax }
+
+// Comments returns the comments and #syntax lines read so far, as COMMENT
+// tokens without the line break.
+func (l *Lexer) Comments() []token.Token { return l.comments }
+
+// Offset returns the byte offset just past the last token read.
+func (l *Lexer) Offset() int { return l.position }
+
+// NewFile returns a lexer whose tokens carry file in their positions.
+func NewFile(file, input string) *Lexer {
+	l := New(input)
+	l.file = file
+	return l
+}
+
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
+		l.position = len(l.input)
 	} else {
 		r, size := utf8.DecodeRuneInString(l.input[l.readPosition:])
 		l.ch = r
@@ -59,17 +73,28 @@ func (l *Lexer) readChar() {
 	}
 }
 
-// MODIFIED: readNumber now handles floating-point numbers.
+// readNumber handles integers, hexadecimal integers and decimal numbers
+// with an optional exponent (1.5, 1e-300, 2.5e10).
 func (l *Lexer) readNumber() (tokType token.TokenType, lit string) {
 	startPosition := l.position
 	tokType = token.SAN_LIT // Assume it's an integer by default
 
+	if l.ch == '0' && (l.peekChar() == 'x' || l.peekChar() == 'X') {
+		l.readChar()
+		l.readChar()
+		for isHexDigit(l.ch) {
+			l.readChar()
+		}
+		return tokType, l.input[startPosition:l.position]
+	}
+
 	for unicode.IsDigit(l.ch) {
 		l.readChar()
 	}
 
-	// If we find a dot, it's a decimal number.
-	if l.ch == '.' {
+	// A dot followed by a digit makes it a decimal number; a lone dot is
+	// left for the parser.
+	if l.ch == '.' && isDigit(l.peekChar()) {
 		tokType = token.AQSHA_LIT
 		l.readChar() // Consume the dot
 		for unicode.IsDigit(l.ch) {
@@ -77,51 +102,93 @@ func (l *Lexer) readNumber() (tokType token.TokenType, lit string) {
 		}
 	}
 
+	if l.ch == 'e' || l.ch == 'E' {
+		next := l.peekChar()
+		if isDigit(next) || ((next == '-' || next == '+') && isDigit(l.peekCharAt(2))) {
+			tokType = token.AQSHA_LIT
+			l.readChar() // Consume the 'e'
+			if l.ch == '-' || l.ch == '+' {
+				l.readChar()
+			}
+			for unicode.IsDigit(l.ch) {
+				l.readChar()
+			}
+		}
+	}
+
 	return tokType, l.input[startPosition:l.position]
 }
 
-
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 	l.skipWhitespace()
+	line, column, offset := l.line, l.column, l.position
 
 	switch l.ch {
+	case '#':
+		if l.readPragma() {
+			l.comment(line, column, offset)
+			return l.NextToken()
+		}
+		tok = token.Token{Type: token.ILLEGAL, Literal: l.input[offset:l.position]}
+		tok.File, tok.Line, tok.Column, tok.Offset = l.file, line, column, offset
+		return tok
 	case '=':
-		if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			literal := string(ch) + string(l.ch)
-			tok = token.Token{Type: token.TANBA, Literal: literal}
-		} else {
-			tok = newToken(token.ASSIGN, l.ch)
+		tok = l.twoCharToken('=', token.EQUAL, token.ASSIGN)
+	case '!':
+		tok = l.twoCharToken('=', token.NOT_EQUAL, token.BANG)
+	case '<':
+		switch l.peekChar() {
+		case '=':
+			tok = l.twoCharToken('=', token.LESS_EQ, token.LESS)
+		case '<':
+			tok = l.twoCharToken('<', token.SHL, token.LESS)
+		default:
+			tok = newToken(token.LESS, l.ch)
 		}
+	case '>':
+		switch l.peekChar() {
+		case '=':
+			tok = l.twoCharToken('=', token.GREATER_EQ, token.GREATER)
+		case '>':
+			tok = l.twoCharToken('>', token.SHR, token.GREATER)
+		default:
+			tok = newToken(token.GREATER, l.ch)
+		}
+	case '&':
+		tok = l.twoCharToken('&', token.AND, token.AMPERSAND)
+	case '|':
+		tok = l.twoCharToken('|', token.OR, token.PIPE)
+	case '^':
+		tok = newToken(token.CARET, l.ch)
 	case ':':
 		tok = newToken(token.COLON, l.ch)
+	case ';':
+		tok = newToken(token.SEMICOLON, l.ch)
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
 		tok = newToken(token.RPAREN, l.ch)
+	case '{':
+		tok = newToken(token.LBRACE, l.ch)
+	case '}':
+		tok = newToken(token.RBRACE, l.ch)
+	case '.':
+		tok = l.twoCharToken('.', token.RANGE, token.DOT)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case '+':
 		tok = newToken(token.PLUS, l.ch)
 	case '-':
-		if l.peekChar() == '>' {
-			ch := l.ch
-			l.readChar()
-			literal := string(ch) + string(l.ch)
-			tok = token.Token{Type: token.JYIM, Literal: literal}
-		} else {
-			tok = newToken(token.MINUS, l.ch)
-		}
+		tok = l.twoCharToken('>', token.ARROW, token.MINUS)
 	case '*':
 		tok = newToken(token.MULTIPLY, l.ch)
 	case '/':
 		tok = newToken(token.DIVIDE, l.ch)
-	case '>':
-		tok = newToken(token.GREATER, l.ch)
+	case '%':
+		tok = newToken(token.MODULO, l.ch)
 	case '"':
-		tok.Type = token.JOL
+		tok.Type = token.JOL_LIT
 		tok.Literal = l.readString()
 	case '[':
 		tok = newToken(token.LBRACKET, l.ch)
@@ -133,11 +200,12 @@ func (l *Lexer) NextToken() token.Token {
 	default:
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
-			tok.Type = LookupIdent(tok.Literal)
+			tok.Type = lookup(tok.Literal, l.syntax)
+			tok.File, tok.Line, tok.Column, tok.Offset = l.file, line, column, offset
 			return tok
 		} else if unicode.IsDigit(l.ch) {
-			// MODIFIED: Call the new readNumber function
 			tok.Type, tok.Literal = l.readNumber()
+			tok.File, tok.Line, tok.Column, tok.Offset = l.file, line, column, offset
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
@@ -145,39 +213,123 @@ func (l *Lexer) NextToken() token.Token {
 	}
 
 	l.readChar()
+	tok.File, tok.Line, tok.Column, tok.Offset = l.file, line, column, offset
 	return tok
 }
 
+// readPragma reads a `#syntax kazakh|latin` line and switches the keyword
+// set. It reports false, leaving the line read, for anything else.
+func (l *Lexer) readPragma() bool {
+	start := l.position
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+	fields := strings.Fields(l.input[start:l.position])
+	if len(fields) != 2 || fields[0] != "#syntax" {
+		return false
+	}
+	s, ok := ParseSyntax(fields[1])
+	if !ok {
+		return false
+	}
+	l.syntax = s
+	return true
+}
+
+// twoCharToken returns a two-character token when the next rune is second,
+// otherwise the one-character fallback.
+func (l *Lexer) twoCharToken(second rune, two, one token.TokenType) token.Token {
+	if l.peekChar() == second {
+		ch := l.ch
+		l.readChar()
+		return token.Token{Type: two, Literal: string(ch) + string(l.ch)}
+	}
+	return newToken(one, l.ch)
+}
+
+// readIdentifier reads an identifier and returns it in NFC, so that a
+// precomposed á and a followed by a combining acute are the same name.
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || isDigit(l.ch) || unicode.Is(unicode.Mn, l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.position]
+	return norm.NFC.String(l.input[position:l.position])
 }
 
+// readString reads a double-quoted string and resolves the escapes
+// \n, \t, \", \\.
 func (l *Lexer) readString() string {
-	position := l.position + 1
+	var out []rune
 	for {
 		l.readChar()
 		if l.ch == '"' || l.ch == 0 {
 			break
 		}
+		if l.ch == '\\' {
+			l.readChar()
+			switch l.ch {
+			case 'n':
+				out = append(out, '\n')
+			case 't':
+				out = append(out, '\t')
+			case 0:
+				return string(out)
+			default:
+				out = append(out, l.ch)
+			}
+			continue
+		}
+		out = append(out, l.ch)
 	}
-	return l.input[position:l.position]
+	return string(out)
 }
 
+// skipWhitespace skips blanks and // line comments.
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
-		l.readChar()
+	for {
+		for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+			l.readChar()
+		}
+		if l.ch == '/' && l.peekChar() == '/' {
+			line, column, offset := l.line, l.column, l.position
+			for l.ch != '\n' && l.ch != 0 {
+				l.readChar()
+			}
+			l.comment(line, column, offset)
+			continue
+		}
+		return
 	}
 }
 
+// comment records the text from offset to the current position.
+func (l *Lexer) comment(line, column, offset int) {
+	l.comments = append(l.comments, token.Token{
+		Type:    token.COMMENT,
+		Literal: strings.TrimRight(l.input[offset:l.position], " \t\r"),
+		File:    l.file, Line: line, Column: column, Offset: offset,
+	})
+}
+
 func (l *Lexer) peekChar() rune {
-	if l.readPosition >= len(l.input) {
+	return l.peekCharAt(1)
+}
+
+// peekCharAt returns the rune n positions after the current one.
+func (l *Lexer) peekCharAt(n int) rune {
+	pos := l.readPosition
+	for i := 1; i < n; i++ {
+		if pos >= len(l.input) {
+			return 0
+		}
+		_, size := utf8.DecodeRuneInString(l.input[pos:])
+		pos += size
+	}
+	if pos >= len(l.input) {
 		return 0
 	}
-	r, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	r, _ := utf8.DecodeRuneInString(l.input[pos:])
 	return r
 }
 
@@ -185,6 +337,14 @@ func isLetter(ch rune) bool {
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || ch == '\'' || unicode.IsLetter(ch)
 }
 
+func isDigit(ch rune) bool {
+	return '0' <= ch && ch <= '9'
+}
+
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
 func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
-}
\ No newline at end of file
+}
@@ -0,0 +1,247 @@
+// FILE: internal/lang/lexer/lexer_test.go
+
+package lexer_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/DauletBai/tenge/internal/lang/lexer"
+	"github.com/DauletBai/tenge/internal/lang/token"
+)
+
+// tokens returns the tokens of src up to EOF as "TYPE literal line:col".
+func tokens(src string) []string {
+	var list []string
+	l := lexer.New(src)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		list = append(list, string(tok.Type)+" "+tok.Literal+" "+tok.Pos())
+	}
+	return list
+}
+
+func TestNextToken(t *testing.T) {
+	src := "jasa x'y = 0x1F + 1.5e-3 * 2e10..3 // c\n\"a\\tb\\\"\" <= >> -> && != # ?"
+	want := []string{
+		"jasa jasa 1:1",
+		"IDENT x'y 1:6",
+		"= = 1:10",
+		"SAN_LIT 0x1F 1:12",
+		"+ + 1:17",
+		"AQSHA_LIT 1.5e-3 1:19",
+		"* * 1:26",
+		"AQSHA_LIT 2e10 1:28",
+		".. .. 1:32",
+		"SAN_LIT 3 1:34",
+		"JOL_LIT a\tb\" 2:1",
+		"<= <= 2:10",
+		">> >> 2:13",
+		"-> -> 2:16",
+		"&& && 2:19",
+		"!= != 2:22",
+		"ILLEGAL # ? 2:25",
+	}
+	if got := tokens(src); !reflect.DeepEqual(got, want) {
+		t.Errorf("tokens\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// TestNumbers checks where numbers end: a dot needs a digit after it to
+// make a decimal, and an e needs one to make an exponent.
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"1.5", []string{"AQSHA_LIT 1.5 1:1"}},
+		{"1..5", []string{"SAN_LIT 1 1:1", ".. .. 1:2", "SAN_LIT 5 1:4"}},
+		{"xs.1", []string{"IDENT xs 1:1", ". . 1:3", "SAN_LIT 1 1:4"}},
+		{"2e", []string{"SAN_LIT 2 1:1", "IDENT e 1:2"}},
+		{"2e+", []string{"SAN_LIT 2 1:1", "IDENT e 1:2", "+ + 1:3"}},
+		{"3E+2", []string{"AQSHA_LIT 3E+2 1:1"}},
+		{"0XfF", []string{"SAN_LIT 0XfF 1:1"}},
+	}
+	for _, tt := range tests {
+		if got := tokens(tt.src); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokens(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+// TestKeywords checks which words are keywords in each syntax.
+func TestKeywords(t *testing.T) {
+	tests := []struct {
+		src  string
+		want token.TokenType
+	}{
+		// without a pragma both sets are keywords
+		{"jasa", token.JASA},
+		{"var", token.JASA},
+		{"let", token.JASA},
+		{"atqar'm", token.ATQARM},
+		{"fn", token.ATQARM},
+		// except the later ones, which need the pragma
+		{"for", token.IDENT},
+		{"chan", token.IDENT},
+		{"mindet", token.IDENT},
+		{"bolsyn", token.IDENT},
+		{"#syntax latin\nfor", token.AR},
+		{"#syntax kazakh\nmindet", token.MINDET},
+		{"#syntax kazakh\nbolsyn", token.JASA},
+		// a pragma makes the other set ordinary names
+		{"#syntax latin\njasa", token.IDENT},
+		{"#syntax kazakh\nvar", token.IDENT},
+		{"#syntax kazakh\nжаса", token.JASA},
+		{"#syntax latin\nжаса", token.IDENT},
+		// aliases and normalization
+		{"атқарым", token.ATQARM},
+		{"atqarym", token.ATQARM},
+		{"korset", token.KORSET},
+		{"a\u0301zirshe", token.AZIRSHE},
+		{"qaıtar", token.QAITAR},
+		{"Jasa", token.IDENT},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.src)
+		if tok := l.NextToken(); tok.Type != tt.want {
+			t.Errorf("%q: %s, want %s", tt.src, tok.Type, tt.want)
+		}
+	}
+}
+
+// TestPragma checks that #syntax lines switch the keyword set, are kept
+// with the comments, and that other # lines are illegal.
+func TestPragma(t *testing.T) {
+	l := lexer.New("// top\n#syntax latin\nlet x = 1\n#syntax kazakh\njasa y = 2 // end\n#pragma once\n")
+	var kinds []token.TokenType
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Type == token.JASA || tok.Type == token.ILLEGAL {
+			kinds = append(kinds, tok.Type)
+		}
+	}
+	if want := []token.TokenType{token.JASA, token.JASA, token.ILLEGAL}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("tokens %v, want %v", kinds, want)
+	}
+	if l.Syntax() != lexer.Kazakh {
+		t.Errorf("syntax %s after the last pragma, want kazakh", l.Syntax())
+	}
+	var comments []string
+	for _, c := range l.Comments() {
+		comments = append(comments, c.Literal+" "+c.Pos())
+	}
+	want := []string{"// top 1:1", "#syntax latin 2:1", "#syntax kazakh 4:1", "// end 5:12"}
+	if !reflect.DeepEqual(comments, want) {
+		t.Errorf("comments %q, want %q", comments, want)
+	}
+}
+
+func TestIdentifierNFC(t *testing.T) {
+	composed := tokens("\u00e1zir = 1")[0]
+	decomposed := tokens("a\u0301zir = 1")[0]
+	if composed != "IDENT ázir 1:1" || decomposed != composed {
+		t.Errorf("identifiers %q and %q, want both IDENT ázir 1:1", composed, decomposed)
+	}
+}
+
+func TestScripts(t *testing.T) {
+	tests := []struct {
+		ident string
+		want  []string
+	}{
+		{"qaıtar", []string{"Latin"}},
+		{"сан", []string{"Cyrillic"}},
+		{"x1'", []string{"Latin"}},
+		{"_1", nil},
+		{"c\u0430n", []string{"Latin", "Cyrillic"}}, // a Cyrillic а
+		{"λx", []string{"Greek", "Latin"}},
+	}
+	for _, tt := range tests {
+		if got := lexer.Scripts(tt.ident); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Scripts(%q) = %q, want %q", tt.ident, got, tt.want)
+		}
+	}
+}
+
+func TestSpelling(t *testing.T) {
+	tests := []struct {
+		tt         token.TokenType
+		like, want string
+	}{
+		{token.AITPECE, "eger", "áıtpece"},
+		{token.AITPECE, "if", "else"},
+		{token.AITPECE, "егер", "әйтпесе"},
+		{token.ISHINDE, "for", "in"},
+		{token.JASA, "let", "var"},
+	}
+	for _, tt := range tests {
+		if got := lexer.Respell(tt.tt, tt.like); got != tt.want {
+			t.Errorf("Respell(%s, %s) = %s, want %s", tt.tt, tt.like, got, tt.want)
+		}
+	}
+	if got := lexer.Spelling(token.ATQARM, lexer.Latin); got != "fn" {
+		t.Errorf("Spelling(ATQARM, latin) = %s, want fn", got)
+	}
+	if got := lexer.Spelling(token.ATQARM, lexer.Mixed); got != "atqar'm" {
+		t.Errorf("Spelling(ATQARM, mixed) = %s, want atqar'm", got)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name, src, kazakh, latin string
+	}{
+		{"let and var",
+			"let x = 1\nvar y = 2\nfn f() { return }\n",
+			"#syntax kazakh\nbolsyn x = 1\njasa y = 2\natqar'm f() { qaıtar }\n",
+			"#syntax latin\nlet x = 1\nvar y = 2\nfn f() { return }\n"},
+		{"layout and comments",
+			"#syntax latin\nif  x>1 {   // if x\n\tshow(\"if\")\n}\n",
+			"#syntax kazakh\neger  x>1 {   // if x\n\tkórset(\"if\")\n}\n",
+			"#syntax latin\nif  x>1 {   // if x\n\tshow(\"if\")\n}\n"},
+		{"cyrillic",
+			"#syntax kazakh\nжаса x = 1\nкөрсет(x)\n",
+			"#syntax kazakh\nжаса x = 1\nкөрсет(x)\n",
+			"#syntax latin\nvar x = 1\nshow(x)\n"},
+		{"pragma-only words",
+			"#syntax latin\nlet s = parallel for i in 0..n sum { i }\n",
+			"#syntax kazakh\nbolsyn s = qatarlas ár i ishinde 0..n sum { i }\n",
+			"#syntax latin\nlet s = parallel for i in 0..n sum { i }\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kazakh, err := lexer.Convert(tt.src, lexer.Kazakh)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kazakh != tt.kazakh {
+				t.Errorf("to kazakh:\n%s\nwant\n%s", kazakh, tt.kazakh)
+			}
+			latin, err := lexer.Convert(kazakh, lexer.Latin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if latin != tt.latin {
+				t.Errorf("back to latin:\n%s\nwant\n%s", latin, tt.latin)
+			}
+		})
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		to  lexer.Syntax
+		err string
+	}{
+		{"jasa in = 1\n", lexer.Latin, "1:6: identifier in is a keyword in latin syntax"},
+		{"#syntax latin\nvar jol = 1\n", lexer.Kazakh, "2:5: identifier jol is a keyword in kazakh syntax"},
+		{"var x = 1 ?\n", lexer.Kazakh, `1:11: unexpected "?"`},
+	}
+	for _, tt := range tests {
+		_, err := lexer.Convert(tt.src, tt.to)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("Convert(%q, %s): %v, want %s", tt.src, tt.to, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+// FILE: internal/lang/lexer/script.go
+
+package lexer
+
+import "unicode"
+
+var scripts = []struct {
+	name  string
+	table *unicode.RangeTable
+}{
+	{"Latin", unicode.Latin},
+	{"Cyrillic", unicode.Cyrillic},
+	{"Greek", unicode.Greek},
+}
+
+// Scripts returns the scripts of the letters in ident, in order of first
+// appearance. More than one usually means a look-alike letter from another
+// keyboard layout, such as a Cyrillic а in an otherwise Latin name.
+// Digits, marks and the apostrophe belong to no script.
+func Scripts(ident string) []string {
+	var found []string
+	seen := make(map[string]bool)
+	for _, r := range ident {
+		for _, s := range scripts {
+			if unicode.Is(s.table, r) && !seen[s.name] {
+				seen[s.name] = true
+				found = append(found, s.name)
+			}
+		}
+	}
+	return found
+}
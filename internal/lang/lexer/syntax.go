@@ -0,0 +1,357 @@
+// FILE: internal/lang/lexer/syntax.go
+
+package lexer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/token"
+)
+
+// Syntax selects the keyword set of a source file. Both sets produce the
+// same tokens, so the parser and everything after it see one language:
+//
+//	kazakh   latin        kazakh   latin
+//	jasa     var, let     modul    module
+//	bekit    const        engiz    import
+//	atqar'm  fn           ashyq    pub
+//	qaıtar   return       kórset   show
+//	eger     if           san      int
+//	áıtpece  else         aqsha    decimal
+//	ázirshe  while        jol      string
+//	jan      true         tańba    char
+//	j'n      false        aqıqat   bool
+//	qatarlas parallel     j'i'm    array
+//	ár       for          mindet   task
+//	ishinde  in           kút      join
+//	arna     chan         tańda    select
+//
+// bolsyn is the kazakh spelling of let, which converting keeps apart from
+// jasa and var. The kazakh set also accepts the Cyrillic spellings (жаса, бекіт,
+// атқарым, қайтар, ...) and the Latin ones with y for ы and without
+// diacritics (atqarym, jyn, qaitar, korset, ...); see kazakhAliases.
+//
+// A file selects one set with a `#syntax kazakh` or `#syntax latin` line;
+// the words of the other set are then ordinary identifiers. Without the
+// pragma both sets are keywords, except the words of parallel loops, tasks
+// and channels and bolsyn, which came later: they are keywords only under
+// the pragma of their set, so that files without one may use them as
+// names (see pragmaOnly).
+type Syntax int
+
+const (
+	Mixed Syntax = iota
+	Kazakh
+	Latin
+)
+
+func (s Syntax) String() string {
+	switch s {
+	case Kazakh:
+		return "kazakh"
+	case Latin:
+		return "latin"
+	}
+	return "mixed"
+}
+
+// ParseSyntax parses the name of a keyword set as used by #syntax.
+func ParseSyntax(name string) (Syntax, bool) {
+	switch name {
+	case "kazakh":
+		return Kazakh, true
+	case "latin":
+		return Latin, true
+	}
+	return Mixed, false
+}
+
+var kazakhKeywords = map[string]token.TokenType{
+	"jasa":    token.JASA,
+	"bekit":   token.BEKIT,
+	"atqar'm": token.ATQARM,
+	"qaıtar":  token.QAITAR,
+	"eger":    token.EGER,
+	"áıtpece": token.AITPECE,
+	"ázirshe": token.AZIRSHE,
+	"jan":     token.JAN,
+	"j'n":     token.JYN,
+	"kórset":  token.KORSET,
+	"modul":   token.MODUL,
+	"engiz":   token.ENGIZ,
+	"ashyq":   token.ASHYQ,
+	"san":     token.SAN,
+	"aqsha":   token.AQSHA,
+	"jol":     token.JOL,
+	"tańba":   token.TANBA,
+	"aqıqat":  token.AQIQAT,
+	"j'i'm":   token.JYIM,
+
+	"qatarlas": token.QATARLAS,
+	"ár":       token.AR,
+	"ishinde":  token.ISHINDE,
+	"mindet":   token.MINDET,
+	"kút":      token.KUT,
+
+	"arna":  token.ARNA,
+	"tańda": token.TANDA,
+}
+
+// kazakhAliases are the other spellings of the kazakh keywords. Keys are in
+// NFC, like the identifiers the lexer produces.
+var kazakhAliases = map[string]token.TokenType{
+	// Cyrillic
+	"жаса":    token.JASA,
+	"бекіт":   token.BEKIT,
+	"атқарым": token.ATQARM,
+	"қайтар":  token.QAITAR,
+	"егер":    token.EGER,
+	"әйтпесе": token.AITPECE,
+	"әзірше":  token.AZIRSHE,
+	"жан":     token.JAN,
+	"жын":     token.JYN,
+	"көрсет":  token.KORSET,
+	"модуль":  token.MODUL,
+	"енгіз":   token.ENGIZ,
+	"ашық":    token.ASHYQ,
+	"сан":     token.SAN,
+	"ақша":    token.AQSHA,
+	"жол":     token.JOL,
+	"таңба":   token.TANBA,
+	"ақиқат":  token.AQIQAT,
+	"жиым":    token.JYIM,
+
+	"қатарлас": token.QATARLAS,
+	"әр":       token.AR,
+	"ішінде":   token.ISHINDE,
+	"міндет":   token.MINDET,
+	"күт":      token.KUT,
+
+	"арна":  token.ARNA,
+	"таңда": token.TANDA,
+
+	"bolsyn": token.JASA,
+	"болсын": token.JASA,
+
+	// Latin with y for ы, as in the official alphabet
+	"atqarym": token.ATQARM,
+	"jyn":     token.JYN,
+	"jıym":    token.JYIM,
+	"áıtpese": token.AITPECE,
+
+	// Latin without diacritics
+	"qaitar":  token.QAITAR,
+	"aitpece": token.AITPECE,
+	"aitpese": token.AITPECE,
+	"azirshe": token.AZIRSHE,
+	"korset":  token.KORSET,
+	"tanba":   token.TANBA,
+	"aqiqat":  token.AQIQAT,
+	"jiym":    token.JYIM,
+	"kut":     token.KUT,
+	"tanda":   token.TANDA,
+}
+
+// let is a synonym of var; see pairedSpellings.
+var latinKeywords = map[string]token.TokenType{
+	"var":     token.JASA,
+	"let":     token.JASA,
+	"const":   token.BEKIT,
+	"fn":      token.ATQARM,
+	"return":  token.QAITAR,
+	"if":      token.EGER,
+	"else":    token.AITPECE,
+	"while":   token.AZIRSHE,
+	"true":    token.JAN,
+	"false":   token.JYN,
+	"show":    token.KORSET,
+	"module":  token.MODUL,
+	"import":  token.ENGIZ,
+	"pub":     token.ASHYQ,
+	"int":     token.SAN,
+	"decimal": token.AQSHA,
+	"string":  token.JOL,
+	"char":    token.TANBA,
+	"bool":    token.AQIQAT,
+	"array":   token.JYIM,
+
+	"parallel": token.QATARLAS,
+	"for":      token.AR,
+	"in":       token.ISHINDE,
+	"task":     token.MINDET,
+	"join":     token.KUT,
+
+	"chan":   token.ARNA,
+	"select": token.TANDA,
+}
+
+// pairedSpellings are the synonyms of a keyword that Convert writes as
+// each other rather than as the main spelling of the other set, so that
+// converting back restores them.
+var pairedSpellings = map[string]string{
+	"let":    "bolsyn",
+	"bolsyn": "let",
+	"болсын": "let",
+}
+
+// pragmaOnly are the keywords that a file without a #syntax pragma reads
+// as identifiers, in every spelling; pragmaOnlyWords are single spellings
+// of other keywords that it does.
+var pragmaOnly = map[token.TokenType]bool{
+	token.QATARLAS: true,
+	token.AR:       true,
+	token.ISHINDE:  true,
+	token.MINDET:   true,
+	token.KUT:      true,
+	token.ARNA:     true,
+	token.TANDA:    true,
+}
+
+var pragmaOnlyWords = map[string]bool{
+	"bolsyn": true,
+	"болсын": true,
+}
+
+var latinSpelling = map[token.TokenType]string{}
+
+// cyrillicSpelling is the Cyrillic alias of each kazakh keyword.
+var cyrillicSpelling = map[token.TokenType]string{}
+
+func init() {
+	for word, tt := range latinKeywords {
+		if _, ok := pairedSpellings[word]; !ok {
+			latinSpelling[tt] = word
+		}
+	}
+	for word, tt := range kazakhAliases {
+		if _, ok := pairedSpellings[word]; !ok && isCyrillic(word) {
+			cyrillicSpelling[tt] = word
+		}
+	}
+}
+
+func isCyrillic(word string) bool {
+	r, _ := utf8.DecodeRuneInString(word)
+	return unicode.Is(unicode.Cyrillic, r)
+}
+
+func lookup(ident string, s Syntax) token.TokenType {
+	tt := keyword(ident, s)
+	if s == Mixed && (pragmaOnly[tt] || pragmaOnlyWords[ident]) {
+		return token.IDENT
+	}
+	return tt
+}
+
+func keyword(ident string, s Syntax) token.TokenType {
+	if s != Latin {
+		if tt, ok := kazakhKeywords[ident]; ok {
+			return tt
+		}
+		if tt, ok := kazakhAliases[ident]; ok {
+			return tt
+		}
+	}
+	if s != Kazakh {
+		if tt, ok := latinKeywords[ident]; ok {
+			return tt
+		}
+	}
+	return token.IDENT
+}
+
+// Keywords returns the main spellings of the keywords of both sets, for
+// suggestions.
+func Keywords() []string {
+	words := make([]string, 0, len(kazakhKeywords)+len(latinKeywords))
+	for w := range kazakhKeywords {
+		words = append(words, w)
+	}
+	for w := range latinKeywords {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	return words
+}
+
+// Spelling returns how the keyword tt is written in syntax s; Mixed uses
+// the Kazakh spelling.
+func Spelling(tt token.TokenType, s Syntax) string {
+	if s == Latin {
+		return latinSpelling[tt]
+	}
+	return string(tt)
+}
+
+// Respell returns how the keyword tt is written alongside like, a keyword
+// as it appears in the source: in latin if like is latin, in Cyrillic if
+// like is Cyrillic and in the main Kazakh spelling otherwise. The printer
+// uses it for keywords the syntax tree does not keep, such as áıtpece.
+func Respell(tt token.TokenType, like string) string {
+	if _, ok := latinKeywords[like]; ok {
+		return latinSpelling[tt]
+	}
+	if isCyrillic(like) {
+		if word, ok := cyrillicSpelling[tt]; ok {
+			return word
+		}
+	}
+	return string(tt)
+}
+
+var pragma = regexp.MustCompile(`(?m)^([ \t]*#syntax[ \t]+)\S+`)
+
+// Convert rewrites the keywords of src in the keyword set of syntax to and
+// updates its #syntax pragma. Keywords already spelled in the target set,
+// identifiers, literals, comments and layout are left exactly as they
+// are, so converting back restores the original, except that aliases
+// such as Cyrillic `жаса` come back in the main spelling; `let` and
+// `bolsyn` convert to each other. A file without a pragma gets one when
+// it needs it, because it uses a word such as bolsyn that is a keyword only
+// under the pragma. It fails when src does not lex or uses an identifier
+// that is a keyword of the target syntax.
+func Convert(src string, to Syntax) (string, error) {
+	var out strings.Builder
+	last := 0
+	needPragma := false
+	l := New(src)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch {
+		case tok.Type == token.ILLEGAL:
+			return "", errors.New(tok.Pos() + ": " + msg.Sprintf(msg.Unexpected, fmt.Sprintf("%q", tok.Literal)))
+		case tok.Type == token.IDENT:
+			if lookup(tok.Literal, to) != token.IDENT {
+				return "", errors.New(tok.Pos() + ": " + msg.Sprintf(msg.KeywordInSyntax, tok.Literal, to))
+			}
+		case latinSpelling[tok.Type] != "" && lookup(tok.Literal, to) != tok.Type:
+			// The literal is normalized; the source text ends where the
+			// lexer stopped.
+			out.WriteString(src[last:tok.Offset])
+			word, ok := pairedSpellings[tok.Literal]
+			if !ok || lookup(word, to) != tok.Type {
+				word = Spelling(tok.Type, to)
+			}
+			out.WriteString(word)
+			needPragma = needPragma || lookup(word, Mixed) != tok.Type
+			last = l.position
+		}
+	}
+	out.WriteString(src[last:])
+	if to == Mixed {
+		return out.String(), nil
+	}
+	if !pragma.MatchString(src) {
+		if needPragma {
+			return "#syntax " + to.String() + "\n" + out.String(), nil
+		}
+		return out.String(), nil
+	}
+	return pragma.ReplaceAllString(out.String(), "${1}"+to.String()), nil
+}
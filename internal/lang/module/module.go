@@ -0,0 +1,295 @@
+// FILE: internal/lang/module/module.go
+
+// Package module loads multi-file tenge programs.
+//
+// A program starts at a main file. Every `engiz "path"` in a file names
+// another module: the file <root>/<path>.tng, where root is the directory
+// of the main file. That file must begin with `modul <name>`, where name is
+// the last element of the path, and its `ashyq` declarations are used
+// qualified by that name:
+//
+//	// stats/rng.tng
+//	modul rng
+//	ashyq jasa next : atqar'm (s: u64) -> u64 { ... }
+//
+//	// main.tng
+//	engiz "stats/rng"
+//	jasa x = rng.next(42)
+//
+// The paths of the standard modules, which are built into the compiler
+// (see the std directory), name those instead of files:
+//
+//	engiz "simd/f64x4"
+//	f64x4.add(dst, a, b)
+//
+// Import cycles are errors. Load returns the modules in dependency order,
+// so that each module comes after everything it imports.
+package module
+
+import (
+	"embed"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/diag"
+	"github.com/DauletBai/tenge/internal/lang/lexer"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/parser"
+	"github.com/DauletBai/tenge/internal/lang/token"
+)
+
+// Module is one parsed source file.
+type Module struct {
+	Name    string // qualifier used by importers; "main" for the main file
+	Path    string // import path; empty for the main file
+	File    string // file name as used in positions
+	Program *ast.Program
+	Imports []*Import
+	Std     bool // built into the compiler
+}
+
+// Import is a resolved `engiz` statement.
+type Import struct {
+	Decl   *ast.EngizStatement
+	Module *Module
+}
+
+// Program is a loaded program: its modules in dependency order, the main
+// module last.
+type Program struct {
+	Root    string
+	Modules []*Module
+}
+
+// Main returns the module of the main file.
+func (p *Program) Main() *Module {
+	return p.Modules[len(p.Modules)-1]
+}
+
+//go:embed std
+var std embed.FS
+
+// stdSource returns the source of the standard module with the given
+// import path, and whether there is one.
+func stdSource(path string) ([]byte, bool) {
+	src, err := std.ReadFile(stdFile(path))
+	return src, err == nil
+}
+
+// stdFile is the name of the file of a standard module in positions.
+func stdFile(path string) string {
+	return "std/" + path + ".tng"
+}
+
+// ParseFile parses a single file. The errors carry the file name.
+func ParseFile(file string) (*ast.Program, diag.List) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return nil, diag.List{{Message: err.Error()}}
+	}
+	return parseSource(file, src)
+}
+
+func parseSource(file string, src []byte) (*ast.Program, diag.List) {
+	p := parser.New(lexer.NewFile(file, string(src)))
+	program := p.ParseProgram()
+	return program, p.Diagnostics()
+}
+
+// Load parses the main file and every module it imports, directly or
+// indirectly. Errors are prefixed with "file:line:col".
+func Load(mainFile string) (*Program, diag.List) {
+	l := newLoader(mainFile)
+	main := l.parse(mainFile, "")
+	if main == nil {
+		return nil, l.errors
+	}
+	return l.load(main)
+}
+
+// LoadParsed is like Load with the main file already parsed, as editors
+// do with unsaved text. The imported modules are read from disk.
+func LoadParsed(mainFile string, program *ast.Program) (*Program, diag.List) {
+	l := newLoader(mainFile)
+	return l.load(l.module(mainFile, "", program))
+}
+
+func newLoader(mainFile string) *loader {
+	return &loader{
+		root:    filepath.Dir(mainFile),
+		modules: make(map[string]*Module),
+		state:   make(map[string]int),
+	}
+}
+
+func (l *loader) load(main *Module) (*Program, diag.List) {
+	l.visit(main, nil)
+	if len(l.errors) > 0 {
+		return nil, l.errors
+	}
+	return &Program{Root: l.root, Modules: l.order}, nil
+}
+
+const (
+	unvisited = iota
+	visiting  // on the current import chain
+	done
+)
+
+type loader struct {
+	root    string
+	modules map[string]*Module // by import path
+	state   map[string]int     // by import path
+	order   []*Module
+	errors  diag.List
+}
+
+func (l *loader) errorf(tok token.Token, code msg.Code, args ...interface{}) {
+	l.errors = append(l.errors, diag.New(diag.Error, tok, code, args...))
+}
+
+// fileStart returns a position at the start of file.
+func fileStart(file string) token.Token {
+	return token.Token{File: file, Line: 1, Column: 1}
+}
+
+// parse reads a module file and checks its header. path is empty for the
+// main file.
+func (l *loader) parse(file, path string) *Module {
+	program, errs := ParseFile(file)
+	if len(errs) > 0 {
+		l.errors = append(l.errors, errs...)
+		return nil
+	}
+	return l.module(file, path, program)
+}
+
+// parseSource is parse for a standard module.
+func (l *loader) parseSource(file, path string, src []byte) *Module {
+	program, errs := parseSource(file, src)
+	if len(errs) > 0 {
+		l.errors = append(l.errors, errs...)
+		return nil
+	}
+	return l.module(file, path, program)
+}
+
+// module checks the header of a parsed file.
+func (l *loader) module(file, path string, program *ast.Program) *Module {
+	m := &Module{Name: "main", Path: path, File: file, Program: program}
+
+	for i, s := range program.Statements {
+		switch s := s.(type) {
+		case *ast.ModulStatement:
+			if i != 0 {
+				l.errorf(s.Token, msg.ModulNotFirst)
+			}
+			m.Name = s.Name.Value
+		case *ast.EngizStatement:
+			if !importsOnly(program.Statements[:i]) {
+				l.errorf(s.Token, msg.EngizNotFirst)
+			}
+			m.Imports = append(m.Imports, &Import{Decl: s})
+		}
+	}
+
+	if path != "" {
+		want := path[strings.LastIndex(path, "/")+1:]
+		switch {
+		case len(program.Statements) == 0:
+			l.errorf(fileStart(file), msg.MissingModul, want)
+		case m.Name == "main":
+			if _, ok := program.Statements[0].(*ast.ModulStatement); !ok {
+				l.errorf(fileStart(file), msg.MissingModul, want)
+			} else {
+				l.errorf(fileStart(file), msg.ImportMain)
+			}
+		case m.Name != want:
+			l.errorf(program.Statements[0].(*ast.ModulStatement).Name.Token, msg.ModulMismatch, m.Name, path, want)
+		}
+	}
+	return m
+}
+
+func importsOnly(stmts []ast.Statement) bool {
+	for _, s := range stmts {
+		switch s.(type) {
+		case *ast.ModulStatement, *ast.EngizStatement:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// visit resolves the imports of m depth-first and appends m to the order
+// once all of them are loaded. chain is the current import chain, used to
+// report cycles.
+func (l *loader) visit(m *Module, chain []string) {
+	l.state[m.Path] = visiting
+	chain = append(chain, m.Name)
+	seen := make(map[string]bool)
+
+	for _, imp := range m.Imports {
+		path := imp.Decl.Path.Value
+		pos := imp.Decl.Path.Token
+		if !validPath(path) {
+			l.errorf(pos, msg.BadImportPath, path)
+			continue
+		}
+		if seen[imp.Decl.Name()] {
+			l.errorf(pos, msg.DuplicateEngiz, imp.Decl.Name())
+			continue
+		}
+		seen[imp.Decl.Name()] = true
+
+		switch l.state[path] {
+		case visiting:
+			l.errorf(pos, msg.ImportCycle, strings.Join(chain, " -> "), imp.Decl.Name())
+			continue
+		case done:
+			imp.Module = l.modules[path]
+			continue
+		}
+
+		var dep *Module
+		if src, ok := stdSource(path); ok {
+			dep = l.parseSource(stdFile(path), path, src)
+			if dep != nil {
+				dep.Std = true
+			}
+		} else {
+			file := filepath.Join(l.root, filepath.FromSlash(path)+".tng")
+			if _, err := os.Stat(file); err != nil {
+				l.errorf(pos, msg.ModuleNotFound, path, file)
+				continue
+			}
+			dep = l.parse(file, path)
+		}
+		if dep == nil {
+			l.state[path] = done
+			continue
+		}
+		l.modules[path] = dep
+		imp.Module = dep
+		l.visit(dep, chain)
+	}
+
+	l.state[m.Path] = done
+	l.order = append(l.order, m)
+}
+
+// validPath accepts slash-separated paths of identifier-like elements.
+func validPath(path string) bool {
+	if path == "" {
+		return false
+	}
+	for _, elem := range strings.Split(path, "/") {
+		if elem == "" || elem == "." || elem == ".." || strings.ContainsAny(elem, `\:`) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,132 @@
+// FILE: internal/lang/module/module_test.go
+
+package module_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DauletBai/tenge/internal/lang/module"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+)
+
+// tree writes files, by their paths relative to a new directory, and
+// returns the path of main.tng in it.
+func tree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, "main.tng")
+}
+
+func TestLoad(t *testing.T) {
+	main := tree(t, map[string]string{
+		"main.tng": `engiz "stats/rng"
+engiz "util"
+engiz "simd/f64x4"
+kórset(util.twice(rng.next(1)))
+`,
+		"util.tng": `modul util
+engiz "stats/rng"
+ashyq atqar'm twice(x: san) -> san { qaıtar rng.next(x) * 2 }
+`,
+		"stats/rng.tng": `modul rng
+ashyq atqar'm next(x: san) -> san { qaıtar x + 1 }
+`,
+	})
+	prog, errs := module.Load(main)
+	if len(errs) > 0 {
+		t.Fatal(errs[0])
+	}
+	var order []string
+	for _, m := range prog.Modules {
+		order = append(order, m.Name)
+	}
+	if got, want := strings.Join(order, " "), "rng util f64x4 main"; got != want {
+		t.Errorf("modules %s, want %s", got, want)
+	}
+	m := prog.Main()
+	if m.Name != "main" || m.Path != "" || m.File != main {
+		t.Errorf("main module %s %q %s", m.Name, m.Path, m.File)
+	}
+	if len(m.Imports) != 3 || m.Imports[0].Module != prog.Modules[0] || m.Imports[1].Module != prog.Modules[1] {
+		t.Fatalf("imports of main not resolved")
+	}
+	if util := prog.Modules[1]; util.Imports[0].Module != prog.Modules[0] {
+		t.Errorf("util and main import different copies of stats/rng")
+	}
+	if f := m.Imports[2].Module; !f.Std || f.Path != "simd/f64x4" {
+		t.Errorf("simd/f64x4: Std %v, path %q", f.Std, f.Path)
+	}
+}
+
+// TestLoadErrors checks the errors of the import graph and of the module
+// headers, and where they are reported.
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		code  msg.Code
+		want  string // the error, with FILE for its file in the directory
+	}{
+		{"not found", map[string]string{
+			"main.tng": `engiz "nowhere"`,
+		}, msg.ModuleNotFound, `main.tng:1:7: cannot find module "nowhere" (looked for FILE)`},
+		{"cycle", map[string]string{
+			"main.tng": `engiz "a"`,
+			"a.tng":    "modul a\nengiz \"b\"",
+			"b.tng":    "modul b\nengiz \"a\"",
+		}, msg.ImportCycle, "b.tng:2:7: import cycle: main -> a -> b -> a"},
+		{"importing itself", map[string]string{
+			"main.tng": `engiz "a"`,
+			"a.tng":    "modul a\nengiz \"a\"",
+		}, msg.ImportCycle, "a.tng:2:7: import cycle: main -> a -> a"},
+		{"name mismatch", map[string]string{
+			"main.tng":      `engiz "lib/stats"`,
+			"lib/stats.tng": "modul stat",
+		}, msg.ModulMismatch, `stats.tng:1:7: modul stat does not match import path "lib/stats" (want modul stats)`},
+		{"no modul", map[string]string{
+			"main.tng": `engiz "a"`,
+			"a.tng":    "jasa x = 1",
+		}, msg.MissingModul, "a.tng:1:1: missing modul a declaration"},
+		{"modul not first", map[string]string{
+			"main.tng": `engiz "a"`,
+			"a.tng":    "jasa x = 1\nmodul a",
+		}, msg.ModulNotFirst, "a.tng:2:1: modul must be the first statement of the file"},
+		{"engiz after code", map[string]string{
+			"main.tng": "jasa x = 1\nengiz \"a\"",
+			"a.tng":    "modul a",
+		}, msg.EngizNotFirst, "main.tng:2:1: engiz must come before the other statements of the file"},
+		{"imported twice", map[string]string{
+			"main.tng": "engiz \"a\"\nengiz \"a\"",
+			"a.tng":    "modul a",
+		}, msg.DuplicateEngiz, "main.tng:2:7: a imported more than once"},
+		{"bad path", map[string]string{
+			"main.tng": `engiz "../a"`,
+		}, msg.BadImportPath, `main.tng:1:7: invalid import path "../a"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			main := tree(t, tt.files)
+			dir := filepath.Dir(main)
+			_, errs := module.Load(main)
+			if len(errs) != 1 {
+				t.Fatalf("errors %v, want one", errs)
+			}
+			want := strings.ReplaceAll(tt.want, "FILE", filepath.Join(dir, "nowhere.tng"))
+			if got := errs[0].Error(); errs[0].Code != tt.code || !strings.HasPrefix(got, dir) || !strings.HasSuffix(got, want) {
+				t.Errorf("error %s %s, want %s ...%s", errs[0].Code, got, tt.code, want)
+			}
+		})
+	}
+}
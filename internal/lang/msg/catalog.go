@@ -0,0 +1,1179 @@
+// FILE: internal/lang/msg/catalog.go
+
+package msg
+
+// Syntax errors.
+const (
+	ExpectedToken      Code = "E0101"
+	Unexpected         Code = "E0102"
+	DeclNeedsType      Code = "E0103"
+	ConstNeedsValue    Code = "E0104"
+	ExpectedAfterAshyq Code = "E0105"
+	CannotAssign       Code = "E0106"
+	UnclosedBlock      Code = "E0107"
+	ExpectedType       Code = "E0108"
+	BadSan             Code = "E0109"
+	SanOverflow        Code = "E0110"
+	BadAqsha           Code = "E0111"
+	KeywordInSyntax    Code = "E0112"
+	TaskNotCall        Code = "E0113"
+	ClauseNotComm      Code = "E0114"
+	ArraySizeInName    Code = "E0115"
+)
+
+// Module errors.
+const (
+	ModulNotFirst  Code = "E0201"
+	EngizNotFirst  Code = "E0202"
+	MissingModul   Code = "E0203"
+	ImportMain     Code = "E0204"
+	ModulMismatch  Code = "E0205"
+	BadImportPath  Code = "E0206"
+	DuplicateEngiz Code = "E0207"
+	ImportCycle    Code = "E0208"
+	ModuleNotFound Code = "E0209"
+	NotLoaded      Code = "E0210"
+)
+
+// Type errors and warnings.
+const (
+	Redeclared        Code = "E0301"
+	UnknownType       Code = "E0302"
+	ConstraintAsType  Code = "E0303"
+	NotConstraint     Code = "E0304"
+	AshyqNotTop       Code = "E0305"
+	NoValue           Code = "E0306"
+	MissingReturn     Code = "E0307"
+	TooManyReturns    Code = "E0308"
+	AssignConst       Code = "E0309"
+	AssignOtherModule Code = "E0310"
+	CannotUse         Code = "E0311"
+	Undefined         Code = "E0312"
+	TypeNotExpr       Code = "E0313"
+	ModulNoSelector   Code = "E0314"
+	InvalidSelector   Code = "E0315"
+	NotModul          Code = "E0316"
+	Unexported        Code = "E0317"
+	MixedElements     Code = "E0318"
+	AddressOf         Code = "E0319"
+	InvalidUnary      Code = "E0320"
+	CannotDeref       Code = "E0321"
+	UnknownOperator   Code = "E0322"
+	MismatchedTypes   Code = "E0323"
+	InvalidShift      Code = "E0324"
+	NotOrdered        Code = "E0325"
+	OperatorUndefined Code = "E0326"
+	ArgCount          Code = "E0327"
+	NotFunction       Code = "E0328"
+	CannotInfer       Code = "E0329"
+	NotSatisfied      Code = "E0330"
+	ConversionArgs    Code = "E0331"
+	CannotConvert     Code = "E0332"
+	InvalidIndex      Code = "E0333"
+	CannotIndex       Code = "E0334"
+	IntegerArg        Code = "E0335"
+	JyimArg           Code = "E0336"
+	InvalidArg        Code = "E0337"
+	ParallelAssign    Code = "E0338"
+	ParallelPointer   Code = "E0339"
+	ParallelEffect    Code = "E0340"
+	ReturnInParallel  Code = "E0341"
+	TaskOutsideKut    Code = "E0342"
+	TaskFunc          Code = "E0343"
+	TaskTarget        Code = "E0344"
+	UnknownReduction  Code = "E0345"
+	ReductionValue    Code = "E0346"
+	ReductionType     Code = "E0347"
+	ChanArg           Code = "E0348"
+	TimeoutOutside    Code = "E0349"
+	DuplicateClause   Code = "E0350"
+
+	MixedScript Code = "W0301"
+)
+
+// Runtime errors.
+const (
+	TypeMismatch      Code = "E0401"
+	UnknownOp         Code = "E0402"
+	ShiftCount        Code = "E0403"
+	DivisionByZero    Code = "E0404"
+	PrintfArgs        Code = "E0405"
+	NumberArg         Code = "E0406"
+	InvalidLength     Code = "E0407"
+	LenArg            Code = "E0408"
+	FirstJyimArg      Code = "E0409"
+	JyimArgRT         Code = "E0410"
+	IndexArgs         Code = "E0411"
+	OutOfRange        Code = "E0412"
+	BuiltinArgCount   Code = "E0413"
+	AssertionFailed   Code = "E0414"
+	AssertionMessage  Code = "E0415"
+	IndexNotSan       Code = "E0416"
+	CannotEvaluate    Code = "E0417"
+	NotIndexable      Code = "E0418"
+	BadIndex          Code = "E0419"
+	NotPointer        Code = "E0420"
+	NonBoolCondition  Code = "E0421"
+	NotModulRT        Code = "E0422"
+	NotCallable       Code = "E0423"
+	ConvertRT         Code = "E0424"
+	OutOfMemory       Code = "E0425"
+	NegativeLength    Code = "E0426"
+	IndexNotSupported Code = "E0428"
+	StackOverflow     Code = "E0429"
+	EmptyReduction    Code = "E0430"
+	SendClosed        Code = "E0431"
+	CloseClosed       Code = "E0432"
+	ChanNotMade       Code = "E0433"
+	Deadlock          Code = "E0434"
+	NegativeCapacity  Code = "E0435"
+)
+
+// Backend errors. The backends append where the program failed to compile,
+// one of InVM, InIR and InCompiled, as the last argument.
+const (
+	BackendType        Code = "E0501"
+	BackendUnsupported Code = "E0502"
+	BackendInterpret   Code = "E0503"
+	BackendNested      Code = "E0504"
+	BackendFuncLit     Code = "E0505"
+	BackendDirectCall  Code = "E0506"
+	BackendBuiltin     Code = "E0507"
+	BackendOperator    Code = "E0508"
+	BackendOperatorOn  Code = "E0509"
+	BackendExpression  Code = "E0510"
+	BackendUse         Code = "E0511"
+	BackendUseTyped    Code = "E0512"
+	BackendChannel     Code = "E0513"
+	BackendPrint       Code = "E0514"
+	BackendIndex       Code = "E0515"
+	BackendLen         Code = "E0516"
+	BackendAssign      Code = "E0517"
+	BackendEvaluate    Code = "E0518"
+	BackendNotValue    Code = "E0519"
+	BackendCallOnly    Code = "E0520"
+	BackendGeneric     Code = "E0521"
+	BackendEgerValue   Code = "E0522"
+	BackendEgerSingle  Code = "E0523"
+)
+
+// Errors in the files and flags of the tools.
+const (
+	PGOInvalid         Code = "E0601"
+	PGOVersion         Code = "E0602"
+	PGOMode            Code = "E0603"
+	PGOStale           Code = "E0604"
+	PGORecord          Code = "E0605"
+	PGODemo            Code = "E0606"
+	NotBytecode        Code = "E0607"
+	BytecodeTruncated  Code = "E0608"
+	BytecodeChecksum   Code = "E0609"
+	BytecodeVersion    Code = "E0610"
+	BadBytecode        Code = "E0611"
+	BytecodeTrailing   Code = "E0612"
+	BytecodeEnd        Code = "E0613"
+	BytecodeGlobals    Code = "E0614"
+	BytecodeInit       Code = "E0615"
+	BytecodeMain       Code = "E0616"
+	BytecodeResults    Code = "E0617"
+	BytecodeFunc       Code = "E0618"
+	BytecodeRegs       Code = "E0619"
+	BytecodeParam      Code = "E0620"
+	BytecodeCallFunc   Code = "E0621"
+	BytecodeCallArgs   Code = "E0622"
+	BytecodeCallResult Code = "E0623"
+	BytecodeCallArg    Code = "E0624"
+	BytecodeNoCode     Code = "E0625"
+	BytecodeCodeEnd    Code = "E0626"
+	BytecodeOpcode     Code = "E0627"
+	BytecodeOperand    Code = "E0628"
+	UnknownProfile     Code = "E0629"
+	UnknownPass        Code = "E0630"
+)
+
+// Phrases used inside other messages.
+const (
+	EndOfFile   Code = "T001"
+	InDecl      Code = "T002"
+	InReturn    Code = "T003"
+	InAssign    Code = "T004"
+	InCondition Code = "T005"
+	InArgument  Code = "T006"
+	OneOrTwo    Code = "T007"
+	Range       Code = "T008"
+	Latin       Code = "T009"
+	Cyrillic    Code = "T010"
+	Greek       Code = "T011"
+	ErrorLabel  Code = "T012"
+
+	SeverityError   Code = "T013"
+	SeverityWarning Code = "T014"
+	NoteLabel       Code = "T015"
+	HelpLabel       Code = "T016"
+	DidYouMean      Code = "T017"
+	PreviousDecl    Code = "T018"
+	BlockOpened     Code = "T019"
+	DeclaredHere    Code = "T020"
+	InRange         Code = "T021"
+	AssignedHere    Code = "T022"
+	BlockedIn       Code = "T023"
+	InVM            Code = "T024"
+	InIR            Code = "T025"
+	InCompiled      Code = "T026"
+)
+
+// catalog holds the English, Russian and Kazakh text of every code, in
+// the order of Lang. The Kazakh text is in the Cyrillic script, like the
+// Kazakh pages of the project documentation; keywords and type names are
+// left as they are written in code.
+var catalog = map[Code][3]string{
+	ExpectedToken: {
+		"expected next token to be %s, got %s instead",
+		"ожидался токен %s, получено %s",
+		"%s лексемасы күтілді, бірақ %s кездесті",
+	},
+	Unexpected: {
+		"unexpected %s",
+		"неожиданный %s",
+		"күтілмеген %s",
+	},
+	DeclNeedsType: {
+		"declaration of %s needs a type or a value",
+		"объявлению %s нужен тип или значение",
+		"%s жариялауына тип немесе мән керек",
+	},
+	ConstNeedsValue: {
+		"constant %s needs a value",
+		"константе %s нужно значение",
+		"%s тұрақтысына мән керек",
+	},
+	ExpectedAfterAshyq: {
+		"expected jasa, bekit or atqar'm after ashyq, got %s instead",
+		"после ashyq ожидалось jasa, bekit или atqar'm, получено %s",
+		"ashyq сөзінен кейін jasa, bekit немесе atqar'm күтілді, бірақ %s кездесті",
+	},
+	CannotAssign: {
+		"cannot assign to %s",
+		"нельзя присвоить значение %s",
+		"мән беруге болмайды: %s",
+	},
+	UnclosedBlock: {
+		"expected %s to close block opened at %s, got end of file",
+		"ожидалась %s, закрывающая блок из %s, но файл закончился",
+		"%[2]s ашылған блокты жабатын %[1]s күтілді, бірақ файл аяқталды",
+	},
+	ExpectedType: {
+		"expected a type, got %s",
+		"ожидался тип, получено %s",
+		"тип күтілді, бірақ %s кездесті",
+	},
+	BadSan: {
+		"could not parse %q as san",
+		"не удалось разобрать %q как san",
+		"%q мәнін san ретінде талдау мүмкін емес",
+	},
+	SanOverflow: {
+		"san literal %s overflows",
+		"литерал san %s вызывает переполнение",
+		"san литералы %s тым үлкен",
+	},
+	BadAqsha: {
+		"could not parse %q as aqsha",
+		"не удалось разобрать %q как aqsha",
+		"%q мәнін aqsha ретінде талдау мүмкін емес",
+	},
+	KeywordInSyntax: {
+		"identifier %s is a keyword in %s syntax",
+		"идентификатор %s является ключевым словом в синтаксисе %s",
+		"%s идентификаторы %s синтаксисінде кілт сөз болып табылады",
+	},
+	TaskNotCall: {
+		"%s needs a function call, not %s",
+		"%s требует вызова функции, а не %s",
+		"%s функция шақыруын қажет етеді, %s емес",
+	},
+	ClauseNotComm: {
+		"%s clause needs recv, send, timeout or %s, not %s",
+		"ветке %s нужен recv, send, timeout или %s, а не %s",
+		"%s тармағына recv, send, timeout немесе %s керек, %s емес",
+	},
+	ArraySizeInName: {
+		"%s[...] is C syntax: declare an array as %s: []T = [...]",
+		"%s[...] — синтаксис C: массив объявляется как %s: []T = [...]",
+		"%s[...] — C синтаксисі: массив %s: []T = [...] түрінде жарияланады",
+	},
+
+	ModulNotFirst: {
+		"modul must be the first statement of the file",
+		"modul должен быть первой инструкцией файла",
+		"modul файлдың бірінші нұсқауы болуы керек",
+	},
+	EngizNotFirst: {
+		"engiz must come before the other statements of the file",
+		"engiz должен стоять перед остальными инструкциями файла",
+		"engiz файлдың басқа нұсқауларынан бұрын тұруы керек",
+	},
+	MissingModul: {
+		"missing modul %s declaration",
+		"отсутствует объявление modul %s",
+		"modul %s жариялауы жоқ",
+	},
+	ImportMain: {
+		"modul main cannot be imported",
+		"modul main нельзя импортировать",
+		"modul main енгізуге болмайды",
+	},
+	ModulMismatch: {
+		"modul %s does not match import path %q (want modul %s)",
+		"modul %s не соответствует пути импорта %q (нужен modul %s)",
+		"modul %s енгізу жолына %q сәйкес келмейді (modul %s керек)",
+	},
+	BadImportPath: {
+		"invalid import path %q",
+		"недопустимый путь импорта %q",
+		"жарамсыз енгізу жолы %q",
+	},
+	DuplicateEngiz: {
+		"%s imported more than once",
+		"%s импортирован более одного раза",
+		"%s бірнеше рет енгізілген",
+	},
+	ImportCycle: {
+		"import cycle: %s -> %s",
+		"цикл импорта: %s -> %s",
+		"енгізу циклі: %s -> %s",
+	},
+	ModuleNotFound: {
+		"cannot find module %q (looked for %s)",
+		"модуль %q не найден (искали %s)",
+		"%q модулі табылмады (%s ізделді)",
+	},
+	NotLoaded: {
+		"module %q is not loaded",
+		"модуль %q не загружен",
+		"%q модулі жүктелмеген",
+	},
+
+	Redeclared: {
+		"%s redeclared in this block",
+		"%s повторно объявлен в этом блоке",
+		"%s осы блокта қайта жарияланған",
+	},
+	UnknownType: {
+		"unknown type %s",
+		"неизвестный тип %s",
+		"белгісіз тип %s",
+	},
+	ConstraintAsType: {
+		"cannot use constraint %s as a type",
+		"ограничение %s нельзя использовать как тип",
+		"%s шектеуін тип ретінде қолдануға болмайды",
+	},
+	NotConstraint: {
+		"%s is not a constraint (want Ordered, Number or Integer)",
+		"%s не является ограничением (нужно Ordered, Number или Integer)",
+		"%s шектеу емес (Ordered, Number немесе Integer керек)",
+	},
+	AshyqNotTop: {
+		"ashyq is only allowed at the top level of a module",
+		"ashyq допускается только на верхнем уровне модуля",
+		"ashyq тек модульдің жоғарғы деңгейінде рұқсат етілген",
+	},
+	NoValue: {
+		"%s (no value) used as value",
+		"%s (нет значения) используется как значение",
+		"%s (мәні жоқ) мән ретінде қолданылған",
+	},
+	MissingReturn: {
+		"missing return value (want %s)",
+		"отсутствует возвращаемое значение (нужен %s)",
+		"қайтарылатын мән жоқ (%s керек)",
+	},
+	TooManyReturns: {
+		"too many return values",
+		"слишком много возвращаемых значений",
+		"қайтарылатын мәндер тым көп",
+	},
+	AssignConst: {
+		"cannot assign to constant %s",
+		"нельзя присвоить значение константе %s",
+		"%s тұрақтысына мән беруге болмайды",
+	},
+	AssignOtherModule: {
+		"cannot assign to %s (declared in another module)",
+		"нельзя присвоить значение %s (объявлено в другом модуле)",
+		"мән беруге болмайды: %s (басқа модульде жарияланған)",
+	},
+	CannotUse: {
+		"cannot use %s (%s) as %s %s",
+		"нельзя использовать %s (%s) как %s %s",
+		"%[4]s %[1]s (%[2]s) мәнін %[3]s ретінде қолдануға болмайды",
+	},
+	Undefined: {
+		"undefined: %s",
+		"не определено: %s",
+		"анықталмаған: %s",
+	},
+	TypeNotExpr: {
+		"type %s is not an expression",
+		"тип %s не является выражением",
+		"%s типі өрнек емес",
+	},
+	ModulNoSelector: {
+		"use of modul %s without selector",
+		"использование modul %s без селектора",
+		"modul %s селекторсыз қолданылған",
+	},
+	InvalidSelector: {
+		"invalid selector %s: only module names can be qualified",
+		"недопустимый селектор %s: уточнять можно только имена модулей",
+		"жарамсыз селектор %s: тек модуль атауларын нақтылауға болады",
+	},
+	NotModul: {
+		"%s undefined (%s is not a modul)",
+		"%s не определено (%s не является modul)",
+		"%s анықталмаған (%s modul емес)",
+	},
+	Unexported: {
+		"cannot refer to unexported name %s (declare it %s in %s %s)",
+		"нельзя обратиться к неэкспортируемому имени %s (объявите его %s в %s %s)",
+		"экспортталмаған %[1]s атауына сілтеме жасауға болмайды (оны %[3]s %[4]s ішінде %[2]s деп жариялаңыз)",
+	},
+	MixedElements: {
+		"mixed element types %s and %s in j'i'm literal",
+		"разные типы элементов %s и %s в литерале j'i'm",
+		"j'i'm литералында %s және %s элемент типтері аралас",
+	},
+	AddressOf: {
+		"cannot take the address of %s",
+		"нельзя взять адрес %s",
+		"адресін алуға болмайды: %s",
+	},
+	InvalidUnary: {
+		"invalid operation: %s%s (%s)",
+		"недопустимая операция: %s%s (%s)",
+		"жарамсыз операция: %s%s (%s)",
+	},
+	CannotDeref: {
+		"invalid operation: cannot dereference %s (%s)",
+		"недопустимая операция: нельзя разыменовать %s (%s)",
+		"жарамсыз операция: %s (%s) сілтемесін ашуға болмайды",
+	},
+	UnknownOperator: {
+		"unknown operator %s",
+		"неизвестный оператор %s",
+		"белгісіз оператор %s",
+	},
+	MismatchedTypes: {
+		"invalid operation: %s (mismatched types %s and %s)",
+		"недопустимая операция: %s (несовпадающие типы %s и %s)",
+		"жарамсыз операция: %s (%s және %s типтері сәйкес емес)",
+	},
+	InvalidShift: {
+		"invalid operation: shift of %s by %s",
+		"недопустимая операция: сдвиг %s на %s",
+		"жарамсыз операция: %s мәнін %s мөлшеріне жылжыту",
+	},
+	NotOrdered: {
+		"invalid operation: %s (%s is not ordered)",
+		"недопустимая операция: %s (%s не упорядочен)",
+		"жарамсыз операция: %s (%s реттелмейді)",
+	},
+	OperatorUndefined: {
+		"invalid operation: operator %s not defined on %s (%s)",
+		"недопустимая операция: оператор %s не определён для %s (%s)",
+		"жарамсыз операция: %s операторы %s (%s) үшін анықталмаған",
+	},
+	ArgCount: {
+		"wrong number of arguments in call to %s: have %d, want %s",
+		"неверное число аргументов в вызове %s: передано %d, нужно %s",
+		"%s шақыруындағы аргументтер саны қате: %d берілді, %s керек",
+	},
+	NotFunction: {
+		"cannot call non-function %s (%s)",
+		"нельзя вызвать %s (%s): это не функция",
+		"%s (%s) функция емес, оны шақыруға болмайды",
+	},
+	CannotInfer: {
+		"cannot infer %s in call to %s",
+		"не удаётся вывести %s в вызове %s",
+		"%[2]s шақыруында %[1]s типін анықтау мүмкін емес",
+	},
+	NotSatisfied: {
+		"%s does not satisfy %s (%s in call to %s)",
+		"%s не удовлетворяет %s (%s в вызове %s)",
+		"%s %s шектеуін қанағаттандырмайды (%s, %s шақыруында)",
+	},
+	ConversionArgs: {
+		"conversion to %s needs exactly one argument",
+		"преобразованию в %s нужен ровно один аргумент",
+		"%s түріне түрлендіруге дәл бір аргумент керек",
+	},
+	CannotConvert: {
+		"cannot convert %s (%s) to %s",
+		"нельзя преобразовать %s (%s) в %s",
+		"%s (%s) мәнін %s түріне түрлендіруге болмайды",
+	},
+	InvalidIndex: {
+		"invalid index %s (%s)",
+		"недопустимый индекс %s (%s)",
+		"жарамсыз индекс %s (%s)",
+	},
+	CannotIndex: {
+		"invalid operation: cannot index %s (%s)",
+		"недопустимая операция: %s (%s) нельзя индексировать",
+		"жарамсыз операция: %s (%s) индекстеуге болмайды",
+	},
+	IntegerArg: {
+		"cannot use %s (%s) as integer argument to %s",
+		"нельзя использовать %s (%s) как целый аргумент %s",
+		"%s (%s) мәнін %s үшін бүтін аргумент ретінде қолдануға болмайды",
+	},
+	JyimArg: {
+		"cannot use %s (%s) as j'i'm argument to %s",
+		"нельзя использовать %s (%s) как аргумент j'i'm для %s",
+		"%s (%s) мәнін %s үшін j'i'm аргументі ретінде қолдануға болмайды",
+	},
+	InvalidArg: {
+		"invalid argument %s (%s) for %s",
+		"недопустимый аргумент %s (%s) для %s",
+		"%[3]s үшін жарамсыз аргумент %[1]s (%[2]s)",
+	},
+	ParallelAssign: {
+		"cannot assign to %s inside %s: it is declared outside",
+		"нельзя присваивать %s внутри %s: переменная объявлена снаружи",
+		"%[2]s ішінде %[1]s мәнін беруге болмайды: ол сыртта жарияланған",
+	},
+	ParallelPointer: {
+		"cannot use pointer %s (%s) in %s: pointers cannot be shared between threads",
+		"нельзя использовать указатель %s (%s) в %s: указатели нельзя разделять между потоками",
+		"%[3]s ішінде %[1]s (%[2]s) көрсеткішін қолдануға болмайды: көрсеткіштерді ағындар арасында бөлісуге болмайды",
+	},
+	ParallelEffect: {
+		"%s assigns %s, which is declared outside it, so it cannot run in parallel",
+		"%s присваивает %s, объявленной вне функции, поэтому её нельзя выполнять параллельно",
+		"%s өзінен тыс жарияланған %s мәнін береді, сондықтан оны қатар орындауға болмайды",
+	},
+	ReturnInParallel: {
+		"%s cannot leave %s",
+		"%s не может выйти из %s",
+		"%s %s ішінен шыға алмайды",
+	},
+	TaskOutsideKut: {
+		"%s outside a %s block",
+		"%s вне блока %s",
+		"%s %s блогынан тыс",
+	},
+	TaskFunc: {
+		"%s needs a call of a function declared outside the %s block, not %s",
+		"%s требует вызова функции, объявленной вне блока %s, а не %s",
+		"%s %s блогынан тыс жарияланған функцияның шақыруын қажет етеді, %s емес",
+	},
+	TaskTarget: {
+		"the result of %s must go to a variable declared outside the %s block, not %s",
+		"результат %s должен сохраняться в переменную, объявленную вне блока %s, а не в %s",
+		"%s нәтижесі %s блогынан тыс жарияланған айнымалыға жазылуы керек, %s емес",
+	},
+	UnknownReduction: {
+		"unknown reduction %s (want sum, min, max or collect)",
+		"неизвестная свёртка %s (нужна sum, min, max или collect)",
+		"белгісіз жинақтау %s (qosyndy, kishi, úlken немесе jına керек)",
+	},
+	ReductionValue: {
+		"%s %s needs a body that ends in a value",
+		"%s %s требует тела, которое заканчивается значением",
+		"%s %s мәнмен аяқталатын денені қажет етеді",
+	},
+	ReductionType: {
+		"invalid reduction %s of %s values (want a number)",
+		"недопустимая свёртка %s значений %s (нужно число)",
+		"%[2]s мәндерінің жарамсыз жинақтауы %[1]s (сан керек)",
+	},
+	ChanArg: {
+		"cannot use %s (%s) as %s argument to %s",
+		"нельзя использовать %s (%s) как аргумент %s для %s",
+		"%[1]s (%[2]s) мәнін %[4]s үшін %[3]s аргументі ретінде қолдануға болмайды",
+	},
+	TimeoutOutside: {
+		"timeout outside a %s clause",
+		"timeout вне ветки %s",
+		"timeout %s тармағынан тыс",
+	},
+	DuplicateClause: {
+		"%s has more than one timeout or %s clause",
+		"у %s больше одной ветки timeout или %s",
+		"%s ішінде бірден көп timeout немесе %s тармағы бар",
+	},
+	MixedScript: {
+		"%s mixes %s and %s letters",
+		"в имени %s смешаны алфавиты: %s и %s",
+		"%s атауында әліпбилер араласқан: %s және %s",
+	},
+
+	TypeMismatch: {
+		"type mismatch: %s %s %s",
+		"несоответствие типов: %s %s %s",
+		"типтер сәйкес емес: %s %s %s",
+	},
+	UnknownOp: {
+		"unknown operator: %s",
+		"неизвестный оператор: %s",
+		"белгісіз оператор: %s",
+	},
+	ShiftCount: {
+		"invalid shift count %s",
+		"недопустимая величина сдвига %s",
+		"жарамсыз жылжыту мөлшері %s",
+	},
+	DivisionByZero: {
+		"division by zero",
+		"деление на ноль",
+		"нөлге бөлу",
+	},
+	PrintfArgs: {
+		"printf: want (f64, san), got (%s, %s)",
+		"printf: нужно (f64, san), получено (%s, %s)",
+		"printf: (f64, san) керек, (%s, %s) берілді",
+	},
+	NumberArg: {
+		"%s: argument must be a number, got %s",
+		"%s: аргумент должен быть числом, получено %s",
+		"%s: аргумент сан болуы керек, %s берілді",
+	},
+	InvalidLength: {
+		"%s: invalid length %s",
+		"%s: недопустимая длина %s",
+		"%s: жарамсыз ұзындық %s",
+	},
+	LenArg: {
+		"len: argument not supported, got %s",
+		"len: аргумент не поддерживается, получено %s",
+		"len: бұл аргумент қолданылмайды, %s берілді",
+	},
+	FirstJyimArg: {
+		"%s: first argument must be j'i'm, got %s",
+		"%s: первый аргумент должен быть j'i'm, получено %s",
+		"%s: бірінші аргумент j'i'm болуы керек, %s берілді",
+	},
+	JyimArgRT: {
+		"%s: argument must be j'i'm, got %s",
+		"%s: аргумент должен быть j'i'm, получено %s",
+		"%s: аргумент j'i'm болуы керек, %s берілді",
+	},
+	IndexArgs: {
+		"index: want (j'i'm, san), got (%s, %s)",
+		"index: нужно (j'i'm, san), получено (%s, %s)",
+		"index: (j'i'm, san) керек, (%s, %s) берілді",
+	},
+	OutOfRange: {
+		"index out of range [%d] with length %d",
+		"индекс вне диапазона [%d] при длине %d",
+		"индекс ауқымнан тыс [%d], ұзындығы %d",
+	},
+	BuiltinArgCount: {
+		"wrong number of arguments to %s: have %d, want %s",
+		"неверное число аргументов %s: передано %d, нужно %s",
+		"%s аргументтерінің саны қате: %d берілді, %s керек",
+	},
+	AssertionFailed: {
+		"assertion failed",
+		"утверждение не выполнено",
+		"тексеру сәтсіз аяқталды",
+	},
+	AssertionMessage: {
+		"assertion failed: %s",
+		"утверждение не выполнено: %s",
+		"тексеру сәтсіз аяқталды: %s",
+	},
+	IndexNotSan: {
+		"%s: index must be san, got %s",
+		"%s: индекс должен быть san, получено %s",
+		"%s: индекс san болуы керек, %s берілді",
+	},
+	CannotEvaluate: {
+		"cannot evaluate %T",
+		"невозможно вычислить %T",
+		"%T есептеу мүмкін емес",
+	},
+	NotIndexable: {
+		"cannot index %s",
+		"нельзя индексировать %s",
+		"%s индекстеуге болмайды",
+	},
+	BadIndex: {
+		"invalid index %s",
+		"недопустимый индекс %s",
+		"жарамсыз индекс %s",
+	},
+	NotPointer: {
+		"cannot dereference %s",
+		"нельзя разыменовать %s",
+		"%s сілтемесін ашуға болмайды",
+	},
+	NonBoolCondition: {
+		"non-aqıqat condition %s (%s)",
+		"условие %s (%s) не aqıqat",
+		"%s (%s) шарты aqıqat емес",
+	},
+	NotModulRT: {
+		"%s is not a modul",
+		"%s не является modul",
+		"%s modul емес",
+	},
+	NotCallable: {
+		"not a function: %s",
+		"не функция: %s",
+		"функция емес: %s",
+	},
+	ConvertRT: {
+		"cannot convert %s to %s",
+		"нельзя преобразовать %s в %s",
+		"%s мәнін %s түріне түрлендіруге болмайды",
+	},
+	OutOfMemory: {
+		"out of memory",
+		"недостаточно памяти",
+		"жады жеткіліксіз",
+	},
+	NegativeLength: {
+		"negative array length",
+		"отрицательная длина массива",
+		"массив ұзындығы теріс",
+	},
+	IndexNotSupported: {
+		"index operator not supported: %s",
+		"оператор индексации не поддерживается: %s",
+		"индекстеу операторы қолданылмайды: %s",
+	},
+	StackOverflow: {
+		"stack overflow: more than %d nested calls",
+		"переполнение стека: более %d вложенных вызовов",
+		"стек толып кетті: %d-ден астам ішкі шақыру",
+	},
+	EmptyReduction: {
+		"%s of an empty range",
+		"%s пустого диапазона",
+		"бос аралықтың %s мәні",
+	},
+	SendClosed: {
+		"send on a closed channel",
+		"send в закрытый канал",
+		"жабық арнаға send",
+	},
+	CloseClosed: {
+		"close of a closed channel",
+		"close закрытого канала",
+		"жабық арнаны close",
+	},
+	ChanNotMade: {
+		"%s on a channel that was not made",
+		"%s на канале, который не был создан",
+		"жасалмаған арнада %s",
+	},
+	Deadlock: {
+		"deadlock: every task is blocked",
+		"взаимная блокировка: все задачи заблокированы",
+		"өзара бұғаттау: барлық тапсырма бұғатталған",
+	},
+	NegativeCapacity: {
+		"negative channel capacity %d",
+		"отрицательная ёмкость канала %d",
+		"арна сыйымдылығы теріс: %d",
+	},
+
+	BackendType: {
+		"type %s is not supported in %s",
+		"тип %s не поддерживается в %s",
+		"%[2]s %[1]s түріне қолдау көрсетілмейді",
+	},
+	BackendUnsupported: {
+		"%s is not supported in %s",
+		"%s не поддерживается в %s",
+		"%[2]s %[1]s қолдау көрсетілмейді",
+	},
+	BackendInterpret: {
+		"%s is not supported in %s; run the program with the interpreter or build it",
+		"%s не поддерживается в %s; запустите программу интерпретатором или соберите её",
+		"%[2]s %[1]s қолдау көрсетілмейді; бағдарламаны интерпретатормен іске қосыңыз немесе жинаңыз",
+	},
+	BackendNested: {
+		"nested function %s is not supported in %s; declare it at top level",
+		"вложенная функция %s не поддерживается в %s; объявите её на верхнем уровне",
+		"%[2]s %[1]s ішкі функциясына қолдау көрсетілмейді; оны жоғарғы деңгейде жариялаңыз",
+	},
+	BackendFuncLit: {
+		"function literals are not supported in %s",
+		"функциональные литералы не поддерживаются в %s",
+		"%s функция литералдарына қолдау көрсетілмейді",
+	},
+	BackendDirectCall: {
+		"only direct calls of top-level functions are supported in %s",
+		"в %s поддерживаются только прямые вызовы функций верхнего уровня",
+		"%s тек жоғарғы деңгейдегі функцияларды тікелей шақыруға болады",
+	},
+	BackendBuiltin: {
+		"built-in %s is not supported in %s",
+		"встроенная функция %s не поддерживается в %s",
+		"%[2]s %[1]s кіріктірілген функциясына қолдау көрсетілмейді",
+	},
+	BackendOperator: {
+		"unsupported operator %s in %s",
+		"неподдерживаемый оператор %s в %s",
+		"%[2]s %[1]s операторына қолдау көрсетілмейді",
+	},
+	BackendOperatorOn: {
+		"operator %s on %s is not supported in %s",
+		"оператор %s для %s не поддерживается в %s",
+		"%[3]s %[2]s үшін %[1]s операторына қолдау көрсетілмейді",
+	},
+	BackendExpression: {
+		"unsupported expression %s in %s",
+		"неподдерживаемое выражение %s в %s",
+		"%[2]s %[1]s өрнегіне қолдау көрсетілмейді",
+	},
+	BackendUse: {
+		"cannot use %s as %s in %s",
+		"нельзя использовать %s как %s в %s",
+		"%[3]s %[1]s мәнін %[2]s ретінде қолдануға болмайды",
+	},
+	BackendUseTyped: {
+		"cannot use %s (%s) as %s in %s",
+		"нельзя использовать %s (%s) как %s в %s",
+		"%[4]s %[1]s (%[2]s) мәнін %[3]s ретінде қолдануға болмайды",
+	},
+	BackendChannel: {
+		"cannot use %s (%s) as a channel in %s",
+		"нельзя использовать %s (%s) как канал в %s",
+		"%[3]s %[1]s (%[2]s) мәнін арна ретінде қолдануға болмайды",
+	},
+	BackendPrint: {
+		"cannot print %s (%s) in %s",
+		"нельзя вывести %s (%s) в %s",
+		"%[3]s %[1]s (%[2]s) мәнін шығаруға болмайды",
+	},
+	BackendIndex: {
+		"cannot index %s (%s) in %s",
+		"нельзя индексировать %s (%s) в %s",
+		"%[3]s %[1]s (%[2]s) мәнін индекстеуге болмайды",
+	},
+	BackendLen: {
+		"cannot take the length of %s (%s) in %s",
+		"нельзя получить длину %s (%s) в %s",
+		"%[3]s %[1]s (%[2]s) ұзындығын алуға болмайды",
+	},
+	BackendAssign: {
+		"cannot assign to %s in %s",
+		"нельзя присвоить значение %s в %s",
+		"%[2]s %[1]s мән беруге болмайды",
+	},
+	BackendEvaluate: {
+		"cannot evaluate %s in %s",
+		"нельзя вычислить %s в %s",
+		"%[2]s %[1]s есептеуге болмайды",
+	},
+	BackendNotValue: {
+		"%s cannot be used as a value in %s",
+		"%s нельзя использовать как значение в %s",
+		"%[2]s %[1]s мән ретінде қолдануға болмайды",
+	},
+	BackendCallOnly: {
+		"%s can only be called in %s",
+		"%s можно только вызывать в %s",
+		"%[2]s %[1]s тек шақыруға болады",
+	},
+	BackendGeneric: {
+		"generic function %s can only be called in %s",
+		"обобщённую функцию %s можно только вызывать в %s",
+		"%[2]s %[1]s жалпылама функциясын тек шақыруға болады",
+	},
+	BackendEgerValue: {
+		"eger used as a value must have a value in each branch in %s",
+		"eger, используемый как значение, должен иметь значение в каждой ветви в %s",
+		"%s мән ретінде қолданылған eger әр тармағында мән беруі керек",
+	},
+	BackendEgerSingle: {
+		"eger used as a value must have a single expression in each branch in %s",
+		"eger, используемый как значение, должен содержать одно выражение в каждой ветви в %s",
+		"%s мән ретінде қолданылған eger әр тармағында бір өрнектен тұруы керек",
+	},
+	PGOInvalid: {
+		"%s: not a profile: %v",
+		"%s: не профиль: %v",
+		"%s: профиль емес: %v",
+	},
+	PGOVersion: {
+		"%s: unsupported profile version %d",
+		"%s: неподдерживаемая версия профиля %d",
+		"%s: профильдің %d нұсқасына қолдау жоқ",
+	},
+	PGOMode: {
+		"unknown PGO mode %q (want gen or use)",
+		"неизвестный режим PGO %q (нужен gen или use)",
+		"белгісіз PGO режимі %q (gen немесе use керек)",
+	},
+	PGOStale: {
+		"%s: profile of other sources than %s (record it again with -pgo=gen)",
+		"%s: профиль других исходников, чем %s (запишите его заново с -pgo=gen)",
+		"%s: %s емес, басқа бастапқы кодтың профилі (оны -pgo=gen арқылы қайта жазыңыз)",
+	},
+	PGORecord: {
+		"%v (build with -pgo=gen and run the binary to record a profile)",
+		"%v (соберите с -pgo=gen и запустите программу, чтобы записать профиль)",
+		"%v (профильді жазу үшін -pgo=gen арқылы құрастырып, бағдарламаны іске қосыңыз)",
+	},
+	PGODemo: {
+		"%s: -pgo needs tenge code, and this demo is hand-written C",
+		"%s: -pgo нужен код на tenge, а это демо написано на C вручную",
+		"%s: -pgo үшін tenge коды керек, ал бұл демо C тілінде қолмен жазылған",
+	},
+	NotBytecode: {
+		"not a tenge bytecode file",
+		"не файл байт-кода tenge",
+		"tenge байт-код файлы емес",
+	},
+	BytecodeTruncated: {
+		"bytecode file is truncated",
+		"файл байт-кода обрезан",
+		"байт-код файлы қысқартылған",
+	},
+	BytecodeChecksum: {
+		"bytecode checksum mismatch: the file is damaged",
+		"контрольная сумма байт-кода не совпадает: файл повреждён",
+		"байт-кодтың бақылау сомасы сәйкес емес: файл бүлінген",
+	},
+	BytecodeVersion: {
+		"bytecode format %d is not supported (want %d); recompile the program",
+		"формат байт-кода %d не поддерживается (нужен %d); скомпилируйте программу заново",
+		"%d байт-код пішіміне қолдау жоқ (%d керек); бағдарламаны қайта компиляциялаңыз",
+	},
+	BadBytecode: {
+		"bad bytecode file: %v",
+		"неверный файл байт-кода: %v",
+		"жарамсыз байт-код файлы: %v",
+	},
+	BytecodeTrailing: {
+		"trailing data",
+		"лишние данные в конце",
+		"соңында артық деректер",
+	},
+	BytecodeEnd: {
+		"unexpected end of data",
+		"неожиданный конец данных",
+		"деректер күтпеген жерде аяқталды",
+	},
+	BytecodeGlobals: {
+		"negative global count",
+		"отрицательное число глобальных переменных",
+		"ғаламдық айнымалылар саны теріс",
+	},
+	BytecodeInit: {
+		"bad init function %d",
+		"неверная функция инициализации %d",
+		"%d инициализация функциясы жарамсыз",
+	},
+	BytecodeMain: {
+		"bad main function %d",
+		"неверная функция main %d",
+		"%d main функциясы жарамсыз",
+	},
+	BytecodeResults: {
+		"func %s: returns values of different banks",
+		"функция %s: возвращает значения разных банков",
+		"%s функциясы: әртүрлі банктердің мәндерін қайтарады",
+	},
+	BytecodeFunc: {
+		"func %s: %v",
+		"функция %s: %v",
+		"%s функциясы: %v",
+	},
+	BytecodeRegs: {
+		"negative register count",
+		"отрицательное число регистров",
+		"регистрлер саны теріс",
+	},
+	BytecodeParam: {
+		"bad parameter %s",
+		"неверный параметр %s",
+		"%s параметрі жарамсыз",
+	},
+	BytecodeCallFunc: {
+		"call %d: bad function %d",
+		"вызов %d: неверная функция %d",
+		"%d шақыру: %d функциясы жарамсыз",
+	},
+	BytecodeCallArgs: {
+		"call %d: %d arguments for %d parameters",
+		"вызов %d: %d аргументов для %d параметров",
+		"%d шақыру: %[3]d параметрге %[2]d аргумент",
+	},
+	BytecodeCallResult: {
+		"call %d: bad result %s",
+		"вызов %d: неверный результат %s",
+		"%d шақыру: %s нәтижесі жарамсыз",
+	},
+	BytecodeCallArg: {
+		"call %d: bad argument %s",
+		"вызов %d: неверный аргумент %s",
+		"%d шақыру: %s аргументі жарамсыз",
+	},
+	BytecodeNoCode: {
+		"no code",
+		"нет кода",
+		"код жоқ",
+	},
+	BytecodeCodeEnd: {
+		"code does not end in a jump or return",
+		"код не заканчивается переходом или возвратом",
+		"код секіріспен немесе қайтарумен аяқталмайды",
+	},
+	BytecodeOpcode: {
+		"%d: bad opcode %d",
+		"%d: неверный код операции %d",
+		"%d: %d операция коды жарамсыз",
+	},
+	BytecodeOperand: {
+		"%d: operand %d of %s out of range",
+		"%d: операнд %d инструкции %s вне диапазона",
+		"%d: %[3]s нұсқаулығының %[2]d операнды ауқымнан тыс",
+	},
+	UnknownProfile: {
+		"unknown build profile %q (want release, debug or hardened)",
+		"неизвестный профиль сборки %q (нужен release, debug или hardened)",
+		"белгісіз құрастыру профилі %q (release, debug немесе hardened керек)",
+	},
+	UnknownPass: {
+		"unknown pass %q (want %s or none)",
+		"неизвестный проход %q (нужен %s или none)",
+		"белгісіз өту %q (%s немесе none керек)",
+	},
+	EndOfFile: {
+		"end of file",
+		"конец файла",
+		"файл соңы",
+	},
+	InDecl: {
+		"in declaration of %s",
+		"в объявлении %s",
+		"%s жариялауында",
+	},
+	InReturn: {
+		"in return",
+		"в возвращаемом значении",
+		"қайтарылатын мәнде",
+	},
+	InAssign: {
+		"in assignment",
+		"в присваивании",
+		"мән беруде",
+	},
+	InCondition: {
+		"in condition",
+		"в условии",
+		"шартта",
+	},
+	InArgument: {
+		"in argument to %s",
+		"в аргументе %s",
+		"%s аргументінде",
+	},
+	OneOrTwo: {
+		"1 or 2",
+		"1 или 2",
+		"1 немесе 2",
+	},
+	Range: {
+		"%d to %d",
+		"от %d до %d",
+		"%d–%d",
+	},
+	Latin: {
+		"Latin",
+		"латиница",
+		"латын",
+	},
+	Cyrillic: {
+		"Cyrillic",
+		"кириллица",
+		"кирилл",
+	},
+	Greek: {
+		"Greek",
+		"греческий",
+		"грек",
+	},
+	ErrorLabel: {
+		"ERROR",
+		"ОШИБКА",
+		"QATE",
+	},
+	SeverityError: {
+		"error",
+		"ошибка",
+		"қате",
+	},
+	SeverityWarning: {
+		"warning",
+		"предупреждение",
+		"ескерту",
+	},
+	NoteLabel: {
+		"note",
+		"примечание",
+		"ескертпе",
+	},
+	HelpLabel: {
+		"help",
+		"подсказка",
+		"кеңес",
+	},
+	DidYouMean: {
+		"did you mean `%s`?",
+		"возможно, имелось в виду `%s`?",
+		"`%s` деп жазғыңыз келді ме?",
+	},
+	PreviousDecl: {
+		"previous declaration of %s",
+		"предыдущее объявление %s",
+		"%s алдыңғы жариялауы",
+	},
+	BlockOpened: {
+		"block opened here",
+		"блок открыт здесь",
+		"блок осында ашылған",
+	},
+	DeclaredHere: {
+		"%s is declared here",
+		"%s объявлено здесь",
+		"%s осында жарияланған",
+	},
+	InRange: {
+		"in range",
+		"в диапазоне",
+		"аралықта",
+	},
+	AssignedHere: {
+		"%s is assigned here",
+		"%s присваивается здесь",
+		"%s мәні осында беріледі",
+	},
+	BlockedIn: {
+		"blocked in %s",
+		"заблокирована в %s",
+		"%s ішінде бұғатталған",
+	},
+	InVM: {
+		"the VM",
+		"виртуальной машине",
+		"виртуалды машинада",
+	},
+	InIR: {
+		"the IR",
+		"промежуточном представлении",
+		"аралық көріністе",
+	},
+	InCompiled: {
+		"compiled code",
+		"скомпилированном коде",
+		"компиляцияланған кодта",
+	},
+}
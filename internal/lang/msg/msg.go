@@ -0,0 +1,96 @@
+// FILE: internal/lang/msg/msg.go
+
+// Package msg is the catalog of diagnostics. Every message of the lexer,
+// parser, module loader, type checker and runtimes is identified by a
+// Code and translated into Kazakh, Russian and English:
+//
+//	p.errorf(tok, msg.Undefined, name)   // "undefined: x" / "анықталмаған: x"
+//
+// Codes are stable: E01xx are syntax errors, E02xx module errors, E03xx
+// type errors, E04xx runtime errors, E05xx backend errors, E06xx errors in
+// the files and flags of the tools and W03xx type checker warnings.
+// Codes starting with T are phrases used inside other messages.
+//
+// The language is process-wide and chosen once at startup with SetLang,
+// from the -lang flag or the TENGE_LANG environment variable. It defaults
+// to English.
+package msg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lang is a message language.
+type Lang int
+
+const (
+	EN Lang = iota
+	RU
+	KK
+)
+
+func (l Lang) String() string {
+	switch l {
+	case RU:
+		return "ru"
+	case KK:
+		return "kk"
+	}
+	return "en"
+}
+
+// ParseLang parses a language tag such as "kk", "ru-RU" or "en_US.UTF-8".
+// Only the language part is used; "kz" is accepted for Kazakh.
+func ParseLang(tag string) (Lang, bool) {
+	tag = strings.ToLower(tag)
+	if i := strings.IndexAny(tag, "-_."); i >= 0 {
+		tag = tag[:i]
+	}
+	switch tag {
+	case "en":
+		return EN, true
+	case "ru":
+		return RU, true
+	case "kk", "kz":
+		return KK, true
+	}
+	return EN, false
+}
+
+var lang = EN
+
+// SetLang selects the language of all later messages. It reports false,
+// leaving the language unchanged, for an unknown tag.
+func SetLang(tag string) bool {
+	l, ok := ParseLang(tag)
+	if ok {
+		lang = l
+	}
+	return ok
+}
+
+// Current returns the selected language.
+func Current() Lang { return lang }
+
+// Code identifies a message.
+type Code string
+
+// Text returns the message code in the selected language, falling back to
+// English for a missing translation.
+func Text(code Code) string {
+	e, ok := catalog[code]
+	if !ok {
+		return string(code)
+	}
+	if s := e[lang]; s != "" {
+		return s
+	}
+	return e[EN]
+}
+
+// Sprintf formats the message code with args. Translations may reorder
+// the arguments with explicit indexes such as %[2]s.
+func Sprintf(code Code, args ...interface{}) string {
+	return fmt.Sprintf(Text(code), args...)
+}
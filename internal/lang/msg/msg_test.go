@@ -0,0 +1,153 @@
+// FILE: internal/lang/msg/msg_test.go
+
+package msg
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// codes returns the codes declared in catalog.go by their names.
+func codes(t *testing.T) map[string]Code {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "catalog.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decl := make(map[string]Code)
+	for _, d := range f.Decls {
+		g, ok := d.(*ast.GenDecl)
+		if !ok || g.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range g.Specs {
+			v := spec.(*ast.ValueSpec)
+			for i, name := range v.Names {
+				lit := v.Values[i].(*ast.BasicLit)
+				s, _ := strconv.Unquote(lit.Value)
+				decl[name.Name] = Code(s)
+			}
+		}
+	}
+	return decl
+}
+
+// TestCatalog checks that every code is unique and has a text in every
+// language, and that the translations format the same arguments with the
+// same verbs as the English text.
+func TestCatalog(t *testing.T) {
+	decl := codes(t)
+	seen := make(map[Code]string)
+	for name, code := range decl {
+		if other, ok := seen[code]; ok {
+			t.Errorf("%s and %s are both %s", name, other, code)
+		}
+		seen[code] = name
+		texts, ok := catalog[code]
+		if !ok {
+			t.Errorf("%s (%s) is not in the catalog", name, code)
+			continue
+		}
+		want := verbs(texts[EN])
+		for _, l := range []Lang{EN, RU, KK} {
+			if texts[l] == "" {
+				t.Errorf("%s (%s) has no %s text", name, code, l)
+				continue
+			}
+			if got := verbs(texts[l]); got != want {
+				t.Errorf("%s (%s): %s text %q formats %s, English %s", name, code, l, texts[l], got, want)
+			}
+		}
+	}
+	if len(seen) != len(catalog) {
+		t.Errorf("catalog has %d entries for %d codes", len(catalog), len(seen))
+	}
+}
+
+// verbs returns the verbs of format by argument, as in "1:s 2:d".
+func verbs(format string) string {
+	byArg := make(map[int]byte)
+	arg, last := 1, 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0123456789.", format[i]) >= 0 {
+			i++
+		}
+		if i < len(format) && format[i] == '[' {
+			end := strings.IndexByte(format[i:], ']')
+			n, _ := strconv.Atoi(format[i+1 : i+end])
+			arg, i = n, i+end+1
+		}
+		if i >= len(format) || format[i] == '%' {
+			continue
+		}
+		byArg[arg] = format[i]
+		last = max(last, arg)
+		arg++
+	}
+	var b strings.Builder
+	for n := 1; n <= last; n++ {
+		if v, ok := byArg[n]; ok {
+			b.WriteString(strconv.Itoa(n) + ":" + string(v) + " ")
+		} else {
+			b.WriteString(strconv.Itoa(n) + ":- ")
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func TestVerbs(t *testing.T) {
+	tests := []struct{ format, want string }{
+		{"no verbs", ""},
+		{"100%% of %s", "1:s"},
+		{"%s: %d of %q", "1:s 2:d 3:q"},
+		{"%[2]d then %[1]s", "1:s 2:d"},
+		{"%d: %[3]s of %[2]d", "1:d 2:d 3:s"},
+		{"%8.2f", "1:f"},
+		{"only %[2]s", "1:- 2:s"},
+	}
+	for _, tt := range tests {
+		if got := verbs(tt.format); got != tt.want {
+			t.Errorf("verbs(%q) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestParseLang(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want Lang
+		ok   bool
+	}{
+		{"kk", KK, true},
+		{"kz", KK, true},
+		{"ru-RU", RU, true},
+		{"en_US.UTF-8", EN, true},
+		{"EN", EN, true},
+		{"de", EN, false},
+		{"", EN, false},
+	}
+	for _, tt := range tests {
+		if got, ok := ParseLang(tt.tag); got != tt.want || ok != tt.ok {
+			t.Errorf("ParseLang(%q) = %s, %v, want %s, %v", tt.tag, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	defer func(l Lang) { lang = l }(lang)
+	lang = KK
+	if got, want := Sprintf(Undefined, "x"), "анықталмаған: x"; got != want {
+		t.Errorf("Sprintf(Undefined, x) in Kazakh = %q, want %q", got, want)
+	}
+	if got := Text("E9999"); got != "E9999" {
+		t.Errorf("Text of an unknown code = %q, want the code", got)
+	}
+}
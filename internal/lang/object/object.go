@@ -3,8 +3,14 @@
 package object
 
 import (
+	"bytes"
 	"fmt"
-	//"github.com/DauletBai/tenge/internal/lang/ast"
+	"strconv"
+	"strings"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/token"
 	"github.com/shopspring/decimal"
 )
 
@@ -13,12 +19,21 @@ type ObjectType string
 
 // All object types are now based on the tenge language keywords.
 const (
-	SAN_OBJ    = "SAN"
-	AQSHA_OBJ  = "AQSHA"
-	AQIQAT_OBJ = "AQIQAT"
-	NULL_OBJ   = "NULL"
-	QAITAR_VAL = "QAITAR_VAL"
-	ERROR_OBJ  = "ERROR"
+	SAN_OBJ     = "SAN"
+	I32_OBJ     = "I32"
+	U64_OBJ     = "U64"
+	F64_OBJ     = "F64"
+	AQSHA_OBJ   = "AQSHA"
+	AQIQAT_OBJ  = "AQIQAT"
+	JOL_OBJ     = "JOL"
+	JYIM_OBJ    = "JYIM"
+	POINTER_OBJ = "POINTER"
+	ATQARM_OBJ  = "ATQARM"
+	BUILTIN_OBJ = "BUILTIN"
+	MODULE_OBJ  = "MODULE"
+	NULL_OBJ    = "NULL"
+	QAITAR_VAL  = "QAITAR_VAL"
+	ERROR_OBJ   = "ERROR"
 )
 
 // Singleton instances for common values, named after the language's philosophy.
@@ -40,13 +55,23 @@ type Object interface {
 type San struct {
 	Value int64
 }
+
 func (s *San) Type() ObjectType { return SAN_OBJ }
 func (s *San) Inspect() string  { return fmt.Sprintf("%d", s.Value) }
 
+// I32 represents a 32-bit signed integer; arithmetic on it wraps.
+type I32 struct {
+	Value int32
+}
+
+func (i *I32) Type() ObjectType { return I32_OBJ }
+func (i *I32) Inspect() string  { return strconv.FormatInt(int64(i.Value), 10) }
+
 // Aqsha represents a decimal object for financial calculations.
 type Aqsha struct {
 	Value decimal.Decimal
 }
+
 func (a *Aqsha) Type() ObjectType { return AQSHA_OBJ }
 func (a *Aqsha) Inspect() string  { return a.Value.String() }
 
@@ -54,6 +79,7 @@ func (a *Aqsha) Inspect() string  { return a.Value.String() }
 type Aqıqat struct {
 	Value bool
 }
+
 func (a *Aqıqat) Type() ObjectType { return AQIQAT_OBJ }
 func (a *Aqıqat) Inspect() string {
 	if a.Value {
@@ -64,29 +90,196 @@ func (a *Aqıqat) Inspect() string {
 
 // Null represents the absence of a value.
 type Null struct{}
+
 func (n *Null) Type() ObjectType { return NULL_OBJ }
 func (n *Null) Inspect() string  { return "null" }
 
-// QaıtarValue is a wrapper to handle return values.
+// QaıtarValue is a wrapper to handle return values. For `qaıtar f(args)`
+// with f a user function, Call holds f and its arguments instead of the
+// result: the function returning runs it, as a jump back to its start
+// when f is that function, so that tail recursion does not grow the
+// stack.
 type QaıtarValue struct {
 	Value Object
+	Call  *TailCall
 }
+
 func (qv *QaıtarValue) Type() ObjectType { return QAITAR_VAL }
-func (qv *QaıtarValue) Inspect() string  { return qv.Value.Inspect() }
+func (qv *QaıtarValue) Inspect() string {
+	if qv.Call != nil {
+		return qv.Call.Fn.Inspect()
+	}
+	return qv.Value.Inspect()
+}
+
+// TailCall is a call left for the caller of the function making it.
+type TailCall struct {
+	Fn   *Atqarm
+	Args []Object
+	At   token.Token // the call, for errors without a position
+}
 
 // Error represents a runtime error.
 type Error struct {
 	Message string
 }
+
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return "QATE: " + e.Message } // QATE: Kazakh for Error
+func (e *Error) Inspect() string  { return msg.Text(msg.ErrorLabel) + ": " + e.Message }
+
+// U64 represents an unsigned 64-bit integer (wrapping arithmetic,
+// logical shifts), used by bit-twiddling code such as RNGs.
+type U64 struct {
+	Value uint64
+}
+
+func (u *U64) Type() ObjectType { return U64_OBJ }
+func (u *U64) Inspect() string  { return strconv.FormatUint(u.Value, 10) }
+
+// F64 represents a binary floating-point number.
+type F64 struct {
+	Value float64
+}
+
+func (f *F64) Type() ObjectType { return F64_OBJ }
+func (f *F64) Inspect() string  { return strconv.FormatFloat(f.Value, 'g', -1, 64) }
+
+// Jol represents a string.
+type Jol struct {
+	Value string
+}
+
+func (j *Jol) Type() ObjectType { return JOL_OBJ }
+func (j *Jol) Inspect() string  { return j.Value }
+
+// Jyim represents an array (`j'i'm`).
+type Jyim struct {
+	Elements []Object
+}
+
+func (j *Jyim) Type() ObjectType { return JYIM_OBJ }
+func (j *Jyim) Inspect() string {
+	elems := make([]string, len(j.Elements))
+	for i, e := range j.Elements {
+		elems[i] = e.Inspect()
+	}
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
+// Pointer refers to a variable binding (`&x`).
+type Pointer struct {
+	Env  *Environment
+	Name string
+}
+
+func (p *Pointer) Type() ObjectType { return POINTER_OBJ }
+func (p *Pointer) Inspect() string  { return "&" + p.Name }
+
+// Load returns the value the pointer refers to.
+func (p *Pointer) Load() Object {
+	obj, _ := p.Env.Get(p.Name)
+	return obj
+}
+
+// Store replaces the value the pointer refers to.
+func (p *Pointer) Store(val Object) {
+	p.Env.Assign(p.Name, val)
+}
+
+// Atqarm represents a user-defined function closed over its environment.
+type Atqarm struct {
+	Literal *ast.AtqarmLiteral
+	Env     *Environment
+}
+
+func (a *Atqarm) Type() ObjectType { return ATQARM_OBJ }
+func (a *Atqarm) Inspect() string {
+	var out bytes.Buffer
+	out.WriteString("atqar'm")
+	if a.Literal.Name != "" {
+		out.WriteString(" " + a.Literal.Name)
+	}
+	params := make([]string, len(a.Literal.Parameters))
+	for i, p := range a.Literal.Parameters {
+		params[i] = p.String()
+	}
+	out.WriteString("(" + strings.Join(params, ", ") + ")")
+	return out.String()
+}
+
+// BuiltinFunction is the Go implementation of a built-in.
+type BuiltinFunction func(args ...Object) Object
+
+// Builtin represents a built-in function.
+type Builtin struct {
+	Name string
+	Fn   BuiltinFunction
+}
+
+func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
+func (b *Builtin) Inspect() string  { return "builtin " + b.Name }
+
+// Module is an imported module; its names are bound in Env.
+type Module struct {
+	Name string
+	Env  *Environment
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string  { return "modul " + m.Name }
 
 // --- Environment ---
 
+// Environment maps names to values and links to the enclosing scope.
 type Environment struct {
 	store map[string]Object
 	outer *Environment
 }
-func NewEnvironment() *Environment { /* ... */ return &Environment{store: make(map[string]Object)} }
-func (e *Environment) Get(name string) (Object, bool) { /* ... */ obj, ok := e.store[name]; if !ok && e.outer != nil { obj, ok = e.outer.Get(name) }; return obj, ok }
-func (e *Environment) Set(name string, val Object) Object { /* ... */ e.store[name] = val; return val }
\ No newline at end of file
+
+// NewEnvironment returns an empty top-level environment.
+func NewEnvironment() *Environment {
+	return &Environment{store: make(map[string]Object)}
+}
+
+// NewEnclosedEnvironment returns an empty environment nested in outer.
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	return env
+}
+
+// Get looks name up in e and its enclosing environments.
+func (e *Environment) Get(name string) (Object, bool) {
+	obj, ok := e.store[name]
+	if !ok && e.outer != nil {
+		obj, ok = e.outer.Get(name)
+	}
+	return obj, ok
+}
+
+// Set binds name in e itself, shadowing any outer binding.
+func (e *Environment) Set(name string, val Object) Object {
+	e.store[name] = val
+	return val
+}
+
+// Assign updates the innermost existing binding of name. It reports
+// false when name is not bound anywhere.
+func (e *Environment) Assign(name string, val Object) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			env.store[name] = val
+			return true
+		}
+	}
+	return false
+}
+
+// Names returns the names bound directly in e.
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	return names
+}
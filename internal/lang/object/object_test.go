@@ -0,0 +1,135 @@
+// FILE: internal/lang/object/object_test.go
+
+package object_test
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/lexer"
+	"github.com/DauletBai/tenge/internal/lang/object"
+	"github.com/DauletBai/tenge/internal/lang/parser"
+	"github.com/shopspring/decimal"
+)
+
+// function returns the function declared by src.
+func function(t *testing.T, src string) *ast.AtqarmLiteral {
+	t.Helper()
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("%s: %s", src, errs[0])
+	}
+	return program.Statements[0].(*ast.BekitStatement).Value.(*ast.AtqarmLiteral)
+}
+
+func TestInspect(t *testing.T) {
+	env := object.NewEnvironment()
+	fn := &object.Atqarm{Literal: function(t, "atqar'm add(a: san, b: []f64) -> san { qaıtar a }"), Env: env}
+	tests := []struct {
+		obj  object.Object
+		typ  object.ObjectType
+		want string
+	}{
+		{&object.San{Value: -42}, object.SAN_OBJ, "-42"},
+		{&object.I32{Value: math.MinInt32}, object.I32_OBJ, "-2147483648"},
+		{&object.U64{Value: math.MaxUint64}, object.U64_OBJ, "18446744073709551615"},
+		{&object.F64{Value: 0.1}, object.F64_OBJ, "0.1"},
+		{&object.F64{Value: 1e21}, object.F64_OBJ, "1e+21"},
+		{&object.F64{Value: 2}, object.F64_OBJ, "2"},
+		{&object.Aqsha{Value: decimal.RequireFromString("12.50")}, object.AQSHA_OBJ, "12.5"},
+		{object.JAN, object.AQIQAT_OBJ, "jan"},
+		{object.JYN, object.AQIQAT_OBJ, "j'n"},
+		{object.NULL, object.NULL_OBJ, "null"},
+		{&object.Jol{Value: "sálem"}, object.JOL_OBJ, "sálem"},
+		{&object.Jyim{Elements: []object.Object{
+			&object.San{Value: 1},
+			&object.Jyim{},
+			&object.Jyim{Elements: []object.Object{&object.Jol{Value: "a"}, object.JAN}},
+		}}, object.JYIM_OBJ, "[1, [], [a, jan]]"},
+		{&object.Pointer{Env: env, Name: "x"}, object.POINTER_OBJ, "&x"},
+		{fn, object.ATQARM_OBJ, "atqar'm add(a: san, b: []f64)"},
+		{&object.Builtin{Name: "len"}, object.BUILTIN_OBJ, "builtin len"},
+		{&object.Module{Name: "math", Env: env}, object.MODULE_OBJ, "modul math"},
+		{&object.Error{Message: "1:2: division by zero"}, object.ERROR_OBJ, "ERROR: 1:2: division by zero"},
+		{&object.QaıtarValue{Value: &object.San{Value: 7}}, object.QAITAR_VAL, "7"},
+		{&object.QaıtarValue{Call: &object.TailCall{Fn: fn}}, object.QAITAR_VAL, "atqar'm add(a: san, b: []f64)"},
+	}
+	for _, tt := range tests {
+		if got := tt.obj.Inspect(); got != tt.want {
+			t.Errorf("Inspect() = %q, want %q", got, tt.want)
+		}
+		if got := tt.obj.Type(); got != tt.typ {
+			t.Errorf("%s: Type() = %s, want %s", tt.want, got, tt.typ)
+		}
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	outer := object.NewEnvironment()
+	outer.Set("x", &object.San{Value: 1})
+	outer.Set("y", &object.San{Value: 2})
+	inner := object.NewEnclosedEnvironment(outer)
+	inner.Set("x", &object.San{Value: 10}) // shadows outer x
+
+	get := func(env *object.Environment, name string) string {
+		obj, ok := env.Get(name)
+		if !ok {
+			return "unbound"
+		}
+		return obj.Inspect()
+	}
+	if got := get(inner, "x") + " " + get(outer, "x") + " " + get(inner, "y"); got != "10 1 2" {
+		t.Errorf("x, outer x, y = %s, want 10 1 2", got)
+	}
+	if got := get(inner, "z"); got != "unbound" {
+		t.Errorf("z = %s, want unbound", got)
+	}
+
+	// Assign changes the innermost binding, which for y is the outer one.
+	if !inner.Assign("y", &object.San{Value: 20}) || !inner.Assign("x", &object.San{Value: 30}) {
+		t.Fatal("Assign of a bound name failed")
+	}
+	if got := get(outer, "y") + " " + get(outer, "x") + " " + get(inner, "x"); got != "20 1 30" {
+		t.Errorf("outer y, outer x, x = %s, want 20 1 30", got)
+	}
+	if inner.Assign("z", object.NULL) {
+		t.Error("Assign of an unbound name succeeded")
+	}
+	if _, ok := inner.Get("z"); ok {
+		t.Error("a failed Assign bound the name")
+	}
+
+	names := outer.Names()
+	sort.Strings(names)
+	if got := strings.Join(names, " "); got != "x y" {
+		t.Errorf("Names() = %s, want x y", got)
+	}
+	if got := inner.Names(); len(got) != 1 || got[0] != "x" {
+		t.Errorf("Names() of the inner environment = %v, want [x]", got)
+	}
+}
+
+// TestPointer checks that a pointer refers to the binding, not to the
+// value it had when the pointer was made.
+func TestPointer(t *testing.T) {
+	outer := object.NewEnvironment()
+	outer.Set("n", &object.San{Value: 1})
+	inner := object.NewEnclosedEnvironment(outer)
+	p := &object.Pointer{Env: inner, Name: "n"}
+
+	outer.Assign("n", &object.San{Value: 2})
+	if got := p.Load().Inspect(); got != "2" {
+		t.Errorf("Load() = %s after an assignment, want 2", got)
+	}
+	p.Store(&object.San{Value: 3})
+	if got, _ := outer.Get("n"); got.Inspect() != "3" {
+		t.Errorf("n = %s after Store, want 3", got.Inspect())
+	}
+	if got := inner.Names(); len(got) != 0 {
+		t.Errorf("Store bound %v in the inner environment", got)
+	}
+}
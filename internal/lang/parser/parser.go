@@ -0,0 +1,1092 @@
+// FILE: internal/lang/parser/parser.go
+
+package parser
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/diag"
+	"github.com/DauletBai/tenge/internal/lang/lexer"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/token"
+	"github.com/shopspring/decimal"
+)
+
+// Operator precedences, lowest first. They follow Go: multiplicative
+// operators (including shifts and '&') bind tighter than additive ones
+// (including '|' and '^').
+const (
+	_ int = iota
+	LOWEST
+	OR      // ||
+	AND     // &&
+	EQUALS  // == != < <= > >=
+	SUM     // + - | ^
+	PRODUCT // * / % << >> &
+	PREFIX  // -x !x &x *x
+	CALL    // f(x) a[i] m.x
+)
+
+var precedences = map[token.TokenType]int{
+	token.OR:         OR,
+	token.AND:        AND,
+	token.EQUAL:      EQUALS,
+	token.NOT_EQUAL:  EQUALS,
+	token.LESS:       EQUALS,
+	token.LESS_EQ:    EQUALS,
+	token.GREATER:    EQUALS,
+	token.GREATER_EQ: EQUALS,
+	token.PLUS:       SUM,
+	token.MINUS:      SUM,
+	token.PIPE:       SUM,
+	token.CARET:      SUM,
+	token.MULTIPLY:   PRODUCT,
+	token.DIVIDE:     PRODUCT,
+	token.MODULO:     PRODUCT,
+	token.SHL:        PRODUCT,
+	token.SHR:        PRODUCT,
+	token.AMPERSAND:  PRODUCT,
+	token.LPAREN:     CALL,
+	token.LBRACKET:   CALL,
+	token.DOT:        CALL,
+}
+
+// typeKeywords are the built-in type names that are lexed as keywords.
+var typeKeywords = []token.TokenType{
+	token.SAN, token.AQSHA, token.JOL, token.TANBA, token.AQIQAT, token.JYIM,
+}
+
+type (
+	prefixParseFn func() ast.Expression
+	infixParseFn  func(ast.Expression) ast.Expression
+)
+
+type Parser struct {
+	l      *lexer.Lexer
+	errors diag.List
+	lead   token.Token // first token of the last statement that starts with an identifier
+
+	// Error recovery: see sync.
+	pending bool        // an error was found in the current statement
+	errTok  token.Token // where the last error was found
+	stay    bool        // the next nextToken keeps curToken
+	depth   int         // nesting of blocks being parsed
+
+	prevToken token.Token
+	curToken  token.Token
+	peekToken token.Token
+
+	prefixParseFns map[token.TokenType]prefixParseFn
+	infixParseFns  map[token.TokenType]infixParseFn
+}
+
+func New(l *lexer.Lexer) *Parser {
+	p := &Parser{l: l}
+
+	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
+	p.registerPrefix(token.IDENT, p.parseIdentifier)
+	p.registerPrefix(token.KORSET, p.parseIdentifier)
+	for _, tt := range typeKeywords {
+		p.registerPrefix(tt, p.parseIdentifier) // conversions: san(x), aqsha(x)
+	}
+	p.registerPrefix(token.SAN_LIT, p.parseSanLiteral)
+	p.registerPrefix(token.AQSHA_LIT, p.parseAqshaLiteral)
+	p.registerPrefix(token.JOL_LIT, p.parseJolLiteral)
+	p.registerPrefix(token.JAN, p.parseAqıqatLiteral)
+	p.registerPrefix(token.JYN, p.parseAqıqatLiteral)
+	p.registerPrefix(token.BANG, p.parsePrefixExpression)
+	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.AMPERSAND, p.parsePrefixExpression)
+	p.registerPrefix(token.MULTIPLY, p.parsePrefixExpression)
+	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
+	p.registerPrefix(token.LBRACKET, p.parseJyimLiteral)
+	p.registerPrefix(token.EGER, p.parseEgerExpression)
+	p.registerPrefix(token.ATQARM, p.parseAtqarmLiteral)
+	p.registerPrefix(token.QATARLAS, p.parseQatarlasExpression)
+	p.registerPrefix(token.ARNA, p.parseChanType)
+
+	p.infixParseFns = make(map[token.TokenType]infixParseFn)
+	for tt, prec := range precedences {
+		if prec < CALL {
+			p.registerInfix(tt, p.parseInfixExpression)
+		}
+	}
+	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseSelectorExpression)
+
+	// Read two tokens, so curToken and peekToken are both set
+	p.nextToken()
+	p.nextToken()
+
+	return p
+}
+
+func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
+	p.prefixParseFns[tokenType] = fn
+}
+
+func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
+	p.infixParseFns[tokenType] = fn
+}
+
+// Errors returns the parse errors, each prefixed with its position
+// ("file:line:col", or "line:col" for unnamed input).
+func (p *Parser) Errors() []string {
+	return p.errors.Strings()
+}
+
+// Diagnostics returns the parse errors with their spans and suggestions.
+func (p *Parser) Diagnostics() diag.List {
+	return p.errors
+}
+
+func (p *Parser) nextToken() {
+	if p.stay {
+		p.stay = false
+		return
+	}
+	p.prevToken = p.curToken
+	p.curToken = p.peekToken
+	p.peekToken = p.l.NextToken()
+}
+
+func (p *Parser) curTokenIs(t token.TokenType) bool {
+	return p.curToken.Type == t
+}
+
+func (p *Parser) peekTokenIs(t token.TokenType) bool {
+	return p.peekToken.Type == t
+}
+
+// newlineBefore reports whether the peek token starts on a later line than
+// the current one. Like Go, a line break ends an expression unless the line
+// ends with an operator.
+func (p *Parser) newlineBefore() bool {
+	return p.peekToken.Line > p.curToken.Line
+}
+
+func (p *Parser) expectPeek(t token.TokenType) bool {
+	if p.peekTokenIs(t) {
+		p.nextToken()
+		return true
+	}
+	p.peekError(t)
+	return false
+}
+
+// errorf reports an error at tok. A misspelled keyword usually parses as
+// an identifier and fails later on the same line, as in `egr (x) {`, so
+// the error suggests the keyword closest to such an identifier.
+//
+// Like Go, only the first error on a line is reported: the rest are
+// usually caused by it.
+// keyword returns how tt is written in the syntax of the source, for
+// messages.
+func (p *Parser) keyword(tt token.TokenType) string {
+	return lexer.Spelling(tt, p.l.Syntax())
+}
+
+func (p *Parser) errorf(tok token.Token, code msg.Code, args ...interface{}) *diag.Diagnostic {
+	d := diag.New(diag.Error, tok, code, args...)
+	if p.lead.Type == token.IDENT && p.lead.File == tok.File && p.lead.Line == tok.Line && p.lead.Offset < tok.Offset {
+		d.Suggest(p.lead, lexer.Keywords())
+	}
+	if n := len(p.errors); n == 0 || p.errors[n-1].Span.File != tok.File || p.errors[n-1].Span.Line != tok.Line {
+		p.errors = append(p.errors, d)
+	}
+	p.pending, p.errTok = true, tok
+	return d
+}
+
+func (p *Parser) peekError(t token.TokenType) {
+	p.errorf(p.peekToken, msg.ExpectedToken, t, describe(p.peekToken))
+}
+
+func (p *Parser) noPrefixParseFnError(tok token.Token) {
+	p.errorf(tok, msg.Unexpected, describe(tok))
+}
+
+func describe(tok token.Token) string {
+	if tok.Type == token.EOF {
+		return msg.Text(msg.EndOfFile)
+	}
+	return fmt.Sprintf("%q", tok.Literal)
+}
+
+func (p *Parser) peekPrecedence() int {
+	if p, ok := precedences[p.peekToken.Type]; ok {
+		return p
+	}
+	return LOWEST
+}
+
+func (p *Parser) curPrecedence() int {
+	if p, ok := precedences[p.curToken.Type]; ok {
+		return p
+	}
+	return LOWEST
+}
+
+// ParseProgram parses the whole input. Check Errors() afterwards. A
+// statement with errors becomes an ast.BadStmt and parsing goes on with
+// the next one, so one run reports every independent error.
+func (p *Parser) ParseProgram() *ast.Program {
+	program := &ast.Program{}
+	program.Statements = []ast.Statement{}
+
+	for !p.curTokenIs(token.EOF) {
+		if p.curTokenIs(token.SEMICOLON) {
+			p.nextToken()
+			continue
+		}
+		stmt := p.parseStatement()
+		if stmt != nil {
+			program.Statements = append(program.Statements, stmt)
+		}
+		p.nextToken()
+	}
+	program.Comments = p.l.Comments()
+	program.Syntax = p.l.Syntax()
+	return program
+}
+
+func (p *Parser) parseStatement() ast.Statement {
+	from := p.curToken
+	if p.curTokenIs(token.IDENT) {
+		p.lead = p.curToken
+	}
+	var stmt ast.Statement
+	switch p.curToken.Type {
+	case token.JASA:
+		stmt = p.parseJasaStatement()
+	case token.BEKIT:
+		stmt = p.parseBekitStatement()
+	case token.QAITAR:
+		stmt = p.parseQaıtarStatement()
+	case token.AZIRSHE:
+		stmt = p.parseAzirsheStatement()
+	case token.KUT:
+		stmt = p.parseKutStatement()
+	case token.MINDET:
+		stmt = p.parseMindetStatement()
+	case token.TANDA:
+		stmt = p.parseTandaStatement()
+	case token.MODUL:
+		stmt = p.parseModulStatement()
+	case token.ENGIZ:
+		stmt = p.parseEngizStatement()
+	case token.ASHYQ:
+		stmt = p.parseAshyqDeclaration()
+	case token.ATQARM:
+		if p.peekTokenIs(token.IDENT) {
+			stmt = p.parseFuncDeclaration()
+		} else {
+			stmt = p.parseExpressionOrAssignStatement()
+		}
+	default:
+		stmt = p.parseExpressionOrAssignStatement()
+	}
+	if p.pending {
+		p.sync(from)
+		if stmt == nil {
+			to := p.curToken
+			if p.stay {
+				to = p.prevToken
+			}
+			stmt = &ast.BadStmt{From: from, To: to}
+		}
+		return stmt
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// statementKeywords start a statement wherever they appear.
+var statementKeywords = map[token.TokenType]bool{
+	token.JASA: true, token.BEKIT: true, token.QAITAR: true, token.AZIRSHE: true,
+	token.MODUL: true, token.ENGIZ: true, token.ASHYQ: true, token.KUT: true, token.MINDET: true,
+	token.TANDA: true,
+}
+
+// sync skips the rest of a statement that failed to parse, from its first
+// token from, so that the next one starts clean. It stops after a ';' and
+// before a statement keyword, a token on a new line or the '}' closing the
+// enclosing block; braces opened in between are skipped with their
+// contents. When the error was found at such a token it is not skipped,
+// as in `jasa x = (1 +` followed by `jasa y = 2` on the next line.
+func (p *Parser) sync(from token.Token) {
+	p.pending = false
+	cur := p.curToken
+	if cur.Offset == p.errTok.Offset && cur.File == p.errTok.File && cur.Offset != from.Offset {
+		if statementKeywords[cur.Type] || cur.Type == token.RBRACE && p.depth > 0 {
+			p.stay = true
+			return
+		}
+	}
+	depth := 0
+	if p.curTokenIs(token.LBRACE) {
+		depth = 1 // read, but the block was not parsed
+	}
+	for !p.peekTokenIs(token.EOF) {
+		switch {
+		case p.peekTokenIs(token.LBRACE):
+			depth++
+		case p.peekTokenIs(token.RBRACE):
+			if depth == 0 {
+				return
+			}
+			depth--
+		case depth > 0:
+		case p.peekTokenIs(token.SEMICOLON):
+			p.nextToken()
+			return
+		case p.newlineBefore() || statementKeywords[p.peekToken.Type]:
+			return
+		}
+		p.nextToken()
+	}
+}
+
+// parseBinding parses `NAME [: TYPE] [= VALUE]` after `jasa`/`bekit`.
+// A binding typed `atqar'm` followed by a parameter list declares a
+// function: `jasa f : atqar'm (x: san) -> san { ... }`.
+func (p *Parser) parseBinding() (*ast.Identifier, *ast.TypeNode, ast.Expression, bool) {
+	if !p.expectPeek(token.IDENT) {
+		return nil, nil, nil, false
+	}
+	name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if p.peekTokenIs(token.LBRACKET) {
+		p.nextToken()
+		p.errorf(p.curToken, msg.ArraySizeInName, name.Value, name.Value)
+		return nil, nil, nil, false
+	}
+
+	var typ *ast.TypeNode
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		p.nextToken()
+		if p.curTokenIs(token.ATQARM) && (p.peekTokenIs(token.LPAREN) || p.peekTokenIs(token.LBRACKET)) {
+			fn := p.parseAtqarmLiteral()
+			if fn == nil {
+				return nil, nil, nil, false
+			}
+			fn.(*ast.AtqarmLiteral).Name = name.Value
+			tok := fn.(*ast.AtqarmLiteral).Token
+			return name, &ast.TypeNode{Token: tok, Name: canonical(tok)}, fn, true
+		}
+		typ = p.parseType()
+		if typ == nil {
+			return nil, nil, nil, false
+		}
+	}
+
+	if !p.peekTokenIs(token.ASSIGN) {
+		return name, typ, nil, true
+	}
+	p.nextToken()
+	p.nextToken()
+	value := p.parseValue()
+	if fn, ok := value.(*ast.AtqarmLiteral); ok && fn.Name == "" {
+		fn.Name = name.Value
+	}
+	return name, typ, value, true
+}
+
+func (p *Parser) parseJasaStatement() ast.Statement {
+	stmt := &ast.JasaStatement{Token: p.curToken}
+	name, typ, value, ok := p.parseBinding()
+	if !ok {
+		return nil
+	}
+	if typ == nil && value == nil {
+		p.errorf(stmt.Token, msg.DeclNeedsType, name.Value)
+		return nil
+	}
+	stmt.Name, stmt.Type, stmt.Value = name, typ, value
+	return stmt
+}
+
+func (p *Parser) parseBekitStatement() ast.Statement {
+	stmt := &ast.BekitStatement{Token: p.curToken}
+	name, typ, value, ok := p.parseBinding()
+	if !ok {
+		return nil
+	}
+	if value == nil {
+		p.errorf(stmt.Token, msg.ConstNeedsValue, name.Value)
+		return nil
+	}
+	stmt.Name, stmt.Type, stmt.Value = name, typ, value
+	return stmt
+}
+
+func (p *Parser) parseModulStatement() ast.Statement {
+	stmt := &ast.ModulStatement{Token: p.curToken}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	return stmt
+}
+
+func (p *Parser) parseEngizStatement() ast.Statement {
+	stmt := &ast.EngizStatement{Token: p.curToken}
+	if !p.expectPeek(token.JOL_LIT) {
+		return nil
+	}
+	stmt.Path = p.parseJolLiteral().(*ast.JolLiteral)
+	return stmt
+}
+
+// parseAshyqDeclaration parses `ashyq jasa ...` and `ashyq bekit ...`.
+func (p *Parser) parseAshyqDeclaration() ast.Statement {
+	switch p.peekToken.Type {
+	case token.JASA:
+		p.nextToken()
+		if stmt, ok := p.parseJasaStatement().(*ast.JasaStatement); ok {
+			stmt.Public = true
+			return stmt
+		}
+	case token.BEKIT:
+		p.nextToken()
+		if stmt, ok := p.parseBekitStatement().(*ast.BekitStatement); ok {
+			stmt.Public = true
+			return stmt
+		}
+	case token.ATQARM:
+		p.nextToken()
+		if stmt, ok := p.parseFuncDeclaration().(*ast.BekitStatement); ok {
+			stmt.Public = true
+			return stmt
+		}
+	default:
+		p.errorf(p.peekToken, msg.ExpectedAfterAshyq, describe(p.peekToken))
+	}
+	return nil
+}
+
+// parseFuncDeclaration parses `atqar'm name (...) -> T { ... }` (latin
+// `fn name(...)`), which declares name like `bekit name = atqar'm ...`.
+func (p *Parser) parseFuncDeclaration() ast.Statement {
+	tok := p.curToken
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	lit := p.parseFunction(&ast.AtqarmLiteral{Token: tok, Name: name.Value})
+	if lit == nil {
+		return nil
+	}
+	return &ast.BekitStatement{Token: tok, Name: name, Value: lit}
+}
+
+func (p *Parser) parseQaıtarStatement() ast.Statement {
+	stmt := &ast.QaıtarStatement{Token: p.curToken}
+	if p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF) || p.newlineBefore() {
+		return stmt
+	}
+	p.nextToken()
+	stmt.ReturnValue = p.parseValue()
+	return stmt
+}
+
+func (p *Parser) parseAzirsheStatement() ast.Statement {
+	stmt := &ast.AzirsheStatement{Token: p.curToken}
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+	if stmt.Condition == nil || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+	return stmt
+}
+
+func (p *Parser) parseKutStatement() ast.Statement {
+	stmt := &ast.KutStatement{Token: p.curToken}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+	return stmt
+}
+
+// parseMindetStatement parses `mindet f(a)` and `mindet x = f(a)`.
+func (p *Parser) parseMindetStatement() ast.Statement {
+	stmt := &ast.MindetStatement{Token: p.curToken}
+	p.nextToken()
+	expr := p.parseExpression(LOWEST)
+	if expr == nil {
+		return nil
+	}
+	if p.peekTokenIs(token.ASSIGN) {
+		target, ok := expr.(*ast.Identifier)
+		if !ok {
+			p.errorf(p.peekToken, msg.CannotAssign, expr.String())
+			return nil
+		}
+		stmt.Target = target
+		p.nextToken()
+		p.nextToken()
+		tok := p.curToken
+		if expr = p.parseExpression(LOWEST); expr == nil {
+			return nil
+		}
+		if _, ok := expr.(*ast.CallExpression); !ok {
+			p.errorf(tok, msg.TaskNotCall, p.keyword(token.MINDET), expr.String())
+			return nil
+		}
+	}
+	call, ok := expr.(*ast.CallExpression)
+	if !ok {
+		p.errorf(stmt.Token, msg.TaskNotCall, p.keyword(token.MINDET), expr.String())
+		return nil
+	}
+	stmt.Call = call
+	return stmt
+}
+
+// parseTandaStatement parses `tańda { clause... }`; see ast.TandaClause.
+func (p *Parser) parseTandaStatement() ast.Statement {
+	stmt := &ast.TandaStatement{Token: p.curToken}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	open := p.curToken
+
+	p.depth++
+	defer func() { p.depth-- }()
+
+	p.nextToken()
+	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+		if p.curTokenIs(token.SEMICOLON) {
+			p.nextToken()
+			continue
+		}
+		cl := p.parseTandaClause()
+		if cl == nil {
+			return nil
+		}
+		stmt.Clauses = append(stmt.Clauses, cl)
+		p.nextToken()
+	}
+	stmt.Rbrace = p.curToken
+	if !p.curTokenIs(token.RBRACE) {
+		p.errorf(p.curToken, msg.UnclosedBlock, token.RBRACE, open.Pos()).Label(open, msg.Text(msg.BlockOpened))
+		return nil
+	}
+	return stmt
+}
+
+func (p *Parser) parseTandaClause() *ast.TandaClause {
+	cl := &ast.TandaClause{Token: p.curToken}
+	if p.curTokenIs(token.AITPECE) {
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		cl.Body = p.parseBlockStatement()
+		return cl
+	}
+	if p.curTokenIs(token.JASA) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		cl.Define = true
+		cl.Target = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if !p.expectPeek(token.ASSIGN) {
+			return nil
+		}
+		p.nextToken()
+	}
+	tok := p.curToken
+	expr := p.parseExpression(LOWEST)
+	if expr == nil {
+		return nil
+	}
+	if cl.Target == nil && p.peekTokenIs(token.ASSIGN) {
+		target, ok := expr.(*ast.Identifier)
+		if !ok {
+			p.errorf(p.peekToken, msg.CannotAssign, expr.String())
+			return nil
+		}
+		cl.Target = target
+		p.nextToken()
+		p.nextToken()
+		tok = p.curToken
+		if expr = p.parseExpression(LOWEST); expr == nil {
+			return nil
+		}
+	}
+	call, ok := expr.(*ast.CallExpression)
+	if !ok {
+		p.errorf(tok, msg.ClauseNotComm, p.keyword(token.TANDA), p.keyword(token.AITPECE), expr.String())
+		return nil
+	}
+	cl.Comm = call
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	cl.Body = p.parseBlockStatement()
+	return cl
+}
+
+func (p *Parser) parseExpressionOrAssignStatement() ast.Statement {
+	tok := p.curToken
+	expr := p.parseExpression(LOWEST)
+	if expr == nil {
+		return nil
+	}
+	if !p.peekTokenIs(token.ASSIGN) {
+		return &ast.ExpressionStatement{Token: tok, Expression: expr}
+	}
+	p.nextToken()
+	stmt := &ast.AssignStatement{Token: p.curToken, Target: expr}
+	switch t := expr.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+	case *ast.PrefixExpression:
+		if t.Operator != "*" {
+			p.errorf(stmt.Token, msg.CannotAssign, expr.String())
+			return nil
+		}
+	default:
+		p.errorf(stmt.Token, msg.CannotAssign, expr.String())
+		return nil
+	}
+	p.nextToken()
+	stmt.Value = p.parseValue()
+	return stmt
+}
+
+func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	block := &ast.BlockStatement{Token: p.curToken}
+	block.Statements = []ast.Statement{}
+
+	p.depth++
+	defer func() { p.depth-- }()
+
+	p.nextToken()
+	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+		if p.curTokenIs(token.SEMICOLON) {
+			p.nextToken()
+			continue
+		}
+		stmt := p.parseStatement()
+		if stmt != nil {
+			block.Statements = append(block.Statements, stmt)
+		}
+		p.nextToken()
+	}
+	block.Rbrace = p.curToken
+	if !p.curTokenIs(token.RBRACE) {
+		p.errorf(p.curToken, msg.UnclosedBlock, token.RBRACE, block.Token.Pos()).Label(block.Token, msg.Text(msg.BlockOpened))
+	}
+	return block
+}
+
+// parseType parses a type starting at curToken.
+func (p *Parser) parseType() *ast.TypeNode {
+	switch p.curToken.Type {
+	case token.LBRACKET:
+		tn := &ast.TypeNode{Token: p.curToken}
+		if !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		p.nextToken()
+		tn.Elem = p.parseType()
+		if tn.Elem == nil {
+			return nil
+		}
+		return tn
+	case token.AMPERSAND:
+		tn := &ast.TypeNode{Token: p.curToken}
+		p.nextToken()
+		tn.Elem = p.parseType()
+		if tn.Elem == nil {
+			return nil
+		}
+		return tn
+	case token.ARNA:
+		tn := &ast.TypeNode{Token: p.curToken}
+		if !p.expectPeek(token.LBRACKET) {
+			return nil
+		}
+		p.nextToken()
+		tn.Elem = p.parseType()
+		if tn.Elem == nil || !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		return tn
+	case token.IDENT, token.ATQARM:
+		return &ast.TypeNode{Token: p.curToken, Name: canonical(p.curToken)}
+	}
+	for _, tt := range typeKeywords {
+		if p.curTokenIs(tt) {
+			return &ast.TypeNode{Token: p.curToken, Name: canonical(p.curToken)}
+		}
+	}
+	p.errorf(p.curToken, msg.ExpectedType, describe(p.curToken))
+	return nil
+}
+
+// --- Expressions ---
+
+func (p *Parser) parseExpression(precedence int) ast.Expression {
+	prefix := p.prefixParseFns[p.curToken.Type]
+	if prefix == nil {
+		p.noPrefixParseFnError(p.curToken)
+		return nil
+	}
+	leftExp := prefix()
+
+	for leftExp != nil && !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() && !p.newlineBefore() {
+		infix := p.infixParseFns[p.peekToken.Type]
+		if infix == nil {
+			return leftExp
+		}
+		p.nextToken()
+		leftExp = infix(leftExp)
+	}
+	return leftExp
+}
+
+// parseChanType parses a channel type used as a value, as in
+// `arna[f64](16)`, which makes a channel.
+func (p *Parser) parseChanType() ast.Expression {
+	if tn := p.parseType(); tn != nil {
+		return tn
+	}
+	return nil
+}
+
+// parseValue parses the value of a declaration, assignment or qaıtar. On
+// error it returns an ast.BadExpr, so that the statement is kept and a
+// name it declares does not cause more errors where it is used.
+func (p *Parser) parseValue() ast.Expression {
+	tok := p.curToken
+	if value := p.parseExpression(LOWEST); value != nil {
+		return value
+	}
+	return &ast.BadExpr{Token: tok}
+}
+
+// parseIdentifier also parses the keywords that name built-ins, such as
+// kórset and the type names; their value is the Kazakh spelling whichever
+// syntax they were written in.
+func (p *Parser) parseIdentifier() ast.Expression {
+	return &ast.Identifier{Token: p.curToken, Value: canonical(p.curToken)}
+}
+
+func canonical(tok token.Token) string {
+	if tok.Type == token.IDENT {
+		return tok.Literal
+	}
+	return string(tok.Type)
+}
+
+func (p *Parser) parseSanLiteral() ast.Expression {
+	lit := &ast.SanLiteral{Token: p.curToken}
+	value, err := strconv.ParseUint(p.curToken.Literal, 0, 64)
+	if err != nil {
+		p.errorf(p.curToken, msg.BadSan, p.curToken.Literal)
+		return nil
+	}
+	// Literals above math.MaxInt64 keep their bit pattern; they are only
+	// meaningful as u64 values.
+	lit.Value = int64(value)
+	if value > math.MaxInt64 && !isHex(p.curToken.Literal) {
+		p.errorf(p.curToken, msg.SanOverflow, p.curToken.Literal)
+		return nil
+	}
+	return lit
+}
+
+func isHex(lit string) bool {
+	return len(lit) > 2 && lit[0] == '0' && (lit[1] == 'x' || lit[1] == 'X')
+}
+
+func (p *Parser) parseAqshaLiteral() ast.Expression {
+	lit := &ast.AqshaLiteral{Token: p.curToken}
+	value, err := decimal.NewFromString(p.curToken.Literal)
+	if err != nil {
+		p.errorf(p.curToken, msg.BadAqsha, p.curToken.Literal)
+		return nil
+	}
+	lit.Value = value
+	return lit
+}
+
+func (p *Parser) parseJolLiteral() ast.Expression {
+	return &ast.JolLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+func (p *Parser) parseAqıqatLiteral() ast.Expression {
+	return &ast.AqıqatLiteral{Token: p.curToken, Value: p.curTokenIs(token.JAN)}
+}
+
+func (p *Parser) parsePrefixExpression() ast.Expression {
+	expression := &ast.PrefixExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+	}
+	p.nextToken()
+	expression.Right = p.parseExpression(PREFIX)
+	if expression.Right == nil {
+		return nil
+	}
+	return expression
+}
+
+func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
+	expression := &ast.InfixExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Left:     left,
+	}
+	precedence := p.curPrecedence()
+	p.nextToken()
+	expression.Right = p.parseExpression(precedence)
+	if expression.Right == nil {
+		return nil
+	}
+	return expression
+}
+
+func (p *Parser) parseGroupedExpression() ast.Expression {
+	p.nextToken()
+	exp := p.parseExpression(LOWEST)
+	if exp == nil || !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return exp
+}
+
+func (p *Parser) parseJyimLiteral() ast.Expression {
+	array := &ast.JyimLiteral{Token: p.curToken}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if array.Elements == nil {
+		return nil
+	}
+	return array
+}
+
+func (p *Parser) parseEgerExpression() ast.Expression {
+	expression := &ast.EgerExpression{Token: p.curToken}
+	p.nextToken()
+	expression.Condition = p.parseExpression(LOWEST)
+	if expression.Condition == nil || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	expression.Consequence = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.AITPECE) {
+		p.nextToken()
+		if p.peekTokenIs(token.EGER) {
+			// `áıtpece eger` chains are desugared into a nested block.
+			p.nextToken()
+			tok := p.curToken
+			nested := p.parseEgerExpression()
+			if nested == nil {
+				return nil
+			}
+			expression.Alternative = &ast.BlockStatement{
+				Token:      token.Token{Type: token.LBRACE, Literal: "{", Line: tok.Line, Column: tok.Column},
+				Statements: []ast.Statement{&ast.ExpressionStatement{Token: tok, Expression: nested}},
+				Rbrace:     p.curToken,
+			}
+			return expression
+		}
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+		expression.Alternative = p.parseBlockStatement()
+	}
+	return expression
+}
+
+// parseQatarlasExpression parses `qatarlas ár i ishinde a..b [reduction] { ... }`.
+func (p *Parser) parseQatarlasExpression() ast.Expression {
+	expression := &ast.QatarlasExpression{Token: p.curToken}
+	if !p.expectPeek(token.AR) || !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	expression.Var = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.ISHINDE) {
+		return nil
+	}
+	p.nextToken()
+	if expression.From = p.parseExpression(LOWEST); expression.From == nil || !p.expectPeek(token.RANGE) {
+		return nil
+	}
+	p.nextToken()
+	if expression.To = p.parseExpression(LOWEST); expression.To == nil {
+		return nil
+	}
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		expression.Reduce = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	expression.Body = p.parseBlockStatement()
+	return expression
+}
+
+func (p *Parser) parseAtqarmLiteral() ast.Expression {
+	if lit := p.parseFunction(&ast.AtqarmLiteral{Token: p.curToken}); lit != nil {
+		return lit
+	}
+	return nil
+}
+
+// parseFunction parses the type parameters, parameters, result type and
+// body of a function whose 'atqar'm' token (and name) have been read.
+func (p *Parser) parseFunction(lit *ast.AtqarmLiteral) *ast.AtqarmLiteral {
+	if p.peekTokenIs(token.LBRACKET) {
+		p.nextToken()
+		tparams, ok := p.parseTypeParams()
+		if !ok {
+			return nil
+		}
+		lit.TypeParams = tparams
+	}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	params, ok := p.parseParameters()
+	if !ok {
+		return nil
+	}
+	lit.Parameters = params
+
+	if p.peekTokenIs(token.ARROW) {
+		p.nextToken()
+		p.nextToken()
+		lit.ReturnType = p.parseType()
+		if lit.ReturnType == nil {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	lit.Body = p.parseBlockStatement()
+	return lit
+}
+
+// parseTypeParams parses `[T, U: Ordered]` starting at the '['.
+func (p *Parser) parseTypeParams() ([]*ast.TypeParam, bool) {
+	var tparams []*ast.TypeParam
+	for {
+		if !p.expectPeek(token.IDENT) {
+			return nil, false
+		}
+		tp := &ast.TypeParam{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) {
+				return nil, false
+			}
+			tp.Constraint = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		}
+		tparams = append(tparams, tp)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(token.RBRACKET) {
+		return nil, false
+	}
+	return tparams, true
+}
+
+func (p *Parser) parseParameters() ([]*ast.Parameter, bool) {
+	params := []*ast.Parameter{}
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return params, true
+	}
+	for {
+		if !p.expectPeek(token.IDENT) {
+			return nil, false
+		}
+		param := &ast.Parameter{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			p.nextToken()
+			param.Type = p.parseType()
+			if param.Type == nil {
+				return nil, false
+			}
+		}
+		params = append(params, param)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(token.RPAREN) {
+		return nil, false
+	}
+	return params, true
+}
+
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	exp := &ast.CallExpression{Token: p.curToken, Function: function}
+	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	if exp.Arguments == nil {
+		return nil
+	}
+	return exp
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	p.nextToken()
+	exp.Index = p.parseExpression(LOWEST)
+	if exp.Index == nil || !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	return exp
+}
+
+func (p *Parser) parseSelectorExpression(left ast.Expression) ast.Expression {
+	exp := &ast.SelectorExpression{Token: p.curToken, X: left}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Sel = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	return exp
+}
+
+// parseExpressionList parses a comma-separated list up to end. It returns
+// nil on error and an empty slice for an empty list.
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return list
+	}
+	for {
+		p.nextToken()
+		expr := p.parseExpression(LOWEST)
+		if expr == nil {
+			return nil
+		}
+		list = append(list, expr)
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+	if !p.expectPeek(end) {
+		return nil
+	}
+	return list
+}
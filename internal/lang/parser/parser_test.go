@@ -0,0 +1,229 @@
+// FILE: internal/lang/parser/parser_test.go
+
+package parser_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/lexer"
+	"github.com/DauletBai/tenge/internal/lang/parser"
+)
+
+func parse(t *testing.T, src string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("%s: %s", src, errs[0])
+	}
+	return program
+}
+
+// group writes an expression with every operation in parentheses, so
+// that tests see how the parser grouped it.
+func group(e ast.Expression) string {
+	switch e := e.(type) {
+	case *ast.InfixExpression:
+		return "(" + group(e.Left) + " " + e.Operator + " " + group(e.Right) + ")"
+	case *ast.PrefixExpression:
+		return "(" + e.Operator + group(e.Right) + ")"
+	case *ast.CallExpression:
+		args := make([]string, len(e.Arguments))
+		for i, a := range e.Arguments {
+			args[i] = group(a)
+		}
+		return group(e.Function) + "(" + strings.Join(args, ", ") + ")"
+	case *ast.IndexExpression:
+		return "(" + group(e.Left) + "[" + group(e.Index) + "])"
+	}
+	return e.TokenLiteral()
+}
+
+func TestPrecedence(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"a + b * c", "(a + (b * c))"},
+		{"(a + b) * c", "((a + b) * c)"},
+		{"a - b - c", "((a - b) - c)"},
+		{"a / b * c", "((a / b) * c)"},
+		{"-a * b", "((-a) * b)"},
+		{"!a == b", "((!a) == b)"},
+		{"a < b == c", "((a < b) == c)"},
+		{"a || b && c", "(a || (b && c))"},
+		{"a + b << c", "(a + (b << c))"},
+		{"a | b & c", "(a | (b & c))"},
+		{"f(a + b, c)[i] * 2", "((f((a + b), c)[i]) * 2)"},
+		{"*p + &x", "((*p) + (&x))"},
+	}
+	for _, tt := range tests {
+		program := parse(t, tt.src)
+		if len(program.Statements) != 1 {
+			t.Errorf("%s: %d statements", tt.src, len(program.Statements))
+			continue
+		}
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Errorf("%s: parsed as %T", tt.src, program.Statements[0])
+			continue
+		}
+		if got := group(stmt.Expression); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestStatements(t *testing.T) {
+	program := parse(t, `
+jasa x : san = 1
+bekit y = 2.5
+jasa xs : []san = [1, 2, 3]
+jasa f : atqar'm (a: san, b) -> san {
+    qaıtar a
+}
+ázirshe x < 10 { x = x + 1 }
+xs[0] = eger x > 1 { 1 } áıtpece { 2 }
+kórset(x); qaıtar
+`)
+	want := []string{
+		"*ast.JasaStatement", "*ast.BekitStatement", "*ast.JasaStatement", "*ast.JasaStatement",
+		"*ast.AzirsheStatement", "*ast.AssignStatement", "*ast.ExpressionStatement", "*ast.QaıtarStatement",
+	}
+	if len(program.Statements) != len(want) {
+		t.Fatalf("%d statements, want %d", len(program.Statements), len(want))
+	}
+	for i, s := range program.Statements {
+		if got := fmt.Sprintf("%T", s); got != want[i] {
+			t.Errorf("statement %d is %s, want %s", i+1, got, want[i])
+		}
+	}
+
+	x := program.Statements[0].(*ast.JasaStatement)
+	if x.Name.Value != "x" || x.Type == nil || x.Type.Token.Literal != "san" || group(x.Value) != "1" {
+		t.Errorf("jasa x : san = 1 parsed as %s", x)
+	}
+	if y := program.Statements[1].(*ast.BekitStatement); y.Type != nil || group(y.Value) != "2.5" {
+		t.Errorf("bekit y = 2.5 parsed as %s", y)
+	}
+	xs := program.Statements[2].(*ast.JasaStatement)
+	if xs.Type.Elem == nil || len(xs.Value.(*ast.JyimLiteral).Elements) != 3 {
+		t.Errorf("jasa xs parsed as %s", xs)
+	}
+	fn, ok := program.Statements[3].(*ast.JasaStatement).Value.(*ast.AtqarmLiteral)
+	if !ok {
+		t.Fatalf("jasa f does not declare a function")
+	}
+	if fn.Name != "f" || len(fn.Parameters) != 2 || fn.Parameters[1].Type != nil || fn.ReturnType == nil || len(fn.Body.Statements) != 1 {
+		t.Errorf("function f parsed as %s", fn)
+	}
+	assign := program.Statements[5].(*ast.AssignStatement)
+	if group(assign.Target) != "(xs[0])" {
+		t.Errorf("assignment target %s", group(assign.Target))
+	}
+	if eger, ok := assign.Value.(*ast.EgerExpression); !ok || eger.Alternative == nil {
+		t.Errorf("assigned value %s", assign.Value)
+	}
+	if r := program.Statements[7].(*ast.QaıtarStatement); r.ReturnValue != nil {
+		t.Errorf("bare qaıtar returns %s", r.ReturnValue)
+	}
+}
+
+// TestErrors checks where the first error of a malformed program is
+// reported.
+func TestErrors(t *testing.T) {
+	tests := []struct{ src, pos string }{
+		{"jasa = 1", "1:6"},
+		{"jasa x", "1:1"},
+		{"bekit k : san", "1:1"},
+		{"jasa x : = 1", "1:10"},
+		{"kórset(1 +)", "1:11"},
+		{"1 + 2 = 3", "1:7"},
+		{"ázirshe x { x = x - 1", "1:22"},
+		{"jasa f : atqar'm (a san) { }", "1:21"},
+		{"jasa n = 99999999999999999999", "1:10"},
+	}
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.src))
+		p.ParseProgram()
+		errs := p.Errors()
+		if len(errs) == 0 {
+			t.Errorf("%s: no error", tt.src)
+			continue
+		}
+		if !strings.HasPrefix(errs[0], tt.pos+": ") {
+			t.Errorf("%s: first error %q, want it at %s", tt.src, errs[0], tt.pos)
+		}
+	}
+}
+
+// TestRecovery checks that a statement with an error does not hide the
+// errors after it, and does not cause more errors where parsing resumes.
+func TestRecovery(t *testing.T) {
+	tests := []struct {
+		src   string
+		errs  []string
+		stmts string // the kinds of the top-level statements
+	}{
+		{"jasa = 1\njasa y = 2\nkórset(1 +)\nkórset(y)",
+			[]string{`1:6: expected next token to be IDENT, got "=" instead`, `3:11: unexpected ")"`},
+			"BadStmt JasaStatement BadStmt ExpressionStatement"},
+		{"jasa x = (1 +\njasa y = 2",
+			[]string{`2:1: unexpected "jasa"`},
+			"JasaStatement JasaStatement"},
+		{"jasa x = 1 ) 2; jasa y = 2",
+			[]string{`1:12: unexpected ")"`},
+			"JasaStatement BadStmt JasaStatement"},
+		{"atqar'm f() {\n  jasa = 1\n  kórset(2)\n}\nkórset(f())",
+			[]string{`2:8: expected next token to be IDENT, got "=" instead`},
+			"BekitStatement ExpressionStatement"},
+		{"atqar'm f() {\n  jasa x = 1 +\n}\njasa z = 3",
+			[]string{`3:1: unexpected "}"`},
+			"BekitStatement JasaStatement"},
+		{"eger x { jasa = } \nkórset(1)",
+			[]string{`1:15: expected next token to be IDENT, got "=" instead`},
+			"ExpressionStatement ExpressionStatement"},
+	}
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.src))
+		program := p.ParseProgram()
+		if got := p.Errors(); fmt.Sprint(got) != fmt.Sprint(tt.errs) {
+			t.Errorf("%q: errors %q, want %q", tt.src, got, tt.errs)
+		}
+		var kinds []string
+		for _, s := range program.Statements {
+			kinds = append(kinds, strings.TrimPrefix(fmt.Sprintf("%T", s), "*ast."))
+		}
+		if got := strings.Join(kinds, " "); got != tt.stmts {
+			t.Errorf("%q: statements %s, want %s", tt.src, got, tt.stmts)
+		}
+	}
+}
+
+// TestDump checks that the dump of a tree closes every bracket at the
+// indentation it was opened at.
+func TestDump(t *testing.T) {
+	program := parse(t, "jasa xs : []san = [1, 2]\njasa f : atqar'm () { kórset(xs[0]) }\nkórset()")
+	var b strings.Builder
+	if err := ast.Fprint(&b, program); err != nil {
+		t.Fatal(err)
+	}
+	var open []int
+	for i, line := range strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n") {
+		trimmed := strings.TrimLeft(line, " .\t")
+		indent := len(line) - len(trimmed)
+		if trimmed == "}" {
+			if len(open) == 0 || open[len(open)-1] != indent {
+				t.Fatalf("line %d closes a bracket at indentation %d:\n%s", i+1, indent, b.String())
+			}
+			open = open[:len(open)-1]
+			continue
+		}
+		if strings.HasSuffix(trimmed, "{") {
+			open = append(open, indent)
+		}
+	}
+	if len(open) > 0 {
+		t.Fatalf("%d brackets left open:\n%s", len(open), b.String())
+	}
+}
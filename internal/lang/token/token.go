@@ -9,25 +9,40 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	File    string // source file, empty for input without a name (REPL)
+	Line    int    // 1-based line of the first character
+	Column  int    // 1-based column (in runes) of the first character
+	Offset  int    // byte offset of the first character
 }
 
 func (t Token) String() string {
 	return fmt.Sprintf("Token{Type:%s, Literal:`%s`}", t.Type, t.Literal)
 }
 
+// Pos returns the "file:line:col" position of the token for diagnostics,
+// or "line:col" when the source has no file name.
+func (t Token) Pos() string {
+	if t.File != "" {
+		return fmt.Sprintf("%s:%d:%d", t.File, t.Line, t.Column)
+	}
+	return fmt.Sprintf("%d:%d", t.Line, t.Column)
+}
+
 // All token types are now based on the tenge language keywords.
 const (
 	// Special Tokens
 	ILLEGAL = "ILLEGAL" // Represents a token we don't know
 	EOF     = "EOF"     // End of File
+	COMMENT = "COMMENT" // A // comment or #syntax line; see lexer.Lexer.Comments
 
 	// Identifiers & Literals
-	IDENT     = "IDENT"      // a, myVar, etc.
-	SAN_LIT   = "SAN_LIT"    // 123
-	AQSHA_LIT = "AQSHA_LIT"  // 12.34
-	JOL_LIT   = "JOL_LIT"    // "hello"
+	IDENT     = "IDENT"     // a, myVar, etc.
+	SAN_LIT   = "SAN_LIT"   // 123
+	AQSHA_LIT = "AQSHA_LIT" // 12.34
+	JOL_LIT   = "JOL_LIT"   // "hello"
 
-	// Keywords
+	// Keywords. The values are the Kazakh spellings; see lexer.Syntax for
+	// the Latin ones.
 	JASA    = "jasa"
 	BEKIT   = "bekit"
 	ATQARM  = "atqar'm"
@@ -38,6 +53,22 @@ const (
 	JAN     = "jan"
 	JYN     = "j'n"
 	KORSET  = "kórset"
+	MODUL   = "modul"
+	ENGIZ   = "engiz"
+	ASHYQ   = "ashyq"
+
+	// Parallelism: `qatarlas ár i ishinde 0..n { ... }` and
+	// `kút { mindet x = f(a) }`.
+	QATARLAS = "qatarlas"
+	AR       = "ár"
+	ISHINDE  = "ishinde"
+	MINDET   = "mindet"
+	KUT      = "kút"
+
+	// Channels: `jasa c = arna[san](8)` and
+	// `tańda { x = recv(c) { ... } timeout(100) { ... } }`.
+	ARNA  = "arna"
+	TANDA = "tańda"
 
 	// Types
 	SAN    = "san"
@@ -48,20 +79,38 @@ const (
 	JYIM   = "j'i'm"
 
 	// Operators
-	ASSIGN    = "="
-	PLUS      = "+"
-	MINUS     = "-"
-	MULTIPLY  = "*"
-	DIVIDE    = "/"
-	EQUAL     = "=="
-	GREATER   = ">"
+	ASSIGN     = "="
+	PLUS       = "+"
+	MINUS      = "-"
+	MULTIPLY   = "*"
+	DIVIDE     = "/"
+	MODULO     = "%"
+	BANG       = "!"
+	EQUAL      = "=="
+	NOT_EQUAL  = "!="
+	GREATER    = ">"
+	GREATER_EQ = ">="
+	LESS       = "<"
+	LESS_EQ    = "<="
+	AND        = "&&"
+	OR         = "||"
+	AMPERSAND  = "&"
+	PIPE       = "|"
+	CARET      = "^"
+	SHL        = "<<"
+	SHR        = ">>"
 
 	// Delimiters
 	COMMA     = ","
+	DOT       = "."
 	COLON     = ":"
+	SEMICOLON = ";"
 	LPAREN    = "("
 	RPAREN    = ")"
 	LBRACKET  = "["
 	RBRACKET  = "]"
+	LBRACE    = "{"
+	RBRACE    = "}"
 	ARROW     = "->"
-)
\ No newline at end of file
+	RANGE     = ".."
+)
@@ -0,0 +1,110 @@
+// FILE: internal/lang/types/channel.go
+
+package types
+
+import (
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/diag"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/token"
+)
+
+// makeChan checks `arna[T](n)`, which makes a channel holding up to n
+// values; without n the channel is unbuffered.
+func (c *Checker) makeChan(e *ast.CallExpression, tn *ast.TypeNode) Type {
+	t := c.record(tn, c.resolve(tn))
+	args := c.exprs(e.Arguments)
+	if c.argCount(e, args, 0, 1) && len(args) == 1 {
+		c.argInteger(e, args, 0)
+	}
+	return t
+}
+
+// tanda checks a tańda statement: every clause is a call of recv, send or
+// timeout, or áıtpece, and at most one is a timeout or áıtpece. Each
+// clause has a scope around its body for the variable it may declare.
+func (c *Checker) tanda(s *ast.TandaStatement) {
+	var fallback *ast.TandaClause
+	for _, cl := range s.Clauses {
+		if cl.Comm == nil || commName(cl) == "timeout" {
+			if fallback != nil {
+				c.errors = append(c.errors, diag.New(diag.Error, cl.Token, msg.DuplicateClause, c.keyword(token.TANDA), c.keyword(token.AITPECE)))
+			}
+			fallback = cl
+		}
+		c.openScope()
+		c.clause(cl)
+		c.block(cl.Body)
+		c.closeScope()
+	}
+}
+
+// commName returns the name of the function the clause calls, or "".
+func commName(cl *ast.TandaClause) string {
+	if cl.Comm == nil {
+		return ""
+	}
+	if id, ok := cl.Comm.Function.(*ast.Identifier); ok {
+		return id.Value
+	}
+	return ""
+}
+
+// clause checks the call of a tańda clause and the variable given the
+// received value.
+func (c *Checker) clause(cl *ast.TandaClause) {
+	if cl.Comm == nil {
+		return
+	}
+	name := commName(cl)
+	var sym *Symbol
+	if name != "" {
+		sym = c.scope.Lookup(name)
+	}
+	if sym == nil || sym.Kind != BuiltinSym || name != "recv" && name != "send" && name != "timeout" {
+		c.exprs(cl.Comm.Arguments)
+		c.errorf(cl.Comm, msg.ClauseNotComm, c.keyword(token.TANDA), c.keyword(token.AITPECE), cl.Comm)
+		if cl.Define {
+			c.declare(cl.Target, VarSym, Typ[Invalid])
+		}
+		return
+	}
+	var result Type
+	if name == "timeout" {
+		id := cl.Comm.Function.(*ast.Identifier)
+		c.info.Uses[id] = sym
+		c.record(id, sym.Type)
+		args := c.exprs(cl.Comm.Arguments)
+		if c.argCount(cl.Comm, args, 1, 1) {
+			c.argInteger(cl.Comm, args, 0)
+		}
+		result = c.record(cl.Comm, Typ[Void])
+	} else {
+		result = c.expr(cl.Comm)
+	}
+	if cl.Target == nil {
+		return
+	}
+	if IsVoid(result) {
+		c.errorf(cl.Comm, msg.NoValue, cl.Comm)
+		result = Typ[Invalid]
+	}
+	if cl.Define {
+		c.declare(cl.Target, VarSym, result)
+		return
+	}
+	target := c.lookup(cl.Target)
+	if target == nil {
+		return
+	}
+	switch target.Kind {
+	case VarSym:
+		c.assigned(cl.Target, cl.Target, target)
+	case ConstSym:
+		c.errorf(cl.Target, msg.AssignConst, cl.Target.Value)
+	default:
+		c.errorf(cl.Target, msg.CannotAssign, cl.Target.Value)
+	}
+	c.record(cl.Target, target.Type)
+	c.assignable(cl.Comm, result, target.Type, msg.Text(msg.InAssign))
+}
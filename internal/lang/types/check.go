@@ -0,0 +1,1227 @@
+// FILE: internal/lang/types/check.go
+
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/diag"
+	"github.com/DauletBai/tenge/internal/lang/lexer"
+	"github.com/DauletBai/tenge/internal/lang/module"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/token"
+)
+
+// Info holds the results of type checking a program.
+type Info struct {
+	Types map[ast.Expression]Type           // type of every checked expression
+	Defs  map[*ast.Identifier]*Symbol       // declaring identifiers
+	Uses  map[*ast.Identifier]*Symbol       // referring identifiers
+	Funcs map[*ast.AtqarmLiteral]*Signature // signature of every function literal
+
+	// Scopes holds the scope of every program (its top level), function
+	// body and block, for tools that ask what is visible at a position.
+	Scopes map[ast.Node]*Scope
+
+	// Instances holds the instantiation of every call of a generic function.
+	Instances map[*ast.CallExpression]*Instance
+
+	// Warnings are diagnostics that do not stop compilation, such as
+	// names that mix scripts.
+	Warnings diag.List
+
+	generics map[*Signature]*ast.AtqarmLiteral
+
+	// For the check of parallel code: the function literal of every
+	// signature, the first write of each function to a variable declared
+	// outside it and the functions each one calls.
+	bodies map[*Signature]*ast.AtqarmLiteral
+	writes map[*ast.AtqarmLiteral]write
+	calls  map[*ast.AtqarmLiteral][]*ast.AtqarmLiteral
+}
+
+// Instance records the type arguments inferred for a call of a generic
+// function. Calls inside another generic function may have type arguments
+// that mention the type parameters of Outer.
+type Instance struct {
+	Func     *ast.AtqarmLiteral // the generic function
+	TypeArgs []Type             // in the order of the type parameters
+	Sig      *Signature         // the signature with the type arguments substituted
+	Outer    *ast.AtqarmLiteral // enclosing generic function; nil outside generic code
+}
+
+// FuncKey identifies the code the backends generate for a function: a
+// top-level function, or an instance of a generic one with its type
+// arguments spelled out.
+type FuncKey struct {
+	Lit  *ast.AtqarmLiteral
+	Args string // "" for a function that is not generic
+}
+
+// Instantiate returns the key of the instance of the generic function
+// called by inst and the types of its type parameters, where outer gives
+// the types of the type parameters of the code the call is in.
+func (info *Info) Instantiate(inst *Instance, outer map[*TypeParam]Type) (FuncKey, map[*TypeParam]Type) {
+	generic := info.Funcs[inst.Func]
+	subst := make(map[*TypeParam]Type)
+	args := make([]string, len(generic.TypeParams))
+	for i, tp := range generic.TypeParams {
+		subst[tp] = Subst(inst.TypeArgs[i], outer)
+		args[i] = subst[tp].String()
+	}
+	return FuncKey{Lit: inst.Func, Args: strings.Join(args, ", ")}, subst
+}
+
+// TypeOf returns the recorded type of e, or nil.
+func (info *Info) TypeOf(e ast.Expression) Type {
+	return info.Types[e]
+}
+
+// Checker walks a program and records type information.
+type Checker struct {
+	info    *Info
+	scope   *Scope
+	errors  diag.List
+	result  Type                            // result type of the enclosing function; nil at top level
+	generic *ast.AtqarmLiteral              // enclosing generic function, if any
+	imports map[*ast.EngizStatement]*Module // modules bound by engiz statements
+	syntax  lexer.Syntax                    // keyword set of the program, for messages
+
+	fn       *ast.AtqarmLiteral // enclosing function; nil at top level
+	fnScope  *Scope             // scope of the parameters of fn
+	region   *region            // innermost qatarlas body or kút block
+	parallel []parallelCall     // calls in regions, checked by CheckProgram
+
+	// untyped holds the array literals of untyped constants whose element
+	// type is not settled yet: the type they are used as gives it, and
+	// CheckProgram gives the others the default one.
+	untyped map[*ast.JyimLiteral]bool
+}
+
+// Check type-checks program. Info is usable even when there are errors.
+func Check(program *ast.Program) (*Info, diag.List) {
+	c := NewChecker()
+	c.CheckProgram(program)
+	return c.info, c.errors
+}
+
+// CheckModules type-checks the modules of a loaded program in dependency
+// order, each in its own top-level scope. The results of all modules are
+// recorded in one Info.
+func CheckModules(prog *module.Program) (*Info, diag.List) {
+	info := newInfo()
+	checked := make(map[*module.Module]*Module)
+	var errors diag.List
+	for _, m := range prog.Modules {
+		c := &Checker{info: info, scope: NewScope(Universe), imports: make(map[*ast.EngizStatement]*Module)}
+		for _, imp := range m.Imports {
+			c.imports[imp.Decl] = checked[imp.Module]
+		}
+		c.CheckProgram(m.Program)
+		checked[m] = &Module{Name: m.Name, Path: m.Path, Scope: c.scope}
+		errors = append(errors, c.errors...)
+	}
+	return info, errors
+}
+
+// NewChecker returns a checker with a fresh top-level scope.
+func NewChecker() *Checker {
+	return &Checker{info: newInfo(), scope: NewScope(Universe)}
+}
+
+func newInfo() *Info {
+	return &Info{
+		Types: make(map[ast.Expression]Type),
+		Defs:  make(map[*ast.Identifier]*Symbol),
+		Uses:  make(map[*ast.Identifier]*Symbol),
+		Funcs: make(map[*ast.AtqarmLiteral]*Signature),
+
+		Scopes:    make(map[ast.Node]*Scope),
+		Instances: make(map[*ast.CallExpression]*Instance),
+		generics:  make(map[*Signature]*ast.AtqarmLiteral),
+
+		bodies: make(map[*Signature]*ast.AtqarmLiteral),
+		writes: make(map[*ast.AtqarmLiteral]write),
+		calls:  make(map[*ast.AtqarmLiteral][]*ast.AtqarmLiteral),
+	}
+}
+
+// CheckProgram checks program in the checker's top-level scope. Calling it
+// repeatedly checks each program as a continuation of the previous ones.
+func (c *Checker) CheckProgram(program *ast.Program) {
+	c.info.Scopes[program] = c.scope
+	c.syntax = program.Syntax
+	c.declareFuncs(program.Statements)
+	for _, s := range program.Statements {
+		c.stmt(s)
+	}
+	for lit := range c.untyped {
+		c.convertUntyped(lit, c.info.Types[lit])
+	}
+	c.checkParallelCalls()
+}
+
+// Info returns the information recorded so far.
+func (c *Checker) Info() *Info { return c.info }
+
+// Errors returns the errors reported so far, each prefixed with its
+// position.
+func (c *Checker) Errors() []string { return c.errors.Strings() }
+
+// Diagnostics returns the errors reported so far.
+func (c *Checker) Diagnostics() diag.List { return c.errors }
+
+// ResetErrors clears the reported errors.
+func (c *Checker) ResetErrors() { c.errors = nil }
+
+// Scope returns the checker's top-level scope.
+func (c *Checker) Scope() *Scope { return c.scope }
+
+// keyword returns how tt is written in the program being checked, for
+// messages.
+func (c *Checker) keyword(tt token.TokenType) string {
+	return lexer.Spelling(tt, c.syntax)
+}
+
+func (c *Checker) errorf(at ast.Node, code msg.Code, args ...interface{}) *diag.Diagnostic {
+	d := diag.New(diag.Error, Pos(at), code, args...)
+	c.errors = append(c.errors, d)
+	return d
+}
+
+// Pos returns the token where node starts, for error positions.
+func Pos(n ast.Node) token.Token {
+	switch n := n.(type) {
+	case *ast.InfixExpression:
+		return Pos(n.Left)
+	case *ast.CallExpression:
+		return Pos(n.Function)
+	case *ast.IndexExpression:
+		return Pos(n.Left)
+	case *ast.SelectorExpression:
+		return Pos(n.X)
+	case *ast.ModulStatement:
+		return n.Token
+	case *ast.EngizStatement:
+		return n.Token
+	case *ast.AssignStatement:
+		return Pos(n.Target)
+	case *ast.ExpressionStatement:
+		return Pos(n.Expression)
+	case *ast.Identifier:
+		return n.Token
+	case *ast.PrefixExpression:
+		return n.Token
+	case *ast.SanLiteral:
+		return n.Token
+	case *ast.AqshaLiteral:
+		return n.Token
+	case *ast.JolLiteral:
+		return n.Token
+	case *ast.AqıqatLiteral:
+		return n.Token
+	case *ast.JyimLiteral:
+		return n.Token
+	case *ast.EgerExpression:
+		return n.Token
+	case *ast.AtqarmLiteral:
+		return n.Token
+	case *ast.JasaStatement:
+		return n.Token
+	case *ast.BekitStatement:
+		return n.Token
+	case *ast.QaıtarStatement:
+		return n.Token
+	case *ast.AzirsheStatement:
+		return n.Token
+	case *ast.KutStatement:
+		return n.Token
+	case *ast.MindetStatement:
+		return n.Token
+	case *ast.TandaStatement:
+		return n.Token
+	case *ast.QatarlasExpression:
+		return n.Token
+	case *ast.BlockStatement:
+		return n.Token
+	case *ast.TypeNode:
+		return n.Token
+	case *ast.BadExpr:
+		return n.Token
+	case *ast.BadStmt:
+		return n.From
+	}
+	return token.Token{}
+}
+
+func (c *Checker) openScope()  { c.scope = NewScope(c.scope) }
+func (c *Checker) closeScope() { c.scope = c.scope.parent }
+
+// scriptNames maps the names returned by lexer.Scripts to messages.
+var scriptNames = map[string]msg.Code{
+	"Latin":    msg.Latin,
+	"Cyrillic": msg.Cyrillic,
+	"Greek":    msg.Greek,
+}
+
+func (c *Checker) declare(id *ast.Identifier, kind SymbolKind, t Type) *Symbol {
+	if prev := c.scope.LookupLocal(id.Value); prev != nil && prev.Decl != id {
+		d := c.errorf(id, msg.Redeclared, id.Value)
+		if prev.Decl != nil {
+			d.Label(prev.Decl.Token, msg.Sprintf(msg.PreviousDecl, id.Value))
+		}
+	}
+	if c.info.Defs[id] == nil {
+		if s := lexer.Scripts(id.Value); len(s) > 1 {
+			c.info.Warnings = append(c.info.Warnings, diag.New(diag.Warning, id.Token, msg.MixedScript, id.Value, msg.Text(scriptNames[s[0]]), msg.Text(scriptNames[s[1]])))
+		}
+	}
+	sym := &Symbol{Name: id.Value, Kind: kind, Type: t, Decl: id}
+	c.scope.Insert(sym)
+	c.info.Defs[id] = sym
+	return sym
+}
+
+// --- Types ---
+
+// resolve converts a type annotation into a Type.
+func (c *Checker) resolve(tn *ast.TypeNode) Type {
+	switch tn.Token.Type {
+	case token.LBRACKET:
+		return &Array{Elem: c.resolve(tn.Elem)}
+	case token.AMPERSAND:
+		return &Pointer{Elem: c.resolve(tn.Elem)}
+	case token.ARNA:
+		return &Chan{Elem: c.resolve(tn.Elem)}
+	case token.ATQARM:
+		return Typ[Any]
+	}
+	sym := c.scope.Lookup(tn.Name)
+	if sym == nil || sym.Kind != TypeSym {
+		c.errorf(tn, msg.UnknownType, tn.Token.Literal)
+		return Typ[Invalid]
+	}
+	if _, ok := sym.Type.(*Constraint); ok {
+		c.errorf(tn, msg.ConstraintAsType, tn.Token.Literal)
+		return Typ[Invalid]
+	}
+	return sym.Type
+}
+
+// typeParams declares the type parameters of a generic function in the
+// current scope.
+func (c *Checker) typeParams(fn *ast.AtqarmLiteral) []*TypeParam {
+	var tparams []*TypeParam
+	for _, p := range fn.TypeParams {
+		tp := &TypeParam{Name: p.Name.Value}
+		if p.Constraint != nil {
+			var con *Constraint
+			if sym := c.scope.Lookup(p.Constraint.Value); sym != nil {
+				con, _ = sym.Type.(*Constraint)
+			}
+			if con != nil {
+				tp.Constraint = con
+			} else {
+				c.errorf(p.Constraint, msg.NotConstraint, p.Constraint.Value)
+			}
+		}
+		c.declare(p.Name, TypeSym, tp)
+		tparams = append(tparams, tp)
+	}
+	return tparams
+}
+
+// signature computes the type of a function literal. Unannotated
+// parameters are `any`; an unannotated result is `any` when the body
+// returns a value and void otherwise.
+func (c *Checker) signature(fn *ast.AtqarmLiteral) *Signature {
+	if sig, ok := c.info.Funcs[fn]; ok {
+		return sig
+	}
+	sig := &Signature{Result: Typ[Void]}
+	if len(fn.TypeParams) > 0 {
+		c.openScope()
+		defer c.closeScope()
+		sig.TypeParams = c.typeParams(fn)
+		c.info.generics[sig] = fn
+	}
+	for _, p := range fn.Parameters {
+		if p.Type == nil {
+			sig.Params = append(sig.Params, Typ[Any])
+		} else {
+			sig.Params = append(sig.Params, c.resolve(p.Type))
+		}
+	}
+	if fn.ReturnType != nil {
+		sig.Result = c.resolve(fn.ReturnType)
+	} else if returnsValue(fn.Body) {
+		sig.Result = Typ[Any]
+	}
+	c.info.Funcs[fn] = sig
+	c.info.bodies[sig] = fn
+	return sig
+}
+
+func returnsValue(block *ast.BlockStatement) bool {
+	if block == nil {
+		return false
+	}
+	for _, s := range block.Statements {
+		switch s := s.(type) {
+		case *ast.QaıtarStatement:
+			if s.ReturnValue != nil {
+				return true
+			}
+		case *ast.BlockStatement:
+			if returnsValue(s) {
+				return true
+			}
+		case *ast.AzirsheStatement:
+			if returnsValue(s.Body) {
+				return true
+			}
+		case *ast.ExpressionStatement:
+			if e, ok := s.Expression.(*ast.EgerExpression); ok {
+				if returnsValue(e.Consequence) || returnsValue(e.Alternative) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// declareFuncs declares the functions of a statement list up front so that
+// they may be called before their declaration and recursively.
+func (c *Checker) declareFuncs(stmts []ast.Statement) {
+	for _, s := range stmts {
+		if name, fn := ast.FuncDecl(s); fn != nil {
+			sig := c.signature(fn)
+			c.declare(name, FuncSym, sig).Exported = c.exported(s)
+		}
+	}
+}
+
+// exported reports whether s is an `ashyq` declaration, which is only
+// allowed at the top level.
+func (c *Checker) exported(s ast.Statement) bool {
+	public := false
+	switch s := s.(type) {
+	case *ast.JasaStatement:
+		public = s.Public
+	case *ast.BekitStatement:
+		public = s.Public
+	}
+	if public && c.scope.parent != Universe {
+		c.errorf(s, msg.AshyqNotTop)
+		return false
+	}
+	return public
+}
+
+// --- Statements ---
+
+func (c *Checker) stmt(s ast.Statement) {
+	switch s := s.(type) {
+	case *ast.JasaStatement:
+		c.binding(s.Name, s.Type, s.Value, VarSym)
+		c.export(s, s.Name)
+	case *ast.BekitStatement:
+		c.binding(s.Name, s.Type, s.Value, ConstSym)
+		c.export(s, s.Name)
+	case *ast.ModulStatement:
+	case *ast.EngizStatement:
+		c.engiz(s)
+	case *ast.QaıtarStatement:
+		c.qaıtar(s)
+	case *ast.ExpressionStatement:
+		if id, ok := s.Expression.(*ast.Identifier); ok && c.scope.Lookup(id.Value) == nil {
+			// A word standing alone is more likely a misspelled keyword,
+			// as in `qaytar x`, than a name.
+			c.errorf(id, msg.Undefined, id.Value).Suggest(id.Token, append(c.visible(), lexer.Keywords()...))
+			c.record(id, Typ[Invalid])
+			return
+		}
+		c.expr(s.Expression)
+	case *ast.AssignStatement:
+		c.assign(s)
+	case *ast.BlockStatement:
+		c.block(s)
+	case *ast.AzirsheStatement:
+		c.condition(s.Condition)
+		c.block(s.Body)
+	case *ast.KutStatement:
+		c.kut(s)
+	case *ast.MindetStatement:
+		c.mindet(s)
+	case *ast.TandaStatement:
+		c.tanda(s)
+	}
+}
+
+// export marks the symbol declared by s as exported when s is ashyq.
+func (c *Checker) export(s ast.Statement, name *ast.Identifier) {
+	if sym := c.info.Defs[name]; sym != nil && c.exported(s) {
+		sym.Exported = true
+	}
+}
+
+// engiz binds the qualifier of an imported module.
+func (c *Checker) engiz(s *ast.EngizStatement) {
+	mod := c.imports[s]
+	if mod == nil {
+		c.errorf(s, msg.NotLoaded, s.Path.Value)
+		return
+	}
+	id := &ast.Identifier{Token: s.Path.Token, Value: s.Name()}
+	c.declare(id, ModuleSym, mod)
+}
+
+func (c *Checker) binding(name *ast.Identifier, tn *ast.TypeNode, value ast.Expression, kind SymbolKind) {
+	if fn, ok := value.(*ast.AtqarmLiteral); ok {
+		sig := c.signature(fn)
+		if c.scope.LookupLocal(name.Value) == nil || c.info.Defs[name] == nil {
+			c.declare(name, FuncSym, sig)
+		}
+		c.funcBody(fn, sig)
+		c.record(fn, sig)
+		return
+	}
+
+	var declared Type
+	if tn != nil {
+		declared = c.resolve(tn)
+	}
+	if value == nil {
+		c.declare(name, kind, declared)
+		return
+	}
+	vt := c.expr(value)
+	switch {
+	case IsVoid(vt):
+		c.errorf(value, msg.NoValue, value)
+		vt = Typ[Invalid]
+	case declared != nil:
+		c.assignable(value, vt, declared, msg.Sprintf(msg.InDecl, name.Value))
+	}
+	if declared == nil {
+		declared = Default(vt)
+		c.convertUntyped(value, declared)
+	}
+	c.declare(name, kind, declared)
+}
+
+func (c *Checker) funcBody(fn *ast.AtqarmLiteral, sig *Signature) {
+	if len(fn.TypeParams) > 0 {
+		outer := c.generic
+		c.generic = fn
+		c.openScope()
+		for _, p := range fn.TypeParams {
+			c.scope.Insert(c.info.Defs[p.Name])
+		}
+		defer func() {
+			c.closeScope()
+			c.generic = outer
+		}()
+	}
+	outer, outerFn, outerScope := c.result, c.fn, c.fnScope
+	c.result = sig.Result
+	c.openScope()
+	c.fn, c.fnScope = fn, c.scope
+	c.info.Scopes[fn.Body] = c.scope
+	for i, p := range fn.Parameters {
+		c.declare(p.Name, VarSym, sig.Params[i])
+	}
+	c.declareFuncs(fn.Body.Statements)
+	for _, s := range fn.Body.Statements {
+		c.stmt(s)
+	}
+	c.closeScope()
+	c.result, c.fn, c.fnScope = outer, outerFn, outerScope
+}
+
+func (c *Checker) block(b *ast.BlockStatement) {
+	c.openScope()
+	c.info.Scopes[b] = c.scope
+	c.declareFuncs(b.Statements)
+	for _, s := range b.Statements {
+		c.stmt(s)
+	}
+	c.closeScope()
+}
+
+func (c *Checker) qaıtar(s *ast.QaıtarStatement) {
+	for r := c.region; r != nil; r = r.outer {
+		if r.fn == c.fn {
+			c.errorf(s, msg.ReturnInParallel, c.keyword(token.QAITAR), c.keyword(r.keyword()))
+			break
+		}
+	}
+	if s.ReturnValue == nil {
+		if c.result != nil && !IsVoid(c.result) && !IsAny(c.result) {
+			c.errorf(s, msg.MissingReturn, c.result)
+		}
+		return
+	}
+	vt := c.expr(s.ReturnValue)
+	if c.result == nil {
+		return
+	}
+	if IsVoid(c.result) {
+		c.errorf(s.ReturnValue, msg.TooManyReturns)
+		return
+	}
+	c.assignable(s.ReturnValue, vt, c.result, msg.Text(msg.InReturn))
+}
+
+func (c *Checker) assign(s *ast.AssignStatement) {
+	var target Type
+	switch t := s.Target.(type) {
+	case *ast.Identifier:
+		sym := c.lookup(t)
+		if sym == nil {
+			c.expr(s.Value)
+			return
+		}
+		switch sym.Kind {
+		case ConstSym:
+			c.errorf(t, msg.AssignConst, t.Value)
+		case FuncSym, TypeSym, BuiltinSym, ModuleSym:
+			c.errorf(t, msg.CannotAssign, t.Value)
+		}
+		c.assigned(t, t, sym)
+		target = sym.Type
+		c.record(t, target)
+	case *ast.SelectorExpression:
+		target = c.expr(t)
+		c.errorf(t, msg.AssignOtherModule, t)
+	case *ast.PrefixExpression:
+		target = c.expr(t)
+		if id, ok := t.Right.(*ast.Identifier); ok {
+			if sym := c.info.Uses[id]; sym != nil {
+				c.wrote(t, id, sym)
+			}
+		}
+	default:
+		target = c.expr(s.Target)
+	}
+	vt := c.expr(s.Value)
+	c.assignable(s.Value, vt, target, msg.Text(msg.InAssign))
+}
+
+func (c *Checker) condition(e ast.Expression) {
+	t := c.expr(e)
+	c.assignable(e, t, Typ[Aqıqat], msg.Text(msg.InCondition))
+}
+
+// --- Expressions ---
+
+func (c *Checker) record(e ast.Expression, t Type) Type {
+	c.info.Types[e] = t
+	return t
+}
+
+// convertUntyped gives an untyped expression tree its final type.
+func (c *Checker) convertUntyped(e ast.Expression, t Type) {
+	if lit, ok := e.(*ast.JyimLiteral); ok && c.untyped[lit] {
+		if a, ok := t.(*Array); ok && !IsAny(a.Elem) {
+			delete(c.untyped, lit)
+			c.info.Types[lit] = a
+			for _, el := range lit.Elements {
+				c.convertUntyped(el, a.Elem)
+			}
+		}
+		return
+	}
+	if !IsUntyped(c.info.Types[e]) || IsUntyped(t) || IsAny(t) {
+		return
+	}
+	c.info.Types[e] = t
+	switch e := e.(type) {
+	case *ast.PrefixExpression:
+		c.convertUntyped(e.Right, t)
+	case *ast.InfixExpression:
+		switch e.Operator {
+		case "==", "!=", "<", "<=", ">", ">=", "&&", "||":
+		case "<<", ">>":
+			c.convertUntyped(e.Left, t)
+		default:
+			c.convertUntyped(e.Left, t)
+			c.convertUntyped(e.Right, t)
+		}
+	}
+}
+
+// assignable reports an error unless a value of type vt may be used as t.
+func (c *Checker) assignable(e ast.Expression, vt, t Type, context string) bool {
+	if isKind(vt, Invalid) || isKind(t, Invalid) {
+		return false
+	}
+	if !AssignableTo(vt, t) && !c.untypedAs(e, t) {
+		c.errorf(e, msg.CannotUse, e, vt, t, context)
+		return false
+	}
+	c.convertUntyped(e, t)
+	return true
+}
+
+// visible returns the names in scope, for suggestions.
+func (c *Checker) visible() []string {
+	var names []string
+	for s := c.scope; s != nil; s = s.Parent() {
+		names = append(names, s.Names()...)
+	}
+	return names
+}
+
+// exportedNames returns the names a module exports.
+func exportedNames(s *Scope) []string {
+	var names []string
+	for _, name := range s.Names() {
+		if s.LookupLocal(name).Exported {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (c *Checker) lookup(id *ast.Identifier) *Symbol {
+	sym := c.scope.Lookup(id.Value)
+	if sym == nil {
+		c.errorf(id, msg.Undefined, id.Value).Suggest(id.Token, c.visible())
+		c.record(id, Typ[Invalid])
+		return nil
+	}
+	c.info.Uses[id] = sym
+	return sym
+}
+
+func (c *Checker) expr(e ast.Expression) Type {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		sym := c.lookup(e)
+		if sym == nil {
+			return Typ[Invalid]
+		}
+		switch sym.Kind {
+		case TypeSym:
+			c.errorf(e, msg.TypeNotExpr, e.Value)
+			return c.record(e, Typ[Invalid])
+		case ModuleSym:
+			c.errorf(e, msg.ModulNoSelector, e.Value)
+			return c.record(e, Typ[Invalid])
+		}
+		c.shared(e, e, sym, sym.Type)
+		return c.record(e, sym.Type)
+	case *ast.SanLiteral:
+		return c.record(e, Typ[UntypedInt])
+	case *ast.AqshaLiteral:
+		return c.record(e, Typ[UntypedFloat])
+	case *ast.JolLiteral:
+		return c.record(e, Typ[Jol])
+	case *ast.AqıqatLiteral:
+		return c.record(e, Typ[Aqıqat])
+	case *ast.JyimLiteral:
+		return c.record(e, c.jyimLiteral(e))
+	case *ast.PrefixExpression:
+		return c.record(e, c.prefix(e))
+	case *ast.InfixExpression:
+		return c.record(e, c.infix(e))
+	case *ast.EgerExpression:
+		return c.record(e, c.eger(e))
+	case *ast.AtqarmLiteral:
+		sig := c.signature(e)
+		c.funcBody(e, sig)
+		return c.record(e, sig)
+	case *ast.QatarlasExpression:
+		return c.record(e, c.qatarlas(e))
+	case *ast.CallExpression:
+		return c.record(e, c.call(e))
+	case *ast.IndexExpression:
+		return c.record(e, c.index(e))
+	case *ast.SelectorExpression:
+		return c.record(e, c.selector(e))
+	case *ast.TypeNode:
+		c.resolve(e)
+		c.errorf(e, msg.TypeNotExpr, e)
+		return c.record(e, Typ[Invalid])
+	case *ast.BadExpr:
+		return c.record(e, Typ[Invalid]) // reported by the parser
+	}
+	return Typ[Invalid]
+}
+
+// selector resolves a name qualified by an imported module.
+func (c *Checker) selector(e *ast.SelectorExpression) Type {
+	id, ok := e.X.(*ast.Identifier)
+	if !ok {
+		c.expr(e.X)
+		c.errorf(e, msg.InvalidSelector, e)
+		return Typ[Invalid]
+	}
+	sym := c.lookup(id)
+	if sym == nil {
+		return Typ[Invalid]
+	}
+	mod, ok := sym.Type.(*Module)
+	if !ok {
+		c.errorf(e, msg.NotModul, e, id.Value)
+		return Typ[Invalid]
+	}
+	c.record(id, mod)
+	target := mod.Scope.LookupLocal(e.Sel.Value)
+	if target == nil {
+		c.errorf(e.Sel, msg.Undefined, e).Suggest(e.Sel.Token, exportedNames(mod.Scope))
+		return c.record(e.Sel, Typ[Invalid])
+	}
+	if !target.Exported {
+		d := c.errorf(e.Sel, msg.Unexported, e, c.keyword(token.ASHYQ), c.keyword(token.MODUL), mod.Name)
+		if target.Decl != nil {
+			d.Label(target.Decl.Token, msg.Sprintf(msg.DeclaredHere, target.Name))
+		}
+	}
+	c.info.Uses[e.Sel] = target
+	return c.record(e.Sel, target.Type)
+}
+
+func (c *Checker) jyimLiteral(e *ast.JyimLiteral) Type {
+	var elem Type
+	untyped := true
+	for _, el := range e.Elements {
+		t := c.expr(el)
+		if lit, ok := el.(*ast.JyimLiteral); !IsUntyped(t) && !(ok && c.untyped[lit]) {
+			untyped = false
+		}
+		switch {
+		case elem == nil:
+			elem = t
+		case AssignableTo(t, elem):
+		case AssignableTo(elem, t):
+			elem = t
+		default:
+			c.errorf(el, msg.MixedElements, elem, t)
+			return Typ[Invalid]
+		}
+	}
+	if elem == nil {
+		return &Array{Elem: Typ[Any]}
+	}
+	if untyped {
+		// The elements keep their untyped types until the literal is used.
+		if c.untyped == nil {
+			c.untyped = make(map[*ast.JyimLiteral]bool)
+		}
+		c.untyped[e] = true
+		return &Array{Elem: Default(elem)}
+	}
+	elem = Default(elem)
+	for _, el := range e.Elements {
+		c.convertUntyped(el, elem)
+	}
+	return &Array{Elem: elem}
+}
+
+// untypedAs reports whether e is an array literal of untyped constants
+// whose elements may all be used as elements of t.
+func (c *Checker) untypedAs(e ast.Expression, t Type) bool {
+	lit, ok := e.(*ast.JyimLiteral)
+	a, isArray := t.(*Array)
+	if !ok || !isArray || !c.untyped[lit] {
+		return false
+	}
+	for _, el := range lit.Elements {
+		if !AssignableTo(c.info.Types[el], a.Elem) && !c.untypedAs(el, a.Elem) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Checker) prefix(e *ast.PrefixExpression) Type {
+	if e.Operator == "&" {
+		id, ok := e.Right.(*ast.Identifier)
+		if !ok {
+			c.expr(e.Right)
+			c.errorf(e, msg.AddressOf, e.Right)
+			return Typ[Invalid]
+		}
+		t := c.expr(id)
+		sym := c.info.Uses[id]
+		if sym != nil && sym.Kind != VarSym {
+			c.errorf(e, msg.AddressOf, id.Value)
+		}
+		if sym != nil {
+			c.shared(e, id, sym, &Pointer{Elem: t})
+			c.wrote(e, id, sym) // the pointer may be used to assign it
+		}
+		return &Pointer{Elem: t}
+	}
+
+	t := c.expr(e.Right)
+	if isKind(t, Invalid) || IsAny(t) {
+		return t
+	}
+	switch e.Operator {
+	case "-":
+		if !IsNumeric(t) || isKind(t, U64) {
+			c.errorf(e, msg.InvalidUnary, "-", e.Right, t)
+			return Typ[Invalid]
+		}
+		return t
+	case "!":
+		if !Identical(t, Typ[Aqıqat]) {
+			c.errorf(e, msg.InvalidUnary, "!", e.Right, t)
+			return Typ[Invalid]
+		}
+		return t
+	case "*":
+		p, ok := t.(*Pointer)
+		if !ok {
+			c.errorf(e, msg.CannotDeref, e.Right, t)
+			return Typ[Invalid]
+		}
+		return p.Elem
+	}
+	c.errorf(e, msg.UnknownOperator, e.Operator)
+	return Typ[Invalid]
+}
+
+// unify returns the common type of two operands, converting an untyped
+// operand to the type of the other one. It returns nil on mismatch.
+func (c *Checker) unify(le, re ast.Expression, l, r Type) Type {
+	switch {
+	case Identical(l, r):
+		return l
+	case IsAny(l) || IsAny(r):
+		return Typ[Any]
+	case IsUntyped(l) && IsUntyped(r):
+		if IsFloat(l) || IsFloat(r) {
+			return Typ[UntypedFloat]
+		}
+		return Typ[UntypedInt]
+	case IsUntyped(l) && AssignableTo(l, r):
+		c.convertUntyped(le, r)
+		return r
+	case IsUntyped(r) && AssignableTo(r, l):
+		c.convertUntyped(re, l)
+		return l
+	}
+	return nil
+}
+
+func (c *Checker) infix(e *ast.InfixExpression) Type {
+	l, r := c.expr(e.Left), c.expr(e.Right)
+	if isKind(l, Invalid) || isKind(r, Invalid) {
+		return Typ[Invalid]
+	}
+	mismatch := func() Type {
+		c.errorf(e, msg.MismatchedTypes, e, l, r).Label(Pos(e.Left), l.String()).Label(Pos(e.Right), r.String())
+		return Typ[Invalid]
+	}
+
+	switch e.Operator {
+	case "&&", "||":
+		if !AssignableTo(l, Typ[Aqıqat]) || !AssignableTo(r, Typ[Aqıqat]) {
+			return mismatch()
+		}
+		return Typ[Aqıqat]
+	case "<<", ">>":
+		if (!IsInteger(l) && !IsAny(l)) || (!IsInteger(r) && !IsAny(r)) {
+			c.errorf(e, msg.InvalidShift, l, r)
+			return Typ[Invalid]
+		}
+		return l
+	}
+
+	t := c.unify(e.Left, e.Right, l, r)
+	if t == nil {
+		return mismatch()
+	}
+	if IsAny(t) {
+		switch e.Operator {
+		case "==", "!=", "<", "<=", ">", ">=":
+			return Typ[Aqıqat]
+		}
+		return t
+	}
+
+	switch e.Operator {
+	case "==", "!=":
+		return Typ[Aqıqat]
+	case "<", "<=", ">", ">=":
+		if !IsOrdered(t) {
+			c.errorf(e, msg.NotOrdered, e, t)
+			return Typ[Invalid]
+		}
+		return Typ[Aqıqat]
+	case "+":
+		if isKind(t, Jol) {
+			return t
+		}
+		fallthrough
+	case "-", "*", "/":
+		if !IsNumeric(t) {
+			c.errorf(e, msg.OperatorUndefined, e.Operator, e.Left, t)
+			return Typ[Invalid]
+		}
+		return t
+	case "%", "&", "|", "^":
+		if !IsInteger(t) {
+			c.errorf(e, msg.OperatorUndefined, e.Operator, e.Left, t)
+			return Typ[Invalid]
+		}
+		return t
+	}
+	c.errorf(e, msg.UnknownOperator, e.Operator)
+	return Typ[Invalid]
+}
+
+func (c *Checker) eger(e *ast.EgerExpression) Type {
+	c.condition(e.Condition)
+	c.block(e.Consequence)
+	if e.Alternative == nil {
+		return Typ[Void]
+	}
+	c.block(e.Alternative)
+
+	// Both branches ending in an expression make the eger a value.
+	lt, lok := c.blockValue(e.Consequence)
+	rt, rok := c.blockValue(e.Alternative)
+	if !lok || !rok {
+		return Typ[Void]
+	}
+	if t := c.unify(nil, nil, lt, rt); t != nil {
+		return t
+	}
+	return Typ[Void]
+}
+
+func (c *Checker) blockValue(b *ast.BlockStatement) (Type, bool) {
+	if len(b.Statements) == 0 {
+		return nil, false
+	}
+	es, ok := b.Statements[len(b.Statements)-1].(*ast.ExpressionStatement)
+	if !ok {
+		return nil, false
+	}
+	t := c.info.Types[es.Expression]
+	if t == nil || IsVoid(t) {
+		return nil, false
+	}
+	return t, true
+}
+
+func (c *Checker) call(e *ast.CallExpression) Type {
+	if tn, ok := e.Function.(*ast.TypeNode); ok {
+		return c.makeChan(e, tn)
+	}
+	if id, ok := e.Function.(*ast.Identifier); ok {
+		sym := c.scope.Lookup(id.Value)
+		if sym != nil && sym.Kind == TypeSym {
+			c.info.Uses[id] = sym
+			c.record(id, sym.Type)
+			if _, ok := sym.Type.(*Constraint); ok {
+				c.exprs(e.Arguments)
+				c.errorf(e, msg.ConstraintAsType, id.Value)
+				return Typ[Invalid]
+			}
+			return c.conversion(e, sym.Type)
+		}
+		if sym != nil && sym.Kind == BuiltinSym {
+			c.info.Uses[id] = sym
+			c.record(id, sym.Type)
+			args := c.exprs(e.Arguments)
+			return builtinRules[id.Value](c, e, args)
+		}
+	}
+
+	ft := c.expr(e.Function)
+	args := c.exprs(e.Arguments)
+	switch sig := ft.(type) {
+	case *Signature:
+		if fn := c.info.bodies[sig]; fn != nil {
+			c.called(e, fn)
+		}
+		if len(args) != len(sig.Params) {
+			c.errorf(e, msg.ArgCount, e.Function, len(args), fmt.Sprint(len(sig.Params)))
+			if len(sig.TypeParams) > 0 {
+				return Typ[Invalid]
+			}
+			return sig.Result
+		}
+		if len(sig.TypeParams) > 0 {
+			if sig = c.instantiate(e, sig, args); sig == nil {
+				return Typ[Invalid]
+			}
+		}
+		for i, a := range args {
+			c.assignable(e.Arguments[i], a, sig.Params[i], msg.Sprintf(msg.InArgument, e.Function))
+		}
+		return sig.Result
+	case *Basic:
+		if sig.Kind == Invalid || sig.Kind == Any {
+			return sig
+		}
+	}
+	c.errorf(e, msg.NotFunction, e.Function, ft)
+	return Typ[Invalid]
+}
+
+// instantiate infers the type arguments of a call of a generic function
+// from the argument types, checks them against the constraints and
+// returns the instantiated signature. Untyped constants only decide a type
+// parameter that no typed argument decides, with their default type.
+func (c *Checker) instantiate(e *ast.CallExpression, sig *Signature, args []Type) *Signature {
+	m := make(map[*TypeParam]Type)
+	for _, untyped := range []bool{false, true} {
+		for i, p := range sig.Params {
+			infer(m, p, args[i], untyped)
+		}
+	}
+	targs := make([]Type, len(sig.TypeParams))
+	for i, tp := range sig.TypeParams {
+		t, ok := m[tp]
+		if !ok {
+			c.errorf(e, msg.CannotInfer, tp.Name, e.Function)
+			return nil
+		}
+		if !tp.Constraint.Satisfies(t) {
+			c.errorf(e, msg.NotSatisfied, t, tp.Constraint, tp.Name, e.Function)
+			return nil
+		}
+		targs[i] = t
+	}
+	inst := Subst(sig, m).(*Signature)
+	inst.TypeParams = nil
+	c.info.Instances[e] = &Instance{Func: c.info.generics[sig], TypeArgs: targs, Sig: inst, Outer: c.generic}
+	return inst
+}
+
+// infer matches a parameter type against an argument type and records the
+// types found for type parameters.
+func infer(m map[*TypeParam]Type, param, arg Type, untyped bool) {
+	switch p := param.(type) {
+	case *TypeParam:
+		if _, ok := m[p]; ok || IsAny(arg) || isKind(arg, Invalid) || IsUntyped(arg) != untyped {
+			return
+		}
+		m[p] = Default(arg)
+	case *Array:
+		if a, ok := arg.(*Array); ok {
+			infer(m, p.Elem, a.Elem, untyped)
+		}
+	case *Pointer:
+		if a, ok := arg.(*Pointer); ok {
+			infer(m, p.Elem, a.Elem, untyped)
+		}
+	case *Chan:
+		if a, ok := arg.(*Chan); ok {
+			infer(m, p.Elem, a.Elem, untyped)
+		}
+	}
+}
+
+func (c *Checker) conversion(e *ast.CallExpression, t Type) Type {
+	if len(e.Arguments) != 1 {
+		c.errorf(e, msg.ConversionArgs, t)
+		return t
+	}
+	at := c.expr(e.Arguments[0])
+	if isKind(at, Invalid) {
+		return t
+	}
+	if !(IsNumeric(at) && IsNumeric(t)) && !AssignableTo(at, t) {
+		c.errorf(e, msg.CannotConvert, e.Arguments[0], at, t)
+		return t
+	}
+	// An untyped operand takes the target type when it can, so that
+	// f64(1.5) never holds a decimal.
+	switch {
+	case !IsUntyped(at):
+	case AssignableTo(at, t):
+		c.convertUntyped(e.Arguments[0], t)
+	case isKind(at, UntypedFloat) && IsNumeric(t):
+		c.convertUntyped(e.Arguments[0], Typ[F64])
+	default:
+		c.convertUntyped(e.Arguments[0], Default(at))
+	}
+	return t
+}
+
+func (c *Checker) index(e *ast.IndexExpression) Type {
+	lt := c.expr(e.Left)
+	it := c.expr(e.Index)
+	if !IsInteger(it) && !IsAny(it) && !isKind(it, Invalid) {
+		c.errorf(e.Index, msg.InvalidIndex, e.Index, it)
+	} else if IsUntyped(it) {
+		c.convertUntyped(e.Index, Typ[San])
+	}
+	switch lt := lt.(type) {
+	case *Array:
+		return lt.Elem
+	case *Basic:
+		if lt.Kind == Any || lt.Kind == Invalid {
+			return lt
+		}
+	}
+	c.errorf(e, msg.CannotIndex, e.Left, lt)
+	return Typ[Invalid]
+}
+
+func (c *Checker) exprs(es []ast.Expression) []Type {
+	ts := make([]Type, len(es))
+	for i, e := range es {
+		ts[i] = c.expr(e)
+	}
+	return ts
+}
+
+// --- Built-in argument helpers ---
+
+func (c *Checker) argCount(call *ast.CallExpression, args []Type, min, max int) bool {
+	if len(args) < min || len(args) > max {
+		want := fmt.Sprint(min)
+		if max != min {
+			want = msg.Sprintf(msg.Range, min, max)
+		}
+		c.errorf(call, msg.ArgCount, call.Function, len(args), want)
+		return false
+	}
+	return true
+}
+
+func (c *Checker) argInteger(call *ast.CallExpression, args []Type, i int) {
+	if !IsInteger(args[i]) && !IsAny(args[i]) && !isKind(args[i], Invalid) {
+		c.errorf(call.Arguments[i], msg.IntegerArg, call.Arguments[i], args[i], call.Function)
+		return
+	}
+	if IsUntyped(args[i]) {
+		c.convertUntyped(call.Arguments[i], Typ[San])
+	}
+}
+
+func (c *Checker) argAssignable(call *ast.CallExpression, args []Type, i int, t Type) {
+	c.assignable(call.Arguments[i], args[i], t, msg.Sprintf(msg.InArgument, call.Function))
+}
+
+func (c *Checker) argArray(call *ast.CallExpression, args []Type, i int) *Array {
+	if a, ok := args[i].(*Array); ok {
+		return a
+	}
+	if !IsAny(args[i]) && !isKind(args[i], Invalid) {
+		c.errorf(call.Arguments[i], msg.JyimArg, call.Arguments[i], args[i], call.Function)
+	}
+	return nil
+}
+
+func (c *Checker) argChan(call *ast.CallExpression, args []Type, i int) *Chan {
+	if ch, ok := args[i].(*Chan); ok {
+		return ch
+	}
+	if !IsAny(args[i]) && !isKind(args[i], Invalid) {
+		c.errorf(call.Arguments[i], msg.ChanArg, call.Arguments[i], args[i], c.keyword(token.ARNA), call.Function)
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+// FILE: internal/lang/types/check_test.go
+
+package types_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/lexer"
+	"github.com/DauletBai/tenge/internal/lang/module"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/parser"
+	"github.com/DauletBai/tenge/internal/lang/types"
+)
+
+// check parses and type-checks src and returns the positions of its
+// type errors.
+func check(t *testing.T, src string) (*ast.Program, *types.Info, []string) {
+	t.Helper()
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("%s: %s", src, errs[0])
+	}
+	info, errs := types.Check(program)
+	var pos []string
+	for _, err := range errs {
+		pos = append(pos, err.Span.String())
+	}
+	return program, info, pos
+}
+
+func TestValid(t *testing.T) {
+	for _, src := range []string{
+		"jasa x = 1\nx = x + 2",
+		"jasa x : i32 = 1\njasa y = x * 2\ny = x",
+		"jasa a : aqsha = 1.25\na = a + 1",
+		"jasa f : f64 = 1\nf = f / 3",
+		"jasa f : atqar'm (a: san, b: san) -> san { qaıtar a + b }\nkórset(f(1, 2))",
+		"jasa f : atqar'm (n: san) -> san { eger n < 2 { qaıtar n }\nqaıtar f(n - 1) }",
+		"jasa xs : []san = [1, 2, 3]\nxs[0] = len(xs)",
+		"jasa xs : []f64 = [1, 2.5]\nxs[1] = 2",
+		"jasa m : [][]aqsha = [[1, 2], [3]]",
+		"jasa s = \"a\" + \"b\"\nkórset(s)",
+		"jasa b = 1 < 2 && !(2 < 1)",
+		"jasa x = eger 1 < 2 { 1 } áıtpece { 2 }",
+		"jasa x : i32 = i32(3.0) + 1",
+	} {
+		if _, _, errs := check(t, src); len(errs) > 0 {
+			t.Errorf("%q: errors at %v", src, errs)
+		}
+	}
+}
+
+// TestErrors checks where type errors are reported.
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"kórset(x)", []string{"1:8"}},
+		{"jasa x = 1\njasa x = 2", []string{"2:6"}},
+		{"bekit k = 1\nk = 2", []string{"2:1"}},
+		{"jasa x : san = \"a\"", []string{"1:16"}},
+		{"jasa x : i32 = 1\njasa y : san = 2\nkórset(x + y)", []string{"3:8"}},
+		{"jasa xs = [1, \"a\"]", []string{"1:15"}},
+		{"jasa f : atqar'm () -> san { qaıtar }", []string{"1:30"}},
+		{"jasa f : atqar'm () -> san { qaıtar \"a\" }", []string{"1:37"}},
+		{"jasa f : atqar'm (a: san) -> san { qaıtar a }\nf(1, 2)", []string{"2:1"}},
+		{"jasa x : qate = 1", []string{"1:10"}},
+		{"eger 1 { kórset(1) }", []string{"1:6"}},
+		{"jasa xs : []san = [1]\nxs[\"a\"] = 1", []string{"2:4"}},
+		{"jasa x = 1\nkórset(x, y, z)", []string{"2:11", "2:14"}},
+	}
+	for _, tt := range tests {
+		_, _, errs := check(t, tt.src)
+		if strings.Join(errs, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%q: errors at %v, want %v", tt.src, errs, tt.want)
+		}
+	}
+}
+
+// TestUntyped checks the types constants settle on.
+func TestUntyped(t *testing.T) {
+	tests := []struct{ src, want string }{
+		{"jasa x = 1", "san"},
+		{"jasa x = 1.5", "aqsha"},
+		{"jasa x : f64 = 1.5", "f64"},
+		{"jasa x : i32 = 1 + 2", "i32"},
+		{"jasa x : aqsha = 2 * 3", "aqsha"},
+		{"jasa x : []f64 = [1, 2]", "[]f64"},
+		{"jasa x = [1, 2]", "[]san"},
+		{"jasa x : [][]i32 = [[1], [2, 3]]", "[][]i32"},
+	}
+	for _, tt := range tests {
+		program, info, errs := check(t, tt.src)
+		if len(errs) > 0 {
+			t.Errorf("%q: errors at %v", tt.src, errs)
+			continue
+		}
+		value := program.Statements[0].(*ast.JasaStatement).Value
+		if got := info.TypeOf(value).String(); got != tt.want {
+			t.Errorf("%q: value has type %s, want %s", tt.src, got, tt.want)
+		}
+		var elems func(ast.Expression, string)
+		elems = func(e ast.Expression, want string) {
+			lit, ok := e.(*ast.JyimLiteral)
+			if !ok {
+				return
+			}
+			elem := strings.TrimPrefix(want, "[]")
+			for _, el := range lit.Elements {
+				if got := info.TypeOf(el).String(); got != elem {
+					t.Errorf("%q: element %s has type %s, want %s", tt.src, el, got, elem)
+				}
+				elems(el, elem)
+			}
+		}
+		elems(value, tt.want)
+	}
+}
+
+// TestSuggestions checks the fix offered for an undefined name: keywords
+// only for a word standing alone as a statement, and nothing for short
+// names.
+func TestSuggestions(t *testing.T) {
+	tests := []struct {
+		src, want string // want is empty for no suggestion
+	}{
+		{"atqar'm f(x: san) -> san {\nqaytar x\n}", "qaıtar"},
+		{"jasa total = 1\nkórset(totla)", "total"},
+		{"jasa total = 1\nkórset(totl)", ""},
+		{"jasa x = 1\nkórset(n)", ""},   // not fn
+		{"jasa x = 1\nkórset(i)", ""},   // not if
+		{"jasa x = 1\nkórset(end)", ""}, // not send
+		{"jasa x = 1\nkórset(qaytar)", ""},
+	}
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.src))
+		program := p.ParseProgram()
+		_, errs := types.Check(program)
+		if len(errs) == 0 {
+			t.Errorf("%q: no error", tt.src)
+			continue
+		}
+		got := ""
+		if fixes := errs[0].Fixes; len(fixes) > 0 {
+			got = fixes[0].Text
+		}
+		if got != tt.want {
+			t.Errorf("%q: suggestion %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+// TestUnexported checks that the error for an unexported name of another
+// module spells ashyq and modul the way the file referring to it does.
+func TestUnexported(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"#syntax latin\nimport \"random/xoshiro\"\nlet x: []u64 = [u64(1)]\nprint(xoshiro.splitmix64(x))",
+			"4:15: cannot refer to unexported name xoshiro.splitmix64 (declare it pub in module xoshiro)"},
+		{"#syntax kazakh\nengiz \"random/xoshiro\"\njasa x: []u64 = [u64(1)]\nkórset(xoshiro.splitmix64(x))",
+			"4:16: cannot refer to unexported name xoshiro.splitmix64 (declare it ashyq in modul xoshiro)"},
+	}
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.src))
+		prog, errs := module.LoadParsed("main.tng", p.ParseProgram())
+		if len(errs) == 0 {
+			_, errs = types.CheckModules(prog)
+		}
+		if len(errs) != 1 || errs[0].Code != msg.Unexported || errs[0].Error() != tt.want {
+			t.Errorf("%q: errors %v, want %s", tt.src, errs, tt.want)
+		}
+	}
+}
+
+// TestMixedScript checks the warning for a name that mixes scripts, as a
+// Cyrillic letter typed into a Latin name does.
+func TestMixedScript(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"jasa s\u0430n = 1\nkórset(s\u0430n)", []string{"1:6: s\u0430n mixes Latin and Cyrillic letters"}},
+		{"jasa бала_x = 1", []string{"1:6: бала_x mixes Cyrillic and Latin letters"}},
+		{"jasa бала = 1\njasa qaıtar2 = 2\njasa x1 = 3", nil},
+		{"atqar'm f(p\u0430ram: san) {}", []string{"1:11: p\u0430ram mixes Latin and Cyrillic letters"}},
+	}
+	for _, tt := range tests {
+		_, info, errs := check(t, tt.src)
+		if len(errs) > 0 {
+			t.Fatalf("%q: errors at %v", tt.src, errs)
+		}
+		var got []string
+		for _, w := range info.Warnings {
+			if w.Code == msg.MixedScript {
+				got = append(got, w.Error())
+			}
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%q: warnings %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+// TestGenerics checks the type arguments inferred for calls of generic
+// functions, and the errors for calls that break a constraint.
+func TestGenerics(t *testing.T) {
+	const mx = "atqar'm mx[T: Ordered](a: T, b: T) -> T { eger a > b { qaıtar a }\nqaıtar b }\n"
+	tests := []struct {
+		src  string
+		want []string // "pos: type arguments" of every call, or the errors
+	}{
+		{mx + "kórset(mx(1, 2))", []string{"3:10: [san]"}},
+		{mx + "kórset(mx(1.5, 2))", []string{"3:10: [aqsha]"}},
+		{mx + "jasa x: i32 = 1\nkórset(mx(x, 2))", []string{"4:10: [i32]"}}, // the untyped 2 follows x
+		{mx + "kórset(mx(\"a\", \"b\"))", []string{"3:10: [jol]"}},
+		{"atqar'm sum[T: Number](xs: []T) -> T { jasa s: T = 0\nqaıtar s }\njasa ys: []f64 = [1, 2]\nkórset(sum(ys))",
+			[]string{"4:11: [f64]"}},
+		{"atqar'm pair[K, V: Integer](k: K, v: V) {}\npair(\"k\", u64(1))", []string{"2:5: [jol u64]"}},
+
+		{mx + "kórset(mx(jan, j'n))", []string{"E0330 3:8: aqıqat does not satisfy Ordered (T in call to mx)"}},
+		{"atqar'm id[T: Integer](a: T) -> T { qaıtar a }\nkórset(id(1.5))",
+			[]string{"E0330 2:8: aqsha does not satisfy Integer (T in call to id)"}},
+		{mx + "jasa x: i32 = 1\njasa y: i64 = 1\nkórset(mx(x, y))",
+			[]string{"E0311 5:14: cannot use y (san) as i32 in argument to mx"}},
+		{"atqar'm mk[T]() -> T { qaıtar mk() }\nkórset(mk())",
+			[]string{"E0329 1:31: cannot infer T in call to mk", "E0329 2:8: cannot infer T in call to mk"}},
+		{"atqar'm f[T: Foo](a: T) {}", []string{"E0304 1:14: Foo is not a constraint (want Ordered, Number or Integer)"}},
+		{"jasa x: Ordered = 1", []string{"E0303 1:9: cannot use constraint Ordered as a type"}},
+	}
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.src))
+		program := p.ParseProgram()
+		if errs := p.Errors(); len(errs) > 0 {
+			t.Fatalf("%s: %s", tt.src, errs[0])
+		}
+		info, errs := types.Check(program)
+		var got []string
+		for _, err := range errs {
+			got = append(got, string(err.Code)+" "+err.Error())
+		}
+		if len(errs) == 0 {
+			for call, inst := range info.Instances {
+				got = append(got, fmt.Sprintf("%s: %v", call.Token.Pos(), inst.TypeArgs))
+			}
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%q:\n%s\nwant\n%s", tt.src, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
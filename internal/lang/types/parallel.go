@@ -0,0 +1,247 @@
+// FILE: internal/lang/types/parallel.go
+
+package types
+
+import (
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/token"
+)
+
+// region is a qatarlas body or kút block being checked. Code inside it
+// runs at the same time as other code, so it may not change variables
+// declared outside it, directly or through the functions it calls.
+type region struct {
+	node  ast.Node           // *ast.QatarlasExpression or *ast.KutStatement
+	scope *Scope             // holds the loop variable or the kút block
+	fn    *ast.AtqarmLiteral // function the region is in; nil at top level
+	outer *region
+}
+
+// keyword returns the keyword that opens r, for messages.
+func (r *region) keyword() token.TokenType {
+	if _, ok := r.node.(*ast.KutStatement); ok {
+		return token.KUT
+	}
+	return token.QATARLAS
+}
+
+// write is the first assignment a function makes to a variable declared
+// outside it.
+type write struct {
+	at   ast.Node
+	name string
+}
+
+// parallelCall is a call that runs in parallel with other code, whose
+// callee is checked for writes when the program has been checked.
+type parallelCall struct {
+	call *ast.CallExpression
+	fn   *ast.AtqarmLiteral
+}
+
+// enter opens a scope for the region of node and makes it the innermost
+// region.
+func (c *Checker) enter(node ast.Node) {
+	c.openScope()
+	c.region = &region{node: node, scope: c.scope, fn: c.fn, outer: c.region}
+}
+
+func (c *Checker) leave() {
+	c.region = c.region.outer
+	c.closeScope()
+}
+
+// declaredIn reports whether sym is declared in s or a scope nested in it
+// that encloses the current one.
+func (c *Checker) declaredIn(sym *Symbol, s *Scope) bool {
+	for sc := c.scope; sc != nil; sc = sc.parent {
+		if sc.LookupLocal(sym.Name) == sym {
+			return true
+		}
+		if sc == s {
+			break
+		}
+	}
+	return false
+}
+
+// assigned checks an assignment to the variable sym: it is an error in a
+// region that sym is declared outside of, and a write of the enclosing
+// function if sym is declared outside it.
+func (c *Checker) assigned(at ast.Node, id *ast.Identifier, sym *Symbol) {
+	if sym.Kind != VarSym {
+		return
+	}
+	if r := c.region; r != nil && !c.declaredIn(sym, r.scope) {
+		c.errorf(at, msg.ParallelAssign, id.Value, c.keyword(r.keyword()))
+	}
+	c.wrote(at, id, sym)
+}
+
+// wrote records a write of the variable sym if it is declared outside the
+// enclosing function.
+func (c *Checker) wrote(at ast.Node, id *ast.Identifier, sym *Symbol) {
+	if c.fn == nil || c.declaredIn(sym, c.fnScope) {
+		return
+	}
+	if _, ok := c.info.writes[c.fn]; !ok {
+		c.info.writes[c.fn] = write{at: at, name: id.Value}
+	}
+}
+
+// shared reports a pointer from outside the innermost qatarlas body used
+// in it. A kút block itself runs on one thread; its tasks get no pointers.
+func (c *Checker) shared(e ast.Expression, id *ast.Identifier, sym *Symbol, t Type) {
+	r := c.region
+	if r == nil || sym.Kind != VarSym && sym.Kind != ConstSym || c.declaredIn(sym, r.scope) {
+		return
+	}
+	if _, ok := r.node.(*ast.QatarlasExpression); !ok {
+		return
+	}
+	if _, ok := t.(*Pointer); ok {
+		c.errorf(e, msg.ParallelPointer, id.Value, t, c.keyword(r.keyword()))
+	}
+}
+
+// called records a call of the function literal fn.
+func (c *Checker) called(e *ast.CallExpression, fn *ast.AtqarmLiteral) {
+	if c.fn != nil {
+		c.info.calls[c.fn] = append(c.info.calls[c.fn], fn)
+	}
+	if c.region != nil {
+		c.parallel = append(c.parallel, parallelCall{call: e, fn: fn})
+	}
+}
+
+// checkParallelCalls reports the calls in regions of functions that
+// assign, themselves or through the functions they call, variables
+// declared outside them.
+func (c *Checker) checkParallelCalls() {
+	seen := make(map[*ast.AtqarmLiteral]*ast.AtqarmLiteral)
+	var find func(fn *ast.AtqarmLiteral) *ast.AtqarmLiteral
+	find = func(fn *ast.AtqarmLiteral) *ast.AtqarmLiteral {
+		if w, ok := seen[fn]; ok {
+			return w
+		}
+		seen[fn] = nil
+		if _, ok := c.info.writes[fn]; ok {
+			seen[fn] = fn
+			return fn
+		}
+		for _, callee := range c.info.calls[fn] {
+			if w := find(callee); w != nil {
+				seen[fn] = w
+				return w
+			}
+		}
+		return nil
+	}
+	for _, pc := range c.parallel {
+		fn := find(pc.fn)
+		if fn == nil {
+			continue
+		}
+		w := c.info.writes[fn]
+		name := fn.Name
+		if name == "" {
+			name = token.ATQARM
+		}
+		c.errorf(pc.call, msg.ParallelEffect, name, w.name).Label(Pos(w.at), msg.Sprintf(msg.AssignedHere, w.name))
+	}
+	c.parallel = nil
+}
+
+// qatarlas checks a parallel loop and returns the type of its reduction,
+// void without one.
+func (c *Checker) qatarlas(e *ast.QatarlasExpression) Type {
+	for _, bound := range []ast.Expression{e.From, e.To} {
+		c.assignable(bound, c.expr(bound), Typ[San], msg.Text(msg.InRange))
+	}
+	c.enter(e)
+	c.declare(e.Var, ConstSym, Typ[San])
+	c.block(e.Body)
+	c.leave()
+
+	reduction := e.Reduction()
+	switch reduction {
+	case "":
+		return Typ[Void]
+	case "?":
+		c.errorf(e.Reduce, msg.UnknownReduction, e.Reduce.Value)
+		return Typ[Invalid]
+	}
+	t, ok := c.blockValue(e.Body)
+	if !ok {
+		c.errorf(e.Reduce, msg.ReductionValue, c.keyword(token.QATARLAS), e.Reduce.Value)
+		return Typ[Invalid]
+	}
+	last := e.Body.Statements[len(e.Body.Statements)-1].(*ast.ExpressionStatement).Expression
+	t = Default(t)
+	c.convertUntyped(last, t)
+	if reduction == ast.Collect {
+		return &Array{Elem: t}
+	}
+	if !IsNumeric(t) && !IsAny(t) && !isKind(t, Invalid) {
+		c.errorf(e.Reduce, msg.ReductionType, e.Reduce.Value, t)
+		return Typ[Invalid]
+	}
+	return t
+}
+
+func (c *Checker) kut(s *ast.KutStatement) {
+	c.enter(s)
+	c.block(s.Body)
+	c.leave()
+}
+
+// mindet checks a task: a call, in the kút block that directly encloses
+// it, of a function declared outside that block, whose result goes to a
+// variable declared outside it.
+func (c *Checker) mindet(s *ast.MindetStatement) {
+	r := c.region
+	if r != nil {
+		if _, ok := r.node.(*ast.KutStatement); !ok || r.fn != c.fn {
+			r = nil
+		}
+	}
+	if r == nil {
+		c.errorf(s, msg.TaskOutsideKut, c.keyword(token.MINDET), c.keyword(token.KUT))
+	}
+	result := c.expr(s.Call)
+	if id, ok := s.Call.Function.(*ast.Identifier); ok {
+		if sym := c.info.Uses[id]; sym != nil && r != nil && (sym.Kind == BuiltinSym || sym.Kind == TypeSym || c.declaredIn(sym, r.scope)) {
+			c.errorf(s.Call, msg.TaskFunc, c.keyword(token.MINDET), c.keyword(token.KUT), id.Value)
+		}
+	}
+	for _, a := range s.Call.Arguments {
+		if t, ok := c.info.Types[a].(*Pointer); ok {
+			c.errorf(a, msg.ParallelPointer, a, t, c.keyword(token.MINDET))
+		}
+	}
+	if s.Target == nil {
+		return
+	}
+	sym := c.lookup(s.Target)
+	if sym == nil {
+		return
+	}
+	switch sym.Kind {
+	case VarSym:
+		if r != nil && c.declaredIn(sym, r.scope) {
+			c.errorf(s.Target, msg.TaskTarget, c.keyword(token.MINDET), c.keyword(token.KUT), s.Target.Value)
+		}
+		c.wrote(s.Target, s.Target, sym)
+	case ConstSym:
+		c.errorf(s.Target, msg.AssignConst, s.Target.Value)
+	default:
+		c.errorf(s.Target, msg.CannotAssign, s.Target.Value)
+	}
+	c.record(s.Target, sym.Type)
+	if IsVoid(result) {
+		c.errorf(s.Call, msg.NoValue, s.Call)
+		return
+	}
+	c.assignable(s.Call, result, sym.Type, msg.Text(msg.InAssign))
+}
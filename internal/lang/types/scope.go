@@ -0,0 +1,77 @@
+// FILE: internal/lang/types/scope.go
+
+package types
+
+import "github.com/DauletBai/tenge/internal/lang/ast"
+
+// SymbolKind classifies what a name refers to.
+type SymbolKind int
+
+const (
+	VarSym     SymbolKind = iota // jasa
+	ConstSym                     // bekit
+	FuncSym                      // atqar'm declaration
+	TypeSym                      // type name
+	BuiltinSym                   // built-in function
+	ModuleSym                    // imported module qualifier
+)
+
+// Symbol is a named entity in a scope.
+type Symbol struct {
+	Name string
+	Kind SymbolKind
+	Type Type
+	Decl *ast.Identifier // declaring identifier; nil for universe symbols
+
+	Exported bool // declared `ashyq` at the top level of a module
+}
+
+// Scope maps names to symbols and links to its enclosing scope.
+type Scope struct {
+	parent  *Scope
+	symbols map[string]*Symbol
+}
+
+// NewScope returns an empty scope nested in parent.
+func NewScope(parent *Scope) *Scope {
+	return &Scope{parent: parent, symbols: make(map[string]*Symbol)}
+}
+
+// Parent returns the enclosing scope.
+func (s *Scope) Parent() *Scope { return s.parent }
+
+// Lookup finds name in s or its enclosing scopes.
+func (s *Scope) Lookup(name string) *Symbol {
+	for ; s != nil; s = s.parent {
+		if sym, ok := s.symbols[name]; ok {
+			return sym
+		}
+	}
+	return nil
+}
+
+// LookupLocal finds name in s only.
+func (s *Scope) LookupLocal(name string) *Symbol {
+	return s.symbols[name]
+}
+
+// Insert adds sym to s and returns any symbol it replaced.
+func (s *Scope) Insert(sym *Symbol) *Symbol {
+	prev := s.symbols[sym.Name]
+	s.symbols[sym.Name] = sym
+	return prev
+}
+
+// Remove removes name from s.
+func (s *Scope) Remove(name string) {
+	delete(s.symbols, name)
+}
+
+// Names returns the names declared directly in s.
+func (s *Scope) Names() []string {
+	names := make([]string, 0, len(s.symbols))
+	for name := range s.symbols {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,363 @@
+// FILE: internal/lang/types/types.go
+
+// Package types implements the tenge type checker. It resolves identifiers,
+// computes the type of every expression and reports type errors.
+package types
+
+import (
+	"strings"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+)
+
+// Kind identifies a basic type.
+type Kind int
+
+const (
+	Invalid Kind = iota
+	Any          // unannotated parameters and j'i'm elements; compatible with everything
+	Void         // result of functions that return nothing
+	Aqıqat       // aqıqat: boolean
+	San          // san, i64: 64-bit signed integer
+	I32          // i32: 32-bit signed integer
+	U64          // u64: 64-bit unsigned integer
+	F64          // f64: binary floating point
+	Aqsha        // aqsha: exact decimal for money
+	Jol          // jol: string
+	Tańba        // tańba: character
+
+	// Literal constants stay untyped until they meet a typed operand or
+	// a declaration, like Go's untyped constants.
+	UntypedInt
+	UntypedFloat
+)
+
+// Type is implemented by all tenge types.
+type Type interface {
+	String() string
+}
+
+// Basic is a predeclared scalar type.
+type Basic struct {
+	Kind Kind
+	name string
+}
+
+func (b *Basic) String() string { return b.name }
+
+// Typ holds the predeclared basic types indexed by Kind.
+var Typ = [...]*Basic{
+	Invalid:      {Invalid, "invalid"},
+	Any:          {Any, "any"},
+	Void:         {Void, "void"},
+	Aqıqat:       {Aqıqat, "aqıqat"},
+	San:          {San, "san"},
+	I32:          {I32, "i32"},
+	U64:          {U64, "u64"},
+	F64:          {F64, "f64"},
+	Aqsha:        {Aqsha, "aqsha"},
+	Jol:          {Jol, "jol"},
+	Tańba:        {Tańba, "tańba"},
+	UntypedInt:   {UntypedInt, "untyped san"},
+	UntypedFloat: {UntypedFloat, "untyped aqsha"},
+}
+
+// Array is a growable array (`j'i'm`, `[]T`).
+type Array struct {
+	Elem Type
+}
+
+func (a *Array) String() string {
+	if isKind(a.Elem, Any) {
+		return "j'i'm"
+	}
+	return "[]" + a.Elem.String()
+}
+
+// Pointer is a reference to a variable (`&T`).
+type Pointer struct {
+	Elem Type
+}
+
+func (p *Pointer) String() string { return "&" + p.Elem.String() }
+
+// Chan is a channel passing values of type Elem between tasks
+// (`arna[T]`).
+type Chan struct {
+	Elem Type
+}
+
+func (c *Chan) String() string { return "arna[" + c.Elem.String() + "]" }
+
+// Signature is the type of an `atqar'm` function.
+type Signature struct {
+	TypeParams []*TypeParam // non-empty for generic functions
+	Params     []Type
+	Result     Type // Typ[Void] when nothing is returned
+}
+
+func (s *Signature) String() string {
+	out := "atqar'm "
+	if len(s.TypeParams) > 0 {
+		tparams := make([]string, len(s.TypeParams))
+		for i, tp := range s.TypeParams {
+			tparams[i] = tp.Name
+			if tp.Constraint != nil {
+				tparams[i] += ": " + tp.Constraint.Name
+			}
+		}
+		out += "[" + strings.Join(tparams, ", ") + "] "
+	}
+	params := make([]string, len(s.Params))
+	for i, p := range s.Params {
+		params[i] = p.String()
+	}
+	out += "(" + strings.Join(params, ", ") + ")"
+	if !isKind(s.Result, Void) {
+		out += " -> " + s.Result.String()
+	}
+	return out
+}
+
+// TypeParam is a type parameter of a generic function. Inside the function
+// it stands for every type that satisfies Constraint.
+type TypeParam struct {
+	Name       string
+	Constraint *Constraint // nil when any type is allowed
+}
+
+func (tp *TypeParam) String() string { return tp.Name }
+
+// Constraint restricts the type arguments of a type parameter. The
+// predeclared constraints nest: Integer types are Number types, and
+// Number types are Ordered.
+type Constraint struct {
+	Name string
+	kind constraintKind
+}
+
+type constraintKind int
+
+const (
+	ordered constraintKind = iota // numbers and jol: ==, <, ...
+	number                        // numbers: + - * /
+	integer                       // integers: % & | ^ << >>
+)
+
+func (c *Constraint) String() string { return c.Name }
+
+// Satisfies reports whether t may be used as a type argument for c.
+func (c *Constraint) Satisfies(t Type) bool {
+	if c == nil {
+		return !IsUntyped(t)
+	}
+	switch c.kind {
+	case integer:
+		return IsInteger(t) && !IsUntyped(t)
+	case number:
+		return IsNumeric(t) && !IsUntyped(t)
+	}
+	return IsOrdered(t) && !IsUntyped(t)
+}
+
+// Module is the type of an import qualifier. Its exported names are looked
+// up in Scope.
+type Module struct {
+	Name  string
+	Path  string
+	Scope *Scope
+}
+
+func (m *Module) String() string { return "modul " + m.Name }
+
+// Builtin is the type of a built-in function; its calls are checked by
+// the rule registered in the universe.
+type Builtin struct {
+	Name string
+}
+
+func (b *Builtin) String() string { return "builtin " + b.Name }
+
+func isKind(t Type, k Kind) bool {
+	b, ok := t.(*Basic)
+	return ok && b.Kind == k
+}
+
+// constrained reports whether t is a type parameter whose constraint is
+// at least k.
+func constrained(t Type, k constraintKind) bool {
+	tp, ok := t.(*TypeParam)
+	return ok && tp.Constraint != nil && tp.Constraint.kind >= k
+}
+
+// IsInteger reports whether t is an integer type (typed or untyped).
+func IsInteger(t Type) bool {
+	if constrained(t, integer) {
+		return true
+	}
+	b, ok := t.(*Basic)
+	return ok && (b.Kind == San || b.Kind == I32 || b.Kind == U64 || b.Kind == UntypedInt)
+}
+
+// IsFloat reports whether t is f64 or an untyped decimal constant.
+func IsFloat(t Type) bool {
+	return isKind(t, F64) || isKind(t, UntypedFloat)
+}
+
+// IsNumeric reports whether arithmetic is defined on t.
+func IsNumeric(t Type) bool {
+	return IsInteger(t) || IsFloat(t) || isKind(t, Aqsha) || constrained(t, number)
+}
+
+// IsOrdered reports whether the comparison operators < <= > >= are
+// defined on t.
+func IsOrdered(t Type) bool {
+	return IsNumeric(t) || isKind(t, Jol) || constrained(t, ordered)
+}
+
+// IsUntyped reports whether t is the type of an untyped constant.
+func IsUntyped(t Type) bool {
+	return isKind(t, UntypedInt) || isKind(t, UntypedFloat)
+}
+
+// IsAny reports whether t is the dynamic `any` type.
+func IsAny(t Type) bool {
+	return isKind(t, Any)
+}
+
+// IsVoid reports whether t is the empty result type.
+func IsVoid(t Type) bool {
+	return isKind(t, Void)
+}
+
+// Default returns the type an untyped constant takes when nothing else
+// decides it: san for integers, aqsha for decimals.
+func Default(t Type) Type {
+	switch {
+	case isKind(t, UntypedInt):
+		return Typ[San]
+	case isKind(t, UntypedFloat):
+		return Typ[Aqsha]
+	}
+	return t
+}
+
+// Identical reports whether x and y are the same type.
+func Identical(x, y Type) bool {
+	switch x := x.(type) {
+	case *Basic:
+		y, ok := y.(*Basic)
+		return ok && x.Kind == y.Kind
+	case *Array:
+		y, ok := y.(*Array)
+		return ok && Identical(x.Elem, y.Elem)
+	case *Pointer:
+		y, ok := y.(*Pointer)
+		return ok && Identical(x.Elem, y.Elem)
+	case *Chan:
+		y, ok := y.(*Chan)
+		return ok && Identical(x.Elem, y.Elem)
+	case *Signature:
+		y, ok := y.(*Signature)
+		if !ok || len(x.Params) != len(y.Params) || !Identical(x.Result, y.Result) {
+			return false
+		}
+		for i := range x.Params {
+			if !Identical(x.Params[i], y.Params[i]) {
+				return false
+			}
+		}
+		return true
+	case *Builtin:
+		y, ok := y.(*Builtin)
+		return ok && x.Name == y.Name
+	case *TypeParam:
+		return x == y
+	}
+	return false
+}
+
+// Subst replaces the type parameters in t by the types they map to.
+func Subst(t Type, m map[*TypeParam]Type) Type {
+	if len(m) == 0 {
+		return t
+	}
+	switch t := t.(type) {
+	case *TypeParam:
+		if u, ok := m[t]; ok {
+			return u
+		}
+	case *Array:
+		return &Array{Elem: Subst(t.Elem, m)}
+	case *Pointer:
+		return &Pointer{Elem: Subst(t.Elem, m)}
+	case *Chan:
+		return &Chan{Elem: Subst(t.Elem, m)}
+	case *Signature:
+		sig := &Signature{TypeParams: t.TypeParams, Result: Subst(t.Result, m)}
+		for _, p := range t.Params {
+			sig.Params = append(sig.Params, Subst(p, m))
+		}
+		return sig
+	}
+	return t
+}
+
+// AssignableTo reports whether a value of type v may be stored in a
+// variable of type t.
+func AssignableTo(v, t Type) bool {
+	if Identical(v, t) || IsAny(v) || IsAny(t) {
+		return true
+	}
+	switch {
+	case isKind(v, UntypedInt):
+		return IsNumeric(t)
+	case isKind(v, UntypedFloat):
+		return IsFloat(t) || isKind(t, Aqsha)
+	}
+	if va, ok := v.(*Array); ok {
+		if ta, ok := t.(*Array); ok {
+			return IsAny(va.Elem) || IsAny(ta.Elem)
+		}
+	}
+	return false
+}
+
+// Comparand returns the type the backends convert both operands of the
+// comparison x to, given the types l and r of its operands: their type
+// when they agree, the type of one operand when the other is a number
+// literal that fits it, and otherwise the widest of f64, aqsha, u64 and
+// jol that either has, or san. Untyped constants count as their default
+// type and `any` as san, as in compiled code.
+func Comparand(x *ast.InfixExpression, l, r Type) Type {
+	l, r = compiled(l), compiled(r)
+	switch {
+	case Identical(l, r):
+		return l
+	case ast.IsNumberLiteral(x.Right) && fits(x.Right, l):
+		return l
+	case ast.IsNumberLiteral(x.Left) && fits(x.Left, r):
+		return r
+	case isKind(l, F64) || isKind(r, F64):
+		return Typ[F64]
+	case isKind(l, Aqsha) || isKind(r, Aqsha):
+		return Typ[Aqsha]
+	case isKind(l, U64) || isKind(r, U64):
+		return Typ[U64]
+	case isKind(l, Jol) || isKind(r, Jol):
+		return Typ[Jol]
+	}
+	return Typ[San]
+}
+
+func compiled(t Type) Type {
+	if IsAny(t) {
+		return Typ[San]
+	}
+	return Default(t)
+}
+
+// fits reports whether the number literal x can be a constant of type t.
+func fits(x ast.Expression, t Type) bool {
+	return isKind(t, F64) || isKind(t, Aqsha) || (IsInteger(t) || isKind(t, Tańba)) && ast.NumberValue(x).IsInteger()
+}
@@ -0,0 +1,190 @@
+// FILE: internal/lang/types/universe.go
+
+package types
+
+import (
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/token"
+)
+
+// builtinRule checks a call to a built-in and returns its result type.
+type builtinRule func(c *Checker, call *ast.CallExpression, args []Type) Type
+
+// Universe is the outermost scope holding the predeclared names.
+var Universe = NewScope(nil)
+
+var builtinRules = map[string]builtinRule{}
+
+func init() {
+	typeNames := map[string]Type{
+		"san":    Typ[San],
+		"i64":    Typ[San],
+		"i32":    Typ[I32],
+		"u64":    Typ[U64],
+		"f64":    Typ[F64],
+		"aqsha":  Typ[Aqsha],
+		"jol":    Typ[Jol],
+		"tańba":  Typ[Tańba],
+		"aqıqat": Typ[Aqıqat],
+		"j'i'm":  &Array{Elem: Typ[Any]},
+	}
+	for name, t := range typeNames {
+		Universe.Insert(&Symbol{Name: name, Kind: TypeSym, Type: t})
+	}
+	for _, c := range []*Constraint{{"Ordered", ordered}, {"Number", number}, {"Integer", integer}} {
+		Universe.Insert(&Symbol{Name: c.Name, Kind: TypeSym, Type: c})
+	}
+	Universe.Insert(&Symbol{Name: "PI", Kind: ConstSym, Type: Typ[F64]})
+
+	printRule := func(c *Checker, call *ast.CallExpression, args []Type) Type { return Typ[Void] }
+	defBuiltin("kórset", printRule)
+	defBuiltin("print", printRule)
+	defBuiltin("printi", func(c *Checker, call *ast.CallExpression, args []Type) Type {
+		if c.argCount(call, args, 1, 1) {
+			c.argInteger(call, args, 0)
+		}
+		return Typ[Void]
+	})
+	defBuiltin("print_time_ns", builtinFunc("print_time_ns", nil, IsInteger))
+	defBuiltin("printf", func(c *Checker, call *ast.CallExpression, args []Type) Type {
+		if c.argCount(call, args, 2, 2) {
+			c.argAssignable(call, args, 0, Typ[F64])
+			c.argInteger(call, args, 1)
+		}
+		return Typ[Void]
+	})
+	defBuiltin("argi", func(c *Checker, call *ast.CallExpression, args []Type) Type {
+		if c.argCount(call, args, 1, 2) {
+			for i := range args {
+				c.argInteger(call, args, i)
+			}
+		}
+		// Command-line values adapt to the declared type like a literal.
+		return Typ[UntypedInt]
+	})
+	defBuiltin("argf", func(c *Checker, call *ast.CallExpression, args []Type) Type {
+		if c.argCount(call, args, 1, 2) {
+			c.argInteger(call, args, 0)
+			if len(args) == 2 {
+				c.argAssignable(call, args, 1, Typ[F64])
+			}
+		}
+		return Typ[UntypedFloat]
+	})
+	for _, name := range []string{"now_ns", "time_ns", "now_ms"} {
+		defBuiltin(name, builtinFunc(name, Typ[San]))
+	}
+	for _, name := range []string{"sqrt", "ln", "exp", "cos", "sin", "floor"} {
+		defBuiltin(name, func(c *Checker, call *ast.CallExpression, args []Type) Type {
+			if c.argCount(call, args, 1, 1) {
+				c.argAssignable(call, args, 0, Typ[F64])
+			}
+			return Typ[F64]
+		})
+	}
+	defBuiltin("make_f64", builtinFunc("make_f64", &Array{Elem: Typ[F64]}, IsInteger))
+	defBuiltin("make_i32", builtinFunc("make_i32", &Array{Elem: Typ[I32]}, IsInteger))
+	defBuiltin("len", func(c *Checker, call *ast.CallExpression, args []Type) Type {
+		if c.argCount(call, args, 1, 1) {
+			if _, ok := args[0].(*Array); !ok && !isKind(args[0], Jol) && !IsAny(args[0]) {
+				c.errorf(call.Arguments[0], msg.InvalidArg, call.Arguments[0], args[0], "len")
+			}
+		}
+		return Typ[San]
+	})
+	defBuiltin("push", func(c *Checker, call *ast.CallExpression, args []Type) Type {
+		if !c.argCount(call, args, 2, 2) {
+			return Typ[Invalid]
+		}
+		arr := c.argArray(call, args, 0)
+		if arr == nil {
+			return args[0]
+		}
+		c.argAssignable(call, args, 1, arr.Elem)
+		return arr
+	})
+	defBuiltin("index", func(c *Checker, call *ast.CallExpression, args []Type) Type {
+		if !c.argCount(call, args, 2, 2) {
+			return Typ[Invalid]
+		}
+		c.argInteger(call, args, 1)
+		if arr := c.argArray(call, args, 0); arr != nil {
+			return arr.Elem
+		}
+		return Typ[Any]
+	})
+	defBuiltin("sort", func(c *Checker, call *ast.CallExpression, args []Type) Type {
+		if !c.argCount(call, args, 1, 1) {
+			return Typ[Invalid]
+		}
+		if arr := c.argArray(call, args, 0); arr != nil {
+			return arr
+		}
+		return args[0]
+	})
+	defBuiltin("send", func(c *Checker, call *ast.CallExpression, args []Type) Type {
+		if c.argCount(call, args, 2, 2) {
+			if ch := c.argChan(call, args, 0); ch != nil {
+				c.argAssignable(call, args, 1, ch.Elem)
+			}
+		}
+		return Typ[Void]
+	})
+	defBuiltin("recv", func(c *Checker, call *ast.CallExpression, args []Type) Type {
+		if !c.argCount(call, args, 1, 2) {
+			return Typ[Invalid]
+		}
+		if len(args) == 2 {
+			// recv(c, &ok) sets ok to false when c is closed and empty.
+			c.argAssignable(call, args, 1, &Pointer{Elem: Typ[Aqıqat]})
+		}
+		if ch := c.argChan(call, args, 0); ch != nil {
+			return ch.Elem
+		}
+		return Typ[Any]
+	})
+	defBuiltin("close", func(c *Checker, call *ast.CallExpression, args []Type) Type {
+		if c.argCount(call, args, 1, 1) {
+			c.argChan(call, args, 0)
+		}
+		return Typ[Void]
+	})
+	defBuiltin("timeout", func(c *Checker, call *ast.CallExpression, args []Type) Type {
+		c.errorf(call, msg.TimeoutOutside, c.keyword(token.TANDA))
+		return Typ[Void]
+	})
+	defBuiltin("assert", func(c *Checker, call *ast.CallExpression, args []Type) Type {
+		if c.argCount(call, args, 1, 2) {
+			c.argAssignable(call, args, 0, Typ[Aqıqat])
+			if len(args) == 2 {
+				c.argAssignable(call, args, 1, Typ[Jol])
+			}
+		}
+		return Typ[Void]
+	})
+}
+
+func defBuiltin(name string, rule builtinRule) {
+	builtinRules[name] = rule
+	Universe.Insert(&Symbol{Name: name, Kind: BuiltinSym, Type: &Builtin{Name: name}})
+}
+
+// builtinFunc builds a rule for a fixed-arity built-in whose arguments are
+// validated by the given predicates. A nil result means void.
+func builtinFunc(name string, result Type, params ...func(Type) bool) builtinRule {
+	if result == nil {
+		result = Typ[Void]
+	}
+	return func(c *Checker, call *ast.CallExpression, args []Type) Type {
+		if !c.argCount(call, args, len(params), len(params)) {
+			return result
+		}
+		for i, ok := range params {
+			if !ok(args[i]) && !IsAny(args[i]) {
+				c.errorf(call.Arguments[i], msg.InvalidArg, call.Arguments[i], args[i], name)
+			}
+		}
+		return result
+	}
+}
@@ -0,0 +1,421 @@
+// FILE: internal/lsp/document.go
+
+package lsp
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/diag"
+	"github.com/DauletBai/tenge/internal/lang/format"
+	"github.com/DauletBai/tenge/internal/lang/lexer"
+	"github.com/DauletBai/tenge/internal/lang/module"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/parser"
+	"github.com/DauletBai/tenge/internal/lang/token"
+	"github.com/DauletBai/tenge/internal/lang/types"
+)
+
+// document is an open text document and the result of checking it.
+type document struct {
+	uri     string
+	file    string // file name in positions; empty if uri is not a file
+	version int
+	text    string
+	lines   []string
+	syntax  lexer.Syntax // the keyword set of the text, for what we show
+
+	program *ast.Program
+	info    *types.Info
+	diags   diag.List
+
+	files map[string][]string // lines of other files, for positions in them
+}
+
+// analyze parses and type-checks text. Modules it imports are read from
+// disk next to the document.
+func analyze(uri string, version int, text string) *document {
+	d := &document{uri: uri, file: uriToFile(uri), version: version, text: text, lines: strings.Split(text, "\n")}
+	l := lexer.NewFile(d.file, text)
+	p := parser.New(l)
+	d.program = p.ParseProgram()
+	d.syntax = l.Syntax()
+	d.diags = append(d.diags, p.Diagnostics()...)
+
+	var errs diag.List
+	if d.file != "" && imports(d.program) {
+		prog, lerrs := module.LoadParsed(d.file, d.program)
+		if prog != nil {
+			d.info, errs = types.CheckModules(prog)
+		} else {
+			d.diags = append(d.diags, lerrs...)
+		}
+	}
+	if d.info == nil {
+		var all diag.List
+		d.info, all = types.Check(d.program)
+		for _, e := range all {
+			if e.Code != msg.NotLoaded { // the loader said why
+				errs = append(errs, e)
+			}
+		}
+	}
+	d.diags = append(d.diags, errs...)
+	d.diags = append(d.diags, d.info.Warnings...)
+	return d
+}
+
+func imports(program *ast.Program) bool {
+	for _, s := range program.Statements {
+		if _, ok := s.(*ast.EngizStatement); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func uriToFile(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+func (d *document) fileURI(file string) string {
+	if file == d.file {
+		return d.uri
+	}
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(file)}).String()
+}
+
+// --- positions ---
+//
+// Tokens count lines from 1 and columns in runes from 1; LSP counts both
+// from 0 and columns in UTF-16 code units.
+
+// line returns line n of file, counting from 1.
+func (d *document) line(file string, n int) string {
+	lines := d.lines
+	if file != d.file {
+		var ok bool
+		if lines, ok = d.files[file]; !ok {
+			src, _ := os.ReadFile(file)
+			lines = strings.Split(string(src), "\n")
+			if d.files == nil {
+				d.files = make(map[string][]string)
+			}
+			d.files[file] = lines
+		}
+	}
+	if n < 1 || n > len(lines) {
+		return ""
+	}
+	return lines[n-1]
+}
+
+// position converts a line and rune column to an LSP position.
+func (d *document) position(file string, line, column int) Position {
+	runes := []rune(d.line(file, line))
+	char := 0
+	for i := 0; i < column-1; i++ {
+		if i < len(runes) {
+			char += len(utf16.Encode(runes[i : i+1]))
+		} else {
+			char++
+		}
+	}
+	return Position{Line: max(line-1, 0), Character: char}
+}
+
+// spanRange returns the range of a diagnostic span.
+func (d *document) spanRange(s diag.Span) Range {
+	return Range{
+		Start: d.position(s.File, s.Line, s.Column),
+		End:   d.position(s.File, s.Line, s.Column+s.Len),
+	}
+}
+
+func (d *document) tokenRange(tok token.Token) Range {
+	return d.spanRange(diag.At(tok))
+}
+
+// cursor converts an LSP position in the document to a line and rune
+// column.
+func (d *document) cursor(pos Position) (line, column int) {
+	units := 0
+	column = 1
+	for _, r := range d.line(d.file, pos.Line+1) {
+		if units >= pos.Character {
+			break
+		}
+		units += len(utf16.Encode([]rune{r}))
+		column++
+	}
+	return pos.Line + 1, column
+}
+
+// before reports whether tok starts before line:column.
+func before(tok token.Token, line, column int) bool {
+	return tok.Line < line || tok.Line == line && tok.Column < column
+}
+
+// --- features ---
+
+func (d *document) diagnostics() []Diagnostic {
+	out := []Diagnostic{}
+	for _, e := range d.diags {
+		if e.Span.File != d.file {
+			continue
+		}
+		dg := Diagnostic{
+			Range:    d.spanRange(e.Span),
+			Severity: severityError,
+			Code:     string(e.Code),
+			Source:   "tenge",
+			Message:  e.Message,
+		}
+		if e.Severity == diag.Warning {
+			dg.Severity = severityWarning
+		}
+		for _, n := range e.Notes {
+			dg.Message += "\n" + msg.Text(msg.NoteLabel) + ": " + n
+		}
+		for _, f := range e.Fixes {
+			dg.Message += "\n" + msg.Text(msg.HelpLabel) + ": " + msg.Sprintf(msg.DidYouMean, f.Text)
+		}
+		for _, l := range e.Labels {
+			dg.RelatedInformation = append(dg.RelatedInformation, DiagnosticRelatedInformation{
+				Location: Location{URI: d.fileURI(l.Span.File), Range: d.spanRange(l.Span)},
+				Message:  l.Message,
+			})
+		}
+		out = append(out, dg)
+	}
+	return out
+}
+
+// identAt returns the identifier under the cursor and its symbol.
+func (d *document) identAt(pos Position) (*ast.Identifier, *types.Symbol) {
+	line, column := d.cursor(pos)
+	var best *ast.Identifier
+	var bestSym *types.Symbol
+	find := func(ids map[*ast.Identifier]*types.Symbol) {
+		for id, sym := range ids {
+			s := diag.At(id.Token)
+			if sym == nil || s.File != d.file || s.Line != line || column < s.Column || column > s.Column+s.Len {
+				continue
+			}
+			// At the boundary of two names, prefer the one the cursor is in.
+			if best == nil || column < s.Column+s.Len {
+				best, bestSym = id, sym
+			}
+		}
+	}
+	find(d.info.Defs)
+	find(d.info.Uses)
+	return best, bestSym
+}
+
+func (d *document) hover(pos Position) *Hover {
+	id, sym := d.identAt(pos)
+	if id == nil {
+		return nil
+	}
+	text := sym.Name
+	if t := d.typeString(sym.Type); t != "" {
+		text += " : " + t
+	}
+	switch sym.Kind {
+	case types.VarSym:
+		text = lexer.Spelling(token.JASA, d.syntax) + " " + text
+	case types.ConstSym:
+		text = lexer.Spelling(token.BEKIT, d.syntax) + " " + text
+	}
+	r := d.tokenRange(id.Token)
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: "```tenge\n" + text + "\n```"}, Range: &r}
+}
+
+func (d *document) definition(pos Position) *Location {
+	_, sym := d.identAt(pos)
+	if sym == nil || sym.Decl == nil {
+		return nil
+	}
+	return &Location{URI: d.fileURI(sym.Decl.Token.File), Range: d.tokenRange(sym.Decl.Token)}
+}
+
+func (d *document) references(pos Position, withDecl bool) []Location {
+	locs := []Location{}
+	_, sym := d.identAt(pos)
+	if sym == nil {
+		return locs
+	}
+	var toks []token.Token
+	for id, s := range d.info.Uses {
+		if s == sym {
+			toks = append(toks, id.Token)
+		}
+	}
+	if withDecl && sym.Decl != nil {
+		toks = append(toks, sym.Decl.Token)
+	}
+	sort.Slice(toks, func(i, j int) bool {
+		a, b := toks[i], toks[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Offset < b.Offset
+	})
+	for _, tok := range toks {
+		locs = append(locs, Location{URI: d.fileURI(tok.File), Range: d.tokenRange(tok)})
+	}
+	return locs
+}
+
+// symbols returns the declarations at the top level of the document, with
+// those at the top level of function bodies as children.
+func (d *document) symbols() []DocumentSymbol {
+	return d.declarations(d.program.Statements)
+}
+
+func (d *document) declarations(stmts []ast.Statement) []DocumentSymbol {
+	out := []DocumentSymbol{}
+	for _, s := range stmts {
+		var name *ast.Identifier
+		var value ast.Expression
+		kind := symbolVariable
+		switch s := s.(type) {
+		case *ast.JasaStatement:
+			name, value = s.Name, s.Value
+		case *ast.BekitStatement:
+			name, value, kind = s.Name, s.Value, symbolConstant
+		case *ast.ModulStatement:
+			out = append(out, DocumentSymbol{
+				Name:           s.Name.Value,
+				Kind:           symbolModule,
+				Range:          Range{Start: d.tokenRange(s.Token).Start, End: d.tokenRange(s.Name.Token).End},
+				SelectionRange: d.tokenRange(s.Name.Token),
+			})
+			continue
+		default:
+			continue
+		}
+		sym := DocumentSymbol{
+			Name:           name.Value,
+			Kind:           kind,
+			Range:          Range{Start: d.tokenRange(types.Pos(s)).Start, End: d.tokenRange(name.Token).End},
+			SelectionRange: d.tokenRange(name.Token),
+		}
+		if def := d.info.Defs[name]; def != nil {
+			sym.Detail = d.typeString(def.Type)
+		}
+		if fn, ok := value.(*ast.AtqarmLiteral); ok && fn.Body != nil {
+			sym.Kind = symbolFunction
+			if fn.Body.Rbrace.Type == token.RBRACE {
+				sym.Range.End = d.tokenRange(fn.Body.Rbrace).End
+			}
+			sym.Children = d.declarations(fn.Body.Statements)
+		}
+		out = append(out, sym)
+	}
+	return out
+}
+
+// completion offers the keywords and the names visible at the cursor.
+func (d *document) completion(pos Position) []CompletionItem {
+	line, column := d.cursor(pos)
+
+	// The innermost block around the cursor, else the top level.
+	top := d.info.Scopes[d.program]
+	scope := top
+	var inner *ast.BlockStatement
+	for node, s := range d.info.Scopes {
+		b, ok := node.(*ast.BlockStatement)
+		if !ok || b.Token.File != d.file || !before(b.Token, line, column) {
+			continue
+		}
+		if b.Rbrace.Type == token.RBRACE && !before(token.Token{Line: line, Column: column}, b.Rbrace.Line, b.Rbrace.Column+1) {
+			continue
+		}
+		if inner == nil || before(inner.Token, b.Token.Line, b.Token.Column) {
+			inner, scope = b, s
+		}
+	}
+
+	items := []CompletionItem{}
+	seen := make(map[string]bool)
+	for s := scope; s != nil; s = s.Parent() {
+		for _, name := range s.Names() {
+			sym := s.LookupLocal(name)
+			if seen[name] {
+				continue // shadowed
+			}
+			// Locals are visible after their declaration; the top level
+			// is visible everywhere.
+			if s != top && sym.Decl != nil && !before(sym.Decl.Token, line, column) {
+				continue
+			}
+			seen[name] = true
+			items = append(items, CompletionItem{Label: name, Kind: completionKind(sym.Kind), Detail: d.typeString(sym.Type)})
+		}
+	}
+	for _, kw := range lexer.Keywords() {
+		if !seen[kw] {
+			items = append(items, CompletionItem{Label: kw, Kind: completionKeyword})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+	return items
+}
+
+// typeString returns t as written in the keyword set of the document, or
+// "" if it is unknown because of an error.
+func (d *document) typeString(t types.Type) string {
+	if t == nil || t == types.Typ[types.Invalid] {
+		return ""
+	}
+	s := t.String()
+	if d.syntax != lexer.Mixed {
+		if conv, err := lexer.Convert(s, d.syntax); err == nil {
+			s = conv
+		}
+	}
+	return s
+}
+
+func completionKind(k types.SymbolKind) int {
+	switch k {
+	case types.ConstSym:
+		return completionConstant
+	case types.FuncSym, types.BuiltinSym:
+		return completionFunction
+	case types.TypeSym:
+		return completionType
+	case types.ModuleSym:
+		return completionModule
+	}
+	return completionVariable
+}
+
+// format returns the edit that formats the whole document, nil if it does
+// not lex.
+func (d *document) format() []TextEdit {
+	out, err := format.Source(d.text)
+	if err != nil {
+		return nil
+	}
+	if out == d.text {
+		return []TextEdit{}
+	}
+	last := len(d.lines)
+	end := d.position(d.file, last, len([]rune(d.lines[last-1]))+1)
+	return []TextEdit{{Range: Range{End: end}, NewText: out}}
+}
@@ -0,0 +1,247 @@
+// FILE: internal/lsp/server.go
+
+// Package lsp is a Language Server Protocol server for tenge, spoken as
+// JSON-RPC over a pair of streams (stdin and stdout for `tenge lsp`).
+//
+// Open documents are parsed and type-checked on every change, and their
+// parse errors, type errors and warnings are published as diagnostics.
+// The parser recovers from errors, so hover, go-to-definition, references,
+// document symbols and completion also work in code that does not compile
+// yet. Formatting uses package format.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// Server holds the open documents. The zero value is not usable; call
+// NewServer.
+type Server struct {
+	in   *bufio.Reader
+	out  io.Writer
+	docs map[string]*document // by URI
+
+	shutdown bool
+}
+
+// NewServer returns a server reading requests from in and writing
+// responses and notifications to out.
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{in: bufio.NewReader(in), out: out, docs: make(map[string]*document)}
+}
+
+// Serve handles messages until the client sends exit or closes the input.
+// It returns an error if the input ends without shutdown or is not
+// well-formed.
+func (s *Server) Serve() error {
+	for {
+		body, err := s.read()
+		if err == io.EOF {
+			if s.shutdown {
+				return nil
+			}
+			return errors.New("lsp: input closed before shutdown")
+		}
+		if err != nil {
+			return err
+		}
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.replyError(nil, codeParseError, err.Error())
+			continue
+		}
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("lsp: exit before shutdown")
+			}
+			return nil
+		}
+		result, rerr := s.handle(&req)
+		if req.ID == nil {
+			continue // a notification: no response
+		}
+		if rerr != nil {
+			s.replyError(req.ID, rerr.Code, rerr.Message)
+			continue
+		}
+		s.write(response{JSONRPC: "2.0", ID: req.ID, Result: result})
+	}
+}
+
+// read reads one message: headers, a blank line and a body of
+// Content-Length bytes.
+func (s *Server) read() ([]byte, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("lsp: reading header: %v", err)
+	}
+	n, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("lsp: bad Content-Length %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, fmt.Errorf("lsp: reading body: %v", err)
+	}
+	return body, nil
+}
+
+func (s *Server) write(msg interface{}) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		panic(err) // all messages are plain data
+	}
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func (s *Server) replyError(id *json.RawMessage, code int, message string) {
+	s.write(errorResponse{JSONRPC: "2.0", ID: id, Error: &responseError{Code: code, Message: message}})
+}
+
+func (s *Server) notify(method string, params interface{}) {
+	s.write(notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// handle runs a request or notification and returns its result.
+func (s *Server) handle(req *request) (interface{}, *responseError) {
+	if s.shutdown && req.Method != "exit" {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shut down"}
+	}
+	switch req.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":           1, // full text on every change
+				"hoverProvider":              true,
+				"definitionProvider":         true,
+				"referencesProvider":         true,
+				"documentSymbolProvider":     true,
+				"documentFormattingProvider": true,
+				"completionProvider":         map[string]interface{}{},
+			},
+			"serverInfo": map[string]string{"name": "tenge"},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var p DidOpenTextDocumentParams
+		if err := unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		s.update(p.TextDocument.URI, p.TextDocument.Version, p.TextDocument.Text)
+		return nil, nil
+	case "textDocument/didChange":
+		var p DidChangeTextDocumentParams
+		if err := unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if n := len(p.ContentChanges); n > 0 {
+			s.update(p.TextDocument.URI, p.TextDocument.Version, p.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var p DidCloseTextDocumentParams
+		if err := unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		delete(s.docs, p.TextDocument.URI)
+		s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []Diagnostic{}})
+		return nil, nil
+
+	case "textDocument/hover":
+		var p TextDocumentPositionParams
+		if err := unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if doc := s.docs[p.TextDocument.URI]; doc != nil {
+			if h := doc.hover(p.Position); h != nil {
+				return h, nil
+			}
+		}
+		return nil, nil
+	case "textDocument/definition":
+		var p TextDocumentPositionParams
+		if err := unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if doc := s.docs[p.TextDocument.URI]; doc != nil {
+			if loc := doc.definition(p.Position); loc != nil {
+				return loc, nil
+			}
+		}
+		return nil, nil
+	case "textDocument/references":
+		var p ReferenceParams
+		if err := unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if doc := s.docs[p.TextDocument.URI]; doc != nil {
+			return doc.references(p.Position, p.Context.IncludeDeclaration), nil
+		}
+		return []Location{}, nil
+	case "textDocument/documentSymbol":
+		var p DocumentSymbolParams
+		if err := unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if doc := s.docs[p.TextDocument.URI]; doc != nil {
+			return doc.symbols(), nil
+		}
+		return []DocumentSymbol{}, nil
+	case "textDocument/completion":
+		var p TextDocumentPositionParams
+		if err := unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if doc := s.docs[p.TextDocument.URI]; doc != nil {
+			return doc.completion(p.Position), nil
+		}
+		return []CompletionItem{}, nil
+	case "textDocument/formatting":
+		var p DocumentFormattingParams
+		if err := unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if doc := s.docs[p.TextDocument.URI]; doc != nil {
+			return doc.format(), nil
+		}
+		return []TextEdit{}, nil
+	}
+	if strings.HasPrefix(req.Method, "$/") {
+		return nil, nil // optional notifications such as $/cancelRequest
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+}
+
+func unmarshal(params json.RawMessage, v interface{}) *responseError {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// update analyzes the new text of a document and publishes its
+// diagnostics.
+func (s *Server) update(uri string, version int, text string) {
+	doc := analyze(uri, version, text)
+	s.docs[uri] = doc
+	s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         uri,
+		Version:     version,
+		Diagnostics: doc.diagnostics(),
+	})
+}
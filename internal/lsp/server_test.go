@@ -0,0 +1,367 @@
+// FILE: internal/lsp/server_test.go
+
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// client drives a Server over in-memory pipes, the way an editor would.
+type client struct {
+	t     *testing.T
+	in    *io.PipeWriter
+	out   *bufio.Reader
+	id    int
+	notes []PublishDiagnosticsParams // diagnostics published so far
+	done  chan error
+}
+
+func newClient(t *testing.T) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &client{t: t, in: inW, out: bufio.NewReader(outR), done: make(chan error, 1)}
+	go func() {
+		err := NewServer(inR, outW).Serve()
+		outW.Close()
+		c.done <- err
+	}()
+	c.call("initialize", map[string]interface{}{}, nil)
+	c.notify("initialized", map[string]interface{}{})
+	return c
+}
+
+func (c *client) send(msg interface{}) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err := fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// receive reads one message from the server.
+func (c *client) receive() map[string]json.RawMessage {
+	header, err := textproto.NewReader(c.out).ReadMIMEHeader()
+	if err != nil {
+		c.t.Fatalf("reading header: %v", err)
+	}
+	n, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		c.t.Fatalf("bad Content-Length %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(c.out, body); err != nil {
+		c.t.Fatalf("reading body: %v", err)
+	}
+	var msg map[string]json.RawMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		c.t.Fatalf("%v: %s", err, body)
+	}
+	return msg
+}
+
+// call sends a request and decodes its result into result, keeping the
+// notifications that arrive before the response.
+func (c *client) call(method string, params, result interface{}) {
+	c.id++
+	c.send(map[string]interface{}{"jsonrpc": "2.0", "id": c.id, "method": method, "params": params})
+	for {
+		msg := c.receive()
+		if _, ok := msg["id"]; !ok {
+			c.note(msg)
+			continue
+		}
+		if e, ok := msg["error"]; ok {
+			c.t.Fatalf("%s: %s", method, e)
+		}
+		if result != nil {
+			if err := json.Unmarshal(msg["result"], result); err != nil {
+				c.t.Fatalf("%s: %v: %s", method, err, msg["result"])
+			}
+		}
+		return
+	}
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.send(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+func (c *client) note(msg map[string]json.RawMessage) {
+	var method string
+	json.Unmarshal(msg["method"], &method)
+	if method != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("unexpected notification %s", method)
+	}
+	var p PublishDiagnosticsParams
+	if err := json.Unmarshal(msg["params"], &p); err != nil {
+		c.t.Fatal(err)
+	}
+	c.notes = append(c.notes, p)
+}
+
+// open opens a document and returns the diagnostics published for it.
+func (c *client) open(uri, text string) []Diagnostic {
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uri, Version: 1, Text: text}})
+	c.note(c.receive())
+	p := c.notes[len(c.notes)-1]
+	if p.URI != uri {
+		c.t.Fatalf("diagnostics for %s, want %s", p.URI, uri)
+	}
+	return p.Diagnostics
+}
+
+func (c *client) close() {
+	c.call("shutdown", nil, nil)
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		c.t.Errorf("Serve: %v", err)
+	}
+}
+
+func at(uri string, line, char int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: line, Character: char}}
+}
+
+func testURI(t *testing.T, name string) string {
+	return "file://" + filepath.ToSlash(filepath.Join(t.TempDir(), name))
+}
+
+const kazakhSource = `#syntax kazakh
+jasa total : san = 0
+
+atqar'm add(a: san, b: san) -> san {
+    jasa s = a + b
+    qaıtar s
+}
+
+total = add(1, 2)
+kórset(total)
+`
+
+const latinSource = `#syntax latin
+var total: int = 0
+const limit = 10
+
+fn add(a: int, b: int) -> int {
+    return a + b
+}
+
+total = add(1, limit)
+show(total)
+`
+
+func TestDiagnostics(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	uri := testURI(t, "bad.tng")
+	diags := c.open(uri, "jasa x : san = \"text\"\n")
+	if len(diags) != 1 {
+		t.Fatalf("got %d diagnostics, want 1: %+v", len(diags), diags)
+	}
+	d := diags[0]
+	if d.Severity != 1 || d.Source != "tenge" || d.Code != "E0311" {
+		t.Errorf("got %+v, want a tenge error E0311", d)
+	}
+	if d.Range.Start.Line != 0 {
+		t.Errorf("diagnostic on line %d, want 0", d.Range.Start.Line)
+	}
+
+	c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+		"contentChanges": []map[string]string{{"text": "jasa x : san = 1\n"}},
+	})
+	c.note(c.receive())
+	if p := c.notes[len(c.notes)-1]; p.Version != 2 || len(p.Diagnostics) != 0 {
+		t.Errorf("after the fix got version %d and %+v, want version 2 and none", p.Version, p.Diagnostics)
+	}
+
+	c.notify("textDocument/didClose", DidCloseTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}})
+	c.note(c.receive())
+	if p := c.notes[len(c.notes)-1]; p.URI != uri || len(p.Diagnostics) != 0 {
+		t.Errorf("on close got %+v, want no diagnostics for %s", p, uri)
+	}
+}
+
+func TestHover(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	kaz, lat := testURI(t, "kaz.tng"), testURI(t, "lat.tng")
+	c.open(kaz, kazakhSource)
+	c.open(lat, latinSource)
+	for _, test := range []struct {
+		uri        string
+		line, char int
+		want       string
+	}{
+		{kaz, 1, 6, "jasa total : san"},
+		{kaz, 8, 9, "add : atqar'm (san, san) -> san"},
+		{kaz, 5, 12, "jasa s : san"},
+		{lat, 1, 5, "var total : int"},
+		{lat, 2, 7, "const limit : int"},
+		{lat, 8, 9, "add : fn (int, int) -> int"},
+		{lat, 5, 11, "var a : int"},
+	} {
+		var h *Hover
+		c.call("textDocument/hover", at(test.uri, test.line, test.char), &h)
+		if h == nil {
+			t.Errorf("%s:%d:%d: no hover", filepath.Base(test.uri), test.line, test.char)
+			continue
+		}
+		if want := "```tenge\n" + test.want + "\n```"; h.Contents.Value != want {
+			t.Errorf("%s:%d:%d: hover %q, want %q", filepath.Base(test.uri), test.line, test.char, h.Contents.Value, want)
+		}
+	}
+
+	var h *Hover
+	c.call("textDocument/hover", at(kaz, 3, 0), &h)
+	if h != nil {
+		t.Errorf("hover on a keyword: %+v, want none", h)
+	}
+}
+
+func TestDefinitionAndReferences(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	uri := testURI(t, "kaz.tng")
+	c.open(uri, kazakhSource)
+
+	var loc *Location
+	c.call("textDocument/definition", at(uri, 8, 9), &loc)
+	want := Location{URI: uri, Range: Range{Start: Position{3, 8}, End: Position{3, 11}}}
+	if loc == nil || *loc != want {
+		t.Errorf("definition of add: %+v, want %+v", loc, want)
+	}
+
+	refs := func(withDecl bool) []Location {
+		var p ReferenceParams
+		p.TextDocument.URI = uri
+		p.Position = Position{Line: 1, Character: 6}
+		p.Context.IncludeDeclaration = withDecl
+		var locs []Location
+		c.call("textDocument/references", p, &locs)
+		return locs
+	}
+	var lines []int
+	for _, l := range refs(true) {
+		lines = append(lines, l.Range.Start.Line)
+	}
+	if fmt.Sprint(lines) != "[1 8 9]" {
+		t.Errorf("references of total on lines %v, want [1 8 9]", lines)
+	}
+	if n := len(refs(false)); n != 2 {
+		t.Errorf("got %d references without the declaration, want 2", n)
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	uri := testURI(t, "kaz.tng")
+	c.open(uri, kazakhSource)
+
+	var syms []DocumentSymbol
+	c.call("textDocument/documentSymbol", DocumentSymbolParams{TextDocument: TextDocumentIdentifier{URI: uri}}, &syms)
+	if len(syms) != 2 {
+		t.Fatalf("got %d symbols, want 2: %+v", len(syms), syms)
+	}
+	if s := syms[0]; s.Name != "total" || s.Kind != symbolVariable || s.Detail != "san" {
+		t.Errorf("first symbol %+v, want the variable total", s)
+	}
+	fn := syms[1]
+	if fn.Name != "add" || fn.Kind != symbolFunction {
+		t.Errorf("second symbol %+v, want the function add", fn)
+	}
+	if fn.Range.Start.Line != 3 || fn.Range.End.Line != 6 {
+		t.Errorf("add spans lines %d-%d, want 3-6", fn.Range.Start.Line, fn.Range.End.Line)
+	}
+	if len(fn.Children) != 1 || fn.Children[0].Name != "s" {
+		t.Errorf("children of add %+v, want s", fn.Children)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	uri := testURI(t, "kaz.tng")
+	c.open(uri, kazakhSource)
+
+	labels := func(line, char int) map[string]CompletionItem {
+		var items []CompletionItem
+		c.call("textDocument/completion", at(uri, line, char), &items)
+		m := make(map[string]CompletionItem)
+		for _, it := range items {
+			m[it.Label] = it
+		}
+		return m
+	}
+	inside := labels(5, 4) // before `qaıtar s`
+	for _, name := range []string{"a", "b", "s", "total", "add", "jasa", "qaıtar"} {
+		if _, ok := inside[name]; !ok {
+			t.Errorf("completion in add lacks %s", name)
+		}
+	}
+	if it := inside["a"]; it.Kind != completionVariable || it.Detail != "san" {
+		t.Errorf("completion of a: %+v", it)
+	}
+	if it := inside["qaıtar"]; it.Kind != completionKeyword {
+		t.Errorf("completion of qaıtar: %+v, want a keyword", it)
+	}
+
+	outside := labels(8, 0)
+	for _, name := range []string{"a", "s"} {
+		if _, ok := outside[name]; ok {
+			t.Errorf("completion at the top level offers the local %s", name)
+		}
+	}
+	if _, ok := labels(4, 4)["s"]; ok {
+		t.Errorf("completion offers s before its declaration")
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	params := func(uri string) DocumentFormattingParams {
+		return DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: uri}}
+	}
+
+	messy := testURI(t, "messy.tng")
+	c.open(messy, "jasa  x:san=1\nkórset( x )\n")
+	var edits []TextEdit
+	c.call("textDocument/formatting", params(messy), &edits)
+	if len(edits) != 1 {
+		t.Fatalf("got %d edits, want 1", len(edits))
+	}
+	if e := edits[0]; e.NewText != "jasa x: san = 1\nkórset(x)\n" || e.Range.Start != (Position{}) {
+		t.Errorf("edit %+v", e)
+	}
+
+	tidy := testURI(t, "tidy.tng")
+	c.open(tidy, edits[0].NewText)
+	c.call("textDocument/formatting", params(tidy), &edits)
+	if len(edits) != 0 {
+		t.Errorf("formatting formatted code: %+v, want no edits", edits)
+	}
+}
+
+func TestUnknownMethod(t *testing.T) {
+	c := newClient(t)
+	defer c.close()
+	c.id++
+	c.send(map[string]interface{}{"jsonrpc": "2.0", "id": c.id, "method": "textDocument/unknown", "params": map[string]interface{}{}})
+	msg := c.receive()
+	var e responseError
+	if err := json.Unmarshal(msg["error"], &e); err != nil || e.Code != codeMethodNotFound || !strings.Contains(e.Message, "textDocument/unknown") {
+		t.Errorf("got %s, want a method-not-found error", msg["error"])
+	}
+}
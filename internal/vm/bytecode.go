@@ -0,0 +1,358 @@
+// FILE: internal/vm/bytecode.go
+
+// Package vm runs type-checked tenge programs on a register-based bytecode
+// virtual machine, the stage between the tree-walking interpreter and the
+// AOT compiler.
+//
+// Compile translates the typed AST to a Program: one Function per
+// top-level function (generic functions are monomorphized like in the C
+// backend), plus one per module running its top-level statements. Run
+// executes it.
+//
+// Registers are unboxed and come in three banks: the int bank holds
+// integers, booleans and characters as int64, the float bank holds
+// float64, and the ref bank holds strings, aqsha decimals, arrays and the
+// cells of variables whose address is taken. Every instruction names the
+// bank of its operands in its opcode (ADDI, ADDF), so the dispatch loop
+// never inspects a value's type.
+package vm
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// Op is an instruction opcode. In the comments I, F and R are the int,
+// float and ref registers of the current frame, GI, GF and GR the globals,
+// and A, B, C the operands of the instruction.
+type Op uint8
+
+const (
+	NOP Op = iota
+
+	// Moves and constants.
+	MOVI   // I[A] = I[B]
+	MOVF   // F[A] = F[B]
+	MOVR   // R[A] = R[B]
+	LOADI  // I[A] = B
+	LOADKI // I[A] = Ints[B]
+	LOADKF // F[A] = Floats[B]
+	LOADKS // R[A] = Strings[B]
+	LOADKD // R[A] = Decimals[B]
+	ZERO   // R[A] = the zero value of elem kind B (an empty array or nil cell)
+
+	// Globals.
+	GETGI // I[A] = GI[B]
+	GETGF // F[A] = GF[B]
+	GETGR // R[A] = GR[B]
+	SETGI // GI[A] = I[B]
+	SETGF // GF[A] = F[B]
+	SETGR // GR[A] = R[B]
+
+	// Cells hold variables whose address is taken; pointers are cells.
+	NEWCI // R[A] = new cell holding I[B]
+	NEWCF // R[A] = new cell holding F[B]
+	NEWCR // R[A] = new cell holding R[B]
+	LDCI  // I[A] = *R[B]
+	LDCF  // F[A] = *R[B]
+	LDCR  // R[A] = *R[B]
+	STCI  // *R[A] = I[B]
+	STCF  // *R[A] = F[B]
+	STCR  // *R[A] = R[B]
+
+	// Integer arithmetic wraps around like two's complement hardware.
+	ADDI  // I[A] = I[B] + I[C]
+	ADDIK // I[A] = I[B] + C
+	SUBI  // I[A] = I[B] - I[C]
+	MULI  // I[A] = I[B] * I[C]
+	DIVI  // I[A] = I[B] / I[C]
+	MODI  // I[A] = I[B] % I[C]
+	DIVU  // I[A] = uint64(I[B]) / uint64(I[C])
+	MODU  // I[A] = uint64(I[B]) % uint64(I[C])
+	ANDI  // I[A] = I[B] & I[C]
+	ORI   // I[A] = I[B] | I[C]
+	XORI  // I[A] = I[B] ^ I[C]
+	SHLI  // I[A] = I[B] << I[C]
+	SHRI  // I[A] = I[B] >> I[C]
+	SHRU  // I[A] = uint64(I[B]) >> I[C]
+	NEGI  // I[A] = -I[B]
+	NOT   // I[A] = !I[B]
+	SEXT  // I[A] = int64(int32(I[B]))
+
+	// Float arithmetic.
+	ADDF // F[A] = F[B] + F[C]
+	SUBF // F[A] = F[B] - F[C]
+	MULF // F[A] = F[B] * F[C]
+	DIVF // F[A] = F[B] / F[C]
+	MODF // F[A] = math.Mod(F[B], F[C])
+	NEGF // F[A] = -F[B]
+
+	// Conversions.
+	ITOF // F[A] = float64(I[B])
+	UTOF // F[A] = float64(uint64(I[B]))
+	FTOI // I[A] = int64(F[B])
+	FTOU // I[A] = int64(uint64(F[B]))
+
+	// Decimal (aqsha) arithmetic on decimal.Decimal values in the ref
+	// bank, with the interpreter's rounding.
+	ADDD // R[A] = R[B] + R[C]
+	SUBD // R[A] = R[B] - R[C]
+	MULD // R[A] = R[B] * R[C]
+	DIVD // R[A] = R[B] / R[C]
+	MODD // R[A] = R[B] % R[C]
+	NEGD // R[A] = -R[B]
+	ITOD // R[A] = decimal(I[B])
+	UTOD // R[A] = decimal(uint64(I[B]))
+	FTOD // R[A] = decimal(F[B])
+	DTOI // I[A] = integer part of R[B]
+	DTOU // I[A] = int64(uint64(integer part of R[B]))
+	DTOF // F[A] = float64(R[B])
+
+	// Comparisons yield 1 or 0 in I[A].
+	EQI // I[A] = I[B] == I[C]
+	NEI // I[A] = I[B] != I[C]
+	LTI // I[A] = I[B] < I[C]
+	LEI // I[A] = I[B] <= I[C]
+	LTU // I[A] = uint64(I[B]) < uint64(I[C])
+	LEU // I[A] = uint64(I[B]) <= uint64(I[C])
+	EQF // I[A] = F[B] == F[C]
+	NEF // I[A] = F[B] != F[C]
+	LTF // I[A] = F[B] < F[C]
+	LEF // I[A] = F[B] <= F[C]
+	EQS // I[A] = R[B] == R[C]
+	NES // I[A] = R[B] != R[C]
+	LTS // I[A] = R[B] < R[C]
+	LES // I[A] = R[B] <= R[C]
+	EQD // I[A] = R[B] == R[C] as decimals
+	NED // I[A] = R[B] != R[C]
+	LTD // I[A] = R[B] < R[C]
+	LED // I[A] = R[B] <= R[C]
+
+	// Jumps take absolute instruction indexes.
+	JMP   // goto A
+	JT    // if I[A] != 0 goto B
+	JF    // if I[A] == 0 goto B
+	BEQI  // if I[A] == I[B] goto C
+	BNEI  // if I[A] != I[B] goto C
+	BLTI  // if I[A] < I[B] goto C
+	BLEI  // if I[A] <= I[B] goto C
+	BLTU  // if uint64(I[A]) < uint64(I[B]) goto C
+	BLEU  // if uint64(I[A]) <= uint64(I[B]) goto C
+	BEQIK // if I[A] == B goto C
+	BNEIK // if I[A] != B goto C
+	BLTIK // if I[A] < B goto C
+	BLEIK // if I[A] <= B goto C
+	BGTIK // if I[A] > B goto C
+	BGEIK // if I[A] >= B goto C
+
+	// Calls.
+	CALL // call Calls[A] of the current function
+	RET  // return nothing
+	RETI // return I[A]
+	RETF // return F[A]
+	RETR // return R[A]
+
+	// Strings and arrays. Int arrays ([]san, []i32, []u64, []aqıqat) are
+	// []int64; float arrays are []float64.
+	CONCAT // R[A] = R[B] + R[C]
+	NEWAI  // R[A] = int array of length I[B]
+	NEWAF  // R[A] = float array of length I[B]
+	GETAI  // I[A] = R[B][I[C]]
+	GETAF  // F[A] = R[B][I[C]]
+	SETAI  // R[A][I[B]] = I[C]
+	SETAF  // R[A][I[B]] = F[C]
+	PUSHI  // R[A] = append(R[B], I[C])
+	PUSHF  // R[A] = append(R[B], F[C])
+	SORT   // R[A] = sorted copy of R[B], an array of elem kind C
+	LEN    // I[A] = length of the array or string R[B]
+
+	// Built-in functions.
+	ARGI  // I[A] = program argument I[B] as an integer, or I[C]
+	ARGF  // F[A] = program argument I[B] as a float, or F[C]
+	NOWNS // I[A] = monotonic time in nanoseconds
+	SQRT  // F[A] = math.Sqrt(F[B])
+	LOG   // F[A] = math.Log(F[B])
+	EXP   // F[A] = math.Exp(F[B])
+	COS   // F[A] = math.Cos(F[B])
+	SIN   // F[A] = math.Sin(F[B])
+	FLOOR // F[A] = math.Floor(F[B])
+
+	// Output.
+	SHOWI   // print I[A] as san
+	SHOWU   // print I[A] as u64
+	SHOWB   // print I[A] as aqıqat
+	SHOWF   // print F[A]
+	SHOWD   // print the decimal R[A]
+	SHOWS   // print the string R[A]
+	SHOWA   // print the array R[A] of elem kind B
+	NEWLINE // print a newline
+	PRINTF  // print F[A] with I[B] digits after the point
+	TIMENS  // print "TIME_NS: I[A]"
+	ASSERT  // fail unless I[A], with the message R[B] when B >= 0
+
+	numOps
+)
+
+var opNames = [...]string{
+	NOP: "NOP",
+
+	MOVI: "MOVI", MOVF: "MOVF", MOVR: "MOVR",
+	LOADI: "LOADI", LOADKI: "LOADKI", LOADKF: "LOADKF", LOADKS: "LOADKS", LOADKD: "LOADKD", ZERO: "ZERO",
+
+	GETGI: "GETGI", GETGF: "GETGF", GETGR: "GETGR",
+	SETGI: "SETGI", SETGF: "SETGF", SETGR: "SETGR",
+
+	NEWCI: "NEWCI", NEWCF: "NEWCF", NEWCR: "NEWCR",
+	LDCI: "LDCI", LDCF: "LDCF", LDCR: "LDCR",
+	STCI: "STCI", STCF: "STCF", STCR: "STCR",
+
+	ADDI: "ADDI", ADDIK: "ADDIK", SUBI: "SUBI", MULI: "MULI", DIVI: "DIVI", MODI: "MODI",
+	DIVU: "DIVU", MODU: "MODU", ANDI: "ANDI", ORI: "ORI", XORI: "XORI",
+	SHLI: "SHLI", SHRI: "SHRI", SHRU: "SHRU", NEGI: "NEGI", NOT: "NOT", SEXT: "SEXT",
+
+	ADDF: "ADDF", SUBF: "SUBF", MULF: "MULF", DIVF: "DIVF", MODF: "MODF", NEGF: "NEGF",
+
+	ITOF: "ITOF", UTOF: "UTOF", FTOI: "FTOI", FTOU: "FTOU",
+
+	ADDD: "ADDD", SUBD: "SUBD", MULD: "MULD", DIVD: "DIVD", MODD: "MODD", NEGD: "NEGD",
+	ITOD: "ITOD", UTOD: "UTOD", FTOD: "FTOD", DTOI: "DTOI", DTOU: "DTOU", DTOF: "DTOF",
+
+	EQI: "EQI", NEI: "NEI", LTI: "LTI", LEI: "LEI", LTU: "LTU", LEU: "LEU",
+	EQF: "EQF", NEF: "NEF", LTF: "LTF", LEF: "LEF",
+	EQS: "EQS", NES: "NES", LTS: "LTS", LES: "LES",
+	EQD: "EQD", NED: "NED", LTD: "LTD", LED: "LED",
+
+	JMP: "JMP", JT: "JT", JF: "JF",
+	BEQI: "BEQI", BNEI: "BNEI", BLTI: "BLTI", BLEI: "BLEI", BLTU: "BLTU", BLEU: "BLEU",
+	BEQIK: "BEQIK", BNEIK: "BNEIK", BLTIK: "BLTIK", BLEIK: "BLEIK", BGTIK: "BGTIK", BGEIK: "BGEIK",
+
+	CALL: "CALL", RET: "RET", RETI: "RETI", RETF: "RETF", RETR: "RETR",
+
+	CONCAT: "CONCAT", NEWAI: "NEWAI", NEWAF: "NEWAF",
+	GETAI: "GETAI", GETAF: "GETAF", SETAI: "SETAI", SETAF: "SETAF",
+	PUSHI: "PUSHI", PUSHF: "PUSHF", SORT: "SORT", LEN: "LEN",
+
+	ARGI: "ARGI", ARGF: "ARGF", NOWNS: "NOWNS",
+	SQRT: "SQRT", LOG: "LOG", EXP: "EXP", COS: "COS", SIN: "SIN", FLOOR: "FLOOR",
+
+	SHOWI: "SHOWI", SHOWU: "SHOWU", SHOWB: "SHOWB", SHOWF: "SHOWF", SHOWD: "SHOWD", SHOWS: "SHOWS",
+	SHOWA: "SHOWA", NEWLINE: "NEWLINE", PRINTF: "PRINTF", TIMENS: "TIMENS", ASSERT: "ASSERT",
+}
+
+func (op Op) String() string {
+	if op < numOps && opNames[op] != "" {
+		return opNames[op]
+	}
+	return fmt.Sprintf("Op(%d)", op)
+}
+
+// Instr is one instruction.
+type Instr struct {
+	Op      Op
+	A, B, C int32
+}
+
+func (in Instr) String() string {
+	return fmt.Sprintf("%-7s %d %d %d", in.Op, in.A, in.B, in.C)
+}
+
+// Bank is a register bank.
+type Bank uint8
+
+const (
+	IntBank Bank = iota
+	FloatBank
+	RefBank
+)
+
+func (b Bank) String() string {
+	switch b {
+	case IntBank:
+		return "I"
+	case FloatBank:
+		return "F"
+	}
+	return "R"
+}
+
+// Loc is a register.
+type Loc struct {
+	Bank Bank
+	Reg  int32
+}
+
+func (l Loc) String() string { return fmt.Sprintf("%s%d", l.Bank, l.Reg) }
+
+// Regs counts registers, or global slots, of each bank.
+type Regs struct {
+	Int, Float, Ref int32
+}
+
+// Elem kinds of arrays, for ZERO, SORT and SHOWA.
+const (
+	ElemInt     = iota // []san, []i32, j'i'm
+	ElemU64            // []u64
+	ElemBool           // []aqıqat
+	ElemFloat          // []f64
+	ElemCell           // a pointer (ZERO only)
+	ElemJol            // a jol (ZERO only)
+	ElemDecimal        // an aqsha (ZERO only)
+)
+
+// Call is a call site: the arguments are copied from the caller's
+// registers into the parameters of Func, and the result into Result.
+type Call struct {
+	Func   int32
+	Args   []Loc
+	Result Loc // ignored when the function returns nothing
+}
+
+// Line maps the instructions from PC on to a source position.
+type Line struct {
+	PC, Line, Column int32
+}
+
+// Function is a compiled function.
+type Function struct {
+	Name   string
+	File   string
+	Regs   Regs  // registers of a frame
+	Params []Loc // registers of the parameters, in order
+	Code   []Instr
+	Calls  []Call
+	Lines  []Line
+}
+
+// Pos returns the source position of the instruction at pc as
+// "file:line:col", or "" when it is unknown.
+func (f *Function) Pos(pc int) string {
+	var l *Line
+	for i := range f.Lines {
+		if int(f.Lines[i].PC) > pc {
+			break
+		}
+		l = &f.Lines[i]
+	}
+	if l == nil {
+		return ""
+	}
+	if f.File == "" {
+		return fmt.Sprintf("%d:%d", l.Line, l.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", f.File, l.Line, l.Column)
+}
+
+// Program is a compiled program. The Init functions run the top-level
+// statements of each module in dependency order; Main, when it is not -1,
+// is the `main` function of the main module, called after them.
+type Program struct {
+	Funcs    []*Function
+	Ints     []int64
+	Floats   []float64
+	Strings  []string
+	Decimals []decimal.Decimal
+	Globals  Regs
+	Init     []int32
+	Main     int32
+}
@@ -0,0 +1,707 @@
+// FILE: internal/vm/compile.go
+
+package vm
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/diag"
+	"github.com/DauletBai/tenge/internal/lang/module"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/token"
+	"github.com/DauletBai/tenge/internal/lang/types"
+	"github.com/shopspring/decimal"
+)
+
+// Compile translates prog to bytecode. info must come from a successful
+// type check of prog. Constructs the VM does not support, the same ones
+// the C backend rejects, are reported as errors.
+func Compile(prog *module.Program, info *types.Info) (*Program, diag.List) {
+	c := &compiler{
+		info:      info,
+		prog:      &Program{Main: -1},
+		seen:      make(map[string]bool),
+		ints:      make(map[int64]int32),
+		floats:    make(map[uint64]int32),
+		strs:      make(map[string]int32),
+		decimals:  make(map[string]int32),
+		globals:   make(map[*ast.Identifier]variable),
+		addressed: make(map[*ast.Identifier]bool),
+		funcs:     make(map[types.FuncKey]int32),
+		decls:     make(map[*ast.Identifier]*ast.AtqarmLiteral),
+		tops:      make(map[*ast.AtqarmLiteral]bool),
+	}
+	c.program(prog)
+	return c.prog, c.errors
+}
+
+type compiler struct {
+	info   *types.Info
+	prog   *Program
+	errors diag.List
+	seen   map[string]bool
+
+	// Constant pools, indexed by value.
+	ints     map[int64]int32
+	floats   map[uint64]int32 // by bits, so that -0 and NaN get their own entries
+	strs     map[string]int32
+	decimals map[string]int32 // by coefficient and exponent, so that 1.50 keeps its digits
+
+	globals   map[*ast.Identifier]variable           // top-level variables by declaring identifier
+	addressed map[*ast.Identifier]bool               // variables whose address is taken
+	funcs     map[types.FuncKey]int32                // compiled and queued functions
+	decls     map[*ast.Identifier]*ast.AtqarmLiteral // top-level functions by name
+	tops      map[*ast.AtqarmLiteral]bool            // top-level function literals
+	queue     []pending                              // functions left to compile
+
+	fs *funcState // function being compiled
+}
+
+type pending struct {
+	index int32
+	lit   *ast.AtqarmLiteral
+	sig   *types.Signature
+	subst map[*types.TypeParam]types.Type
+}
+
+// variable is where a variable lives: a register or a global slot of the
+// bank of its kind, or, when its address is taken, a cell in a ref
+// register or ref slot.
+type variable struct {
+	loc    Loc
+	kind   kind
+	cell   bool
+	global bool
+}
+
+// funcState holds the registers of the function being compiled.
+type funcState struct {
+	f      *Function
+	vars   map[*ast.Identifier]variable // locals by declaring identifier
+	next   [3]int32                     // first free register of each bank
+	max    [3]int32
+	result types.Type                   // nil in the top-level code of a module
+	tails  map[*ast.CallExpression]bool // the calls in tail position
+	subst  map[*types.TypeParam]types.Type
+	pos    token.Token // position of the code being emitted
+}
+
+// errorf reports code at the position of node at, naming the VM as the
+// backend that cannot run it. A message is only reported once.
+func (c *compiler) errorf(at ast.Node, code msg.Code, args ...interface{}) {
+	args = append(args, msg.Text(msg.InVM))
+	d := &diag.Diagnostic{Code: code, Span: diag.At(types.Pos(at)), Message: msg.Sprintf(code, args...)}
+	if !c.seen[d.Error()] {
+		c.seen[d.Error()] = true
+		c.errors = append(c.errors, d)
+	}
+}
+
+// --- Declarations ---
+
+func (c *compiler) program(prog *module.Program) {
+	for _, m := range prog.Modules {
+		ast.Inspect(m.Program, func(n ast.Node) bool {
+			if p, ok := n.(*ast.PrefixExpression); ok && p.Operator == "&" {
+				if id, ok := p.Right.(*ast.Identifier); ok {
+					if sym := c.info.Uses[id]; sym != nil && sym.Decl != nil {
+						c.addressed[sym.Decl] = true
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	// Top-level functions come first, in source order, then the code of
+	// the modules and the generic instances as they are called.
+	for _, m := range prog.Modules {
+		for _, s := range m.Program.Statements {
+			name, lit := ast.FuncDecl(s)
+			if lit == nil {
+				c.global(s)
+				continue
+			}
+			c.decls[name] = lit
+			c.tops[lit] = true
+			if len(lit.TypeParams) > 0 {
+				continue
+			}
+			i := c.function(types.FuncKey{Lit: lit}, qualified(m, name.Value), lit, c.info.Funcs[lit], nil)
+			if m == prog.Main() && name.Value == "main" && len(lit.Parameters) == 0 {
+				c.prog.Main = i
+			}
+		}
+	}
+	for _, m := range prog.Modules {
+		c.prog.Init = append(c.prog.Init, c.topLevel(m))
+	}
+	for len(c.queue) > 0 {
+		p := c.queue[0]
+		c.queue = c.queue[1:]
+		c.body(p)
+	}
+}
+
+// qualified returns the name of a top-level declaration of m as the
+// disassembler shows it.
+func qualified(m *module.Module, name string) string {
+	if m.Path == "" {
+		return name
+	}
+	return m.Path + "." + name
+}
+
+// global assigns the slot of a top-level variable.
+func (c *compiler) global(s ast.Statement) {
+	var name *ast.Identifier
+	switch s := s.(type) {
+	case *ast.JasaStatement:
+		name = s.Name
+	case *ast.BekitStatement:
+		name = s.Name
+	default:
+		return
+	}
+	k := c.kind(c.symType(name), name)
+	v := variable{kind: k, global: true, cell: c.addressed[name]}
+	b := k.bank()
+	if v.cell {
+		b = RefBank
+	}
+	slots := &c.prog.Globals
+	v.loc = Loc{Bank: b, Reg: slots.count(b)}
+	slots.add(b)
+	c.globals[name] = v
+}
+
+func (r *Regs) count(b Bank) int32 {
+	switch b {
+	case IntBank:
+		return r.Int
+	case FloatBank:
+		return r.Float
+	}
+	return r.Ref
+}
+
+func (r *Regs) add(b Bank) {
+	switch b {
+	case IntBank:
+		r.Int++
+	case FloatBank:
+		r.Float++
+	default:
+		r.Ref++
+	}
+}
+
+// function returns the index of the function for key, queueing it for
+// compilation the first time.
+func (c *compiler) function(key types.FuncKey, name string, lit *ast.AtqarmLiteral, sig *types.Signature, subst map[*types.TypeParam]types.Type) int32 {
+	if i, ok := c.funcs[key]; ok {
+		return i
+	}
+	i := int32(len(c.prog.Funcs))
+	c.prog.Funcs = append(c.prog.Funcs, &Function{Name: name, File: lit.Token.File})
+	c.funcs[key] = i
+	c.queue = append(c.queue, pending{index: i, lit: lit, sig: sig, subst: subst})
+	return i
+}
+
+// instance returns the index of the generic function called by inst,
+// instantiated in the function being compiled.
+func (c *compiler) instance(inst *types.Instance, at ast.Node) int32 {
+	generic := c.info.Funcs[inst.Func]
+	if generic == nil || !c.tops[inst.Func] {
+		c.errorf(at, msg.BackendDirectCall)
+		return -1
+	}
+	key, subst := c.info.Instantiate(inst, c.fs.subst)
+	name := inst.Func.Name + "[" + key.Args + "]"
+	return c.function(key, name, inst.Func, types.Subst(generic, subst).(*types.Signature), subst)
+}
+
+func (c *compiler) symType(id *ast.Identifier) types.Type {
+	if sym := c.info.Defs[id]; sym != nil && sym.Type != nil {
+		if c.fs != nil {
+			return types.Subst(sym.Type, c.fs.subst)
+		}
+		return sym.Type
+	}
+	return types.Typ[types.Any]
+}
+
+// begin starts compiling the function at index i.
+func (c *compiler) begin(i int32, result types.Type, subst map[*types.TypeParam]types.Type) {
+	c.fs = &funcState{
+		f:      c.prog.Funcs[i],
+		vars:   make(map[*ast.Identifier]variable),
+		result: result,
+		subst:  subst,
+	}
+}
+
+// end finishes the function being compiled. A jump past the last return,
+// such as from an if whose branches all return, still needs a target.
+func (c *compiler) end() {
+	fs := c.fs
+	if n := len(fs.f.Code); n == 0 || !isReturn(fs.f.Code[n-1].Op) && fs.f.Code[n-1].Op != JMP || jumpsTo(fs.f.Code, n) {
+		c.emit(RET, 0, 0, 0)
+	}
+	fs.f.Regs = Regs{Int: fs.max[IntBank], Float: fs.max[FloatBank], Ref: fs.max[RefBank]}
+	c.fs = nil
+}
+
+func isReturn(op Op) bool {
+	return op == RET || op == RETI || op == RETF || op == RETR
+}
+
+// jumpsTo reports whether any instruction in code branches to pc.
+func jumpsTo(code []Instr, pc int) bool {
+	for _, in := range code {
+		for k, o := range operands[in.Op] {
+			if o == oPC && int([3]int32{in.A, in.B, in.C}[k]) == pc {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *compiler) body(p pending) {
+	c.begin(p.index, p.sig.Result, p.subst)
+	c.at(p.lit.Token)
+	c.fs.tails = make(map[*ast.CallExpression]bool)
+	for _, call := range ast.TailCalls(p.lit) {
+		c.fs.tails[call] = true
+	}
+	for i, param := range p.lit.Parameters {
+		k := c.kind(p.sig.Params[i], param.Name)
+		r := c.alloc(k.bank())
+		c.fs.f.Params = append(c.fs.f.Params, Loc{Bank: k.bank(), Reg: r})
+		c.fs.vars[param.Name] = variable{loc: Loc{Bank: k.bank(), Reg: r}, kind: k}
+	}
+	// Parameters whose address is taken move into cells on entry.
+	for _, param := range p.lit.Parameters {
+		if c.addressed[param.Name] {
+			v := c.fs.vars[param.Name]
+			cell := c.alloc(RefBank)
+			c.emit(newCell[v.loc.Bank], cell, v.loc.Reg, 0)
+			c.fs.vars[param.Name] = variable{loc: Loc{Bank: RefBank, Reg: cell}, kind: v.kind, cell: true}
+		}
+	}
+	c.statements(p.lit.Body.Statements)
+	c.end()
+}
+
+// topLevel compiles the top-level statements of m into a function and
+// returns its index. The variables they declare are the globals.
+func (c *compiler) topLevel(m *module.Module) int32 {
+	i := int32(len(c.prog.Funcs))
+	name := "<init>"
+	if m.Path != "" {
+		name = "<init " + m.Path + ">"
+	}
+	c.prog.Funcs = append(c.prog.Funcs, &Function{Name: name, File: m.File})
+	c.begin(i, nil, nil)
+	for _, s := range m.Program.Statements {
+		if _, lit := ast.FuncDecl(s); lit != nil {
+			continue
+		}
+		var name *ast.Identifier
+		var value ast.Expression
+		switch s := s.(type) {
+		case *ast.JasaStatement:
+			name, value = s.Name, s.Value
+		case *ast.BekitStatement:
+			name, value = s.Name, s.Value
+		default:
+			c.stmt(s)
+			continue
+		}
+		c.at(types.Pos(s))
+		mark := c.fs.next
+		g := c.globals[name]
+		r := c.alloc(g.kind.bank())
+		if value == nil {
+			c.zero(g.kind, c.symType(name), r)
+		} else {
+			c.convertInto(value, c.symType(name), r)
+		}
+		if g.cell {
+			cell := c.alloc(RefBank)
+			c.emit(newCell[g.kind.bank()], cell, r, 0)
+			r = cell
+		}
+		c.emit(setGlobal[g.loc.Bank], g.loc.Reg, r, 0)
+		c.fs.next = mark
+	}
+	c.end()
+	return i
+}
+
+// --- Registers and code ---
+
+// alloc returns a free register of bank b.
+func (c *compiler) alloc(b Bank) int32 {
+	fs := c.fs
+	r := fs.next[b]
+	fs.next[b]++
+	if fs.next[b] > fs.max[b] {
+		fs.max[b] = fs.next[b]
+	}
+	return r
+}
+
+// at sets the source position of the instructions emitted next.
+func (c *compiler) at(tok token.Token) {
+	if tok.Line != 0 {
+		c.fs.pos = tok
+	}
+}
+
+// emit appends an instruction and returns its index.
+func (c *compiler) emit(op Op, a, b, cc int32) int {
+	f := c.fs.f
+	pc := len(f.Code)
+	if pos := c.fs.pos; pos.Line != 0 {
+		n := len(f.Lines)
+		if n == 0 || f.Lines[n-1].Line != int32(pos.Line) || f.Lines[n-1].Column != int32(pos.Column) {
+			if n > 0 && int(f.Lines[n-1].PC) == pc {
+				f.Lines = f.Lines[:n-1]
+			}
+			f.Lines = append(f.Lines, Line{PC: int32(pc), Line: int32(pos.Line), Column: int32(pos.Column)})
+		}
+	}
+	f.Code = append(f.Code, Instr{Op: op, A: a, B: b, C: cc})
+	return pc
+}
+
+// pc returns the index of the next instruction.
+func (c *compiler) pc() int { return len(c.fs.f.Code) }
+
+// patch points the jumps at pcs to target.
+func (c *compiler) patch(pcs []int, target int) {
+	for _, pc := range pcs {
+		in := &c.fs.f.Code[pc]
+		switch in.Op {
+		case JMP:
+			in.A = int32(target)
+		case JT, JF:
+			in.B = int32(target)
+		default:
+			in.C = int32(target)
+		}
+	}
+}
+
+func (c *compiler) intConst(v int64) int32 {
+	if i, ok := c.ints[v]; ok {
+		return i
+	}
+	i := int32(len(c.prog.Ints))
+	c.prog.Ints = append(c.prog.Ints, v)
+	c.ints[v] = i
+	return i
+}
+
+func (c *compiler) floatConst(v float64) int32 {
+	bits := math.Float64bits(v)
+	if i, ok := c.floats[bits]; ok {
+		return i
+	}
+	i := int32(len(c.prog.Floats))
+	c.prog.Floats = append(c.prog.Floats, v)
+	c.floats[bits] = i
+	return i
+}
+
+func (c *compiler) strConst(s string) int32 {
+	if i, ok := c.strs[s]; ok {
+		return i
+	}
+	i := int32(len(c.prog.Strings))
+	c.prog.Strings = append(c.prog.Strings, s)
+	c.strs[s] = i
+	return i
+}
+
+func (c *compiler) decimalConst(d decimal.Decimal) int32 {
+	key := d.Coefficient().String() + "e" + strconv.Itoa(int(d.Exponent()))
+	if i, ok := c.decimals[key]; ok {
+		return i
+	}
+	i := int32(len(c.prog.Decimals))
+	c.prog.Decimals = append(c.prog.Decimals, d)
+	c.decimals[key] = i
+	return i
+}
+
+// loadInt emits I[dst] = v.
+func (c *compiler) loadInt(dst int32, v int64) {
+	if v == int64(int32(v)) {
+		c.emit(LOADI, dst, int32(v), 0)
+		return
+	}
+	c.emit(LOADKI, dst, c.intConst(v), 0)
+}
+
+// --- Statements ---
+
+func (c *compiler) statements(stmts []ast.Statement) {
+	for _, s := range stmts {
+		c.stmt(s)
+	}
+}
+
+// block compiles a block; its registers are free again after it.
+func (c *compiler) block(b *ast.BlockStatement) {
+	mark := c.fs.next
+	c.statements(b.Statements)
+	c.fs.next = mark
+}
+
+func (c *compiler) stmt(s ast.Statement) {
+	switch s := s.(type) {
+	case *ast.JasaStatement:
+		c.local(s, s.Name, s.Value)
+		return
+	case *ast.BekitStatement:
+		c.local(s, s.Name, s.Value)
+		return
+	}
+	// Other statements leave no registers behind.
+	mark := c.fs.next
+	switch s := s.(type) {
+	case *ast.QaıtarStatement:
+		c.at(s.Token)
+		c.qaıtar(s)
+	case *ast.ExpressionStatement:
+		if eg, ok := s.Expression.(*ast.EgerExpression); ok {
+			c.at(eg.Token)
+			c.eger(eg)
+		} else if call, ok := s.Expression.(*ast.CallExpression); !ok || !c.fs.tails[call] || !c.tailCall(call) {
+			c.at(types.Pos(s))
+			c.effect(s.Expression)
+		}
+	case *ast.AssignStatement:
+		c.at(types.Pos(s))
+		c.assign(s)
+	case *ast.BlockStatement:
+		c.block(s)
+	case *ast.AzirsheStatement:
+		c.at(s.Token)
+		c.azirshe(s)
+	case *ast.KutStatement, *ast.MindetStatement, *ast.TandaStatement:
+		c.errorf(s, msg.BackendInterpret, s.TokenLiteral())
+	}
+	c.fs.next = mark
+}
+
+func (c *compiler) local(s ast.Statement, name *ast.Identifier, value ast.Expression) {
+	if _, ok := value.(*ast.AtqarmLiteral); ok {
+		c.errorf(s, msg.BackendNested, name.Value)
+		return
+	}
+	c.at(types.Pos(s))
+	t := c.symType(name)
+	k := c.kind(t, name)
+	r := c.alloc(k.bank())
+	keep := c.fs.next
+	if value == nil {
+		c.zero(k, t, r)
+	} else {
+		c.convertInto(value, t, r)
+	}
+	c.fs.next = keep
+	v := variable{loc: Loc{Bank: k.bank(), Reg: r}, kind: k}
+	if c.addressed[name] {
+		cell := c.alloc(RefBank)
+		c.emit(newCell[k.bank()], cell, r, 0)
+		v.loc, v.cell = Loc{Bank: RefBank, Reg: cell}, true
+	}
+	c.fs.vars[name] = v
+}
+
+// zero emits the zero value of type t into register r.
+func (c *compiler) zero(k kind, t types.Type, r int32) {
+	switch k {
+	case kFloat:
+		c.emit(LOADKF, r, c.floatConst(0), 0)
+	case kDecimal:
+		c.emit(ZERO, r, ElemDecimal, 0)
+	case kJol:
+		c.emit(ZERO, r, ElemJol, 0)
+	case kArray:
+		c.emit(ZERO, r, c.elem(t.(*types.Array), nil), 0)
+	case kPointer:
+		c.emit(ZERO, r, ElemCell, 0)
+	default:
+		c.emit(LOADI, r, 0, 0)
+	}
+}
+
+func (c *compiler) qaıtar(s *ast.QaıtarStatement) {
+	if call, ok := s.ReturnValue.(*ast.CallExpression); ok && c.fs.tails[call] && c.tailCall(call) {
+		return
+	}
+	result := c.fs.result
+	switch {
+	case s.ReturnValue == nil || result == nil:
+		c.emit(RET, 0, 0, 0)
+	case types.IsVoid(result):
+		c.effect(s.ReturnValue)
+		c.emit(RET, 0, 0, 0)
+	default:
+		k := c.kind(result, s)
+		c.emit(ret[k.bank()], c.convert(s.ReturnValue, result), 0, 0)
+	}
+}
+
+// tailCall compiles a tail call of f in the body of f as a loop: the
+// arguments move into the parameters and the code jumps back to the
+// start. It reports false, compiling nothing, for calls of other
+// functions.
+func (c *compiler) tailCall(x *ast.CallExpression) bool {
+	if id, ok := x.Function.(*ast.Identifier); ok {
+		if sym := c.info.Uses[id]; sym != nil && (sym.Kind == types.TypeSym || sym.Kind == types.BuiltinSym) {
+			return false
+		}
+	}
+	fn, sig := c.callee(x)
+	if sig == nil || c.prog.Funcs[fn] != c.fs.f {
+		return false
+	}
+	params := c.fs.f.Params
+	regs := make([]int32, len(params))
+	for i, a := range x.Arguments {
+		regs[i] = c.convert(a, sig.Params[i])
+	}
+	// Every argument is computed before a parameter changes; one that is
+	// another parameter is copied first, so that f(b, a) swaps them.
+	isParam := func(l Loc) bool {
+		for _, p := range params {
+			if p == l {
+				return true
+			}
+		}
+		return false
+	}
+	for i, p := range params {
+		if r := regs[i]; r != p.Reg && isParam(Loc{Bank: p.Bank, Reg: r}) {
+			regs[i] = c.alloc(p.Bank)
+			c.emit(move[p.Bank], regs[i], r, 0)
+		}
+	}
+	for i, p := range params {
+		if regs[i] != p.Reg {
+			c.emit(move[p.Bank], p.Reg, regs[i], 0)
+		}
+	}
+	c.at(x.Token)
+	c.emit(JMP, 0, 0, 0)
+	return true
+}
+
+var (
+	ret       = [...]Op{IntBank: RETI, FloatBank: RETF, RefBank: RETR}
+	move      = [...]Op{IntBank: MOVI, FloatBank: MOVF, RefBank: MOVR}
+	getGlobal = [...]Op{IntBank: GETGI, FloatBank: GETGF, RefBank: GETGR}
+	setGlobal = [...]Op{IntBank: SETGI, FloatBank: SETGF, RefBank: SETGR}
+	newCell   = [...]Op{IntBank: NEWCI, FloatBank: NEWCF, RefBank: NEWCR}
+	loadCell  = [...]Op{IntBank: LDCI, FloatBank: LDCF, RefBank: LDCR}
+	storeCell = [...]Op{IntBank: STCI, FloatBank: STCF, RefBank: STCR}
+)
+
+// effect compiles an expression evaluated only for its side effects.
+func (c *compiler) effect(x ast.Expression) {
+	if call, ok := x.(*ast.CallExpression); ok {
+		c.call(call, -1)
+		return
+	}
+	if _, ok := x.(*ast.QatarlasExpression); !ok && types.IsVoid(c.typeOf(x)) {
+		c.errorf(x, msg.BackendEvaluate, x)
+		return
+	}
+	c.value(x)
+}
+
+func (c *compiler) assign(s *ast.AssignStatement) {
+	switch target := s.Target.(type) {
+	case *ast.Identifier:
+		v, ok := c.variable(target)
+		if !ok {
+			return
+		}
+		t := c.typeOf(target)
+		switch {
+		case !v.cell && !v.global:
+			c.convertInto(s.Value, t, v.loc.Reg)
+		case v.cell:
+			val := c.convert(s.Value, t)
+			cell := v.loc.Reg
+			if v.global {
+				cell = c.alloc(RefBank)
+				c.emit(GETGR, cell, v.loc.Reg, 0)
+			}
+			c.emit(storeCell[v.kind.bank()], cell, val, 0)
+		default:
+			c.emit(setGlobal[v.loc.Bank], v.loc.Reg, c.convert(s.Value, t), 0)
+		}
+	case *ast.IndexExpression:
+		arr, ok := c.typeOf(target.Left).(*types.Array)
+		if !ok {
+			c.errorf(target, msg.BackendAssign, target)
+			return
+		}
+		a := c.value(target.Left)
+		i := c.convert(target.Index, types.Typ[types.San])
+		val := c.convert(s.Value, arr.Elem)
+		c.at(target.Token)
+		if c.elem(arr, target) == ElemFloat {
+			c.emit(SETAF, a, i, val)
+		} else {
+			c.emit(SETAI, a, i, val)
+		}
+	case *ast.PrefixExpression:
+		p, ok := c.typeOf(target.Right).(*types.Pointer)
+		if target.Operator != "*" || !ok {
+			c.errorf(target, msg.BackendAssign, target)
+			return
+		}
+		cell := c.value(target.Right)
+		val := c.convert(s.Value, p.Elem)
+		c.at(target.Token)
+		c.emit(storeCell[c.kind(p.Elem, target).bank()], cell, val, 0)
+	default:
+		c.errorf(target, msg.BackendAssign, target)
+	}
+}
+
+// azirshe compiles a loop with its condition at the bottom, so that each
+// iteration takes one branch.
+func (c *compiler) azirshe(s *ast.AzirsheStatement) {
+	enter := c.emit(JMP, 0, 0, 0)
+	body := c.pc()
+	c.block(s.Body)
+	c.patch([]int{enter}, c.pc())
+	c.at(s.Token)
+	c.patch(c.branch(s.Condition, true), body)
+}
+
+// eger compiles an eger statement.
+func (c *compiler) eger(eg *ast.EgerExpression) {
+	skip := c.branch(eg.Condition, false)
+	c.block(eg.Consequence)
+	if eg.Alternative == nil {
+		c.patch(skip, c.pc())
+		return
+	}
+	done := c.emit(JMP, 0, 0, 0)
+	c.patch(skip, c.pc())
+	c.block(eg.Alternative)
+	c.patch([]int{done}, c.pc())
+}
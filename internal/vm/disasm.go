@@ -0,0 +1,228 @@
+// FILE: internal/vm/disasm.go
+
+package vm
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// operand is what an instruction operand refers to.
+type operand uint8
+
+const (
+	oNone operand = iota
+	oI            // int register
+	oF            // float register
+	oR            // ref register
+	oRopt         // ref register, or -1 for none
+	oGI           // int global
+	oGF           // float global
+	oGR           // ref global
+	oImm          // immediate integer
+	oPC           // instruction index
+	oKI           // index into Ints
+	oKF           // index into Floats
+	oKS           // index into Strings
+	oKD           // index into Decimals
+	oCall         // index into the function's Calls
+	oElem         // elem kind
+)
+
+// operands gives the meaning of A, B and C for each opcode.
+var operands = [numOps][3]operand{
+	NOP: {},
+
+	MOVI: {oI, oI}, MOVF: {oF, oF}, MOVR: {oR, oR},
+	LOADI: {oI, oImm}, LOADKI: {oI, oKI}, LOADKF: {oF, oKF}, LOADKS: {oR, oKS}, LOADKD: {oR, oKD},
+	ZERO: {oR, oElem},
+
+	GETGI: {oI, oGI}, GETGF: {oF, oGF}, GETGR: {oR, oGR},
+	SETGI: {oGI, oI}, SETGF: {oGF, oF}, SETGR: {oGR, oR},
+
+	NEWCI: {oR, oI}, NEWCF: {oR, oF}, NEWCR: {oR, oR},
+	LDCI: {oI, oR}, LDCF: {oF, oR}, LDCR: {oR, oR},
+	STCI: {oR, oI}, STCF: {oR, oF}, STCR: {oR, oR},
+
+	ADDI: {oI, oI, oI}, ADDIK: {oI, oI, oImm}, SUBI: {oI, oI, oI}, MULI: {oI, oI, oI},
+	DIVI: {oI, oI, oI}, MODI: {oI, oI, oI}, DIVU: {oI, oI, oI}, MODU: {oI, oI, oI},
+	ANDI: {oI, oI, oI}, ORI: {oI, oI, oI}, XORI: {oI, oI, oI},
+	SHLI: {oI, oI, oI}, SHRI: {oI, oI, oI}, SHRU: {oI, oI, oI},
+	NEGI: {oI, oI}, NOT: {oI, oI}, SEXT: {oI, oI},
+
+	ADDF: {oF, oF, oF}, SUBF: {oF, oF, oF}, MULF: {oF, oF, oF},
+	DIVF: {oF, oF, oF}, MODF: {oF, oF, oF}, NEGF: {oF, oF},
+
+	ITOF: {oF, oI}, UTOF: {oF, oI}, FTOI: {oI, oF}, FTOU: {oI, oF},
+
+	ADDD: {oR, oR, oR}, SUBD: {oR, oR, oR}, MULD: {oR, oR, oR},
+	DIVD: {oR, oR, oR}, MODD: {oR, oR, oR}, NEGD: {oR, oR},
+	ITOD: {oR, oI}, UTOD: {oR, oI}, FTOD: {oR, oF},
+	DTOI: {oI, oR}, DTOU: {oI, oR}, DTOF: {oF, oR},
+
+	EQI: {oI, oI, oI}, NEI: {oI, oI, oI}, LTI: {oI, oI, oI}, LEI: {oI, oI, oI},
+	LTU: {oI, oI, oI}, LEU: {oI, oI, oI},
+	EQF: {oI, oF, oF}, NEF: {oI, oF, oF}, LTF: {oI, oF, oF}, LEF: {oI, oF, oF},
+	EQS: {oI, oR, oR}, NES: {oI, oR, oR}, LTS: {oI, oR, oR}, LES: {oI, oR, oR},
+	EQD: {oI, oR, oR}, NED: {oI, oR, oR}, LTD: {oI, oR, oR}, LED: {oI, oR, oR},
+
+	JMP: {oPC}, JT: {oI, oPC}, JF: {oI, oPC},
+	BEQI: {oI, oI, oPC}, BNEI: {oI, oI, oPC}, BLTI: {oI, oI, oPC}, BLEI: {oI, oI, oPC},
+	BLTU: {oI, oI, oPC}, BLEU: {oI, oI, oPC},
+	BEQIK: {oI, oImm, oPC}, BNEIK: {oI, oImm, oPC}, BLTIK: {oI, oImm, oPC},
+	BLEIK: {oI, oImm, oPC}, BGTIK: {oI, oImm, oPC}, BGEIK: {oI, oImm, oPC},
+
+	CALL: {oCall}, RET: {}, RETI: {oI}, RETF: {oF}, RETR: {oR},
+
+	CONCAT: {oR, oR, oR}, NEWAI: {oR, oI}, NEWAF: {oR, oI},
+	GETAI: {oI, oR, oI}, GETAF: {oF, oR, oI}, SETAI: {oR, oI, oI}, SETAF: {oR, oI, oF},
+	PUSHI: {oR, oR, oI}, PUSHF: {oR, oR, oF}, SORT: {oR, oR, oElem}, LEN: {oI, oR},
+
+	ARGI: {oI, oI, oI}, ARGF: {oF, oI, oF}, NOWNS: {oI},
+	SQRT: {oF, oF}, LOG: {oF, oF}, EXP: {oF, oF}, COS: {oF, oF}, SIN: {oF, oF}, FLOOR: {oF, oF},
+
+	SHOWI: {oI}, SHOWU: {oI}, SHOWB: {oI}, SHOWF: {oF}, SHOWD: {oR}, SHOWS: {oR},
+	SHOWA: {oR, oElem}, NEWLINE: {}, PRINTF: {oF, oI}, TIMENS: {oI}, ASSERT: {oI, oRopt},
+}
+
+var elemNames = [...]string{
+	ElemInt: "int", ElemU64: "u64", ElemBool: "bool", ElemFloat: "float",
+	ElemCell: "cell", ElemJol: "jol", ElemDecimal: "aqsha",
+}
+
+// Disassemble writes a listing of p: the constant pools, then every
+// function with its registers, code, call sites and source positions.
+func Disassemble(w io.Writer, p *Program) {
+	fmt.Fprintf(w, "; tenge bytecode, format %d\n", FormatVersion)
+	fmt.Fprintf(w, "; globals: I%d F%d R%d\n", p.Globals.Int, p.Globals.Float, p.Globals.Ref)
+	for i, v := range p.Ints {
+		fmt.Fprintf(w, "; KI%d = %d\n", i, v)
+	}
+	for i, v := range p.Floats {
+		fmt.Fprintf(w, "; KF%d = %s\n", i, showFloat(v))
+	}
+	for i, v := range p.Strings {
+		fmt.Fprintf(w, "; KS%d = %s\n", i, strconv.Quote(v))
+	}
+	for i, v := range p.Decimals {
+		fmt.Fprintf(w, "; KD%d = %s\n", i, v.String())
+	}
+	for i, f := range p.Funcs {
+		fmt.Fprintln(w)
+		disassembleFunc(w, p, int32(i), f)
+	}
+}
+
+func disassembleFunc(w io.Writer, p *Program, index int32, f *Function) {
+	var role string
+	switch {
+	case index == p.Main:
+		role = " (main)"
+	case slices.Contains(p.Init, index):
+		role = " (init)"
+	}
+	fmt.Fprintf(w, "func %d %s%s\n", index, f.Name, role)
+	if f.File != "" {
+		fmt.Fprintf(w, "  ; file %s\n", f.File)
+	}
+	fmt.Fprintf(w, "  ; regs I%d F%d R%d, params %s\n", f.Regs.Int, f.Regs.Float, f.Regs.Ref, locs(f.Params))
+
+	line := 0
+	for pc, in := range f.Code {
+		for line < len(f.Lines) && int(f.Lines[line].PC) <= pc {
+			l := f.Lines[line]
+			fmt.Fprintf(w, "  ; %d:%d\n", l.Line, l.Column)
+			line++
+		}
+		fmt.Fprintf(w, "  %4d  %s\n", pc, formatInstr(p, f, in))
+	}
+}
+
+// formatInstr writes an instruction with its operands named by what they
+// refer to, such as "ADDI I2, I0, I1" or "LOADKS R0, \"hi\"".
+func formatInstr(p *Program, f *Function, in Instr) string {
+	s := in.Op.String()
+	if in.Op >= numOps {
+		return s
+	}
+	sep := strings.Repeat(" ", max(1, 8-len(s)))
+	for k, o := range operands[in.Op] {
+		if o == oNone {
+			break
+		}
+		s += sep + formatOperand(p, f, o, [3]int32{in.A, in.B, in.C}[k])
+		sep = ", "
+	}
+	return s
+}
+
+func formatOperand(p *Program, f *Function, o operand, v int32) string {
+	switch o {
+	case oI:
+		return fmt.Sprintf("I%d", v)
+	case oF:
+		return fmt.Sprintf("F%d", v)
+	case oR:
+		return fmt.Sprintf("R%d", v)
+	case oRopt:
+		if v < 0 {
+			return "-"
+		}
+		return fmt.Sprintf("R%d", v)
+	case oGI:
+		return fmt.Sprintf("GI%d", v)
+	case oGF:
+		return fmt.Sprintf("GF%d", v)
+	case oGR:
+		return fmt.Sprintf("GR%d", v)
+	case oPC:
+		return fmt.Sprintf("@%d", v)
+	case oKI:
+		if int(v) < len(p.Ints) {
+			return fmt.Sprintf("KI%d(%d)", v, p.Ints[v])
+		}
+	case oKF:
+		if int(v) < len(p.Floats) {
+			return fmt.Sprintf("KF%d(%s)", v, showFloat(p.Floats[v]))
+		}
+	case oKS:
+		if int(v) < len(p.Strings) {
+			return fmt.Sprintf("KS%d(%s)", v, strconv.Quote(p.Strings[v]))
+		}
+	case oKD:
+		if int(v) < len(p.Decimals) {
+			return fmt.Sprintf("KD%d(%s)", v, p.Decimals[v].String())
+		}
+	case oCall:
+		if int(v) < len(f.Calls) {
+			call := f.Calls[v]
+			name := "?"
+			if int(call.Func) < len(p.Funcs) {
+				name = p.Funcs[call.Func].Name
+			}
+			return fmt.Sprintf("%s(%s) -> %s", name, locs(call.Args), call.Result)
+		}
+	case oElem:
+		if v >= 0 && int(v) < len(elemNames) {
+			return elemNames[v]
+		}
+	}
+	return strconv.Itoa(int(v))
+}
+
+func locs(ls []Loc) string {
+	if len(ls) == 0 {
+		return "none"
+	}
+	s := ""
+	for i, l := range ls {
+		if i > 0 {
+			s += ", "
+		}
+		s += l.String()
+	}
+	return s
+}
@@ -0,0 +1,869 @@
+// FILE: internal/vm/expr.go
+
+package vm
+
+import (
+	"github.com/DauletBai/tenge/internal/lang/ast"
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/DauletBai/tenge/internal/lang/types"
+)
+
+// --- Types ---
+
+// kind is the representation of a type in the VM.
+type kind uint8
+
+const (
+	kInt     kind = iota // san, any: int64
+	kI32                 // i32, tańba: int64 holding an int32
+	kU64                 // u64: int64 holding the bits
+	kBool                // aqıqat: 0 or 1
+	kFloat               // f64
+	kDecimal             // aqsha and untyped decimal constants: a decimal.Decimal
+	kJol                 // jol: a Go string
+	kArray               // []T: []int64 or []float64
+	kPointer             // &T: a cell
+	kVoid
+)
+
+func (k kind) bank() Bank {
+	switch k {
+	case kFloat:
+		return FloatBank
+	case kDecimal, kJol, kArray, kPointer:
+		return RefBank
+	}
+	return IntBank
+}
+
+func (c *compiler) typeOf(x ast.Expression) types.Type {
+	if t := c.info.TypeOf(x); t != nil {
+		return types.Subst(t, c.fs.subst)
+	}
+	return types.Typ[types.Any]
+}
+
+// kind returns the representation of t. `any` values are 64-bit integers
+// in compiled code, like in the C backend.
+func (c *compiler) kind(t types.Type, at ast.Node) kind {
+	switch t := t.(type) {
+	case *types.Basic:
+		switch t.Kind {
+		case types.Any, types.San, types.UntypedInt:
+			return kInt
+		case types.I32, types.Tańba:
+			return kI32
+		case types.U64:
+			return kU64
+		case types.F64:
+			return kFloat
+		case types.Aqsha, types.UntypedFloat:
+			return kDecimal
+		case types.Aqıqat:
+			return kBool
+		case types.Jol:
+			return kJol
+		case types.Void:
+			return kVoid
+		}
+	case *types.Array:
+		c.elem(t, at)
+		return kArray
+	case *types.Pointer:
+		c.kind(t.Elem, at)
+		return kPointer
+	}
+	c.errorf(at, msg.BackendType, t)
+	return kInt
+}
+
+func (c *compiler) kindOf(x ast.Expression) kind {
+	return c.kind(c.typeOf(x), x)
+}
+
+// elem returns the elem kind of an array type. at may be nil when the type
+// has been checked before.
+func (c *compiler) elem(t *types.Array, at ast.Node) int32 {
+	if b, ok := t.Elem.(*types.Basic); ok {
+		switch b.Kind {
+		case types.Any, types.San, types.UntypedInt, types.I32, types.Tańba:
+			return ElemInt
+		case types.U64:
+			return ElemU64
+		case types.Aqıqat:
+			return ElemBool
+		case types.F64, types.UntypedFloat:
+			return ElemFloat
+		}
+	}
+	if at != nil {
+		c.errorf(at, msg.BackendType, t)
+	}
+	return ElemInt
+}
+
+// --- Values ---
+
+// variable returns where the variable id refers to lives.
+func (c *compiler) variable(id *ast.Identifier) (variable, bool) {
+	sym := c.info.Uses[id]
+	if sym == nil || sym.Decl == nil {
+		c.errorf(id, msg.BackendNotValue, id.Value)
+		return variable{}, false
+	}
+	if v, ok := c.fs.vars[sym.Decl]; ok {
+		return v, true
+	}
+	if v, ok := c.globals[sym.Decl]; ok {
+		return v, true
+	}
+	if _, ok := c.decls[sym.Decl]; ok {
+		c.errorf(id, msg.BackendCallOnly, id.Value)
+	} else {
+		c.errorf(id, msg.BackendUnsupported, id.Value)
+	}
+	return variable{}, false
+}
+
+// value returns a register of the bank of x's kind holding x. Local
+// variables are used in place.
+func (c *compiler) value(x ast.Expression) int32 {
+	if id, ok := x.(*ast.Identifier); ok {
+		if sym := c.info.Uses[id]; sym != nil {
+			if v, ok := c.fs.vars[sym.Decl]; ok && !v.cell {
+				return v.loc.Reg
+			}
+		}
+	}
+	r := c.alloc(c.kindOf(x).bank())
+	c.into(x, r)
+	return r
+}
+
+// convert returns a register holding x as a value of type t.
+func (c *compiler) convert(x ast.Expression, t types.Type) int32 {
+	to := c.kind(t, x)
+	if same(c.kindOf(x), to) {
+		return c.value(x)
+	}
+	r := c.alloc(to.bank())
+	c.convertInto(x, t, r)
+	return r
+}
+
+// convertInto compiles x as a value of type t into register dst.
+func (c *compiler) convertInto(x ast.Expression, t types.Type, dst int32) {
+	from, to := c.kindOf(x), c.kind(t, x)
+	switch {
+	case same(from, to):
+		c.into(x, dst)
+	case ast.IsNumberLiteral(x) && to != kJol && to != kArray && to != kPointer:
+		c.literal(x, to, dst)
+	default:
+		c.conv(from, to, dst, c.value(x), x)
+	}
+}
+
+// same reports whether values of kind from are already values of kind to.
+func same(from, to kind) bool {
+	if from == to {
+		return true
+	}
+	switch to {
+	case kInt, kU64:
+		return from == kInt || from == kU64 || from == kI32
+	}
+	return false
+}
+
+// literal loads the number literal x as a constant of kind k.
+func (c *compiler) literal(x ast.Expression, k kind, dst int32) {
+	i, f, isInt := number(x)
+	switch {
+	case k == kDecimal:
+		c.emit(LOADKD, dst, c.decimalConst(ast.NumberValue(x)), 0)
+	case k == kFloat:
+		c.emit(LOADKF, dst, c.floatConst(f), 0)
+	case !isInt:
+		c.errorf(x, msg.BackendUse, x, c.typeOf(x))
+	case k == kI32:
+		c.loadInt(dst, int64(int32(i)))
+	default:
+		c.loadInt(dst, i)
+	}
+}
+
+// number returns the value of a number literal as an integer, when it is
+// one, and as a float.
+func number(x ast.Expression) (i int64, f float64, isInt bool) {
+	switch x := x.(type) {
+	case *ast.SanLiteral:
+		return x.Value, float64(x.Value), true
+	case *ast.AqshaLiteral:
+		f, _ = x.Value.Float64()
+		return 0, f, false
+	case *ast.PrefixExpression:
+		i, f, isInt = number(x.Right)
+		return -i, -f, isInt
+	}
+	return 0, 0, false
+}
+
+// conv emits the conversion of register src of kind from to register dst
+// of kind to.
+func (c *compiler) conv(from, to kind, dst, src int32, at ast.Expression) {
+	switch {
+	case same(from, to):
+		if dst != src {
+			c.emit(move[to.bank()], dst, src, 0)
+		}
+	case to == kI32 && from.bank() == IntBank:
+		c.emit(SEXT, dst, src, 0)
+	case to == kFloat && from == kU64:
+		c.emit(UTOF, dst, src, 0)
+	case to == kFloat && from.bank() == IntBank && from != kBool:
+		c.emit(ITOF, dst, src, 0)
+	case from == kFloat && to == kU64:
+		c.emit(FTOU, dst, src, 0)
+	case from == kFloat && (to == kInt || to == kI32):
+		c.emit(FTOI, dst, src, 0)
+		if to == kI32 {
+			c.emit(SEXT, dst, dst, 0)
+		}
+	case to == kDecimal && from == kU64:
+		c.emit(UTOD, dst, src, 0)
+	case to == kDecimal && from.bank() == IntBank && from != kBool:
+		c.emit(ITOD, dst, src, 0)
+	case to == kDecimal && from == kFloat:
+		c.emit(FTOD, dst, src, 0)
+	case from == kDecimal && to == kFloat:
+		c.emit(DTOF, dst, src, 0)
+	case from == kDecimal && to == kU64:
+		c.emit(DTOU, dst, src, 0)
+	case from == kDecimal && (to == kInt || to == kI32):
+		c.emit(DTOI, dst, src, 0)
+		if to == kI32 {
+			c.emit(SEXT, dst, dst, 0)
+		}
+	case from == kArray && to == kArray:
+		// Arrays of any and of san share a representation.
+		c.emit(MOVR, dst, src, 0)
+	default:
+		c.errorf(at, msg.BackendUseTyped, at, c.typeOf(at), kindNames[to])
+	}
+}
+
+var kindNames = [...]string{
+	kInt: "san", kI32: "i32", kU64: "u64", kBool: "aqıqat", kFloat: "f64",
+	kDecimal: "aqsha", kJol: "jol", kArray: "array", kPointer: "pointer", kVoid: "void",
+}
+
+// into compiles x into register dst of the bank of its kind. dst is
+// written only after the operands of x are read, so x may read the
+// variable in dst.
+func (c *compiler) into(x ast.Expression, dst int32) {
+	switch x := x.(type) {
+	case *ast.Identifier:
+		c.ident(x, dst)
+	case *ast.SelectorExpression:
+		c.ident(x.Sel, dst)
+	case *ast.SanLiteral, *ast.AqshaLiteral:
+		c.literal(x, c.kindOf(x), dst)
+	case *ast.JolLiteral:
+		c.emit(LOADKS, dst, c.strConst(x.Value), 0)
+	case *ast.AqıqatLiteral:
+		v := int32(0)
+		if x.Value {
+			v = 1
+		}
+		c.emit(LOADI, dst, v, 0)
+	case *ast.JyimLiteral:
+		c.jyimLiteral(x, dst)
+	case *ast.PrefixExpression:
+		c.prefix(x, dst)
+	case *ast.InfixExpression:
+		c.infix(x, dst)
+	case *ast.EgerExpression:
+		c.egerValue(x, dst)
+	case *ast.CallExpression:
+		c.call(x, dst)
+	case *ast.IndexExpression:
+		c.index(x.Left, x.Index, dst, x)
+	case *ast.AtqarmLiteral:
+		c.errorf(x, msg.BackendFuncLit)
+	case *ast.QatarlasExpression:
+		c.errorf(x, msg.BackendInterpret, x.Token.Literal)
+	default:
+		c.errorf(x, msg.BackendExpression, x)
+	}
+}
+
+func (c *compiler) ident(id *ast.Identifier, dst int32) {
+	if sym := c.info.Uses[id]; sym != nil && sym.Decl == nil && sym.Name == "PI" {
+		c.emit(LOADKF, dst, c.floatConst(3.141592653589793), 0)
+		return
+	}
+	v, ok := c.variable(id)
+	if !ok {
+		return
+	}
+	b := v.kind.bank()
+	switch {
+	case v.cell && v.global:
+		cell := c.alloc(RefBank)
+		c.emit(GETGR, cell, v.loc.Reg, 0)
+		c.emit(loadCell[b], dst, cell, 0)
+	case v.cell:
+		c.emit(loadCell[b], dst, v.loc.Reg, 0)
+	case v.global:
+		c.emit(getGlobal[b], dst, v.loc.Reg, 0)
+	case v.loc.Reg != dst:
+		c.emit(move[b], dst, v.loc.Reg, 0)
+	}
+}
+
+func (c *compiler) jyimLiteral(x *ast.JyimLiteral, dst int32) {
+	t, _ := c.typeOf(x).(*types.Array)
+	if t == nil {
+		t = &types.Array{Elem: types.Typ[types.Any]}
+	}
+	elem := c.elem(t, x)
+	// The array is built in a temporary: an element may read dst.
+	arr, n := c.alloc(RefBank), c.alloc(IntBank)
+	c.loadInt(n, int64(len(x.Elements)))
+	set := SETAI
+	if elem == ElemFloat {
+		c.emit(NEWAF, arr, n, 0)
+		set = SETAF
+	} else {
+		c.emit(NEWAI, arr, n, 0)
+	}
+	for i, el := range x.Elements {
+		v := c.convert(el, t.Elem)
+		c.loadInt(n, int64(i))
+		c.emit(set, arr, n, v)
+	}
+	c.emit(MOVR, dst, arr, 0)
+}
+
+func (c *compiler) prefix(x *ast.PrefixExpression, dst int32) {
+	switch x.Operator {
+	case "-":
+		if ast.IsNumberLiteral(x.Right) {
+			c.literal(x, c.kindOf(x), dst)
+			return
+		}
+		k := c.kindOf(x)
+		r := c.convert(x.Right, c.typeOf(x))
+		switch k {
+		case kFloat:
+			c.emit(NEGF, dst, r, 0)
+		case kDecimal:
+			c.emit(NEGD, dst, r, 0)
+		case kI32:
+			c.emit(NEGI, dst, r, 0)
+			c.emit(SEXT, dst, dst, 0)
+		default:
+			c.emit(NEGI, dst, r, 0)
+		}
+	case "!":
+		c.emit(NOT, dst, c.value(x.Right), 0)
+	case "&":
+		id, _ := x.Right.(*ast.Identifier)
+		v, ok := c.variable(id)
+		if !ok || !v.cell {
+			return
+		}
+		if v.global {
+			c.emit(GETGR, dst, v.loc.Reg, 0)
+		} else {
+			c.emit(MOVR, dst, v.loc.Reg, 0)
+		}
+	case "*":
+		cell := c.value(x.Right)
+		c.at(x.Token)
+		c.emit(loadCell[c.kindOf(x).bank()], dst, cell, 0)
+	default:
+		c.errorf(x, msg.BackendOperator, x.Operator)
+	}
+}
+
+// operands returns the type both operands of a comparison are converted
+// to.
+func (c *compiler) operands(x *ast.InfixExpression) types.Type {
+	return types.Comparand(x, c.typeOf(x.Left), c.typeOf(x.Right))
+}
+
+// Comparison opcodes by operand kind: ==, !=, <, <=.
+var compare = map[kind][4]Op{
+	kInt:     {EQI, NEI, LTI, LEI},
+	kI32:     {EQI, NEI, LTI, LEI},
+	kBool:    {EQI, NEI, LTI, LEI},
+	kU64:     {EQI, NEI, LTU, LEU},
+	kFloat:   {EQF, NEF, LTF, LEF},
+	kDecimal: {EQD, NED, LTD, LED},
+	kJol:     {EQS, NES, LTS, LES},
+}
+
+var arith = map[string][3]Op{ // int, float, decimal
+	"+":  {ADDI, ADDF, ADDD},
+	"-":  {SUBI, SUBF, SUBD},
+	"*":  {MULI, MULF, MULD},
+	"/":  {DIVI, DIVF, DIVD},
+	"%":  {MODI, MODF, MODD},
+	"&":  {ANDI, NOP, NOP},
+	"|":  {ORI, NOP, NOP},
+	"^":  {XORI, NOP, NOP},
+	"<<": {SHLI, NOP, NOP},
+	">>": {SHRI, NOP, NOP},
+}
+
+func (c *compiler) infix(x *ast.InfixExpression, dst int32) {
+	switch x.Operator {
+	case "&&", "||":
+		c.boolValue(x, dst)
+		return
+	case "==", "!=", "<", "<=", ">", ">=":
+		t := c.operands(x)
+		ops := compare[c.kind(t, x)]
+		l, r := c.convert(x.Left, t), c.convert(x.Right, t)
+		switch x.Operator {
+		case "==":
+			c.emit(ops[0], dst, l, r)
+		case "!=":
+			c.emit(ops[1], dst, l, r)
+		case "<":
+			c.emit(ops[2], dst, l, r)
+		case "<=":
+			c.emit(ops[3], dst, l, r)
+		case ">":
+			c.emit(ops[2], dst, r, l)
+		case ">=":
+			c.emit(ops[3], dst, r, l)
+		}
+		return
+	}
+
+	t := c.typeOf(x)
+	k := c.kind(t, x)
+	if k == kJol && x.Operator == "+" {
+		c.emit(CONCAT, dst, c.value(x.Left), c.value(x.Right))
+		return
+	}
+	ops, ok := arith[x.Operator]
+	if !ok || (k == kFloat && ops[1] == NOP) || (k == kDecimal && ops[2] == NOP) ||
+		(k != kFloat && k != kDecimal && k.bank() != IntBank) {
+		c.errorf(x, msg.BackendOperatorOn, x.Operator, t)
+		return
+	}
+	l := c.convert(x.Left, t)
+	switch k {
+	case kFloat:
+		c.emit(ops[1], dst, l, c.convert(x.Right, t))
+		return
+	case kDecimal:
+		r := c.convert(x.Right, t)
+		if ops[2] == DIVD || ops[2] == MODD {
+			c.at(x.Token)
+		}
+		c.emit(ops[2], dst, l, r)
+		return
+	}
+
+	op := ops[0]
+	// Adding or subtracting a small constant needs no register for it.
+	if v, ok := smallInt(x.Right); ok && (op == ADDI || op == SUBI) && v != -1<<31 {
+		if op == SUBI {
+			v = -v
+		}
+		c.emit(ADDIK, dst, l, v)
+		if k == kI32 {
+			c.emit(SEXT, dst, dst, 0)
+		}
+		return
+	}
+	var r int32
+	if op == SHLI || op == SHRI {
+		r = c.convert(x.Right, types.Typ[types.San])
+	} else {
+		r = c.convert(x.Right, t)
+	}
+	if k == kU64 {
+		switch op {
+		case DIVI:
+			op = DIVU
+		case MODI:
+			op = MODU
+		case SHRI:
+			op = SHRU
+		}
+	}
+	if op == DIVI || op == MODI || op == DIVU || op == MODU {
+		c.at(x.Token)
+	}
+	c.emit(op, dst, l, r)
+	if k == kI32 && (op == ADDI || op == SUBI || op == MULI || op == DIVI || op == SHLI) {
+		c.emit(SEXT, dst, dst, 0)
+	}
+}
+
+// smallInt returns the value of an integer literal that fits an operand.
+func smallInt(x ast.Expression) (int32, bool) {
+	var v int64
+	switch x := x.(type) {
+	case *ast.SanLiteral:
+		v = x.Value
+	case *ast.PrefixExpression:
+		lit, ok := x.Right.(*ast.SanLiteral)
+		if x.Operator != "-" || !ok {
+			return 0, false
+		}
+		v = -lit.Value
+	default:
+		return 0, false
+	}
+	if v != int64(int32(v)) {
+		return 0, false
+	}
+	return int32(v), true
+}
+
+// boolValue computes a condition into dst by branching on it.
+func (c *compiler) boolValue(x ast.Expression, dst int32) {
+	skip := c.branch(x, false)
+	c.emit(LOADI, dst, 1, 0)
+	done := c.emit(JMP, 0, 0, 0)
+	c.patch(skip, c.pc())
+	c.emit(LOADI, dst, 0, 0)
+	c.patch([]int{done}, c.pc())
+}
+
+// Branches on integers: the jump taken when the operands compare with
+// ==, !=, <, <=, > or >=.
+var (
+	branchOps = map[string]Op{"==": BEQI, "!=": BNEI, "<": BLTI, "<=": BLEI}
+	branchK   = map[string]Op{"==": BEQIK, "!=": BNEIK, "<": BLTIK, "<=": BLEIK, ">": BGTIK, ">=": BGEIK}
+	negated   = map[string]string{"==": "!=", "!=": "==", "<": ">=", "<=": ">", ">": "<=", ">=": "<"}
+	mirrored  = map[string]string{"==": "==", "!=": "!=", "<": ">", "<=": ">=", ">": "<", ">=": "<="}
+)
+
+// branch emits jumps taken when condition x is jumpIf and falls through
+// otherwise. It returns the jumps for the caller to patch.
+func (c *compiler) branch(x ast.Expression, jumpIf bool) []int {
+	switch x := x.(type) {
+	case *ast.AqıqatLiteral:
+		if x.Value == jumpIf {
+			return []int{c.emit(JMP, 0, 0, 0)}
+		}
+		return nil
+	case *ast.PrefixExpression:
+		if x.Operator == "!" {
+			return c.branch(x.Right, !jumpIf)
+		}
+	case *ast.InfixExpression:
+		switch x.Operator {
+		case "&&", "||":
+			// a && b jumps when false if either does, and when true only
+			// if both are; a || b the other way round.
+			if (x.Operator == "&&") != jumpIf {
+				return append(c.branch(x.Left, jumpIf), c.branch(x.Right, jumpIf)...)
+			}
+			skip := c.branch(x.Left, !jumpIf)
+			jumps := c.branch(x.Right, jumpIf)
+			c.patch(skip, c.pc())
+			return jumps
+		case "==", "!=", "<", "<=", ">", ">=":
+			if t := c.operands(x); c.kind(t, x) == kInt || c.kind(t, x) == kI32 || c.kind(t, x) == kBool {
+				op := x.Operator
+				if !jumpIf {
+					op = negated[op]
+				}
+				return []int{c.compareJump(x.Left, x.Right, op, t)}
+			}
+		}
+	}
+	r := c.convert(x, types.Typ[types.Aqıqat])
+	if jumpIf {
+		return []int{c.emit(JT, r, 0, 0)}
+	}
+	return []int{c.emit(JF, r, 0, 0)}
+}
+
+// compareJump emits a jump taken when l op r for signed integers.
+func (c *compiler) compareJump(l, r ast.Expression, op string, t types.Type) int {
+	if _, ok := smallInt(r); !ok {
+		if _, ok := smallInt(l); ok {
+			l, r, op = r, l, mirrored[op]
+		}
+	}
+	if k, ok := smallInt(r); ok {
+		return c.emit(branchK[op], c.convert(l, t), k, 0)
+	}
+	a, b := c.convert(l, t), c.convert(r, t)
+	switch op {
+	case ">":
+		a, b, op = b, a, "<"
+	case ">=":
+		a, b, op = b, a, "<="
+	}
+	return c.emit(branchOps[op], a, b, 0)
+}
+
+// egerValue compiles an eger whose branches both end in a value.
+func (c *compiler) egerValue(x *ast.EgerExpression, dst int32) {
+	t := c.typeOf(x)
+	if x.Alternative == nil || types.IsVoid(t) {
+		c.errorf(x, msg.BackendEgerValue)
+		return
+	}
+	skip := c.branch(x.Condition, false)
+	c.blockValue(x.Consequence, t, dst)
+	done := c.emit(JMP, 0, 0, 0)
+	c.patch(skip, c.pc())
+	c.blockValue(x.Alternative, t, dst)
+	c.patch([]int{done}, c.pc())
+}
+
+// blockValue compiles a block whose last statement is an expression and
+// puts its value into dst.
+func (c *compiler) blockValue(b *ast.BlockStatement, t types.Type, dst int32) {
+	mark := c.fs.next
+	n := len(b.Statements)
+	c.statements(b.Statements[:n-1])
+	last := b.Statements[n-1].(*ast.ExpressionStatement)
+	c.at(types.Pos(last))
+	c.convertInto(last.Expression, t, dst)
+	c.fs.next = mark
+}
+
+// index compiles a[i] into dst.
+func (c *compiler) index(left, idx ast.Expression, dst int32, at ast.Node) {
+	arr, ok := c.typeOf(left).(*types.Array)
+	if !ok {
+		c.errorf(at, msg.BackendIndex, left, c.typeOf(left))
+		return
+	}
+	a := c.value(left)
+	i := c.convert(idx, types.Typ[types.San])
+	if x, ok := at.(*ast.IndexExpression); ok {
+		c.at(x.Token)
+	} else {
+		c.at(types.Pos(at))
+	}
+	if c.elem(arr, at) == ElemFloat {
+		c.emit(GETAF, dst, a, i)
+	} else {
+		c.emit(GETAI, dst, a, i)
+	}
+}
+
+// --- Calls ---
+
+// call compiles a call. dst receives the result; it is -1 when the result
+// is not used.
+func (c *compiler) call(x *ast.CallExpression, dst int32) {
+	if tn, ok := x.Function.(*ast.TypeNode); ok {
+		c.errorf(x, msg.BackendInterpret, tn.Token.Literal)
+		return
+	}
+	if id, ok := x.Function.(*ast.Identifier); ok {
+		if sym := c.info.Uses[id]; sym != nil {
+			switch sym.Kind {
+			case types.TypeSym:
+				if len(x.Arguments) == 1 {
+					if dst < 0 {
+						dst = c.alloc(c.kindOf(x).bank())
+					}
+					c.convertInto(x.Arguments[0], types.Subst(sym.Type, c.fs.subst), dst)
+					return
+				}
+			case types.BuiltinSym:
+				c.builtin(id.Value, x, dst)
+				return
+			}
+		}
+	}
+
+	fn, sig := c.callee(x)
+	if sig == nil {
+		c.errorf(x, msg.BackendDirectCall)
+		return
+	}
+	site := Call{Func: fn, Args: make([]Loc, len(x.Arguments))}
+	for i, a := range x.Arguments {
+		site.Args[i] = Loc{Bank: c.kind(sig.Params[i], a).bank(), Reg: c.convert(a, sig.Params[i])}
+	}
+	if k := c.kind(sig.Result, x); k != kVoid {
+		// The result is converted to the type recorded for the call, which
+		// differs from the signature for `any` results.
+		want := c.kindOf(x)
+		if dst < 0 {
+			dst = c.alloc(want.bank())
+		}
+		site.Result = Loc{Bank: k.bank(), Reg: dst}
+		if !same(k, want) {
+			site.Result.Reg = c.alloc(k.bank())
+		}
+		defer c.conv(k, want, dst, site.Result.Reg, x)
+	}
+	f := c.fs.f
+	c.at(x.Token)
+	c.emit(CALL, int32(len(f.Calls)), 0, 0)
+	f.Calls = append(f.Calls, site)
+}
+
+// callee returns the index and signature of the function x calls. The
+// signature is nil when x is not a direct call of a top-level function.
+func (c *compiler) callee(x *ast.CallExpression) (int32, *types.Signature) {
+	sig, ok := c.typeOf(x.Function).(*types.Signature)
+	id, isIdent := x.Function.(*ast.Identifier)
+	if sel, isSel := x.Function.(*ast.SelectorExpression); isSel {
+		id, isIdent = sel.Sel, true
+	}
+	inst := c.info.Instances[x]
+	if inst != nil {
+		sig, ok = types.Subst(inst.Sig, c.fs.subst).(*types.Signature)
+	}
+	var lit *ast.AtqarmLiteral
+	if isIdent {
+		if sym := c.info.Uses[id]; sym != nil {
+			lit = c.decls[sym.Decl]
+		}
+	}
+	if !ok || lit == nil || len(sig.Params) != len(x.Arguments) {
+		return -1, nil
+	}
+	if inst != nil {
+		return c.instance(inst, x), sig
+	}
+	return c.funcs[types.FuncKey{Lit: lit}], sig
+}
+
+// builtin compiles a call of a built-in function.
+func (c *compiler) builtin(name string, x *ast.CallExpression, dst int32) {
+	args := x.Arguments
+	f64, san := types.Typ[types.F64], types.Typ[types.San]
+	result := func() int32 {
+		if dst < 0 {
+			dst = c.alloc(c.kindOf(x).bank())
+		}
+		return dst
+	}
+	// natural computes a result of kind k, then converts it to the kind
+	// the checker gave the call.
+	natural := func(k kind, emit func(r int32)) {
+		want := c.kindOf(x)
+		if same(k, want) {
+			emit(result())
+			return
+		}
+		r := c.alloc(k.bank())
+		emit(r)
+		c.conv(k, want, result(), r, x)
+	}
+	c.at(x.Token)
+
+	switch name {
+	case "kórset", "print":
+		for _, a := range args {
+			c.show(a)
+			if name == "print" && c.kindOf(a) != kJol {
+				c.emit(NEWLINE, 0, 0, 0)
+			}
+		}
+	case "printi":
+		c.emit(SHOWI, c.convert(args[0], san), 0, 0)
+	case "printf":
+		c.emit(PRINTF, c.convert(args[0], f64), c.convert(args[1], san), 0)
+	case "print_time_ns":
+		c.emit(TIMENS, c.convert(args[0], san), 0, 0)
+	case "argi":
+		i, def := c.convert(args[0], san), c.alloc(IntBank)
+		if len(args) == 2 {
+			c.convertInto(args[1], san, def)
+		} else {
+			c.emit(LOADI, def, 0, 0)
+		}
+		natural(kInt, func(r int32) { c.emit(ARGI, r, i, def) })
+	case "argf":
+		i, def := c.convert(args[0], san), c.alloc(FloatBank)
+		if len(args) == 2 {
+			c.convertInto(args[1], f64, def)
+		} else {
+			c.emit(LOADKF, def, c.floatConst(0), 0)
+		}
+		natural(kFloat, func(r int32) { c.emit(ARGF, r, i, def) })
+	case "now_ns", "time_ns":
+		c.emit(NOWNS, result(), 0, 0)
+	case "now_ms":
+		ms := c.alloc(IntBank)
+		c.loadInt(ms, 1000000)
+		c.emit(NOWNS, result(), 0, 0)
+		c.emit(DIVI, dst, dst, ms)
+	case "sqrt", "ln", "exp", "cos", "sin", "floor":
+		op := map[string]Op{"sqrt": SQRT, "ln": LOG, "exp": EXP, "cos": COS, "sin": SIN, "floor": FLOOR}[name]
+		c.emit(op, result(), c.convert(args[0], f64), 0)
+	case "make_f64":
+		c.emit(NEWAF, result(), c.convert(args[0], san), 0)
+	case "make_i32":
+		c.emit(NEWAI, result(), c.convert(args[0], san), 0)
+	case "len":
+		if k := c.kindOf(args[0]); k != kJol && k != kArray {
+			c.errorf(args[0], msg.BackendLen, args[0], c.typeOf(args[0]))
+			return
+		}
+		c.emit(LEN, result(), c.value(args[0]), 0)
+	case "push":
+		arr, _ := c.typeOf(x).(*types.Array)
+		if arr == nil {
+			arr = &types.Array{Elem: types.Typ[types.Any]}
+		}
+		a, v := c.value(args[0]), c.convert(args[1], arr.Elem)
+		if c.elem(arr, x) == ElemFloat {
+			c.emit(PUSHF, result(), a, v)
+		} else {
+			c.emit(PUSHI, result(), a, v)
+		}
+	case "index":
+		c.index(args[0], args[1], result(), x)
+	case "sort":
+		arr, _ := c.typeOf(x).(*types.Array)
+		if arr == nil {
+			arr = &types.Array{Elem: types.Typ[types.Any]}
+		}
+		c.emit(SORT, result(), c.value(args[0]), c.elem(arr, x))
+	case "assert":
+		cond, text := c.convert(args[0], types.Typ[types.Aqıqat]), int32(-1)
+		if len(args) == 2 {
+			text = c.convert(args[1], types.Typ[types.Jol])
+		}
+		c.emit(ASSERT, cond, text, 0)
+	default:
+		c.errorf(x, msg.BackendBuiltin, name)
+	}
+}
+
+// show compiles printing a value without a trailing newline.
+func (c *compiler) show(x ast.Expression) {
+	switch t := c.typeOf(x).(type) {
+	case *types.Array:
+		c.emit(SHOWA, c.value(x), c.elem(t, x), 0)
+		return
+	}
+	switch k := c.kindOf(x); k {
+	case kJol:
+		c.emit(SHOWS, c.value(x), 0, 0)
+	case kBool:
+		c.emit(SHOWB, c.value(x), 0, 0)
+	case kFloat:
+		c.emit(SHOWF, c.value(x), 0, 0)
+	case kDecimal:
+		c.emit(SHOWD, c.value(x), 0, 0)
+	case kU64:
+		c.emit(SHOWU, c.value(x), 0, 0)
+	case kInt, kI32:
+		c.emit(SHOWI, c.value(x), 0, 0)
+	default:
+		c.errorf(x, msg.BackendPrint, x, c.typeOf(x))
+	}
+}
@@ -0,0 +1,415 @@
+// FILE: internal/vm/tbc.go
+
+package vm
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math"
+	"math/big"
+
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/shopspring/decimal"
+)
+
+// A .tbc file holds a compiled Program, so that running it skips lexing,
+// parsing and checking. The layout is
+//
+//	magic    "TBC\x00"
+//	version  uvarint, FormatVersion
+//	pools    ints, floats, strings, decimals
+//	globals  int, float and ref counts
+//	init     function indexes
+//	main     varint, -1 when there is none
+//	funcs    name, file, regs, params, code, calls, lines
+//	checksum CRC-32 (IEEE) of everything before it, 4 bytes little-endian
+//
+// Counts and indexes are uvarints and signed values varints. Floats are
+// their IEEE bits, and decimals their coefficient (sign, then big-endian
+// magnitude bytes) and exponent, so that aqsha constants keep every digit.
+// Instructions are the opcode byte followed by A, B and C as varints.
+
+// FormatVersion is the version of the .tbc format written by Encode. Any
+// change to the layout or the instruction set increments it.
+const FormatVersion = 1
+
+const magic = "TBC\x00"
+
+// Encode serializes p in the .tbc format.
+func Encode(p *Program) []byte {
+	var e encoder
+	e.buf = append(e.buf, magic...)
+	e.uint(FormatVersion)
+
+	e.uint(len(p.Ints))
+	for _, v := range p.Ints {
+		e.int(v)
+	}
+	e.uint(len(p.Floats))
+	for _, v := range p.Floats {
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+	}
+	e.uint(len(p.Strings))
+	for _, v := range p.Strings {
+		e.string(v)
+	}
+	e.uint(len(p.Decimals))
+	for _, v := range p.Decimals {
+		e.decimal(v)
+	}
+
+	e.regs(p.Globals)
+	e.uint(len(p.Init))
+	for _, i := range p.Init {
+		e.uint(int(i))
+	}
+	e.int(int64(p.Main))
+
+	e.uint(len(p.Funcs))
+	for _, f := range p.Funcs {
+		e.string(f.Name)
+		e.string(f.File)
+		e.regs(f.Regs)
+		e.locs(f.Params)
+		e.uint(len(f.Code))
+		for _, in := range f.Code {
+			e.buf = append(e.buf, byte(in.Op))
+			e.int(int64(in.A))
+			e.int(int64(in.B))
+			e.int(int64(in.C))
+		}
+		e.uint(len(f.Calls))
+		for _, c := range f.Calls {
+			e.uint(int(c.Func))
+			e.locs(c.Args)
+			e.loc(c.Result)
+		}
+		e.uint(len(f.Lines))
+		for _, l := range f.Lines {
+			e.uint(int(l.PC))
+			e.uint(int(l.Line))
+			e.uint(int(l.Column))
+		}
+	}
+
+	return binary.LittleEndian.AppendUint32(e.buf, crc32.ChecksumIEEE(e.buf))
+}
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) uint(v int)      { e.buf = binary.AppendUvarint(e.buf, uint64(v)) }
+func (e *encoder) int(v int64)     { e.buf = binary.AppendVarint(e.buf, v) }
+func (e *encoder) string(s string) { e.uint(len(s)); e.buf = append(e.buf, s...) }
+
+func (e *encoder) decimal(d decimal.Decimal) {
+	c := d.Coefficient()
+	e.buf = append(e.buf, byte(c.Sign()+1))
+	mag := c.Bytes()
+	e.uint(len(mag))
+	e.buf = append(e.buf, mag...)
+	e.int(int64(d.Exponent()))
+}
+
+func (e *encoder) regs(r Regs) {
+	e.uint(int(r.Int))
+	e.uint(int(r.Float))
+	e.uint(int(r.Ref))
+}
+
+func (e *encoder) loc(l Loc) {
+	e.buf = append(e.buf, byte(l.Bank))
+	e.uint(int(l.Reg))
+}
+
+func (e *encoder) locs(ls []Loc) {
+	e.uint(len(ls))
+	for _, l := range ls {
+		e.loc(l)
+	}
+}
+
+// IsEncoded reports whether data starts like a .tbc file.
+func IsEncoded(data []byte) bool {
+	return len(data) >= len(magic) && string(data[:len(magic)]) == magic
+}
+
+// Decode reads a Program written by Encode. It checks the checksum and
+// that every operand refers to something that exists, so that running
+// the result cannot index outside its registers, pools or code.
+func Decode(data []byte) (*Program, error) {
+	if !IsEncoded(data) {
+		return nil, errors.New(msg.Text(msg.NotBytecode))
+	}
+	if len(data) < len(magic)+4 {
+		return nil, errors.New(msg.Text(msg.BytecodeTruncated))
+	}
+	body, sum := data[:len(data)-4], binary.LittleEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return nil, errors.New(msg.Text(msg.BytecodeChecksum))
+	}
+
+	d := &decoder{buf: body[len(magic):]}
+	if v := d.uint(); d.err == nil && v != FormatVersion {
+		return nil, errors.New(msg.Sprintf(msg.BytecodeVersion, v, FormatVersion))
+	}
+
+	p := &Program{}
+	p.Ints = make([]int64, d.count(1))
+	for i := range p.Ints {
+		p.Ints[i] = d.int()
+	}
+	p.Floats = make([]float64, d.count(8))
+	for i := range p.Floats {
+		p.Floats[i] = math.Float64frombits(binary.LittleEndian.Uint64(d.bytes(8)))
+	}
+	p.Strings = make([]string, d.count(1))
+	for i := range p.Strings {
+		p.Strings[i] = d.string()
+	}
+	p.Decimals = make([]decimal.Decimal, d.count(3))
+	for i := range p.Decimals {
+		p.Decimals[i] = d.decimal()
+	}
+
+	p.Globals = d.regs()
+	p.Init = make([]int32, d.count(1))
+	for i := range p.Init {
+		p.Init[i] = int32(d.uint())
+	}
+	p.Main = int32(d.int())
+
+	p.Funcs = make([]*Function, d.count(1))
+	for i := range p.Funcs {
+		f := &Function{Name: d.string(), File: d.string(), Regs: d.regs()}
+		f.Params = d.locs()
+		f.Code = make([]Instr, d.count(4))
+		for k := range f.Code {
+			f.Code[k] = Instr{Op: Op(d.byte()), A: int32(d.int()), B: int32(d.int()), C: int32(d.int())}
+		}
+		f.Calls = make([]Call, d.count(3))
+		for k := range f.Calls {
+			f.Calls[k] = Call{Func: int32(d.uint()), Args: d.locs(), Result: d.loc()}
+		}
+		f.Lines = make([]Line, d.count(3))
+		for k := range f.Lines {
+			f.Lines[k] = Line{PC: int32(d.uint()), Line: int32(d.uint()), Column: int32(d.uint())}
+		}
+		p.Funcs[i] = f
+	}
+	if d.err == nil && len(d.buf) > 0 {
+		d.err = errors.New(msg.Text(msg.BytecodeTrailing))
+	}
+	if d.err != nil {
+		return nil, errors.New(msg.Sprintf(msg.BadBytecode, d.err))
+	}
+	if err := verify(p); err != nil {
+		return nil, errors.New(msg.Sprintf(msg.BadBytecode, err))
+	}
+	return p, nil
+}
+
+// decoder reads the values of a .tbc body. After the first error it
+// returns zero values and keeps the error.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = errors.New(msg.Text(msg.BytecodeEnd))
+	}
+	d.buf = nil
+}
+
+func (d *decoder) bytes(n int) []byte {
+	if n < 0 || n > len(d.buf) {
+		d.fail()
+		return make([]byte, max(n, 0))
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) byte() byte { return d.bytes(1)[0] }
+
+func (d *decoder) uint() uint64 {
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) int() int64 {
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+// count reads a length of items taking at least size bytes each, so that
+// a damaged count cannot make the decoder allocate more than the data.
+func (d *decoder) count(size int) int {
+	n := d.uint()
+	if n > uint64(len(d.buf)/size) {
+		d.fail()
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) string() string { return string(d.bytes(d.count(1))) }
+
+func (d *decoder) decimal() decimal.Decimal {
+	sign := int(d.byte()) - 1
+	c := new(big.Int).SetBytes(d.bytes(d.count(1)))
+	if sign < 0 {
+		c.Neg(c)
+	}
+	exp := d.int()
+	if exp != int64(int32(exp)) {
+		d.fail()
+		return decimal.Zero
+	}
+	return decimal.NewFromBigInt(c, int32(exp))
+}
+
+func (d *decoder) regs() Regs {
+	return Regs{Int: int32(d.uint()), Float: int32(d.uint()), Ref: int32(d.uint())}
+}
+
+func (d *decoder) loc() Loc {
+	return Loc{Bank: Bank(d.byte()), Reg: int32(d.uint())}
+}
+
+func (d *decoder) locs() []Loc {
+	ls := make([]Loc, d.count(2))
+	for i := range ls {
+		ls[i] = d.loc()
+	}
+	return ls
+}
+
+// verify checks that every index in p is in range.
+func verify(p *Program) error {
+	if p.Globals.Int < 0 || p.Globals.Float < 0 || p.Globals.Ref < 0 {
+		return errors.New(msg.Text(msg.BytecodeGlobals))
+	}
+	nf := int32(len(p.Funcs))
+	for _, i := range p.Init {
+		if i < 0 || i >= nf || len(p.Funcs[i].Params) > 0 {
+			return errors.New(msg.Sprintf(msg.BytecodeInit, i))
+		}
+	}
+	if p.Main < -1 || p.Main >= nf || p.Main >= 0 && len(p.Funcs[p.Main].Params) > 0 {
+		return errors.New(msg.Sprintf(msg.BytecodeMain, p.Main))
+	}
+	// The bank of each function's result, from its return instructions,
+	// or -1 when it returns nothing.
+	results := make([]int, nf)
+	for i, f := range p.Funcs {
+		results[i] = -1
+		for _, in := range f.Code {
+			b := -1
+			switch in.Op {
+			case RETI:
+				b = int(IntBank)
+			case RETF:
+				b = int(FloatBank)
+			case RETR:
+				b = int(RefBank)
+			default:
+				continue
+			}
+			if results[i] >= 0 && results[i] != b {
+				return errors.New(msg.Sprintf(msg.BytecodeResults, f.Name))
+			}
+			results[i] = b
+		}
+	}
+	for _, f := range p.Funcs {
+		if err := verifyFunc(p, f, results); err != nil {
+			return errors.New(msg.Sprintf(msg.BytecodeFunc, f.Name, err))
+		}
+	}
+	return nil
+}
+
+func verifyFunc(p *Program, f *Function, results []int) error {
+	if f.Regs.Int < 0 || f.Regs.Float < 0 || f.Regs.Ref < 0 {
+		return errors.New(msg.Text(msg.BytecodeRegs))
+	}
+	loc := func(r Regs, l Loc) bool {
+		switch l.Bank {
+		case IntBank:
+			return l.Reg >= 0 && l.Reg < r.Int
+		case FloatBank:
+			return l.Reg >= 0 && l.Reg < r.Float
+		case RefBank:
+			return l.Reg >= 0 && l.Reg < r.Ref
+		}
+		return false
+	}
+	for _, l := range f.Params {
+		if !loc(f.Regs, l) {
+			return errors.New(msg.Sprintf(msg.BytecodeParam, l))
+		}
+	}
+	for i, c := range f.Calls {
+		if c.Func < 0 || int(c.Func) >= len(p.Funcs) {
+			return errors.New(msg.Sprintf(msg.BytecodeCallFunc, i, c.Func))
+		}
+		callee := p.Funcs[c.Func]
+		if len(c.Args) != len(callee.Params) {
+			return errors.New(msg.Sprintf(msg.BytecodeCallArgs, i, len(c.Args), len(callee.Params)))
+		}
+		if r := results[c.Func]; r >= 0 && (int(c.Result.Bank) != r || !loc(f.Regs, c.Result)) {
+			return errors.New(msg.Sprintf(msg.BytecodeCallResult, i, c.Result))
+		}
+		for k, a := range c.Args {
+			if !loc(f.Regs, a) || a.Bank != callee.Params[k].Bank {
+				return errors.New(msg.Sprintf(msg.BytecodeCallArg, i, a))
+			}
+		}
+	}
+	if len(f.Code) == 0 {
+		return errors.New(msg.Text(msg.BytecodeNoCode))
+	}
+	switch f.Code[len(f.Code)-1].Op {
+	case JMP, RET, RETI, RETF, RETR:
+	default:
+		return errors.New(msg.Text(msg.BytecodeCodeEnd))
+	}
+
+	limit := [...]int{
+		oI: int(f.Regs.Int), oF: int(f.Regs.Float), oR: int(f.Regs.Ref), oRopt: int(f.Regs.Ref),
+		oGI: int(p.Globals.Int), oGF: int(p.Globals.Float), oGR: int(p.Globals.Ref),
+		oPC: len(f.Code), oKI: len(p.Ints), oKF: len(p.Floats), oKS: len(p.Strings),
+		oKD: len(p.Decimals), oCall: len(f.Calls), oElem: len(elemNames),
+	}
+	for pc, in := range f.Code {
+		if in.Op >= numOps {
+			return errors.New(msg.Sprintf(msg.BytecodeOpcode, pc, in.Op))
+		}
+		for k, o := range operands[in.Op] {
+			v := int([3]int32{in.A, in.B, in.C}[k])
+			switch {
+			case o == oNone || o == oImm:
+			case o == oRopt && v == -1:
+			case v < 0 || v >= limit[o]:
+				return errors.New(msg.Sprintf(msg.BytecodeOperand, pc, k+1, in.Op))
+			}
+		}
+	}
+	return nil
+}
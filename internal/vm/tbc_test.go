@@ -0,0 +1,159 @@
+// FILE: internal/vm/tbc_test.go
+
+package vm_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"strings"
+	"testing"
+
+	"github.com/DauletBai/tenge/internal/lang/lexer"
+	"github.com/DauletBai/tenge/internal/lang/module"
+	"github.com/DauletBai/tenge/internal/lang/parser"
+	"github.com/DauletBai/tenge/internal/lang/types"
+	"github.com/DauletBai/tenge/internal/vm"
+)
+
+// compile returns the bytecode of src.
+func compile(t *testing.T, src string) *vm.Program {
+	t.Helper()
+	p := parser.New(lexer.New(src))
+	prog, errs := module.LoadParsed("test.tng", p.ParseProgram())
+	errs = append(p.Diagnostics(), errs...)
+	var info *types.Info
+	if len(errs) == 0 {
+		info, errs = types.CheckModules(prog)
+	}
+	var code *vm.Program
+	if len(errs) == 0 {
+		code, errs = vm.Compile(prog, info)
+	}
+	if len(errs) > 0 {
+		t.Fatal(errs[0])
+	}
+	return code
+}
+
+// output runs p and returns what it printed.
+func output(t *testing.T, p *vm.Program) string {
+	t.Helper()
+	var out strings.Builder
+	if err := vm.Run(p, []string{"test.tng"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+// sealed returns body, which starts with the magic, with its checksum.
+func sealed(body []byte) []byte {
+	return binary.LittleEndian.AppendUint32(append([]byte(nil), body...), crc32.ChecksumIEEE(body))
+}
+
+// A program with something in every pool, globals and calls.
+const tbcSrc = `
+let greeting = "sálem"
+var count = 0
+fn step(x: int, y: f64) -> f64 {
+    count = count + 1
+    return y * 2.5 + f64(x)
+}
+let price: decimal = 12.345678901234567890123
+print(greeting, " ", step(-7, 1.5), " ", price * 3, " ", count)
+`
+
+func TestEncodeRoundTrip(t *testing.T) {
+	p := compile(t, tbcSrc)
+	want := output(t, p)
+	data := vm.Encode(p)
+	if !vm.IsEncoded(data) {
+		t.Fatalf("IsEncoded(Encode(p)) = false")
+	}
+	q, err := vm.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again := vm.Encode(q); !bytes.Equal(again, data) {
+		t.Errorf("Encode(Decode(data)) differs from data")
+	}
+	if got := output(t, q); got != want {
+		t.Errorf("decoded program printed %q, want %q", got, want)
+	}
+	if !strings.Contains(want, "37.037036703703703670369") {
+		t.Errorf("output %q lost digits of the decimal", want)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	data := vm.Encode(compile(t, tbcSrc))
+	body := data[:len(data)-4]
+	damaged := append([]byte(nil), data...)
+	damaged[len(damaged)/2] ^= 0x40
+	version := append([]byte("TBC\x00"), 2)
+
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"not bytecode", []byte("atqar'm main() {}"), "not a tenge bytecode file"},
+		{"no checksum", []byte("TBC\x00\x01"), "bytecode file is truncated"},
+		{"damaged", damaged, "bytecode checksum mismatch: the file is damaged"},
+		{"other version", sealed(append(version, body[5:]...)), "bytecode format 2 is not supported (want 1); recompile the program"},
+		{"trailing data", sealed(append(body, 0)), "bad bytecode file: trailing data"},
+		{"cut short", sealed(body[:len(body)-1]), "bad bytecode file: unexpected end of data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := vm.Decode(tt.data)
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("Decode: %v, want %s", err, tt.err)
+			}
+		})
+	}
+}
+
+// TestDecodeTruncated decodes every prefix of a file, with a checksum
+// that matches, so that the decoder itself meets the end of the data.
+func TestDecodeTruncated(t *testing.T) {
+	body := vm.Encode(compile(t, tbcSrc))
+	body = body[:len(body)-4]
+	for n := len("TBC\x00"); n < len(body); n++ {
+		if _, err := vm.Decode(sealed(body[:n])); err == nil {
+			t.Errorf("Decode of the first %d of %d bytes succeeded", n, len(body))
+		}
+	}
+}
+
+// TestDecodeVerify checks that Decode rejects programs whose indexes
+// point outside what they have.
+func TestDecodeVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(p *vm.Program)
+		err    string
+	}{
+		{"main", func(p *vm.Program) { p.Main = int32(len(p.Funcs)) }, "bad main function"},
+		{"init", func(p *vm.Program) { p.Init = append(p.Init, -1) }, "bad init function -1"},
+		{"no code", func(p *vm.Program) { p.Funcs[0].Code = nil }, "no code"},
+		{"opcode", func(p *vm.Program) { p.Funcs[0].Code[0].Op = 255 }, "0: bad opcode"},
+		{"call", func(p *vm.Program) {
+			for _, f := range p.Funcs {
+				for i := range f.Calls {
+					f.Calls[i].Func = int32(len(p.Funcs))
+				}
+			}
+		}, "call 0: bad function"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := compile(t, tbcSrc)
+			tt.damage(p)
+			_, err := vm.Decode(vm.Encode(p))
+			if err == nil || !strings.Contains(err.Error(), tt.err) || !strings.HasPrefix(err.Error(), "bad bytecode file: ") {
+				t.Errorf("Decode: %v, want bad bytecode file: ... %s", err, tt.err)
+			}
+		})
+	}
+}
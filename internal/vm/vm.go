@@ -0,0 +1,703 @@
+// FILE: internal/vm/vm.go
+
+package vm
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/DauletBai/tenge/internal/lang/msg"
+	"github.com/shopspring/decimal"
+)
+
+// MaxDepth is the number of nested calls after which a program fails
+// with a stack overflow.
+const MaxDepth = 1 << 20
+
+// Error is a run-time error of a program. Its text starts with the
+// position of the failing instruction, like the interpreter's errors.
+type Error struct {
+	Pos     string // "file:line:col", or "" when unknown
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Pos == "" {
+		return e.Message
+	}
+	return e.Pos + ": " + e.Message
+}
+
+// frame is a suspended call: the caller and where its registers start.
+// It holds no pointers, so pushing one needs no write barrier.
+type frame struct {
+	fn, pc     int32
+	bi, bf, br int32
+}
+
+type machine struct {
+	prog  *Program
+	args  []string
+	out   *bufio.Writer
+	start time.Time
+
+	gi []int64
+	gf []float64
+	gr []interface{}
+
+	// The registers of all frames, each starting where its caller's end.
+	ints   []int64
+	floats []float64
+	refs   []interface{}
+	frames []frame
+
+	// The running function and where its registers start. They are kept
+	// here rather than in locals of the dispatch loop, which needs its
+	// machine registers for the operands.
+	fn         *Function
+	fi         int32 // index of fn in prog.Funcs
+	bi, bf, br int
+}
+
+// Run executes p, writing its output to out. args are the program
+// arguments read by argi and argf; args[0] is the program itself.
+func Run(p *Program, args []string, out io.Writer) error {
+	m := &machine{
+		prog:  p,
+		args:  args,
+		out:   bufio.NewWriter(out),
+		start: time.Now(),
+		gi:    make([]int64, p.Globals.Int),
+		gf:    make([]float64, p.Globals.Float),
+		gr:    make([]interface{}, p.Globals.Ref),
+	}
+	defer m.out.Flush()
+	for _, i := range p.Init {
+		if err := m.run(i); err != nil {
+			return err
+		}
+	}
+	if p.Main >= 0 {
+		return m.run(p.Main)
+	}
+	return nil
+}
+
+// grow makes room for the registers of fn starting at bi, bf and br.
+func (m *machine) grow(fn *Function, bi, bf, br int) {
+	if n := bi + int(fn.Regs.Int); n > len(m.ints) {
+		m.ints = append(m.ints, make([]int64, n+len(m.ints))...)
+	}
+	if n := bf + int(fn.Regs.Float); n > len(m.floats) {
+		m.floats = append(m.floats, make([]float64, n+len(m.floats))...)
+	}
+	if n := br + int(fn.Regs.Ref); n > len(m.refs) {
+		m.refs = append(m.refs, make([]interface{}, n+len(m.refs))...)
+	}
+}
+
+func (m *machine) fault(fn *Function, pc int, code msg.Code, args ...interface{}) error {
+	m.out.Flush()
+	return &Error{Pos: fn.Pos(pc), Message: msg.Sprintf(code, args...)}
+}
+
+// run calls the function with index fi, which has no parameters, on an
+// empty stack.
+func (m *machine) run(fi int32) error {
+	fn := m.prog.Funcs[fi]
+	m.fn, m.fi, m.bi, m.bf, m.br = fn, fi, 0, 0, 0
+	m.grow(fn, 0, 0, 0)
+	I := m.ints[:fn.Regs.Int]
+	F := m.floats[:fn.Regs.Float]
+	R := m.refs[:fn.Regs.Ref]
+	code := fn.Code
+	pc := 0
+
+	for {
+		in := code[pc]
+		pc++
+		switch in.Op {
+		case NOP:
+
+		case MOVI:
+			I[in.A] = I[in.B]
+		case MOVF:
+			F[in.A] = F[in.B]
+		case MOVR:
+			R[in.A] = R[in.B]
+		case LOADI:
+			I[in.A] = int64(in.B)
+		case LOADKI:
+			I[in.A] = m.prog.Ints[in.B]
+		case LOADKF:
+			F[in.A] = m.prog.Floats[in.B]
+		case LOADKS:
+			R[in.A] = m.prog.Strings[in.B]
+		case LOADKD:
+			R[in.A] = m.prog.Decimals[in.B]
+		case ZERO:
+			R[in.A] = zero(in.B)
+
+		case GETGI:
+			I[in.A] = m.gi[in.B]
+		case GETGF:
+			F[in.A] = m.gf[in.B]
+		case GETGR:
+			R[in.A] = m.gr[in.B]
+		case SETGI:
+			m.gi[in.A] = I[in.B]
+		case SETGF:
+			m.gf[in.A] = F[in.B]
+		case SETGR:
+			m.gr[in.A] = R[in.B]
+
+		case NEWCI:
+			v := I[in.B]
+			R[in.A] = &v
+		case NEWCF:
+			v := F[in.B]
+			R[in.A] = &v
+		case NEWCR:
+			v := R[in.B]
+			R[in.A] = &v
+		case LDCI:
+			p, _ := R[in.B].(*int64)
+			if p == nil {
+				return m.fault(m.fn, pc-1, msg.NotPointer, "nil")
+			}
+			I[in.A] = *p
+		case LDCF:
+			p, _ := R[in.B].(*float64)
+			if p == nil {
+				return m.fault(m.fn, pc-1, msg.NotPointer, "nil")
+			}
+			F[in.A] = *p
+		case LDCR:
+			p, _ := R[in.B].(*interface{})
+			if p == nil {
+				return m.fault(m.fn, pc-1, msg.NotPointer, "nil")
+			}
+			R[in.A] = *p
+		case STCI:
+			p, _ := R[in.A].(*int64)
+			if p == nil {
+				return m.fault(m.fn, pc-1, msg.NotPointer, "nil")
+			}
+			*p = I[in.B]
+		case STCF:
+			p, _ := R[in.A].(*float64)
+			if p == nil {
+				return m.fault(m.fn, pc-1, msg.NotPointer, "nil")
+			}
+			*p = F[in.B]
+		case STCR:
+			p, _ := R[in.A].(*interface{})
+			if p == nil {
+				return m.fault(m.fn, pc-1, msg.NotPointer, "nil")
+			}
+			*p = R[in.B]
+
+		case ADDI:
+			I[in.A] = I[in.B] + I[in.C]
+		case ADDIK:
+			I[in.A] = I[in.B] + int64(in.C)
+		case SUBI:
+			I[in.A] = I[in.B] - I[in.C]
+		case MULI:
+			I[in.A] = I[in.B] * I[in.C]
+		case DIVI:
+			d := I[in.C]
+			if d == 0 {
+				return m.fault(m.fn, pc-1, msg.DivisionByZero)
+			}
+			I[in.A] = I[in.B] / d
+		case MODI:
+			d := I[in.C]
+			if d == 0 {
+				return m.fault(m.fn, pc-1, msg.DivisionByZero)
+			}
+			I[in.A] = I[in.B] % d
+		case DIVU:
+			d := uint64(I[in.C])
+			if d == 0 {
+				return m.fault(m.fn, pc-1, msg.DivisionByZero)
+			}
+			I[in.A] = int64(uint64(I[in.B]) / d)
+		case MODU:
+			d := uint64(I[in.C])
+			if d == 0 {
+				return m.fault(m.fn, pc-1, msg.DivisionByZero)
+			}
+			I[in.A] = int64(uint64(I[in.B]) % d)
+		case ANDI:
+			I[in.A] = I[in.B] & I[in.C]
+		case ORI:
+			I[in.A] = I[in.B] | I[in.C]
+		case XORI:
+			I[in.A] = I[in.B] ^ I[in.C]
+		case SHLI:
+			I[in.A] = I[in.B] << uint64(I[in.C])
+		case SHRI:
+			I[in.A] = I[in.B] >> uint64(I[in.C])
+		case SHRU:
+			I[in.A] = int64(uint64(I[in.B]) >> uint64(I[in.C]))
+		case NEGI:
+			I[in.A] = -I[in.B]
+		case NOT:
+			I[in.A] = I[in.B] ^ 1
+		case SEXT:
+			I[in.A] = int64(int32(I[in.B]))
+
+		case ADDF:
+			F[in.A] = F[in.B] + F[in.C]
+		case SUBF:
+			F[in.A] = F[in.B] - F[in.C]
+		case MULF:
+			F[in.A] = F[in.B] * F[in.C]
+		case DIVF:
+			F[in.A] = F[in.B] / F[in.C]
+		case MODF:
+			F[in.A] = math.Mod(F[in.B], F[in.C])
+		case NEGF:
+			F[in.A] = -F[in.B]
+
+		case ADDD:
+			R[in.A] = R[in.B].(decimal.Decimal).Add(R[in.C].(decimal.Decimal))
+		case SUBD:
+			R[in.A] = R[in.B].(decimal.Decimal).Sub(R[in.C].(decimal.Decimal))
+		case MULD:
+			R[in.A] = R[in.B].(decimal.Decimal).Mul(R[in.C].(decimal.Decimal))
+		case DIVD:
+			d := R[in.C].(decimal.Decimal)
+			if d.IsZero() {
+				return m.fault(m.fn, pc-1, msg.DivisionByZero)
+			}
+			R[in.A] = divide(R[in.B].(decimal.Decimal), d)
+		case MODD:
+			d := R[in.C].(decimal.Decimal)
+			if d.IsZero() {
+				return m.fault(m.fn, pc-1, msg.DivisionByZero)
+			}
+			R[in.A] = R[in.B].(decimal.Decimal).Mod(d)
+		case NEGD:
+			R[in.A] = R[in.B].(decimal.Decimal).Neg()
+		case ITOD:
+			R[in.A] = decimal.NewFromInt(I[in.B])
+		case UTOD:
+			R[in.A] = decimal.NewFromUint64(uint64(I[in.B]))
+		case FTOD:
+			R[in.A] = decimal.NewFromFloat(F[in.B])
+		case DTOI, DTOU:
+			I[in.A] = R[in.B].(decimal.Decimal).IntPart()
+		case DTOF:
+			F[in.A], _ = R[in.B].(decimal.Decimal).Float64()
+
+		case ITOF:
+			F[in.A] = float64(I[in.B])
+		case UTOF:
+			F[in.A] = float64(uint64(I[in.B]))
+		case FTOI:
+			I[in.A] = int64(F[in.B])
+		case FTOU:
+			I[in.A] = int64(uint64(F[in.B]))
+
+		case EQI:
+			I[in.A] = b2i(I[in.B] == I[in.C])
+		case NEI:
+			I[in.A] = b2i(I[in.B] != I[in.C])
+		case LTI:
+			I[in.A] = b2i(I[in.B] < I[in.C])
+		case LEI:
+			I[in.A] = b2i(I[in.B] <= I[in.C])
+		case LTU:
+			I[in.A] = b2i(uint64(I[in.B]) < uint64(I[in.C]))
+		case LEU:
+			I[in.A] = b2i(uint64(I[in.B]) <= uint64(I[in.C]))
+		case EQF:
+			I[in.A] = b2i(F[in.B] == F[in.C])
+		case NEF:
+			I[in.A] = b2i(F[in.B] != F[in.C])
+		case LTF:
+			I[in.A] = b2i(F[in.B] < F[in.C])
+		case LEF:
+			I[in.A] = b2i(F[in.B] <= F[in.C])
+		case EQD:
+			I[in.A] = b2i(R[in.B].(decimal.Decimal).Equal(R[in.C].(decimal.Decimal)))
+		case NED:
+			I[in.A] = b2i(!R[in.B].(decimal.Decimal).Equal(R[in.C].(decimal.Decimal)))
+		case LTD:
+			I[in.A] = b2i(R[in.B].(decimal.Decimal).LessThan(R[in.C].(decimal.Decimal)))
+		case LED:
+			I[in.A] = b2i(R[in.B].(decimal.Decimal).LessThanOrEqual(R[in.C].(decimal.Decimal)))
+		case EQS:
+			I[in.A] = b2i(R[in.B].(string) == R[in.C].(string))
+		case NES:
+			I[in.A] = b2i(R[in.B].(string) != R[in.C].(string))
+		case LTS:
+			I[in.A] = b2i(R[in.B].(string) < R[in.C].(string))
+		case LES:
+			I[in.A] = b2i(R[in.B].(string) <= R[in.C].(string))
+
+		case JMP:
+			pc = int(in.A)
+		case JT:
+			if I[in.A] != 0 {
+				pc = int(in.B)
+			}
+		case JF:
+			if I[in.A] == 0 {
+				pc = int(in.B)
+			}
+		case BEQI:
+			if I[in.A] == I[in.B] {
+				pc = int(in.C)
+			}
+		case BNEI:
+			if I[in.A] != I[in.B] {
+				pc = int(in.C)
+			}
+		case BLTI:
+			if I[in.A] < I[in.B] {
+				pc = int(in.C)
+			}
+		case BLEI:
+			if I[in.A] <= I[in.B] {
+				pc = int(in.C)
+			}
+		case BLTU:
+			if uint64(I[in.A]) < uint64(I[in.B]) {
+				pc = int(in.C)
+			}
+		case BLEU:
+			if uint64(I[in.A]) <= uint64(I[in.B]) {
+				pc = int(in.C)
+			}
+		case BEQIK:
+			if I[in.A] == int64(in.B) {
+				pc = int(in.C)
+			}
+		case BNEIK:
+			if I[in.A] != int64(in.B) {
+				pc = int(in.C)
+			}
+		case BLTIK:
+			if I[in.A] < int64(in.B) {
+				pc = int(in.C)
+			}
+		case BLEIK:
+			if I[in.A] <= int64(in.B) {
+				pc = int(in.C)
+			}
+		case BGTIK:
+			if I[in.A] > int64(in.B) {
+				pc = int(in.C)
+			}
+		case BGEIK:
+			if I[in.A] >= int64(in.B) {
+				pc = int(in.C)
+			}
+
+		case CALL:
+			fn := m.fn
+			site := &fn.Calls[in.A]
+			callee := m.prog.Funcs[site.Func]
+			if len(m.frames) >= MaxDepth {
+				return m.fault(m.fn, pc-1, msg.StackOverflow, MaxDepth)
+			}
+			m.frames = append(m.frames, frame{fn: m.fi, pc: int32(pc), bi: int32(m.bi), bf: int32(m.bf), br: int32(m.br)})
+			bi, bf, br := m.bi+int(fn.Regs.Int), m.bf+int(fn.Regs.Float), m.br+int(fn.Regs.Ref)
+			m.grow(callee, bi, bf, br)
+			// The caller's registers are read through the old slices, which
+			// keep their values when the stacks move.
+			CI := m.ints[bi : bi+int(callee.Regs.Int)]
+			CF := m.floats[bf : bf+int(callee.Regs.Float)]
+			CR := m.refs[br : br+int(callee.Regs.Ref)]
+			for k, a := range site.Args {
+				p := callee.Params[k]
+				switch a.Bank {
+				case IntBank:
+					CI[p.Reg] = I[a.Reg]
+				case FloatBank:
+					CF[p.Reg] = F[a.Reg]
+				default:
+					CR[p.Reg] = R[a.Reg]
+				}
+			}
+			m.fn, m.fi, m.bi, m.bf, m.br = callee, site.Func, bi, bf, br
+			code, pc = callee.Code, 0
+			I, F, R = CI, CF, CR
+
+		case RET, RETI, RETF, RETR:
+			n := len(m.frames)
+			if n == 0 {
+				return nil
+			}
+			caller := m.frames[n-1]
+			m.frames = m.frames[:n-1]
+			var iv int64
+			var fv float64
+			var rv interface{}
+			switch in.Op {
+			case RETI:
+				iv = I[in.A]
+			case RETF:
+				fv = F[in.A]
+			case RETR:
+				rv = R[in.A]
+			}
+			// Drop the references of the returning frame for the collector.
+			clear(R)
+			fn := m.prog.Funcs[caller.fn]
+			m.fn, m.fi = fn, caller.fn
+			m.bi, m.bf, m.br = int(caller.bi), int(caller.bf), int(caller.br)
+			code, pc = fn.Code, int(caller.pc)
+			I = m.ints[m.bi : m.bi+int(fn.Regs.Int)]
+			F = m.floats[m.bf : m.bf+int(fn.Regs.Float)]
+			R = m.refs[m.br : m.br+int(fn.Regs.Ref)]
+			res := fn.Calls[code[pc-1].A].Result
+			switch in.Op {
+			case RETI:
+				I[res.Reg] = iv
+			case RETF:
+				F[res.Reg] = fv
+			case RETR:
+				R[res.Reg] = rv
+			}
+
+		case CONCAT:
+			R[in.A] = R[in.B].(string) + R[in.C].(string)
+		case NEWAI:
+			n := I[in.B]
+			if n < 0 {
+				return m.fault(m.fn, pc-1, msg.NegativeLength)
+			}
+			R[in.A] = make([]int64, n)
+		case NEWAF:
+			n := I[in.B]
+			if n < 0 {
+				return m.fault(m.fn, pc-1, msg.NegativeLength)
+			}
+			R[in.A] = make([]float64, n)
+		case GETAI:
+			a, i := R[in.B].([]int64), I[in.C]
+			if uint64(i) >= uint64(len(a)) {
+				return m.fault(m.fn, pc-1, msg.OutOfRange, i, len(a))
+			}
+			I[in.A] = a[i]
+		case GETAF:
+			a, i := R[in.B].([]float64), I[in.C]
+			if uint64(i) >= uint64(len(a)) {
+				return m.fault(m.fn, pc-1, msg.OutOfRange, i, len(a))
+			}
+			F[in.A] = a[i]
+		case SETAI:
+			a, i := R[in.A].([]int64), I[in.B]
+			if uint64(i) >= uint64(len(a)) {
+				return m.fault(m.fn, pc-1, msg.OutOfRange, i, len(a))
+			}
+			a[i] = I[in.C]
+		case SETAF:
+			a, i := R[in.A].([]float64), I[in.B]
+			if uint64(i) >= uint64(len(a)) {
+				return m.fault(m.fn, pc-1, msg.OutOfRange, i, len(a))
+			}
+			a[i] = F[in.C]
+		case PUSHI:
+			R[in.A] = append(R[in.B].([]int64), I[in.C])
+		case PUSHF:
+			R[in.A] = append(R[in.B].([]float64), F[in.C])
+		case SORT:
+			R[in.A] = sorted(R[in.B], in.C)
+		case LEN:
+			switch v := R[in.B].(type) {
+			case string:
+				I[in.A] = int64(utf8.RuneCountInString(v))
+			case []int64:
+				I[in.A] = int64(len(v))
+			case []float64:
+				I[in.A] = int64(len(v))
+			}
+
+		case ARGI:
+			I[in.A] = m.argi(I[in.B], I[in.C])
+		case ARGF:
+			F[in.A] = m.argf(I[in.B], F[in.C])
+		case NOWNS:
+			I[in.A] = int64(time.Since(m.start))
+		case SQRT:
+			F[in.A] = math.Sqrt(F[in.B])
+		case LOG:
+			F[in.A] = math.Log(F[in.B])
+		case EXP:
+			F[in.A] = math.Exp(F[in.B])
+		case COS:
+			F[in.A] = math.Cos(F[in.B])
+		case SIN:
+			F[in.A] = math.Sin(F[in.B])
+		case FLOOR:
+			F[in.A] = math.Floor(F[in.B])
+
+		case SHOWI:
+			m.out.WriteString(strconv.FormatInt(I[in.A], 10))
+		case SHOWU:
+			m.out.WriteString(strconv.FormatUint(uint64(I[in.A]), 10))
+		case SHOWB:
+			m.out.WriteString(showBool(I[in.A]))
+		case SHOWF:
+			m.out.WriteString(showFloat(F[in.A]))
+		case SHOWD:
+			m.out.WriteString(R[in.A].(decimal.Decimal).String())
+		case SHOWS:
+			m.out.WriteString(R[in.A].(string))
+		case SHOWA:
+			m.out.WriteString(showArray(R[in.A], in.B))
+		case NEWLINE:
+			m.out.WriteByte('\n')
+		case PRINTF:
+			m.out.WriteString(strconv.FormatFloat(F[in.A], 'f', int(max(I[in.B], 0)), 64))
+		case TIMENS:
+			m.out.WriteString("TIME_NS: " + strconv.FormatInt(I[in.A], 10) + "\n")
+		case ASSERT:
+			if I[in.A] == 0 {
+				if in.B >= 0 {
+					return m.fault(m.fn, pc-1, msg.AssertionMessage, R[in.B].(string))
+				}
+				return m.fault(m.fn, pc-1, msg.AssertionFailed)
+			}
+
+		default:
+			panic("vm: bad opcode " + in.Op.String())
+		}
+	}
+}
+
+func b2i(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// zero returns the zero value of a ref register of elem kind k.
+func zero(k int32) interface{} {
+	switch k {
+	case ElemFloat:
+		return []float64(nil)
+	case ElemCell:
+		return nil
+	case ElemJol:
+		return ""
+	case ElemDecimal:
+		return decimal.Zero
+	}
+	return []int64(nil)
+}
+
+// divide rounds like the interpreter: to 16 decimal places, keeping at
+// least 17 significant digits.
+func divide(l, r decimal.Decimal) decimal.Decimal {
+	magnitude := (l.NumDigits() + int(l.Exponent())) - (r.NumDigits() + int(r.Exponent()))
+	places := 16
+	if 17-magnitude > places {
+		places = 17 - magnitude
+	}
+	return l.DivRound(r, int32(places))
+}
+
+func (m *machine) argi(i, def int64) int64 {
+	if i < 0 || i >= int64(len(m.args)) {
+		return def
+	}
+	v, err := strconv.ParseInt(m.args[i], 10, 64)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func (m *machine) argf(i int64, def float64) float64 {
+	if i < 0 || i >= int64(len(m.args)) {
+		return def
+	}
+	v, err := strconv.ParseFloat(m.args[i], 64)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// sorted returns a sorted copy of an array of elem kind k.
+func sorted(a interface{}, k int32) interface{} {
+	switch a := a.(type) {
+	case []float64:
+		b := slices.Clone(a)
+		slices.Sort(b)
+		return b
+	case []int64:
+		b := slices.Clone(a)
+		if k == ElemU64 {
+			slices.SortFunc(b, func(x, y int64) int {
+				switch {
+				case uint64(x) < uint64(y):
+					return -1
+				case uint64(x) > uint64(y):
+					return 1
+				}
+				return 0
+			})
+		} else {
+			slices.Sort(b)
+		}
+		return b
+	}
+	return a
+}
+
+// --- Printing, the way the interpreter and compiled programs print ---
+
+func showBool(v int64) string {
+	if v != 0 {
+		return "jan"
+	}
+	return "j'n"
+}
+
+func showFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func showArray(a interface{}, k int32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	switch a := a.(type) {
+	case []float64:
+		for i, v := range a {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(showFloat(v))
+		}
+	case []int64:
+		for i, v := range a {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			switch k {
+			case ElemU64:
+				b.WriteString(strconv.FormatUint(uint64(v), 10))
+			case ElemBool:
+				b.WriteString(showBool(v))
+			default:
+				b.WriteString(strconv.FormatInt(v, 10))
+			}
+		}
+	}
+	b.WriteByte(']')
+	return b.String()
+}